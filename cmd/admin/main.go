@@ -0,0 +1,198 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// admin executes the owner-only actions of token transferrers, and keeps an audit log of them.
+//
+//	admin update-min-version -rpc <url> -version <version> <address>...
+//	admin pause -rpc <url> [-teleporter <address>] <address>...
+//	admin unpause -rpc <url> [-teleporter <address>] <address>...
+//	admin transfer-ownership -rpc <url> -new-owner <address> <address>...
+//	admin audit -rpc <url> -dir <dir> [-from-block <number>] [-confirmations <number>] <address>...
+//
+// update-min-version raises the minimum Teleporter version each token transferrer on the chain at -rpc
+// accepts messages from, after a new Teleporter version is registered. pause and unpause stop and resume the
+// delivery of messages from a Teleporter address, and transfer-ownership hands the token transferrers over to
+// a new owner. Token transferrers the action would not change are reported and skipped. The transactions are
+// sent with the hex encoded private key held by the ADMIN_KEY environment variable, which must own the token
+// transferrers.
+//
+// audit appends the owner-only actions emitted by each token transferrer since the last run to its log in
+// -dir, and prints them as JSON lines.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/admin"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// Address of the TeleporterMessenger deployed with Nick's method on every chain
+	defaultTeleporterAddress = "0x253b2784c75e510dD0fF1da844684a1aC0aa5fcf"
+	// keyEnvVar holds the key of the owner, so that it is not passed on the command line.
+	keyEnvVar = "ADMIN_KEY"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: admin <update-min-version|pause|unpause|transfer-ownership|audit> [flags]")
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "update-min-version", "pause", "unpause", "transfer-ownership":
+		err = execute(os.Args[1], os.Args[2:])
+	case "audit":
+		err = audit(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func execute(command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	rpcURL := flags.String("rpc", "", "RPC endpoint of the chain the token transferrers are deployed on")
+	var (
+		version    *string
+		teleporter *string
+		newOwner   *string
+		usage      string
+	)
+	switch command {
+	case "update-min-version":
+		version = flags.String("version", "", "minimum Teleporter version to accept messages from")
+		usage = "-version <version>"
+	case "pause", "unpause":
+		teleporter = flags.String("teleporter", defaultTeleporterAddress, "address of the TeleporterMessenger")
+		usage = "[-teleporter <address>]"
+	case "transfer-ownership":
+		newOwner = flags.String("new-owner", "", "address of the new owner")
+		usage = "-new-owner <address>"
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	addresses, err := parseAddresses(flags.Args())
+	if *rpcURL == "" || err != nil {
+		return fmt.Errorf("usage: admin %s -rpc <url> %s <address>...", command, usage)
+	}
+
+	var action admin.Action
+	switch command {
+	case "update-min-version":
+		value, ok := new(big.Int).SetString(*version, 10)
+		if !ok || value.Sign() <= 0 {
+			return fmt.Errorf("invalid version %q", *version)
+		}
+		action = admin.UpdateMinTeleporterVersion(value)
+	case "pause", "unpause":
+		if !common.IsHexAddress(*teleporter) {
+			return fmt.Errorf("invalid Teleporter address %q", *teleporter)
+		}
+		action = admin.PauseTeleporterAddress(common.HexToAddress(*teleporter))
+		if command == "unpause" {
+			action = admin.UnpauseTeleporterAddress(common.HexToAddress(*teleporter))
+		}
+	case "transfer-ownership":
+		if !common.IsHexAddress(*newOwner) {
+			return fmt.Errorf("invalid new owner %q", *newOwner)
+		}
+		action = admin.TransferOwnership(common.HexToAddress(*newOwner))
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(os.Getenv(keyEnvVar), "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid owner key in %s: %w", keyEnvVar, err)
+	}
+
+	ctx := context.Background()
+	client, err := ethclient.DialContext(ctx, *rpcURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	for _, address := range addresses {
+		receipt, err := admin.Execute(ctx, client, key, address, action)
+		if errors.Is(err, admin.ErrNoChange) {
+			fmt.Printf("%s: skipped, %s\n", address, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s in %s\n", address, action, receipt.TxHash)
+	}
+	return nil
+}
+
+func audit(args []string) error {
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	rpcURL := flags.String("rpc", "", "RPC endpoint of the chain the token transferrers are deployed on")
+	dir := flags.String("dir", "", "directory of the audit log")
+	fromBlock := flags.Uint64("from-block", 0, "block to start from for token transferrers without entries")
+	confirmations := flags.Uint64("confirmations", 0, "number of blocks above the last block appended")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	addresses, err := parseAddresses(flags.Args())
+	if *rpcURL == "" || *dir == "" || err != nil {
+		return fmt.Errorf("usage: admin audit -rpc <url> -dir <dir> [-from-block <number>] " +
+			"[-confirmations <number>] <address>...")
+	}
+
+	ctx := context.Background()
+	client, err := ethclient.DialContext(ctx, *rpcURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	blockchainID, err := portfolio.BlockchainID(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	log := &admin.AuditLog{Dir: *dir}
+	encoder := json.NewEncoder(os.Stdout)
+	for _, address := range addresses {
+		entries, err := log.Sync(ctx, client, blockchainID, address, *fromBlock, *confirmations)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func parseAddresses(args []string) ([]common.Address, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no address")
+	}
+	addresses := make([]common.Address, 0, len(args))
+	for _, arg := range args {
+		if !common.IsHexAddress(arg) {
+			return nil, fmt.Errorf("invalid address %q", arg)
+		}
+		addresses = append(addresses, common.HexToAddress(arg))
+	}
+	return addresses, nil
+}
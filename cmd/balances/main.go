@@ -0,0 +1,105 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// balances reports where the tokens of an account are, across a TokenHome and every remote registered with it.
+//
+//	balances -home-rpc <url> -home <address> [-rpc <url>]... [-teleporter <address>] [-look-back <blocks>] <account>...
+//
+// The remotes are discovered from the RemoteRegistered events of the home. The balances of each account
+// are reported on the home chain and on each remote chain given by -rpc, both in the token of the chain
+// and in home token units, together with the transfers sent by or to the account that have not been
+// executed on their destination within the last -look-back blocks.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Address of the TeleporterMessenger deployed with Nick's method on every chain
+const defaultTeleporterAddress = "0x253b2784c75e510dD0fF1da844684a1aC0aa5fcf"
+
+// urls collects the values of a repeated flag.
+type urls []string
+
+func (u *urls) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *urls) Set(value string) error {
+	*u = append(*u, value)
+	return nil
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("balances", flag.ExitOnError)
+	homeRPCURL := flags.String("home-rpc", "", "RPC endpoint of the chain the TokenHome is deployed on")
+	home := flags.String("home", "", "address of the TokenHome")
+	var remoteRPCURLs urls
+	flags.Var(&remoteRPCURLs, "rpc", "RPC endpoint of a chain with remotes; may be repeated")
+	teleporter := flags.String("teleporter", defaultTeleporterAddress, "address of the TeleporterMessenger")
+	lookBackBlocks := flags.Uint64("look-back", 10_000, "number of blocks searched for transfers in flight, or 0 for all")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *homeRPCURL == "" || !common.IsHexAddress(*home) || flags.NArg() == 0 {
+		return fmt.Errorf("usage: balances -home-rpc <url> -home <address> [-rpc <url>]... <account>...")
+	}
+	if !common.IsHexAddress(*teleporter) {
+		return fmt.Errorf("invalid Teleporter address %q", *teleporter)
+	}
+	var accounts []common.Address
+	for _, arg := range flags.Args() {
+		if !common.IsHexAddress(arg) {
+			return fmt.Errorf("invalid address %q", arg)
+		}
+		accounts = append(accounts, common.HexToAddress(arg))
+	}
+
+	ctx := context.Background()
+	config := portfolio.Config{
+		HomeAddress:       common.HexToAddress(*home),
+		Chains:            make(map[ids.ID]portfolio.Backend),
+		TeleporterAddress: common.HexToAddress(*teleporter),
+		LookBackBlocks:    *lookBackBlocks,
+	}
+	for i, url := range append([]string{*homeRPCURL}, remoteRPCURLs...) {
+		client, err := ethclient.Dial(url)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		blockchainID, err := portfolio.BlockchainID(ctx, client)
+		if err != nil {
+			return fmt.Errorf("%s: %w", url, err)
+		}
+		if i == 0 {
+			config.HomeBlockchainID = blockchainID
+		}
+		config.Chains[blockchainID] = client
+	}
+
+	for _, account := range accounts {
+		p, err := portfolio.Get(ctx, config, account)
+		if err != nil {
+			return err
+		}
+		fmt.Println(p)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// bytecode-verifier checks whether a deployment of token transferrer contracts matches an audited release.
+//
+//	bytecode-verifier verify -home-rpc <url> -home <address> [-rpc <url>]...
+//
+// verify reports the audited or unaudited status of the TokenHome and of every remote registered with it,
+// as discovered from the RemoteRegistered events of the home. Remotes are verified on the chains given by
+// -rpc. Proxies are reported with the status of their implementation, read from their EIP-1967 slot.
+// The exit status is non-zero if any contract is unaudited, or on a chain without -rpc.
+//
+// The audited releases are those of the contract artifact registry generated with -audited, see
+// cmd/contract-artifacts.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	auditedbytecode "github.com/ava-labs/avalanche-interchain-token-transfer/utils/audited-bytecode"
+	contractartifacts "github.com/ava-labs/avalanche-interchain-token-transfer/utils/contract-artifacts"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var errUnaudited = errors.New("found unaudited contracts")
+
+// urls collects the values of a repeated flag.
+type urls []string
+
+func (u *urls) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *urls) Set(value string) error {
+	*u = append(*u, value)
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: bytecode-verifier verify [flags]")
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "verify":
+		err = verify(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func verify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	homeRPCURL := flags.String("home-rpc", "", "RPC endpoint of the chain the TokenHome is deployed on")
+	home := flags.String("home", "", "address of the TokenHome")
+	var remoteRPCURLs urls
+	flags.Var(&remoteRPCURLs, "rpc", "RPC endpoint of a chain with remotes; may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *homeRPCURL == "" || !common.IsHexAddress(*home) {
+		return fmt.Errorf("usage: bytecode-verifier verify -home-rpc <url> -home <address> [-rpc <url>]...")
+	}
+
+	registry, err := contractartifacts.Load()
+	if err != nil {
+		return err
+	}
+	releases := registry.AuditedBytecode()
+	if len(releases) == 0 {
+		return fmt.Errorf("no audited release in the contract artifact registry")
+	}
+
+	ctx := context.Background()
+	deployment := auditedbytecode.Deployment{
+		HomeAddress: common.HexToAddress(*home),
+		Chains:      make(map[ids.ID]portfolio.Backend),
+	}
+	for i, url := range append([]string{*homeRPCURL}, remoteRPCURLs...) {
+		client, err := ethclient.Dial(url)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		blockchainID, err := portfolio.BlockchainID(ctx, client)
+		if err != nil {
+			return fmt.Errorf("%s: %w", url, err)
+		}
+		if i == 0 {
+			deployment.HomeBlockchainID = blockchainID
+		}
+		deployment.Chains[blockchainID] = client
+	}
+
+	results, err := auditedbytecode.VerifyHome(ctx, deployment, releases)
+	if err != nil {
+		return err
+	}
+
+	unaudited := 0
+	for _, result := range results {
+		description := fmt.Sprintf("%s on %s", result.Address.Hex(), result.BlockchainID)
+		if result.Unreachable {
+			unaudited++
+			fmt.Printf("%s: NOT VERIFIED, no -rpc for its chain\n", description)
+			continue
+		}
+		description += " " + string(result.Kind)
+		if result.Implementation != (common.Address{}) {
+			description += fmt.Sprintf(" (implementation %s)", result.Implementation.Hex())
+		}
+		if !result.Audited() {
+			unaudited++
+			fmt.Printf("%s: UNAUDITED\n", description)
+			continue
+		}
+		matches := make([]string, 0, len(result.Matches))
+		for _, match := range result.Matches {
+			matches = append(matches, match.String())
+		}
+		fmt.Printf("%s: audited, matches %s\n", description, strings.Join(matches, ", "))
+	}
+	if unaudited != 0 {
+		return fmt.Errorf("%w: %d of %d", errUnaudited, unaudited, len(results))
+	}
+	return nil
+}
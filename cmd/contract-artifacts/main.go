@@ -0,0 +1,214 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// contract-artifacts generates the contract artifacts of a release, and looks up deployed contracts in them.
+//
+//	contract-artifacts generate -forge-out <dir> -version <version> -commit <commit> [-audited] -o <file>
+//	contract-artifacts generate -bindings -storage-layouts <layouts> -version <version> -commit <commit> -o <file>
+//	contract-artifacts lookup -rpc <url> <address>...
+//
+// generate writes the artifact file of a release from the forge build output at that release.
+// The file is committed to utils/contract-artifacts/releases to be embedded in the registry.
+// Releases listed in audits/README.md are generated with -audited, so that the bytecode verifier
+// checks deployments against them. When the forge build of a release is not available, -bindings builds the
+// file from the Go bindings of the working tree instead, with the storage layouts read from -storage-layouts.
+//
+// lookup reports the contracts of the embedded releases whose runtime bytecode matches the code at each
+// address on the chain at -rpc. Proxies are reported along with the contracts their implementation matches.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	proxyadmin "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/ProxyAdmin"
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	erc20tokenhomeupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHomeUpgradeable"
+	nativetokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHome"
+	nativetokenhomeupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHomeUpgradeable"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	erc20tokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemoteUpgradeable"
+	nativetokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemote"
+	nativetokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemoteUpgradeable"
+	transparentupgradeableproxy "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TransparentUpgradeableProxy"
+	wrappednativetoken "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/WrappedNativeToken"
+	contractartifacts "github.com/ava-labs/avalanche-interchain-token-transfer/utils/contract-artifacts"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	storagelayout "github.com/ava-labs/avalanche-interchain-token-transfer/utils/storage-layout"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: contract-artifacts <generate|lookup> [flags]")
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "generate":
+		err = generate(os.Args[2:])
+	case "lookup":
+		err = lookup(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func generate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	forgeOut := flags.String("forge-out", "", "forge output directory of the contracts built at the release")
+	version := flags.String("version", "", "release version")
+	commit := flags.String("commit", "", "commit of the release")
+	audited := flags.Bool("audited", false, "whether the release is audited")
+	output := flags.String("o", "", "path of the release file to write")
+	fromBindings := flags.Bool("bindings", false, "build the release from the Go bindings instead of -forge-out")
+	layoutsPath := flags.String("storage-layouts", "", "JSON file of the storage layouts of the release, with -bindings")
+	solcVersion := flags.String("solc-version", "0.8.25", "solc version the bindings were built with, with -bindings")
+	evmVersion := flags.String("evm-version", "shanghai", "EVM version the bindings were built for, with -bindings")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *fromBindings == (*forgeOut != "") {
+		return fmt.Errorf("exactly one of -forge-out and -bindings is required")
+	}
+	if *version == "" || *commit == "" || *output == "" {
+		return fmt.Errorf("-version, -commit and -o are required")
+	}
+
+	var release contractartifacts.Release
+	var err error
+	if *fromBindings {
+		release, err = releaseFromBindings(*version, *commit, *solcVersion, *evmVersion, *layoutsPath)
+	} else {
+		release, err = contractartifacts.ReleaseFromForgeArtifacts(*forgeOut, *version, *commit)
+	}
+	if err != nil {
+		return err
+	}
+	if len(release.Contracts) == 0 {
+		return fmt.Errorf("no contracts found in %s", *forgeOut)
+	}
+	release.Audited = *audited
+	return contractartifacts.WriteRelease(*output, release)
+}
+
+// releaseFromBindings builds the release from the Go bindings of the contracts that a forge build of the release
+// would include, and the storage layouts in the file at layoutsPath.
+func releaseFromBindings(
+	version string,
+	commit string,
+	solcVersion string,
+	evmVersion string,
+	layoutsPath string,
+) (contractartifacts.Release, error) {
+	if layoutsPath == "" {
+		return contractartifacts.Release{}, fmt.Errorf("-storage-layouts is required with -bindings")
+	}
+	data, err := os.ReadFile(layoutsPath)
+	if err != nil {
+		return contractartifacts.Release{}, err
+	}
+	var layouts storagelayout.Layouts
+	if err := json.Unmarshal(data, &layouts); err != nil {
+		return contractartifacts.Release{}, fmt.Errorf("failed to parse %s: %w", layoutsPath, err)
+	}
+
+	bindings := map[string]*bind.MetaData{
+		"ERC20TokenHome":               erc20tokenhome.ERC20TokenHomeMetaData,
+		"ERC20TokenHomeUpgradeable":    erc20tokenhomeupgradeable.ERC20TokenHomeUpgradeableMetaData,
+		"NativeTokenHome":              nativetokenhome.NativeTokenHomeMetaData,
+		"NativeTokenHomeUpgradeable":   nativetokenhomeupgradeable.NativeTokenHomeUpgradeableMetaData,
+		"ERC20TokenRemote":             erc20tokenremote.ERC20TokenRemoteMetaData,
+		"ERC20TokenRemoteUpgradeable":  erc20tokenremoteupgradeable.ERC20TokenRemoteUpgradeableMetaData,
+		"NativeTokenRemote":            nativetokenremote.NativeTokenRemoteMetaData,
+		"NativeTokenRemoteUpgradeable": nativetokenremoteupgradeable.NativeTokenRemoteUpgradeableMetaData,
+		"WrappedNativeToken":           wrappednativetoken.WrappedNativeTokenMetaData,
+		"TransparentUpgradeableProxy":  transparentupgradeableproxy.TransparentUpgradeableProxyMetaData,
+		"ProxyAdmin":                   proxyadmin.ProxyAdminMetaData,
+	}
+	compiler := contractartifacts.Compiler{
+		Version:      solcVersion,
+		EVMVersion:   evmVersion,
+		BytecodeHash: "none",
+	}
+	return contractartifacts.ReleaseFromBindings(version, commit, compiler, bindings, layouts)
+}
+
+func lookup(args []string) error {
+	flags := flag.NewFlagSet("lookup", flag.ExitOnError)
+	rpcURL := flags.String("rpc", "", "RPC endpoint of the chain the contracts are deployed on")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *rpcURL == "" || flags.NArg() == 0 {
+		return fmt.Errorf("usage: contract-artifacts lookup -rpc <url> <address>...")
+	}
+	var addresses []common.Address
+	for _, arg := range flags.Args() {
+		if !common.IsHexAddress(arg) {
+			return fmt.Errorf("invalid address %q", arg)
+		}
+		addresses = append(addresses, common.HexToAddress(arg))
+	}
+
+	registry, err := contractartifacts.Load()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	client, err := ethclient.DialContext(ctx, *rpcURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// describe returns the artifacts matching the code at the address
+	describe := func(address common.Address) (string, error) {
+		code, err := client.CodeAt(ctx, address, nil)
+		if err != nil {
+			return "", err
+		}
+		if len(code) == 0 {
+			return "no code", nil
+		}
+		artifacts := registry.ByCode(code)
+		if len(artifacts) == 0 {
+			return "unknown", nil
+		}
+		names := make([]string, 0, len(artifacts))
+		for _, artifact := range artifacts {
+			names = append(names, artifact.String())
+		}
+		return strings.Join(names, ", "), nil
+	}
+	for _, address := range addresses {
+		description, err := describe(address)
+		if err != nil {
+			return err
+		}
+		info, err := inspect.Inspect(ctx, client, address)
+		if err != nil {
+			return err
+		}
+		if info.IsProxy {
+			implementation, err := describe(info.Implementation)
+			if err != nil {
+				return err
+			}
+			description += fmt.Sprintf(" (implementation %s: %s)", info.Implementation.Hex(), implementation)
+		}
+		fmt.Printf("%s: %s\n", address.Hex(), description)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// gateway serves an HTTP API to quote, build, submit and track the transfers of a TokenHome and its remotes,
+// until interrupted.
+//
+//	gateway -home-rpc <url> -home <address> [-rpc <url>]... [-listen <address>] [-stream]
+//
+// The remotes are discovered from the RemoteRegistered events of the home. Transfers can be built from the home
+// chain and from each remote chain given by -rpc, and are tracked within the last -look-back blocks. The API
+// is described by the OpenAPI document served at /openapi.yaml. If the GATEWAY_SIGNER_KEY environment variable
+// holds a hex encoded private key, transfers can also be submitted, and are signed and sent with that key.
+//
+// With -stream, the events of the transfers are also pushed to the websocket clients of /transfers/stream,
+// once they have -confirmations blocks on top of them. The RPC endpoints must then be websocket endpoints.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/gateway"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/subscription"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// Address of the TeleporterMessenger deployed with Nick's method on every chain
+	defaultTeleporterAddress = "0x253b2784c75e510dD0fF1da844684a1aC0aa5fcf"
+	// signerKeyEnvVar holds the key of the managed signer, so that it is not passed on the command line.
+	signerKeyEnvVar = "GATEWAY_SIGNER_KEY"
+	// Time allowed for the requests in progress to complete once interrupted
+	shutdownTimeout = 30 * time.Second
+)
+
+// urls collects the values of a repeated flag.
+type urls []string
+
+func (u *urls) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *urls) Set(value string) error {
+	*u = append(*u, value)
+	return nil
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("gateway", flag.ExitOnError)
+	homeRPCURL := flags.String("home-rpc", "", "RPC endpoint of the chain the TokenHome is deployed on")
+	home := flags.String("home", "", "address of the TokenHome")
+	var remoteRPCURLs urls
+	flags.Var(&remoteRPCURLs, "rpc", "RPC endpoint of a chain with remotes; may be repeated")
+	teleporter := flags.String("teleporter", defaultTeleporterAddress, "address of the TeleporterMessenger")
+	lookBackBlocks := flags.Uint64("look-back", 10_000, "number of blocks searched for transfers, or 0 for all")
+	listen := flags.String("listen", "127.0.0.1:8080", "address to serve the API on")
+	stream := flags.Bool("stream", false, "stream the events of transfers over a websocket")
+	confirmations := flags.Uint64("confirmations", 0, "number of blocks on top of a streamed event")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *homeRPCURL == "" || !common.IsHexAddress(*home) {
+		return fmt.Errorf("usage: gateway -home-rpc <url> -home <address> [-rpc <url>]... [-listen <address>] [-stream]")
+	}
+	if !common.IsHexAddress(*teleporter) {
+		return fmt.Errorf("invalid Teleporter address %q", *teleporter)
+	}
+
+	log.SetDefault(log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stderr, log.LevelInfo, false)))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config := gateway.Config{
+		HomeAddress:       common.HexToAddress(*home),
+		Chains:            make(map[ids.ID]gateway.Backend),
+		TeleporterAddress: common.HexToAddress(*teleporter),
+		LookBackBlocks:    *lookBackBlocks,
+		Confirmations:     *confirmations,
+	}
+	if key := os.Getenv(signerKeyEnvVar); key != "" {
+		signerKey, err := crypto.HexToECDSA(strings.TrimPrefix(key, "0x"))
+		if err != nil {
+			return fmt.Errorf("invalid signer key: %w", err)
+		}
+		config.SignerKey = signerKey
+	}
+	rpcURLs := make(map[ids.ID]string)
+	for i, url := range append([]string{*homeRPCURL}, remoteRPCURLs...) {
+		client, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		blockchainID, err := portfolio.BlockchainID(ctx, client)
+		if err != nil {
+			return fmt.Errorf("%s: %w", url, err)
+		}
+		if i == 0 {
+			config.HomeBlockchainID = blockchainID
+		}
+		config.Chains[blockchainID] = client
+		rpcURLs[blockchainID] = url
+	}
+	if *stream {
+		config.Dial = func(ctx context.Context, blockchainID ids.ID) (subscription.Backend, error) {
+			return ethclient.DialContext(ctx, rpcURLs[blockchainID])
+		}
+	}
+
+	s, err := gateway.New(config)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	streamErr := make(chan error, 1)
+	if *stream {
+		go func() {
+			streamErr <- s.RunStream(ctx)
+		}()
+	}
+	log.Info("Serving gateway", "address", *listen, "home", config.HomeAddress, "signer", s.SignerAddress())
+
+	select {
+	case err := <-serveErr:
+		return err
+	case err := <-streamErr:
+		if ctx.Err() == nil {
+			_ = server.Close()
+			return err
+		}
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// guardian pauses the delivery of Teleporter messages to a TokenHome and its remotes when their reserves
+// break an invariant or an anomaly rule, until interrupted.
+//
+//	guardian -home-rpc <url> -home <address> [-rpc <url>]... [-max-mint <amount>] [-max-outflow <amount>]
+//	    [-interval <duration>] [-incidents <file>]
+//
+// Every -interval, the balance of the home, the balances it transferred to its remotes and the supply of
+// the remotes on the chains given by -rpc are checked. The home must cover the balances transferred, and the
+// supply of each remote must be backed. With -max-mint, the supply of a remote must not increase by more than
+// that value in home units between two checks, and with -max-outflow, the balance of the home must not
+// decrease by more than that amount. On any violation, the Teleporter address is paused on the home and on
+// each remote that broke a rule, and the incident report is appended as a JSON line to -incidents, or written
+// to the standard output.
+//
+// The pause transactions are sent with the hex encoded private key held by the GUARDIAN_KEY environment
+// variable, which must own the token transferrers.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/guardian"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// Address of the TeleporterMessenger deployed with Nick's method on every chain
+	defaultTeleporterAddress = "0x253b2784c75e510dD0fF1da844684a1aC0aa5fcf"
+	// keyEnvVar holds the key of the guardian, so that it is not passed on the command line.
+	keyEnvVar = "GUARDIAN_KEY"
+)
+
+// urls collects the values of a repeated flag.
+type urls []string
+
+func (u *urls) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *urls) Set(value string) error {
+	*u = append(*u, value)
+	return nil
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("guardian", flag.ExitOnError)
+	homeRPCURL := flags.String("home-rpc", "", "RPC endpoint of the chain the TokenHome is deployed on")
+	home := flags.String("home", "", "address of the TokenHome")
+	var remoteRPCURLs urls
+	flags.Var(&remoteRPCURLs, "rpc", "RPC endpoint of a chain with remotes; may be repeated")
+	teleporter := flags.String("teleporter", defaultTeleporterAddress, "address of the TeleporterMessenger to pause")
+	maxMint := flags.String("max-mint", "", "largest increase of the supply of a remote between checks, in home units")
+	maxOutflow := flags.String("max-outflow", "", "largest decrease of the balance of the home between checks")
+	interval := flags.Duration("interval", time.Minute, "time between two checks")
+	incidentsFile := flags.String("incidents", "", "file to append incident reports to, instead of the standard output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *homeRPCURL == "" || !common.IsHexAddress(*home) || !common.IsHexAddress(*teleporter) {
+		return fmt.Errorf("usage: guardian -home-rpc <url> -home <address> [-rpc <url>]... [-max-mint <amount>] " +
+			"[-max-outflow <amount>] [-interval <duration>] [-incidents <file>]")
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(os.Getenv(keyEnvVar), "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid guardian key in %s: %w", keyEnvVar, err)
+	}
+	var rules guardian.Rules
+	if rules.MaxMint, err = parseAmount(*maxMint); err != nil {
+		return fmt.Errorf("invalid max mint: %w", err)
+	}
+	if rules.MaxOutflow, err = parseAmount(*maxOutflow); err != nil {
+		return fmt.Errorf("invalid max outflow: %w", err)
+	}
+
+	incidentsOutput := os.Stdout
+	if *incidentsFile != "" {
+		incidentsOutput, err = os.OpenFile(*incidentsFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		defer incidentsOutput.Close()
+	}
+
+	log.SetDefault(log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stderr, log.LevelInfo, false)))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config := guardian.Config{
+		HomeAddress:       common.HexToAddress(*home),
+		Chains:            make(map[ids.ID]guardian.Backend),
+		TeleporterAddress: common.HexToAddress(*teleporter),
+		Key:               key,
+		Rules:             rules,
+		Interval:          *interval,
+	}
+	for i, url := range append([]string{*homeRPCURL}, remoteRPCURLs...) {
+		client, err := ethclient.DialContext(ctx, url)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		blockchainID, err := portfolio.BlockchainID(ctx, client)
+		if err != nil {
+			return fmt.Errorf("%s: %w", url, err)
+		}
+		if i == 0 {
+			config.HomeBlockchainID = blockchainID
+		}
+		config.Chains[blockchainID] = client
+	}
+	g, err := guardian.New(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	incidents := make(chan guardian.Incident)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- g.Run(ctx, incidents)
+	}()
+	log.Info("Guarding token transferrers", "home", config.HomeAddress, "address", g.Address())
+	encoder := json.NewEncoder(incidentsOutput)
+	for {
+		select {
+		case incident := <-incidents:
+			if err := encoder.Encode(incident); err != nil {
+				log.Error("Failed to write incident report", "err", err)
+			}
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+// parseAmount parses an optional decimal amount of the smallest units of a token.
+func parseAmount(amount string) (*big.Int, error) {
+	if amount == "" {
+		return nil, nil
+	}
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+	return value, nil
+}
@@ -0,0 +1,177 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// relayer relays the Teleporter messages sent between token transferrers, until interrupted.
+//
+//	relayer -config <file>
+//
+// The JSON config file lists the chains to relay between, the token transferrers on each chain, and the
+// policy applied to the messages sent from each chain:
+//
+//	{
+//	  "teleporterContractAddress": "0x253b2784c75e510dD0fF1da844684a1aC0aa5fcf",
+//	  "confirmations": 0,
+//	  "chains": [
+//	    {
+//	      "name": "C-Chain",
+//	      "rpcURL": "http://127.0.0.1:9650/ext/bc/C/rpc",
+//	      "wsURL": "ws://127.0.0.1:9650/ext/bc/C/ws",
+//	      "nodeURI": "http://127.0.0.1:9650",
+//	      "transferrers": ["0x..."],
+//	      "policy": {"minFees": {"0x...": 1000000000000000000}, "minAmount": 1}
+//	    },
+//	    {
+//	      "name": "subnet A",
+//	      "subnetID": "...",
+//	      ...
+//	    }
+//	  ]
+//	}
+//
+// The subnet ID is omitted for the C-Chain. The hex encoded private key that pays for the deliveries and
+// receives the fees is read from the RELAYER_KEY environment variable, or else from the "key" field.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/relayer"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/subscription"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// keyEnvVar overrides the key of the config file, so that it does not have to be written to disk.
+const keyEnvVar = "RELAYER_KEY"
+
+type config struct {
+	TeleporterContractAddress common.Address `json:"teleporterContractAddress"`
+	Key                       string         `json:"key,omitempty"`
+	Confirmations             uint64         `json:"confirmations"`
+	// Time to wait for the signatures of a message to be aggregated, such as "30s"
+	SignatureTimeout string        `json:"signatureTimeout,omitempty"`
+	Chains           []chainConfig `json:"chains"`
+}
+
+type chainConfig struct {
+	Name string `json:"name"`
+	// Omitted for the C-Chain
+	SubnetID     ids.ID           `json:"subnetID"`
+	RPCURL       string           `json:"rpcURL"`
+	WSURL        string           `json:"wsURL"`
+	NodeURI      string           `json:"nodeURI"`
+	Transferrers []common.Address `json:"transferrers"`
+	Policy       relayer.Policy   `json:"policy"`
+	FromBlock    uint64           `json:"fromBlock,omitempty"`
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("relayer", flag.ExitOnError)
+	configFile := flags.String("config", "", "JSON config file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *configFile == "" {
+		return fmt.Errorf("usage: relayer -config <file>")
+	}
+	c, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	log.SetDefault(log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stderr, log.LevelInfo, false)))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	relayerConfig, err := c.relayerConfig(ctx)
+	if err != nil {
+		return err
+	}
+	r, err := relayer.New(ctx, relayerConfig)
+	if err != nil {
+		return err
+	}
+	log.Info("Relaying messages", "chains", len(relayerConfig.Chains), "address", r.Address())
+	return r.Run(ctx, nil)
+}
+
+func loadConfig(file string) (config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return config{}, err
+	}
+	var c config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return config{}, fmt.Errorf("failed to parse relayer config %s: %w", file, err)
+	}
+	if key := os.Getenv(keyEnvVar); key != "" {
+		c.Key = key
+	}
+	return c, nil
+}
+
+// relayerConfig connects to the RPC endpoints of the chains to complete the config of the relayer.
+func (c config) relayerConfig(ctx context.Context) (relayer.Config, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(c.Key, "0x"))
+	if err != nil {
+		return relayer.Config{}, fmt.Errorf("invalid relayer key: %w", err)
+	}
+	var signatureTimeout time.Duration
+	if c.SignatureTimeout != "" {
+		signatureTimeout, err = time.ParseDuration(c.SignatureTimeout)
+		if err != nil {
+			return relayer.Config{}, fmt.Errorf("invalid signature timeout: %w", err)
+		}
+	}
+
+	relayerConfig := relayer.Config{
+		TeleporterContractAddress: c.TeleporterContractAddress,
+		Key:                       key,
+		Confirmations:             c.Confirmations,
+		SignatureTimeout:          signatureTimeout,
+	}
+	for _, chain := range c.Chains {
+		client, err := ethclient.DialContext(ctx, chain.RPCURL)
+		if err != nil {
+			return relayer.Config{}, fmt.Errorf("failed to connect to %s: %w", chain.Name, err)
+		}
+		blockchainID, err := portfolio.BlockchainID(ctx, client)
+		if err != nil {
+			return relayer.Config{}, fmt.Errorf("failed to get blockchain ID of %s: %w", chain.Name, err)
+		}
+		wsURL := chain.WSURL
+		relayerConfig.Chains = append(relayerConfig.Chains, relayer.Chain{
+			BlockchainID: blockchainID,
+			SubnetID:     chain.SubnetID,
+			Client:       client,
+			Dial: func(ctx context.Context) (subscription.Backend, error) {
+				return ethclient.DialContext(ctx, wsURL)
+			},
+			NodeURI:      chain.NodeURI,
+			Transferrers: chain.Transferrers,
+			Policy:       chain.Policy,
+			FromBlock:    chain.FromBlock,
+		})
+	}
+	return relayerConfig, nil
+}
@@ -0,0 +1,228 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// reserves attests the reserves of a TokenHome, and verifies such attestations.
+//
+//	reserves attest -home-rpc <url> -home <address> [-rpc <url>]... [-block <blockchainID>:<number>]... [-o <file>]
+//	reserves verify -attestation <file> -home-rpc <url> [-rpc <url>]... [-signer <address>]
+//
+// attest reads the balance of the home, the balance transferred to each of its remotes, and the supply of the
+// remotes on the chains given by -rpc, at the block given by -block for each chain, or at its latest block.
+// The attestation is signed with the hex encoded private key held by the RESERVES_SIGNER_KEY environment
+// variable, and written as JSON to -o or to the standard output.
+//
+// verify checks the signature of an attestation, and reads the same values again at the same blocks, which
+// requires archive RPC endpoints. The exit status is non-zero if the attestation is not signed by -signer, or
+// does not match the chains.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/reserves"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// signerKeyEnvVar holds the key attestations are signed with, so that it is not passed on the command line.
+const signerKeyEnvVar = "RESERVES_SIGNER_KEY"
+
+var errMismatch = errors.New("attestation does not match the chains")
+
+// urls collects the values of a repeated flag.
+type urls []string
+
+func (u *urls) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *urls) Set(value string) error {
+	*u = append(*u, value)
+	return nil
+}
+
+// blocks collects the blocks to pin of the repeated -block flag, by blockchain ID.
+type blocks map[ids.ID]uint64
+
+func (b blocks) String() string {
+	var pinned []string
+	for blockchainID, number := range b {
+		pinned = append(pinned, fmt.Sprintf("%s:%d", blockchainID, number))
+	}
+	return strings.Join(pinned, ",")
+}
+
+func (b blocks) Set(value string) error {
+	blockchainID, number, ok := strings.Cut(value, ":")
+	if !ok {
+		return fmt.Errorf("expected <blockchainID>:<number>, got %q", value)
+	}
+	id, err := ids.FromString(blockchainID)
+	if err != nil {
+		return fmt.Errorf("invalid blockchain ID %q: %w", blockchainID, err)
+	}
+	b[id], err = strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid block number %q: %w", number, err)
+	}
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: reserves <attest|verify> [flags]")
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "attest":
+		err = attest(os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func attest(args []string) error {
+	flags := flag.NewFlagSet("attest", flag.ExitOnError)
+	homeRPCURL := flags.String("home-rpc", "", "RPC endpoint of the chain the TokenHome is deployed on")
+	home := flags.String("home", "", "address of the TokenHome")
+	var remoteRPCURLs urls
+	flags.Var(&remoteRPCURLs, "rpc", "RPC endpoint of a chain with remotes; may be repeated")
+	pinned := make(blocks)
+	flags.Var(pinned, "block", "block to read a chain at, as <blockchainID>:<number>; may be repeated")
+	output := flags.String("o", "", "file to write the attestation to, instead of the standard output")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *homeRPCURL == "" || !common.IsHexAddress(*home) {
+		return fmt.Errorf("usage: reserves attest -home-rpc <url> -home <address> [-rpc <url>]... " +
+			"[-block <blockchainID>:<number>]... [-o <file>]")
+	}
+	key := os.Getenv(signerKeyEnvVar)
+	if key == "" {
+		return fmt.Errorf("missing signer key in %s", signerKeyEnvVar)
+	}
+	signerKey, err := crypto.HexToECDSA(strings.TrimPrefix(key, "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid signer key: %w", err)
+	}
+
+	ctx := context.Background()
+	chains, homeBlockchainID, err := dial(ctx, append([]string{*homeRPCURL}, remoteRPCURLs...))
+	if err != nil {
+		return err
+	}
+	a, err := reserves.Gather(ctx, reserves.Config{
+		HomeBlockchainID: homeBlockchainID,
+		HomeAddress:      common.HexToAddress(*home),
+		Chains:           chains,
+		BlockNumbers:     pinned,
+	})
+	if err != nil {
+		return err
+	}
+	signed, err := reserves.Sign(a, signerKey)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		return err
+	}
+	encoded = append(encoded, '\n')
+	if *output == "" {
+		_, err = os.Stdout.Write(encoded)
+		return err
+	}
+	return os.WriteFile(*output, encoded, 0o644)
+}
+
+func verify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	file := flags.String("attestation", "", "file of the signed attestation")
+	homeRPCURL := flags.String("home-rpc", "", "archive RPC endpoint of the chain the TokenHome is deployed on")
+	var remoteRPCURLs urls
+	flags.Var(&remoteRPCURLs, "rpc", "archive RPC endpoint of a chain with remotes; may be repeated")
+	signer := flags.String("signer", "", "address the attestation must be signed by")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" || *homeRPCURL == "" || (*signer != "" && !common.IsHexAddress(*signer)) {
+		return fmt.Errorf("usage: reserves verify -attestation <file> -home-rpc <url> [-rpc <url>]... " +
+			"[-signer <address>]")
+	}
+
+	encoded, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	var signed reserves.SignedAttestation
+	if err := json.Unmarshal(encoded, &signed); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", *file, err)
+	}
+	a, err := signed.Open()
+	if err != nil {
+		return err
+	}
+	if *signer != "" && signed.Signer != common.HexToAddress(*signer) {
+		return fmt.Errorf("%w: signed by %s, not %s", reserves.ErrInvalidSignature, signed.Signer, *signer)
+	}
+
+	ctx := context.Background()
+	chains, _, err := dial(ctx, append([]string{*homeRPCURL}, remoteRPCURLs...))
+	if err != nil {
+		return err
+	}
+	differences, err := reserves.Verify(ctx, a, chains)
+	if err != nil {
+		return err
+	}
+	for _, difference := range differences {
+		fmt.Println(difference)
+	}
+	if len(differences) != 0 {
+		return errMismatch
+	}
+	fmt.Printf("attestation signed by %s matches the chains; covered: %t, surplus: %s\n",
+		signed.Signer, a.Covered, a.Surplus)
+	return nil
+}
+
+// dial connects to each RPC endpoint, and returns the clients by blockchain ID, along with the blockchain ID
+// of the first endpoint. The clients are left open until the command exits.
+func dial(ctx context.Context, rpcURLs []string) (map[ids.ID]reserves.Backend, ids.ID, error) {
+	chains := make(map[ids.ID]reserves.Backend)
+	var first ids.ID
+	for i, url := range rpcURLs {
+		client, err := ethclient.Dial(url)
+		if err != nil {
+			return nil, ids.Empty, err
+		}
+		blockchainID, err := portfolio.BlockchainID(ctx, client)
+		if err != nil {
+			return nil, ids.Empty, fmt.Errorf("%s: %w", url, err)
+		}
+		if i == 0 {
+			first = blockchainID
+		}
+		chains[blockchainID] = client
+	}
+	return chains, first, nil
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// storage-layout-checker compares the ERC-7201 namespaced storage layouts of two versions of the
+// upgradeable contracts, and exits with a non-zero status if the new version is not compatible.
+//
+// The new version is a forge output directory (contracts/out). The old version is either a release of the
+// contract artifact registry, by default the latest one, or another forge output directory.
+//
+//	storage-layout-checker -new ./contracts/out
+//	storage-layout-checker -old v1.0.0 -new ./contracts/out
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	contractartifacts "github.com/ava-labs/avalanche-interchain-token-transfer/utils/contract-artifacts"
+	storagelayout "github.com/ava-labs/avalanche-interchain-token-transfer/utils/storage-layout"
+)
+
+func main() {
+	oldVersion := flag.String(
+		"old",
+		"",
+		"release version or forge output directory of the currently deployed version, defaults to the latest release",
+	)
+	newPath := flag.String("new", "", "forge output directory of the version to upgrade to")
+	contracts := flag.String(
+		"contracts",
+		strings.Join(storagelayout.UpgradeableContracts, ","),
+		"comma separated list of contracts to check",
+	)
+	flag.Parse()
+
+	if err := run(*oldVersion, *newPath, strings.Split(*contracts, ",")); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(oldVersion string, newPath string, contracts []string) error {
+	if newPath == "" {
+		return fmt.Errorf("-new is required")
+	}
+	newLayouts, err := storagelayout.Load(newPath, contracts)
+	if err != nil {
+		return err
+	}
+	oldLayouts, err := loadOld(oldVersion, contracts)
+	if err != nil {
+		return err
+	}
+
+	incompatibilities := storagelayout.Compare(oldLayouts, newLayouts)
+	for _, incompatibility := range incompatibilities {
+		fmt.Println(incompatibility)
+	}
+	if len(incompatibilities) != 0 {
+		return fmt.Errorf("found %d storage layout incompatibilities", len(incompatibilities))
+	}
+	fmt.Printf("Storage layouts of %d contracts are compatible\n", len(oldLayouts))
+	return nil
+}
+
+// loadOld returns the layouts of the contracts from the forge output directory at oldVersion, or else from
+// the release of the registry with that version, or the latest one if oldVersion is empty. Contracts without
+// a layout in the release are an error, since their upgrades could not be checked.
+func loadOld(oldVersion string, contracts []string) (storagelayout.Layouts, error) {
+	if info, err := os.Stat(oldVersion); err == nil && info.IsDir() {
+		return storagelayout.Load(oldVersion, contracts)
+	}
+
+	registry, err := contractartifacts.Load()
+	if err != nil {
+		return nil, err
+	}
+	var release contractartifacts.Release
+	if oldVersion == "" {
+		release, err = registry.LatestRelease()
+	} else {
+		release, err = registry.Release(oldVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	releaseLayouts := release.StorageLayouts()
+	layouts := make(storagelayout.Layouts, len(contracts))
+	var missing []string
+	for _, name := range contracts {
+		layout, ok := releaseLayouts[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		layouts[name] = layout
+	}
+	if len(missing) != 0 {
+		return nil, fmt.Errorf("no storage layout of %s in release %s", strings.Join(missing, ", "), release.Version)
+	}
+	return layouts, nil
+}
@@ -0,0 +1,98 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// topology prints the token transferrers of a TokenHome and the routes between them.
+//
+//	topology -home-rpc <url> -home <address> [-rpc <url>]... [-format json|dot]
+//
+// The remotes are discovered from the RemoteRegistered events of the home, and described by the settings
+// the home registered them with. The remotes on the chains given by -rpc are also inspected, and checked
+// against those settings. The topology is printed as JSON, or as a Graphviz digraph with -format dot:
+//
+//	topology -home-rpc <url> -home <address> -rpc <url> -format dot | dot -Tsvg > topology.svg
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/topology"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// urls collects the values of a repeated flag.
+type urls []string
+
+func (u *urls) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *urls) Set(value string) error {
+	*u = append(*u, value)
+	return nil
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("topology", flag.ExitOnError)
+	homeRPCURL := flags.String("home-rpc", "", "RPC endpoint of the chain the TokenHome is deployed on")
+	home := flags.String("home", "", "address of the TokenHome")
+	var remoteRPCURLs urls
+	flags.Var(&remoteRPCURLs, "rpc", "RPC endpoint of a chain with remotes; may be repeated")
+	format := flags.String("format", "json", "output format: json or dot")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *homeRPCURL == "" || !common.IsHexAddress(*home) {
+		return fmt.Errorf("usage: topology -home-rpc <url> -home <address> [-rpc <url>]... [-format json|dot]")
+	}
+	if *format != "json" && *format != "dot" {
+		return fmt.Errorf("invalid format %q", *format)
+	}
+
+	ctx := context.Background()
+	config := topology.Config{
+		HomeAddress: common.HexToAddress(*home),
+		Chains:      make(map[ids.ID]portfolio.Backend),
+	}
+	for i, url := range append([]string{*homeRPCURL}, remoteRPCURLs...) {
+		client, err := ethclient.Dial(url)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		blockchainID, err := portfolio.BlockchainID(ctx, client)
+		if err != nil {
+			return fmt.Errorf("%s: %w", url, err)
+		}
+		if i == 0 {
+			config.HomeBlockchainID = blockchainID
+		}
+		config.Chains[blockchainID] = client
+	}
+
+	t, err := topology.Discover(ctx, config)
+	if err != nil {
+		return err
+	}
+	if *format == "dot" {
+		fmt.Print(t.DOT())
+		return nil
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(t)
+}
@@ -0,0 +1,194 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// webhooks notifies HTTP endpoints of the events of token transferrers, until interrupted.
+//
+//	webhooks -config <file> [-redeliver]
+//
+// The JSON config file lists the chains whose token transferrers are followed, and the subscriptions
+// notified of their events:
+//
+//	{
+//	  "confirmations": 1,
+//	  "deadLetterFile": "dead-letters.jsonl",
+//	  "chains": [
+//	    {
+//	      "name": "C-Chain",
+//	      "wsURL": "ws://127.0.0.1:9650/ext/bc/C/ws",
+//	      "transferrers": ["0x..."],
+//	      "fromBlock": 0
+//	    }
+//	  ],
+//	  "subscriptions": [
+//	    {
+//	      "name": "treasury",
+//	      "url": "https://example.com/hooks/treasury",
+//	      "secretEnv": "TREASURY_WEBHOOK_SECRET",
+//	      "filter": {"events": ["TokensSent"], "minAmount": 1000000000000000000000}
+//	    },
+//	    {
+//	      "name": "ops",
+//	      "url": "https://example.com/hooks/ops",
+//	      "secret": "...",
+//	      "filter": {"events": ["CollateralAdded"], "fullyCollateralizedOnly": true}
+//	    }
+//	  ]
+//	}
+//
+// The secret of a subscription is read from the environment variable named by "secretEnv", or else
+// from the "secret" field. Notifications that cannot be delivered after "maxAttempts" attempts are
+// appended to the dead letter file. With -redeliver, the dead letters of the file are delivered again
+// instead, and those still failing are kept in the file.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/subscription"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/webhook"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+type config struct {
+	Confirmations uint64 `json:"confirmations"`
+	// Defaults to dead-letters.jsonl in the working directory
+	DeadLetterFile string `json:"deadLetterFile,omitempty"`
+	MaxAttempts    int    `json:"maxAttempts,omitempty"`
+	// Time waited before the first retry of a delivery, and at most between retries, such as "1s" and "5m"
+	InitialBackoff string               `json:"initialBackoff,omitempty"`
+	MaxBackoff     string               `json:"maxBackoff,omitempty"`
+	Chains         []chainConfig        `json:"chains"`
+	Subscriptions  []subscriptionConfig `json:"subscriptions"`
+}
+
+type chainConfig struct {
+	Name         string           `json:"name"`
+	WSURL        string           `json:"wsURL"`
+	Transferrers []common.Address `json:"transferrers"`
+	FromBlock    uint64           `json:"fromBlock,omitempty"`
+}
+
+type subscriptionConfig struct {
+	webhook.Subscription
+	SecretEnv string `json:"secretEnv,omitempty"`
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("webhooks", flag.ExitOnError)
+	configFile := flags.String("config", "", "JSON config file")
+	redeliver := flags.Bool("redeliver", false, "deliver the dead letters again and exit")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *configFile == "" {
+		return fmt.Errorf("usage: webhooks -config <file> [-redeliver]")
+	}
+	c, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	log.SetDefault(log.NewLogger(log.NewTerminalHandlerWithLevel(os.Stderr, log.LevelInfo, false)))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	webhookConfig, err := c.webhookConfig(ctx)
+	if err != nil {
+		return err
+	}
+	d, err := webhook.New(webhookConfig)
+	if err != nil {
+		return err
+	}
+	if *redeliver {
+		delivered, err := d.Redeliver(ctx)
+		log.Info("Redelivered dead letters", "delivered", delivered)
+		return err
+	}
+	log.Info("Notifying subscriptions", "chains", len(webhookConfig.Chains), "subscriptions", len(c.Subscriptions))
+	return d.Run(ctx)
+}
+
+func loadConfig(file string) (config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return config{}, err
+	}
+	var c config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return config{}, fmt.Errorf("failed to parse webhooks config %s: %w", file, err)
+	}
+	for i, s := range c.Subscriptions {
+		if s.SecretEnv != "" {
+			c.Subscriptions[i].Secret = os.Getenv(s.SecretEnv)
+		}
+	}
+	if c.DeadLetterFile == "" {
+		c.DeadLetterFile = "dead-letters.jsonl"
+	}
+	return c, nil
+}
+
+// webhookConfig connects to the chains to get their blockchain IDs and complete the config of the dispatcher.
+func (c config) webhookConfig(ctx context.Context) (webhook.Config, error) {
+	webhookConfig := webhook.Config{
+		Confirmations: c.Confirmations,
+		MaxAttempts:   c.MaxAttempts,
+		DeadLetters:   &webhook.FileDeadLetters{Path: c.DeadLetterFile},
+	}
+	var err error
+	if c.InitialBackoff != "" {
+		webhookConfig.InitialBackoff, err = time.ParseDuration(c.InitialBackoff)
+		if err != nil {
+			return webhook.Config{}, fmt.Errorf("invalid initial backoff: %w", err)
+		}
+	}
+	if c.MaxBackoff != "" {
+		webhookConfig.MaxBackoff, err = time.ParseDuration(c.MaxBackoff)
+		if err != nil {
+			return webhook.Config{}, fmt.Errorf("invalid max backoff: %w", err)
+		}
+	}
+	for _, s := range c.Subscriptions {
+		webhookConfig.Subscriptions = append(webhookConfig.Subscriptions, s.Subscription)
+	}
+	for _, chain := range c.Chains {
+		client, err := ethclient.DialContext(ctx, chain.WSURL)
+		if err != nil {
+			return webhook.Config{}, fmt.Errorf("failed to connect to %s: %w", chain.Name, err)
+		}
+		blockchainID, err := portfolio.BlockchainID(ctx, client)
+		client.Close()
+		if err != nil {
+			return webhook.Config{}, fmt.Errorf("failed to get blockchain ID of %s: %w", chain.Name, err)
+		}
+		wsURL := chain.WSURL
+		webhookConfig.Chains = append(webhookConfig.Chains, webhook.Chain{
+			BlockchainID: blockchainID,
+			Dial: func(ctx context.Context) (subscription.Backend, error) {
+				return ethclient.DialContext(ctx, wsURL)
+			},
+			Transferrers: chain.Transferrers,
+			FromBlock:    chain.FromBlock,
+		})
+	}
+	return webhookConfig, nil
+}
@@ -0,0 +1,61 @@
+// (c) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// SPDX-License-Identifier: Ecosystem
+
+pragma solidity 0.8.25;
+
+import {TokenScalingUtils} from "../utils/TokenScalingUtils.sol";
+
+/**
+ * THIS IS AN EXAMPLE CONTRACT THAT USES UN-AUDITED CODE.
+ * DO NOT USE THIS CODE IN PRODUCTION.
+ */
+
+/**
+ * @notice Exposes the internal functions of {TokenScalingUtils}, so that they can be
+ * called directly in tests of off-chain implementations of token scaling.
+ */
+contract TokenScalingUtilsHarness {
+    function applyTokenScale(
+        uint256 tokenMultiplier,
+        bool multiplyOnRemote,
+        uint256 homeTokenAmount
+    ) external pure returns (uint256) {
+        return TokenScalingUtils.applyTokenScale(tokenMultiplier, multiplyOnRemote, homeTokenAmount);
+    }
+
+    function removeTokenScale(
+        uint256 tokenMultiplier,
+        bool multiplyOnRemote,
+        uint256 remoteTokenAmount
+    ) external pure returns (uint256) {
+        return
+            TokenScalingUtils.removeTokenScale(tokenMultiplier, multiplyOnRemote, remoteTokenAmount);
+    }
+
+    function deriveTokenMultiplierValues(
+        uint8 homeTokenDecimals,
+        uint8 remoteTokenDecimals
+    ) external pure returns (uint256, bool) {
+        return TokenScalingUtils.deriveTokenMultiplierValues(homeTokenDecimals, remoteTokenDecimals);
+    }
+
+    /**
+     * @notice Calculates the collateral needed for a TokenRemote instance, in the same way as
+     * {TokenHome-_registerRemote}.
+     */
+    function calculateCollateralNeeded(
+        uint256 tokenMultiplier,
+        bool multiplyOnRemote,
+        uint256 initialReserveImbalance
+    ) external pure returns (uint256) {
+        uint256 collateralNeeded = TokenScalingUtils.removeTokenScale(
+            tokenMultiplier, multiplyOnRemote, initialReserveImbalance
+        );
+        if (multiplyOnRemote && initialReserveImbalance % tokenMultiplier != 0) {
+            collateralNeeded += 1;
+        }
+        return collateralNeeded;
+    }
+}
@@ -0,0 +1,53 @@
+#!/usr/bin/env bash
+# Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+# See the file LICENSE for licensing terms.
+
+# Generates the contract artifacts of a release, to be embedded in the contract artifact registry.
+# Releases listed in audits/README.md are generated with --audited, to verify deployments against.
+# Usage: ./scripts/contract_artifacts.sh <version> <commit> [--audited]
+
+set -e
+set -o pipefail
+
+AVALANCHE_INTERCHAIN_TOKEN_TRANSFER_PATH=$(
+  cd "$(dirname "${BASH_SOURCE[0]}")"
+  cd .. && pwd
+)
+
+if [ $# -lt 2 ] || [ $# -gt 3 ] || { [ $# -eq 3 ] && [ "$3" != "--audited" ]; }; then
+    echo "Usage: $0 <version> <commit> [--audited]"
+    exit 1
+fi
+version=$1
+commit=$2
+audited=false
+if [ $# -eq 3 ]; then
+  audited=true
+fi
+
+if command -v forge &> /dev/null; then
+  FORGE_COMMAND="forge build --skip test"
+else
+  echo "Forge command not found, attempting to use from $HOME"
+  FORGE_COMMAND="$HOME/.foundry/bin/forge build --skip test"
+fi
+
+# Build the contracts at the release commit in a separate worktree, so that the working tree is not modified
+worktree=$(mktemp -d)
+trap "git -C $AVALANCHE_INTERCHAIN_TOKEN_TRANSFER_PATH worktree remove --force $worktree" EXIT
+
+git -C $AVALANCHE_INTERCHAIN_TOKEN_TRANSFER_PATH worktree add --detach $worktree $commit
+git -C $worktree submodule update --init --recursive
+
+cd $worktree/contracts
+$FORGE_COMMAND
+
+cd $AVALANCHE_INTERCHAIN_TOKEN_TRANSFER_PATH
+go run ./cmd/contract-artifacts generate \
+    -forge-out $worktree/contracts/out \
+    -version $version \
+    -commit $commit \
+    -audited=$audited \
+    -o $AVALANCHE_INTERCHAIN_TOKEN_TRANSFER_PATH/utils/contract-artifacts/releases/$version.json
+
+echo "Generated utils/contract-artifacts/releases/$version.json"
@@ -0,0 +1,34 @@
+#!/usr/bin/env bash
+# Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+# See the file LICENSE for licensing terms.
+
+set -e
+
+AVALANCHE_INTERCHAIN_TOKEN_TRANSFER_PATH=$(
+  cd "$(dirname "${BASH_SOURCE[0]}")"
+  cd .. && pwd
+)
+
+if [ -z "$EXTERNAL_NETWORK_CONFIG" ]; then
+  echo "EXTERNAL_NETWORK_CONFIG must be set to the config file of the external network"
+  exit 1
+fi
+
+cd $AVALANCHE_INTERCHAIN_TOKEN_TRANSFER_PATH
+# Build ginkgo
+# to install the ginkgo binary (required for test build and run)
+go install -v github.com/onsi/ginkgo/v2/ginkgo
+
+ginkgo build ./tests/external/suite/
+
+# Run the tests
+echo "Running e2e tests against the external network"
+./tests/external/suite/suite.test \
+  --ginkgo.vv \
+  --ginkgo.label-filter=${GINKGO_LABEL_FILTER:-""} \
+  --ginkgo.focus=${GINKGO_FOCUS:-""} \
+  ${GINKGO_SEED:+--ginkgo.seed=$GINKGO_SEED} \
+  --ginkgo.trace
+
+echo "e2e tests against the external network passed"
+exit 0
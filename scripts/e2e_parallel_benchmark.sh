@@ -0,0 +1,43 @@
+#!/usr/bin/env bash
+# Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+# See the file LICENSE for licensing terms.
+
+# Measures the wall-clock time of the e2e tests run serially and on GINKGO_PROCS parallel processes.
+# Accepts the same environment variables as e2e_test.sh. Besides the total time of each run, which includes
+# installing the dependencies and building the contracts, the time of the specs is reported by Ginkgo.
+# In GitHub Actions, the times are also written to the summary of the run.
+
+set -e
+
+AVALANCHE_INTERCHAIN_TOKEN_TRANSFER_PATH=$(
+  cd "$(dirname "${BASH_SOURCE[0]}")"
+  cd .. && pwd
+)
+
+PROCS=${GINKGO_PROCS:-4}
+LOG_DIR=$(mktemp -d)
+
+SECONDS=0
+env -u GINKGO_PROCS "$AVALANCHE_INTERCHAIN_TOKEN_TRANSFER_PATH"/scripts/e2e_test.sh > "$LOG_DIR"/serial.log 2>&1
+SERIAL_SECONDS=$SECONDS
+
+SECONDS=0
+GINKGO_PROCS=$PROCS "$AVALANCHE_INTERCHAIN_TOKEN_TRANSFER_PATH"/scripts/e2e_test.sh > "$LOG_DIR"/parallel.log 2>&1
+PARALLEL_SECONDS=$SECONDS
+
+SERIAL_SPECS=$(grep -o 'Ran [0-9]* of [0-9]* Specs in [0-9.]* seconds' "$LOG_DIR"/serial.log || echo "no specs ran")
+PARALLEL_SPECS=$(grep -o 'Ran [0-9]* of [0-9]* Specs in [0-9.]* seconds' "$LOG_DIR"/parallel.log || echo "no specs ran")
+
+echo "Test output written to $LOG_DIR"
+echo "serial: ${SERIAL_SECONDS}s total, ${SERIAL_SPECS}"
+echo "parallel (${PROCS} processes): ${PARALLEL_SECONDS}s total, ${PARALLEL_SPECS}"
+
+if [ -n "$GITHUB_STEP_SUMMARY" ]; then
+  {
+    echo "| Run | Total | Specs |"
+    echo "| --- | --- | --- |"
+    echo "| serial | ${SERIAL_SECONDS}s | ${SERIAL_SPECS} |"
+    echo "| ${PROCS} processes | ${PARALLEL_SECONDS}s | ${PARALLEL_SPECS} |"
+  } >> "$GITHUB_STEP_SUMMARY"
+fi
+exit 0
@@ -45,13 +45,26 @@ go install -v github.com/onsi/ginkgo/v2/ginkgo
 
 ginkgo build ./tests/local/
 
-# Run the tests
-echo "Running e2e tests"
-RUN_E2E=true ./tests/local/local.test \
-  --ginkgo.vv \
-  --ginkgo.label-filter=${GINKGO_LABEL_FILTER:-""} \
-  --ginkgo.focus=${GINKGO_FOCUS:-""} \
-  --ginkgo.trace
+# Run the tests, on GINKGO_PROCS parallel processes if it is set
+if [ -n "$GINKGO_PROCS" ]; then
+  echo "Running e2e tests on $GINKGO_PROCS parallel processes"
+  RUN_E2E=true ginkgo run \
+    --procs=$GINKGO_PROCS \
+    -vv \
+    --label-filter=${GINKGO_LABEL_FILTER:-""} \
+    --focus=${GINKGO_FOCUS:-""} \
+    ${GINKGO_SEED:+--seed=$GINKGO_SEED} \
+    --trace \
+    ./tests/local/local.test
+else
+  echo "Running e2e tests"
+  RUN_E2E=true ./tests/local/local.test \
+    --ginkgo.vv \
+    --ginkgo.label-filter=${GINKGO_LABEL_FILTER:-""} \
+    --ginkgo.focus=${GINKGO_FOCUS:-""} \
+    ${GINKGO_SEED:+--ginkgo.seed=$GINKGO_SEED} \
+    --ginkgo.trace
+fi
 
 echo "e2e tests passed"
 exit 0
@@ -0,0 +1,201 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package diagnostics
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	nativetokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	nativetokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemote"
+	wrappednativetoken "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/WrappedNativeToken"
+	exampleerc20 "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/mocks/ExampleERC20Decimals"
+	mockERC20SACR "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/mocks/MockERC20SendAndCallReceiver"
+	mockNSACR "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/mocks/MockNativeSendAndCallReceiver"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/precompile/contracts/warp"
+	teleportermessenger "github.com/ava-labs/teleporter/abi-bindings/go/teleporter/TeleporterMessenger"
+	teleporterregistry "github.com/ava-labs/teleporter/abi-bindings/go/teleporter/registry/TeleporterRegistry"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Byte arrays longer than this are truncated when formatted, since Teleporter messages
+// and their payloads would otherwise make up most of the report.
+const maxFormattedBytes = 64
+
+// Methods, events and errors of the contracts a token transfer goes through, used to decode
+// call traces, revert data and logs. Those shared by several contracts are decoded with the
+// first ABI that defines them, since they have the same signature.
+type abiIndex struct {
+	methods map[[4]byte]abi.Method
+	events  map[common.Hash]abi.Event
+	errors  map[[4]byte]abi.Error
+}
+
+var knownABIs = func() abiIndex {
+	index := abiIndex{
+		methods: make(map[[4]byte]abi.Method),
+		events:  make(map[common.Hash]abi.Event),
+		errors:  make(map[[4]byte]abi.Error),
+	}
+	abis := []abi.ABI{warp.WarpABI}
+	for _, metaData := range []*bind.MetaData{
+		erc20tokenhome.ERC20TokenHomeMetaData,
+		nativetokenhome.NativeTokenHomeMetaData,
+		erc20tokenremote.ERC20TokenRemoteMetaData,
+		nativetokenremote.NativeTokenRemoteMetaData,
+		wrappednativetoken.WrappedNativeTokenMetaData,
+		exampleerc20.ExampleERC20DecimalsMetaData,
+		mockERC20SACR.MockERC20SendAndCallReceiverMetaData,
+		mockNSACR.MockNativeSendAndCallReceiverMetaData,
+		teleportermessenger.TeleporterMessengerMetaData,
+		teleporterregistry.TeleporterRegistryMetaData,
+	} {
+		contractABI, err := metaData.GetAbi()
+		if err != nil {
+			panic(err)
+		}
+		abis = append(abis, *contractABI)
+	}
+
+	for _, contractABI := range abis {
+		for _, method := range contractABI.Methods {
+			var id [4]byte
+			copy(id[:], method.ID)
+			if _, ok := index.methods[id]; !ok {
+				index.methods[id] = method
+			}
+		}
+		for _, event := range contractABI.Events {
+			if _, ok := index.events[event.ID]; !ok {
+				index.events[event.ID] = event
+			}
+		}
+		for _, abiError := range contractABI.Errors {
+			var id [4]byte
+			copy(id[:], abiError.ID[:4])
+			if _, ok := index.errors[id]; !ok {
+				index.errors[id] = abiError
+			}
+		}
+	}
+	return index
+}()
+
+// Arg is a decoded argument of a call, event or error.
+type Arg struct {
+	Name  string
+	Value interface{}
+}
+
+// DecodeCall decodes the method called with input, if it is one of the known methods.
+func DecodeCall(input []byte) (string, []Arg, error) {
+	if len(input) < 4 {
+		return "", nil, fmt.Errorf("call data too short: %d bytes", len(input))
+	}
+	var id [4]byte
+	copy(id[:], input[:4])
+	method, ok := knownABIs.methods[id]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown method %s", hexutil.Encode(id[:]))
+	}
+	values, err := method.Inputs.Unpack(input[4:])
+	if err != nil {
+		return method.Name, nil, err
+	}
+	return method.Name, namedArgs(method.Inputs, values), nil
+}
+
+// decodeCustomError decodes revert data of a custom error, such as those of the OpenZeppelin contracts.
+func decodeCustomError(data []byte) (string, bool) {
+	if len(data) < 4 {
+		return "", false
+	}
+	var id [4]byte
+	copy(id[:], data[:4])
+	abiError, ok := knownABIs.errors[id]
+	if !ok {
+		return "", false
+	}
+	values, err := abiError.Inputs.Unpack(data[4:])
+	if err != nil {
+		return "", false
+	}
+	return formatCall(abiError.Name, namedArgs(abiError.Inputs, values)), true
+}
+
+func namedArgs(arguments abi.Arguments, values []interface{}) []Arg {
+	args := make([]Arg, 0, len(values))
+	for i, value := range values {
+		args = append(args, Arg{Name: arguments[i].Name, Value: value})
+	}
+	return args
+}
+
+func formatCall(name string, args []Arg) string {
+	formatted := make([]string, 0, len(args))
+	for _, arg := range args {
+		formatted = append(formatted, formatArg(arg))
+	}
+	return name + "(" + strings.Join(formatted, ", ") + ")"
+}
+
+func formatArg(arg Arg) string {
+	if arg.Name == "" {
+		return formatValue(reflect.ValueOf(arg.Value))
+	}
+	return arg.Name + "=" + formatValue(reflect.ValueOf(arg.Value))
+}
+
+// formatValue formats the values unpacked from ABI encoded data, with addresses and
+// 32 byte values in hex, and the fields of structs named.
+func formatValue(value reflect.Value) string {
+	if !value.IsValid() {
+		return "<nil>"
+	}
+	switch v := value.Interface().(type) {
+	case common.Address:
+		return v.Hex()
+	case *big.Int:
+		return v.String()
+	case [32]byte:
+		return common.Hash(v).Hex()
+	case []byte:
+		return formatBytes(v)
+	}
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return "<nil>"
+		}
+		return formatValue(value.Elem())
+	case reflect.Struct:
+		fields := make([]string, 0, value.NumField())
+		for i := 0; i < value.NumField(); i++ {
+			fields = append(fields, value.Type().Field(i).Name+": "+formatValue(value.Field(i)))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case reflect.Slice, reflect.Array:
+		elements := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			elements = append(elements, formatValue(value.Index(i)))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+func formatBytes(b []byte) string {
+	if len(b) <= maxFormattedBytes {
+		return hexutil.Encode(b)
+	}
+	return fmt.Sprintf("%s...(%d bytes)", hexutil.Encode(b[:maxFormattedBytes]), len(b))
+}
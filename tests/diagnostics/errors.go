@@ -0,0 +1,199 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package diagnostics
+
+import "strings"
+
+// KnownError is a revert reason of the token transferrer or Teleporter contracts,
+// with the likely cause of the revert in a test.
+type KnownError struct {
+	// The contract that reverts with the reason, such as "TokenHome"
+	Contract string
+	Reason   string
+	Hint     string
+}
+
+// The revert reasons of the contracts in contracts/src, kept in sync by TestKnownErrorsMatchContracts,
+// and the Teleporter revert reasons most commonly hit by the token transferrers.
+// The contract of each error is set by LookupError.
+var knownErrors = []KnownError{
+	{Reason: "TokenHome: cannot register remote on same chain", Hint: "a TokenRemote was deployed on the home's chain"},
+	{Reason: "TokenHome: collateral needed for remote", Hint: "call addCollateral for the remote before sending to it"},
+	{Reason: "TokenHome: insufficient amount to cover fees", Hint: "the amount does not exceed the secondary fee"},
+	{
+		Reason: "TokenHome: insufficient token transfer balance",
+		Hint:   "more tokens are sent back than were transferred to the remote",
+	},
+	{
+		Reason: "TokenHome: invalid home token decimals",
+		Hint:   "the TokenRemote was registered with different home token decimals",
+	},
+	{
+		Reason: "TokenHome: invalid recipient gas limit",
+		Hint:   "the recipient gas limit is not below the required gas limit",
+	},
+	{
+		Reason: "TokenHome: mismatched origin sender address",
+		Hint:   "the message was sent by another token transferrer than the one sent to",
+	},
+	{
+		Reason: "TokenHome: mismatched source blockchain ID",
+		Hint:   "the message was sent from another chain than the one sent to",
+	},
+	{
+		Reason: "TokenHome: non-zero multi-hop fallback",
+		Hint:   "multiHopFallback must be zero for transfers ending on the home chain",
+	},
+	{
+		Reason: "TokenHome: non-zero secondary fee",
+		Hint:   "secondaryFee must be zero for transfers ending on the home chain",
+	},
+	{Reason: "TokenHome: remote already registered", Hint: "the TokenRemote sent registerWithHome more than once"},
+	{
+		Reason: "TokenHome: remote not collateralized",
+		Hint:   "the remote is registered with an initial reserve imbalance to collateralize",
+	},
+	{Reason: "TokenHome: remote not registered", Hint: "relay the registerWithHome message of the remote first"},
+	{Reason: "TokenHome: remote token decimals too high", Hint: "the remote token has more than 18 decimals"},
+	{Reason: "TokenHome: token decimals too high", Hint: "the home token has more than 18 decimals"},
+	{Reason: "TokenHome: zero collateral needed", Hint: "the remote is already fully collateralized"},
+	{Reason: "TokenHome: zero fallback recipient address", Hint: "set fallbackRecipient for sendAndCall"},
+	{Reason: "TokenHome: zero recipient address", Hint: "set the recipient of the transfer"},
+	{Reason: "TokenHome: zero recipient contract address", Hint: "set recipientContract for sendAndCall"},
+	{Reason: "TokenHome: zero recipient gas limit", Hint: "set recipientGasLimit for sendAndCall"},
+	{Reason: "TokenHome: zero remote blockchain ID", Hint: "set the destination blockchain ID"},
+	{Reason: "TokenHome: zero remote token transferrer address", Hint: "set the destination token transferrer address"},
+	{Reason: "TokenHome: zero required gas limit", Hint: "set requiredGasLimit of the transfer"},
+	{Reason: "TokenHome: zero scaled amount", Hint: "the amount is dust that scales to zero on the remote"},
+	{Reason: "TokenHome: zero token address", Hint: "the TokenHome was initialized without a token"},
+	{Reason: "TokenHome: zero token amount", Hint: "the amount sent by the remote scales to zero on the home chain"},
+	{
+		Reason: "NativeTokenHome: invalid receive payable sender",
+		Hint:   "native tokens were sent directly to the NativeTokenHome",
+	},
+	{Reason: "TokenRemote: already registered", Hint: "the TokenRemote already registered with its home"},
+	{
+		Reason: "TokenRemote: cannot deploy to same blockchain as token home",
+		Hint:   "deploy the TokenRemote on another chain than its home",
+	},
+	{
+		Reason: "TokenRemote: insufficient tokens to transfer",
+		Hint:   "the amount does not exceed the secondary fee once scaled to the home",
+	},
+	{
+		Reason: "TokenRemote: invalid destination token transferrer address",
+		Hint:   "transfers to the home must target the TokenHome, and multi-hops can not target the sending remote",
+	},
+	{
+		Reason: "TokenRemote: invalid message type",
+		Hint:   "the TokenRemote only receives single-hop transfers from its home",
+	},
+	{
+		Reason: "TokenRemote: invalid origin sender address",
+		Hint:   "the message was not sent by the TokenHome of the remote",
+	},
+	{
+		Reason: "TokenRemote: invalid recipient gas limit",
+		Hint:   "the recipient gas limit is not below the required gas limit",
+	},
+	{
+		Reason: "TokenRemote: invalid source blockchain ID",
+		Hint:   "the message was not sent from the chain of the TokenHome",
+	},
+	{
+		Reason: "TokenRemote: non-zero multi-hop fallback",
+		Hint:   "multiHopFallback must be zero for transfers to the home chain",
+	},
+	{Reason: "TokenRemote: non-zero secondary fee", Hint: "secondaryFee must be zero for transfers to the home chain"},
+	{Reason: "TokenRemote: token decimals too high", Hint: "the remote token has more than 18 decimals"},
+	{Reason: "TokenRemote: token home decimals too high", Hint: "the home token has more than 18 decimals"},
+	{Reason: "TokenRemote: zero destination blockchain ID", Hint: "set the destination blockchain ID"},
+	{
+		Reason: "TokenRemote: zero destination token transferrer address",
+		Hint:   "set the destination token transferrer address",
+	},
+	{Reason: "TokenRemote: zero fallback recipient address", Hint: "set fallbackRecipient for sendAndCall"},
+	{Reason: "TokenRemote: zero multi-hop fallback", Hint: "set multiHopFallback for multi-hop transfers"},
+	{Reason: "TokenRemote: zero recipient address", Hint: "set the recipient of the transfer"},
+	{Reason: "TokenRemote: zero recipient contract address", Hint: "set recipientContract for sendAndCall"},
+	{Reason: "TokenRemote: zero recipient gas limit", Hint: "set recipientGasLimit for sendAndCall"},
+	{Reason: "TokenRemote: zero required gas limit", Hint: "set requiredGasLimit of the transfer"},
+	{Reason: "TokenRemote: zero token home address", Hint: "the TokenRemote was initialized without a TokenHome"},
+	{Reason: "TokenRemote: zero token home blockchain ID", Hint: "the TokenRemote was initialized without a TokenHome"},
+	{
+		Reason: "NativeTokenRemote: burn address balance not greater than last report",
+		Hint:   "no transaction fees were burned since the last reportBurnedTxFees",
+	},
+	{
+		Reason: "NativeTokenRemote: contract undercollateralized",
+		Hint:   "the remote is still waiting on addCollateral for its initial reserve imbalance",
+	},
+	{Reason: "NativeTokenRemote: invalid percentage", Hint: "the burned fees reward percentage is not below 100"},
+	{
+		Reason: "NativeTokenRemote: zero initial reserve imbalance",
+		Hint:   "a NativeTokenRemote needs an initial reserve imbalance",
+	},
+	{
+		Reason: "NativeTokenRemote: zero scaled amount to report burn",
+		Hint:   "the burned fees scale to zero on the home chain",
+	},
+	{Reason: "CallUtils: insufficient gas", Hint: "the recipient gas limit exceeds the gas left for the call"},
+	{Reason: "CallUtils: insufficient value", Hint: "the contract holds fewer native tokens than the call sends"},
+	{
+		Reason: "SafeERC20TransferFrom: balance not increased",
+		Hint:   "the transferred amount is zero after the token's own fees",
+	},
+	{Reason: "SafeWrappedNativeTokenDeposit: balance not increased", Hint: "no native tokens were deposited"},
+	{
+		Reason: "SendReentrancyGuard: send reentrancy",
+		Hint:   "a sendAndCall recipient sent tokens through the same transferrer",
+	},
+	{
+		Reason: "TeleporterMessenger: insufficient gas",
+		Hint:   "the relayer provided less gas than the message's required gas limit",
+	},
+	{Reason: "TeleporterMessenger: message already received", Hint: "the message was already delivered"},
+	{Reason: "TeleporterMessenger: unauthorized relayer", Hint: "the message restricts its allowed relayer addresses"},
+	{
+		Reason: "TeleporterMessenger: invalid warp message",
+		Hint:   "the Warp message is missing from the delivery transaction",
+	},
+	{Reason: "ReentrancyGuards: receiver reentrancy", Hint: "a message handler delivered another Teleporter message"},
+	{
+		Reason: "ReentrancyGuards: sender reentrancy",
+		Hint:   "a message handler sent through Teleporter from within the send",
+	},
+}
+
+var knownErrorHints = func() map[string]string {
+	hints := make(map[string]string, len(knownErrors))
+	for _, knownError := range knownErrors {
+		hints[knownError.Reason] = knownError.Hint
+	}
+	return hints
+}()
+
+// Hints for revert reasons that are not listed in knownErrors, by the contract in their prefix.
+var contractHints = map[string]string{
+	"TeleporterMessenger":   "check the relayer and the Teleporter address of the network",
+	"TeleporterRegistry":    "check the Teleporter registry versions of the network",
+	"TeleporterRegistryApp": "check the Teleporter version and paused addresses of the token transferrer",
+	"TeleporterUpgradeable": "check the Teleporter version and paused addresses of the token transferrer",
+}
+
+// LookupError maps a revert reason to the contract that reverts with it, taken from the "Contract: " prefix
+// of the reason, and the likely cause of the revert. It returns false if the reason is not a known error,
+// in which case the hint is a generic one for the contract, if any.
+func LookupError(reason string) (KnownError, bool) {
+	knownError := KnownError{Reason: reason}
+	if contract, _, ok := strings.Cut(reason, ": "); ok && !strings.Contains(contract, " ") {
+		knownError.Contract = contract
+	}
+	hint, ok := knownErrorHints[reason]
+	if !ok {
+		hint = contractHints[knownError.Contract]
+	}
+	knownError.Hint = hint
+	return knownError, ok
+}
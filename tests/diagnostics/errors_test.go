@@ -0,0 +1,106 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package diagnostics
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const contractsDir = "../../contracts/src"
+
+// Matches revert reason literals of the form "Contract: reason"
+var revertReasonRegexp = regexp.MustCompile(`"([A-Za-z0-9]+: [^"]+)"`)
+
+// TestKnownErrorsMatchContracts checks that every revert reason of the contracts has a hint,
+// so that the table is updated along with the contracts.
+func TestKnownErrorsMatchContracts(t *testing.T) {
+	var reasons []string
+	err := filepath.WalkDir(contractsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Mocks are only used by the tests of the contracts
+		if d.IsDir() && d.Name() == "mocks" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".sol") {
+			return nil
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, match := range revertReasonRegexp.FindAllStringSubmatch(string(source), -1) {
+			reasons = append(reasons, match[1])
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, reasons)
+
+	for _, reason := range reasons {
+		knownError, ok := LookupError(reason)
+		require.True(t, ok, "no hint for revert reason %q", reason)
+		require.NotEmpty(t, knownError.Hint)
+	}
+}
+
+func TestLookupError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		reason   string
+		expected KnownError
+		known    bool
+	}{
+		{
+			name:   "token home",
+			reason: "TokenHome: remote not registered",
+			expected: KnownError{
+				Contract: "TokenHome",
+				Reason:   "TokenHome: remote not registered",
+				Hint:     "relay the registerWithHome message of the remote first",
+			},
+			known: true,
+		},
+		{
+			name:   "unknown Teleporter error",
+			reason: "TeleporterRegistryApp: Teleporter address paused",
+			expected: KnownError{
+				Contract: "TeleporterRegistryApp",
+				Reason:   "TeleporterRegistryApp: Teleporter address paused",
+				Hint:     "check the Teleporter version and paused addresses of the token transferrer",
+			},
+		},
+		{
+			name:     "unknown contract",
+			reason:   "Unknown: reason",
+			expected: KnownError{Contract: "Unknown", Reason: "Unknown: reason"},
+		},
+		{
+			name:     "no contract",
+			reason:   "out of gas",
+			expected: KnownError{Reason: "out of gas"},
+		},
+		{
+			name:     "panic",
+			reason:   "arithmetic underflow or overflow: 0x11",
+			expected: KnownError{Reason: "arithmetic underflow or overflow: 0x11"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			knownError, ok := LookupError(tc.reason)
+			require.Equal(t, tc.known, ok)
+			require.Equal(t, tc.expected, knownError)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package diagnostics
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var errAnonymousLog = errors.New("log without topics")
+
+// Event is a decoded log of a transaction.
+type Event struct {
+	Address common.Address
+	Name    string
+	Args    []Arg
+}
+
+// String formats the event as its name and named arguments, prefixed by the emitting contract.
+func (e Event) String() string {
+	return e.Address.Hex() + " " + formatCall(e.Name, e.Args)
+}
+
+// DecodeLog decodes a log of the token transferrer, token, Teleporter, or Warp contracts.
+func DecodeLog(log *types.Log) (Event, error) {
+	if len(log.Topics) == 0 {
+		return Event{}, errAnonymousLog
+	}
+	event, ok := knownABIs.events[log.Topics[0]]
+	if !ok {
+		return Event{}, fmt.Errorf("unknown event %s", log.Topics[0])
+	}
+
+	values := make(map[string]interface{}, len(event.Inputs))
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(values, indexed, log.Topics[1:]); err != nil {
+		return Event{}, fmt.Errorf("failed to decode topics of %s: %w", event.Name, err)
+	}
+	if err := event.Inputs.UnpackIntoMap(values, log.Data); err != nil {
+		return Event{}, fmt.Errorf("failed to decode data of %s: %w", event.Name, err)
+	}
+
+	args := make([]Arg, 0, len(event.Inputs))
+	for _, input := range event.Inputs {
+		args = append(args, Arg{Name: input.Name, Value: values[input.Name]})
+	}
+	return Event{
+		Address: log.Address,
+		Name:    event.Name,
+		Args:    args,
+	}, nil
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package diagnostics
+
+import (
+	"math/big"
+	"testing"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeLog(t *testing.T) {
+	tokenHomeABI, err := erc20tokenhome.ERC20TokenHomeMetaData.GetAbi()
+	require.NoError(t, err)
+	event := tokenHomeABI.Events["RemoteRegistered"]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(1000), uint8(6))
+	require.NoError(t, err)
+	remoteBlockchainID := ids.GenerateTestID()
+
+	log := &types.Log{
+		Address: testHome,
+		Topics: []common.Hash{
+			event.ID,
+			common.Hash(remoteBlockchainID),
+			common.BytesToHash(testToken.Bytes()),
+		},
+		Data: data,
+	}
+	decoded, err := DecodeLog(log)
+	require.NoError(t, err)
+	require.Equal(t, testHome, decoded.Address)
+	require.Equal(t, "RemoteRegistered", decoded.Name)
+	require.Equal(t, []Arg{
+		{Name: "remoteBlockchainID", Value: [32]byte(remoteBlockchainID)},
+		{Name: "remoteTokenTransferrerAddress", Value: testToken},
+		{Name: "initialCollateralNeeded", Value: big.NewInt(1000)},
+		{Name: "tokenDecimals", Value: uint8(6)},
+	}, decoded.Args)
+	require.Equal(
+		t,
+		testHome.Hex()+" RemoteRegistered(remoteBlockchainID="+common.Hash(remoteBlockchainID).Hex()+
+			", remoteTokenTransferrerAddress="+testToken.Hex()+", initialCollateralNeeded=1000, tokenDecimals=6)",
+		decoded.String(),
+	)
+
+	remote, ok := eventRemote(decoded)
+	require.True(t, ok)
+	require.Equal(t, Remote{BlockchainID: remoteBlockchainID, Address: testToken}, remote)
+
+	_, err = DecodeLog(&types.Log{Topics: []common.Hash{common.HexToHash("0x1234")}})
+	require.ErrorContains(t, err, "unknown event")
+	_, err = DecodeLog(&types.Log{})
+	require.ErrorIs(t, err, errAnonymousLog)
+}
@@ -0,0 +1,239 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package diagnostics builds reports of the transactions of failed tests, with the decoded call trace,
+// the revert reason mapped to the known errors of the contracts, the decoded token transferrer and
+// Teleporter events, and the settings of the token transferrers involved. Reports are attached to
+// the Ginkgo report rather than printed, so that failing specs are reported like any other failure.
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/onsi/ginkgo/v2"
+
+	. "github.com/onsi/gomega"
+)
+
+// Report describes a transaction for the diagnosis of a failed test.
+// Reports are built on a best effort basis, and the errors met while building them are
+// recorded in Errors, so that building a report never fails the test itself.
+type Report struct {
+	Chain        string
+	BlockchainID ids.ID
+	TxHash       common.Hash
+	Status       uint64
+	GasUsed      uint64
+
+	// Nil if the node does not have the debug API enabled
+	Trace *CallFrame
+	// The call that caused the first revert of the trace, and its revert reason
+	// mapped to the known errors of the contracts
+	Revert      *CallFrame
+	RevertError KnownError
+	KnownRevert bool
+
+	Events       []Event
+	Transferrers []*TransferrerSettings
+	Errors       []error
+}
+
+// NewReport builds the report of a transaction on subnet. The settings of the given remotes are
+// reported by the TokenHome instances involved in the transaction, along with the remotes named by
+// the transaction's events and the sender of the Teleporter message it delivers, if any.
+func NewReport(
+	ctx context.Context,
+	subnet interfaces.SubnetTestInfo,
+	receipt *types.Receipt,
+	remotes ...Remote,
+) *Report {
+	report := &Report{
+		Chain:        subnet.SubnetName,
+		BlockchainID: subnet.BlockchainID,
+		TxHash:       receipt.TxHash,
+		Status:       receipt.Status,
+		GasUsed:      receipt.GasUsed,
+	}
+
+	trace, err := TraceCalls(ctx, subnet.RPCClient, receipt.TxHash)
+	if err != nil {
+		report.Errors = append(report.Errors, err)
+	} else {
+		report.Trace = trace
+		if report.Revert = trace.FirstRevert(); report.Revert != nil {
+			report.RevertError, report.KnownRevert = LookupError(report.Revert.Reason())
+		}
+	}
+
+	// Token transferrers involved in the transaction, in order of appearance
+	var addresses []common.Address
+	seen := make(map[common.Address]struct{})
+	addAddress := func(address common.Address) {
+		if _, ok := seen[address]; !ok && address != (common.Address{}) {
+			seen[address] = struct{}{}
+			addresses = append(addresses, address)
+		}
+	}
+	remotes = append([]Remote{}, remotes...)
+	addRemote := func(remote Remote) {
+		for _, r := range remotes {
+			if r == remote {
+				return
+			}
+		}
+		remotes = append(remotes, remote)
+	}
+
+	for i, log := range receipt.Logs {
+		addAddress(log.Address)
+		event, err := DecodeLog(log)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("log %d of %s: %w", i, log.Address.Hex(), err))
+			continue
+		}
+		report.Events = append(report.Events, event)
+		if remote, ok := eventRemote(event); ok {
+			addRemote(remote)
+		}
+		if receive, err := subnet.TeleporterMessenger.ParseReceiveCrossChainMessage(*log); err == nil {
+			addAddress(receive.Message.DestinationAddress)
+			addRemote(Remote{
+				BlockchainID: receive.SourceBlockchainID,
+				Address:      receive.Message.OriginSenderAddress,
+			})
+		}
+	}
+	if report.Trace != nil {
+		report.Trace.walk(func(frame *CallFrame) {
+			// Delegate calls are made by proxies to their implementation, which holds no state
+			if frame.Type != "DELEGATECALL" {
+				addAddress(frame.To)
+			}
+		})
+	}
+
+	for _, address := range addresses {
+		settings, err := GetTransferrerSettings(ctx, subnet.RPCClient, address, remotes)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("failed to get settings of %s: %w", address.Hex(), err))
+			continue
+		}
+		if settings != nil {
+			report.Transferrers = append(report.Transferrers, settings)
+		}
+	}
+	return report
+}
+
+// eventRemote returns the remote named by the remoteBlockchainID and remoteTokenTransferrerAddress
+// arguments of the RemoteRegistered and CollateralAdded events.
+func eventRemote(event Event) (Remote, bool) {
+	var (
+		remote                      Remote
+		hasBlockchainID, hasAddress bool
+	)
+	for _, arg := range event.Args {
+		switch value := arg.Value.(type) {
+		case [32]byte:
+			if arg.Name == "remoteBlockchainID" {
+				remote.BlockchainID, hasBlockchainID = value, true
+			}
+		case common.Address:
+			if arg.Name == "remoteTokenTransferrerAddress" {
+				remote.Address, hasAddress = value, true
+			}
+		}
+	}
+	return remote, hasBlockchainID && hasAddress
+}
+
+// Summary describes the outcome of the transaction on one line.
+func (r *Report) Summary() string {
+	summary := fmt.Sprintf("transaction %s on %s", r.TxHash.Hex(), r.Chain)
+	if r.Status == types.ReceiptStatusFailed {
+		summary += " failed"
+	}
+	if r.Revert == nil {
+		return summary
+	}
+	summary += fmt.Sprintf(", %s reverted with %q", r.Revert.To.Hex(), r.RevertError.Reason)
+	if r.RevertError.Hint != "" {
+		summary += " (" + r.RevertError.Hint + ")"
+	}
+	return summary
+}
+
+// String formats the full report, with one section per part of the report.
+func (r *Report) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Transaction %s on %s (%s): status=%d gasUsed=%d\n",
+		r.TxHash.Hex(), r.Chain, r.BlockchainID, r.Status, r.GasUsed)
+
+	if r.Revert != nil {
+		fmt.Fprintf(&sb, "\nRevert: %s\n", r.RevertError.Reason)
+		if r.KnownRevert {
+			fmt.Fprintf(&sb, "  known error of %s: %s\n", r.RevertError.Contract, r.RevertError.Hint)
+		} else if r.RevertError.Hint != "" {
+			fmt.Fprintf(&sb, "  unknown error of %s: %s\n", r.RevertError.Contract, r.RevertError.Hint)
+		}
+		fmt.Fprintf(&sb, "  in call from %s to %s %s\n", r.Revert.From.Hex(), r.Revert.To.Hex(), r.Revert.Method())
+	}
+	if r.Trace != nil {
+		sb.WriteString("\nCall trace:\n")
+		sb.WriteString(r.Trace.String())
+	}
+	if len(r.Events) > 0 {
+		sb.WriteString("\nEvents:\n")
+		for _, event := range r.Events {
+			sb.WriteString(event.String() + "\n")
+		}
+	}
+	if len(r.Transferrers) > 0 {
+		sb.WriteString("\nToken transferrers:\n")
+		for _, settings := range r.Transferrers {
+			sb.WriteString(settings.String() + "\n")
+		}
+	}
+	if len(r.Errors) > 0 {
+		sb.WriteString("\nIncomplete report:\n")
+		for _, err := range r.Errors {
+			sb.WriteString(err.Error() + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Attach adds the report to the Ginkgo report of the running spec. It is only shown for
+// failed specs, or when running verbosely.
+func (r *Report) Attach() {
+	ginkgo.AddReportEntry(
+		"Diagnostics of "+r.TxHash.Hex(),
+		r,
+		ginkgo.ReportEntryVisibilityFailureOrVerbose,
+		ginkgo.Offset(1),
+	)
+}
+
+// ExpectMessageExecuted fails the running spec if the Teleporter message delivered by receipt was not
+// executed, with the report of the delivery transaction attached. Remotes are passed to NewReport.
+func ExpectMessageExecuted(
+	ctx context.Context,
+	subnet interfaces.SubnetTestInfo,
+	receipt *types.Receipt,
+	remotes ...Remote,
+) {
+	_, err := teleporterUtils.GetEventFromLogs(receipt.Logs, subnet.TeleporterMessenger.ParseMessageExecuted)
+	if err == nil {
+		return
+	}
+	report := NewReport(ctx, subnet, receipt, remotes...)
+	report.Attach()
+	ExpectWithOffset(1, err).Should(BeNil(), "message was not executed: %s", report.Summary())
+}
@@ -0,0 +1,143 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/TokenRemote"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Remote identifies a TokenRemote instance, whose settings are reported by the TokenHome instances of a report.
+type Remote struct {
+	BlockchainID ids.ID
+	Address      common.Address
+}
+
+// RemoteSettings are the settings of a remote registered with a TokenHome.
+type RemoteSettings struct {
+	Remote
+	tokenhome.RemoteTokenTransferrerSettings
+	TransferredBalance *big.Int
+}
+
+// TransferrerSettings describe a token transferrer involved in a transaction.
+type TransferrerSettings struct {
+	*inspect.ContractInfo
+
+	// Settings of the remotes of a TokenHome
+	Remotes []RemoteSettings
+
+	// Settings of a TokenRemote
+	IsCollateralized        bool
+	TokenMultiplier         *big.Int
+	MultiplyOnRemote        bool
+	InitialReserveImbalance *big.Int
+}
+
+// GetTransferrerSettings returns the settings of the token transferrer at address, or nil if the
+// contract is not a token transferrer. For TokenHome instances, the settings of the given remotes are returned.
+func GetTransferrerSettings(
+	ctx context.Context,
+	backend inspect.Backend,
+	address common.Address,
+	remotes []Remote,
+) (*TransferrerSettings, error) {
+	info, err := inspect.Inspect(ctx, backend, address)
+	if err != nil {
+		return nil, err
+	}
+	settings := &TransferrerSettings{ContractInfo: info}
+	opts := &bind.CallOpts{Context: ctx}
+
+	switch {
+	case info.Kind.IsHome():
+		home, err := tokenhome.NewTokenHomeCaller(address, backend)
+		if err != nil {
+			return nil, err
+		}
+		for _, remote := range remotes {
+			remoteSettings, err := home.GetRemoteTokenTransferrerSettings(opts, remote.BlockchainID, remote.Address)
+			if err != nil {
+				return nil, err
+			}
+			transferredBalance, err := home.GetTransferredBalance(opts, remote.BlockchainID, remote.Address)
+			if err != nil {
+				return nil, err
+			}
+			settings.Remotes = append(settings.Remotes, RemoteSettings{
+				Remote:                         remote,
+				RemoteTokenTransferrerSettings: remoteSettings,
+				TransferredBalance:             transferredBalance,
+			})
+		}
+	case info.Kind.IsRemote():
+		remote, err := tokenremote.NewTokenRemoteCaller(address, backend)
+		if err != nil {
+			return nil, err
+		}
+		if settings.IsCollateralized, err = remote.GetIsCollateralized(opts); err != nil {
+			return nil, err
+		}
+		if settings.TokenMultiplier, err = remote.GetTokenMultiplier(opts); err != nil {
+			return nil, err
+		}
+		if settings.MultiplyOnRemote, err = remote.GetMultiplyOnRemote(opts); err != nil {
+			return nil, err
+		}
+		if settings.InitialReserveImbalance, err = remote.GetInitialReserveImbalance(opts); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+	return settings, nil
+}
+
+// String formats the settings on one line, followed by one line per remote of a TokenHome.
+func (s *TransferrerSettings) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s blockchainID=%s", s.Address.Hex(), s.Kind, s.BlockchainID)
+	if s.IsProxy {
+		fmt.Fprintf(&sb, " implementation=%s", s.Implementation.Hex())
+	}
+	if s.Kind.IsRemote() {
+		fmt.Fprintf(&sb,
+			" tokenHome=%s on %s decimals=%d isCollateralized=%t initialReserveImbalance=%s"+
+				" tokenMultiplier=%s multiplyOnRemote=%t",
+			s.TokenHomeAddress.Hex(),
+			s.TokenHomeBlockchainID,
+			s.TokenDecimals,
+			s.IsCollateralized,
+			s.InitialReserveImbalance,
+			s.TokenMultiplier,
+			s.MultiplyOnRemote,
+		)
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, " token=%s decimals=%d", s.TokenAddress.Hex(), s.TokenDecimals)
+	for _, remote := range s.Remotes {
+		fmt.Fprintf(&sb,
+			"\n  remote %s on %s: registered=%t collateralNeeded=%s tokenMultiplier=%s multiplyOnRemote=%t"+
+				" transferredBalance=%s",
+			remote.Address.Hex(),
+			remote.BlockchainID,
+			remote.Registered,
+			remote.CollateralNeeded,
+			remote.TokenMultiplier,
+			remote.MultiplyOnRemote,
+			remote.TransferredBalance,
+		)
+	}
+	return sb.String()
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// CallFrame is a call of a transaction, as traced by the callTracer of debug_traceTransaction.
+type CallFrame struct {
+	Type         string         `json:"type"`
+	From         common.Address `json:"from"`
+	To           common.Address `json:"to"`
+	Value        *hexutil.Big   `json:"value,omitempty"`
+	Gas          hexutil.Uint64 `json:"gas"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	Input        hexutil.Bytes  `json:"input"`
+	Output       hexutil.Bytes  `json:"output,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	RevertReason string         `json:"revertReason,omitempty"`
+	Calls        []*CallFrame   `json:"calls,omitempty"`
+}
+
+// TraceCalls returns the call trace of a transaction. The node must have the debug API enabled.
+func TraceCalls(ctx context.Context, rpcClient ethclient.Client, txHash common.Hash) (*CallFrame, error) {
+	var frame CallFrame
+	err := rpcClient.Client().CallContext(
+		ctx,
+		&frame,
+		"debug_traceTransaction",
+		txHash,
+		map[string]string{"tracer": "callTracer"},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to trace transaction %s: %w", txHash, err)
+	}
+	return &frame, nil
+}
+
+// FirstRevert returns the call that caused the first reverted call of the trace, that is
+// the deepest reverted call along the first branch of reverted calls, or nil if no call reverted.
+// Reverted calls are not necessarily fatal to the transaction, for example Teleporter catches
+// the revert of the message execution, and emits MessageExecutionFailed instead.
+func (f *CallFrame) FirstRevert() *CallFrame {
+	for _, call := range f.Calls {
+		if revert := call.FirstRevert(); revert != nil {
+			return revert
+		}
+	}
+	if f.Error != "" {
+		return f
+	}
+	return nil
+}
+
+// Reason returns the revert reason of a reverted call, decoded from its output. Falls back to the
+// error of the call for failures without revert data, such as running out of gas.
+func (f *CallFrame) Reason() string {
+	if f.RevertReason != "" {
+		return f.RevertReason
+	}
+	if reason, err := abi.UnpackRevert(f.Output); err == nil {
+		return reason
+	}
+	if reason, ok := decodeCustomError(f.Output); ok {
+		return reason
+	}
+	if len(f.Output) > 0 {
+		return fmt.Sprintf("%s: %s", f.Error, formatBytes(f.Output))
+	}
+	return f.Error
+}
+
+// Method returns the decoded method and arguments of the call, or its raw input if it is not a known method.
+func (f *CallFrame) Method() string {
+	if len(f.Input) == 0 {
+		return ""
+	}
+	name, args, err := DecodeCall(f.Input)
+	if err != nil {
+		if name != "" {
+			return name + "(" + formatBytes(f.Input[4:]) + ")"
+		}
+		return formatBytes(f.Input)
+	}
+	return formatCall(name, args)
+}
+
+// walk visits the call and its subcalls, in the order they were made.
+func (f *CallFrame) walk(visit func(*CallFrame)) {
+	visit(f)
+	for _, call := range f.Calls {
+		call.walk(visit)
+	}
+}
+
+// String formats the call and its subcalls as an indented tree, one call per line.
+func (f *CallFrame) String() string {
+	var sb strings.Builder
+	f.format(&sb, 0)
+	return sb.String()
+}
+
+func (f *CallFrame) format(sb *strings.Builder, depth int) {
+	sb.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(sb, "%s %s -> %s", f.Type, f.From.Hex(), f.To.Hex())
+	if method := f.Method(); method != "" {
+		sb.WriteString(" " + method)
+	}
+	if f.Value != nil && f.Value.ToInt().Sign() > 0 {
+		fmt.Fprintf(sb, " value=%s", f.Value.ToInt())
+	}
+	fmt.Fprintf(sb, " gasUsed=%d", uint64(f.GasUsed))
+	if f.Error != "" {
+		fmt.Fprintf(sb, " error: %s", f.Reason())
+	}
+	sb.WriteString("\n")
+	for _, call := range f.Calls {
+		call.format(sb, depth+1)
+	}
+}
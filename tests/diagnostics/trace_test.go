@@ -0,0 +1,148 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package diagnostics
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testSender     = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testTeleporter = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testHome       = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	testToken      = common.HexToAddress("0x4444444444444444444444444444444444444444")
+)
+
+func encodeError(t *testing.T, signature string, argType string, value interface{}) []byte {
+	typ, err := abi.NewType(argType, "", nil)
+	require.NoError(t, err)
+	data, err := abi.Arguments{{Type: typ}}.Pack(value)
+	require.NoError(t, err)
+	return append(crypto.Keccak256([]byte(signature))[:4], data...)
+}
+
+func TestCallFrameReason(t *testing.T) {
+	testCases := []struct {
+		name     string
+		frame    CallFrame
+		expected string
+	}{
+		{
+			name:     "revert reason from tracer",
+			frame:    CallFrame{Error: "execution reverted", RevertReason: "TokenHome: zero scaled amount"},
+			expected: "TokenHome: zero scaled amount",
+		},
+		{
+			name: "error string",
+			frame: CallFrame{
+				Error:  "execution reverted",
+				Output: encodeError(t, "Error(string)", "string", "TokenRemote: insufficient tokens to transfer"),
+			},
+			expected: "TokenRemote: insufficient tokens to transfer",
+		},
+		{
+			name: "panic",
+			frame: CallFrame{
+				Error:  "execution reverted",
+				Output: encodeError(t, "Panic(uint256)", "uint256", big.NewInt(0x11)),
+			},
+			expected: "arithmetic underflow or overflow",
+		},
+		{
+			name: "custom error",
+			frame: CallFrame{
+				Error:  "execution reverted",
+				Output: encodeError(t, "SafeERC20FailedOperation(address)", "address", testToken),
+			},
+			expected: "SafeERC20FailedOperation(token=" + testToken.Hex() + ")",
+		},
+		{
+			name:     "unknown revert data",
+			frame:    CallFrame{Error: "execution reverted", Output: []byte{1, 2, 3, 4}},
+			expected: "execution reverted: 0x01020304",
+		},
+		{
+			name:     "no revert data",
+			frame:    CallFrame{Error: "out of gas"},
+			expected: "out of gas",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.frame.Reason())
+		})
+	}
+}
+
+func TestCallFrameFirstRevert(t *testing.T) {
+	tokenHomeABI, err := erc20tokenhome.ERC20TokenHomeMetaData.GetAbi()
+	require.NoError(t, err)
+	sourceBlockchainID := common.HexToHash("0xabcd")
+	input, err := tokenHomeABI.Pack("receiveTeleporterMessage", sourceBlockchainID, testSender, []byte{0xde, 0xad})
+	require.NoError(t, err)
+	output := encodeError(t, "Error(string)", "string", "TokenHome: remote not registered")
+
+	// Teleporter catches the revert of the message execution, so the transaction itself succeeds.
+	trace := `{
+		"type": "CALL",
+		"from": "` + testSender.Hex() + `",
+		"to": "` + testTeleporter.Hex() + `",
+		"gas": "0x100000",
+		"gasUsed": "0x20000",
+		"input": "0x12345678",
+		"calls": [
+			{
+				"type": "STATICCALL",
+				"from": "` + testTeleporter.Hex() + `",
+				"to": "0x0200000000000000000000000000000000000005",
+				"gas": "0x1000",
+				"gasUsed": "0x100",
+				"input": "0x"
+			},
+			{
+				"type": "CALL",
+				"from": "` + testTeleporter.Hex() + `",
+				"to": "` + testHome.Hex() + `",
+				"gas": "0x10000",
+				"gasUsed": "0x2000",
+				"input": "` + hexutil.Encode(input) + `",
+				"output": "` + hexutil.Encode(output) + `",
+				"error": "execution reverted"
+			}
+		]
+	}`
+	var frame CallFrame
+	require.NoError(t, json.Unmarshal([]byte(trace), &frame))
+
+	revert := frame.FirstRevert()
+	require.NotNil(t, revert)
+	require.Equal(t, testHome, revert.To)
+	require.Equal(t, "TokenHome: remote not registered", revert.Reason())
+	require.Equal(
+		t,
+		"receiveTeleporterMessage(sourceBlockchainID="+sourceBlockchainID.Hex()+
+			", originSenderAddress="+testSender.Hex()+", message=0xdead)",
+		revert.Method(),
+	)
+
+	lines := strings.Split(strings.TrimSpace(frame.String()), "\n")
+	require.Len(t, lines, 3)
+	require.Equal(t, "CALL "+testSender.Hex()+" -> "+testTeleporter.Hex()+" 0x12345678 gasUsed=131072", lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "  STATICCALL "))
+	require.True(t, strings.HasPrefix(lines[2], "  CALL "+testTeleporter.Hex()+" -> "+testHome.Hex()))
+	require.True(t, strings.HasSuffix(lines[2], "error: TokenHome: remote not registered"))
+
+	require.Nil(t, frame.Calls[0].FirstRevert())
+}
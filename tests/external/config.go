@@ -0,0 +1,204 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package external
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// ConfigFileEnvVar names the JSON config file of the external network.
+	ConfigFileEnvVar = "EXTERNAL_NETWORK_CONFIG"
+	// FundedKeyEnvVar overrides the funded key of the config file, so that it does not have to be written to disk.
+	FundedKeyEnvVar = "EXTERNAL_NETWORK_FUNDED_KEY"
+
+	// ExternalRelayerType waits for the messages to be delivered by a separately running relayer.
+	ExternalRelayerType = "external"
+	// AggregateSignatureRelayerType delivers the messages with the funded key, using signatures
+	// aggregated by the nodes in nodeURIs.
+	AggregateSignatureRelayerType = "aggregate-signature"
+
+	defaultRelayTimeout   = 30 * time.Second
+	defaultLookBackBlocks = 500
+
+	// Flows use at least two subnets, see teleporterUtils.GetTwoSubnets.
+	minSubnets = 2
+)
+
+var (
+	errMissingFundedKey       = errors.New("missing funded key")
+	errMissingTeleporter      = errors.New("missing Teleporter contract address")
+	errMissingRPCURL          = errors.New("missing RPC URL")
+	errMissingBlockchainID    = errors.New("missing blockchain ID")
+	errMissingSubnetID        = errors.New("missing subnet ID")
+	errPrimaryNetworkSubnetID = errors.New("primary network subnet ID must be omitted")
+	errMissingRegistryAddress = errors.New("missing Teleporter registry address")
+	errMissingNodeURIs        = errors.New("missing node URIs")
+)
+
+// Config describes an already running network, with a primary network and at least two subnets
+// that have Teleporter and a Teleporter registry deployed.
+type Config struct {
+	TeleporterContractAddress common.Address `json:"teleporterContractAddress"`
+	// Hex encoded private key of an account funded with native tokens on every chain.
+	FundedKey      string        `json:"fundedKey,omitempty"`
+	PrimaryNetwork ChainConfig   `json:"primaryNetwork"`
+	Subnets        []ChainConfig `json:"subnets"`
+	Relayer        RelayerConfig `json:"relayer"`
+}
+
+// ChainConfig describes a chain of the network.
+type ChainConfig struct {
+	Name string `json:"name"`
+	// Omitted for the primary network.
+	SubnetID     ids.ID `json:"subnetID"`
+	BlockchainID ids.ID `json:"blockchainID"`
+	RPCURL       string `json:"rpcURL"`
+	WSURL        string `json:"wsURL,omitempty"`
+	// URIs of nodes of the chain, used to get aggregate Warp signatures.
+	NodeURIs                  []string       `json:"nodeURIs,omitempty"`
+	TeleporterRegistryAddress common.Address `json:"teleporterRegistryAddress"`
+}
+
+// RelayerConfig selects how Teleporter messages are delivered between the chains of the network.
+type RelayerConfig struct {
+	// ExternalRelayerType or AggregateSignatureRelayerType. Defaults to ExternalRelayerType.
+	Type string `json:"type"`
+	// Time to wait for a message to be delivered or signed, such as "30s".
+	Timeout string `json:"timeout,omitempty"`
+	// Number of blocks searched for the delivery of a message by ExternalRelayerType.
+	LookBackBlocks uint64 `json:"lookBackBlocks,omitempty"`
+}
+
+// LoadConfig reads the config file named by EXTERNAL_NETWORK_CONFIG, with the funded key
+// overridden by EXTERNAL_NETWORK_FUNDED_KEY if it is set.
+func LoadConfig() (Config, error) {
+	file := os.Getenv(ConfigFileEnvVar)
+	if file == "" {
+		return Config{}, fmt.Errorf("%s is not set", ConfigFileEnvVar)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Config{}, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to parse external network config %s: %w", file, err)
+	}
+	if fundedKey := os.Getenv(FundedKeyEnvVar); fundedKey != "" {
+		config.FundedKey = fundedKey
+	}
+	return config, config.Validate()
+}
+
+// Validate checks that every field needed to connect to the network is set.
+func (c Config) Validate() error {
+	if _, err := c.fundedKey(); err != nil {
+		return err
+	}
+	if c.TeleporterContractAddress == (common.Address{}) {
+		return errMissingTeleporter
+	}
+	if len(c.Subnets) < minSubnets {
+		return fmt.Errorf("external network must have at least %d subnets, got %d", minSubnets, len(c.Subnets))
+	}
+	if _, err := c.relayerTimeout(); err != nil {
+		return err
+	}
+
+	relayerType := c.relayerType()
+	if relayerType != ExternalRelayerType && relayerType != AggregateSignatureRelayerType {
+		return fmt.Errorf("unknown relayer type %s", relayerType)
+	}
+
+	blockchainIDs := make(map[ids.ID]struct{})
+	for i, chain := range append([]ChainConfig{c.PrimaryNetwork}, c.Subnets...) {
+		if err := chain.validate(relayerType, i == 0); err != nil {
+			return fmt.Errorf("invalid chain %s: %w", chain.Name, err)
+		}
+		if _, ok := blockchainIDs[chain.BlockchainID]; ok {
+			return fmt.Errorf("duplicate blockchain ID %s", chain.BlockchainID)
+		}
+		blockchainIDs[chain.BlockchainID] = struct{}{}
+	}
+	return nil
+}
+
+func (c ChainConfig) validate(relayerType string, isPrimaryNetwork bool) error {
+	switch {
+	case isPrimaryNetwork && c.SubnetID != constants.PrimaryNetworkID:
+		return errPrimaryNetworkSubnetID
+	case !isPrimaryNetwork && c.SubnetID == constants.PrimaryNetworkID:
+		return errMissingSubnetID
+	case c.BlockchainID == ids.Empty:
+		return errMissingBlockchainID
+	case c.RPCURL == "":
+		return errMissingRPCURL
+	case c.TeleporterRegistryAddress == (common.Address{}):
+		return errMissingRegistryAddress
+	case relayerType == AggregateSignatureRelayerType && len(c.NodeURIs) == 0:
+		return errMissingNodeURIs
+	}
+	return nil
+}
+
+func (c Config) fundedKey() (*ecdsa.PrivateKey, error) {
+	if c.FundedKey == "" {
+		return nil, errMissingFundedKey
+	}
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(c.FundedKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid funded key: %w", err)
+	}
+	return key, nil
+}
+
+func (c Config) relayerType() string {
+	if c.Relayer.Type == "" {
+		return ExternalRelayerType
+	}
+	return c.Relayer.Type
+}
+
+func (c Config) relayerTimeout() (time.Duration, error) {
+	if c.Relayer.Timeout == "" {
+		return defaultRelayTimeout, nil
+	}
+	timeout, err := time.ParseDuration(c.Relayer.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid relayer timeout: %w", err)
+	}
+	return timeout, nil
+}
+
+// NewRelayer returns the relayer selected by the config.
+func (c Config) NewRelayer() (Relayer, error) {
+	timeout, err := c.relayerTimeout()
+	if err != nil {
+		return nil, err
+	}
+	switch c.relayerType() {
+	case ExternalRelayerType:
+		lookBackBlocks := c.Relayer.LookBackBlocks
+		if lookBackBlocks == 0 {
+			lookBackBlocks = defaultLookBackBlocks
+		}
+		return &ExternalRelayer{Timeout: timeout, LookBackBlocks: lookBackBlocks}, nil
+	case AggregateSignatureRelayerType:
+		return &AggregateSignatureRelayer{Timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("unknown relayer type %s", c.Relayer.Type)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package external
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `{
+	"teleporterContractAddress": "0x253b2784c75e510dD0fF1da844684a1aC0aa5fcf",
+	"fundedKey": "0x56289e99c94b6912bfc12adc093c9b51124f0dc54ac7a766b2bc5ccf558d8027",
+	"primaryNetwork": {
+		"name": "C-Chain",
+		"blockchainID": "3pBcitaW3qwUR52G9ykRYJivVYypckZZ1hLk57gqUcoMkQV6J",
+		"rpcURL": "http://127.0.0.1:9650/ext/bc/C/rpc",
+		"teleporterRegistryAddress": "0x17aB05351fC94a1a67Bf3f56DdbB941aE6c63E25"
+	},
+	"subnets": [
+		{
+			"name": "A",
+			"subnetID": "4HJj93Sz6KqXKdHbjUYoz6NB6gCaLuDfSyiAcxu3FBffdz7XB",
+			"blockchainID": "3pAbijbiRnhdd9KZZ7jXXPFqWtDgMyNPsfsUucz7MF5ySQM52",
+			"rpcURL": "http://127.0.0.1:9652/ext/bc/3pAbijbiRnhdd9KZZ7jXXPFqWtDgMyNPsfsUucz7MF5ySQM52/rpc",
+			"teleporterRegistryAddress": "0x17aB05351fC94a1a67Bf3f56DdbB941aE6c63E25"
+		},
+		{
+			"name": "B",
+			"subnetID": "4HJkT6E4yPmxtsNNTVfGvFHczChurseQBgzEpmwEVXUzPMpud",
+			"blockchainID": "3pAd2nNoJre5CPQLH8qzTYBHQQj1swo8cP9Z7S2JbauKJHQJh",
+			"rpcURL": "http://127.0.0.1:9654/ext/bc/3pAd2nNoJre5CPQLH8qzTYBHQQj1swo8cP9Z7S2JbauKJHQJh/rpc",
+			"teleporterRegistryAddress": "0x17aB05351fC94a1a67Bf3f56DdbB941aE6c63E25"
+		}
+	],
+	"relayer": {
+		"type": "external",
+		"timeout": "1m"
+	}
+}`
+
+func writeTestConfig(t *testing.T, config string) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(file, []byte(config), 0o600))
+	t.Setenv(ConfigFileEnvVar, file)
+	t.Setenv(FundedKeyEnvVar, "")
+}
+
+func TestLoadConfig(t *testing.T) {
+	writeTestConfig(t, testConfig)
+
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, common.HexToAddress("0x253b2784c75e510dD0fF1da844684a1aC0aa5fcf"), config.TeleporterContractAddress)
+	require.Equal(t, constants.PrimaryNetworkID, config.PrimaryNetwork.SubnetID)
+	require.Len(t, config.Subnets, 2)
+	require.Equal(t, "A", config.Subnets[0].Name)
+
+	relayer, err := config.NewRelayer()
+	require.NoError(t, err)
+	require.Equal(t, &ExternalRelayer{Timeout: time.Minute, LookBackBlocks: defaultLookBackBlocks}, relayer)
+	require.False(t, relayer.SupportsIndependentRelaying())
+}
+
+func TestLoadConfigFundedKeyFromEnv(t *testing.T) {
+	writeTestConfig(t, testConfig)
+	key := "0x" + "11"
+	for len(key) < 66 {
+		key += "11"
+	}
+	t.Setenv(FundedKeyEnvVar, key)
+
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, key, config.FundedKey)
+}
+
+func TestLoadConfigNotSet(t *testing.T) {
+	t.Setenv(ConfigFileEnvVar, "")
+	_, err := LoadConfig()
+	require.ErrorContains(t, err, ConfigFileEnvVar+" is not set")
+}
+
+func TestConfigValidate(t *testing.T) {
+	writeTestConfig(t, testConfig)
+	valid, err := LoadConfig()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		modify      func(config *Config)
+		expectedErr string
+	}{
+		{
+			name:        "missing funded key",
+			modify:      func(config *Config) { config.FundedKey = "" },
+			expectedErr: errMissingFundedKey.Error(),
+		},
+		{
+			name:        "invalid funded key",
+			modify:      func(config *Config) { config.FundedKey = "0x1234" },
+			expectedErr: "invalid funded key",
+		},
+		{
+			name:        "missing Teleporter address",
+			modify:      func(config *Config) { config.TeleporterContractAddress = common.Address{} },
+			expectedErr: errMissingTeleporter.Error(),
+		},
+		{
+			name:        "one subnet",
+			modify:      func(config *Config) { config.Subnets = config.Subnets[:1] },
+			expectedErr: "at least 2 subnets",
+		},
+		{
+			name:        "missing RPC URL",
+			modify:      func(config *Config) { config.Subnets[1].RPCURL = "" },
+			expectedErr: "invalid chain B: " + errMissingRPCURL.Error(),
+		},
+		{
+			name:        "missing subnet ID",
+			modify:      func(config *Config) { config.Subnets[0].SubnetID = ids.Empty },
+			expectedErr: "invalid chain A: " + errMissingSubnetID.Error(),
+		},
+		{
+			name:        "primary network with subnet ID",
+			modify:      func(config *Config) { config.PrimaryNetwork.SubnetID = config.Subnets[0].SubnetID },
+			expectedErr: "invalid chain C-Chain: " + errPrimaryNetworkSubnetID.Error(),
+		},
+		{
+			name: "missing registry address",
+			modify: func(config *Config) {
+				config.PrimaryNetwork.TeleporterRegistryAddress = common.Address{}
+			},
+			expectedErr: "invalid chain C-Chain: " + errMissingRegistryAddress.Error(),
+		},
+		{
+			name:        "duplicate blockchain ID",
+			modify:      func(config *Config) { config.Subnets[1].BlockchainID = config.Subnets[0].BlockchainID },
+			expectedErr: "duplicate blockchain ID",
+		},
+		{
+			name:        "unknown relayer",
+			modify:      func(config *Config) { config.Relayer.Type = "carrier-pigeon" },
+			expectedErr: "unknown relayer type carrier-pigeon",
+		},
+		{
+			name:        "invalid relayer timeout",
+			modify:      func(config *Config) { config.Relayer.Timeout = "soon" },
+			expectedErr: "invalid relayer timeout",
+		},
+		{
+			name:        "aggregate signature relayer without node URIs",
+			modify:      func(config *Config) { config.Relayer.Type = AggregateSignatureRelayerType },
+			expectedErr: "invalid chain C-Chain: " + errMissingNodeURIs.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := valid
+			config.Subnets = append([]ChainConfig{}, valid.Subnets...)
+			tc.modify(&config)
+			require.ErrorContains(t, config.Validate(), tc.expectedErr)
+		})
+	}
+}
+
+func TestNewAggregateSignatureRelayer(t *testing.T) {
+	config := Config{Relayer: RelayerConfig{Type: AggregateSignatureRelayerType}}
+	relayer, err := config.NewRelayer()
+	require.NoError(t, err)
+	require.Equal(t, &AggregateSignatureRelayer{Timeout: defaultRelayTimeout}, relayer)
+	require.True(t, relayer.SupportsIndependentRelaying())
+}
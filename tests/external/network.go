@@ -0,0 +1,211 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package external
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+
+	"github.com/ava-labs/avalanchego/ids"
+	avalancheWarp "github.com/ava-labs/avalanchego/vms/platformvm/warp"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ava-labs/subnet-evm/rpc"
+	teleportermessenger "github.com/ava-labs/teleporter/abi-bindings/go/teleporter/TeleporterMessenger"
+	teleporterregistry "github.com/ava-labs/teleporter/abi-bindings/go/teleporter/registry/TeleporterRegistry"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	. "github.com/onsi/gomega"
+)
+
+var _ interfaces.Network = &Network{}
+
+// Network is an interfaces.Network of already running chains, described by a Config.
+// Teleporter messages are delivered by a pluggable Relayer.
+type Network struct {
+	teleporterContractAddress common.Address
+	primaryNetworkInfo        *interfaces.SubnetTestInfo
+	subnetsInfo               []*interfaces.SubnetTestInfo
+	fundedKey                 *ecdsa.PrivateKey
+	relayer                   Relayer
+}
+
+// NewNetworkFromConfig connects to the network described by config, with the relayer selected by the config.
+func NewNetworkFromConfig(ctx context.Context, config Config) (*Network, error) {
+	relayer, err := config.NewRelayer()
+	if err != nil {
+		return nil, err
+	}
+	return NewNetwork(ctx, config, relayer)
+}
+
+// NewNetwork connects to the network described by config, and checks that the Teleporter
+// registry of every chain is deployed. Messages are delivered by relayer.
+func NewNetwork(ctx context.Context, config Config, relayer Relayer) (*Network, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	fundedKey, err := config.fundedKey()
+	if err != nil {
+		return nil, err
+	}
+
+	primaryNetworkInfo, err := newSubnetTestInfo(ctx, config.PrimaryNetwork, config.TeleporterContractAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", config.PrimaryNetwork.Name, err)
+	}
+	subnetsInfo := make([]*interfaces.SubnetTestInfo, 0, len(config.Subnets))
+	for _, subnet := range config.Subnets {
+		subnetInfo, err := newSubnetTestInfo(ctx, subnet, config.TeleporterContractAddress)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", subnet.Name, err)
+		}
+		subnetsInfo = append(subnetsInfo, subnetInfo)
+	}
+	log.Info("Connected to external network",
+		"primaryNetwork", primaryNetworkInfo.BlockchainID,
+		"subnets", len(subnetsInfo),
+		"fundedAddress", crypto.PubkeyToAddress(fundedKey.PublicKey),
+		"independentRelaying", relayer.SupportsIndependentRelaying())
+
+	return &Network{
+		teleporterContractAddress: config.TeleporterContractAddress,
+		primaryNetworkInfo:        primaryNetworkInfo,
+		subnetsInfo:               subnetsInfo,
+		fundedKey:                 fundedKey,
+		relayer:                   relayer,
+	}, nil
+}
+
+func newSubnetTestInfo(
+	ctx context.Context,
+	chain ChainConfig,
+	teleporterContractAddress common.Address,
+) (*interfaces.SubnetTestInfo, error) {
+	// Use a cookie jar so that requests to load balanced RPC endpoints are routed to the same node,
+	// which avoids reading state from nodes that have not yet accepted recent transactions.
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	rpcClient, err := rpc.DialOptions(ctx, chain.RPCURL, rpc.WithHTTPClient(&http.Client{Jar: jar}))
+	if err != nil {
+		return nil, err
+	}
+	ethClient := ethclient.NewClient(rpcClient)
+
+	var wsClient ethclient.Client
+	if chain.WSURL != "" {
+		wsClient, err = ethclient.DialContext(ctx, chain.WSURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	evmChainID, err := ethClient.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	teleporterMessenger, err := teleportermessenger.NewTeleporterMessenger(teleporterContractAddress, ethClient)
+	if err != nil {
+		return nil, err
+	}
+	teleporterRegistry, err := teleporterregistry.NewTeleporterRegistry(chain.TeleporterRegistryAddress, ethClient)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := teleporterRegistry.LatestVersion(&bind.CallOpts{Context: ctx}); err != nil {
+		return nil, fmt.Errorf("no Teleporter registry at %s: %w", chain.TeleporterRegistryAddress, err)
+	}
+
+	return &interfaces.SubnetTestInfo{
+		SubnetName:                chain.Name,
+		SubnetID:                  chain.SubnetID,
+		BlockchainID:              chain.BlockchainID,
+		NodeURIs:                  chain.NodeURIs,
+		WSClient:                  wsClient,
+		RPCClient:                 ethClient,
+		EVMChainID:                evmChainID,
+		TeleporterRegistry:        teleporterRegistry,
+		TeleporterMessenger:       teleporterMessenger,
+		TeleporterRegistryAddress: chain.TeleporterRegistryAddress,
+	}, nil
+}
+
+func (n *Network) GetPrimaryNetworkInfo() interfaces.SubnetTestInfo {
+	return *n.primaryNetworkInfo
+}
+
+// GetSubnetsInfo returns the subnets in the order of the config.
+func (n *Network) GetSubnetsInfo() []interfaces.SubnetTestInfo {
+	subnetsInfo := make([]interfaces.SubnetTestInfo, 0, len(n.subnetsInfo))
+	for _, subnetInfo := range n.subnetsInfo {
+		subnetsInfo = append(subnetsInfo, *subnetInfo)
+	}
+	return subnetsInfo
+}
+
+func (n *Network) GetAllSubnetsInfo() []interfaces.SubnetTestInfo {
+	return append(n.GetSubnetsInfo(), n.GetPrimaryNetworkInfo())
+}
+
+func (n *Network) GetTeleporterContractAddress() common.Address {
+	return n.teleporterContractAddress
+}
+
+func (n *Network) SetTeleporterContractAddress(newTeleporterAddress common.Address) {
+	n.teleporterContractAddress = newTeleporterAddress
+	for _, subnetInfo := range append(n.subnetsInfo, n.primaryNetworkInfo) {
+		teleporterMessenger, err := teleportermessenger.NewTeleporterMessenger(
+			newTeleporterAddress,
+			subnetInfo.RPCClient,
+		)
+		Expect(err).Should(BeNil())
+		subnetInfo.TeleporterMessenger = teleporterMessenger
+	}
+}
+
+func (n *Network) GetFundedAccountInfo() (common.Address, *ecdsa.PrivateKey) {
+	return crypto.PubkeyToAddress(n.fundedKey.PublicKey), n.fundedKey
+}
+
+func (n *Network) IsExternalNetwork() bool {
+	return true
+}
+
+func (n *Network) SupportsIndependentRelaying() bool {
+	return n.relayer.SupportsIndependentRelaying()
+}
+
+func (n *Network) GetSignedMessage(
+	ctx context.Context,
+	source interfaces.SubnetTestInfo,
+	destination interfaces.SubnetTestInfo,
+	messageID ids.ID,
+) *avalancheWarp.Message {
+	signedMessage, err := getSignedMessage(ctx, source, destination, messageID)
+	Expect(err).Should(BeNil())
+	return signedMessage
+}
+
+func (n *Network) RelayMessage(
+	ctx context.Context,
+	sourceReceipt *types.Receipt,
+	source interfaces.SubnetTestInfo,
+	destination interfaces.SubnetTestInfo,
+	expectSuccess bool,
+) *types.Receipt {
+	receipt, err := n.relayer.RelayMessage(ctx, n, sourceReceipt, source, destination, expectSuccess)
+	Expect(err).Should(BeNil())
+	Expect(receipt).ShouldNot(BeNil())
+	return receipt
+}
@@ -0,0 +1,257 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	avalancheWarp "github.com/ava-labs/avalanchego/vms/platformvm/warp"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/precompile/contracts/warp"
+	warpBackend "github.com/ava-labs/subnet-evm/warp"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const relayPollInterval = 500 * time.Millisecond
+
+var (
+	errExpectedFailure     = errors.New("failed deliveries can not be observed when relaying through an external relayer")
+	errNoWarpMessage       = errors.New("no Warp message in source receipt")
+	errNoDeliveryLog       = errors.New("failed to find ReceiveCrossChainMessage log for relayed message")
+	errMultipleDeliveryLog = errors.New("found multiple ReceiveCrossChainMessage logs for relayed message")
+)
+
+// Relayer delivers the Teleporter message sent in a receipt on the source chain to the destination chain,
+// and returns the receipt of the delivery transaction.
+type Relayer interface {
+	// Whether the relayer delivers messages itself, rather than waiting for them to be delivered.
+	SupportsIndependentRelaying() bool
+
+	RelayMessage(
+		ctx context.Context,
+		network interfaces.Network,
+		sourceReceipt *types.Receipt,
+		source interfaces.SubnetTestInfo,
+		destination interfaces.SubnetTestInfo,
+		expectSuccess bool,
+	) (*types.Receipt, error)
+}
+
+var (
+	_ Relayer = &ExternalRelayer{}
+	_ Relayer = &AggregateSignatureRelayer{}
+)
+
+// ExternalRelayer waits for messages to be delivered by a separately running relayer,
+// such as AWM Relayer configured for the chains of the network.
+type ExternalRelayer struct {
+	Timeout time.Duration
+	// Number of blocks before the latest one in which the delivery transaction is searched for
+	LookBackBlocks uint64
+}
+
+func (r *ExternalRelayer) SupportsIndependentRelaying() bool {
+	return false
+}
+
+func (r *ExternalRelayer) RelayMessage(
+	ctx context.Context,
+	_ interfaces.Network,
+	sourceReceipt *types.Receipt,
+	source interfaces.SubnetTestInfo,
+	destination interfaces.SubnetTestInfo,
+	expectSuccess bool,
+) (*types.Receipt, error) {
+	if !expectSuccess {
+		return nil, errExpectedFailure
+	}
+	sendEvent, err := teleporterUtils.GetEventFromLogs(
+		sourceReceipt.Logs,
+		source.TeleporterMessenger.ParseSendCrossChainMessage,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	// Wait until the message is delivered.
+	ticker := time.NewTicker(relayPollInterval)
+	defer ticker.Stop()
+	for {
+		delivered, err := destination.TeleporterMessenger.MessageReceived(
+			&bind.CallOpts{Context: cctx},
+			sendEvent.MessageID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if delivered {
+			break
+		}
+		select {
+		case <-cctx.Done():
+			return nil, fmt.Errorf("message %s was not delivered: %w", ids.ID(sendEvent.MessageID), cctx.Err())
+		case <-ticker.C:
+		}
+	}
+
+	latestBlock, err := destination.RPCClient.BlockNumber(cctx)
+	if err != nil {
+		return nil, err
+	}
+	var startBlock uint64
+	if latestBlock > r.LookBackBlocks {
+		startBlock = latestBlock - r.LookBackBlocks
+	}
+
+	logs, err := destination.TeleporterMessenger.FilterReceiveCrossChainMessage(
+		&bind.FilterOpts{Start: startBlock, Context: cctx},
+		[][32]byte{sendEvent.MessageID},
+		[][32]byte{source.BlockchainID},
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer logs.Close()
+	if !logs.Next() {
+		return nil, errNoDeliveryLog
+	}
+	txHash := logs.Event.Raw.TxHash
+	if logs.Next() {
+		return nil, errMultipleDeliveryLog
+	}
+
+	return teleporterUtils.WaitMined(cctx, destination.RPCClient, txHash)
+}
+
+// AggregateSignatureRelayer delivers messages with the funded key of the network, using
+// Warp signatures aggregated by the nodes of the source chain.
+type AggregateSignatureRelayer struct {
+	Timeout time.Duration
+}
+
+func (r *AggregateSignatureRelayer) SupportsIndependentRelaying() bool {
+	return true
+}
+
+func (r *AggregateSignatureRelayer) RelayMessage(
+	ctx context.Context,
+	network interfaces.Network,
+	sourceReceipt *types.Receipt,
+	source interfaces.SubnetTestInfo,
+	destination interfaces.SubnetTestInfo,
+	expectSuccess bool,
+) (*types.Receipt, error) {
+	sendEvent, err := teleporterUtils.GetEventFromLogs(
+		sourceReceipt.Logs,
+		source.TeleporterMessenger.ParseSendCrossChainMessage,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var unsignedMessage *avalancheWarp.UnsignedMessage
+	for _, txLog := range sourceReceipt.Logs {
+		if txLog.Address != warp.Module.Address {
+			continue
+		}
+		unsignedMessage, err = warp.UnpackSendWarpEventDataToMessage(txLog.Data)
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	if unsignedMessage == nil {
+		return nil, errNoWarpMessage
+	}
+
+	signedMessage, err := r.aggregateSignatures(ctx, source, destination, unsignedMessage.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	_, fundedKey := network.GetFundedAccountInfo()
+	signedTx := teleporterUtils.CreateReceiveCrossChainMessageTransaction(
+		ctx,
+		signedMessage,
+		sendEvent.Message.RequiredGasLimit,
+		network.GetTeleporterContractAddress(),
+		fundedKey,
+		destination,
+	)
+	if !expectSuccess {
+		return teleporterUtils.SendTransactionAndWaitForFailure(ctx, destination, signedTx), nil
+	}
+	return teleporterUtils.SendTransactionAndWaitForSuccess(ctx, destination, signedTx), nil
+}
+
+// aggregateSignatures retries until the nodes of the source chain have accepted the block
+// of the message, and have aggregated enough signatures for it.
+func (r *AggregateSignatureRelayer) aggregateSignatures(
+	ctx context.Context,
+	source interfaces.SubnetTestInfo,
+	destination interfaces.SubnetTestInfo,
+	unsignedMessageID ids.ID,
+) (*avalancheWarp.Message, error) {
+	cctx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(relayPollInterval)
+	defer ticker.Stop()
+	for {
+		signedMessage, err := getSignedMessage(cctx, source, destination, unsignedMessageID)
+		if err == nil {
+			return signedMessage, nil
+		}
+		log.Debug("Failed to aggregate Warp signatures", "messageID", unsignedMessageID, "err", err)
+		select {
+		case <-cctx.Done():
+			return nil, fmt.Errorf("failed to aggregate signatures for message %s: %w", unsignedMessageID, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+func getSignedMessage(
+	ctx context.Context,
+	source interfaces.SubnetTestInfo,
+	destination interfaces.SubnetTestInfo,
+	unsignedMessageID ids.ID,
+) (*avalancheWarp.Message, error) {
+	if len(source.NodeURIs) == 0 {
+		return nil, errMissingNodeURIs
+	}
+	warpClient, err := warpBackend.NewClient(source.NodeURIs[0], source.BlockchainID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	// Messages from the primary network are signed by the validators of the destination subnet.
+	signingSubnetID := source.SubnetID
+	if source.SubnetID == constants.PrimaryNetworkID {
+		signingSubnetID = destination.SubnetID
+	}
+
+	signedMessageBytes, err := warpClient.GetMessageAggregateSignature(
+		ctx,
+		unsignedMessageID,
+		warp.WarpDefaultQuorumNumerator,
+		signingSubnetID.String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return avalancheWarp.ParseMessage(signedMessageBytes)
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package suite
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/external"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/flows"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/format"
+)
+
+const (
+	erc20TokenHomeLabel   = "ERC20TokenHome"
+	erc20TokenRemoteLabel = "ERC20TokenRemote"
+	nativeTokenHomeLabel  = "NativeTokenHome"
+	multiHopLabel         = "MultiHop"
+	sendAndCallLabel      = "SendAndCall"
+	upgradabilityLabel    = "Upgradability"
+	fallbackLabel         = "Fallback"
+	topologyLabel         = "Topology"
+)
+
+var ExternalNetworkInstance *external.Network
+
+func TestExternalE2E(t *testing.T) {
+	if os.Getenv(external.ConfigFileEnvVar) == "" {
+		t.Skipf("Environment variable %s not set; skipping external network E2E tests", external.ConfigFileEnvVar)
+	}
+	format.MaxLength = 10000
+
+	RegisterFailHandler(ginkgo.Fail)
+	ginkgo.RunSpecs(t, "Avalanche Interchain Token Transfer external network e2e test")
+}
+
+var _ = ginkgo.BeforeSuite(func() {
+	config, err := external.LoadConfig()
+	Expect(err).Should(BeNil())
+
+	ExternalNetworkInstance, err = external.NewNetworkFromConfig(context.Background(), config)
+	Expect(err).Should(BeNil())
+	log.Info("Set up ginkgo before suite")
+})
+
+// Flows that deploy a NativeTokenRemote are not run, because they require deployer keys
+// that are allowed to mint native tokens in the genesis of the subnets.
+var _ = ginkgo.Describe("[Avalanche Interchain Token Transfer external network tests]", func() {
+	ginkgo.It("Transfer an ERC20 token between two Subnets",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel),
+		func() {
+			flows.ERC20TokenHomeERC20TokenRemote(ExternalNetworkInstance)
+		})
+	ginkgo.It("Transfer a native token to an ERC20 token",
+		ginkgo.Label(nativeTokenHomeLabel, erc20TokenRemoteLabel),
+		func() {
+			flows.NativeTokenHomeERC20TokenRemote(ExternalNetworkInstance)
+		})
+	ginkgo.It("Transfer an ERC20 token with ERC20TokenHome multi-hop",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, multiHopLabel),
+		func() {
+			flows.ERC20TokenHomeERC20TokenRemoteMultiHop(ExternalNetworkInstance)
+		})
+	ginkgo.It("Transfer a native token with NativeTokenHome multi-hop",
+		ginkgo.Label(nativeTokenHomeLabel, erc20TokenRemoteLabel, multiHopLabel),
+		func() {
+			flows.NativeTokenHomeERC20TokenRemoteMultiHop(ExternalNetworkInstance)
+		})
+	ginkgo.It("Transfer an ERC20 token using sendAndCall",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, sendAndCallLabel),
+		func() {
+			flows.ERC20TokenHomeERC20TokenRemoteSendAndCall(ExternalNetworkInstance)
+		})
+	ginkgo.It("Transparent proxy upgrade",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, upgradabilityLabel),
+		func() {
+			flows.TransparentUpgradeableProxy(ExternalNetworkInstance)
+		})
+	ginkgo.It("Fallback recipient on failed sendAndCall calls",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, sendAndCallLabel, fallbackLabel),
+		func() {
+			flows.ERC20TokenHomeERC20TokenRemoteCallFailed(ExternalNetworkInstance)
+		})
+	ginkgo.It("Token accounting matches the reference model",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, multiHopLabel),
+		func() {
+			flows.TokenAccountingModelDifferential(ExternalNetworkInstance)
+		})
+	ginkgo.It("Transfer an ERC20 token to many remotes",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, multiHopLabel, topologyLabel),
+		func() {
+			flows.ERC20TokenHomeManyRemotes(ExternalNetworkInstance)
+		})
+	ginkgo.It("Transfer ERC20 tokens with a token home on every chain",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, multiHopLabel, topologyLabel),
+		func() {
+			flows.ERC20TokenHomeOnEveryChain(ExternalNetworkInstance)
+		})
+})
@@ -0,0 +1,638 @@
+package flows
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"math/rand"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	nativetokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemote"
+	tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/TokenRemote"
+	exampleerc20 "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/mocks/ExampleERC20Decimals"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/diagnostics"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/model"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	// Remote A scales amounts down, and remote B scales them up
+	differentialHomeDecimals    = 12
+	differentialRemoteADecimals = 6
+	differentialRemoteBDecimals = 14
+
+	differentialAccounts   = 3
+	differentialOperations = 24
+)
+
+type differentialRemote struct {
+	subnet      interfaces.SubnetTestInfo
+	transferrer model.Transferrer
+	requiredGas *big.Int
+	// Set for the ERC20 remotes, which are also the source of sends back to the home
+	tokenRemote *erc20tokenremote.ERC20TokenRemote
+	// Set for the collateralized remote
+	nativeTokenRemote *nativetokenremote.NativeTokenRemote
+}
+
+type differentialAccount struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+/**
+ * Deploy an ERC20 token home on the primary network
+ * Deploy ERC20 token remotes to Subnet A and Subnet B, with fewer and more decimals than the home token
+ * Deploy a native token remote to Subnet A with an initial reserve imbalance, and collateralize it
+ * Run a random sequence of sends from the home, sends back to the home, and multi-hop sends
+ * through both the contracts and the reference model of their accounting
+ * Check after each operation that the balances, transferred balances and emitted events match the model
+ */
+func TokenAccountingModelDifferential(network interfaces.Network) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	subnetAInfo, subnetBInfo := teleporterUtils.GetTwoSubnets(network)
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	seed := ginkgo.GinkgoRandomSeed()
+	log.Info("Running token accounting differential test", "seed", seed)
+	rng := rand.New(rand.NewSource(seed))
+
+	exampleERC20Address, exampleERC20 := utils.DeployExampleERC20(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		differentialHomeDecimals,
+	)
+	erc20TokenHomeAddress, erc20TokenHome := utils.DeployERC20TokenHome(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		fundedAddress,
+		exampleERC20Address,
+		differentialHomeDecimals,
+	)
+	home := model.Transferrer{BlockchainID: cChainInfo.BlockchainID, Address: erc20TokenHomeAddress}
+	m := model.New(home, differentialHomeDecimals)
+
+	subnets := map[ids.ID]interfaces.SubnetTestInfo{cChainInfo.BlockchainID: cChainInfo}
+	var remotes []differentialRemote
+	for _, remoteInfo := range []struct {
+		subnet   interfaces.SubnetTestInfo
+		decimals uint8
+	}{
+		{subnetAInfo, differentialRemoteADecimals},
+		{subnetBInfo, differentialRemoteBDecimals},
+	} {
+		remoteAddress, tokenRemote := utils.DeployERC20TokenRemote(
+			ctx,
+			fundedKey,
+			remoteInfo.subnet,
+			fundedAddress,
+			cChainInfo.BlockchainID,
+			erc20TokenHomeAddress,
+			differentialHomeDecimals,
+			"Differential",
+			"DIFF",
+			remoteInfo.decimals,
+		)
+		remote := differentialRemote{
+			subnet:      remoteInfo.subnet,
+			transferrer: model.Transferrer{BlockchainID: remoteInfo.subnet.BlockchainID, Address: remoteAddress},
+			requiredGas: utils.DefaultERC20RequiredGas,
+			tokenRemote: tokenRemote,
+		}
+		remotes = append(remotes, remote)
+		subnets[remoteInfo.subnet.BlockchainID] = remoteInfo.subnet
+
+		r := m.AddRemote(remote.transferrer, remoteInfo.decimals, big.NewInt(0))
+		Expect(m.RegisterWithHome(remote.transferrer)).Should(Succeed())
+		_, _, err := m.Relay()
+		Expect(err).Should(BeNil())
+		utils.RegisterTokenRemoteOnHome(
+			ctx,
+			network,
+			cChainInfo,
+			erc20TokenHomeAddress,
+			remoteInfo.subnet,
+			remoteAddress,
+			big.NewInt(0),
+			r.TokenMultiplier,
+			r.MultiplyOnRemote,
+		)
+	}
+
+	// The native token remote needs collateral for its initial reserve imbalance before it can receive tokens
+	initialReserveImbalance := utils.ParseAmount("1000", utils.NativeTokenDecimals)
+	nativeRemoteAddress, nativeTokenRemote := utils.DeployNativeTokenRemote(
+		ctx,
+		subnetAInfo,
+		"DIFFN",
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		differentialHomeDecimals,
+		initialReserveImbalance,
+		big.NewInt(1),
+	)
+	nativeRemote := differentialRemote{
+		subnet:            subnetAInfo,
+		transferrer:       model.Transferrer{BlockchainID: subnetAInfo.BlockchainID, Address: nativeRemoteAddress},
+		requiredGas:       utils.DefaultNativeTokenRequiredGas,
+		nativeTokenRemote: nativeTokenRemote,
+	}
+	r := m.AddRemote(nativeRemote.transferrer, utils.NativeTokenDecimals, initialReserveImbalance)
+	Expect(m.RegisterWithHome(nativeRemote.transferrer)).Should(Succeed())
+	_, _, err := m.Relay()
+	Expect(err).Should(BeNil())
+	utils.RegisterTokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetAInfo,
+		nativeRemoteAddress,
+		initialReserveImbalance,
+		r.TokenMultiplier,
+		r.MultiplyOnRemote,
+	)
+	sources := remotes
+	remotes = append(remotes, nativeRemote)
+
+	// Fund each account with gas on every chain, and with home tokens
+	var accounts []differentialAccount
+	for i := 0; i < differentialAccounts; i++ {
+		key, err := crypto.GenerateKey()
+		Expect(err).Should(BeNil())
+		account := differentialAccount{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+		accounts = append(accounts, account)
+
+		for _, subnet := range subnets {
+			teleporterUtils.SendNativeTransfer(ctx, subnet, fundedKey, account.address, big.NewInt(1e18))
+		}
+		amount := big.NewInt(1e18)
+		opts, err := bind.NewKeyedTransactorWithChainID(fundedKey, cChainInfo.EVMChainID)
+		Expect(err).Should(BeNil())
+		tx, err := exampleERC20.Transfer(opts, account.address, amount)
+		Expect(err).Should(BeNil())
+		teleporterUtils.WaitForTransactionSuccess(ctx, cChainInfo, tx.Hash())
+		m.Home.SetBalance(account.address, amount)
+	}
+	expectStateMatchesModel(m, erc20TokenHome, exampleERC20, remotes, accounts)
+
+	collateralizeDifferentialRemote(
+		ctx,
+		m,
+		cChainInfo,
+		erc20TokenHome,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		exampleERC20Address,
+		nativeRemote,
+		remotes,
+		accounts,
+	)
+
+	for i := 0; i < differentialOperations; i++ {
+		sender := accounts[rng.Intn(len(accounts))]
+		recipient := accounts[rng.Intn(len(accounts))]
+		source := sources[rng.Intn(len(sources))]
+		destination := remotes[rng.Intn(len(remotes))]
+		remoteBalance := m.Remotes[source.transferrer].Balance(sender.address)
+
+		var (
+			receipt  *types.Receipt
+			expected []model.Event
+			err      error
+		)
+		switch op := rng.Intn(3); {
+		case op == 0 || remoteBalance.Sign() == 0:
+			amount := randomAmount(rng, m.Home.Balance(sender.address), m.Home.Settings(destination.transferrer))
+			log.Info("Sending from home", "remote", destination.transferrer, "amount", amount)
+			expected, err = m.SendFromHome(sender.address, destination.transferrer, recipient.address, amount)
+			input := erc20tokenhome.SendTokensInput{
+				DestinationBlockchainID:            destination.transferrer.BlockchainID,
+				DestinationTokenTransferrerAddress: destination.transferrer.Address,
+				Recipient:                          recipient.address,
+				PrimaryFeeTokenAddress:             exampleERC20Address,
+				PrimaryFee:                         big.NewInt(0),
+				SecondaryFee:                       big.NewInt(0),
+				RequiredGasLimit:                   destination.requiredGas,
+			}
+			utils.ERC20Approve(ctx, exampleERC20, erc20TokenHomeAddress, amount, cChainInfo, sender.key)
+			receipt = sendOrExpectRevert(
+				ctx,
+				cChainInfo,
+				sender.key,
+				err,
+				func(opts *bind.TransactOpts) (*types.Transaction, error) {
+					return erc20TokenHome.Send(opts, input, amount)
+				},
+			)
+		case op == 1 || source.transferrer == destination.transferrer:
+			amount := new(big.Int).Add(big.NewInt(1), new(big.Int).Rand(rng, remoteBalance))
+			log.Info("Sending back to home", "remote", source.transferrer, "amount", amount)
+			expected, err = m.SendFromRemote(source.transferrer, sender.address, recipient.address, amount)
+			input := erc20tokenremote.SendTokensInput{
+				DestinationBlockchainID:            cChainInfo.BlockchainID,
+				DestinationTokenTransferrerAddress: erc20TokenHomeAddress,
+				Recipient:                          recipient.address,
+				PrimaryFeeTokenAddress:             source.transferrer.Address,
+				PrimaryFee:                         big.NewInt(0),
+				SecondaryFee:                       big.NewInt(0),
+				RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+			}
+			receipt = sendFromDifferentialRemote(ctx, source, sender.key, input, amount, err)
+		default:
+			amount := new(big.Int).Add(big.NewInt(1), new(big.Int).Rand(rng, remoteBalance))
+			secondaryFee := new(big.Int).Rand(rng, new(big.Int).Add(amount, big.NewInt(1)))
+			log.Info(
+				"Sending multi-hop",
+				"source", source.transferrer,
+				"destination", destination.transferrer,
+				"amount", amount,
+				"secondaryFee", secondaryFee,
+			)
+			expected, err = m.SendMultiHop(
+				source.transferrer,
+				sender.address,
+				destination.transferrer,
+				recipient.address,
+				amount,
+				secondaryFee,
+				recipient.address,
+			)
+			input := erc20tokenremote.SendTokensInput{
+				DestinationBlockchainID:            destination.transferrer.BlockchainID,
+				DestinationTokenTransferrerAddress: destination.transferrer.Address,
+				Recipient:                          recipient.address,
+				PrimaryFeeTokenAddress:             source.transferrer.Address,
+				PrimaryFee:                         big.NewInt(0),
+				SecondaryFee:                       secondaryFee,
+				RequiredGasLimit:                   destination.requiredGas,
+				MultiHopFallback:                   recipient.address,
+			}
+			receipt = sendFromDifferentialRemote(ctx, source, sender.key, input, amount, err)
+		}
+		if err != nil {
+			log.Info("Operation reverted as expected", "reason", err)
+			expectStateMatchesModel(m, erc20TokenHome, exampleERC20, remotes, accounts)
+			continue
+		}
+		expectEventsMatchModel(receipt, expected[0].Emitter.BlockchainID, expected, m, erc20TokenHome, remotes)
+
+		// Relay the message sent by the operation, and any message sent on its delivery
+		for len(m.Pending()) != 0 {
+			message, expected, err := m.Relay()
+			sourceSubnet := subnets[message.Source.BlockchainID]
+			destinationSubnet := subnets[message.Destination.BlockchainID]
+			receipt = network.RelayMessage(ctx, receipt, sourceSubnet, destinationSubnet, true)
+			if err != nil {
+				_, parseErr := teleporterUtils.GetEventFromLogs(
+					receipt.Logs,
+					destinationSubnet.TeleporterMessenger.ParseMessageExecutionFailed,
+				)
+				Expect(parseErr).Should(BeNil(), "expected message execution to fail with %s", err)
+				continue
+			}
+			diagnostics.ExpectMessageExecuted(ctx, destinationSubnet, receipt)
+			expectEventsMatchModel(
+				receipt,
+				message.Destination.BlockchainID,
+				expected,
+				m,
+				erc20TokenHome,
+				remotes,
+			)
+		}
+		expectStateMatchesModel(m, erc20TokenHome, exampleERC20, remotes, accounts)
+	}
+}
+
+// collateralizeDifferentialRemote checks that the home rejects sends to the remote until the collateral it needs
+// is added, and adds it in two deposits, the last of which exceeds the collateral still needed and is refunded in
+// part. No collateral can be added once the remote is collateralized.
+func collateralizeDifferentialRemote(
+	ctx context.Context,
+	m *model.Model,
+	cChainInfo interfaces.SubnetTestInfo,
+	erc20TokenHome *erc20tokenhome.ERC20TokenHome,
+	erc20TokenHomeAddress common.Address,
+	exampleERC20 *exampleerc20.ExampleERC20Decimals,
+	exampleERC20Address common.Address,
+	remote differentialRemote,
+	remotes []differentialRemote,
+	accounts []differentialAccount,
+) {
+	sender := accounts[0]
+	amount := big.NewInt(1e6)
+	_, err := m.SendFromHome(sender.address, remote.transferrer, sender.address, amount)
+	Expect(err).Should(MatchError(model.ErrCollateralNeeded))
+	input := erc20tokenhome.SendTokensInput{
+		DestinationBlockchainID:            remote.transferrer.BlockchainID,
+		DestinationTokenTransferrerAddress: remote.transferrer.Address,
+		Recipient:                          sender.address,
+		PrimaryFeeTokenAddress:             exampleERC20Address,
+		PrimaryFee:                         big.NewInt(0),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   remote.requiredGas,
+	}
+	utils.ERC20Approve(ctx, exampleERC20, erc20TokenHomeAddress, amount, cChainInfo, sender.key)
+	sendOrExpectRevert(ctx, cChainInfo, sender.key, err, func(opts *bind.TransactOpts) (*types.Transaction, error) {
+		return erc20TokenHome.Send(opts, input, amount)
+	})
+
+	collateralNeeded := m.Home.Settings(remote.transferrer).CollateralNeeded
+	Expect(collateralNeeded.Sign()).Should(Equal(1))
+	for _, collateral := range []*big.Int{
+		new(big.Int).Div(collateralNeeded, big.NewInt(3)),
+		collateralNeeded,
+		big.NewInt(1),
+	} {
+		log.Info("Adding collateral", "remote", remote.transferrer, "amount", collateral)
+		expected, err := m.AddCollateral(sender.address, remote.transferrer, collateral)
+		utils.ERC20Approve(ctx, exampleERC20, erc20TokenHomeAddress, collateral, cChainInfo, sender.key)
+		receipt := sendOrExpectRevert(
+			ctx,
+			cChainInfo,
+			sender.key,
+			err,
+			func(opts *bind.TransactOpts) (*types.Transaction, error) {
+				return erc20TokenHome.AddCollateral(
+					opts,
+					remote.transferrer.BlockchainID,
+					remote.transferrer.Address,
+					collateral,
+				)
+			},
+		)
+		if err == nil {
+			expectEventsMatchModel(receipt, cChainInfo.BlockchainID, expected, m, erc20TokenHome, remotes)
+		}
+		expectStateMatchesModel(m, erc20TokenHome, exampleERC20, remotes, accounts)
+	}
+	Expect(m.Home.Settings(remote.transferrer).CollateralNeeded.Sign()).Should(Equal(0))
+}
+
+// randomAmount returns a random amount of home tokens up to a quarter of balance,
+// or, one time in four, an amount that scales down to zero on the remote if there is one.
+func randomAmount(rng *rand.Rand, balance *big.Int, settings model.RemoteSettings) *big.Int {
+	if !settings.MultiplyOnRemote && settings.TokenMultiplier.Cmp(big.NewInt(1)) > 0 && rng.Intn(4) == 0 {
+		belowMultiplier := new(big.Int).Sub(settings.TokenMultiplier, big.NewInt(1))
+		return new(big.Int).Add(big.NewInt(1), new(big.Int).Rand(rng, belowMultiplier))
+	}
+	return new(big.Int).Add(big.NewInt(1), new(big.Int).Rand(rng, new(big.Int).Div(balance, big.NewInt(4))))
+}
+
+func sendFromDifferentialRemote(
+	ctx context.Context,
+	remote differentialRemote,
+	senderKey *ecdsa.PrivateKey,
+	input erc20tokenremote.SendTokensInput,
+	amount *big.Int,
+	expectedErr error,
+) *types.Receipt {
+	opts, err := bind.NewKeyedTransactorWithChainID(senderKey, remote.subnet.EVMChainID)
+	Expect(err).Should(BeNil())
+	tx, err := remote.tokenRemote.Approve(opts, remote.transferrer.Address, amount)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, remote.subnet, tx.Hash())
+
+	return sendOrExpectRevert(
+		ctx,
+		remote.subnet,
+		senderKey,
+		expectedErr,
+		func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return remote.tokenRemote.Send(opts, input, amount)
+		},
+	)
+}
+
+// sendOrExpectRevert sends the transaction and waits for it to succeed if expectedErr is nil.
+// Otherwise, it checks that the transaction reverts with the reason of expectedErr without sending it.
+func sendOrExpectRevert(
+	ctx context.Context,
+	subnet interfaces.SubnetTestInfo,
+	senderKey *ecdsa.PrivateKey,
+	expectedErr error,
+	send func(opts *bind.TransactOpts) (*types.Transaction, error),
+) *types.Receipt {
+	opts, err := bind.NewKeyedTransactorWithChainID(senderKey, subnet.EVMChainID)
+	Expect(err).Should(BeNil())
+	if expectedErr != nil {
+		opts.NoSend = true
+		_, err = send(opts)
+		Expect(err).ShouldNot(BeNil(), "expected revert with %s", expectedErr)
+		if expectedErr != model.ErrInsufficientBalance {
+			Expect(err.Error()).Should(ContainSubstring(expectedErr.Error()))
+		}
+		return nil
+	}
+	tx, err := send(opts)
+	Expect(err).Should(BeNil())
+	return teleporterUtils.WaitForTransactionSuccess(ctx, subnet, tx.Hash())
+}
+
+func expectStateMatchesModel(
+	m *model.Model,
+	erc20TokenHome *erc20tokenhome.ERC20TokenHome,
+	exampleERC20 *exampleerc20.ExampleERC20Decimals,
+	remotes []differentialRemote,
+	accounts []differentialAccount,
+) {
+	balance, err := exampleERC20.BalanceOf(&bind.CallOpts{}, m.Home.Address)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(balance, m.Home.Balance(m.Home.Address))
+
+	for _, remote := range remotes {
+		settings, err := erc20TokenHome.GetRemoteTokenTransferrerSettings(
+			&bind.CallOpts{},
+			remote.transferrer.BlockchainID,
+			remote.transferrer.Address,
+		)
+		Expect(err).Should(BeNil())
+		expectedSettings := m.Home.Settings(remote.transferrer)
+		Expect(settings.Registered).Should(Equal(expectedSettings.Registered))
+		Expect(settings.MultiplyOnRemote).Should(Equal(expectedSettings.MultiplyOnRemote))
+		teleporterUtils.ExpectBigEqual(settings.CollateralNeeded, expectedSettings.CollateralNeeded)
+		teleporterUtils.ExpectBigEqual(settings.TokenMultiplier, expectedSettings.TokenMultiplier)
+
+		transferredBalance, err := erc20TokenHome.GetTransferredBalance(
+			&bind.CallOpts{},
+			remote.transferrer.BlockchainID,
+			remote.transferrer.Address,
+		)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(transferredBalance, m.Home.TransferredBalance(remote.transferrer))
+
+		// The balances of the native token remote also pay for gas, so only its collateralization is compared
+		if remote.nativeTokenRemote != nil {
+			isCollateralized, err := remote.nativeTokenRemote.GetIsCollateralized(&bind.CallOpts{})
+			Expect(err).Should(BeNil())
+			Expect(isCollateralized).Should(Equal(m.Remotes[remote.transferrer].IsCollateralized()))
+			continue
+		}
+		totalSupply, err := remote.tokenRemote.TotalSupply(&bind.CallOpts{})
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(totalSupply, m.Remotes[remote.transferrer].TotalSupply())
+		isCollateralized, err := remote.tokenRemote.GetIsCollateralized(&bind.CallOpts{})
+		Expect(err).Should(BeNil())
+		Expect(isCollateralized).Should(Equal(m.Remotes[remote.transferrer].IsCollateralized()))
+	}
+
+	for _, account := range accounts {
+		balance, err := exampleERC20.BalanceOf(&bind.CallOpts{}, account.address)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(balance, m.Home.Balance(account.address))
+		for _, remote := range remotes {
+			if remote.tokenRemote == nil {
+				continue
+			}
+			balance, err := remote.tokenRemote.BalanceOf(&bind.CallOpts{}, account.address)
+			Expect(err).Should(BeNil())
+			teleporterUtils.ExpectBigEqual(balance, m.Remotes[remote.transferrer].Balance(account.address))
+		}
+	}
+}
+
+// expectEventsMatchModel checks that the accounting events emitted by the token transferrers
+// on the chain of the receipt are the events expected by the model, in order.
+func expectEventsMatchModel(
+	receipt *types.Receipt,
+	blockchainID ids.ID,
+	expected []model.Event,
+	m *model.Model,
+	erc20TokenHome *erc20tokenhome.ERC20TokenHome,
+	remotes []differentialRemote,
+) {
+	emitted := []string{}
+	for _, l := range receipt.Logs {
+		if blockchainID == m.Home.BlockchainID && l.Address == m.Home.Address {
+			if event, ok := parseHomeEvent(m.Home.Transferrer, erc20TokenHome, *l); ok {
+				emitted = append(emitted, event.String())
+			}
+			continue
+		}
+		for _, remote := range remotes {
+			if remote.transferrer != (model.Transferrer{BlockchainID: blockchainID, Address: l.Address}) {
+				continue
+			}
+			if event, ok := parseRemoteEvent(remote.transferrer, *l); ok {
+				emitted = append(emitted, event.String())
+			}
+		}
+	}
+
+	expectedEvents := make([]string, 0, len(expected))
+	for _, event := range expected {
+		expectedEvents = append(expectedEvents, event.String())
+	}
+	Expect(emitted).Should(Equal(expectedEvents))
+}
+
+func parseHomeEvent(
+	home model.Transferrer,
+	erc20TokenHome *erc20tokenhome.ERC20TokenHome,
+	l types.Log,
+) (model.Event, bool) {
+	if event, err := erc20TokenHome.ParseTokensSent(l); err == nil {
+		return model.Event{
+			Kind:    model.TokensSent,
+			Emitter: home,
+			Sender:  event.Sender,
+			Destination: model.Transferrer{
+				BlockchainID: event.Input.DestinationBlockchainID,
+				Address:      event.Input.DestinationTokenTransferrerAddress,
+			},
+			Recipient: event.Input.Recipient,
+			Amount:    event.Amount,
+		}, true
+	}
+	if event, err := erc20TokenHome.ParseTokensRouted(l); err == nil {
+		return model.Event{
+			Kind:    model.TokensRouted,
+			Emitter: home,
+			Destination: model.Transferrer{
+				BlockchainID: event.Input.DestinationBlockchainID,
+				Address:      event.Input.DestinationTokenTransferrerAddress,
+			},
+			Recipient: event.Input.Recipient,
+			Amount:    event.Amount,
+		}, true
+	}
+	if event, err := erc20TokenHome.ParseTokensWithdrawn(l); err == nil {
+		return model.Event{
+			Kind:      model.TokensWithdrawn,
+			Emitter:   home,
+			Recipient: event.Recipient,
+			Amount:    event.Amount,
+		}, true
+	}
+	if event, err := erc20TokenHome.ParseCollateralAdded(l); err == nil {
+		return model.Event{
+			Kind:    model.CollateralAdded,
+			Emitter: home,
+			Remote: model.Transferrer{
+				BlockchainID: event.RemoteBlockchainID,
+				Address:      event.RemoteTokenTransferrerAddress,
+			},
+			Amount:    event.Amount,
+			Remaining: event.Remaining,
+		}, true
+	}
+	if event, err := erc20TokenHome.ParseRemoteRegistered(l); err == nil {
+		return model.Event{
+			Kind:    model.RemoteRegistered,
+			Emitter: home,
+			Remote: model.Transferrer{
+				BlockchainID: event.RemoteBlockchainID,
+				Address:      event.RemoteTokenTransferrerAddress,
+			},
+			Remaining:           event.InitialCollateralNeeded,
+			RemoteTokenDecimals: event.TokenDecimals,
+		}, true
+	}
+	return model.Event{}, false
+}
+
+// parseRemoteEvent parses the accounting events of both ERC20 and native token remotes, which are declared by
+// the TokenRemote they inherit from.
+func parseRemoteEvent(remote model.Transferrer, l types.Log) (model.Event, bool) {
+	tokenRemote, err := tokenremote.NewTokenRemoteFilterer(l.Address, nil)
+	Expect(err).Should(BeNil())
+	if event, err := tokenRemote.ParseTokensSent(l); err == nil {
+		return model.Event{
+			Kind:    model.TokensSent,
+			Emitter: remote,
+			Sender:  event.Sender,
+			Destination: model.Transferrer{
+				BlockchainID: event.Input.DestinationBlockchainID,
+				Address:      event.Input.DestinationTokenTransferrerAddress,
+			},
+			Recipient: event.Input.Recipient,
+			Amount:    event.Amount,
+		}, true
+	}
+	if event, err := tokenRemote.ParseTokensWithdrawn(l); err == nil {
+		return model.Event{
+			Kind:      model.TokensWithdrawn,
+			Emitter:   remote,
+			Recipient: event.Recipient,
+			Amount:    event.Amount,
+		}, true
+	}
+	return model.Event{}, false
+}
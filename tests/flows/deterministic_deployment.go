@@ -0,0 +1,393 @@
+package flows
+
+import (
+	"context"
+	"math/big"
+
+	proxyadmin "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/ProxyAdmin"
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	nativetokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	erc20tokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemoteUpgradeable"
+	nativetokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemoteUpgradeable"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/create2"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	. "github.com/onsi/gomega"
+)
+
+/**
+ * Deploy the CREATE2 factory on the primary network, Subnet A and Subnet B with its keyless transaction
+ * Deploy an ERC20TokenHome on the primary network through the factory, at its predicted address
+ * Deploy an ERC20TokenRemoteUpgradeable implementation to Subnet A and Subnet B at the same address
+ * Deploy TransparentUpgradeableProxies of the implementation to Subnet A and Subnet B at the same predicted
+ * address, although their initialization differs by the TeleporterRegistry of each subnet
+ * Check that deploying the same contracts again sends no transaction
+ * Transfers C-Chain example ERC20 tokens to Subnet A, and from Subnet A to Subnet B
+ * Deploy a NativeTokenHome on the primary network through the factory, at its predicted address
+ * Deploy a NativeTokenRemoteUpgradeable proxy to Subnet A at the address allowed to mint native tokens in the
+ * genesis file
+ * Transfers C-Chain native tokens to Subnet A
+ */
+func DeterministicDeployment(network interfaces.Network) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	subnetAInfo, subnetBInfo := teleporterUtils.GetTwoSubnets(network)
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	// The factory is at the same address on every chain, regardless of the state of the chain
+	factory := create2.DefaultFactoryDeployment.Factory
+	deployers := make(map[ids.ID]*create2.Deployer)
+	for _, subnet := range []interfaces.SubnetTestInfo{cChainInfo, subnetAInfo, subnetBInfo} {
+		err := create2.DeployFactory(ctx, subnet.RPCClient, fundedKey, create2.DefaultFactoryDeployment)
+		Expect(err).Should(BeNil())
+		// Deploying the factory again is a no-op
+		err = create2.DeployFactory(ctx, subnet.RPCClient, fundedKey, create2.DefaultFactoryDeployment)
+		Expect(err).Should(BeNil())
+
+		deployer, err := create2.NewDeployer(ctx, subnet.RPCClient, fundedKey, factory)
+		Expect(err).Should(BeNil())
+		deployers[subnet.BlockchainID] = deployer
+	}
+
+	// Deploy an ExampleERC20 on the primary network as the token to be transferred
+	exampleERC20Address, exampleERC20 := utils.DeployExampleERC20(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		erc20TokenHomeDecimals,
+	)
+	tokenName, err := exampleERC20.Name(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	tokenSymbol, err := exampleERC20.Symbol(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	tokenDecimals, err := exampleERC20.Decimals(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+
+	// Predict the address of the ERC20TokenHome before deploying it
+	homeContract, err := create2.BindingContract("ERC20TokenHome")
+	Expect(err).Should(BeNil())
+	homeDeployment := create2.Deployment{
+		Contract: homeContract,
+		Salt:     create2.Salt(tokenSymbol),
+		Args: []interface{}{
+			cChainInfo.TeleporterRegistryAddress,
+			fundedAddress,
+			exampleERC20Address,
+			tokenDecimals,
+		},
+	}
+	predictedHomeAddress, err := homeDeployment.Address(factory)
+	Expect(err).Should(BeNil())
+	erc20TokenHomeAddress, err := deployers[cChainInfo.BlockchainID].Deploy(ctx, homeDeployment)
+	Expect(err).Should(BeNil())
+	Expect(erc20TokenHomeAddress).Should(Equal(predictedHomeAddress))
+	erc20TokenHome, err := erc20tokenhome.NewERC20TokenHome(erc20TokenHomeAddress, cChainInfo.RPCClient)
+	Expect(err).Should(BeNil())
+	tokenAddress, err := erc20TokenHome.GetTokenAddress(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	Expect(tokenAddress).Should(Equal(exampleERC20Address))
+
+	// Deploy the same remote implementation and an initialized proxy of it to each subnet
+	remoteContract, err := create2.BindingContract("ERC20TokenRemoteUpgradeable")
+	Expect(err).Should(BeNil())
+	implementationDeployment := create2.Deployment{
+		Contract: remoteContract,
+		Salt:     create2.Salt(remoteContract.Name),
+		Args:     []interface{}{create2.ICTTInitializableDisallowed},
+	}
+	implementationAddress, err := implementationDeployment.Address(factory)
+	Expect(err).Should(BeNil())
+
+	remoteAddresses := make(map[ids.ID]common.Address)
+	for _, subnet := range []interfaces.SubnetTestInfo{subnetAInfo, subnetBInfo} {
+		deployedImplementationAddress, err := deployers[subnet.BlockchainID].Deploy(ctx, implementationDeployment)
+		Expect(err).Should(BeNil())
+		Expect(deployedImplementationAddress).Should(Equal(implementationAddress))
+
+		// The proxy is initialized with the TeleporterRegistry of the subnet, which differs between subnets,
+		// so it is deployed as a proxy of the placeholder before being upgraded to the implementation
+		initializeData, err := create2.InitializeCall(
+			remoteContract,
+			erc20tokenremoteupgradeable.TokenRemoteSettings{
+				TeleporterRegistryAddress: subnet.TeleporterRegistryAddress,
+				TeleporterManager:         fundedAddress,
+				TokenHomeBlockchainID:     cChainInfo.BlockchainID,
+				TokenHomeAddress:          erc20TokenHomeAddress,
+				TokenHomeDecimals:         tokenDecimals,
+			},
+			tokenName,
+			tokenSymbol,
+			tokenDecimals,
+		)
+		Expect(err).Should(BeNil())
+		predictedProxyAddress, err := create2.ProxyAddress(factory, create2.Salt(tokenSymbol), fundedAddress)
+		Expect(err).Should(BeNil())
+
+		proxyAddress, err := deployers[subnet.BlockchainID].DeployProxy(
+			ctx,
+			create2.Salt(tokenSymbol),
+			implementationAddress,
+			initializeData,
+		)
+		Expect(err).Should(BeNil())
+		Expect(proxyAddress).Should(Equal(predictedProxyAddress))
+		remoteAddresses[subnet.BlockchainID] = proxyAddress
+
+		// The proxy is owned by the deployer through its ProxyAdmin
+		proxyAdmin, err := proxyadmin.NewProxyAdmin(create2.ProxyAdminAddress(proxyAddress), subnet.RPCClient)
+		Expect(err).Should(BeNil())
+		owner, err := proxyAdmin.Owner(&bind.CallOpts{})
+		Expect(err).Should(BeNil())
+		Expect(owner).Should(Equal(fundedAddress))
+
+		// Deploying the same contracts again sends no transaction
+		nonce, err := subnet.RPCClient.NonceAt(ctx, fundedAddress, nil)
+		Expect(err).Should(BeNil())
+		redeployedAddress, err := deployers[subnet.BlockchainID].Deploy(ctx, implementationDeployment)
+		Expect(err).Should(BeNil())
+		Expect(redeployedAddress).Should(Equal(implementationAddress))
+		redeployedAddress, err = deployers[subnet.BlockchainID].DeployProxy(
+			ctx,
+			create2.Salt(tokenSymbol),
+			implementationAddress,
+			initializeData,
+		)
+		Expect(err).Should(BeNil())
+		Expect(redeployedAddress).Should(Equal(proxyAddress))
+		newNonce, err := subnet.RPCClient.NonceAt(ctx, fundedAddress, nil)
+		Expect(err).Should(BeNil())
+		Expect(newNonce).Should(Equal(nonce))
+
+		utils.RegisterERC20TokenRemoteOnHome(
+			ctx,
+			network,
+			cChainInfo,
+			erc20TokenHomeAddress,
+			subnet,
+			proxyAddress,
+		)
+	}
+
+	erc20TokenRemoteAddressA := remoteAddresses[subnetAInfo.BlockchainID]
+	erc20TokenRemoteAddressB := remoteAddresses[subnetBInfo.BlockchainID]
+	Expect(erc20TokenRemoteAddressA).Should(Equal(erc20TokenRemoteAddressB))
+	erc20TokenRemoteA, err := erc20tokenremote.NewERC20TokenRemote(erc20TokenRemoteAddressA, subnetAInfo.RPCClient)
+	Expect(err).Should(BeNil())
+	erc20TokenRemoteB, err := erc20tokenremote.NewERC20TokenRemote(erc20TokenRemoteAddressB, subnetBInfo.RPCClient)
+	Expect(err).Should(BeNil())
+
+	// Generate new recipient to receive transferred tokens
+	recipientKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	recipientAddress := crypto.PubkeyToAddress(recipientKey.PublicKey)
+
+	// Send tokens from C-Chain to recipient on subnet A
+	input := erc20tokenhome.SendTokensInput{
+		DestinationBlockchainID:            subnetAInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: erc20TokenRemoteAddressA,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             exampleERC20Address,
+		PrimaryFee:                         big.NewInt(1e18),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+	}
+	amount := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(13))
+
+	receipt, transferredAmount := utils.SendERC20TokenHome(
+		ctx,
+		cChainInfo,
+		erc20TokenHome,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		input,
+		amount,
+		fundedKey,
+	)
+
+	// Relay the message to Subnet A and check for message delivery
+	receipt = network.RelayMessage(
+		ctx,
+		receipt,
+		cChainInfo,
+		subnetAInfo,
+		true,
+	)
+
+	utils.CheckERC20TokenRemoteWithdrawal(
+		ctx,
+		erc20TokenRemoteA,
+		receipt,
+		recipientAddress,
+		transferredAmount,
+	)
+
+	// Check that the recipient received the tokens
+	balance, err := erc20TokenRemoteA.BalanceOf(&bind.CallOpts{}, recipientAddress)
+	Expect(err).Should(BeNil())
+	Expect(balance).Should(Equal(transferredAmount))
+
+	// Multi-hop transfer to Subnet B
+	transferredAmount = big.NewInt(0).Div(transferredAmount, big.NewInt(2))
+	secondaryFeeAmount := big.NewInt(0).Div(transferredAmount, big.NewInt(4))
+	utils.SendERC20TokenMultiHopAndVerify(
+		ctx,
+		network,
+		fundedKey,
+		recipientKey,
+		recipientAddress,
+		subnetAInfo,
+		erc20TokenRemoteA,
+		erc20TokenRemoteAddressA,
+		subnetBInfo,
+		erc20TokenRemoteB,
+		erc20TokenRemoteAddressB,
+		cChainInfo,
+		transferredAmount,
+		secondaryFeeAmount,
+	)
+
+	// Deploy an example WAVAX on the primary network
+	wavaxAddress, wavax := utils.DeployWrappedNativeToken(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		"AVAX",
+	)
+
+	// Deploy a NativeTokenHome on the primary network through the factory
+	nativeHomeContract, err := create2.BindingContract("NativeTokenHome")
+	Expect(err).Should(BeNil())
+	nativeHomeDeployment := create2.Deployment{
+		Contract: nativeHomeContract,
+		Salt:     create2.Salt("AVAX"),
+		Args: []interface{}{
+			cChainInfo.TeleporterRegistryAddress,
+			fundedAddress,
+			wavaxAddress,
+		},
+	}
+	predictedNativeHomeAddress, err := nativeHomeDeployment.Address(factory)
+	Expect(err).Should(BeNil())
+	nativeTokenHomeAddress, err := deployers[cChainInfo.BlockchainID].Deploy(ctx, nativeHomeDeployment)
+	Expect(err).Should(BeNil())
+	Expect(nativeTokenHomeAddress).Should(Equal(predictedNativeHomeAddress))
+	nativeTokenHome, err := nativetokenhome.NewNativeTokenHome(nativeTokenHomeAddress, cChainInfo.RPCClient)
+	Expect(err).Should(BeNil())
+
+	// Deploy a NativeTokenRemoteUpgradeable implementation and a proxy of it to Subnet A. The proxy is owned by
+	// the key whose proxy address is allowed to mint native tokens in the genesis file.
+	nativeRemoteContract, err := create2.BindingContract("NativeTokenRemoteUpgradeable")
+	Expect(err).Should(BeNil())
+	nativeImplementationAddress, err := deployers[subnetAInfo.BlockchainID].Deploy(ctx, create2.Deployment{
+		Contract: nativeRemoteContract,
+		Salt:     create2.Salt(nativeRemoteContract.Name),
+		Args:     []interface{}{create2.ICTTInitializableDisallowed},
+	})
+	Expect(err).Should(BeNil())
+
+	ownerKey := utils.DeterministicNativeTokenRemoteOwnerKey()
+	nativeRemoteSalt := create2.Salt(utils.DeterministicNativeTokenRemoteLabel)
+	predictedNativeRemoteAddress, err := create2.ProxyAddress(
+		factory,
+		nativeRemoteSalt,
+		crypto.PubkeyToAddress(ownerKey.PublicKey),
+	)
+	Expect(err).Should(BeNil())
+	nativeRemoteDeployer, err := create2.NewDeployer(ctx, subnetAInfo.RPCClient, ownerKey, factory)
+	Expect(err).Should(BeNil())
+	initializeData, err := create2.InitializeCall(
+		nativeRemoteContract,
+		nativetokenremoteupgradeable.TokenRemoteSettings{
+			TeleporterRegistryAddress: subnetAInfo.TeleporterRegistryAddress,
+			TeleporterManager:         fundedAddress,
+			TokenHomeBlockchainID:     cChainInfo.BlockchainID,
+			TokenHomeAddress:          nativeTokenHomeAddress,
+			TokenHomeDecimals:         utils.NativeTokenDecimals,
+		},
+		"SUBA",
+		initialReserveImbalance,
+		burnedFeesReportingRewardPercentage,
+	)
+	Expect(err).Should(BeNil())
+	nativeTokenRemoteAddress, err := nativeRemoteDeployer.DeployProxy(
+		ctx,
+		nativeRemoteSalt,
+		nativeImplementationAddress,
+		initializeData,
+	)
+	Expect(err).Should(BeNil())
+	Expect(nativeTokenRemoteAddress).Should(Equal(predictedNativeRemoteAddress))
+
+	// Register the NativeTokenRemote on the NativeTokenHome
+	collateralAmount := utils.RegisterTokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		nativeTokenHomeAddress,
+		subnetAInfo,
+		nativeTokenRemoteAddress,
+		initialReserveImbalance,
+		big.NewInt(1),
+		multiplyOnRemote,
+	)
+
+	utils.AddCollateralToNativeTokenHome(
+		ctx,
+		cChainInfo,
+		nativeTokenHome,
+		nativeTokenHomeAddress,
+		subnetAInfo.BlockchainID,
+		nativeTokenRemoteAddress,
+		collateralAmount,
+		fundedKey,
+	)
+
+	// Send native tokens from C-Chain to a new recipient on Subnet A, which are minted by the NativeTokenRemote
+	nativeRecipientKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	nativeRecipientAddress := crypto.PubkeyToAddress(nativeRecipientKey.PublicKey)
+
+	nativeInput := nativetokenhome.SendTokensInput{
+		DestinationBlockchainID:            subnetAInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: nativeTokenRemoteAddress,
+		Recipient:                          nativeRecipientAddress,
+		PrimaryFeeTokenAddress:             wavaxAddress,
+		PrimaryFee:                         big.NewInt(1e18),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultNativeTokenRequiredGas,
+	}
+	nativeAmount := utils.ParseAmount("13", utils.NativeTokenDecimals)
+
+	receipt, _ = utils.SendNativeTokenHome(
+		ctx,
+		cChainInfo,
+		nativeTokenHome,
+		nativeTokenHomeAddress,
+		wavax,
+		nativeInput,
+		nativeAmount,
+		fundedKey,
+	)
+
+	network.RelayMessage(
+		ctx,
+		receipt,
+		cChainInfo,
+		subnetAInfo,
+		true,
+	)
+
+	teleporterUtils.CheckBalance(
+		ctx,
+		nativeRecipientAddress,
+		nativeAmount,
+		subnetAInfo.RPCClient,
+	)
+}
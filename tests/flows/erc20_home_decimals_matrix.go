@@ -0,0 +1,555 @@
+package flows
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	nativetokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemote"
+	exampleerc20 "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/mocks/ExampleERC20Decimals"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/model"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	. "github.com/onsi/gomega"
+)
+
+// The initial reserve imbalance of the NativeTokenRemote on Subnet B is one wei more than
+// a whole number of tokens, so that the collateral needed is rounded up whenever the home
+// token has fewer decimals than the native token.
+var decimalsMatrixInitialReserveImbalance = new(big.Int).Add(
+	new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1000)),
+	big.NewInt(1),
+)
+
+/**
+ * Deploy an ERC20 token with homeDecimals on the primary network, and an ERC20TokenHome for it
+ * Deploy an ERC20TokenRemote with remoteDecimals to Subnet A, and a NativeTokenRemote to Subnet B
+ * Check the collateral needed by the NativeTokenRemote, and that partial and excess collateral are accounted for
+ * Transfer tokens from the C-Chain to Subnet A and back, including amounts with dust that is lost to scaling
+ * Multi-hop transfer tokens from Subnet A to Subnet B and back, through the C-Chain
+ * Check that amounts that scale to zero revert, or are sent to the multi-hop fallback on the C-Chain
+ */
+func ERC20TokenHomeDecimalsMatrix(network interfaces.Network, homeDecimals uint8, remoteDecimals uint8) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	subnetAInfo, subnetBInfo := teleporterUtils.GetTwoSubnets(network)
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	homeUnit := utils.GetTokenMultiplier(homeDecimals)
+	tokenMultiplierA, multiplyOnRemoteA := model.TokenMultiplierValues(homeDecimals, remoteDecimals)
+	tokenMultiplierB, multiplyOnRemoteB := model.TokenMultiplierValues(homeDecimals, utils.NativeTokenDecimals)
+
+	exampleERC20Address, exampleERC20 := utils.DeployExampleERC20(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		homeDecimals,
+	)
+
+	erc20TokenHomeAddress, erc20TokenHome := utils.DeployERC20TokenHome(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		fundedAddress,
+		exampleERC20Address,
+		homeDecimals,
+	)
+
+	erc20TokenRemoteAddressA, erc20TokenRemoteA := utils.DeployERC20TokenRemote(
+		ctx,
+		fundedKey,
+		subnetAInfo,
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		homeDecimals,
+		"Wrapped Token",
+		"WTKN",
+		remoteDecimals,
+	)
+
+	nativeTokenRemoteAddressB, nativeTokenRemoteB := utils.DeployNativeTokenRemote(
+		ctx,
+		subnetBInfo,
+		"SUBB",
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		homeDecimals,
+		decimalsMatrixInitialReserveImbalance,
+		burnedFeesReportingRewardPercentage,
+	)
+
+	// Register both remotes, checking the token multiplier values and collateral needed for each.
+	collateralNeededA := utils.RegisterTokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetAInfo,
+		erc20TokenRemoteAddressA,
+		big.NewInt(0),
+		tokenMultiplierA,
+		multiplyOnRemoteA,
+	)
+	Expect(collateralNeededA.Sign()).Should(BeZero())
+	expectDecimalsMatrixSettings(erc20TokenHome, subnetAInfo.BlockchainID, erc20TokenRemoteAddressA,
+		tokenMultiplierA, multiplyOnRemoteA, big.NewInt(0))
+
+	collateralNeededB := utils.RegisterTokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetBInfo,
+		nativeTokenRemoteAddressB,
+		decimalsMatrixInitialReserveImbalance,
+		tokenMultiplierB,
+		multiplyOnRemoteB,
+	)
+	expectDecimalsMatrixSettings(erc20TokenHome, subnetBInfo.BlockchainID, nativeTokenRemoteAddressB,
+		tokenMultiplierB, multiplyOnRemoteB, collateralNeededB)
+
+	// Sending to Subnet B is not possible until it is fully collateralized.
+	sendToB := erc20tokenhome.SendTokensInput{
+		DestinationBlockchainID:            subnetBInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: nativeTokenRemoteAddressB,
+		Recipient:                          fundedAddress,
+		PrimaryFeeTokenAddress:             exampleERC20Address,
+		PrimaryFee:                         big.NewInt(0),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultNativeTokenRequiredGas,
+	}
+	sendFromDecimalsMatrixHome(
+		ctx,
+		cChainInfo,
+		erc20TokenHome,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		sendToB,
+		homeUnit,
+		fundedKey,
+		model.ErrCollateralNeeded,
+	)
+
+	// Add all but one of the collateral needed, then the rest with an excess that is returned to the sender.
+	addDecimalsMatrixCollateral(
+		ctx,
+		cChainInfo,
+		erc20TokenHome,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		subnetBInfo.BlockchainID,
+		nativeTokenRemoteAddressB,
+		new(big.Int).Sub(collateralNeededB, big.NewInt(1)),
+		new(big.Int).Sub(collateralNeededB, big.NewInt(1)),
+		big.NewInt(1),
+		fundedKey,
+	)
+	addDecimalsMatrixCollateral(
+		ctx,
+		cChainInfo,
+		erc20TokenHome,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		subnetBInfo.BlockchainID,
+		nativeTokenRemoteAddressB,
+		new(big.Int).Add(big.NewInt(1), homeUnit),
+		big.NewInt(1),
+		big.NewInt(0),
+		fundedKey,
+	)
+
+	recipientKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	recipientAddress := crypto.PubkeyToAddress(recipientKey.PublicKey)
+
+	// Fund the recipient on Subnet A so it can send tokens back and through multi-hop transfers.
+	teleporterUtils.SendNativeTransfer(
+		ctx,
+		subnetAInfo,
+		fundedKey,
+		recipientAddress,
+		big.NewInt(1e18),
+	)
+
+	// An amount of home tokens that is less than one remote token can not be sent to Subnet A.
+	sendToA := erc20tokenhome.SendTokensInput{
+		DestinationBlockchainID:            subnetAInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: erc20TokenRemoteAddressA,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             exampleERC20Address,
+		PrimaryFee:                         big.NewInt(0),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+	}
+	if !multiplyOnRemoteA && tokenMultiplierA.Cmp(big.NewInt(1)) > 0 {
+		sendFromDecimalsMatrixHome(
+			ctx,
+			cChainInfo,
+			erc20TokenHome,
+			erc20TokenHomeAddress,
+			exampleERC20,
+			sendToA,
+			new(big.Int).Sub(tokenMultiplierA, big.NewInt(1)),
+			fundedKey,
+			model.ErrZeroScaledAmount,
+		)
+	}
+
+	// Send 100 home tokens to Subnet A, with one unit of dust that is kept by the home
+	// if it is scaled down on Subnet A.
+	homeAmount := new(big.Int).Add(new(big.Int).Mul(homeUnit, big.NewInt(100)), big.NewInt(1))
+	expectedAmountA := utils.ApplyTokenScaling(tokenMultiplierA, multiplyOnRemoteA, homeAmount)
+	homeBalanceBefore, err := exampleERC20.BalanceOf(&bind.CallOpts{}, erc20TokenHomeAddress)
+	Expect(err).Should(BeNil())
+
+	receipt := sendFromDecimalsMatrixHome(
+		ctx,
+		cChainInfo,
+		erc20TokenHome,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		sendToA,
+		homeAmount,
+		fundedKey,
+		nil,
+	)
+	event, err := teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenHome.ParseTokensSent)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(event.Amount, expectedAmountA)
+
+	// The home keeps the full amount sent, including any dust that was scaled away.
+	homeBalance, err := exampleERC20.BalanceOf(&bind.CallOpts{}, erc20TokenHomeAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(homeBalance, new(big.Int).Add(homeBalanceBefore, homeAmount))
+	expectDecimalsMatrixTransferredBalance(erc20TokenHome, subnetAInfo.BlockchainID, erc20TokenRemoteAddressA,
+		expectedAmountA)
+
+	receipt = network.RelayMessage(ctx, receipt, cChainInfo, subnetAInfo, true)
+	utils.CheckERC20TokenRemoteWithdrawal(ctx, erc20TokenRemoteA, receipt, recipientAddress, expectedAmountA)
+
+	// An amount of remote tokens that is less than one home token can not be sent back to the home.
+	sendToHome := erc20tokenremote.SendTokensInput{
+		DestinationBlockchainID:            cChainInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: erc20TokenHomeAddress,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             common.Address{},
+		PrimaryFee:                         big.NewInt(0),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+	}
+	dustA := big.NewInt(0)
+	if multiplyOnRemoteA && tokenMultiplierA.Cmp(big.NewInt(1)) > 0 {
+		dustA.Sub(tokenMultiplierA, big.NewInt(1))
+		sendFromDecimalsMatrixRemote(
+			ctx,
+			subnetAInfo,
+			erc20TokenRemoteA,
+			erc20TokenRemoteAddressA,
+			sendToHome,
+			dustA,
+			recipientKey,
+			model.ErrInsufficientTokensToTransfer,
+		)
+	}
+
+	// Send half of the tokens back, plus dust that is burned on Subnet A but not received on the home.
+	remoteAmount := new(big.Int).Add(new(big.Int).Div(expectedAmountA, big.NewInt(2)), dustA)
+	expectedHomeAmount := utils.RemoveTokenScaling(tokenMultiplierA, multiplyOnRemoteA, remoteAmount)
+	receipt = sendFromDecimalsMatrixRemote(
+		ctx,
+		subnetAInfo,
+		erc20TokenRemoteA,
+		erc20TokenRemoteAddressA,
+		sendToHome,
+		remoteAmount,
+		recipientKey,
+		nil,
+	)
+	receipt = network.RelayMessage(ctx, receipt, subnetAInfo, cChainInfo, true)
+	utils.CheckERC20TokenHomeWithdrawal(
+		ctx,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		receipt,
+		recipientAddress,
+		expectedHomeAmount,
+	)
+	balance, err := exampleERC20.BalanceOf(&bind.CallOpts{}, recipientAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(balance, expectedHomeAmount)
+
+	transferredBalanceA := new(big.Int).Sub(expectedAmountA, remoteAmount)
+	expectDecimalsMatrixTransferredBalance(erc20TokenHome, subnetAInfo.BlockchainID, erc20TokenRemoteAddressA,
+		transferredBalanceA)
+
+	// Multi-hop 10 tokens from Subnet A to Subnet B, paying a secondary fee of one token.
+	remoteUnitA := utils.GetTokenMultiplier(remoteDecimals)
+	multiHopAmountA := new(big.Int).Mul(remoteUnitA, big.NewInt(10))
+	secondaryFeeA := remoteUnitA
+	routedHomeAmount := new(big.Int).Sub(
+		utils.RemoveTokenScaling(tokenMultiplierA, multiplyOnRemoteA, multiHopAmountA),
+		utils.RemoveTokenScaling(tokenMultiplierA, multiplyOnRemoteA, secondaryFeeA),
+	)
+	expectedAmountB := utils.ApplyTokenScaling(tokenMultiplierB, multiplyOnRemoteB, routedHomeAmount)
+
+	multiHopToB := erc20tokenremote.SendTokensInput{
+		DestinationBlockchainID:            subnetBInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: nativeTokenRemoteAddressB,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             common.Address{},
+		PrimaryFee:                         big.NewInt(0),
+		SecondaryFee:                       secondaryFeeA,
+		RequiredGasLimit:                   utils.DefaultNativeTokenRequiredGas,
+		MultiHopFallback:                   recipientAddress,
+	}
+	receipt = sendFromDecimalsMatrixRemote(
+		ctx,
+		subnetAInfo,
+		erc20TokenRemoteA,
+		erc20TokenRemoteAddressA,
+		multiHopToB,
+		multiHopAmountA,
+		recipientKey,
+		nil,
+	)
+	receipt = network.RelayMessage(ctx, receipt, subnetAInfo, cChainInfo, true)
+	routedEvent, err := teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenHome.ParseTokensRouted)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(routedEvent.Amount, expectedAmountB)
+
+	transferredBalanceA.Sub(transferredBalanceA, multiHopAmountA)
+	expectDecimalsMatrixTransferredBalance(erc20TokenHome, subnetAInfo.BlockchainID, erc20TokenRemoteAddressA,
+		transferredBalanceA)
+	transferredBalanceB := new(big.Int).Set(expectedAmountB)
+	expectDecimalsMatrixTransferredBalance(erc20TokenHome, subnetBInfo.BlockchainID, nativeTokenRemoteAddressB,
+		transferredBalanceB)
+
+	network.RelayMessage(ctx, receipt, cChainInfo, subnetBInfo, true)
+	teleporterUtils.CheckBalance(ctx, recipientAddress, expectedAmountB, subnetBInfo.RPCClient)
+
+	// Multi-hop half of the received native tokens back to Subnet A, without a secondary fee.
+	// Any amount below one token on Subnet A is kept by the home.
+	multiHopAmountB := new(big.Int).Div(expectedAmountB, big.NewInt(2))
+	expectedReturnedA := utils.ApplyTokenScaling(
+		tokenMultiplierA,
+		multiplyOnRemoteA,
+		utils.RemoveTokenScaling(tokenMultiplierB, multiplyOnRemoteB, multiHopAmountB),
+	)
+	multiHopToA := nativetokenremote.SendTokensInput{
+		DestinationBlockchainID:            subnetAInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: erc20TokenRemoteAddressA,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             nativeTokenRemoteAddressB,
+		PrimaryFee:                         big.NewInt(0),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+		MultiHopFallback:                   recipientAddress,
+	}
+	receipt, _ = utils.SendNativeTokenRemote(
+		ctx,
+		subnetBInfo,
+		nativeTokenRemoteB,
+		nativeTokenRemoteAddressB,
+		multiHopToA,
+		multiHopAmountB,
+		recipientKey,
+	)
+	receipt = network.RelayMessage(ctx, receipt, subnetBInfo, cChainInfo, true)
+	routedEvent, err = teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenHome.ParseTokensRouted)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(routedEvent.Amount, expectedReturnedA)
+
+	transferredBalanceB.Sub(transferredBalanceB, multiHopAmountB)
+	expectDecimalsMatrixTransferredBalance(erc20TokenHome, subnetBInfo.BlockchainID, nativeTokenRemoteAddressB,
+		transferredBalanceB)
+	transferredBalanceA.Add(transferredBalanceA, expectedReturnedA)
+	expectDecimalsMatrixTransferredBalance(erc20TokenHome, subnetAInfo.BlockchainID, erc20TokenRemoteAddressA,
+		transferredBalanceA)
+
+	balanceA, err := erc20TokenRemoteA.BalanceOf(&bind.CallOpts{}, recipientAddress)
+	Expect(err).Should(BeNil())
+	receipt = network.RelayMessage(ctx, receipt, cChainInfo, subnetAInfo, true)
+	utils.CheckERC20TokenRemoteWithdrawal(ctx, erc20TokenRemoteA, receipt, recipientAddress, expectedReturnedA)
+	newBalanceA, err := erc20TokenRemoteA.BalanceOf(&bind.CallOpts{}, recipientAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(newBalanceA, new(big.Int).Add(balanceA, expectedReturnedA))
+
+	// A multi-hop transfer that scales down to zero on Subnet A is sent to the fallback on the C-Chain instead.
+	if multiplyOnRemoteA || tokenMultiplierA.Cmp(big.NewInt(1)) == 0 {
+		return
+	}
+	fallbackHomeAmount := new(big.Int).Sub(tokenMultiplierA, big.NewInt(1))
+	multiHopDustB := utils.ApplyTokenScaling(tokenMultiplierB, multiplyOnRemoteB, fallbackHomeAmount)
+	fallbackBalance, err := exampleERC20.BalanceOf(&bind.CallOpts{}, recipientAddress)
+	Expect(err).Should(BeNil())
+
+	receipt, _ = utils.SendNativeTokenRemote(
+		ctx,
+		subnetBInfo,
+		nativeTokenRemoteB,
+		nativeTokenRemoteAddressB,
+		multiHopToA,
+		multiHopDustB,
+		recipientKey,
+	)
+	receipt = network.RelayMessage(ctx, receipt, subnetBInfo, cChainInfo, true)
+	_, err = teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenHome.ParseTokensRouted)
+	Expect(err).ShouldNot(BeNil())
+	utils.CheckERC20TokenHomeWithdrawal(
+		ctx,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		receipt,
+		recipientAddress,
+		fallbackHomeAmount,
+	)
+	newFallbackBalance, err := exampleERC20.BalanceOf(&bind.CallOpts{}, recipientAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(newFallbackBalance, new(big.Int).Add(fallbackBalance, fallbackHomeAmount))
+
+	transferredBalanceB.Sub(transferredBalanceB, multiHopDustB)
+	expectDecimalsMatrixTransferredBalance(erc20TokenHome, subnetBInfo.BlockchainID, nativeTokenRemoteAddressB,
+		transferredBalanceB)
+	expectDecimalsMatrixTransferredBalance(erc20TokenHome, subnetAInfo.BlockchainID, erc20TokenRemoteAddressA,
+		transferredBalanceA)
+}
+
+// sendFromDecimalsMatrixHome approves and sends amount from the ERC20TokenHome,
+// or checks that the send reverts with expectedErr if it is not nil.
+func sendFromDecimalsMatrixHome(
+	ctx context.Context,
+	subnet interfaces.SubnetTestInfo,
+	erc20TokenHome *erc20tokenhome.ERC20TokenHome,
+	erc20TokenHomeAddress common.Address,
+	token *exampleerc20.ExampleERC20Decimals,
+	input erc20tokenhome.SendTokensInput,
+	amount *big.Int,
+	senderKey *ecdsa.PrivateKey,
+	expectedErr error,
+) *types.Receipt {
+	utils.ERC20Approve(ctx, token, erc20TokenHomeAddress, amount, subnet, senderKey)
+	return sendOrExpectRevert(
+		ctx,
+		subnet,
+		senderKey,
+		expectedErr,
+		func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return erc20TokenHome.Send(opts, input, amount)
+		},
+	)
+}
+
+// sendFromDecimalsMatrixRemote approves and sends amount from the ERC20TokenRemote,
+// or checks that the send reverts with expectedErr if it is not nil.
+func sendFromDecimalsMatrixRemote(
+	ctx context.Context,
+	subnet interfaces.SubnetTestInfo,
+	erc20TokenRemote *erc20tokenremote.ERC20TokenRemote,
+	erc20TokenRemoteAddress common.Address,
+	input erc20tokenremote.SendTokensInput,
+	amount *big.Int,
+	senderKey *ecdsa.PrivateKey,
+	expectedErr error,
+) *types.Receipt {
+	opts, err := bind.NewKeyedTransactorWithChainID(senderKey, subnet.EVMChainID)
+	Expect(err).Should(BeNil())
+	tx, err := erc20TokenRemote.Approve(opts, erc20TokenRemoteAddress, amount)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, subnet, tx.Hash())
+
+	return sendOrExpectRevert(
+		ctx,
+		subnet,
+		senderKey,
+		expectedErr,
+		func(opts *bind.TransactOpts) (*types.Transaction, error) {
+			return erc20TokenRemote.Send(opts, input, amount)
+		},
+	)
+}
+
+// addDecimalsMatrixCollateral adds amount as collateral for the remote, and checks that only
+// expectedAdded is taken from the sender, leaving expectedRemaining collateral needed.
+func addDecimalsMatrixCollateral(
+	ctx context.Context,
+	subnet interfaces.SubnetTestInfo,
+	erc20TokenHome *erc20tokenhome.ERC20TokenHome,
+	erc20TokenHomeAddress common.Address,
+	token *exampleerc20.ExampleERC20Decimals,
+	remoteBlockchainID ids.ID,
+	remoteAddress common.Address,
+	amount *big.Int,
+	expectedAdded *big.Int,
+	expectedRemaining *big.Int,
+	senderKey *ecdsa.PrivateKey,
+) {
+	senderAddress := crypto.PubkeyToAddress(senderKey.PublicKey)
+	balanceBefore, err := token.BalanceOf(&bind.CallOpts{}, senderAddress)
+	Expect(err).Should(BeNil())
+
+	utils.ERC20Approve(ctx, token, erc20TokenHomeAddress, amount, subnet, senderKey)
+	opts, err := bind.NewKeyedTransactorWithChainID(senderKey, subnet.EVMChainID)
+	Expect(err).Should(BeNil())
+	tx, err := erc20TokenHome.AddCollateral(opts, remoteBlockchainID, remoteAddress, amount)
+	Expect(err).Should(BeNil())
+	receipt := teleporterUtils.WaitForTransactionSuccess(ctx, subnet, tx.Hash())
+
+	event, err := teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenHome.ParseCollateralAdded)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(event.Amount, expectedAdded)
+	teleporterUtils.ExpectBigEqual(event.Remaining, expectedRemaining)
+
+	balance, err := token.BalanceOf(&bind.CallOpts{}, senderAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(balance, new(big.Int).Sub(balanceBefore, expectedAdded))
+
+	settings, err := erc20TokenHome.GetRemoteTokenTransferrerSettings(
+		&bind.CallOpts{},
+		remoteBlockchainID,
+		remoteAddress,
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(settings.CollateralNeeded, expectedRemaining)
+}
+
+func expectDecimalsMatrixSettings(
+	erc20TokenHome *erc20tokenhome.ERC20TokenHome,
+	remoteBlockchainID ids.ID,
+	remoteAddress common.Address,
+	expectedTokenMultiplier *big.Int,
+	expectedMultiplyOnRemote bool,
+	expectedCollateralNeeded *big.Int,
+) {
+	settings, err := erc20TokenHome.GetRemoteTokenTransferrerSettings(
+		&bind.CallOpts{},
+		remoteBlockchainID,
+		remoteAddress,
+	)
+	Expect(err).Should(BeNil())
+	Expect(settings.Registered).Should(BeTrue())
+	teleporterUtils.ExpectBigEqual(settings.TokenMultiplier, expectedTokenMultiplier)
+	Expect(settings.MultiplyOnRemote).Should(Equal(expectedMultiplyOnRemote))
+	teleporterUtils.ExpectBigEqual(settings.CollateralNeeded, expectedCollateralNeeded)
+}
+
+func expectDecimalsMatrixTransferredBalance(
+	erc20TokenHome *erc20tokenhome.ERC20TokenHome,
+	remoteBlockchainID ids.ID,
+	remoteAddress common.Address,
+	expectedBalance *big.Int,
+) {
+	balance, err := erc20TokenHome.GetTransferredBalance(&bind.CallOpts{}, remoteBlockchainID, remoteAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(balance, expectedBalance)
+}
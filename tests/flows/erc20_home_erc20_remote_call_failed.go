@@ -0,0 +1,287 @@
+package flows
+
+import (
+	"context"
+	"math/big"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/crypto"
+	. "github.com/onsi/gomega"
+)
+
+/**
+ * Deploy an ERC20TokenHome on the primary network
+ * Deploys ERC20TokenRemote to Subnet A
+ * Transfers C-Chain example ERC20 tokens to a mock contract on Subnet A using sendAndCall
+ * with an empty payload, so that the recipient contract call fails
+ * Check that CallFailed is emitted and the fallback recipient on Subnet A receives the tokens
+ * Transfers tokens from Subnet A back to a mock contract on the C-Chain with an empty payload
+ * Check that CallFailed is emitted and the fallback recipient on the C-Chain receives the tokens
+ * Check that the transferred balance of the ERC20TokenRemote is kept consistent throughout
+ */
+func ERC20TokenHomeERC20TokenRemoteCallFailed(network interfaces.Network) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	subnetAInfo, _ := teleporterUtils.GetTwoSubnets(network)
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	// Deploy an ExampleERC20 on the primary network as the token to be transferred
+	exampleERC20Address, exampleERC20 := utils.DeployExampleERC20(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		erc20TokenHomeDecimals,
+	)
+
+	exampleERC20Decimals, err := exampleERC20.Decimals(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+
+	// Create an ERC20TokenHome for transferring the ERC20 token
+	erc20TokenHomeAddress, erc20TokenHome := utils.DeployERC20TokenHome(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		fundedAddress,
+		exampleERC20Address,
+		exampleERC20Decimals,
+	)
+
+	homeMockERC20SACRAddress, _ := utils.DeployMockERC20SendAndCallReceiver(
+		ctx,
+		fundedKey,
+		cChainInfo,
+	)
+
+	remoteMockERC20SACRAddress, _ := utils.DeployMockERC20SendAndCallReceiver(
+		ctx,
+		fundedKey,
+		subnetAInfo,
+	)
+
+	// Token representation on subnet A will have same name, symbol, and decimals
+	tokenName, err := exampleERC20.Name(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	tokenSymbol, err := exampleERC20.Symbol(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+
+	// Deploy an ERC20TokenRemote to Subnet A
+	erc20TokenRemoteAddress, erc20TokenRemote := utils.DeployERC20TokenRemote(
+		ctx,
+		fundedKey,
+		subnetAInfo,
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		exampleERC20Decimals,
+		tokenName,
+		tokenSymbol,
+		exampleERC20Decimals,
+	)
+
+	utils.RegisterERC20TokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetAInfo,
+		erc20TokenRemoteAddress,
+	)
+
+	// Generate new sender on subnet A to send tokens back to the C-Chain
+	senderKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	senderAddress := crypto.PubkeyToAddress(senderKey.PublicKey)
+
+	// Generate new fallback recipient to receive the tokens of failed calls
+	fallbackKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	fallbackAddress := crypto.PubkeyToAddress(fallbackKey.PublicKey)
+
+	amount := big.NewInt(0).Mul(big.NewInt(1e18), big.NewInt(13))
+
+	// Send tokens from C-Chain to the mock contract on subnet A with an empty payload.
+	// The mock contract reverts on an empty payload, so the full amount goes to the fallback recipient.
+	{
+		input := erc20tokenhome.SendAndCallInput{
+			DestinationBlockchainID:            subnetAInfo.BlockchainID,
+			DestinationTokenTransferrerAddress: erc20TokenRemoteAddress,
+			RecipientContract:                  remoteMockERC20SACRAddress,
+			RecipientPayload:                   []byte{},
+			RequiredGasLimit:                   teleporterUtils.BigIntMul(big.NewInt(10), utils.DefaultERC20RequiredGas),
+			RecipientGasLimit:                  teleporterUtils.BigIntMul(big.NewInt(5), utils.DefaultERC20RequiredGas),
+			FallbackRecipient:                  fallbackAddress,
+			PrimaryFeeTokenAddress:             exampleERC20Address,
+			PrimaryFee:                         big.NewInt(1e18),
+			SecondaryFee:                       big.NewInt(0),
+		}
+
+		receipt, transferredAmount := utils.SendAndCallERC20TokenHome(
+			ctx,
+			cChainInfo,
+			erc20TokenHome,
+			erc20TokenHomeAddress,
+			exampleERC20,
+			input,
+			amount,
+			fundedKey,
+		)
+
+		// The tokens are accounted to the remote as soon as they are sent
+		transferredBalance, err := erc20TokenHome.GetTransferredBalance(
+			&bind.CallOpts{},
+			subnetAInfo.BlockchainID,
+			erc20TokenRemoteAddress,
+		)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(transferredBalance, transferredAmount)
+
+		// Relay the message to Subnet A and check for message delivery
+		receipt = network.RelayMessage(
+			ctx,
+			receipt,
+			cChainInfo,
+			subnetAInfo,
+			true,
+		)
+
+		event, err := teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenRemote.ParseCallFailed)
+		Expect(err).Should(BeNil())
+		Expect(event.RecipientContract).Should(Equal(input.RecipientContract))
+		teleporterUtils.ExpectBigEqual(event.Amount, transferredAmount)
+
+		_, err = teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenRemote.ParseCallSucceeded)
+		Expect(err).ShouldNot(BeNil())
+
+		// Check that the fallback recipient received the tokens, and the contract received none
+		balance, err := erc20TokenRemote.BalanceOf(&bind.CallOpts{}, fallbackAddress)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(balance, transferredAmount)
+
+		balance, err = erc20TokenRemote.BalanceOf(&bind.CallOpts{}, remoteMockERC20SACRAddress)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(balance, big.NewInt(0))
+
+		// Check that the remote supply still matches the transferred balance on the home
+		totalSupply, err := erc20TokenRemote.TotalSupply(&bind.CallOpts{})
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(totalSupply, transferredBalance)
+	}
+
+	// Send the fallback tokens from subnet A to the sender that sends them back to the C-Chain
+	{
+		teleporterUtils.SendNativeTransfer(
+			ctx,
+			subnetAInfo,
+			fundedKey,
+			fallbackAddress,
+			big.NewInt(1e18),
+		)
+		teleporterUtils.SendNativeTransfer(
+			ctx,
+			subnetAInfo,
+			fundedKey,
+			senderAddress,
+			big.NewInt(1e18),
+		)
+
+		balance, err := erc20TokenRemote.BalanceOf(&bind.CallOpts{}, fallbackAddress)
+		Expect(err).Should(BeNil())
+
+		opts, err := bind.NewKeyedTransactorWithChainID(fallbackKey, subnetAInfo.EVMChainID)
+		Expect(err).Should(BeNil())
+		tx, err := erc20TokenRemote.Transfer(opts, senderAddress, balance)
+		Expect(err).Should(BeNil())
+		teleporterUtils.WaitForTransactionSuccess(ctx, subnetAInfo, tx.Hash())
+	}
+
+	// Send tokens from subnet A to the mock contract on the C-Chain with an empty payload.
+	// The mock contract reverts on an empty payload, so the full amount goes to the fallback recipient.
+	{
+		remoteBalance, err := erc20TokenRemote.BalanceOf(&bind.CallOpts{}, senderAddress)
+		Expect(err).Should(BeNil())
+
+		initialTransferredBalance, err := erc20TokenHome.GetTransferredBalance(
+			&bind.CallOpts{},
+			subnetAInfo.BlockchainID,
+			erc20TokenRemoteAddress,
+		)
+		Expect(err).Should(BeNil())
+
+		initialHomeBalance, err := exampleERC20.BalanceOf(&bind.CallOpts{}, erc20TokenHomeAddress)
+		Expect(err).Should(BeNil())
+
+		input := erc20tokenremote.SendAndCallInput{
+			DestinationBlockchainID:            cChainInfo.BlockchainID,
+			DestinationTokenTransferrerAddress: erc20TokenHomeAddress,
+			RecipientContract:                  homeMockERC20SACRAddress,
+			RecipientPayload:                   []byte{},
+			RequiredGasLimit:                   teleporterUtils.BigIntMul(big.NewInt(10), utils.DefaultERC20RequiredGas),
+			RecipientGasLimit:                  teleporterUtils.BigIntMul(big.NewInt(5), utils.DefaultERC20RequiredGas),
+			FallbackRecipient:                  fallbackAddress,
+			PrimaryFeeTokenAddress:             erc20TokenRemoteAddress,
+			PrimaryFee:                         big.NewInt(1e10),
+			SecondaryFee:                       big.NewInt(0),
+		}
+
+		receipt, transferredAmount := utils.SendAndCallERC20TokenRemote(
+			ctx,
+			subnetAInfo,
+			erc20TokenRemote,
+			erc20TokenRemoteAddress,
+			input,
+			teleporterUtils.BigIntSub(remoteBalance, input.PrimaryFee),
+			senderKey,
+		)
+
+		receipt = network.RelayMessage(
+			ctx,
+			receipt,
+			subnetAInfo,
+			cChainInfo,
+			true,
+		)
+
+		homeEvent, err := teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenHome.ParseCallFailed)
+		Expect(err).Should(BeNil())
+		Expect(homeEvent.RecipientContract).Should(Equal(input.RecipientContract))
+		teleporterUtils.ExpectBigEqual(homeEvent.Amount, transferredAmount)
+
+		_, err = teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenHome.ParseCallSucceeded)
+		Expect(err).ShouldNot(BeNil())
+
+		// Check that the fallback recipient received the tokens, and the contract received none
+		balance, err := exampleERC20.BalanceOf(&bind.CallOpts{}, fallbackAddress)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(balance, transferredAmount)
+
+		balance, err = exampleERC20.BalanceOf(&bind.CallOpts{}, homeMockERC20SACRAddress)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(balance, big.NewInt(0))
+
+		// Check that the home released the tokens and reduced the transferred balance of the remote
+		balance, err = exampleERC20.BalanceOf(&bind.CallOpts{}, erc20TokenHomeAddress)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(balance, teleporterUtils.BigIntSub(initialHomeBalance, transferredAmount))
+
+		transferredBalance, err := erc20TokenHome.GetTransferredBalance(
+			&bind.CallOpts{},
+			subnetAInfo.BlockchainID,
+			erc20TokenRemoteAddress,
+		)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(
+			transferredBalance,
+			teleporterUtils.BigIntSub(initialTransferredBalance, transferredAmount),
+		)
+
+		totalSupply, err := erc20TokenRemote.TotalSupply(&bind.CallOpts{})
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(totalSupply, transferredBalance)
+	}
+}
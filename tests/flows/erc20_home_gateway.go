@@ -0,0 +1,326 @@
+package flows
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/gateway"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/subscription"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ava-labs/subnet-evm/interfaces"
+	testinterfaces "github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/gomega"
+)
+
+/**
+ * Deploy an ERC20TokenHome on the primary network, and an ERC20TokenRemote with fewer decimals on Subnet A
+ * Serve the gateway API for the home, with the funded account as its managed signer
+ * List the remotes, and quote a transfer whose excess precision is dropped by the home
+ * Build an unsigned transfer to Subnet A, sign and send its transactions, and track it until it is delivered,
+ * both by polling its status and from the events pushed by the transfer stream
+ * Submit a transfer with the managed signer and an idempotency key, and check that a retry is replayed
+ * without transferring the tokens twice
+ */
+func ERC20TokenHomeGateway(network testinterfaces.Network) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	subnetAInfo, _ := teleporterUtils.GetTwoSubnets(network)
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	// Deploy an ExampleERC20 on the primary network as the token to be transferred
+	exampleERC20Address, exampleERC20 := utils.DeployExampleERC20(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		erc20TokenHomeDecimals,
+	)
+	tokenName, err := exampleERC20.Name(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	tokenSymbol, err := exampleERC20.Symbol(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	homeDecimals, err := exampleERC20.Decimals(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	remoteDecimals := homeDecimals - 6
+
+	erc20TokenHomeAddress, _ := utils.DeployERC20TokenHome(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		fundedAddress,
+		exampleERC20Address,
+		homeDecimals,
+	)
+	erc20TokenRemoteAddress, erc20TokenRemote := utils.DeployERC20TokenRemote(
+		ctx,
+		fundedKey,
+		subnetAInfo,
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		homeDecimals,
+		tokenName,
+		tokenSymbol,
+		remoteDecimals,
+	)
+	utils.RegisterERC20TokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetAInfo,
+		erc20TokenRemoteAddress,
+	)
+
+	s, err := gateway.New(gateway.Config{
+		HomeBlockchainID: cChainInfo.BlockchainID,
+		HomeAddress:      erc20TokenHomeAddress,
+		Chains: map[ids.ID]gateway.Backend{
+			cChainInfo.BlockchainID:  cChainInfo.RPCClient,
+			subnetAInfo.BlockchainID: subnetAInfo.RPCClient,
+		},
+		TeleporterAddress: network.GetTeleporterContractAddress(),
+		SignerKey:         fundedKey,
+		Dial: func(ctx context.Context, blockchainID ids.ID) (subscription.Backend, error) {
+			info := cChainInfo
+			if blockchainID == subnetAInfo.BlockchainID {
+				info = subnetAInfo
+			}
+			return ethclient.DialContext(ctx, teleporterUtils.HttpToWebsocketURI(info.NodeURIs[0], blockchainID.String()))
+		},
+	})
+	Expect(err).Should(BeNil())
+	server := httptest.NewServer(s)
+	defer server.Close()
+	streamCtx, stopStream := context.WithCancel(ctx)
+	streamErr := make(chan error, 1)
+	go func() {
+		streamErr <- s.RunStream(streamCtx)
+	}()
+	defer func() {
+		stopStream()
+		Expect(<-streamErr).Should(MatchError(context.Canceled))
+	}()
+
+	// The remote is listed with its decimals and scaling
+	var remotes gateway.Remotes
+	gatewayRequest(server.URL, http.MethodGet, "/remotes", nil, nil, http.StatusOK, &remotes)
+	Expect(remotes.Home.Kind).Should(Equal(inspect.KindERC20TokenHome))
+	Expect(remotes.Home.TokenAddress).Should(Equal(exampleERC20Address))
+	Expect(remotes.Remotes).Should(HaveLen(1))
+	remote := remotes.Remotes[0]
+	Expect(remote.BlockchainID).Should(Equal(subnetAInfo.BlockchainID))
+	Expect(remote.Address).Should(Equal(erc20TokenRemoteAddress))
+	Expect(remote.Reachable).Should(BeTrue())
+	Expect(remote.Kind).Should(Equal(inspect.KindERC20TokenRemote))
+	Expect(remote.TokenDecimals).Should(Equal(remoteDecimals))
+	teleporterUtils.ExpectBigEqual(remote.TokenMultiplier.Int(), big.NewInt(1e6))
+
+	// The excess precision of the amount is dropped when it is scaled to the remote
+	amount := new(big.Int).Add(utils.ParseAmount("13", homeDecimals), big.NewInt(1))
+	expectedReceived := utils.ParseAmount("13", remoteDecimals)
+	quoteRequest := gateway.QuoteRequest{
+		SourceBlockchainID:      cChainInfo.BlockchainID,
+		Source:                  erc20TokenHomeAddress,
+		DestinationBlockchainID: subnetAInfo.BlockchainID,
+		Destination:             erc20TokenRemoteAddress,
+		Amount:                  gateway.NewAmount(amount),
+	}
+	var quote gateway.Quote
+	gatewayRequest(server.URL, http.MethodPost, "/quote", nil, quoteRequest, http.StatusOK, &quote)
+	Expect(quote.MultiHop).Should(BeFalse())
+	teleporterUtils.ExpectBigEqual(quote.ReceivedAmount.Int(), expectedReceived)
+
+	recipientKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	recipientAddress := crypto.PubkeyToAddress(recipientKey.PublicKey)
+
+	// Follow the transfers to the recipient. The stream may take a moment to start.
+	streamURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/transfers/stream?recipient=" + recipientAddress.Hex()
+	var conn *websocket.Conn
+	Eventually(func() error {
+		conn, _, err = websocket.DefaultDialer.Dial(streamURL, nil)
+		return err
+	}, 30*time.Second, 500*time.Millisecond).Should(Succeed())
+	defer conn.Close()
+
+	// Build the transfer, and sign and send its approval and send transactions
+	transferRequest := gateway.TransferRequest{
+		QuoteRequest: quoteRequest,
+		Sender:       fundedAddress,
+		Recipient:    recipientAddress,
+	}
+	var built gateway.TransferResponse
+	gatewayRequest(server.URL, http.MethodPost, "/transfers", nil, transferRequest, http.StatusOK, &built)
+	Expect(built.Submitted).Should(BeFalse())
+	Expect(built.Transactions).Should(HaveLen(2))
+	Expect(built.Transactions[0].To).Should(Equal(exampleERC20Address))
+	Expect(built.Transactions[1].To).Should(Equal(erc20TokenHomeAddress))
+	var receipt *types.Receipt
+	for _, tx := range built.Transactions {
+		receipt = sendGatewayTransaction(ctx, cChainInfo, tx, fundedKey)
+	}
+	messageID := sentMessageID(cChainInfo, receipt)
+
+	var transfer gateway.Transfer
+	gatewayRequest(server.URL, http.MethodGet, "/transfers/"+messageID.String(), nil, nil, http.StatusOK, &transfer)
+	Expect(transfer.Status).Should(Equal(portfolio.StatusPending))
+	Expect(transfer.Sender).Should(Equal(fundedAddress))
+	Expect(transfer.Recipient).Should(Equal(recipientAddress))
+	teleporterUtils.ExpectBigEqual(transfer.Amount.Int(), expectedReceived)
+
+	network.RelayMessage(ctx, receipt, cChainInfo, subnetAInfo, true)
+	gatewayRequest(server.URL, http.MethodGet, "/transfers/"+messageID.String(), nil, nil, http.StatusOK, &transfer)
+	Expect(transfer.Status).Should(Equal(portfolio.StatusDelivered))
+	balance, err := erc20TokenRemote.BalanceOf(&bind.CallOpts{}, recipientAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(balance, expectedReceived)
+
+	// The stream pushed the send on the C-Chain and the withdrawal on Subnet A
+	sent := readTransferEvent(conn)
+	Expect(sent.Event).Should(Equal(gateway.EventTokensSent))
+	Expect(sent.MessageID).Should(Equal(messageID))
+	Expect(sent.BlockchainID).Should(Equal(cChainInfo.BlockchainID))
+	Expect(*sent.Sender).Should(Equal(fundedAddress))
+	withdrawn := readTransferEvent(conn)
+	Expect(withdrawn.Event).Should(Equal(gateway.EventTokensWithdrawn))
+	Expect(withdrawn.MessageID).Should(Equal(messageID))
+	Expect(withdrawn.BlockchainID).Should(Equal(subnetAInfo.BlockchainID))
+	Expect(withdrawn.Sequence).Should(BeNumerically(">", sent.Sequence))
+	teleporterUtils.ExpectBigEqual(withdrawn.Amount.Int(), expectedReceived)
+
+	// Submit the transfer with the managed signer, and retry it with the same idempotency key
+	transferRequest.Submit = true
+	header := http.Header{gateway.IdempotencyKeyHeader: []string{"gateway-flow-transfer"}}
+	var submitted gateway.TransferResponse
+	body := gatewayRequest(
+		server.URL,
+		http.MethodPost,
+		"/transfers",
+		header,
+		transferRequest,
+		http.StatusCreated,
+		&submitted,
+	)
+	Expect(submitted.Submitted).Should(BeTrue())
+	Expect(submitted.MessageID).ShouldNot(BeNil())
+	Expect(submitted.Transactions).Should(HaveLen(2))
+
+	var replayed gateway.TransferResponse
+	replayedBody := gatewayRequest(
+		server.URL,
+		http.MethodPost,
+		"/transfers",
+		header,
+		transferRequest,
+		http.StatusCreated,
+		&replayed,
+	)
+	Expect(replayedBody).Should(Equal(body))
+
+	sendTxHash := submitted.Transactions[len(submitted.Transactions)-1].Hash
+	Expect(sendTxHash).ShouldNot(BeNil())
+	receipt, err = cChainInfo.RPCClient.TransactionReceipt(ctx, *sendTxHash)
+	Expect(err).Should(BeNil())
+	Expect(sentMessageID(cChainInfo, receipt)).Should(Equal(*submitted.MessageID))
+	network.RelayMessage(ctx, receipt, cChainInfo, subnetAInfo, true)
+
+	// The tokens were only transferred once more
+	balance, err = erc20TokenRemote.BalanceOf(&bind.CallOpts{}, recipientAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(balance, new(big.Int).Mul(expectedReceived, big.NewInt(2)))
+	gatewayRequest(
+		server.URL,
+		http.MethodGet,
+		"/transfers/"+submitted.MessageID.String(),
+		nil,
+		nil,
+		http.StatusOK,
+		&transfer,
+	)
+	Expect(transfer.Status).Should(Equal(portfolio.StatusDelivered))
+}
+
+// gatewayRequest sends a request to the gateway with the JSON encoded request body, if any, expects the
+// response to have the given status, and decodes its body into response. It returns the body of the response.
+func gatewayRequest(
+	url string,
+	method string,
+	path string,
+	header http.Header,
+	request interface{},
+	expectedStatus int,
+	response interface{},
+) []byte {
+	var body bytes.Buffer
+	if request != nil {
+		Expect(json.NewEncoder(&body).Encode(request)).Should(Succeed())
+	}
+	httpRequest, err := http.NewRequest(method, url+path, &body)
+	Expect(err).Should(BeNil())
+	for key, values := range header {
+		httpRequest.Header[key] = values
+	}
+	httpResponse, err := http.DefaultClient.Do(httpRequest)
+	Expect(err).Should(BeNil())
+	defer httpResponse.Body.Close()
+
+	responseBody, err := io.ReadAll(httpResponse.Body)
+	Expect(err).Should(BeNil())
+	Expect(httpResponse.StatusCode).Should(Equal(expectedStatus), string(responseBody))
+	Expect(json.Unmarshal(responseBody, response)).Should(Succeed())
+	return responseBody
+}
+
+// readTransferEvent reads the next event pushed by the transfer stream.
+func readTransferEvent(conn *websocket.Conn) gateway.TransferEvent {
+	Expect(conn.SetReadDeadline(time.Now().Add(30 * time.Second))).Should(Succeed())
+	var event gateway.TransferEvent
+	Expect(conn.ReadJSON(&event)).Should(Succeed())
+	return event
+}
+
+// sendGatewayTransaction signs a transaction built by the gateway with the key, and sends it.
+func sendGatewayTransaction(
+	ctx context.Context,
+	subnet testinterfaces.SubnetTestInfo,
+	tx gateway.Transaction,
+	key *ecdsa.PrivateKey,
+) *types.Receipt {
+	gas, err := subnet.RPCClient.EstimateGas(ctx, interfaces.CallMsg{
+		From:  tx.From,
+		To:    &tx.To,
+		Data:  tx.Data,
+		Value: tx.Value.Int(),
+	})
+	Expect(err).Should(BeNil())
+	gasFeeCap, gasTipCap, _ := teleporterUtils.CalculateTxParams(ctx, subnet, tx.From)
+	signed := teleporterUtils.SignTransaction(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   tx.ChainID.Int(),
+		Nonce:     tx.Nonce,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Gas:       gas,
+		To:        &tx.To,
+		Value:     tx.Value.Int(),
+		Data:      tx.Data,
+	}), key, tx.ChainID.Int())
+	return teleporterUtils.SendTransactionAndWaitForSuccess(ctx, subnet, signed)
+}
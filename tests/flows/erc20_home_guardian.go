@@ -0,0 +1,179 @@
+package flows
+
+import (
+	"context"
+	"math/big"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/guardian"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/crypto"
+	. "github.com/onsi/gomega"
+)
+
+/**
+ * Deploy an ERC20TokenHome on the primary network, and an ERC20TokenRemote on Subnet A, both owned by
+ * a dedicated guardian key
+ * Check the reserves with a guardian that allows the supply of the remote to increase by 10 tokens
+ * between checks, after a transfer of 1 token
+ * Transfer 100 tokens to Subnet A, and check that the guardian reports the mint spike and pauses the
+ * Teleporter address on the home and the remote
+ * Check that transfers from the paused remote are rejected
+ */
+func ERC20TokenHomeGuardian(network interfaces.Network) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	subnetAInfo, _ := teleporterUtils.GetTwoSubnets(network)
+	_, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	guardianKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	guardianAddress := crypto.PubkeyToAddress(guardianKey.PublicKey)
+	for _, subnetInfo := range []interfaces.SubnetTestInfo{cChainInfo, subnetAInfo} {
+		teleporterUtils.SendNativeTransfer(ctx, subnetInfo, fundedKey, guardianAddress, big.NewInt(1e18))
+	}
+
+	exampleERC20Address, exampleERC20 := utils.DeployExampleERC20(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		erc20TokenHomeDecimals,
+	)
+	erc20TokenHomeAddress, erc20TokenHome := utils.DeployERC20TokenHome(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		guardianAddress,
+		exampleERC20Address,
+		erc20TokenHomeDecimals,
+	)
+	erc20TokenRemoteAddress, erc20TokenRemote := utils.DeployERC20TokenRemote(
+		ctx,
+		fundedKey,
+		subnetAInfo,
+		guardianAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		erc20TokenHomeDecimals,
+		"Wrapped Token",
+		"WTKN",
+		erc20TokenHomeDecimals,
+	)
+	utils.RegisterERC20TokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetAInfo,
+		erc20TokenRemoteAddress,
+	)
+
+	recipientKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	recipientAddress := crypto.PubkeyToAddress(recipientKey.PublicKey)
+	input := erc20tokenhome.SendTokensInput{
+		DestinationBlockchainID:            subnetAInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: erc20TokenRemoteAddress,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             exampleERC20Address,
+		PrimaryFee:                         big.NewInt(0),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+	}
+	sendToRemote := func(amount *big.Int) {
+		receipt, _ := utils.SendERC20TokenHome(
+			ctx,
+			cChainInfo,
+			erc20TokenHome,
+			erc20TokenHomeAddress,
+			exampleERC20,
+			input,
+			amount,
+			fundedKey,
+		)
+		network.RelayMessage(ctx, receipt, cChainInfo, subnetAInfo, true)
+	}
+	sendToRemote(big.NewInt(1e18))
+
+	teleporterAddress := network.GetTeleporterContractAddress()
+	g, err := guardian.New(ctx, guardian.Config{
+		HomeBlockchainID: cChainInfo.BlockchainID,
+		HomeAddress:      erc20TokenHomeAddress,
+		Chains: map[ids.ID]guardian.Backend{
+			cChainInfo.BlockchainID:  cChainInfo.RPCClient,
+			subnetAInfo.BlockchainID: subnetAInfo.RPCClient,
+		},
+		TeleporterAddress: teleporterAddress,
+		Key:               guardianKey,
+		Rules:             guardian.Rules{MaxMint: new(big.Int).Mul(big.NewInt(1e18), big.NewInt(10))},
+	})
+	Expect(err).Should(BeNil())
+	Expect(g.Address()).Should(Equal(guardianAddress))
+
+	// The reserves back the token sent so far
+	incident, err := g.Check(ctx)
+	Expect(err).Should(BeNil())
+	Expect(incident).Should(BeNil())
+
+	// Mint far more on the remote than the guardian allows between two checks
+	sendToRemote(new(big.Int).Mul(big.NewInt(1e18), big.NewInt(100)))
+	incident, err = g.Check(ctx)
+	Expect(err).Should(BeNil())
+	Expect(incident).ShouldNot(BeNil())
+	Expect(incident.Violations).Should(HaveLen(1))
+	Expect(incident.Violations[0].Rule).Should(Equal(guardian.RuleMintSpike))
+	Expect(incident.Violations[0].BlockchainID).Should(Equal(subnetAInfo.BlockchainID))
+	Expect(incident.Violations[0].Address).Should(Equal(erc20TokenRemoteAddress))
+	Expect(incident.Reserves.Covered).Should(BeTrue())
+	Expect(incident.Pauses).Should(HaveLen(2))
+	Expect(incident.Pauses[0].Endpoint).Should(Equal(guardian.Endpoint{
+		BlockchainID: cChainInfo.BlockchainID,
+		Address:      erc20TokenHomeAddress,
+	}))
+	Expect(incident.Pauses[1].Endpoint).Should(Equal(guardian.Endpoint{
+		BlockchainID: subnetAInfo.BlockchainID,
+		Address:      erc20TokenRemoteAddress,
+	}))
+	for _, pause := range incident.Pauses {
+		Expect(pause.Error).Should(BeEmpty())
+		Expect(pause.AlreadyPaused).Should(BeFalse())
+		Expect(pause.TxHash).ShouldNot(BeNil())
+	}
+
+	paused, err := erc20TokenHome.IsTeleporterAddressPaused(&bind.CallOpts{}, teleporterAddress)
+	Expect(err).Should(BeNil())
+	Expect(paused).Should(BeTrue())
+	paused, err = erc20TokenRemote.IsTeleporterAddressPaused(&bind.CallOpts{}, teleporterAddress)
+	Expect(err).Should(BeNil())
+	Expect(paused).Should(BeTrue())
+
+	// Transfers from the paused remote are rejected
+	balance, err := erc20TokenRemote.BalanceOf(&bind.CallOpts{}, recipientAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.SendNativeTransfer(ctx, subnetAInfo, fundedKey, recipientAddress, big.NewInt(1e18))
+	opts, err := bind.NewKeyedTransactorWithChainID(recipientKey, subnetAInfo.EVMChainID)
+	Expect(err).Should(BeNil())
+	opts.GasLimit = 1_000_000
+	tx, err := erc20TokenRemote.Send(opts, erc20tokenremote.SendTokensInput{
+		DestinationBlockchainID:            cChainInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: erc20TokenHomeAddress,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             erc20TokenRemoteAddress,
+		PrimaryFee:                         big.NewInt(0),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+	}, balance)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionFailure(ctx, subnetAInfo, tx.Hash())
+
+	// The supply has not changed since the spike, so the next check finds no violation
+	incident, err = g.Check(ctx)
+	Expect(err).Should(BeNil())
+	Expect(incident).Should(BeNil())
+}
@@ -0,0 +1,301 @@
+package flows
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/relayer"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/subscription"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	. "github.com/onsi/gomega"
+)
+
+// Time to wait for the relayer to handle a message
+const relayerResultTimeout = time.Minute
+
+/**
+ * Deploy an ERC20TokenHome on the primary network, and ERC20TokenRemotes on Subnet A and Subnet B
+ * Start a relayer for the three token transferrers, with a minimum fee for the messages sent by the home
+ * Transfer C-Chain example ERC20 tokens to Subnet A, which the relayer delivers
+ * Transfer C-Chain example ERC20 tokens to Subnet A with a fee below the minimum, which the relayer does not deliver
+ * Transfer tokens from Subnet A to Subnet B through multi-hop, which the relayer delivers to the home,
+ * and then follows to Subnet B
+ */
+func ERC20TokenHomeICTTRelayer(network interfaces.Network) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	subnetAInfo, subnetBInfo := teleporterUtils.GetTwoSubnets(network)
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	// Deploy an ExampleERC20 on the primary network as the token to be transferred
+	exampleERC20Address, exampleERC20 := utils.DeployExampleERC20(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		erc20TokenHomeDecimals,
+	)
+	tokenName, err := exampleERC20.Name(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	tokenSymbol, err := exampleERC20.Symbol(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	tokenDecimals, err := exampleERC20.Decimals(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+
+	erc20TokenHomeAddress, erc20TokenHome := utils.DeployERC20TokenHome(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		fundedAddress,
+		exampleERC20Address,
+		tokenDecimals,
+	)
+	erc20TokenRemoteAddressA, erc20TokenRemoteA := utils.DeployERC20TokenRemote(
+		ctx,
+		fundedKey,
+		subnetAInfo,
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		tokenDecimals,
+		tokenName,
+		tokenSymbol,
+		tokenDecimals,
+	)
+	erc20TokenRemoteAddressB, erc20TokenRemoteB := utils.DeployERC20TokenRemote(
+		ctx,
+		fundedKey,
+		subnetBInfo,
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		tokenDecimals,
+		tokenName,
+		tokenSymbol,
+		tokenDecimals,
+	)
+
+	// The registrations are relayed by the network, before the relayer is started
+	utils.RegisterERC20TokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetAInfo,
+		erc20TokenRemoteAddressA,
+	)
+	utils.RegisterERC20TokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetBInfo,
+		erc20TokenRemoteAddressB,
+	)
+
+	// Start a relayer with its own key. Messages sent by the home must pay at least minFee in the example
+	// ERC20, including the second hop of multi-hop transfers, whose fee is the secondary fee.
+	relayerKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	minFee := big.NewInt(1e16)
+	chains := []struct {
+		info        interfaces.SubnetTestInfo
+		transferrer common.Address
+		policy      relayer.Policy
+	}{
+		{
+			info:        cChainInfo,
+			transferrer: erc20TokenHomeAddress,
+			policy:      relayer.Policy{MinFees: map[common.Address]*big.Int{exampleERC20Address: minFee}},
+		},
+		{info: subnetAInfo, transferrer: erc20TokenRemoteAddressA},
+		{info: subnetBInfo, transferrer: erc20TokenRemoteAddressB},
+	}
+	relayerConfig := relayer.Config{
+		TeleporterContractAddress: network.GetTeleporterContractAddress(),
+		Key:                       relayerKey,
+	}
+	for _, chain := range chains {
+		teleporterUtils.SendNativeTransfer(
+			ctx,
+			chain.info,
+			fundedKey,
+			crypto.PubkeyToAddress(relayerKey.PublicKey),
+			big.NewInt(1e18),
+		)
+		fromBlock, err := chain.info.RPCClient.BlockNumber(ctx)
+		Expect(err).Should(BeNil())
+		wsURL := teleporterUtils.HttpToWebsocketURI(chain.info.NodeURIs[0], chain.info.BlockchainID.String())
+		relayerConfig.Chains = append(relayerConfig.Chains, relayer.Chain{
+			BlockchainID: chain.info.BlockchainID,
+			SubnetID:     chain.info.SubnetID,
+			Client:       chain.info.RPCClient,
+			Dial: func(ctx context.Context) (subscription.Backend, error) {
+				return ethclient.DialContext(ctx, wsURL)
+			},
+			NodeURI:      chain.info.NodeURIs[0],
+			Transferrers: []common.Address{chain.transferrer},
+			Policy:       chain.policy,
+			FromBlock:    fromBlock + 1,
+		})
+	}
+	r, err := relayer.New(ctx, relayerConfig)
+	Expect(err).Should(BeNil())
+
+	runCtx, stop := context.WithCancel(ctx)
+	results := make(chan relayer.Result, 16)
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- r.Run(runCtx, results)
+	}()
+	defer func() {
+		stop()
+		Expect(<-runErr).Should(MatchError(context.Canceled))
+	}()
+
+	recipientKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	recipientAddress := crypto.PubkeyToAddress(recipientKey.PublicKey)
+
+	// Send tokens from C-Chain to Subnet A with the minimum fee
+	input := erc20tokenhome.SendTokensInput{
+		DestinationBlockchainID:            subnetAInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: erc20TokenRemoteAddressA,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             exampleERC20Address,
+		PrimaryFee:                         minFee,
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+	}
+	amount := utils.ParseAmount("13", tokenDecimals)
+	receipt, transferredAmount := utils.SendERC20TokenHome(
+		ctx,
+		cChainInfo,
+		erc20TokenHome,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		input,
+		amount,
+		fundedKey,
+	)
+
+	// The relayer delivers the message
+	result := expectRelayerResult(results, sentMessageID(cChainInfo, receipt))
+	Expect(result.Err).Should(BeNil())
+	Expect(result.ExecutionFailed).Should(BeFalse())
+	Expect(result.Message.Type).Should(Equal(relayer.SingleHopSend))
+	teleporterUtils.ExpectBigEqual(result.Message.Amount, transferredAmount)
+	utils.CheckERC20TokenRemoteWithdrawal(
+		ctx,
+		erc20TokenRemoteA,
+		result.DeliveryReceipt,
+		recipientAddress,
+		transferredAmount,
+	)
+
+	// Send tokens from C-Chain to Subnet A with a fee below the minimum, which the relayer does not deliver
+	input.PrimaryFee = new(big.Int).Sub(minFee, big.NewInt(1))
+	receipt, _ = utils.SendERC20TokenHome(
+		ctx,
+		cChainInfo,
+		erc20TokenHome,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		input,
+		amount,
+		fundedKey,
+	)
+	underpaidMessageID := sentMessageID(cChainInfo, receipt)
+	result = expectRelayerResult(results, underpaidMessageID)
+	Expect(errors.Is(result.Err, relayer.ErrFeeTooLow)).Should(BeTrue())
+	Expect(result.DeliveryReceipt).Should(BeNil())
+	received, err := subnetAInfo.TeleporterMessenger.MessageReceived(&bind.CallOpts{}, underpaidMessageID)
+	Expect(err).Should(BeNil())
+	Expect(received).Should(BeFalse())
+
+	// Send tokens from Subnet A to Subnet B through multi-hop. The relayer delivers the first hop
+	// to the home, and then the second hop sent by the delivery to Subnet B.
+	teleporterUtils.SendNativeTransfer(ctx, subnetAInfo, fundedKey, recipientAddress, big.NewInt(1e18))
+	multiHopAmount := new(big.Int).Div(transferredAmount, big.NewInt(2))
+	secondaryFee := minFee
+	receipt, _ = utils.SendERC20TokenRemote(
+		ctx,
+		subnetAInfo,
+		erc20TokenRemoteA,
+		erc20TokenRemoteAddressA,
+		erc20tokenremote.SendTokensInput{
+			DestinationBlockchainID:            subnetBInfo.BlockchainID,
+			DestinationTokenTransferrerAddress: erc20TokenRemoteAddressB,
+			Recipient:                          recipientAddress,
+			PrimaryFeeTokenAddress:             common.Address{},
+			PrimaryFee:                         big.NewInt(0),
+			SecondaryFee:                       secondaryFee,
+			RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+			MultiHopFallback:                   recipientAddress,
+		},
+		multiHopAmount,
+		recipientKey,
+	)
+	// The second hop may be relayed by the subscription to the home before the first result is reported
+	multiHopResults := receiveRelayerResults(results, 2)
+	result, ok := multiHopResults[sentMessageID(subnetAInfo, receipt)]
+	Expect(ok).Should(BeTrue())
+	Expect(result.Err).Should(BeNil())
+	Expect(result.Message.Type).Should(Equal(relayer.MultiHopSend))
+	Expect(result.Message.FinalDestinationBlockchainID).Should(Equal(subnetBInfo.BlockchainID))
+
+	result, ok = multiHopResults[sentMessageID(cChainInfo, result.DeliveryReceipt)]
+	Expect(ok).Should(BeTrue())
+	Expect(result.Err).Should(BeNil())
+	Expect(result.SourceBlockchainID).Should(Equal(cChainInfo.BlockchainID))
+	Expect(result.DestinationBlockchainID).Should(Equal(subnetBInfo.BlockchainID))
+	Expect(result.Message.Type).Should(Equal(relayer.SingleHopSend))
+	routedAmount := new(big.Int).Sub(multiHopAmount, secondaryFee)
+	utils.CheckERC20TokenRemoteWithdrawal(
+		ctx,
+		erc20TokenRemoteB,
+		result.DeliveryReceipt,
+		recipientAddress,
+		routedAmount,
+	)
+	balance, err := erc20TokenRemoteB.BalanceOf(&bind.CallOpts{}, recipientAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(balance, routedAmount)
+}
+
+// sentMessageID returns the ID of the Teleporter message sent in the receipt.
+func sentMessageID(subnet interfaces.SubnetTestInfo, receipt *types.Receipt) ids.ID {
+	event, err := teleporterUtils.GetEventFromLogs(receipt.Logs, subnet.TeleporterMessenger.ParseSendCrossChainMessage)
+	Expect(err).Should(BeNil())
+	return event.MessageID
+}
+
+// expectRelayerResult waits for the next result of the relayer, which must be for the given message.
+func expectRelayerResult(results <-chan relayer.Result, messageID ids.ID) relayer.Result {
+	result := receiveRelayerResults(results, 1)[messageID]
+	Expect(result.MessageID).Should(Equal(messageID))
+	return result
+}
+
+// receiveRelayerResults waits for the next count results of the relayer, and returns them by message ID.
+func receiveRelayerResults(results <-chan relayer.Result, count int) map[ids.ID]relayer.Result {
+	received := make(map[ids.ID]relayer.Result)
+	for i := 0; i < count; i++ {
+		var result relayer.Result
+		Eventually(results, relayerResultTimeout).Should(Receive(&result))
+		received[result.MessageID] = result
+	}
+	return received
+}
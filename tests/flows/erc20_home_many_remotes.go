@@ -0,0 +1,405 @@
+package flows
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	exampleerc20 "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/mocks/ExampleERC20Decimals"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/reserves"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/topology"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	// Number of ERC20TokenRemote instances deployed to each subnet by ERC20TokenHomeManyRemotes
+	remotesPerSubnet = 2
+
+	manyRemotesTokenDecimals = 18
+)
+
+var manyRemotesSecondaryFee = big.NewInt(1e15)
+
+type manyRemotesHome struct {
+	subnet       interfaces.SubnetTestInfo
+	address      common.Address
+	tokenHome    *erc20tokenhome.ERC20TokenHome
+	token        *exampleerc20.ExampleERC20Decimals
+	tokenAddress common.Address
+}
+
+type manyRemotesRemote struct {
+	subnet      interfaces.SubnetTestInfo
+	address     common.Address
+	tokenRemote *erc20tokenremote.ERC20TokenRemote
+}
+
+/**
+ * Deploy an ERC20TokenHome on the primary network
+ * Deploy several ERC20TokenRemote instances to every subnet of the network
+ * Discover the topology of the home, and check that every route between the remotes is viable
+ * Transfer tokens from the C-Chain to every remote
+ * Multi-hop transfer tokens between every ordered pair of remotes, including remotes on the same subnet
+ * Transfer all tokens back to the C-Chain, and check that only the secondary fees were not returned
+ * Attest the reserves of the home, and verify the signed attestation against the chains
+ */
+func ERC20TokenHomeManyRemotes(network interfaces.Network) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	_, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	home := deployManyRemotesHome(ctx, network, cChainInfo)
+
+	var remotes []manyRemotesRemote
+	for _, subnetInfo := range network.GetSubnetsInfo() {
+		for i := 0; i < remotesPerSubnet; i++ {
+			remotes = append(remotes, deployManyRemotesRemote(ctx, network, home, subnetInfo))
+		}
+	}
+	checkManyRemotesTopology(ctx, network, home, remotes)
+
+	recipientKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+
+	transferBetweenManyRemotes(ctx, network, fundedKey, recipientKey, home, remotes)
+
+	checkManyRemotesReserves(ctx, network, home, remotes)
+}
+
+/**
+ * Deploy an ERC20TokenHome to every chain of the network, including the C-Chain
+ * For each home, deploy an ERC20TokenRemote to every other chain, so that every chain
+ * has remotes of the homes on all other chains
+ * For each home, transfer tokens to its remotes, multi-hop between every ordered pair of them,
+ * and transfer all tokens back to the home chain
+ */
+func ERC20TokenHomeOnEveryChain(network interfaces.Network) {
+	chains := append([]interfaces.SubnetTestInfo{network.GetPrimaryNetworkInfo()}, network.GetSubnetsInfo()...)
+	_, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	homes := make([]manyRemotesHome, len(chains))
+	remotes := make([][]manyRemotesRemote, len(chains))
+	for i, homeChain := range chains {
+		homes[i] = deployManyRemotesHome(ctx, network, homeChain)
+		for j, remoteChain := range chains {
+			if i == j {
+				continue
+			}
+			remotes[i] = append(remotes[i], deployManyRemotesRemote(ctx, network, homes[i], remoteChain))
+		}
+	}
+
+	// All of the contracts are deployed before any transfers, so that every transfer
+	// is to or from a chain that also has a home and remotes of other homes.
+	for i := range homes {
+		recipientKey, err := crypto.GenerateKey()
+		Expect(err).Should(BeNil())
+
+		log.Info("Transferring between remotes", "home", homes[i].address, "chain", chains[i].BlockchainID)
+		transferBetweenManyRemotes(ctx, network, fundedKey, recipientKey, homes[i], remotes[i])
+	}
+}
+
+func deployManyRemotesHome(
+	ctx context.Context,
+	network interfaces.Network,
+	subnetInfo interfaces.SubnetTestInfo,
+) manyRemotesHome {
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	tokenAddress, token := utils.DeployExampleERC20(
+		ctx,
+		fundedKey,
+		subnetInfo,
+		manyRemotesTokenDecimals,
+	)
+	address, tokenHome := utils.DeployERC20TokenHome(
+		ctx,
+		fundedKey,
+		subnetInfo,
+		fundedAddress,
+		tokenAddress,
+		manyRemotesTokenDecimals,
+	)
+	return manyRemotesHome{
+		subnet:       subnetInfo,
+		address:      address,
+		tokenHome:    tokenHome,
+		token:        token,
+		tokenAddress: tokenAddress,
+	}
+}
+
+func deployManyRemotesRemote(
+	ctx context.Context,
+	network interfaces.Network,
+	home manyRemotesHome,
+	subnetInfo interfaces.SubnetTestInfo,
+) manyRemotesRemote {
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	address, tokenRemote := utils.DeployERC20TokenRemote(
+		ctx,
+		fundedKey,
+		subnetInfo,
+		fundedAddress,
+		home.subnet.BlockchainID,
+		home.address,
+		manyRemotesTokenDecimals,
+		"Wrapped Token",
+		"WTKN",
+		manyRemotesTokenDecimals,
+	)
+	utils.RegisterERC20TokenRemoteOnHome(
+		ctx,
+		network,
+		home.subnet,
+		home.address,
+		subnetInfo,
+		address,
+	)
+	return manyRemotesRemote{
+		subnet:      subnetInfo,
+		address:     address,
+		tokenRemote: tokenRemote,
+	}
+}
+
+// checkManyRemotesTopology checks that the topology of the home has each of the remotes, in the order they
+// were registered, with every route between them viable.
+func checkManyRemotesTopology(
+	ctx context.Context,
+	network interfaces.Network,
+	home manyRemotesHome,
+	remotes []manyRemotesRemote,
+) {
+	config := topology.Config{
+		HomeBlockchainID: home.subnet.BlockchainID,
+		HomeAddress:      home.address,
+		Chains:           map[ids.ID]portfolio.Backend{home.subnet.BlockchainID: home.subnet.RPCClient},
+	}
+	for _, subnetInfo := range network.GetSubnetsInfo() {
+		config.Chains[subnetInfo.BlockchainID] = subnetInfo.RPCClient
+	}
+	t, err := topology.Discover(ctx, config)
+	Expect(err).Should(BeNil())
+
+	Expect(t.Home.Kind).Should(Equal(inspect.KindERC20TokenHome))
+	Expect(t.Registrations).Should(HaveLen(len(remotes)))
+	for i, registration := range t.Registrations {
+		remote := registration.Remote
+		Expect(remote.BlockchainID).Should(Equal(remotes[i].subnet.BlockchainID))
+		Expect(remote.Address).Should(Equal(remotes[i].address))
+		Expect(remote.Reachable).Should(BeTrue())
+		Expect(remote.Kind).Should(Equal(inspect.KindERC20TokenRemote))
+		Expect(remote.TokenDecimals).Should(Equal(uint8(manyRemotesTokenDecimals)))
+		Expect(remote.Problems).Should(BeEmpty())
+		Expect(registration.Collateralized()).Should(BeTrue())
+		teleporterUtils.ExpectBigEqual(registration.TokenMultiplier, big.NewInt(1))
+	}
+	// A route to and from the home for each remote, and a multi-hop route between every ordered pair
+	Expect(t.Routes).Should(HaveLen(2*len(remotes) + len(remotes)*(len(remotes)-1)))
+	for _, route := range t.Routes {
+		Expect(route.Viable).Should(BeTrue(), route.Reason)
+		teleporterUtils.ExpectBigEqual(route.MinAmount, big.NewInt(1))
+	}
+	Expect(t.DOT()).Should(ContainSubstring(remotes[len(remotes)-1].address.Hex()))
+}
+
+// checkManyRemotesReserves checks that the reserves of the home cover the secondary fees left on the remotes,
+// and that a signed attestation of them is verified against the chains.
+func checkManyRemotesReserves(
+	ctx context.Context,
+	network interfaces.Network,
+	home manyRemotesHome,
+	remotes []manyRemotesRemote,
+) {
+	chains := map[ids.ID]reserves.Backend{home.subnet.BlockchainID: home.subnet.RPCClient}
+	for _, subnetInfo := range network.GetSubnetsInfo() {
+		chains[subnetInfo.BlockchainID] = subnetInfo.RPCClient
+	}
+	a, err := reserves.Gather(ctx, reserves.Config{
+		HomeBlockchainID: home.subnet.BlockchainID,
+		HomeAddress:      home.address,
+		Chains:           chains,
+	})
+	Expect(err).Should(BeNil())
+
+	homeBalance, err := home.token.BalanceOf(&bind.CallOpts{}, home.address)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(a.Home.Balance, homeBalance)
+	Expect(a.Blocks).Should(HaveLen(len(network.GetSubnetsInfo()) + 1))
+	Expect(a.Remotes).Should(HaveLen(len(remotes)))
+	for i, remote := range a.Remotes {
+		Expect(remote.Address).Should(Equal(remotes[i].address))
+		Expect(remote.Attested).Should(BeTrue())
+		Expect(remote.Backed).Should(BeTrue())
+		// The secondary fees kept by the relayer are the only tokens left on the remotes
+		teleporterUtils.ExpectBigEqual(remote.Supply, remote.TransferredBalance)
+	}
+	Expect(a.Covered).Should(BeTrue())
+	teleporterUtils.ExpectBigEqual(a.Surplus, new(big.Int).Sub(a.Home.Balance, a.TotalTransferred))
+
+	signerKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	signed, err := reserves.Sign(a, signerKey)
+	Expect(err).Should(BeNil())
+	opened, err := signed.Open()
+	Expect(err).Should(BeNil())
+	differences, err := reserves.Verify(ctx, opened, chains)
+	Expect(err).Should(BeNil())
+	Expect(differences).Should(BeEmpty())
+}
+
+// transferBetweenManyRemotes sends tokens from the home to each of the remotes, multi-hops one token
+// from every remote to every other remote, and sends all tokens back to the home.
+// All the remotes must have the same decimals as the home token.
+func transferBetweenManyRemotes(
+	ctx context.Context,
+	network interfaces.Network,
+	fundedKey *ecdsa.PrivateKey,
+	recipientKey *ecdsa.PrivateKey,
+	home manyRemotesHome,
+	remotes []manyRemotesRemote,
+) {
+	Expect(len(remotes)).Should(BeNumerically(">=", 2))
+	recipientAddress := crypto.PubkeyToAddress(recipientKey.PublicKey)
+
+	// Every remote multi-hops one token to each of the others, so send it one token per remote.
+	tokenAmount := big.NewInt(1e18)
+	amount := new(big.Int).Mul(tokenAmount, big.NewInt(int64(len(remotes))))
+	for _, remote := range remotes {
+		input := erc20tokenhome.SendTokensInput{
+			DestinationBlockchainID:            remote.subnet.BlockchainID,
+			DestinationTokenTransferrerAddress: remote.address,
+			Recipient:                          recipientAddress,
+			PrimaryFeeTokenAddress:             home.tokenAddress,
+			PrimaryFee:                         big.NewInt(0),
+			SecondaryFee:                       big.NewInt(0),
+			RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+		}
+		receipt, transferredAmount := utils.SendERC20TokenHome(
+			ctx,
+			home.subnet,
+			home.tokenHome,
+			home.address,
+			home.token,
+			input,
+			amount,
+			fundedKey,
+		)
+		receipt = network.RelayMessage(ctx, receipt, home.subnet, remote.subnet, true)
+		utils.CheckERC20TokenRemoteWithdrawal(ctx, remote.tokenRemote, receipt, recipientAddress, transferredAmount)
+	}
+
+	for i, from := range remotes {
+		for j, to := range remotes {
+			if i == j {
+				continue
+			}
+			log.Info("Multi-hop transfer between remotes",
+				"from", fmt.Sprintf("%s:%s", from.subnet.BlockchainID, from.address),
+				"to", fmt.Sprintf("%s:%s", to.subnet.BlockchainID, to.address))
+			utils.SendERC20TokenMultiHopAndVerify(
+				ctx,
+				network,
+				fundedKey,
+				recipientKey,
+				recipientAddress,
+				from.subnet,
+				from.tokenRemote,
+				from.address,
+				to.subnet,
+				to.tokenRemote,
+				to.address,
+				home.subnet,
+				tokenAmount,
+				manyRemotesSecondaryFee,
+			)
+		}
+	}
+
+	// Each remote received one token less the secondary fee from every other remote.
+	multiHops := int64(len(remotes) - 1)
+	expectedRemoteBalance := new(big.Int).Sub(
+		amount,
+		new(big.Int).Mul(manyRemotesSecondaryFee, big.NewInt(multiHops)),
+	)
+	for _, remote := range remotes {
+		balance, err := remote.tokenRemote.BalanceOf(&bind.CallOpts{}, recipientAddress)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(balance, expectedRemoteBalance)
+
+		transferredBalance, err := home.tokenHome.GetTransferredBalance(
+			&bind.CallOpts{},
+			remote.subnet.BlockchainID,
+			remote.address,
+		)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(transferredBalance, expectedRemoteBalance)
+	}
+
+	// Send everything back to the home. The recipient was funded for gas on every
+	// remote chain by the multi-hop transfers.
+	for _, remote := range remotes {
+		input := erc20tokenremote.SendTokensInput{
+			DestinationBlockchainID:            home.subnet.BlockchainID,
+			DestinationTokenTransferrerAddress: home.address,
+			Recipient:                          recipientAddress,
+			PrimaryFeeTokenAddress:             common.Address{},
+			PrimaryFee:                         big.NewInt(0),
+			SecondaryFee:                       big.NewInt(0),
+			RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+		}
+		receipt, transferredAmount := utils.SendERC20TokenRemote(
+			ctx,
+			remote.subnet,
+			remote.tokenRemote,
+			remote.address,
+			input,
+			expectedRemoteBalance,
+			recipientKey,
+		)
+		receipt = network.RelayMessage(ctx, receipt, remote.subnet, home.subnet, true)
+		utils.CheckERC20TokenHomeWithdrawal(
+			ctx,
+			home.address,
+			home.token,
+			receipt,
+			recipientAddress,
+			transferredAmount,
+		)
+
+		transferredBalance, err := home.tokenHome.GetTransferredBalance(
+			&bind.CallOpts{},
+			remote.subnet.BlockchainID,
+			remote.address,
+		)
+		Expect(err).Should(BeNil())
+		Expect(transferredBalance.Sign()).Should(BeZero())
+	}
+
+	// Only the secondary fees of the multi-hop transfers were not returned.
+	balance, err := home.token.BalanceOf(&bind.CallOpts{}, recipientAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(
+		balance,
+		new(big.Int).Mul(expectedRemoteBalance, big.NewInt(int64(len(remotes)))),
+	)
+}
@@ -0,0 +1,425 @@
+package flows
+
+import (
+	"context"
+	"math/big"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	exampleerc20 "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/mocks/ExampleERC20Decimals"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	. "github.com/onsi/gomega"
+)
+
+/**
+ * Deploy an ERC20TokenHome on the primary network
+ * Deploys ERC20TokenRemote to Subnet A and Subnet B
+ * Deploys NativeTokenRemote to Subnet B
+ * Registers the ERC20TokenRemote on Subnet A, and the NativeTokenRemote on Subnet B without collateralizing it
+ * Transfers C-Chain example ERC20 tokens to Subnet A
+ * Multi-hop transfer from Subnet A to the unregistered ERC20TokenRemote on Subnet B,
+ * and check that the tokens are sent to the multi-hop fallback on the C-Chain
+ * Multi-hop transfer from Subnet A to the under-collateralized NativeTokenRemote on Subnet B,
+ * and check that the tokens are sent to the multi-hop fallback on the C-Chain
+ * Multi-hop sendAndCall from Subnet A to the unregistered ERC20TokenRemote on Subnet B,
+ * and check that the tokens are sent to the multi-hop fallback on the C-Chain
+ * Check that the transferred balances on the home are kept consistent throughout
+ */
+func ERC20TokenHomeMultiHopFallback(network interfaces.Network) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	subnetAInfo, subnetBInfo := teleporterUtils.GetTwoSubnets(network)
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	// Deploy an ExampleERC20 on the primary network as the token to be transferred
+	exampleERC20Address, exampleERC20 := utils.DeployExampleERC20(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		erc20TokenHomeDecimals,
+	)
+
+	homeTokenDecimals, err := exampleERC20.Decimals(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+
+	// Create an ERC20TokenHome for transferring the ERC20 token
+	erc20TokenHomeAddress, erc20TokenHome := utils.DeployERC20TokenHome(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		fundedAddress,
+		exampleERC20Address,
+		homeTokenDecimals,
+	)
+
+	// Token representation on subnets A and B will have same name, symbol, and decimals
+	tokenName, err := exampleERC20.Name(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	tokenSymbol, err := exampleERC20.Symbol(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+
+	// Deploy an ERC20TokenRemote to Subnet A
+	erc20TokenRemoteAddressA, erc20TokenRemoteA := utils.DeployERC20TokenRemote(
+		ctx,
+		fundedKey,
+		subnetAInfo,
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		homeTokenDecimals,
+		tokenName,
+		tokenSymbol,
+		homeTokenDecimals,
+	)
+
+	// Deploy an ERC20TokenRemote to Subnet B, which is never registered with the home
+	erc20TokenRemoteAddressB, erc20TokenRemoteB := utils.DeployERC20TokenRemote(
+		ctx,
+		fundedKey,
+		subnetBInfo,
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		homeTokenDecimals,
+		tokenName,
+		tokenSymbol,
+		homeTokenDecimals,
+	)
+
+	// Deploy a NativeTokenRemote to Subnet B, which is registered but never collateralized
+	nativeTokenRemoteAddressB, _ := utils.DeployNativeTokenRemote(
+		ctx,
+		subnetBInfo,
+		"SUBB",
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		homeTokenDecimals,
+		initialReserveImbalance,
+		burnedFeesReportingRewardPercentage,
+	)
+
+	utils.RegisterERC20TokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetAInfo,
+		erc20TokenRemoteAddressA,
+	)
+	collateralNeeded := utils.RegisterTokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetBInfo,
+		nativeTokenRemoteAddressB,
+		initialReserveImbalance,
+		utils.GetTokenMultiplier(decimalsShift),
+		multiplyOnRemote,
+	)
+
+	// Generate new recipient to receive transferred tokens
+	recipientKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	recipientAddress := crypto.PubkeyToAddress(recipientKey.PublicKey)
+
+	// Send tokens from C-Chain to Subnet A
+	input := erc20tokenhome.SendTokensInput{
+		DestinationBlockchainID:            subnetAInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: erc20TokenRemoteAddressA,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             exampleERC20Address,
+		PrimaryFee:                         big.NewInt(1e18),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+	}
+	amount := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(13))
+
+	receipt, transferredAmount := utils.SendERC20TokenHome(
+		ctx,
+		cChainInfo,
+		erc20TokenHome,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		input,
+		amount,
+		fundedKey,
+	)
+
+	receipt = network.RelayMessage(
+		ctx,
+		receipt,
+		cChainInfo,
+		subnetAInfo,
+		true,
+	)
+
+	utils.CheckERC20TokenRemoteWithdrawal(
+		ctx,
+		erc20TokenRemoteA,
+		receipt,
+		recipientAddress,
+		transferredAmount,
+	)
+
+	// Fund the recipient with gas tokens on Subnet A to send the multi-hop transfers
+	teleporterUtils.SendNativeTransfer(
+		ctx,
+		subnetAInfo,
+		fundedKey,
+		recipientAddress,
+		big.NewInt(1e18),
+	)
+
+	multiHopAmount := big.NewInt(0).Div(transferredAmount, big.NewInt(4))
+	secondaryFeeAmount := big.NewInt(0).Div(multiHopAmount, big.NewInt(4))
+
+	// Multi-hop transfer to the unregistered ERC20TokenRemote on Subnet B
+	{
+		fallbackKey, err := crypto.GenerateKey()
+		Expect(err).Should(BeNil())
+		fallbackAddress := crypto.PubkeyToAddress(fallbackKey.PublicKey)
+
+		input := erc20tokenremote.SendTokensInput{
+			DestinationBlockchainID:            subnetBInfo.BlockchainID,
+			DestinationTokenTransferrerAddress: erc20TokenRemoteAddressB,
+			Recipient:                          recipientAddress,
+			PrimaryFeeTokenAddress:             common.Address{},
+			PrimaryFee:                         big.NewInt(0),
+			SecondaryFee:                       secondaryFeeAmount,
+			RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+			MultiHopFallback:                   fallbackAddress,
+		}
+
+		sendMultiHopAndVerifyFallback(
+			ctx,
+			network,
+			erc20TokenHome,
+			erc20TokenHomeAddress,
+			exampleERC20,
+			subnetAInfo,
+			erc20TokenRemoteAddressA,
+			subnetBInfo,
+			erc20TokenRemoteAddressB,
+			cChainInfo,
+			fallbackAddress,
+			func() *types.Receipt {
+				receipt, _ := utils.SendERC20TokenRemote(
+					ctx,
+					subnetAInfo,
+					erc20TokenRemoteA,
+					erc20TokenRemoteAddressA,
+					input,
+					multiHopAmount,
+					recipientKey,
+				)
+				return receipt
+			},
+			multiHopAmount,
+		)
+
+		// No tokens are minted on the unregistered remote
+		totalSupply, err := erc20TokenRemoteB.TotalSupply(&bind.CallOpts{})
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(totalSupply, big.NewInt(0))
+	}
+
+	// Multi-hop transfer to the under-collateralized NativeTokenRemote on Subnet B
+	{
+		fallbackKey, err := crypto.GenerateKey()
+		Expect(err).Should(BeNil())
+		fallbackAddress := crypto.PubkeyToAddress(fallbackKey.PublicKey)
+
+		input := erc20tokenremote.SendTokensInput{
+			DestinationBlockchainID:            subnetBInfo.BlockchainID,
+			DestinationTokenTransferrerAddress: nativeTokenRemoteAddressB,
+			Recipient:                          recipientAddress,
+			PrimaryFeeTokenAddress:             common.Address{},
+			PrimaryFee:                         big.NewInt(0),
+			SecondaryFee:                       secondaryFeeAmount,
+			RequiredGasLimit:                   utils.DefaultNativeTokenRequiredGas,
+			MultiHopFallback:                   fallbackAddress,
+		}
+
+		sendMultiHopAndVerifyFallback(
+			ctx,
+			network,
+			erc20TokenHome,
+			erc20TokenHomeAddress,
+			exampleERC20,
+			subnetAInfo,
+			erc20TokenRemoteAddressA,
+			subnetBInfo,
+			nativeTokenRemoteAddressB,
+			cChainInfo,
+			fallbackAddress,
+			func() *types.Receipt {
+				receipt, _ := utils.SendERC20TokenRemote(
+					ctx,
+					subnetAInfo,
+					erc20TokenRemoteA,
+					erc20TokenRemoteAddressA,
+					input,
+					multiHopAmount,
+					recipientKey,
+				)
+				return receipt
+			},
+			multiHopAmount,
+		)
+
+		// The collateral needed by the NativeTokenRemote is unchanged
+		remoteSettings, err := erc20TokenHome.GetRemoteTokenTransferrerSettings(
+			&bind.CallOpts{},
+			subnetBInfo.BlockchainID,
+			nativeTokenRemoteAddressB,
+		)
+		Expect(err).Should(BeNil())
+		teleporterUtils.ExpectBigEqual(remoteSettings.CollateralNeeded, collateralNeeded)
+	}
+
+	// Multi-hop sendAndCall to the unregistered ERC20TokenRemote on Subnet B
+	{
+		fallbackKey, err := crypto.GenerateKey()
+		Expect(err).Should(BeNil())
+		fallbackAddress := crypto.PubkeyToAddress(fallbackKey.PublicKey)
+
+		input := erc20tokenremote.SendAndCallInput{
+			DestinationBlockchainID:            subnetBInfo.BlockchainID,
+			DestinationTokenTransferrerAddress: erc20TokenRemoteAddressB,
+			RecipientContract:                  recipientAddress,
+			RecipientPayload:                   []byte{1},
+			RequiredGasLimit:                   teleporterUtils.BigIntMul(big.NewInt(10), utils.DefaultERC20RequiredGas),
+			RecipientGasLimit:                  teleporterUtils.BigIntMul(big.NewInt(5), utils.DefaultERC20RequiredGas),
+			MultiHopFallback:                   fallbackAddress,
+			FallbackRecipient:                  recipientAddress,
+			PrimaryFeeTokenAddress:             common.Address{},
+			PrimaryFee:                         big.NewInt(0),
+			SecondaryFee:                       secondaryFeeAmount,
+		}
+
+		sendMultiHopAndVerifyFallback(
+			ctx,
+			network,
+			erc20TokenHome,
+			erc20TokenHomeAddress,
+			exampleERC20,
+			subnetAInfo,
+			erc20TokenRemoteAddressA,
+			subnetBInfo,
+			erc20TokenRemoteAddressB,
+			cChainInfo,
+			fallbackAddress,
+			func() *types.Receipt {
+				receipt, _ := utils.SendAndCallERC20TokenRemote(
+					ctx,
+					subnetAInfo,
+					erc20TokenRemoteA,
+					erc20TokenRemoteAddressA,
+					input,
+					multiHopAmount,
+					recipientKey,
+				)
+				return receipt
+			},
+			multiHopAmount,
+		)
+	}
+}
+
+// sendMultiHopAndVerifyFallback sends a multi-hop transfer from the remote on fromSubnet with the given send
+// function, relays it to the home, and checks that the home does not route the transfer to the remote on
+// toSubnet, but instead withdraws the full amount to the multi-hop fallback on the home chain.
+func sendMultiHopAndVerifyFallback(
+	ctx context.Context,
+	network interfaces.Network,
+	erc20TokenHome *erc20tokenhome.ERC20TokenHome,
+	erc20TokenHomeAddress common.Address,
+	exampleERC20 *exampleerc20.ExampleERC20Decimals,
+	fromSubnet interfaces.SubnetTestInfo,
+	fromTokenTransferrerAddress common.Address,
+	toSubnet interfaces.SubnetTestInfo,
+	toTokenTransferrerAddress common.Address,
+	cChainInfo interfaces.SubnetTestInfo,
+	multiHopFallback common.Address,
+	send func() *types.Receipt,
+	amount *big.Int,
+) {
+	initialFromBalance := getTransferredBalance(erc20TokenHome, fromSubnet.BlockchainID, fromTokenTransferrerAddress)
+	initialToBalance := getTransferredBalance(erc20TokenHome, toSubnet.BlockchainID, toTokenTransferrerAddress)
+	initialHomeBalance, err := exampleERC20.BalanceOf(&bind.CallOpts{}, erc20TokenHomeAddress)
+	Expect(err).Should(BeNil())
+
+	originReceipt := send()
+
+	// Relay the message to the home. The home cannot route the transfer to the destination, so it
+	// sends the tokens to the multi-hop fallback instead of sending a second Teleporter message.
+	receipt := network.RelayMessage(
+		ctx,
+		originReceipt,
+		fromSubnet,
+		cChainInfo,
+		true,
+	)
+
+	_, err = teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenHome.ParseTokensRouted)
+	Expect(err).ShouldNot(BeNil())
+	_, err = teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenHome.ParseTokensAndCallRouted)
+	Expect(err).ShouldNot(BeNil())
+	_, err = teleporterUtils.GetEventFromLogs(receipt.Logs, cChainInfo.TeleporterMessenger.ParseSendCrossChainMessage)
+	Expect(err).ShouldNot(BeNil())
+
+	// The fallback receives the full amount, since the secondary fee is only deducted when routing.
+	// The token multiplier is 1, so the amount is the same on the home and the remote.
+	withdrawnEvent, err := teleporterUtils.GetEventFromLogs(receipt.Logs, erc20TokenHome.ParseTokensWithdrawn)
+	Expect(err).Should(BeNil())
+	Expect(withdrawnEvent.Recipient).Should(Equal(multiHopFallback))
+	teleporterUtils.ExpectBigEqual(withdrawnEvent.Amount, amount)
+
+	utils.CheckERC20TokenHomeWithdrawal(
+		ctx,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		receipt,
+		multiHopFallback,
+		amount,
+	)
+
+	balance, err := exampleERC20.BalanceOf(&bind.CallOpts{}, multiHopFallback)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(balance, amount)
+
+	// The home released the tokens, the transferred balance of the source remote is reduced,
+	// and the transferred balance of the destination remote is unchanged.
+	balance, err = exampleERC20.BalanceOf(&bind.CallOpts{}, erc20TokenHomeAddress)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(balance, teleporterUtils.BigIntSub(initialHomeBalance, amount))
+
+	teleporterUtils.ExpectBigEqual(
+		getTransferredBalance(erc20TokenHome, fromSubnet.BlockchainID, fromTokenTransferrerAddress),
+		teleporterUtils.BigIntSub(initialFromBalance, amount),
+	)
+	teleporterUtils.ExpectBigEqual(
+		getTransferredBalance(erc20TokenHome, toSubnet.BlockchainID, toTokenTransferrerAddress),
+		initialToBalance,
+	)
+}
+
+func getTransferredBalance(
+	erc20TokenHome *erc20tokenhome.ERC20TokenHome,
+	remoteBlockchainID ids.ID,
+	remoteAddress common.Address,
+) *big.Int {
+	balance, err := erc20TokenHome.GetTransferredBalance(&bind.CallOpts{}, remoteBlockchainID, remoteAddress)
+	Expect(err).Should(BeNil())
+	return balance
+}
@@ -0,0 +1,280 @@
+package flows
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/subscription"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/webhook"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	. "github.com/onsi/gomega"
+)
+
+const webhookSecret = "e2e-webhook-secret"
+
+// webhookReceiver collects the notifications of each subscription, after rejecting the first request
+// it receives so that its delivery is retried.
+type webhookReceiver struct {
+	lock          sync.Mutex
+	rejected      bool
+	notifications map[string][]webhook.Notification
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := webhook.VerifyRequest(req, webhookSecret, time.Minute)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var n webhook.Notification
+	if err := json.Unmarshal(body, &n); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.rejected {
+		r.rejected = true
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	r.notifications[n.Subscription] = append(r.notifications[n.Subscription], n)
+}
+
+// events returns the notifications of each event to a subscription.
+func (r *webhookReceiver) events(subscription string) map[webhook.EventType]webhook.Notification {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	events := make(map[webhook.EventType]webhook.Notification)
+	for _, n := range r.notifications[subscription] {
+		events[n.Event] = n
+	}
+	return events
+}
+
+// decodeNotificationData decodes the data of a notification, received as JSON, into one of the data types
+// of the webhook package.
+func decodeNotificationData(n webhook.Notification, data interface{}) {
+	encoded, err := json.Marshal(n.Data)
+	Expect(err).Should(BeNil())
+	Expect(json.Unmarshal(encoded, data)).Should(Succeed())
+}
+
+/**
+ * Deploy an ERC20TokenHome on the primary network, and a NativeTokenRemote on Subnet A
+ * Notify an operations subscription of the registration of the remote, of the collateral that fully
+ * collateralizes it, and of the report of the fees burned on Subnet A
+ * Notify a treasury subscription of the transfers of at least 5 tokens, and not of smaller transfers
+ * Check that the first notification, which is rejected by the receiver, is delivered again
+ */
+func ERC20TokenHomeWebhooks(network interfaces.Network) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	subnetAInfo, _ := teleporterUtils.GetTwoSubnets(network)
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	exampleERC20Address, exampleERC20 := utils.DeployExampleERC20(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		erc20TokenHomeDecimals,
+	)
+	exampleERC20Decimals, err := exampleERC20.Decimals(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	erc20TokenHomeAddress, erc20TokenHome := utils.DeployERC20TokenHome(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		fundedAddress,
+		exampleERC20Address,
+		exampleERC20Decimals,
+	)
+	nativeTokenRemoteAddressA, nativeTokenRemoteA := utils.DeployNativeTokenRemote(
+		ctx,
+		subnetAInfo,
+		"SUBA",
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		exampleERC20Decimals,
+		initialReserveImbalance,
+		burnedFeesReportingRewardPercentage,
+	)
+
+	// Follow the events from the current blocks, before the remote is registered
+	cChainStart, err := cChainInfo.RPCClient.BlockNumber(ctx)
+	Expect(err).Should(BeNil())
+	subnetAStart, err := subnetAInfo.RPCClient.BlockNumber(ctx)
+	Expect(err).Should(BeNil())
+	receiver := &webhookReceiver{notifications: make(map[string][]webhook.Notification)}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	deadLetterStore := &webhook.MemoryDeadLetters{}
+	largeAmount := new(big.Int).Mul(big.NewInt(1e18), big.NewInt(10))
+	dial := func(subnet interfaces.SubnetTestInfo) func(ctx context.Context) (subscription.Backend, error) {
+		return func(ctx context.Context) (subscription.Backend, error) {
+			wsURI := teleporterUtils.HttpToWebsocketURI(subnet.NodeURIs[0], subnet.BlockchainID.String())
+			return ethclient.DialContext(ctx, wsURI)
+		}
+	}
+	d, err := webhook.New(webhook.Config{
+		Chains: []webhook.Chain{
+			{
+				BlockchainID: cChainInfo.BlockchainID,
+				Dial:         dial(cChainInfo),
+				Transferrers: []common.Address{erc20TokenHomeAddress},
+				FromBlock:    cChainStart,
+			},
+			{
+				BlockchainID: subnetAInfo.BlockchainID,
+				Dial:         dial(subnetAInfo),
+				Transferrers: []common.Address{nativeTokenRemoteAddressA},
+				FromBlock:    subnetAStart,
+			},
+		},
+		Subscriptions: []webhook.Subscription{
+			{
+				Name:   "operations",
+				URL:    server.URL,
+				Secret: webhookSecret,
+				Filter: webhook.Filter{
+					Events: []webhook.EventType{
+						webhook.EventRemoteRegistered,
+						webhook.EventCollateralAdded,
+						webhook.EventReportBurnedTxFees,
+					},
+					FullyCollateralizedOnly: true,
+				},
+			},
+			{
+				Name:   "treasury",
+				URL:    server.URL,
+				Secret: webhookSecret,
+				Filter: webhook.Filter{
+					Events:        []webhook.EventType{webhook.EventTokensSent},
+					BlockchainIDs: []ids.ID{cChainInfo.BlockchainID},
+					MinAmount:     new(big.Int).Div(largeAmount, big.NewInt(2)),
+				},
+			},
+		},
+		InitialBackoff: 100 * time.Millisecond,
+		DeadLetters:    deadLetterStore,
+	})
+	Expect(err).Should(BeNil())
+	dispatcherCtx, stopDispatcher := context.WithCancel(ctx)
+	dispatcherErr := make(chan error, 1)
+	go func() {
+		dispatcherErr <- d.Run(dispatcherCtx)
+	}()
+	defer func() {
+		stopDispatcher()
+		Expect(<-dispatcherErr).Should(MatchError(context.Canceled))
+	}()
+
+	collateralAmount := utils.RegisterTokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetAInfo,
+		nativeTokenRemoteAddressA,
+		initialReserveImbalance,
+		tokenMultiplier,
+		multiplyOnRemote,
+	)
+	utils.AddCollateralToERC20TokenHome(
+		ctx,
+		cChainInfo,
+		erc20TokenHome,
+		erc20TokenHomeAddress,
+		exampleERC20,
+		subnetAInfo.BlockchainID,
+		nativeTokenRemoteAddressA,
+		collateralAmount,
+		fundedKey,
+	)
+
+	// Send a small and a large amount to Subnet A. Only the large transfer is notified.
+	recipientKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	recipientAddress := crypto.PubkeyToAddress(recipientKey.PublicKey)
+	input := erc20tokenhome.SendTokensInput{
+		DestinationBlockchainID:            subnetAInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: nativeTokenRemoteAddressA,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             exampleERC20Address,
+		PrimaryFee:                         big.NewInt(0),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultNativeTokenRequiredGas,
+	}
+	var sentMessageIDs []ids.ID
+	for _, amount := range []*big.Int{big.NewInt(1e18), largeAmount} {
+		receipt, _ := utils.SendERC20TokenHome(
+			ctx,
+			cChainInfo,
+			erc20TokenHome,
+			erc20TokenHomeAddress,
+			exampleERC20,
+			input,
+			amount,
+			fundedKey,
+		)
+		sentMessageIDs = append(sentMessageIDs, sentMessageID(cChainInfo, receipt))
+		network.RelayMessage(ctx, receipt, cChainInfo, subnetAInfo, true)
+	}
+
+	// Report the fees burned on Subnet A by the transactions so far
+	opts, err := bind.NewKeyedTransactorWithChainID(fundedKey, subnetAInfo.EVMChainID)
+	Expect(err).Should(BeNil())
+	tx, err := nativeTokenRemoteA.ReportBurnedTxFees(opts, utils.DefaultNativeTokenRequiredGas)
+	Expect(err).Should(BeNil())
+	receipt := teleporterUtils.WaitForTransactionSuccess(ctx, subnetAInfo, tx.Hash())
+	reported, err := teleporterUtils.GetEventFromLogs(receipt.Logs, nativeTokenRemoteA.ParseReportBurnedTxFees)
+	Expect(err).Should(BeNil())
+
+	Eventually(func() int {
+		return len(receiver.events("operations"))
+	}, 60*time.Second, 500*time.Millisecond).Should(Equal(3))
+	operations := receiver.events("operations")
+	var registered webhook.RemoteRegisteredData
+	decodeNotificationData(operations[webhook.EventRemoteRegistered], &registered)
+	Expect(registered.RemoteBlockchainID).Should(Equal(subnetAInfo.BlockchainID))
+	Expect(registered.RemoteTokenTransferrerAddress).Should(Equal(nativeTokenRemoteAddressA))
+	var collateralized webhook.CollateralAddedData
+	decodeNotificationData(operations[webhook.EventCollateralAdded], &collateralized)
+	Expect(collateralized.Remaining).Should(Equal("0"))
+	var burned webhook.ReportBurnedTxFeesData
+	decodeNotificationData(operations[webhook.EventReportBurnedTxFees], &burned)
+	Expect(burned.MessageID).Should(Equal(ids.ID(reported.TeleporterMessageID)))
+	Expect(burned.FeesBurned).Should(Equal(reported.FeesBurned.String()))
+	Expect(operations[webhook.EventReportBurnedTxFees].BlockchainID).Should(Equal(subnetAInfo.BlockchainID))
+
+	Eventually(func() int {
+		return len(receiver.events("treasury"))
+	}, 60*time.Second, 500*time.Millisecond).Should(Equal(1))
+	var sent webhook.TokensSentData
+	decodeNotificationData(receiver.events("treasury")[webhook.EventTokensSent], &sent)
+	Expect(sent.MessageID).Should(Equal(sentMessageIDs[1]))
+	Expect(sent.Amount).Should(Equal(largeAmount.String()))
+	Expect(sent.Recipient).Should(Equal(recipientAddress))
+
+	// Every notification was delivered, including the one first rejected by the receiver
+	deadLetters, err := deadLetterStore.List()
+	Expect(err).Should(BeNil())
+	Expect(deadLetters).Should(BeEmpty())
+}
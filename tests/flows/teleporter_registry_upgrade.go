@@ -0,0 +1,361 @@
+package flows
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/admin"
+	avalancheWarp "github.com/ava-labs/avalanchego/vms/platformvm/warp"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	teleporterregistry "github.com/ava-labs/teleporter/abi-bindings/go/teleporter/registry/TeleporterRegistry"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+/**
+ * Deploy a TeleporterRegistry on the primary network and Subnet A with the current Teleporter version
+ * Deploy an ERC20TokenHome on the primary network, and an ERC20TokenRemote on Subnet A, using those registries
+ * Transfer tokens to Subnet A through the current Teleporter version
+ * Deploy a new Teleporter version, and register it in the registries of the home and the remote
+ * Migrate the home and the remote to the new version with the admin tooling: raise their minimum Teleporter
+ * version, and pause the previous Teleporter address
+ * Transfer tokens to Subnet A through the new version
+ * Pause the new Teleporter address on the remote, and check that a transfer fails to execute until the
+ * address is unpaused and the message is retried
+ * Transfer the ownership of the home, and check that the previous owner can no longer administer it
+ * Check that the audit log of each contract records every action in order
+ *
+ * The new version is only registered in the registries of the home and the remote, and the Teleporter address of
+ * the network is restored once the spec ends, so that the other specs keep using the current version. The nodes
+ * are restarted to register the new version, so no other spec may run alongside this one.
+ */
+func TeleporterRegistryUpgrade(network interfaces.LocalNetwork, teleporterByteCodeFile string) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	subnetAInfo, _ := teleporterUtils.GetTwoSubnets(network)
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	oldTeleporterAddress := network.GetTeleporterContractAddress()
+	ginkgo.DeferCleanup(func() {
+		network.SetTeleporterContractAddress(oldTeleporterAddress)
+	})
+	homeRegistryAddress := deployTeleporterRegistry(ctx, cChainInfo, fundedKey, oldTeleporterAddress)
+	remoteRegistryAddress := deployTeleporterRegistry(ctx, subnetAInfo, fundedKey, oldTeleporterAddress)
+	cChainInfo = withTeleporterRegistry(cChainInfo, homeRegistryAddress)
+	subnetAInfo = withTeleporterRegistry(subnetAInfo, remoteRegistryAddress)
+
+	exampleERC20Address, exampleERC20 := utils.DeployExampleERC20(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		erc20TokenHomeDecimals,
+	)
+	erc20TokenHomeAddress, erc20TokenHome := utils.DeployERC20TokenHome(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		fundedAddress,
+		exampleERC20Address,
+		erc20TokenHomeDecimals,
+	)
+	erc20TokenRemoteAddress, erc20TokenRemote := utils.DeployERC20TokenRemote(
+		ctx,
+		fundedKey,
+		subnetAInfo,
+		fundedAddress,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		erc20TokenHomeDecimals,
+		"Wrapped Token",
+		"WTKN",
+		erc20TokenHomeDecimals,
+	)
+	utils.RegisterERC20TokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		erc20TokenHomeAddress,
+		subnetAInfo,
+		erc20TokenRemoteAddress,
+	)
+
+	// Audit the actions taken from here on
+	homeFromBlock, err := cChainInfo.RPCClient.BlockNumber(ctx)
+	Expect(err).Should(BeNil())
+	remoteFromBlock, err := subnetAInfo.RPCClient.BlockNumber(ctx)
+	Expect(err).Should(BeNil())
+
+	recipientKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	recipientAddress := crypto.PubkeyToAddress(recipientKey.PublicKey)
+	input := erc20tokenhome.SendTokensInput{
+		DestinationBlockchainID:            subnetAInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: erc20TokenRemoteAddress,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             exampleERC20Address,
+		PrimaryFee:                         big.NewInt(0),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultERC20RequiredGas,
+	}
+	amount := big.NewInt(1e18)
+	// sendToRemote sends the amount to Subnet A, and returns the receipt of its delivery
+	sendToRemote := func() *types.Receipt {
+		receipt, _ := utils.SendERC20TokenHome(
+			ctx,
+			cChainInfo,
+			erc20TokenHome,
+			erc20TokenHomeAddress,
+			exampleERC20,
+			input,
+			amount,
+			fundedKey,
+		)
+		return network.RelayMessage(ctx, receipt, cChainInfo, subnetAInfo, true)
+	}
+	expectRemoteBalance := func(transfers int64) {
+		balance, err := erc20TokenRemote.BalanceOf(&bind.CallOpts{}, recipientAddress)
+		Expect(err).Should(BeNil())
+		Expect(balance).Should(Equal(new(big.Int).Mul(amount, big.NewInt(transfers))))
+	}
+	sendToRemote()
+	expectRemoteBalance(1)
+
+	// Deploy the new Teleporter version, and register it in the registries of the home and the remote
+	latestVersion, err := cChainInfo.TeleporterRegistry.LatestVersion(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	newVersion := new(big.Int).Add(latestVersion, big.NewInt(1))
+	newTeleporterAddress := teleporterUtils.DeployNewTeleporterVersion(
+		ctx,
+		network,
+		fundedKey,
+		teleporterByteCodeFile,
+	)
+	subnets := []interfaces.SubnetTestInfo{cChainInfo, subnetAInfo}
+	chainConfigs := make(teleporterUtils.ChainConfigMap)
+	offChainMessages := make([]*avalancheWarp.UnsignedMessage, len(subnets))
+	for i, subnetInfo := range subnets {
+		var chainConfig string
+		offChainMessages[i], chainConfig = teleporterUtils.InitOffChainMessageChainConfig(
+			network.GetNetworkID(),
+			subnetInfo,
+			newTeleporterAddress,
+			newVersion.Uint64(),
+		)
+		chainConfigs.Add(subnetInfo, chainConfig)
+	}
+	network.SetChainConfigs(chainConfigs)
+	network.RestartNodes(ctx, network.GetAllNodeIDs())
+	for i, subnetInfo := range subnets {
+		teleporterUtils.AddProtocolVersionAndWaitForAcceptance(
+			ctx,
+			network,
+			subnetInfo,
+			newTeleporterAddress,
+			fundedKey,
+			offChainMessages[i],
+		)
+	}
+	network.SetTeleporterContractAddress(newTeleporterAddress)
+	cChainInfo = withTeleporterRegistry(network.GetPrimaryNetworkInfo(), homeRegistryAddress)
+	subnetAInfo, _ = teleporterUtils.GetTwoSubnets(network)
+	subnetAInfo = withTeleporterRegistry(subnetAInfo, remoteRegistryAddress)
+
+	// Migrate the home and the remote to the new version
+	transferrers := []struct {
+		subnetInfo interfaces.SubnetTestInfo
+		address    common.Address
+	}{
+		{cChainInfo, erc20TokenHomeAddress},
+		{subnetAInfo, erc20TokenRemoteAddress},
+	}
+	for _, transferrer := range transferrers {
+		for _, action := range []admin.Action{
+			admin.UpdateMinTeleporterVersion(newVersion),
+			admin.PauseTeleporterAddress(oldTeleporterAddress),
+		} {
+			client := transferrer.subnetInfo.RPCClient
+			receipt, err := admin.Execute(ctx, client, fundedKey, transferrer.address, action)
+			Expect(err).Should(BeNil())
+			Expect(receipt.Status).Should(Equal(types.ReceiptStatusSuccessful))
+
+			// Actions are only sent once
+			_, err = admin.Execute(ctx, client, fundedKey, transferrer.address, action)
+			Expect(err).Should(MatchError(admin.ErrNoChange))
+		}
+	}
+	for _, version := range []func(*bind.CallOpts) (*big.Int, error){
+		erc20TokenHome.GetMinTeleporterVersion,
+		erc20TokenRemote.GetMinTeleporterVersion,
+	} {
+		minVersion, err := version(&bind.CallOpts{})
+		Expect(err).Should(BeNil())
+		Expect(minVersion.Cmp(newVersion)).Should(Equal(0))
+	}
+
+	// Tokens are sent through the new version
+	receipt := sendToRemote()
+	var deliveredBy []common.Address
+	for _, l := range receipt.Logs {
+		deliveredBy = append(deliveredBy, l.Address)
+	}
+	Expect(deliveredBy).Should(ContainElement(newTeleporterAddress))
+	Expect(deliveredBy).ShouldNot(ContainElement(oldTeleporterAddress))
+	expectRemoteBalance(2)
+
+	// Messages delivered by a paused address fail to execute, and can be retried once it is unpaused
+	_, err = admin.Execute(
+		ctx,
+		subnetAInfo.RPCClient,
+		fundedKey,
+		erc20TokenRemoteAddress,
+		admin.PauseTeleporterAddress(newTeleporterAddress),
+	)
+	Expect(err).Should(BeNil())
+	receipt = sendToRemote()
+	executionFailed, err := teleporterUtils.GetEventFromLogs(
+		receipt.Logs,
+		subnetAInfo.TeleporterMessenger.ParseMessageExecutionFailed,
+	)
+	Expect(err).Should(BeNil())
+	expectRemoteBalance(2)
+
+	_, err = admin.Execute(
+		ctx,
+		subnetAInfo.RPCClient,
+		fundedKey,
+		erc20TokenRemoteAddress,
+		admin.UnpauseTeleporterAddress(newTeleporterAddress),
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.RetryMessageExecutionAndWaitForAcceptance(
+		ctx,
+		cChainInfo.BlockchainID,
+		subnetAInfo,
+		executionFailed.Message,
+		fundedKey,
+	)
+	expectRemoteBalance(3)
+
+	// Hand the home over to a new owner
+	newOwnerKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	newOwnerAddress := crypto.PubkeyToAddress(newOwnerKey.PublicKey)
+	_, err = admin.Execute(
+		ctx,
+		cChainInfo.RPCClient,
+		fundedKey,
+		erc20TokenHomeAddress,
+		admin.TransferOwnership(newOwnerAddress),
+	)
+	Expect(err).Should(BeNil())
+	owner, err := erc20TokenHome.Owner(&bind.CallOpts{})
+	Expect(err).Should(BeNil())
+	Expect(owner).Should(Equal(newOwnerAddress))
+	_, err = admin.Execute(
+		ctx,
+		cChainInfo.RPCClient,
+		fundedKey,
+		erc20TokenHomeAddress,
+		admin.UnpauseTeleporterAddress(oldTeleporterAddress),
+	)
+	Expect(err).Should(MatchError(admin.ErrNotOwner))
+
+	// The audit log records every action in order
+	auditLog := &admin.AuditLog{Dir: ginkgo.GinkgoT().TempDir()}
+	homeEntries, err := auditLog.Sync(
+		ctx,
+		cChainInfo.RPCClient,
+		cChainInfo.BlockchainID,
+		erc20TokenHomeAddress,
+		homeFromBlock+1,
+		0,
+	)
+	Expect(err).Should(BeNil())
+	Expect(homeEntries).Should(HaveLen(3))
+	Expect(homeEntries[0].Event).Should(Equal(admin.EventMinTeleporterVersionUpdated))
+	Expect(homeEntries[0].OldMinTeleporterVersion.Cmp(latestVersion)).Should(Equal(0))
+	Expect(homeEntries[0].NewMinTeleporterVersion.Cmp(newVersion)).Should(Equal(0))
+	Expect(homeEntries[1].Event).Should(Equal(admin.EventTeleporterAddressPaused))
+	Expect(*homeEntries[1].TeleporterAddress).Should(Equal(oldTeleporterAddress))
+	Expect(homeEntries[2].Event).Should(Equal(admin.EventOwnershipTransferred))
+	Expect(*homeEntries[2].PreviousOwner).Should(Equal(fundedAddress))
+	Expect(*homeEntries[2].NewOwner).Should(Equal(newOwnerAddress))
+
+	remoteEntries, err := auditLog.Sync(
+		ctx,
+		subnetAInfo.RPCClient,
+		subnetAInfo.BlockchainID,
+		erc20TokenRemoteAddress,
+		remoteFromBlock+1,
+		0,
+	)
+	Expect(err).Should(BeNil())
+	Expect(remoteEntries).Should(HaveLen(4))
+	Expect(remoteEntries[0].Event).Should(Equal(admin.EventMinTeleporterVersionUpdated))
+	Expect(remoteEntries[1].Event).Should(Equal(admin.EventTeleporterAddressPaused))
+	Expect(*remoteEntries[1].TeleporterAddress).Should(Equal(oldTeleporterAddress))
+	Expect(remoteEntries[2].Event).Should(Equal(admin.EventTeleporterAddressPaused))
+	Expect(*remoteEntries[2].TeleporterAddress).Should(Equal(newTeleporterAddress))
+	Expect(remoteEntries[3].Event).Should(Equal(admin.EventTeleporterAddressUnpaused))
+	Expect(*remoteEntries[3].TeleporterAddress).Should(Equal(newTeleporterAddress))
+
+	// Entries are appended once
+	entries, err := auditLog.Entries(subnetAInfo.BlockchainID, erc20TokenRemoteAddress)
+	Expect(err).Should(BeNil())
+	Expect(entries).Should(Equal(remoteEntries))
+	remoteEntries, err = auditLog.Sync(
+		ctx,
+		subnetAInfo.RPCClient,
+		subnetAInfo.BlockchainID,
+		erc20TokenRemoteAddress,
+		remoteFromBlock+1,
+		0,
+	)
+	Expect(err).Should(BeNil())
+	Expect(remoteEntries).Should(BeEmpty())
+}
+
+// deployTeleporterRegistry deploys a TeleporterRegistry whose only version is the Teleporter at teleporterAddress.
+func deployTeleporterRegistry(
+	ctx context.Context,
+	subnet interfaces.SubnetTestInfo,
+	senderKey *ecdsa.PrivateKey,
+	teleporterAddress common.Address,
+) common.Address {
+	opts, err := bind.NewKeyedTransactorWithChainID(senderKey, subnet.EVMChainID)
+	Expect(err).Should(BeNil())
+	registryAddress, tx, _, err := teleporterregistry.DeployTeleporterRegistry(
+		opts,
+		subnet.RPCClient,
+		[]teleporterregistry.ProtocolRegistryEntry{
+			{
+				Version:         big.NewInt(1),
+				ProtocolAddress: teleporterAddress,
+			},
+		},
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, subnet, tx.Hash())
+	return registryAddress
+}
+
+// withTeleporterRegistry returns the subnet info with the TeleporterRegistry at registryAddress.
+func withTeleporterRegistry(
+	subnet interfaces.SubnetTestInfo,
+	registryAddress common.Address,
+) interfaces.SubnetTestInfo {
+	registry, err := teleporterregistry.NewTeleporterRegistry(registryAddress, subnet.RPCClient)
+	Expect(err).Should(BeNil())
+	subnet.TeleporterRegistryAddress = registryAddress
+	subnet.TeleporterRegistry = registry
+	return subnet
+}
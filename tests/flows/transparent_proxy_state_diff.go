@@ -0,0 +1,378 @@
+package flows
+
+import (
+	"context"
+	"math/big"
+
+	nativetokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHome"
+	nativetokenhomeupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHomeUpgradeable"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	erc20tokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemoteUpgradeable"
+	nativetokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemote"
+	nativetokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemoteUpgradeable"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	. "github.com/onsi/gomega"
+)
+
+/**
+ * Deploy an upgradeable NativeTokenHome behind a transparent upgradeable proxy on the primary network
+ * Deploy an upgradeable NativeTokenRemote behind a transparent upgradeable proxy on Subnet A
+ * Deploy an upgradeable ERC20TokenRemote behind a transparent upgradeable proxy on Subnet B
+ * Register and collateralize both remotes, and transfer tokens to each of them
+ * Snapshot the getters of all three contracts
+ * Upgrade each proxy to a new logic contract
+ * Check that the snapshots taken after the upgrades match the ones taken before
+ * Check that none of the proxies can be initialized again
+ * Transfer tokens from Subnet B back to the primary network through the upgraded contracts
+ */
+func TransparentUpgradeableProxyStateDiff(network interfaces.Network) {
+	cChainInfo := network.GetPrimaryNetworkInfo()
+	subnetAInfo, subnetBInfo := teleporterUtils.GetTwoSubnets(network)
+	fundedAddress, fundedKey := network.GetFundedAccountInfo()
+
+	ctx := context.Background()
+
+	// Deploy an example WAVAX on the primary network
+	cChainWAVAXAddress, wavax := utils.DeployWrappedNativeToken(
+		ctx,
+		fundedKey,
+		cChainInfo,
+		"AVAX",
+	)
+
+	// Deploy the NativeTokenHome logic contract and its proxy on the primary network
+	cChainOpts, err := bind.NewKeyedTransactorWithChainID(fundedKey, cChainInfo.EVMChainID)
+	Expect(err).Should(BeNil())
+	homeImplAddress, tx, _, err := nativetokenhomeupgradeable.DeployNativeTokenHomeUpgradeable(
+		cChainOpts,
+		cChainInfo.RPCClient,
+		utils.ICTTInitializableDisallowed,
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, cChainInfo, tx.Hash())
+
+	nativeTokenHomeAddress, homeProxyAdmin, nativeTokenHome := utils.DeployTransparentUpgradeableProxy(
+		ctx,
+		cChainInfo,
+		fundedKey,
+		homeImplAddress,
+		nativetokenhome.NewNativeTokenHome,
+	)
+	tx, err = nativeTokenHome.Initialize(
+		cChainOpts,
+		cChainInfo.TeleporterRegistryAddress,
+		fundedAddress,
+		cChainWAVAXAddress,
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, cChainInfo, tx.Hash())
+
+	// Deploy the NativeTokenRemote logic contract and its proxy on Subnet A
+	nativeTokenRemoteAddress, _, remoteAProxyAdmin, nativeTokenRemote := utils.DeployNativeTokenRemoteUpgradeable(
+		ctx,
+		fundedKey,
+		subnetAInfo,
+		fundedAddress,
+	)
+	nativeTokenRemoteSettings := nativetokenremote.TokenRemoteSettings{
+		TeleporterRegistryAddress: subnetAInfo.TeleporterRegistryAddress,
+		TeleporterManager:         fundedAddress,
+		TokenHomeBlockchainID:     cChainInfo.BlockchainID,
+		TokenHomeAddress:          nativeTokenHomeAddress,
+		TokenHomeDecimals:         utils.NativeTokenDecimals,
+	}
+	subnetAOpts, err := bind.NewKeyedTransactorWithChainID(fundedKey, subnetAInfo.EVMChainID)
+	Expect(err).Should(BeNil())
+	tx, err = nativeTokenRemote.Initialize(
+		subnetAOpts,
+		nativeTokenRemoteSettings,
+		"SUBA",
+		initialReserveImbalance,
+		burnedFeesReportingRewardPercentage,
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, subnetAInfo, tx.Hash())
+
+	// Deploy the ERC20TokenRemote logic contract and its proxy on Subnet B
+	subnetBOpts, err := bind.NewKeyedTransactorWithChainID(fundedKey, subnetBInfo.EVMChainID)
+	Expect(err).Should(BeNil())
+	remoteBImplAddress, tx, _, err := erc20tokenremoteupgradeable.DeployERC20TokenRemoteUpgradeable(
+		subnetBOpts,
+		subnetBInfo.RPCClient,
+		utils.ICTTInitializableDisallowed,
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, subnetBInfo, tx.Hash())
+
+	erc20TokenRemoteAddress, remoteBProxyAdmin, erc20TokenRemote := utils.DeployTransparentUpgradeableProxy(
+		ctx,
+		subnetBInfo,
+		fundedKey,
+		remoteBImplAddress,
+		erc20tokenremote.NewERC20TokenRemote,
+	)
+	erc20TokenRemoteSettings := erc20tokenremote.TokenRemoteSettings{
+		TeleporterRegistryAddress: subnetBInfo.TeleporterRegistryAddress,
+		TeleporterManager:         fundedAddress,
+		TokenHomeBlockchainID:     cChainInfo.BlockchainID,
+		TokenHomeAddress:          nativeTokenHomeAddress,
+		TokenHomeDecimals:         utils.NativeTokenDecimals,
+	}
+	tx, err = erc20TokenRemote.Initialize(
+		subnetBOpts,
+		erc20TokenRemoteSettings,
+		"Wrapped AVAX",
+		"WAVAX",
+		utils.NativeTokenDecimals,
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, subnetBInfo, tx.Hash())
+
+	// Register both remotes on the NativeTokenHome, and collateralize the NativeTokenRemote
+	collateralAmount := utils.RegisterTokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		nativeTokenHomeAddress,
+		subnetAInfo,
+		nativeTokenRemoteAddress,
+		initialReserveImbalance,
+		big.NewInt(1),
+		multiplyOnRemote,
+	)
+	utils.AddCollateralToNativeTokenHome(
+		ctx,
+		cChainInfo,
+		nativeTokenHome,
+		nativeTokenHomeAddress,
+		subnetAInfo.BlockchainID,
+		nativeTokenRemoteAddress,
+		collateralAmount,
+		fundedKey,
+	)
+
+	utils.RegisterTokenRemoteOnHome(
+		ctx,
+		network,
+		cChainInfo,
+		nativeTokenHomeAddress,
+		subnetBInfo,
+		erc20TokenRemoteAddress,
+		big.NewInt(0),
+		big.NewInt(1),
+		false,
+	)
+
+	// Generate new recipient to receive transferred tokens
+	recipientKey, err := crypto.GenerateKey()
+	Expect(err).Should(BeNil())
+	recipientAddress := crypto.PubkeyToAddress(recipientKey.PublicKey)
+
+	// Send tokens from the primary network to the recipient on both subnets
+	amount := utils.ParseAmount("13", utils.NativeTokenDecimals)
+	for _, destination := range []struct {
+		subnet  interfaces.SubnetTestInfo
+		address common.Address
+	}{
+		{subnetAInfo, nativeTokenRemoteAddress},
+		{subnetBInfo, erc20TokenRemoteAddress},
+	} {
+		input := nativetokenhome.SendTokensInput{
+			DestinationBlockchainID:            destination.subnet.BlockchainID,
+			DestinationTokenTransferrerAddress: destination.address,
+			Recipient:                          recipientAddress,
+			PrimaryFeeTokenAddress:             cChainWAVAXAddress,
+			PrimaryFee:                         big.NewInt(1e18),
+			SecondaryFee:                       big.NewInt(0),
+			RequiredGasLimit:                   utils.DefaultNativeTokenRequiredGas,
+		}
+		receipt, _ := utils.SendNativeTokenHome(
+			ctx,
+			cChainInfo,
+			nativeTokenHome,
+			nativeTokenHomeAddress,
+			wavax,
+			input,
+			amount,
+			fundedKey,
+		)
+		network.RelayMessage(
+			ctx,
+			receipt,
+			cChainInfo,
+			destination.subnet,
+			true,
+		)
+	}
+
+	// Snapshot the state of all three contracts before the upgrades
+	remotes := []utils.RemoteTokenTransferrer{
+		{BlockchainID: subnetAInfo.BlockchainID, Address: nativeTokenRemoteAddress},
+		{BlockchainID: subnetBInfo.BlockchainID, Address: erc20TokenRemoteAddress},
+	}
+	accounts := []common.Address{recipientAddress, fundedAddress}
+	homeSnapshot := utils.SnapshotTokenHome(cChainInfo, nativeTokenHomeAddress, remotes)
+	remoteASnapshot := utils.SnapshotTokenRemote(subnetAInfo, nativeTokenRemoteAddress, true, accounts)
+	remoteBSnapshot := utils.SnapshotTokenRemote(subnetBInfo, erc20TokenRemoteAddress, false, accounts)
+
+	// Check that the snapshots are meaningful before comparing them
+	Expect(remoteASnapshot["getIsCollateralized"]).Should(Equal("true"))
+	Expect(remoteASnapshot["getTotalMinted"]).ShouldNot(Equal("0"))
+	Expect(remoteBSnapshot["totalSupply"]).ShouldNot(Equal("0"))
+
+	// Upgrade each proxy to a new logic contract
+	newHomeImplAddress, tx, _, err := nativetokenhomeupgradeable.DeployNativeTokenHomeUpgradeable(
+		cChainOpts,
+		cChainInfo.RPCClient,
+		utils.ICTTInitializableDisallowed,
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, cChainInfo, tx.Hash())
+	Expect(utils.GetProxyImplementation(ctx, cChainInfo.RPCClient, nativeTokenHomeAddress)).
+		Should(Equal(homeImplAddress))
+	utils.UpgradeTransparentUpgradeableProxy(
+		ctx,
+		cChainInfo,
+		homeProxyAdmin,
+		nativeTokenHomeAddress,
+		newHomeImplAddress,
+		fundedKey,
+	)
+
+	newRemoteAImplAddress, tx, _, err := nativetokenremoteupgradeable.DeployNativeTokenRemoteUpgradeable(
+		subnetAOpts,
+		subnetAInfo.RPCClient,
+		utils.ICTTInitializableDisallowed,
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, subnetAInfo, tx.Hash())
+	utils.UpgradeTransparentUpgradeableProxy(
+		ctx,
+		subnetAInfo,
+		remoteAProxyAdmin,
+		nativeTokenRemoteAddress,
+		newRemoteAImplAddress,
+		fundedKey,
+	)
+
+	newRemoteBImplAddress, tx, _, err := erc20tokenremoteupgradeable.DeployERC20TokenRemoteUpgradeable(
+		subnetBOpts,
+		subnetBInfo.RPCClient,
+		utils.ICTTInitializableDisallowed,
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, subnetBInfo, tx.Hash())
+	utils.UpgradeTransparentUpgradeableProxy(
+		ctx,
+		subnetBInfo,
+		remoteBProxyAdmin,
+		erc20TokenRemoteAddress,
+		newRemoteBImplAddress,
+		fundedKey,
+	)
+
+	// Check that the upgrades did not modify any state
+	utils.ExpectSnapshotUnchanged(
+		homeSnapshot,
+		utils.SnapshotTokenHome(cChainInfo, nativeTokenHomeAddress, remotes),
+	)
+	utils.ExpectSnapshotUnchanged(
+		remoteASnapshot,
+		utils.SnapshotTokenRemote(subnetAInfo, nativeTokenRemoteAddress, true, accounts),
+	)
+	utils.ExpectSnapshotUnchanged(
+		remoteBSnapshot,
+		utils.SnapshotTokenRemote(subnetBInfo, erc20TokenRemoteAddress, false, accounts),
+	)
+
+	// Check that the upgraded proxies can not be initialized again
+	utils.ExpectReinitializationFails(func(opts *bind.TransactOpts) error {
+		_, err := nativeTokenHome.Initialize(
+			opts,
+			cChainInfo.TeleporterRegistryAddress,
+			fundedAddress,
+			cChainWAVAXAddress,
+		)
+		return err
+	}, fundedKey, cChainInfo.EVMChainID)
+	utils.ExpectReinitializationFails(func(opts *bind.TransactOpts) error {
+		_, err := nativeTokenRemote.Initialize(
+			opts,
+			nativeTokenRemoteSettings,
+			"SUBA",
+			initialReserveImbalance,
+			burnedFeesReportingRewardPercentage,
+		)
+		return err
+	}, fundedKey, subnetAInfo.EVMChainID)
+	utils.ExpectReinitializationFails(func(opts *bind.TransactOpts) error {
+		_, err := erc20TokenRemote.Initialize(
+			opts,
+			erc20TokenRemoteSettings,
+			"Wrapped AVAX",
+			"WAVAX",
+			utils.NativeTokenDecimals,
+		)
+		return err
+	}, fundedKey, subnetBInfo.EVMChainID)
+
+	// Send tokens from Subnet B back to the primary network through the upgraded contracts
+	teleporterUtils.SendNativeTransfer(
+		ctx,
+		subnetBInfo,
+		fundedKey,
+		recipientAddress,
+		big.NewInt(1e18),
+	)
+	inputB := erc20tokenremote.SendTokensInput{
+		DestinationBlockchainID:            cChainInfo.BlockchainID,
+		DestinationTokenTransferrerAddress: nativeTokenHomeAddress,
+		Recipient:                          recipientAddress,
+		PrimaryFeeTokenAddress:             erc20TokenRemoteAddress,
+		PrimaryFee:                         big.NewInt(1e10),
+		SecondaryFee:                       big.NewInt(0),
+		RequiredGasLimit:                   utils.DefaultNativeTokenRequiredGas,
+	}
+	sendAmount := teleporterUtils.BigIntSub(amount, inputB.PrimaryFee)
+	receipt, transferredAmount := utils.SendERC20TokenRemote(
+		ctx,
+		subnetBInfo,
+		erc20TokenRemote,
+		erc20TokenRemoteAddress,
+		inputB,
+		sendAmount,
+		recipientKey,
+	)
+
+	receipt = network.RelayMessage(
+		ctx,
+		receipt,
+		subnetBInfo,
+		cChainInfo,
+		true,
+	)
+
+	// Check that the transfer was successful, and expected balances are correct
+	utils.CheckNativeTokenHomeWithdrawal(
+		ctx,
+		nativeTokenHomeAddress,
+		wavax,
+		receipt,
+		transferredAmount,
+	)
+	teleporterUtils.CheckBalance(ctx, recipientAddress, transferredAmount, cChainInfo.RPCClient)
+
+	// Check that the transferred balance of Subnet B was reduced by the upgraded NativeTokenHome
+	transferredBalance, err := nativeTokenHome.GetTransferredBalance(
+		&bind.CallOpts{},
+		subnetBInfo.BlockchainID,
+		erc20TokenRemoteAddress,
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.ExpectBigEqual(transferredBalance, teleporterUtils.BigIntSub(amount, transferredAmount))
+}
@@ -4,12 +4,20 @@
 package local
 
 import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/external"
 	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/flows"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
 	"github.com/ava-labs/teleporter/tests/local"
 	deploymentUtils "github.com/ava-labs/teleporter/utils/deployment-utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -28,9 +36,30 @@ const (
 	sendAndCallLabel       = "SendAndCall"
 	registrationLabel      = "Registration"
 	upgradabilityLabel     = "Upgradability"
+	fallbackLabel          = "Fallback"
+	decimalsLabel          = "Decimals"
+	topologyLabel          = "Topology"
+	relayerLabel           = "Relayer"
+	gatewayLabel           = "Gateway"
+	webhooksLabel          = "Webhooks"
+	guardianLabel          = "Guardian"
+	adminLabel             = "Admin"
+	deterministicLabel     = "Deterministic"
 )
 
-var LocalNetworkInstance *local.LocalNetwork
+var (
+	LocalNetworkInstance *local.LocalNetwork
+
+	// The network as seen by this process, which relays messages with a key of its own
+	processNetwork *external.Network
+	// The funded accounts of the specs run by this process
+	processAccounts *utils.Accounts
+	// The network of the running spec, with a funded account of its own
+	specNetwork *utils.SpecNetwork
+
+	// Created by the first process, in which every process claims the NativeTokenRemote deployer keys it uses
+	deployerKeyClaimDir string
+)
 
 func TestE2E(t *testing.T) {
 	if os.Getenv("RUN_E2E") == "" {
@@ -43,23 +72,28 @@ func TestE2E(t *testing.T) {
 }
 
 // Define the Teleporter before and after suite functions.
-var _ = ginkgo.BeforeSuite(func() {
-	// Create the local network instance
+// The first process creates the local network, and every process then connects to it,
+// so that the specs can be run in parallel with ginkgo -p.
+var _ = ginkgo.SynchronizedBeforeSuite(func() []byte {
+	ctx := context.Background()
+
+	// Create the local network instance, with the subnets configured by the environment
+	topology, err := utils.LoadTopology()
+	Expect(err).Should(BeNil())
+	subnetSpecs := make([]local.SubnetSpec, len(topology.Subnets))
+	for i, subnet := range topology.Subnets {
+		subnetSpecs[i] = local.SubnetSpec{
+			Name:       subnet.Name,
+			EVMChainID: subnet.EVMChainID,
+			NodeCount:  subnet.NodeCount,
+		}
+	}
+	log.Info("Creating local network", "subnets", topology.Subnets)
+
 	LocalNetworkInstance = local.NewLocalNetwork(
 		"interchain-token-transfer-test",
 		warpGenesisTemplateFile,
-		[]local.SubnetSpec{
-			{
-				Name:       "A",
-				EVMChainID: 12345,
-				NodeCount:  1,
-			},
-			{
-				Name:       "B",
-				EVMChainID: 54321,
-				NodeCount:  1,
-			},
-		},
+		subnetSpecs,
 		0,
 	)
 
@@ -82,73 +116,192 @@ var _ = ginkgo.BeforeSuite(func() {
 	)
 
 	LocalNetworkInstance.DeployTeleporterRegistryContracts(teleporterContractAddress, fundedKey)
-	log.Info("Set up ginkgo before suite")
+
+	// Fund the keys of every process, which are derived from the funded key by each process
+	suiteConfig, _ := ginkgo.GinkgoConfiguration()
+	var processAddresses []common.Address
+	for process := 1; process <= suiteConfig.ParallelTotal; process++ {
+		processKey := utils.DeriveKey(fundedKey, processKeyLabel, uint64(process))
+		processAddresses = append(
+			processAddresses,
+			crypto.PubkeyToAddress(processKey.PublicKey),
+			crypto.PubkeyToAddress(utils.DeriveKey(processKey, relayerKeyLabel, 0).PublicKey),
+		)
+	}
+	utils.NewFunder(fundedKey).Fund(ctx, LocalNetworkInstance.GetAllSubnetsInfo(), processAddresses, processBalance)
+
+	deployerKeyClaimDir, err = os.MkdirTemp("", "interchain-token-transfer-deployer-keys")
+	Expect(err).Should(BeNil())
+	log.Info("Set up ginkgo before suite", "processes", suiteConfig.ParallelTotal)
 
 	ginkgo.AddReportEntry(
 		"network directory with node logs & configs; useful in the case of failures",
 		LocalNetworkInstance.Dir(),
 		ginkgo.ReportEntryVisibilityFailureOrVerbose,
 	)
+
+	data, err := json.Marshal(suiteData{
+		Network:             newExternalConfig(LocalNetworkInstance, fundedKey),
+		DeployerKeyClaimDir: deployerKeyClaimDir,
+	})
+	Expect(err).Should(BeNil())
+	return data
+}, func(data []byte) {
+	var suite suiteData
+	Expect(json.Unmarshal(data, &suite)).Should(Succeed())
+	utils.SetNativeTokenRemoteDeployerKeyClaimDir(suite.DeployerKeyClaimDir)
+
+	// Transactions of the process are sent from keys of its own, so that they never share nonces with
+	// those of other processes. Messages are relayed with the relayer key, and the funded account of
+	// each spec is funded by the process key.
+	fundedKey, err := crypto.HexToECDSA(strings.TrimPrefix(suite.Network.FundedKey, "0x"))
+	Expect(err).Should(BeNil())
+	processKey := utils.DeriveKey(fundedKey, processKeyLabel, uint64(ginkgo.GinkgoParallelProcess()))
+	processAccounts = utils.NewAccounts(processKey, specKeyLabel)
+
+	config := suite.Network
+	config.FundedKey = hex.EncodeToString(crypto.FromECDSA(utils.DeriveKey(processKey, relayerKeyLabel, 0)))
+	processNetwork, err = external.NewNetworkFromConfig(context.Background(), config)
+	Expect(err).Should(BeNil())
 })
 
-var _ = ginkgo.AfterSuite(func() {
+var _ = ginkgo.BeforeEach(func() {
+	specNetwork = processAccounts.NewSpecNetwork(context.Background(), processNetwork)
+})
+
+var _ = ginkgo.SynchronizedAfterSuite(func() {}, func() {
 	LocalNetworkInstance.TearDownNetwork()
+	Expect(os.RemoveAll(deployerKeyClaimDir)).Should(Succeed())
 })
 
 var _ = ginkgo.Describe("[Avalanche Interchain Token Transfer integration tests]", func() {
 	ginkgo.It("Transfer an ERC20 token between two Subnets",
 		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel),
 		func() {
-			flows.ERC20TokenHomeERC20TokenRemote(LocalNetworkInstance)
+			flows.ERC20TokenHomeERC20TokenRemote(specNetwork)
 		})
 	ginkgo.It("Transfer a native token to an ERC20 token",
 		ginkgo.Label(nativeTokenHomeLabel, erc20TokenRemoteLabel),
 		func() {
-			flows.NativeTokenHomeERC20TokenRemote(LocalNetworkInstance)
+			flows.NativeTokenHomeERC20TokenRemote(specNetwork)
 		})
 	ginkgo.It("Transfer a native token to a native token",
 		ginkgo.Label(nativeTokenHomeLabel, nativeTokenRemoteLabel),
 		func() {
-			flows.NativeTokenHomeNativeDestination(LocalNetworkInstance)
+			flows.NativeTokenHomeNativeDestination(specNetwork)
 		})
 	ginkgo.It("Transfer an ERC20 token with ERC20TokenHome multi-hop",
 		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, multiHopLabel),
 		func() {
-			flows.ERC20TokenHomeERC20TokenRemoteMultiHop(LocalNetworkInstance)
+			flows.ERC20TokenHomeERC20TokenRemoteMultiHop(specNetwork)
 		})
 	ginkgo.It("Transfer a native token with NativeTokenHome multi-hop",
 		ginkgo.Label(nativeTokenHomeLabel, erc20TokenRemoteLabel, multiHopLabel),
 		func() {
-			flows.NativeTokenHomeERC20TokenRemoteMultiHop(LocalNetworkInstance)
+			flows.NativeTokenHomeERC20TokenRemoteMultiHop(specNetwork)
 		})
 	ginkgo.It("Transfer an ERC20 token to a native token",
 		ginkgo.Label(erc20TokenHomeLabel, nativeTokenRemoteLabel),
 		func() {
-			flows.ERC20TokenHomeNativeTokenRemote(LocalNetworkInstance)
+			flows.ERC20TokenHomeNativeTokenRemote(specNetwork)
 		})
 	ginkgo.It("Transfer a native token with ERC20TokenHome multi-hop",
 		ginkgo.Label(erc20TokenHomeLabel, nativeTokenRemoteLabel, multiHopLabel),
 		func() {
-			flows.ERC20TokenHomeNativeTokenRemoteMultiHop(LocalNetworkInstance)
+			flows.ERC20TokenHomeNativeTokenRemoteMultiHop(specNetwork)
 		})
 	ginkgo.It("Transfer a native token to a native token multi-hop",
 		ginkgo.Label(nativeTokenHomeLabel, nativeTokenRemoteLabel, multiHopLabel),
 		func() {
-			flows.NativeTokenHomeNativeTokenRemoteMultiHop(LocalNetworkInstance)
+			flows.NativeTokenHomeNativeTokenRemoteMultiHop(specNetwork)
 		})
 	ginkgo.It("Transfer an ERC20 token using sendAndCall",
 		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, sendAndCallLabel),
 		func() {
-			flows.ERC20TokenHomeERC20TokenRemoteSendAndCall(LocalNetworkInstance)
+			flows.ERC20TokenHomeERC20TokenRemoteSendAndCall(specNetwork)
 		})
 	ginkgo.It("Registration and collateral checks",
 		ginkgo.Label(erc20TokenHomeLabel, nativeTokenRemoteLabel, registrationLabel),
 		func() {
-			flows.RegistrationAndCollateralCheck(LocalNetworkInstance)
+			flows.RegistrationAndCollateralCheck(specNetwork)
 		})
 	ginkgo.It("Transparent proxy upgrade",
 		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, upgradabilityLabel),
 		func() {
-			flows.TransparentUpgradeableProxy(LocalNetworkInstance)
+			flows.TransparentUpgradeableProxy(specNetwork)
+		})
+	ginkgo.It("Transparent proxy upgrade preserves state",
+		ginkgo.Label(nativeTokenHomeLabel, erc20TokenRemoteLabel, nativeTokenRemoteLabel, upgradabilityLabel),
+		func() {
+			flows.TransparentUpgradeableProxyStateDiff(specNetwork)
+		})
+	ginkgo.It("Fallback recipient on failed sendAndCall calls",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, sendAndCallLabel, fallbackLabel),
+		func() {
+			flows.ERC20TokenHomeERC20TokenRemoteCallFailed(specNetwork)
+		})
+	ginkgo.It("Multi-hop fallback on unroutable multi-hop transfers",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, nativeTokenRemoteLabel, multiHopLabel, fallbackLabel),
+		func() {
+			flows.ERC20TokenHomeMultiHopFallback(specNetwork)
+		})
+	ginkgo.It("Token accounting matches the reference model",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, multiHopLabel),
+		func() {
+			flows.TokenAccountingModelDifferential(specNetwork)
+		})
+	ginkgo.It("Transfer an ERC20 token to many remotes",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, multiHopLabel, topologyLabel),
+		func() {
+			flows.ERC20TokenHomeManyRemotes(specNetwork)
+		})
+	ginkgo.It("Transfer ERC20 tokens with a token home on every chain",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, multiHopLabel, topologyLabel),
+		func() {
+			flows.ERC20TokenHomeOnEveryChain(specNetwork)
+		})
+	ginkgo.It("Relay ERC20 token transfers with the ICTT relayer",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, multiHopLabel, relayerLabel),
+		func() {
+			flows.ERC20TokenHomeICTTRelayer(specNetwork)
+		})
+	ginkgo.It("Quote, build, submit and track ERC20 token transfers with the gateway",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, gatewayLabel),
+		func() {
+			flows.ERC20TokenHomeGateway(specNetwork)
+		})
+	ginkgo.It("Notify webhooks of the events of ERC20 token transferrers",
+		ginkgo.Label(erc20TokenHomeLabel, nativeTokenRemoteLabel, webhooksLabel),
+		func() {
+			flows.ERC20TokenHomeWebhooks(specNetwork)
+		})
+	ginkgo.It("Pause ERC20 token transferrers on a mint spike with the guardian",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, guardianLabel),
+		func() {
+			flows.ERC20TokenHomeGuardian(specNetwork)
+		})
+	ginkgo.It("Deploy token transferrers at predictable addresses with CREATE2",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, upgradabilityLabel, deterministicLabel),
+		func() {
+			flows.DeterministicDeployment(specNetwork)
+		})
+	ginkgo.DescribeTable("Transfer an ERC20 token between different decimals",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, nativeTokenRemoteLabel, multiHopLabel, decimalsLabel),
+		func(homeDecimals uint8, remoteDecimals uint8) {
+			flows.ERC20TokenHomeDecimalsMatrix(specNetwork, homeDecimals, remoteDecimals)
+		},
+		ginkgo.Entry("6 to 18 decimals", uint8(6), uint8(18)),
+		ginkgo.Entry("18 to 6 decimals", uint8(18), uint8(6)),
+		ginkgo.Entry("0 decimal home, maximum difference", uint8(0), uint8(18)),
+		ginkgo.Entry("0 decimal remote, maximum difference", uint8(18), uint8(0)),
+		ginkgo.Entry("equal decimals", uint8(12), uint8(12)),
+	)
+	// Serial specs are run last, by the first process, which is the only one with the local network. The
+	// upgrade restarts the nodes, so no other spec may run alongside it.
+	ginkgo.It("Migrate token transferrers to a new Teleporter version with the admin tooling",
+		ginkgo.Label(erc20TokenHomeLabel, erc20TokenRemoteLabel, adminLabel),
+		ginkgo.Serial,
+		func() {
+			flows.TeleporterRegistryUpgrade(LocalNetworkInstance, teleporterByteCodeFile)
 		})
 })
@@ -0,0 +1,73 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package local
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"math/big"
+	"sort"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/external"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	"github.com/ava-labs/teleporter/tests/local"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// Labels of the keys derived from the funded key of the local network
+	processKeyLabel = "process"
+	relayerKeyLabel = "relayer"
+	specKeyLabel    = "spec"
+)
+
+// Native token balance given to the keys of each process on every chain,
+// from which the funded accounts of the specs of the process are funded.
+var processBalance = new(big.Int).Mul(utils.SpecAccountBalance, big.NewInt(1_000))
+
+// suiteData is passed from the first process, which creates the local network, to every process.
+type suiteData struct {
+	Network             external.Config `json:"network"`
+	DeployerKeyClaimDir string          `json:"deployerKeyClaimDir"`
+}
+
+// newExternalConfig describes the local network as an external network, through which the processes
+// other than the one that created the local network connect to it. Messages are delivered with
+// aggregate signatures, which unlike the local network relayer can be done concurrently.
+func newExternalConfig(network *local.LocalNetwork, fundedKey *ecdsa.PrivateKey) external.Config {
+	chainConfig := func(info interfaces.SubnetTestInfo) external.ChainConfig {
+		return external.ChainConfig{
+			Name:                      info.SubnetName,
+			SubnetID:                  info.SubnetID,
+			BlockchainID:              info.BlockchainID,
+			RPCURL:                    teleporterUtils.HttpToRPCURI(info.NodeURIs[0], info.BlockchainID.String()),
+			WSURL:                     teleporterUtils.HttpToWebsocketURI(info.NodeURIs[0], info.BlockchainID.String()),
+			NodeURIs:                  info.NodeURIs,
+			TeleporterRegistryAddress: info.TeleporterRegistryAddress,
+		}
+	}
+
+	// Sort the subnets so that every process sees them in the same order,
+	// which the local network does not guarantee.
+	subnetsInfo := network.GetSubnetsInfo()
+	sort.Slice(subnetsInfo, func(i, j int) bool {
+		return subnetsInfo[i].SubnetName < subnetsInfo[j].SubnetName
+	})
+	subnets := make([]external.ChainConfig, len(subnetsInfo))
+	for i, subnetInfo := range subnetsInfo {
+		subnets[i] = chainConfig(subnetInfo)
+	}
+
+	primaryNetwork := chainConfig(network.GetPrimaryNetworkInfo())
+	primaryNetwork.Name = "C-Chain"
+	return external.Config{
+		TeleporterContractAddress: network.GetTeleporterContractAddress(),
+		FundedKey:                 hex.EncodeToString(crypto.FromECDSA(fundedKey)),
+		PrimaryNetwork:            primaryNetwork,
+		Subnets:                   subnets,
+		Relayer:                   external.RelayerConfig{Type: external.AggregateSignatureRelayerType},
+	}
+}
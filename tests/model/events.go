@@ -0,0 +1,59 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package model
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// EventKind is the name of a contract event.
+type EventKind string
+
+const (
+	RemoteRegistered EventKind = "RemoteRegistered"
+	CollateralAdded  EventKind = "CollateralAdded"
+	TokensSent       EventKind = "TokensSent"
+	TokensRouted     EventKind = "TokensRouted"
+	TokensWithdrawn  EventKind = "TokensWithdrawn"
+)
+
+// Event is an accounting event emitted by a token transferrer.
+// Only the fields of the event kind are set.
+type Event struct {
+	Kind    EventKind
+	Emitter Transferrer
+
+	// The remote of RemoteRegistered and CollateralAdded
+	Remote Transferrer
+	// The destination of TokensSent and TokensRouted. For multi-hop sends, the final destination.
+	Destination Transferrer
+	Sender      common.Address
+	Recipient   common.Address
+	Amount      *big.Int
+	// The collateral still needed after RemoteRegistered and CollateralAdded
+	Remaining           *big.Int
+	RemoteTokenDecimals uint8
+}
+
+func (e Event) String() string {
+	switch e.Kind {
+	case RemoteRegistered:
+		return fmt.Sprintf("%s %s remote=%s collateralNeeded=%s decimals=%d",
+			e.Emitter, e.Kind, e.Remote, e.Remaining, e.RemoteTokenDecimals)
+	case CollateralAdded:
+		return fmt.Sprintf("%s %s remote=%s amount=%s remaining=%s",
+			e.Emitter, e.Kind, e.Remote, e.Amount, e.Remaining)
+	case TokensSent:
+		return fmt.Sprintf("%s %s sender=%s destination=%s recipient=%s amount=%s",
+			e.Emitter, e.Kind, e.Sender.Hex(), e.Destination, e.Recipient.Hex(), e.Amount)
+	case TokensRouted:
+		return fmt.Sprintf("%s %s destination=%s recipient=%s amount=%s",
+			e.Emitter, e.Kind, e.Destination, e.Recipient.Hex(), e.Amount)
+	default:
+		return fmt.Sprintf("%s %s recipient=%s amount=%s", e.Emitter, e.Kind, e.Recipient.Hex(), e.Amount)
+	}
+}
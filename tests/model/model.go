@@ -0,0 +1,611 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package model is a reference model of the accounting of a TokenHome instance and its TokenRemote instances.
+// It mirrors the observable state of the contracts, the registered remote settings, the balances transferred
+// to each remote, and the token balances of accounts, along with the events emitted by each operation,
+// so that the contracts can be tested against it.
+//
+// Teleporter messages are queued by the sending operation, and only take effect once relayed with [Model.Relay].
+// Teleporter fees paid in a separate fee token are not modeled, since they do not affect the accounting.
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/utils"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MaxTokenDecimals is the maximum number of decimals supported by a token transferrer.
+const MaxTokenDecimals = 18
+
+// Errors returned by operations that revert on chain carry the revert reason of the contracts.
+var (
+	ErrRemoteNotRegistered          = errors.New("TokenHome: remote not registered")
+	ErrRemoteAlreadyRegistered      = errors.New("TokenHome: remote already registered")
+	ErrRemoteTokenDecimalsTooHigh   = errors.New("TokenHome: remote token decimals too high")
+	ErrCollateralNeeded             = errors.New("TokenHome: collateral needed for remote")
+	ErrZeroCollateralNeeded         = errors.New("TokenHome: zero collateral needed")
+	ErrRemoteNotCollateralized      = errors.New("TokenHome: remote not collateralized")
+	ErrZeroScaledAmount             = errors.New("TokenHome: zero scaled amount")
+	ErrZeroTokenAmount              = errors.New("TokenHome: zero token amount")
+	ErrInsufficientTransferBalance  = errors.New("TokenHome: insufficient token transfer balance")
+	ErrInsufficientAmountForFees    = errors.New("TokenHome: insufficient amount to cover fees")
+	ErrAlreadyRegisteredWithHome    = errors.New("TokenRemote: already registered")
+	ErrInsufficientTokensToTransfer = errors.New("TokenRemote: insufficient tokens to transfer")
+	ErrInsufficientBalance          = errors.New("insufficient balance")
+	ErrUnknownTransferrer           = errors.New("unknown token transferrer")
+	ErrNoPendingMessages            = errors.New("no pending messages")
+)
+
+// Transferrer identifies a token transferrer instance.
+type Transferrer struct {
+	BlockchainID ids.ID
+	Address      common.Address
+}
+
+func (t Transferrer) String() string {
+	return fmt.Sprintf("%s:%s", t.BlockchainID, t.Address.Hex())
+}
+
+// RemoteSettings mirrors the RemoteTokenTransferrerSettings the TokenHome stores for each TokenRemote.
+type RemoteSettings struct {
+	Registered       bool
+	CollateralNeeded *big.Int
+	TokenMultiplier  *big.Int
+	MultiplyOnRemote bool
+}
+
+// MessageType is the type of a Teleporter message sent between token transferrers.
+type MessageType uint8
+
+const (
+	RegisterRemote MessageType = iota
+	SingleHopSend
+	MultiHopSend
+)
+
+// Message is a Teleporter message that has been sent, but not yet relayed.
+type Message struct {
+	Type        MessageType
+	Source      Transferrer
+	Destination Transferrer
+	Recipient   common.Address
+	// Denominated in the token of the sending transferrer
+	Amount *big.Int
+
+	// Set for multi-hop sends only
+	FinalDestination Transferrer
+	SecondaryFee     *big.Int
+	MultiHopFallback common.Address
+}
+
+// Home is the model of a TokenHome instance.
+type Home struct {
+	Transferrer
+	TokenDecimals uint8
+
+	remotes             map[Transferrer]*RemoteSettings
+	transferredBalances map[Transferrer]*big.Int
+	// Token balances of accounts on the home chain, including the TokenHome itself
+	balances map[common.Address]*big.Int
+}
+
+// Remote is the model of a TokenRemote instance.
+type Remote struct {
+	Transferrer
+	TokenDecimals           uint8
+	InitialReserveImbalance *big.Int
+	TokenMultiplier         *big.Int
+	MultiplyOnRemote        bool
+
+	isRegistered     bool
+	isCollateralized bool
+	totalSupply      *big.Int
+	balances         map[common.Address]*big.Int
+}
+
+// Model is the reference model of a TokenHome and the TokenRemote instances that send messages to it.
+type Model struct {
+	Home    *Home
+	Remotes map[Transferrer]*Remote
+
+	pending []Message
+	events  []Event
+}
+
+// New returns a model of a TokenHome instance with no registered remotes.
+func New(home Transferrer, homeTokenDecimals uint8) *Model {
+	return &Model{
+		Home: &Home{
+			Transferrer:         home,
+			TokenDecimals:       homeTokenDecimals,
+			remotes:             make(map[Transferrer]*RemoteSettings),
+			transferredBalances: make(map[Transferrer]*big.Int),
+			balances:            make(map[common.Address]*big.Int),
+		},
+		Remotes: make(map[Transferrer]*Remote),
+	}
+}
+
+// AddRemote adds a deployed TokenRemote instance of the model's TokenHome.
+// The remote is collateralized if it has no initial reserve imbalance.
+func (m *Model) AddRemote(remote Transferrer, tokenDecimals uint8, initialReserveImbalance *big.Int) *Remote {
+	tokenMultiplier, multiplyOnRemote := TokenMultiplierValues(m.Home.TokenDecimals, tokenDecimals)
+	r := &Remote{
+		Transferrer:             remote,
+		TokenDecimals:           tokenDecimals,
+		InitialReserveImbalance: new(big.Int).Set(initialReserveImbalance),
+		TokenMultiplier:         tokenMultiplier,
+		MultiplyOnRemote:        multiplyOnRemote,
+		isCollateralized:        initialReserveImbalance.Sign() == 0,
+		totalSupply:             big.NewInt(0),
+		balances:                make(map[common.Address]*big.Int),
+	}
+	m.Remotes[remote] = r
+	return r
+}
+
+// Pending returns the messages that have been sent but not yet relayed, oldest first.
+func (m *Model) Pending() []Message {
+	return m.pending
+}
+
+// Events returns every event emitted so far, in order.
+func (m *Model) Events() []Event {
+	return m.events
+}
+
+// RegisterWithHome sends the register message of the remote to the home.
+func (m *Model) RegisterWithHome(remote Transferrer) error {
+	r, ok := m.Remotes[remote]
+	if !ok {
+		return fmt.Errorf("%w %s", ErrUnknownTransferrer, remote)
+	}
+	if r.isRegistered {
+		return ErrAlreadyRegisteredWithHome
+	}
+	m.pending = append(m.pending, Message{
+		Type:        RegisterRemote,
+		Source:      remote,
+		Destination: m.Home.Transferrer,
+	})
+	return nil
+}
+
+// AddCollateral deposits amount from sender as collateral for the remote.
+// Any amount in excess of the collateral needed is returned to the sender.
+func (m *Model) AddCollateral(sender common.Address, remote Transferrer, amount *big.Int) ([]Event, error) {
+	h := m.Home
+	settings, ok := h.remotes[remote]
+	if !ok || !settings.Registered {
+		return nil, ErrRemoteNotRegistered
+	}
+	if settings.CollateralNeeded.Sign() == 0 {
+		return nil, ErrZeroCollateralNeeded
+	}
+	if err := h.transfer(sender, h.Address, amount); err != nil {
+		return nil, err
+	}
+
+	added := new(big.Int).Set(amount)
+	excess := big.NewInt(0)
+	if added.Cmp(settings.CollateralNeeded) >= 0 {
+		excess.Sub(added, settings.CollateralNeeded)
+		added.Set(settings.CollateralNeeded)
+	}
+	settings.CollateralNeeded = new(big.Int).Sub(settings.CollateralNeeded, added)
+
+	events := []Event{{
+		Kind:      CollateralAdded,
+		Emitter:   h.Transferrer,
+		Remote:    remote,
+		Amount:    added,
+		Remaining: new(big.Int).Set(settings.CollateralNeeded),
+	}}
+	if excess.Sign() > 0 {
+		events = append(events, h.withdraw(sender, excess))
+	}
+	return m.emit(events), nil
+}
+
+// SendFromHome deposits amount from sender and sends the scaled amount to recipient on the remote.
+func (m *Model) SendFromHome(
+	sender common.Address,
+	remote Transferrer,
+	recipient common.Address,
+	amount *big.Int,
+) ([]Event, error) {
+	h := m.Home
+	settings, ok := h.remotes[remote]
+	if !ok || !settings.Registered {
+		return nil, ErrRemoteNotRegistered
+	}
+	if settings.CollateralNeeded.Sign() != 0 {
+		return nil, ErrCollateralNeeded
+	}
+	// The deposit is checked before the scaled amount
+	if h.balance(sender).Cmp(amount) < 0 {
+		return nil, ErrInsufficientBalance
+	}
+	scaledAmount := utils.ApplyTokenScaling(settings.TokenMultiplier, settings.MultiplyOnRemote, amount)
+	if scaledAmount.Sign() == 0 {
+		return nil, ErrZeroScaledAmount
+	}
+	if err := h.transfer(sender, h.Address, amount); err != nil {
+		return nil, err
+	}
+	h.transferredBalance(remote).Add(h.transferredBalance(remote), scaledAmount)
+
+	m.pending = append(m.pending, Message{
+		Type:        SingleHopSend,
+		Source:      h.Transferrer,
+		Destination: remote,
+		Recipient:   recipient,
+		Amount:      scaledAmount,
+	})
+	return m.emit([]Event{{
+		Kind:        TokensSent,
+		Emitter:     h.Transferrer,
+		Sender:      sender,
+		Destination: remote,
+		Recipient:   recipient,
+		Amount:      new(big.Int).Set(scaledAmount),
+	}}), nil
+}
+
+// SendFromRemote burns amount from sender and sends it back to recipient on the home chain.
+func (m *Model) SendFromRemote(
+	remote Transferrer,
+	sender common.Address,
+	recipient common.Address,
+	amount *big.Int,
+) ([]Event, error) {
+	return m.sendFromRemote(Message{
+		Type:        SingleHopSend,
+		Source:      remote,
+		Destination: m.Home.Transferrer,
+		Recipient:   recipient,
+		Amount:      amount,
+	}, sender)
+}
+
+// SendMultiHop burns amount from sender and sends it to recipient on the destination remote,
+// routed through the home. The home deducts secondaryFee from the routed amount to pay for the second hop,
+// and withdraws the full amount to multiHopFallback on the home chain if it can not be routed.
+func (m *Model) SendMultiHop(
+	remote Transferrer,
+	sender common.Address,
+	destination Transferrer,
+	recipient common.Address,
+	amount *big.Int,
+	secondaryFee *big.Int,
+	multiHopFallback common.Address,
+) ([]Event, error) {
+	return m.sendFromRemote(Message{
+		Type:             MultiHopSend,
+		Source:           remote,
+		Destination:      m.Home.Transferrer,
+		Recipient:        recipient,
+		Amount:           amount,
+		FinalDestination: destination,
+		SecondaryFee:     secondaryFee,
+		MultiHopFallback: multiHopFallback,
+	}, sender)
+}
+
+func (m *Model) sendFromRemote(message Message, sender common.Address) ([]Event, error) {
+	r, ok := m.Remotes[message.Source]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownTransferrer, message.Source)
+	}
+	secondaryFee := message.SecondaryFee
+	if secondaryFee == nil {
+		secondaryFee = big.NewInt(0)
+	}
+	// The contract burns the tokens before checking that the amount covers the secondary fee
+	// once scaled to the home token. The revert undoes the burn, so the checks come first here.
+	if r.balance(sender).Cmp(message.Amount) < 0 {
+		return nil, ErrInsufficientBalance
+	}
+	if utils.RemoveTokenScaling(r.TokenMultiplier, r.MultiplyOnRemote, message.Amount).Cmp(
+		utils.RemoveTokenScaling(r.TokenMultiplier, r.MultiplyOnRemote, secondaryFee),
+	) <= 0 {
+		return nil, ErrInsufficientTokensToTransfer
+	}
+	if err := r.burn(sender, message.Amount); err != nil {
+		return nil, err
+	}
+
+	message.Amount = new(big.Int).Set(message.Amount)
+	message.SecondaryFee = new(big.Int).Set(secondaryFee)
+	m.pending = append(m.pending, message)
+
+	destination := message.Destination
+	if message.Type == MultiHopSend {
+		destination = message.FinalDestination
+	}
+	return m.emit([]Event{{
+		Kind:        TokensSent,
+		Emitter:     r.Transferrer,
+		Sender:      sender,
+		Destination: destination,
+		Recipient:   message.Recipient,
+		Amount:      new(big.Int).Set(message.Amount),
+	}}), nil
+}
+
+// Relay delivers the oldest pending message. If the receiving contract reverts, the error is returned
+// and the message is dropped without changing any state, as its execution fails on chain.
+// Messages sent while delivering the message, such as the second hop of a multi-hop send, are queued.
+func (m *Model) Relay() (Message, []Event, error) {
+	if len(m.pending) == 0 {
+		return Message{}, nil, ErrNoPendingMessages
+	}
+	message := m.pending[0]
+	m.pending = m.pending[1:]
+
+	var (
+		events []Event
+		err    error
+	)
+	if message.Destination == m.Home.Transferrer {
+		events, err = m.receiveOnHome(message)
+	} else {
+		events, err = m.receiveOnRemote(message)
+	}
+	if err != nil {
+		return message, nil, err
+	}
+	return message, m.emit(events), nil
+}
+
+func (m *Model) receiveOnHome(message Message) ([]Event, error) {
+	h := m.Home
+	switch message.Type {
+	case RegisterRemote:
+		r, ok := m.Remotes[message.Source]
+		if !ok {
+			return nil, fmt.Errorf("%w %s", ErrUnknownTransferrer, message.Source)
+		}
+		return h.registerRemote(r)
+	case SingleHopSend:
+		homeAmount, _, err := h.processReceivedTransfer(message.Source, message.Amount)
+		if err != nil {
+			return nil, err
+		}
+		h.transferredBalance(message.Source).Sub(h.transferredBalance(message.Source), message.Amount)
+		return []Event{h.withdraw(message.Recipient, homeAmount)}, nil
+	case MultiHopSend:
+		homeAmount, settings, err := h.processReceivedTransfer(message.Source, message.Amount)
+		if err != nil {
+			return nil, err
+		}
+		fee := utils.RemoveTokenScaling(settings.TokenMultiplier, settings.MultiplyOnRemote, message.SecondaryFee)
+
+		// Route to the final destination, falling back to the multi-hop fallback if the destination
+		// is not registered, needs collateral, or the routed amount scales down to zero.
+		var scaledAmount *big.Int
+		destination, ok := h.remotes[message.FinalDestination]
+		if ok && destination.Registered && destination.CollateralNeeded.Sign() == 0 {
+			if homeAmount.Cmp(fee) <= 0 {
+				return nil, ErrInsufficientAmountForFees
+			}
+			scaledAmount = utils.ApplyTokenScaling(
+				destination.TokenMultiplier,
+				destination.MultiplyOnRemote,
+				new(big.Int).Sub(homeAmount, fee),
+			)
+		}
+
+		h.transferredBalance(message.Source).Sub(h.transferredBalance(message.Source), message.Amount)
+		if scaledAmount == nil || scaledAmount.Sign() == 0 {
+			return []Event{h.withdraw(message.MultiHopFallback, homeAmount)}, nil
+		}
+
+		// The fee for the second hop is paid from the tokens held by the home
+		h.balance(h.Address).Sub(h.balance(h.Address), fee)
+		h.transferredBalance(message.FinalDestination).Add(h.transferredBalance(message.FinalDestination), scaledAmount)
+		m.pending = append(m.pending, Message{
+			Type:        SingleHopSend,
+			Source:      h.Transferrer,
+			Destination: message.FinalDestination,
+			Recipient:   message.Recipient,
+			Amount:      scaledAmount,
+		})
+		return []Event{{
+			Kind:        TokensRouted,
+			Emitter:     h.Transferrer,
+			Destination: message.FinalDestination,
+			Recipient:   message.Recipient,
+			Amount:      new(big.Int).Set(scaledAmount),
+		}}, nil
+	default:
+		return nil, fmt.Errorf("invalid message type %d", message.Type)
+	}
+}
+
+func (m *Model) receiveOnRemote(message Message) ([]Event, error) {
+	r, ok := m.Remotes[message.Destination]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownTransferrer, message.Destination)
+	}
+	// Any message from the home implies that the remote is registered and collateralized
+	r.isRegistered = true
+	r.isCollateralized = true
+
+	r.totalSupply.Add(r.totalSupply, message.Amount)
+	r.balance(message.Recipient).Add(r.balance(message.Recipient), message.Amount)
+	return []Event{{
+		Kind:      TokensWithdrawn,
+		Emitter:   r.Transferrer,
+		Recipient: message.Recipient,
+		Amount:    new(big.Int).Set(message.Amount),
+	}}, nil
+}
+
+func (m *Model) emit(events []Event) []Event {
+	m.events = append(m.events, events...)
+	return events
+}
+
+// Settings returns a copy of the settings of the remote, or the zero settings if it is not registered.
+func (h *Home) Settings(remote Transferrer) RemoteSettings {
+	settings, ok := h.remotes[remote]
+	if !ok {
+		return RemoteSettings{
+			CollateralNeeded: big.NewInt(0),
+			TokenMultiplier:  big.NewInt(0),
+		}
+	}
+	return RemoteSettings{
+		Registered:       settings.Registered,
+		CollateralNeeded: new(big.Int).Set(settings.CollateralNeeded),
+		TokenMultiplier:  new(big.Int).Set(settings.TokenMultiplier),
+		MultiplyOnRemote: settings.MultiplyOnRemote,
+	}
+}
+
+// TransferredBalance returns the balance transferred to the remote, denominated in the remote's token.
+func (h *Home) TransferredBalance(remote Transferrer) *big.Int {
+	return new(big.Int).Set(h.transferredBalance(remote))
+}
+
+// Balance returns the home token balance of the account.
+func (h *Home) Balance(account common.Address) *big.Int {
+	return new(big.Int).Set(h.balance(account))
+}
+
+// SetBalance sets the home token balance of the account, such as when funding it outside of the model.
+func (h *Home) SetBalance(account common.Address, amount *big.Int) {
+	h.balances[account] = new(big.Int).Set(amount)
+}
+
+func (h *Home) registerRemote(r *Remote) ([]Event, error) {
+	if settings, ok := h.remotes[r.Transferrer]; ok && settings.Registered {
+		return nil, ErrRemoteAlreadyRegistered
+	}
+	if r.TokenDecimals > MaxTokenDecimals {
+		return nil, ErrRemoteTokenDecimalsTooHigh
+	}
+	tokenMultiplier, multiplyOnRemote := TokenMultiplierValues(h.TokenDecimals, r.TokenDecimals)
+	collateralNeeded := utils.CalculateCollateralNeeded(r.InitialReserveImbalance, tokenMultiplier, multiplyOnRemote)
+	h.remotes[r.Transferrer] = &RemoteSettings{
+		Registered:       true,
+		CollateralNeeded: collateralNeeded,
+		TokenMultiplier:  tokenMultiplier,
+		MultiplyOnRemote: multiplyOnRemote,
+	}
+	return []Event{{
+		Kind:                RemoteRegistered,
+		Emitter:             h.Transferrer,
+		Remote:              r.Transferrer,
+		Remaining:           new(big.Int).Set(collateralNeeded),
+		RemoteTokenDecimals: r.TokenDecimals,
+	}}, nil
+}
+
+// processReceivedTransfer checks that tokens can be received from the remote, and returns
+// the home token amount. The transferred balance is not deducted, so that the caller can
+// still revert without changing any state.
+func (h *Home) processReceivedTransfer(remote Transferrer, amount *big.Int) (*big.Int, *RemoteSettings, error) {
+	settings, ok := h.remotes[remote]
+	if !ok || !settings.Registered {
+		return nil, nil, ErrRemoteNotRegistered
+	}
+	if settings.CollateralNeeded.Sign() != 0 {
+		return nil, nil, ErrRemoteNotCollateralized
+	}
+	if h.transferredBalance(remote).Cmp(amount) < 0 {
+		return nil, nil, ErrInsufficientTransferBalance
+	}
+	homeAmount := utils.RemoveTokenScaling(settings.TokenMultiplier, settings.MultiplyOnRemote, amount)
+	if homeAmount.Sign() == 0 {
+		return nil, nil, ErrZeroTokenAmount
+	}
+	return homeAmount, settings, nil
+}
+
+func (h *Home) withdraw(recipient common.Address, amount *big.Int) Event {
+	h.balance(h.Address).Sub(h.balance(h.Address), amount)
+	h.balance(recipient).Add(h.balance(recipient), amount)
+	return Event{
+		Kind:      TokensWithdrawn,
+		Emitter:   h.Transferrer,
+		Recipient: recipient,
+		Amount:    new(big.Int).Set(amount),
+	}
+}
+
+func (h *Home) transfer(from common.Address, to common.Address, amount *big.Int) error {
+	if h.balance(from).Cmp(amount) < 0 {
+		return ErrInsufficientBalance
+	}
+	h.balance(from).Sub(h.balance(from), amount)
+	h.balance(to).Add(h.balance(to), amount)
+	return nil
+}
+
+func (h *Home) transferredBalance(remote Transferrer) *big.Int {
+	if _, ok := h.transferredBalances[remote]; !ok {
+		h.transferredBalances[remote] = big.NewInt(0)
+	}
+	return h.transferredBalances[remote]
+}
+
+func (h *Home) balance(account common.Address) *big.Int {
+	if _, ok := h.balances[account]; !ok {
+		h.balances[account] = big.NewInt(0)
+	}
+	return h.balances[account]
+}
+
+// IsRegistered returns true once the remote has received a message from the home.
+func (r *Remote) IsRegistered() bool {
+	return r.isRegistered
+}
+
+// IsCollateralized returns true if the remote had no initial reserve imbalance,
+// or once it has received a message from the home.
+func (r *Remote) IsCollateralized() bool {
+	return r.isCollateralized
+}
+
+// TotalSupply returns the amount of tokens minted by the remote, less the amount burned.
+func (r *Remote) TotalSupply() *big.Int {
+	return new(big.Int).Set(r.totalSupply)
+}
+
+// Balance returns the remote token balance of the account.
+func (r *Remote) Balance(account common.Address) *big.Int {
+	return new(big.Int).Set(r.balance(account))
+}
+
+func (r *Remote) burn(account common.Address, amount *big.Int) error {
+	if r.balance(account).Cmp(amount) < 0 {
+		return ErrInsufficientBalance
+	}
+	r.balance(account).Sub(r.balance(account), amount)
+	r.totalSupply.Sub(r.totalSupply, amount)
+	return nil
+}
+
+func (r *Remote) balance(account common.Address) *big.Int {
+	if _, ok := r.balances[account]; !ok {
+		r.balances[account] = big.NewInt(0)
+	}
+	return r.balances[account]
+}
+
+// TokenMultiplierValues returns the token multiplier, and whether it is applied by multiplying amounts
+// sent to the remote, for the given home and remote token decimals.
+func TokenMultiplierValues(homeTokenDecimals uint8, remoteTokenDecimals uint8) (*big.Int, bool) {
+	if remoteTokenDecimals > homeTokenDecimals {
+		return utils.GetTokenMultiplier(remoteTokenDecimals - homeTokenDecimals), true
+	}
+	return utils.GetTokenMultiplier(homeTokenDecimals - remoteTokenDecimals), false
+}
@@ -0,0 +1,295 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package model
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	homeTransferrer = Transferrer{BlockchainID: ids.ID{1}, Address: common.HexToAddress("0x01")}
+	remoteA         = Transferrer{BlockchainID: ids.ID{2}, Address: common.HexToAddress("0x02")}
+	remoteB         = Transferrer{BlockchainID: ids.ID{3}, Address: common.HexToAddress("0x03")}
+	alice           = common.HexToAddress("0xa1")
+	bob             = common.HexToAddress("0xb0")
+)
+
+// newModel returns a model with a 12 decimal home token, remote A with 6 decimals and remote B with 14 decimals,
+// both registered. Alice holds 1e9 home tokens.
+func newModel(t *testing.T, initialReserveImbalanceB *big.Int) *Model {
+	m := New(homeTransferrer, 12)
+	m.AddRemote(remoteA, 6, big.NewInt(0))
+	m.AddRemote(remoteB, 14, initialReserveImbalanceB)
+	m.Home.SetBalance(alice, big.NewInt(1e9))
+
+	require.NoError(t, m.RegisterWithHome(remoteA))
+	require.NoError(t, m.RegisterWithHome(remoteB))
+	relayAll(t, m)
+	return m
+}
+
+func relayAll(t *testing.T, m *Model) []Event {
+	var events []Event
+	for len(m.Pending()) != 0 {
+		_, relayed, err := m.Relay()
+		require.NoError(t, err)
+		events = append(events, relayed...)
+	}
+	return events
+}
+
+func TestTokenMultiplierValues(t *testing.T) {
+	multiplier, multiplyOnRemote := TokenMultiplierValues(18, 6)
+	requireBigEqual(t, big.NewInt(1e12), multiplier)
+	require.False(t, multiplyOnRemote)
+
+	multiplier, multiplyOnRemote = TokenMultiplierValues(6, 18)
+	requireBigEqual(t, big.NewInt(1e12), multiplier)
+	require.True(t, multiplyOnRemote)
+
+	multiplier, multiplyOnRemote = TokenMultiplierValues(9, 9)
+	requireBigEqual(t, big.NewInt(1), multiplier)
+	require.False(t, multiplyOnRemote)
+}
+
+func TestRegisterAndAddCollateral(t *testing.T) {
+	m := newModel(t, big.NewInt(1001))
+	require.Len(t, m.Events(), 2)
+	requireEventsEqual(t, []Event{{
+		Kind:                RemoteRegistered,
+		Emitter:             homeTransferrer,
+		Remote:              remoteB,
+		Remaining:           big.NewInt(11),
+		RemoteTokenDecimals: 14,
+	}}, m.Events()[1:])
+	require.False(t, m.Remotes[remoteB].IsCollateralized())
+
+	_, err := m.SendFromHome(alice, remoteB, bob, big.NewInt(1))
+	require.ErrorIs(t, err, ErrCollateralNeeded)
+	_, err = m.AddCollateral(alice, remoteA, big.NewInt(1))
+	require.ErrorIs(t, err, ErrZeroCollateralNeeded)
+
+	// The excess collateral is returned to the sender
+	events, err := m.AddCollateral(alice, remoteB, big.NewInt(20))
+	require.NoError(t, err)
+	requireEventsEqual(t, []Event{
+		{
+			Kind:      CollateralAdded,
+			Emitter:   homeTransferrer,
+			Remote:    remoteB,
+			Amount:    big.NewInt(11),
+			Remaining: big.NewInt(0),
+		},
+		{
+			Kind:      TokensWithdrawn,
+			Emitter:   homeTransferrer,
+			Recipient: alice,
+			Amount:    big.NewInt(9),
+		},
+	}, events)
+	requireBigEqual(t, big.NewInt(1e9-11), m.Home.Balance(alice))
+	requireBigEqual(t, big.NewInt(11), m.Home.Balance(homeTransferrer.Address))
+
+	// The first message from the home collateralizes the remote
+	_, err = m.SendFromHome(alice, remoteB, bob, big.NewInt(1))
+	require.NoError(t, err)
+	relayAll(t, m)
+	require.True(t, m.Remotes[remoteB].IsCollateralized())
+	requireBigEqual(t, big.NewInt(100), m.Remotes[remoteB].Balance(bob))
+}
+
+func TestSendAndReturn(t *testing.T) {
+	m := newModel(t, big.NewInt(0))
+
+	// 1 remote token on remote A is 1e6 home tokens
+	_, err := m.SendFromHome(alice, remoteA, bob, big.NewInt(1))
+	require.ErrorIs(t, err, ErrZeroScaledAmount)
+	m.Home.SetBalance(alice, big.NewInt(5e6))
+	events, err := m.SendFromHome(alice, remoteA, bob, big.NewInt(5e6+1))
+	require.ErrorIs(t, err, ErrInsufficientBalance)
+	require.Nil(t, events)
+
+	_, err = m.SendFromHome(alice, remoteA, bob, big.NewInt(3e6))
+	require.NoError(t, err)
+	requireBigEqual(t, big.NewInt(3), m.Home.TransferredBalance(remoteA))
+	requireBigEqual(t, big.NewInt(0), m.Remotes[remoteA].Balance(bob))
+	relayAll(t, m)
+	requireBigEqual(t, big.NewInt(3), m.Remotes[remoteA].Balance(bob))
+	requireBigEqual(t, big.NewInt(3), m.Remotes[remoteA].TotalSupply())
+
+	_, err = m.SendFromRemote(remoteA, bob, alice, big.NewInt(4))
+	require.ErrorIs(t, err, ErrInsufficientBalance)
+	_, err = m.SendFromRemote(remoteA, bob, alice, big.NewInt(2))
+	require.NoError(t, err)
+	requireBigEqual(t, big.NewInt(1), m.Remotes[remoteA].TotalSupply())
+	events = relayAll(t, m)
+	requireEventsEqual(t, []Event{{
+		Kind:      TokensWithdrawn,
+		Emitter:   homeTransferrer,
+		Recipient: alice,
+		Amount:    big.NewInt(2e6),
+	}}, events)
+	requireBigEqual(t, big.NewInt(1), m.Home.TransferredBalance(remoteA))
+	requireBigEqual(t, big.NewInt(4e6), m.Home.Balance(alice))
+	requireBigEqual(t, big.NewInt(1e6), m.Home.Balance(homeTransferrer.Address))
+}
+
+func TestSendFromRemoteBelowHomeDenomination(t *testing.T) {
+	m := newModel(t, big.NewInt(0))
+	_, err := m.SendFromHome(alice, remoteB, bob, big.NewInt(1))
+	require.NoError(t, err)
+	relayAll(t, m)
+
+	// 99 remote tokens on remote B are less than 1 home token
+	_, err = m.SendFromRemote(remoteB, bob, alice, big.NewInt(99))
+	require.ErrorIs(t, err, ErrInsufficientTokensToTransfer)
+	requireBigEqual(t, big.NewInt(100), m.Remotes[remoteB].Balance(bob))
+}
+
+func TestMultiHop(t *testing.T) {
+	unregistered := Transferrer{BlockchainID: ids.ID{4}, Address: common.HexToAddress("0x04")}
+	testCases := []struct {
+		name         string
+		source       Transferrer
+		destination  Transferrer
+		amount       *big.Int
+		secondaryFee *big.Int
+		// The amount routed to the destination, or nil if withdrawn to the fallback
+		routed *big.Int
+		// The home token amount withdrawn to the fallback
+		fallback *big.Int
+	}{
+		{
+			// 3 remote A tokens are 3e6 home tokens, of which 1e6 are paid for the second hop
+			name:         "routed",
+			source:       remoteA,
+			destination:  remoteB,
+			amount:       big.NewInt(3),
+			secondaryFee: big.NewInt(1),
+			routed:       big.NewInt(2e8),
+		},
+		{
+			// 1000 remote B tokens are 10 home tokens, and the remaining 8 scale to zero on remote A
+			name:         "scaled to zero",
+			source:       remoteB,
+			destination:  remoteA,
+			amount:       big.NewInt(1000),
+			secondaryFee: big.NewInt(200),
+			fallback:     big.NewInt(10),
+		},
+		{
+			name:         "unregistered destination",
+			source:       remoteB,
+			destination:  unregistered,
+			amount:       big.NewInt(1000),
+			secondaryFee: big.NewInt(200),
+			fallback:     big.NewInt(10),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			m := newModel(t, big.NewInt(0))
+			m.Home.SetBalance(alice, big.NewInt(1e13))
+			_, err := m.SendFromHome(alice, remoteA, bob, big.NewInt(5e6))
+			require.NoError(t, err)
+			_, err = m.SendFromHome(alice, remoteB, bob, big.NewInt(10))
+			require.NoError(t, err)
+			relayAll(t, m)
+			locked := m.Home.Balance(homeTransferrer.Address)
+			sourceBalance := m.Home.TransferredBalance(testCase.source)
+
+			_, err = m.SendMultiHop(
+				testCase.source,
+				bob,
+				testCase.destination,
+				alice,
+				testCase.amount,
+				testCase.secondaryFee,
+				bob,
+			)
+			require.NoError(t, err)
+			_, events, err := m.Relay()
+			require.NoError(t, err)
+			requireBigEqual(
+				t,
+				new(big.Int).Sub(sourceBalance, testCase.amount),
+				m.Home.TransferredBalance(testCase.source),
+			)
+
+			if testCase.routed == nil {
+				requireEventsEqual(t, []Event{{
+					Kind:      TokensWithdrawn,
+					Emitter:   homeTransferrer,
+					Recipient: bob,
+					Amount:    testCase.fallback,
+				}}, events)
+				require.Empty(t, m.Pending())
+				requireBigEqual(t, testCase.fallback, m.Home.Balance(bob))
+				requireBigEqual(t, new(big.Int).Sub(locked, testCase.fallback), m.Home.Balance(homeTransferrer.Address))
+				return
+			}
+
+			requireEventsEqual(t, []Event{{
+				Kind:        TokensRouted,
+				Emitter:     homeTransferrer,
+				Destination: testCase.destination,
+				Recipient:   alice,
+				Amount:      testCase.routed,
+			}}, events)
+			// The fee for the second hop is paid by the home
+			requireBigEqual(t, new(big.Int).Sub(locked, big.NewInt(1e6)), m.Home.Balance(homeTransferrer.Address))
+			initialBalance := m.Remotes[testCase.destination].Balance(alice)
+			relayAll(t, m)
+			requireBigEqual(
+				t,
+				new(big.Int).Add(initialBalance, testCase.routed),
+				m.Remotes[testCase.destination].Balance(alice),
+			)
+		})
+	}
+}
+
+func TestFailedRelayLeavesStateUnchanged(t *testing.T) {
+	m := newModel(t, big.NewInt(1001))
+	_, err := m.SendFromHome(alice, remoteA, bob, big.NewInt(1e6))
+	require.NoError(t, err)
+	relayAll(t, m)
+
+	// Remote B needs collateral, so tokens routed back through the home fall back on the home chain
+	_, err = m.SendMultiHop(remoteA, bob, remoteB, alice, big.NewInt(1), big.NewInt(0), bob)
+	require.NoError(t, err)
+	relayAll(t, m)
+	requireBigEqual(t, big.NewInt(1e6), m.Home.Balance(bob))
+
+	// Messages from a remote that is not collateralized fail on the home
+	m.Remotes[remoteB].balances[bob] = big.NewInt(100)
+	_, err = m.SendFromRemote(remoteB, bob, alice, big.NewInt(100))
+	require.NoError(t, err)
+	events := len(m.Events())
+	_, _, err = m.Relay()
+	require.ErrorIs(t, err, ErrRemoteNotCollateralized)
+	require.Len(t, m.Events(), events)
+	requireBigEqual(t, big.NewInt(0), m.Home.TransferredBalance(remoteB))
+
+	_, _, err = m.Relay()
+	require.ErrorIs(t, err, ErrNoPendingMessages)
+}
+
+func requireBigEqual(t *testing.T, expected *big.Int, actual *big.Int) {
+	t.Helper()
+	require.Zero(t, expected.Cmp(actual), "expected %s, actual %s", expected, actual)
+}
+
+func requireEventsEqual(t *testing.T, expected []Event, actual []Event) {
+	t.Helper()
+	require.Len(t, actual, len(expected))
+	for i := range expected {
+		require.Equal(t, expected[i].String(), actual[i].String())
+	}
+}
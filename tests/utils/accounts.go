@@ -0,0 +1,161 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"math/big"
+	"sync"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	. "github.com/onsi/gomega"
+)
+
+// Native token balance given to each account funded by SpecNetwork, on every chain.
+// Enough for the deployments and native token transfers of any of the flows.
+var SpecAccountBalance = new(big.Int).Mul(big.NewInt(1e18), big.NewInt(10_000))
+
+// DeriveKey deterministically derives the index-th private key of the given label from a parent key,
+// so that every process of a parallel run derives the same keys without sharing them.
+func DeriveKey(parentKey *ecdsa.PrivateKey, label string, index uint64) *ecdsa.PrivateKey {
+	indexBytes := binary.BigEndian.AppendUint64(nil, index)
+	key, err := crypto.ToECDSA(crypto.Keccak256(crypto.FromECDSA(parentKey), []byte(label), indexBytes))
+	Expect(err).Should(BeNil())
+	return key
+}
+
+// Funder sends native tokens from a single key to accounts on several chains at once.
+// Nonces are assigned under a lock from a local count of the transactions sent by the key,
+// rather than read from the chain, so that concurrent transfers never reuse a nonce.
+// The funding key must not be used to send transactions other than through the Funder.
+type Funder struct {
+	lock   sync.Mutex
+	key    *ecdsa.PrivateKey
+	nonces map[ids.ID]uint64
+}
+
+func NewFunder(key *ecdsa.PrivateKey) *Funder {
+	return &Funder{
+		key:    key,
+		nonces: make(map[ids.ID]uint64),
+	}
+}
+
+// Fund sends amount to each recipient on each of the chains, and waits for all of the transfers to be accepted.
+func (f *Funder) Fund(
+	ctx context.Context,
+	chains []interfaces.SubnetTestInfo,
+	recipients []common.Address,
+	amount *big.Int,
+) {
+	type sentTransfer struct {
+		chain  interfaces.SubnetTestInfo
+		txHash common.Hash
+	}
+	var sent []sentTransfer
+	for _, chain := range chains {
+		for _, recipient := range recipients {
+			txHash := f.send(ctx, chain, recipient, amount)
+			sent = append(sent, sentTransfer{chain: chain, txHash: txHash})
+		}
+	}
+	for _, transfer := range sent {
+		teleporterUtils.WaitForTransactionSuccess(ctx, transfer.chain, transfer.txHash)
+	}
+}
+
+func (f *Funder) send(
+	ctx context.Context,
+	chain interfaces.SubnetTestInfo,
+	recipient common.Address,
+	amount *big.Int,
+) common.Hash {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	fromAddress := crypto.PubkeyToAddress(f.key.PublicKey)
+	// The nonce is only read from the chain on the first transfer, when no transfer of the key is pending.
+	gasFeeCap, gasTipCap, chainNonce := teleporterUtils.CalculateTxParams(ctx, chain, fromAddress)
+	nonce, ok := f.nonces[chain.BlockchainID]
+	if !ok {
+		nonce = chainNonce
+	}
+
+	tx := teleporterUtils.SignTransaction(types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chain.EVMChainID,
+		Nonce:     nonce,
+		To:        &recipient,
+		Gas:       teleporterUtils.NativeTransferGas,
+		GasFeeCap: gasFeeCap,
+		GasTipCap: gasTipCap,
+		Value:     amount,
+	}), f.key, chain.EVMChainID)
+	if err := chain.RPCClient.SendTransaction(ctx, tx); err != nil {
+		// Read the nonce from the chain again on the next transfer, since this one may or may not have been accepted.
+		delete(f.nonces, chain.BlockchainID)
+		Expect(err).Should(BeNil())
+	}
+	f.nonces[chain.BlockchainID] = nonce + 1
+	return tx.Hash()
+}
+
+// Accounts derives the funded accounts of the specs run by a single process, from a key of the process.
+type Accounts struct {
+	lock   sync.Mutex
+	label  string
+	count  uint64
+	funder *Funder
+}
+
+// NewAccounts returns the Accounts derived from processKey, which are funded by processKey.
+// The label distinguishes the accounts from other keys derived from processKey.
+func NewAccounts(processKey *ecdsa.PrivateKey, label string) *Accounts {
+	return &Accounts{
+		label:  label,
+		funder: NewFunder(processKey),
+	}
+}
+
+// NewSpecNetwork derives the next account, funds it with SpecAccountBalance on every chain of network,
+// and returns network with the new account as its funded account.
+func (a *Accounts) NewSpecNetwork(ctx context.Context, network interfaces.Network) *SpecNetwork {
+	a.lock.Lock()
+	index := a.count
+	a.count++
+	a.lock.Unlock()
+
+	key := DeriveKey(a.funder.key, a.label, index)
+	a.funder.Fund(
+		ctx,
+		network.GetAllSubnetsInfo(),
+		[]common.Address{crypto.PubkeyToAddress(key.PublicKey)},
+		SpecAccountBalance,
+	)
+	return &SpecNetwork{
+		Network:   network,
+		fundedKey: key,
+	}
+}
+
+var _ interfaces.Network = &SpecNetwork{}
+
+// SpecNetwork is a network whose funded account is specific to a single spec, so that specs running
+// in parallel against the same network each send transactions from their own account.
+// Every other method is that of the underlying network, including the relaying of messages.
+type SpecNetwork struct {
+	interfaces.Network
+	fundedKey *ecdsa.PrivateKey
+}
+
+func (n *SpecNetwork) GetFundedAccountInfo() (common.Address, *ecdsa.PrivateKey) {
+	return crypto.PubkeyToAddress(n.fundedKey.PublicKey), n.fundedKey
+}
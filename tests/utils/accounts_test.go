@@ -0,0 +1,63 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveKey(t *testing.T) {
+	RegisterTestingT(t)
+	parentKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	key := DeriveKey(parentKey, "spec", 0)
+	require.Equal(t, crypto.FromECDSA(key), crypto.FromECDSA(DeriveKey(parentKey, "spec", 0)))
+
+	// Keys of different indices, labels and parents differ
+	otherParentKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	addresses := map[string]struct{}{}
+	for _, derived := range []struct {
+		parent int
+		label  string
+		index  uint64
+	}{
+		{0, "spec", 0},
+		{0, "spec", 1},
+		{0, "relayer", 0},
+		{1, "spec", 0},
+	} {
+		parent := parentKey
+		if derived.parent == 1 {
+			parent = otherParentKey
+		}
+		address := crypto.PubkeyToAddress(DeriveKey(parent, derived.label, derived.index).PublicKey)
+		addresses[address.Hex()] = struct{}{}
+	}
+	require.Len(t, addresses, 4)
+}
+
+func TestNextNativeTokenRemoteDeployerKey(t *testing.T) {
+	RegisterTestingT(t)
+	t.Cleanup(func() {
+		nativeTokenRemoteDeployerKeyIndex = 0
+		SetNativeTokenRemoteDeployerKeyClaimDir("")
+	})
+	SetNativeTokenRemoteDeployerKeyClaimDir(t.TempDir())
+
+	// Another process claims the first two keys
+	require.True(t, claimNativeTokenRemoteDeployerKey(0))
+	require.True(t, claimNativeTokenRemoteDeployerKey(1))
+	require.False(t, claimNativeTokenRemoteDeployerKey(1))
+
+	expectedKey, err := crypto.HexToECDSA(nativeTokenRemoteDeployerKeys[2])
+	require.NoError(t, err)
+	require.Equal(t, crypto.FromECDSA(expectedKey), crypto.FromECDSA(nextNativeTokenRemoteDeployerKey()))
+	require.False(t, claimNativeTokenRemoteDeployerKey(2))
+}
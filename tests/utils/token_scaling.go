@@ -91,14 +91,17 @@ func GetScaledAmountFromNativeTokenHome(
 	)
 }
 
-func calculateCollateralNeeded(
+// CalculateCollateralNeeded returns the amount of home tokens needed to collateralize a TokenRemote
+// instance with the given initial reserve imbalance. The amount is rounded up if the imbalance is not
+// divisible by the token multiplier, so that the full imbalance is accounted for.
+func CalculateCollateralNeeded(
 	initialReserveImbalance *big.Int,
 	tokenMultiplier *big.Int,
 	multiplyOnRemote bool,
 ) *big.Int {
 	collateralNeeded := RemoveTokenScaling(tokenMultiplier, multiplyOnRemote, initialReserveImbalance)
 
-	remainder := big.NewInt(0).Mod(collateralNeeded, tokenMultiplier)
+	remainder := big.NewInt(0).Mod(initialReserveImbalance, tokenMultiplier)
 	if multiplyOnRemote && (remainder.Cmp(big.NewInt(0)) != 0) {
 		collateralNeeded.Add(collateralNeeded, big.NewInt(1))
 	}
@@ -0,0 +1,291 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind/backends"
+	"github.com/ava-labs/subnet-evm/core"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	tokenScalingUtilsHarnessArtifact = "../../contracts/out/TokenScalingUtilsHarness.sol/TokenScalingUtilsHarness.json"
+
+	// Decimals are fuzzed beyond the maximum supported by the token transferrers,
+	// since the library does not check them.
+	maxFuzzedDecimals = 36
+	// TokenScalingUtils.MAX_TOKEN_DECIMALS
+	maxTokenDecimals = 18
+
+	// A simulated backend always uses chain ID 1337
+	simulatedChainID = 1337
+)
+
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// tokenScalingUtilsHarness calls the TokenScalingUtils library through the TokenScalingUtilsHarness
+// contract, deployed to a simulated backend.
+type tokenScalingUtilsHarness struct {
+	contract *bind.BoundContract
+}
+
+// newTokenScalingUtilsHarness deploys the harness from the forge build output.
+// The test is skipped if the contracts have not been built.
+func newTokenScalingUtilsHarness(tb testing.TB) *tokenScalingUtilsHarness {
+	data, err := os.ReadFile(tokenScalingUtilsHarnessArtifact)
+	if errors.Is(err, fs.ErrNotExist) {
+		tb.Skipf("%s not found, run forge build in the contracts directory", tokenScalingUtilsHarnessArtifact)
+	}
+	require.NoError(tb, err)
+	var artifact struct {
+		ABI      json.RawMessage `json:"abi"`
+		Bytecode struct {
+			Object hexutil.Bytes `json:"object"`
+		} `json:"bytecode"`
+	}
+	require.NoError(tb, json.Unmarshal(data, &artifact))
+	parsed, err := abi.JSON(bytes.NewReader(artifact.ABI))
+	require.NoError(tb, err)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(tb, err)
+	backend := backends.NewSimulatedBackend(
+		core.GenesisAlloc{
+			crypto.PubkeyToAddress(key.PublicKey): {Balance: new(big.Int).Mul(big.NewInt(1e18), big.NewInt(100))},
+		},
+		10_000_000,
+	)
+	tb.Cleanup(func() { backend.Close() })
+
+	opts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(simulatedChainID))
+	require.NoError(tb, err)
+	_, _, contract, err := bind.DeployContract(opts, parsed, artifact.Bytecode.Object, backend)
+	require.NoError(tb, err)
+	backend.Commit(true)
+
+	return &tokenScalingUtilsHarness{contract: contract}
+}
+
+// call returns the results of the method, or an error if it reverts.
+func (h *tokenScalingUtilsHarness) call(method string, params ...interface{}) ([]interface{}, error) {
+	var results []interface{}
+	err := h.contract.Call(&bind.CallOpts{}, &results, method, params...)
+	return results, err
+}
+
+// scale calls applyTokenScale or removeTokenScale, and returns false if it reverts.
+func (h *tokenScalingUtilsHarness) scale(
+	tb testing.TB,
+	tokenMultiplier *big.Int,
+	multiplyOnRemote bool,
+	amount *big.Int,
+	isSendToRemote bool,
+) (*big.Int, bool) {
+	method := "removeTokenScale"
+	if isSendToRemote {
+		method = "applyTokenScale"
+	}
+	results, err := h.call(method, tokenMultiplier, multiplyOnRemote, amount)
+	if err != nil {
+		return nil, false
+	}
+	require.Len(tb, results, 1)
+	return results[0].(*big.Int), true
+}
+
+// expectedScale returns the result of scaleTokens, or false if the library is expected to revert,
+// because the multiplication overflows or the token multiplier is zero.
+func expectedScale(tokenMultiplier *big.Int, multiplyOnRemote bool, amount *big.Int, isSendToRemote bool) (*big.Int, bool) {
+	if multiplyOnRemote != isSendToRemote && tokenMultiplier.Sign() == 0 {
+		return nil, false
+	}
+	scaled := scaleTokens(tokenMultiplier, multiplyOnRemote, amount, isSendToRemote)
+	if scaled.Cmp(maxUint256) > 0 {
+		return nil, false
+	}
+	return scaled, true
+}
+
+// uint256FromBytes interprets the last 32 bytes of b as a big endian uint256.
+func uint256FromBytes(b []byte) *big.Int {
+	if len(b) > 32 {
+		b = b[len(b)-32:]
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+func FuzzScaleTokens(f *testing.F) {
+	harness := newTokenScalingUtilsHarness(f)
+
+	f.Add([]byte{1}, false, []byte{})
+	f.Add(big.NewInt(1e12).Bytes(), false, big.NewInt(1e18).Bytes())
+	f.Add(big.NewInt(1e12).Bytes(), true, big.NewInt(1e6+1).Bytes())
+	f.Add([]byte{}, true, []byte{5})
+	f.Add([]byte{2}, true, maxUint256.Bytes())
+	f.Add(GetTokenMultiplier(maxFuzzedDecimals).Bytes(), false, maxUint256.Bytes())
+
+	f.Fuzz(func(t *testing.T, tokenMultiplierBytes []byte, multiplyOnRemote bool, amountBytes []byte) {
+		tokenMultiplier := uint256FromBytes(tokenMultiplierBytes)
+		amount := uint256FromBytes(amountBytes)
+		for _, isSendToRemote := range []bool{true, false} {
+			expected, ok := expectedScale(tokenMultiplier, multiplyOnRemote, amount, isSendToRemote)
+			actual, actualOK := harness.scale(t, tokenMultiplier, multiplyOnRemote, amount, isSendToRemote)
+			require.Equal(t, ok, actualOK, "revert mismatch, isSendToRemote %t", isSendToRemote)
+			if ok {
+				require.Zero(t, expected.Cmp(actual), "expected %s, got %s", expected, actual)
+			}
+		}
+	})
+}
+
+func FuzzDeriveTokenMultiplierValues(f *testing.F) {
+	harness := newTokenScalingUtilsHarness(f)
+
+	f.Add(uint8(18), uint8(6))
+	f.Add(uint8(6), uint8(18))
+	f.Add(uint8(9), uint8(9))
+	f.Add(uint8(0), uint8(maxFuzzedDecimals))
+
+	f.Fuzz(func(t *testing.T, homeTokenDecimals uint8, remoteTokenDecimals uint8) {
+		homeTokenDecimals %= maxFuzzedDecimals + 1
+		remoteTokenDecimals %= maxFuzzedDecimals + 1
+
+		results, err := harness.call("deriveTokenMultiplierValues", homeTokenDecimals, remoteTokenDecimals)
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		multiplyOnRemote := remoteTokenDecimals > homeTokenDecimals
+		decimalsShift := homeTokenDecimals - remoteTokenDecimals
+		if multiplyOnRemote {
+			decimalsShift = remoteTokenDecimals - homeTokenDecimals
+		}
+		expected := GetTokenMultiplier(decimalsShift)
+		require.Zero(t, expected.Cmp(results[0].(*big.Int)), "expected %s, got %s", expected, results[0])
+		require.Equal(t, multiplyOnRemote, results[1].(bool))
+	})
+}
+
+func FuzzCalculateCollateralNeeded(f *testing.F) {
+	harness := newTokenScalingUtilsHarness(f)
+
+	f.Add(uint8(2), true, big.NewInt(1000).Bytes())
+	f.Add(uint8(2), true, big.NewInt(1001).Bytes())
+	f.Add(uint8(12), false, big.NewInt(1e18).Bytes())
+	f.Add(uint8(0), false, []byte{})
+	f.Add(uint8(maxFuzzedDecimals), false, maxUint256.Bytes())
+
+	f.Fuzz(func(t *testing.T, decimalsShift uint8, multiplyOnRemote bool, initialReserveImbalanceBytes []byte) {
+		tokenMultiplier := GetTokenMultiplier(decimalsShift % (maxFuzzedDecimals + 1))
+		initialReserveImbalance := uint256FromBytes(initialReserveImbalanceBytes)
+
+		results, err := harness.call(
+			"calculateCollateralNeeded",
+			tokenMultiplier,
+			multiplyOnRemote,
+			initialReserveImbalance,
+		)
+		// Only removing the scale of an imbalance that is multiplied can revert
+		_, ok := expectedScale(tokenMultiplier, multiplyOnRemote, initialReserveImbalance, false)
+		if !ok {
+			require.Error(t, err)
+			return
+		}
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+
+		expected := CalculateCollateralNeeded(initialReserveImbalance, tokenMultiplier, multiplyOnRemote)
+		require.Zero(t, expected.Cmp(results[0].(*big.Int)), "expected %s, got %s", expected, results[0])
+
+		// The collateral always covers the full imbalance
+		if covered, ok := expectedScale(tokenMultiplier, multiplyOnRemote, expected, true); ok {
+			require.GreaterOrEqual(t, covered.Cmp(initialReserveImbalance), 0)
+		}
+	})
+}
+
+// FuzzRoundTrip searches for sequences of transfers to and from a TokenRemote instance that
+// release more home tokens than were locked. Each operation is encoded by 9 bytes of ops: the first byte
+// selects between a send from the home and a send back from the remote, and the next 8 bytes are its amount.
+// The amount of the first send from the home is given separately, so that it spans the uint256 range.
+func FuzzRoundTrip(f *testing.F) {
+	harness := newTokenScalingUtilsHarness(f)
+
+	f.Add(uint8(18), uint8(6), big.NewInt(1e18+1).Bytes(), []byte{1, 0, 0, 0, 0, 0, 0, 0, 7})
+	f.Add(uint8(6), uint8(18), big.NewInt(1_000_001).Bytes(), []byte{1, 0, 0, 0, 0, 0, 0, 0x10, 0})
+	f.Add(uint8(0), uint8(18), []byte{1}, []byte{0, 0, 0, 0, 0, 0, 0, 0, 3, 1, 0xff, 0xff, 0, 0, 0, 0, 0, 0})
+	f.Add(uint8(18), uint8(0), maxUint256.Bytes(), []byte{1, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, homeTokenDecimals uint8, remoteTokenDecimals uint8, amountBytes []byte, ops []byte) {
+		// Only decimals supported by the token transferrers can be registered
+		homeTokenDecimals %= maxTokenDecimals + 1
+		remoteTokenDecimals %= maxTokenDecimals + 1
+		results, err := harness.call("deriveTokenMultiplierValues", homeTokenDecimals, remoteTokenDecimals)
+		require.NoError(t, err)
+		tokenMultiplier, multiplyOnRemote := results[0].(*big.Int), results[1].(bool)
+
+		var (
+			// Home tokens locked in the TokenHome
+			locked = big.NewInt(0)
+			// Remote tokens minted and not yet burned, which is the transferred balance of the remote
+			outstanding = big.NewInt(0)
+		)
+		sendFromHome := func(amount *big.Int) {
+			scaled, ok := harness.scale(t, tokenMultiplier, multiplyOnRemote, amount, true)
+			if !ok || scaled.Sign() == 0 || new(big.Int).Add(locked, amount).Cmp(maxUint256) > 0 {
+				// The send reverts on the home
+				return
+			}
+			locked.Add(locked, amount)
+			outstanding.Add(outstanding, scaled)
+		}
+		sendFromRemote := func(amount *big.Int) {
+			released, ok := harness.scale(t, tokenMultiplier, multiplyOnRemote, amount, false)
+			require.True(t, ok, "removing the scale of %s reverted", amount)
+			if released.Sign() == 0 {
+				// The send reverts on the remote
+				return
+			}
+			outstanding.Sub(outstanding, amount)
+			require.LessOrEqual(
+				t,
+				released.Cmp(locked),
+				0,
+				"released %s home tokens with %s locked", released, locked,
+			)
+			locked.Sub(locked, released)
+		}
+
+		sendFromHome(uint256FromBytes(amountBytes))
+		for ; len(ops) >= 9; ops = ops[9:] {
+			amount := new(big.Int).SetUint64(binary.BigEndian.Uint64(ops[1:9]))
+			if ops[0]%2 == 0 {
+				sendFromHome(amount)
+				continue
+			}
+			if outstanding.Sign() == 0 {
+				continue
+			}
+			// Send back at most the outstanding remote tokens
+			sendFromRemote(amount.Add(amount.Mod(amount, outstanding), big.NewInt(1)))
+		}
+
+		// Every outstanding remote token can still be sent back
+		if outstanding.Sign() != 0 {
+			sendFromRemote(new(big.Int).Set(outstanding))
+		}
+	})
+}
@@ -0,0 +1,181 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables that configure the subnets of the local E2E test network.
+// E2ETopologyFileEnvVar can not be combined with the other variables.
+const (
+	E2ETopologyFileEnvVar   = "E2E_TOPOLOGY_FILE"
+	E2ESubnetCountEnvVar    = "E2E_SUBNET_COUNT"
+	E2ENodesPerSubnetEnvVar = "E2E_NODES_PER_SUBNET"
+	E2EEVMChainIDsEnvVar    = "E2E_EVM_CHAIN_IDS"
+)
+
+const (
+	// Flows use at least two subnets, see teleporterUtils.GetTwoSubnets.
+	minTopologySubnets = 2
+	// Subnets are named by a single letter.
+	maxTopologySubnets = 26
+
+	// The EVM chain ID of the C-Chain of a local network
+	localCChainEVMChainID = 43112
+	// EVM chain IDs of subnets after the default ones are generated from this base
+	generatedEVMChainIDBase = 60000
+)
+
+// SubnetTopology describes a subnet of the local E2E test network.
+type SubnetTopology struct {
+	Name       string `json:"name"`
+	EVMChainID uint64 `json:"evmChainID"`
+	NodeCount  int    `json:"nodeCount"`
+}
+
+// Topology is the set of subnets created for the local E2E test network, in addition to the primary network.
+type Topology struct {
+	Subnets []SubnetTopology `json:"subnets"`
+}
+
+// DefaultTopology returns two subnets A and B with one node each.
+func DefaultTopology() Topology {
+	return Topology{
+		Subnets: []SubnetTopology{
+			{Name: "A", EVMChainID: 12345, NodeCount: 1},
+			{Name: "B", EVMChainID: 54321, NodeCount: 1},
+		},
+	}
+}
+
+// LoadTopology returns the topology read from the file named by E2E_TOPOLOGY_FILE if it is set.
+// Otherwise the default topology is adjusted by E2E_SUBNET_COUNT, E2E_NODES_PER_SUBNET and
+// E2E_EVM_CHAIN_IDS, a comma separated list of EVM chain IDs, one per subnet.
+func LoadTopology() (Topology, error) {
+	if file := os.Getenv(E2ETopologyFileEnvVar); file != "" {
+		for _, envVar := range []string{E2ESubnetCountEnvVar, E2ENodesPerSubnetEnvVar, E2EEVMChainIDsEnvVar} {
+			if os.Getenv(envVar) != "" {
+				return Topology{}, fmt.Errorf("%s can not be set together with %s", envVar, E2ETopologyFileEnvVar)
+			}
+		}
+		return ReadTopologyFile(file)
+	}
+
+	topology := DefaultTopology()
+
+	var chainIDs []uint64
+	if value := os.Getenv(E2EEVMChainIDsEnvVar); value != "" {
+		for _, field := range strings.Split(value, ",") {
+			chainID, err := strconv.ParseUint(strings.TrimSpace(field), 10, 64)
+			if err != nil {
+				return Topology{}, fmt.Errorf("invalid %s: %w", E2EEVMChainIDsEnvVar, err)
+			}
+			chainIDs = append(chainIDs, chainID)
+		}
+	}
+
+	subnetCount := len(topology.Subnets)
+	if chainIDs != nil {
+		subnetCount = len(chainIDs)
+	}
+	if value := os.Getenv(E2ESubnetCountEnvVar); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return Topology{}, fmt.Errorf("invalid %s: %w", E2ESubnetCountEnvVar, err)
+		}
+		if chainIDs != nil && count != len(chainIDs) {
+			return Topology{}, fmt.Errorf(
+				"%s is %d, but %s has %d chain IDs",
+				E2ESubnetCountEnvVar, count, E2EEVMChainIDsEnvVar, len(chainIDs),
+			)
+		}
+		subnetCount = count
+	}
+	if subnetCount < minTopologySubnets || subnetCount > maxTopologySubnets {
+		return Topology{}, fmt.Errorf(
+			"subnet count must be between %d and %d, got %d",
+			minTopologySubnets, maxTopologySubnets, subnetCount,
+		)
+	}
+
+	nodeCount := topology.Subnets[0].NodeCount
+	if value := os.Getenv(E2ENodesPerSubnetEnvVar); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return Topology{}, fmt.Errorf("invalid %s: %w", E2ENodesPerSubnetEnvVar, err)
+		}
+		nodeCount = count
+	}
+
+	subnets := make([]SubnetTopology, subnetCount)
+	for i := range subnets {
+		subnets[i] = SubnetTopology{
+			Name:       string(rune('A' + i)),
+			EVMChainID: generatedEVMChainIDBase + uint64(i),
+			NodeCount:  nodeCount,
+		}
+		if i < len(topology.Subnets) {
+			subnets[i].EVMChainID = topology.Subnets[i].EVMChainID
+		}
+		if chainIDs != nil {
+			subnets[i].EVMChainID = chainIDs[i]
+		}
+	}
+	topology.Subnets = subnets
+
+	return topology, topology.Validate()
+}
+
+// ReadTopologyFile reads and validates a JSON encoded Topology.
+func ReadTopologyFile(file string) (Topology, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Topology{}, err
+	}
+	var topology Topology
+	if err := json.Unmarshal(data, &topology); err != nil {
+		return Topology{}, fmt.Errorf("failed to parse topology file %s: %w", file, err)
+	}
+	return topology, topology.Validate()
+}
+
+// Validate checks that the topology has enough subnets for the E2E flows, that every subnet has
+// at least one node, and that subnet names and EVM chain IDs are unique.
+func (t Topology) Validate() error {
+	if len(t.Subnets) < minTopologySubnets || len(t.Subnets) > maxTopologySubnets {
+		return fmt.Errorf(
+			"topology must have between %d and %d subnets, got %d",
+			minTopologySubnets, maxTopologySubnets, len(t.Subnets),
+		)
+	}
+	names := make(map[string]struct{})
+	chainIDs := map[uint64]struct{}{localCChainEVMChainID: {}}
+	for _, subnet := range t.Subnets {
+		if subnet.Name == "" {
+			return fmt.Errorf("subnet with EVM chain ID %d has no name", subnet.EVMChainID)
+		}
+		if _, ok := names[subnet.Name]; ok {
+			return fmt.Errorf("duplicate subnet name %s", subnet.Name)
+		}
+		names[subnet.Name] = struct{}{}
+
+		if subnet.EVMChainID == 0 {
+			return fmt.Errorf("subnet %s has no EVM chain ID", subnet.Name)
+		}
+		if _, ok := chainIDs[subnet.EVMChainID]; ok {
+			return fmt.Errorf("subnet %s has duplicate EVM chain ID %d", subnet.Name, subnet.EVMChainID)
+		}
+		chainIDs[subnet.EVMChainID] = struct{}{}
+
+		if subnet.NodeCount < 1 {
+			return fmt.Errorf("subnet %s must have at least one node, got %d", subnet.Name, subnet.NodeCount)
+		}
+	}
+	return nil
+}
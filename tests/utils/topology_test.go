@@ -0,0 +1,147 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTopologyFromEnv(t *testing.T) {
+	testCases := []struct {
+		name        string
+		env         map[string]string
+		expected    []SubnetTopology
+		expectedErr string
+	}{
+		{
+			name:     "default",
+			expected: DefaultTopology().Subnets,
+		},
+		{
+			name: "subnet count and nodes per subnet",
+			env: map[string]string{
+				E2ESubnetCountEnvVar:    "3",
+				E2ENodesPerSubnetEnvVar: "2",
+			},
+			expected: []SubnetTopology{
+				{Name: "A", EVMChainID: 12345, NodeCount: 2},
+				{Name: "B", EVMChainID: 54321, NodeCount: 2},
+				{Name: "C", EVMChainID: 60002, NodeCount: 2},
+			},
+		},
+		{
+			name: "chain IDs set the subnet count",
+			env: map[string]string{
+				E2EEVMChainIDsEnvVar: "111, 222, 333, 444",
+			},
+			expected: []SubnetTopology{
+				{Name: "A", EVMChainID: 111, NodeCount: 1},
+				{Name: "B", EVMChainID: 222, NodeCount: 1},
+				{Name: "C", EVMChainID: 333, NodeCount: 1},
+				{Name: "D", EVMChainID: 444, NodeCount: 1},
+			},
+		},
+		{
+			name: "mismatched subnet count and chain IDs",
+			env: map[string]string{
+				E2ESubnetCountEnvVar: "3",
+				E2EEVMChainIDsEnvVar: "111,222",
+			},
+			expectedErr: "has 2 chain IDs",
+		},
+		{
+			name:        "too few subnets",
+			env:         map[string]string{E2ESubnetCountEnvVar: "1"},
+			expectedErr: "subnet count must be between 2 and 26",
+		},
+		{
+			name:        "too many subnets",
+			env:         map[string]string{E2ESubnetCountEnvVar: "27"},
+			expectedErr: "subnet count must be between 2 and 26",
+		},
+		{
+			name:        "no nodes",
+			env:         map[string]string{E2ENodesPerSubnetEnvVar: "0"},
+			expectedErr: "must have at least one node",
+		},
+		{
+			name:        "duplicate chain IDs",
+			env:         map[string]string{E2EEVMChainIDsEnvVar: "111,111"},
+			expectedErr: "duplicate EVM chain ID 111",
+		},
+		{
+			name:        "C-Chain chain ID",
+			env:         map[string]string{E2EEVMChainIDsEnvVar: "111,43112"},
+			expectedErr: "duplicate EVM chain ID 43112",
+		},
+		{
+			name:        "invalid chain ID",
+			env:         map[string]string{E2EEVMChainIDsEnvVar: "111,abc"},
+			expectedErr: "invalid " + E2EEVMChainIDsEnvVar,
+		},
+		{
+			name: "file combined with other variables",
+			env: map[string]string{
+				E2ETopologyFileEnvVar: "topology.json",
+				E2ESubnetCountEnvVar:  "3",
+			},
+			expectedErr: "can not be set together with " + E2ETopologyFileEnvVar,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, envVar := range []string{
+				E2ETopologyFileEnvVar,
+				E2ESubnetCountEnvVar,
+				E2ENodesPerSubnetEnvVar,
+				E2EEVMChainIDsEnvVar,
+			} {
+				t.Setenv(envVar, tc.env[envVar])
+			}
+
+			topology, err := LoadTopology()
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, topology.Subnets)
+		})
+	}
+}
+
+func TestLoadTopologyFromFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "topology.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{
+		"subnets": [
+			{"name": "X", "evmChainID": 1001, "nodeCount": 3},
+			{"name": "Y", "evmChainID": 1002, "nodeCount": 1}
+		]
+	}`), 0o600))
+	t.Setenv(E2ETopologyFileEnvVar, file)
+	t.Setenv(E2ESubnetCountEnvVar, "")
+	t.Setenv(E2ENodesPerSubnetEnvVar, "")
+	t.Setenv(E2EEVMChainIDsEnvVar, "")
+
+	topology, err := LoadTopology()
+	require.NoError(t, err)
+	require.Equal(t, []SubnetTopology{
+		{Name: "X", EVMChainID: 1001, NodeCount: 3},
+		{Name: "Y", EVMChainID: 1002, NodeCount: 1},
+	}, topology.Subnets)
+
+	require.NoError(t, os.WriteFile(file, []byte(`{
+		"subnets": [
+			{"name": "X", "evmChainID": 1001, "nodeCount": 1},
+			{"name": "X", "evmChainID": 1002, "nodeCount": 1}
+		]
+	}`), 0o600))
+	_, err = LoadTopology()
+	require.ErrorContains(t, err, "duplicate subnet name X")
+}
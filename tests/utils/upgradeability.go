@@ -0,0 +1,307 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	proxyadmin "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/ProxyAdmin"
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	nativetokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemote"
+	nativetokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemoteUpgradeable"
+	tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/TokenRemote"
+	transparentupgradeableproxy "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TransparentUpgradeableProxy"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ava-labs/subnet-evm/rpc"
+	"github.com/ava-labs/teleporter/tests/interfaces"
+	teleporterUtils "github.com/ava-labs/teleporter/tests/utils"
+	"github.com/ethereum/go-ethereum/common"
+
+	. "github.com/onsi/gomega"
+)
+
+// EIP-1967 storage slot holding the implementation address of a proxy.
+// bytes32(uint256(keccak256("eip1967.proxy.implementation")) - 1)
+var ProxyImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+
+// Passed to the constructor of the upgradeable logic contracts so that they cannot be initialized directly.
+const ICTTInitializableDisallowed = uint8(1)
+
+// RemoteTokenTransferrer identifies a TokenRemote instance registered with a TokenHome.
+type RemoteTokenTransferrer struct {
+	BlockchainID ids.ID
+	Address      common.Address
+}
+
+// ContractSnapshot maps the name of each getter, including its arguments, to its formatted return value.
+// Snapshots taken before and after an upgrade are compared to check that the upgrade did not modify state.
+type ContractSnapshot map[string]string
+
+// Diff returns a description of every getter whose value differs between s and other, sorted by getter name.
+func (s ContractSnapshot) Diff(other ContractSnapshot) []string {
+	var diffs []string
+	for key, value := range s {
+		otherValue, ok := other[key]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: %s -> <missing>", key, value))
+		} else if otherValue != value {
+			diffs = append(diffs, fmt.Sprintf("%s: %s -> %s", key, value, otherValue))
+		}
+	}
+	for key, otherValue := range other {
+		if _, ok := s[key]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: <missing> -> %s", key, otherValue))
+		}
+	}
+	sort.Strings(diffs)
+	return diffs
+}
+
+// ExpectSnapshotUnchanged fails the test with the full diff if any getter changed between the two snapshots.
+func ExpectSnapshotUnchanged(before ContractSnapshot, after ContractSnapshot) {
+	Expect(before.Diff(after)).Should(BeEmpty())
+}
+
+// SnapshotTokenHome captures the state of the TokenHome instance at the given address,
+// including the settings and transferred balances of each of the given remotes.
+func SnapshotTokenHome(
+	subnet interfaces.SubnetTestInfo,
+	tokenHomeAddress common.Address,
+	remotes []RemoteTokenTransferrer,
+) ContractSnapshot {
+	tokenHome, err := tokenhome.NewTokenHome(tokenHomeAddress, subnet.RPCClient)
+	Expect(err).Should(BeNil())
+	opts := &bind.CallOpts{}
+	snapshot := ContractSnapshot{}
+
+	owner, err := tokenHome.Owner(opts)
+	Expect(err).Should(BeNil())
+	snapshot["owner"] = owner.Hex()
+
+	blockchainID, err := tokenHome.GetBlockchainID(opts)
+	Expect(err).Should(BeNil())
+	snapshot["getBlockchainID"] = ids.ID(blockchainID).String()
+
+	minTeleporterVersion, err := tokenHome.GetMinTeleporterVersion(opts)
+	Expect(err).Should(BeNil())
+	snapshot["getMinTeleporterVersion"] = minTeleporterVersion.String()
+
+	tokenAddress, err := tokenHome.GetTokenAddress(opts)
+	Expect(err).Should(BeNil())
+	snapshot["getTokenAddress"] = tokenAddress.Hex()
+
+	for _, remote := range remotes {
+		args := fmt.Sprintf("(%s,%s)", remote.BlockchainID, remote.Address.Hex())
+
+		settings, err := tokenHome.GetRemoteTokenTransferrerSettings(opts, remote.BlockchainID, remote.Address)
+		Expect(err).Should(BeNil())
+		snapshot["getRemoteTokenTransferrerSettings"+args+".registered"] = fmt.Sprint(settings.Registered)
+		snapshot["getRemoteTokenTransferrerSettings"+args+".collateralNeeded"] = settings.CollateralNeeded.String()
+		snapshot["getRemoteTokenTransferrerSettings"+args+".tokenMultiplier"] = settings.TokenMultiplier.String()
+		snapshot["getRemoteTokenTransferrerSettings"+args+".multiplyOnRemote"] = fmt.Sprint(settings.MultiplyOnRemote)
+
+		transferredBalance, err := tokenHome.GetTransferredBalance(opts, remote.BlockchainID, remote.Address)
+		Expect(err).Should(BeNil())
+		snapshot["getTransferredBalance"+args] = transferredBalance.String()
+	}
+
+	return snapshot
+}
+
+// SnapshotTokenRemote captures the state of the ERC20TokenRemote or NativeTokenRemote instance
+// at the given address, including the token balances of each of the given accounts.
+// If isNative is set, the NativeTokenRemote specific getters are included as well.
+func SnapshotTokenRemote(
+	subnet interfaces.SubnetTestInfo,
+	tokenRemoteAddress common.Address,
+	isNative bool,
+	accounts []common.Address,
+) ContractSnapshot {
+	tokenRemote, err := tokenremote.NewTokenRemote(tokenRemoteAddress, subnet.RPCClient)
+	Expect(err).Should(BeNil())
+	// Both ERC20TokenRemote and NativeTokenRemote implement ERC20
+	token, err := erc20tokenremote.NewERC20TokenRemote(tokenRemoteAddress, subnet.RPCClient)
+	Expect(err).Should(BeNil())
+	opts := &bind.CallOpts{}
+	snapshot := ContractSnapshot{}
+
+	owner, err := tokenRemote.Owner(opts)
+	Expect(err).Should(BeNil())
+	snapshot["owner"] = owner.Hex()
+
+	blockchainID, err := tokenRemote.GetBlockchainID(opts)
+	Expect(err).Should(BeNil())
+	snapshot["getBlockchainID"] = ids.ID(blockchainID).String()
+
+	minTeleporterVersion, err := tokenRemote.GetMinTeleporterVersion(opts)
+	Expect(err).Should(BeNil())
+	snapshot["getMinTeleporterVersion"] = minTeleporterVersion.String()
+
+	tokenHomeBlockchainID, err := tokenRemote.GetTokenHomeBlockchainID(opts)
+	Expect(err).Should(BeNil())
+	snapshot["getTokenHomeBlockchainID"] = ids.ID(tokenHomeBlockchainID).String()
+
+	tokenHomeAddress, err := tokenRemote.GetTokenHomeAddress(opts)
+	Expect(err).Should(BeNil())
+	snapshot["getTokenHomeAddress"] = tokenHomeAddress.Hex()
+
+	initialReserveImbalance, err := tokenRemote.GetInitialReserveImbalance(opts)
+	Expect(err).Should(BeNil())
+	snapshot["getInitialReserveImbalance"] = initialReserveImbalance.String()
+
+	isCollateralized, err := tokenRemote.GetIsCollateralized(opts)
+	Expect(err).Should(BeNil())
+	snapshot["getIsCollateralized"] = fmt.Sprint(isCollateralized)
+
+	tokenMultiplier, err := tokenRemote.GetTokenMultiplier(opts)
+	Expect(err).Should(BeNil())
+	snapshot["getTokenMultiplier"] = tokenMultiplier.String()
+
+	multiplyOnRemote, err := tokenRemote.GetMultiplyOnRemote(opts)
+	Expect(err).Should(BeNil())
+	snapshot["getMultiplyOnRemote"] = fmt.Sprint(multiplyOnRemote)
+
+	name, err := token.Name(opts)
+	Expect(err).Should(BeNil())
+	snapshot["name"] = name
+
+	symbol, err := token.Symbol(opts)
+	Expect(err).Should(BeNil())
+	snapshot["symbol"] = symbol
+
+	decimals, err := token.Decimals(opts)
+	Expect(err).Should(BeNil())
+	snapshot["decimals"] = fmt.Sprint(decimals)
+
+	totalSupply, err := token.TotalSupply(opts)
+	Expect(err).Should(BeNil())
+	snapshot["totalSupply"] = totalSupply.String()
+
+	for _, account := range accounts {
+		balance, err := token.BalanceOf(opts, account)
+		Expect(err).Should(BeNil())
+		snapshot[fmt.Sprintf("balanceOf(%s)", account.Hex())] = balance.String()
+	}
+
+	if isNative {
+		nativeTokenRemote, err := nativetokenremote.NewNativeTokenRemote(tokenRemoteAddress, subnet.RPCClient)
+		Expect(err).Should(BeNil())
+
+		totalMinted, err := nativeTokenRemote.GetTotalMinted(opts)
+		Expect(err).Should(BeNil())
+		snapshot["getTotalMinted"] = totalMinted.String()
+
+		totalNativeAssetSupply, err := nativeTokenRemote.TotalNativeAssetSupply(opts)
+		Expect(err).Should(BeNil())
+		snapshot["totalNativeAssetSupply"] = totalNativeAssetSupply.String()
+	}
+
+	return snapshot
+}
+
+// GetProxyImplementation returns the implementation address stored in the EIP-1967 slot of the given proxy.
+func GetProxyImplementation(
+	ctx context.Context,
+	client ethclient.Client,
+	proxyAddress common.Address,
+) common.Address {
+	value, err := client.StorageAt(ctx, proxyAddress, ProxyImplementationSlot, nil)
+	Expect(err).Should(BeNil())
+	return common.BytesToAddress(value)
+}
+
+// UpgradeTransparentUpgradeableProxy upgrades the proxy to the new implementation without
+// calling it, and checks that the EIP-1967 implementation slot points to the new implementation.
+func UpgradeTransparentUpgradeableProxy(
+	ctx context.Context,
+	subnet interfaces.SubnetTestInfo,
+	proxyAdmin *proxyadmin.ProxyAdmin,
+	proxyAddress common.Address,
+	newImplAddress common.Address,
+	proxyAdminOwnerKey *ecdsa.PrivateKey,
+) {
+	opts, err := bind.NewKeyedTransactorWithChainID(proxyAdminOwnerKey, subnet.EVMChainID)
+	Expect(err).Should(BeNil())
+	tx, err := proxyAdmin.UpgradeAndCall(opts, proxyAddress, newImplAddress, []byte{})
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, subnet, tx.Hash())
+
+	Expect(GetProxyImplementation(ctx, subnet.RPCClient, proxyAddress)).Should(Equal(newImplAddress))
+}
+
+// DeployNativeTokenRemoteUpgradeable deploys a NativeTokenRemoteUpgradeable logic contract from senderKey,
+// and a TransparentUpgradeableProxy for it from the next NativeTokenRemote deployer key, so that the proxy
+// is an admin of the Native Minter precompile. The ProxyAdmin is owned by proxyAdminOwner.
+// The proxy is not initialized.
+func DeployNativeTokenRemoteUpgradeable(
+	ctx context.Context,
+	senderKey *ecdsa.PrivateKey,
+	subnet interfaces.SubnetTestInfo,
+	proxyAdminOwner common.Address,
+) (common.Address, common.Address, *proxyadmin.ProxyAdmin, *nativetokenremote.NativeTokenRemote) {
+	opts, err := bind.NewKeyedTransactorWithChainID(senderKey, subnet.EVMChainID)
+	Expect(err).Should(BeNil())
+	implAddress, tx, _, err := nativetokenremoteupgradeable.DeployNativeTokenRemoteUpgradeable(
+		opts,
+		subnet.RPCClient,
+		ICTTInitializableDisallowed,
+	)
+	Expect(err).Should(BeNil())
+	teleporterUtils.WaitForTransactionSuccess(ctx, subnet, tx.Hash())
+
+	// The proxy is the contract that mints native tokens, so it needs to be deployed
+	// with the nonce 0 of a deployer key that is set as a Native Minter admin in the genesis.
+	deployerOpts, err := bind.NewKeyedTransactorWithChainID(nextNativeTokenRemoteDeployerKey(), subnet.EVMChainID)
+	Expect(err).Should(BeNil())
+	proxyAddress, tx, proxy, err := transparentupgradeableproxy.DeployTransparentUpgradeableProxy(
+		deployerOpts,
+		subnet.RPCClient,
+		implAddress,
+		proxyAdminOwner,
+		[]byte{},
+	)
+	Expect(err).Should(BeNil())
+	receipt := teleporterUtils.WaitForTransactionSuccess(ctx, subnet, tx.Hash())
+	proxyAdminEvent, err := teleporterUtils.GetEventFromLogs(receipt.Logs, proxy.ParseAdminChanged)
+	Expect(err).Should(BeNil())
+
+	proxyAdmin, err := proxyadmin.NewProxyAdmin(proxyAdminEvent.NewAdmin, subnet.RPCClient)
+	Expect(err).Should(BeNil())
+
+	nativeTokenRemote, err := nativetokenremote.NewNativeTokenRemote(proxyAddress, subnet.RPCClient)
+	Expect(err).Should(BeNil())
+
+	return proxyAddress, implAddress, proxyAdmin, nativeTokenRemote
+}
+
+// ExpectReinitializationFails checks that calling the initializer through the proxy reverts with the
+// InvalidInitialization error of OpenZeppelin's Initializable, rather than failing for any other reason.
+func ExpectReinitializationFails(
+	initialize func(opts *bind.TransactOpts) error,
+	senderKey *ecdsa.PrivateKey,
+	chainID *big.Int,
+) {
+	opts, err := bind.NewKeyedTransactorWithChainID(senderKey, chainID)
+	Expect(err).Should(BeNil())
+	err = initialize(opts)
+	Expect(err).ShouldNot(BeNil())
+
+	// The initializer reverts when the transaction's gas is estimated, with the revert data in the error
+	var dataErr rpc.DataError
+	Expect(errors.As(err, &dataErr)).Should(BeTrue(), "expected a revert, got: %s", err)
+	revertData, ok := dataErr.ErrorData().(string)
+	Expect(ok).Should(BeTrue(), "expected revert data, got: %v", dataErr.ErrorData())
+	parsed, err := nativetokenremoteupgradeable.NativeTokenRemoteUpgradeableMetaData.GetAbi()
+	Expect(err).Should(BeNil())
+	invalidInitialization := parsed.Errors["InvalidInitialization"]
+	Expect(common.FromHex(revertData)).Should(Equal(invalidInitialization.ID[:4]))
+}
@@ -6,8 +6,13 @@ package utils
 import (
 	"context"
 	"crypto/ecdsa"
-	"math"
+	"errors"
+	"fmt"
+	"io/fs"
 	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
 
 	proxyadmin "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/ProxyAdmin"
 	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
@@ -21,6 +26,8 @@ import (
 	exampleerc20 "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/mocks/ExampleERC20Decimals"
 	mockERC20SACR "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/mocks/MockERC20SendAndCallReceiver"
 	mockNSACR "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/mocks/MockNativeSendAndCallReceiver"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/tests/diagnostics"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/amounts"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
 	"github.com/ava-labs/subnet-evm/core/types"
@@ -63,14 +70,61 @@ var nativeTokenRemoteDeployerKeys = []string{
 	// Deployer address:			   0xd466f12795BA59d0fef389c21fA63c287956fb18
 	// NativeTokenRemote address: 0x463a6bE7a5098A5f06435c6c468adD338F15B93A
 	"ebb7f0cf71e0b6fd880326e5f5061b8456b0aef81901566cbe578b5024852ec9",
+	// Deployer address:			   0x03B604D673F75eaB8E1dD9960DD49273b4bEaB85
+	// NativeTokenRemote address: 0xA3d3ae79fAddEfe8FAf8C0823c87475F229C9c2A
+	"9cd477ebcd038fc689cb48909007c82667b575c58f86e1c35b9b354f3748506b",
+	// Deployer address:			   0xB6804bCCB9A10D06a0a6B4950c673e8b22b51308
+	// NativeTokenRemote address: 0x46c682B7A0E7C7D3B752715A5Fb8e7CC0cF1CCc0
+	"6f7b9c101b9797fd747275ece769fdcd9b89a0b2198658c234a7393b10a14c60",
+	// Deployer address:			   0x80e78CB3d61DEa0Ec094cA4D000B2adA4923FfDA
+	// NativeTokenRemote address: 0xE197437b483b474EF20919aE4A60F36356cE3218
+	"fab342a8bce919b36b90a5da16e403745cc08ccf97fffb1af01ad33ceff7581f",
+	// Deployer address:			   0x74A0C6C36dB34cA07Eb50E329F26003a6Fef50c3
+	// NativeTokenRemote address: 0x340E8A997bB3109fAF8EBb7800C72e4834098C7E
+	"baaf9eb5e000083b57e29ffd75a7a4cedc0593de2e4d66f314b3f390afe8bb85",
+	// Deployer address:			   0x3acD2810e9c7B13b447F6df4f00E66730150c63A
+	// NativeTokenRemote address: 0x6b93cc80E9eDd060A0fca6a63A90ccb040C7660D
+	"c2042bce84b068e51c8fb017edcb4a5d32c5948e46667cd766b89d3283b8733a",
+	// Deployer address:			   0x76A194Fb40D18618E0137f741D7c3C02E3b1e48D
+	// NativeTokenRemote address: 0x62723B808153Db8Ac2E1ebA3D60687E725CD2555
+	"a9b95206a029f50abfb668db2104b771bab47f840e59ec3f820beb5c9e2a6c48",
+	// Deployer address:			   0x5F4f56F7DB84e3AC62Ae8e4A0376C72aaC25E8A7
+	// NativeTokenRemote address: 0xf0B31C792a1C47c15d391d51fe0861FF0262200C
+	"5dcd954a815ff7ed2255c0c82f44effb88651eb0816f7cc309af5c7f08280038",
+	// Deployer address:			   0xddb054EA7fc1215b6D3E298D82b8f924613E33A3
+	// NativeTokenRemote address: 0x31c234704e26D43f90F59BC7903c9E2fb1012d8C
+	"1ad622140621ea8408b89cc96e44ef2a912eb813c531c9101ab84218e89823f4",
 }
 
+// Owner of the NativeTokenRemote proxy deployed through the CREATE2 factory with the salt of
+// DeterministicNativeTokenRemoteLabel. The address of the proxy only depends on its owner and salt,
+// so it is set as an admin for the Native Minter precompile in the genesis file.
+// Owner address:             0xb1b7441457736F0486a730c081F803964C2C0499
+// NativeTokenRemote address: 0x124cB1aA0A0D926516a9a9D4EC77D8fbA2fBbaE8
+const deterministicNativeTokenRemoteOwnerKey = "17402e65bb575a441c1c4bd571bd7e27e94bc9de975f359d9c558e13ec49045d"
+
+// DeterministicNativeTokenRemoteLabel is the label of the salt of the NativeTokenRemote proxy deployed through
+// the CREATE2 factory.
+const DeterministicNativeTokenRemoteLabel = "NativeTokenRemote"
+
 var (
-	nativeTokenRemoteDeployerKeyIndex   = 0
+	// Guards the allocation of NativeTokenRemote deployer keys to the specs of a process
+	nativeTokenRemoteDeployerKeyLock  sync.Mutex
+	nativeTokenRemoteDeployerKeyIndex = 0
+	// Directory shared by the processes of a parallel run, in which each deployer key is claimed before use
+	nativeTokenRemoteDeployerKeyClaimDir string
+
 	ExpectedExampleERC20DeployerBalance = new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e10))
 )
 
-const NativeTokenDecimals = 18
+const NativeTokenDecimals = amounts.NativeTokenDecimals
+
+// ParseAmount returns the on-chain value of a decimal amount, such as "13.25", of a token with the given decimals.
+func ParseAmount(amount string, decimals uint8) *big.Int {
+	value, err := amounts.Token{Decimals: decimals}.Parse(amount)
+	Expect(err).Should(BeNil())
+	return value
+}
 
 func DeployERC20TokenHome(
 	ctx context.Context,
@@ -150,10 +204,7 @@ func DeployNativeTokenRemote(
 ) (common.Address, *nativetokenremote.NativeTokenRemote) {
 	// The NativeTokenRemote needs a unique deployer key, whose nonce 0 is used to deploy the contract.
 	// The resulting contract address has been added to the genesis file as an admin for the Native Minter precompile.
-	Expect(nativeTokenRemoteDeployerKeyIndex).Should(BeNumerically("<", len(nativeTokenRemoteDeployerKeys)))
-	deployerKeyStr := nativeTokenRemoteDeployerKeys[nativeTokenRemoteDeployerKeyIndex]
-	deployerPK, err := crypto.HexToECDSA(deployerKeyStr)
-	Expect(err).Should(BeNil())
+	deployerPK := nextNativeTokenRemoteDeployerKey()
 
 	opts, err := bind.NewKeyedTransactorWithChainID(
 		deployerPK,
@@ -178,10 +229,63 @@ func DeployNativeTokenRemote(
 	Expect(err).Should(BeNil())
 	teleporterUtils.WaitForTransactionSuccess(ctx, subnet, tx.Hash())
 
+	return implAddress, nativeTokenRemote
+}
+
+// DeterministicNativeTokenRemoteOwnerKey returns the key of the owner of the NativeTokenRemote proxy deployed
+// through the CREATE2 factory.
+func DeterministicNativeTokenRemoteOwnerKey() *ecdsa.PrivateKey {
+	key, err := crypto.HexToECDSA(deterministicNativeTokenRemoteOwnerKey)
+	Expect(err).Should(BeNil())
+	return key
+}
+
+// nextNativeTokenRemoteDeployerKey returns the next unused NativeTokenRemote deployer key.
+// Each key may only be used once, since the Native Minter admin address is derived from its nonce 0.
+func nextNativeTokenRemoteDeployerKey() *ecdsa.PrivateKey {
+	nativeTokenRemoteDeployerKeyLock.Lock()
+	defer nativeTokenRemoteDeployerKeyLock.Unlock()
+
+	// Skip the keys claimed by other processes
+	for nativeTokenRemoteDeployerKeyIndex < len(nativeTokenRemoteDeployerKeys) &&
+		!claimNativeTokenRemoteDeployerKey(nativeTokenRemoteDeployerKeyIndex) {
+		nativeTokenRemoteDeployerKeyIndex++
+	}
+	Expect(nativeTokenRemoteDeployerKeyIndex).Should(BeNumerically("<", len(nativeTokenRemoteDeployerKeys)))
+	deployerPK, err := crypto.HexToECDSA(nativeTokenRemoteDeployerKeys[nativeTokenRemoteDeployerKeyIndex])
+	Expect(err).Should(BeNil())
+
 	// Increment to the next deployer key so that the next contract deployment succeeds
 	nativeTokenRemoteDeployerKeyIndex++
 
-	return implAddress, nativeTokenRemote
+	return deployerPK
+}
+
+// SetNativeTokenRemoteDeployerKeyClaimDir makes the NativeTokenRemote deployer keys be claimed
+// with a file in dir before use, so that the processes of a parallel run sharing dir never use the same key.
+func SetNativeTokenRemoteDeployerKeyClaimDir(dir string) {
+	nativeTokenRemoteDeployerKeyLock.Lock()
+	defer nativeTokenRemoteDeployerKeyLock.Unlock()
+	nativeTokenRemoteDeployerKeyClaimDir = dir
+}
+
+// claimNativeTokenRemoteDeployerKey returns false if the key was already claimed by another process.
+func claimNativeTokenRemoteDeployerKey(index int) bool {
+	if nativeTokenRemoteDeployerKeyClaimDir == "" {
+		return true
+	}
+	// Exclusively creating the claim file fails if it already exists
+	claimFile, err := os.OpenFile(
+		filepath.Join(nativeTokenRemoteDeployerKeyClaimDir, fmt.Sprintf("deployer-key-%d", index)),
+		os.O_CREATE|os.O_EXCL|os.O_WRONLY,
+		0o600,
+	)
+	if errors.Is(err, fs.ErrExist) {
+		return false
+	}
+	Expect(err).Should(BeNil())
+	Expect(claimFile.Close()).Should(BeNil())
+	return true
 }
 
 func DeployNativeTokenHome(
@@ -404,13 +508,7 @@ func RegisterTokenRemoteOnHome(
 
 	// Relay the register message to the home
 	receipt = network.RelayMessage(ctx, receipt, remoteSubnet, homeSubnet, true)
-	_, err = teleporterUtils.GetEventFromLogs(
-		receipt.Logs,
-		homeSubnet.TeleporterMessenger.ParseMessageExecuted,
-	)
-	if err != nil {
-		teleporterUtils.TraceTransactionAndExit(ctx, homeSubnet.RPCClient, receipt.TxHash)
-	}
+	diagnostics.ExpectMessageExecuted(ctx, homeSubnet, receipt)
 
 	// Check that the remote registered event was emitted
 	tokenHome, err := tokenhome.NewTokenHome(homeAddress, homeSubnet.RPCClient)
@@ -422,7 +520,7 @@ func RegisterTokenRemoteOnHome(
 
 	// Based on the initial reserve balance of the TokenRemote instance,
 	// calculate the collateral amount of home tokens needed to collateralize the remote.
-	collateralNeeded := calculateCollateralNeeded(
+	collateralNeeded := CalculateCollateralNeeded(
 		expectedInitialReserveBalance,
 		expectedTokenMultiplier,
 		expectedmultiplyOnRemote,
@@ -976,13 +1074,12 @@ func SendERC20TokenMultiHopAndVerify(
 		cChainInfo,
 		true,
 	)
-	_, err := teleporterUtils.GetEventFromLogs(
-		intermediateReceipt.Logs,
-		cChainInfo.TeleporterMessenger.ParseMessageExecuted,
+	diagnostics.ExpectMessageExecuted(
+		ctx,
+		cChainInfo,
+		intermediateReceipt,
+		diagnostics.Remote{BlockchainID: toSubnet.BlockchainID, Address: toTokenTransferrerAddress},
 	)
-	if err != nil {
-		teleporterUtils.TraceTransactionAndExit(ctx, cChainInfo.RPCClient, intermediateReceipt.TxHash)
-	}
 
 	initialBalance, err := toTokenTransferrer.BalanceOf(&bind.CallOpts{}, recipientAddress)
 	Expect(err).Should(BeNil())
@@ -997,10 +1094,7 @@ func SendERC20TokenMultiHopAndVerify(
 		toSubnet,
 		true,
 	)
-	_, err = teleporterUtils.GetEventFromLogs(remoteReceipt.Logs, toSubnet.TeleporterMessenger.ParseMessageExecuted)
-	if err != nil {
-		teleporterUtils.TraceTransactionAndExit(ctx, toSubnet.RPCClient, remoteReceipt.TxHash)
-	}
+	diagnostics.ExpectMessageExecuted(ctx, toSubnet, remoteReceipt)
 
 	transferredAmount := big.NewInt(0).Sub(amount, input.SecondaryFee)
 	CheckERC20TokenRemoteWithdrawal(
@@ -1061,7 +1155,7 @@ func CheckNativeTokenHomeWithdrawal(
 func GetTokenMultiplier(
 	decimalsShift uint8,
 ) *big.Int {
-	return big.NewInt(int64(math.Pow10(int(decimalsShift))))
+	return big.NewInt(0).Exp(big.NewInt(10), big.NewInt(int64(decimalsShift)), nil)
 }
 
 type WrappedToken interface {
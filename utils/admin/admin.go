@@ -0,0 +1,187 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package admin executes the owner-only actions of token transferrers, and keeps an audit log of them.
+//
+// Every token transferrer inherits the Teleporter version management of the Teleporter registry apps: its
+// owner can raise the minimum Teleporter version it accepts messages from, pause and unpause Teleporter
+// addresses, and transfer its ownership. Execute checks that an action would change the state of the
+// contract before sending it, since the reason of a reverted transaction is not reported.
+package admin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	ErrNotOwner = errors.New("not the owner")
+	// ErrNoChange is returned for actions that would not change the contract, such as pausing an address
+	// that is already paused.
+	ErrNoChange = errors.New("action changes nothing")
+	ErrReverted = errors.New("transaction reverted")
+)
+
+// Backend is the subset of the RPC client of a chain needed to execute actions.
+type Backend interface {
+	bind.ContractBackend
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// ActionType is an owner-only function of the token transferrers.
+type ActionType string
+
+const (
+	ActionUpdateMinTeleporterVersion ActionType = "updateMinTeleporterVersion"
+	ActionPauseTeleporterAddress     ActionType = "pauseTeleporterAddress"
+	ActionUnpauseTeleporterAddress   ActionType = "unpauseTeleporterAddress"
+	ActionTransferOwnership          ActionType = "transferOwnership"
+)
+
+// Action is a call of an owner-only function, with the argument of its type.
+type Action struct {
+	Type ActionType
+	// The minimum Teleporter version of ActionUpdateMinTeleporterVersion
+	Version *big.Int
+	// The address of ActionPauseTeleporterAddress and ActionUnpauseTeleporterAddress
+	TeleporterAddress common.Address
+	// The owner of ActionTransferOwnership
+	NewOwner common.Address
+}
+
+func UpdateMinTeleporterVersion(version *big.Int) Action {
+	return Action{Type: ActionUpdateMinTeleporterVersion, Version: version}
+}
+
+func PauseTeleporterAddress(teleporterAddress common.Address) Action {
+	return Action{Type: ActionPauseTeleporterAddress, TeleporterAddress: teleporterAddress}
+}
+
+func UnpauseTeleporterAddress(teleporterAddress common.Address) Action {
+	return Action{Type: ActionUnpauseTeleporterAddress, TeleporterAddress: teleporterAddress}
+}
+
+func TransferOwnership(newOwner common.Address) Action {
+	return Action{Type: ActionTransferOwnership, NewOwner: newOwner}
+}
+
+func (a Action) String() string {
+	switch a.Type {
+	case ActionUpdateMinTeleporterVersion:
+		return fmt.Sprintf("%s(%s)", a.Type, a.Version)
+	case ActionTransferOwnership:
+		return fmt.Sprintf("%s(%s)", a.Type, a.NewOwner)
+	default:
+		return fmt.Sprintf("%s(%s)", a.Type, a.TeleporterAddress)
+	}
+}
+
+// Execute sends the action to the token transferrer at the address, signed with the key of its owner,
+// and returns the receipt of the accepted transaction.
+func Execute(
+	ctx context.Context,
+	backend Backend,
+	key *ecdsa.PrivateKey,
+	address common.Address,
+	action Action,
+) (*types.Receipt, error) {
+	// The owner-only functions are inherited by every token transferrer, so the TokenHome binding
+	// calls them on remotes too.
+	contract, err := tokenhome.NewTokenHome(address, backend)
+	if err != nil {
+		return nil, err
+	}
+	callOpts := &bind.CallOpts{Context: ctx}
+	owner, err := contract.Owner(callOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get owner of %s: %w", address, err)
+	}
+	if sender := crypto.PubkeyToAddress(key.PublicKey); owner != sender {
+		return nil, fmt.Errorf("%w: %s is owned by %s, not %s", ErrNotOwner, address, owner, sender)
+	}
+	if err := checkChange(contract, callOpts, owner, action); err != nil {
+		return nil, fmt.Errorf("%s on %s: %w", action, address, err)
+	}
+
+	chainID, err := backend.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	if err != nil {
+		return nil, err
+	}
+	opts.Context = ctx
+	var tx *types.Transaction
+	switch action.Type {
+	case ActionUpdateMinTeleporterVersion:
+		tx, err = contract.UpdateMinTeleporterVersion(opts, action.Version)
+	case ActionPauseTeleporterAddress:
+		tx, err = contract.PauseTeleporterAddress(opts, action.TeleporterAddress)
+	case ActionUnpauseTeleporterAddress:
+		tx, err = contract.UnpauseTeleporterAddress(opts, action.TeleporterAddress)
+	case ActionTransferOwnership:
+		tx, err = contract.TransferOwnership(opts, action.NewOwner)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s to %s: %w", action, address, err)
+	}
+	receipt, err := bind.WaitMined(ctx, backend, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for transaction %s: %w", tx.Hash(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return receipt, fmt.Errorf("%w: %s to %s in %s", ErrReverted, action, address, tx.Hash())
+	}
+	return receipt, nil
+}
+
+// checkChange returns an error if the action is invalid, or would not change the state of the contract.
+func checkChange(contract *tokenhome.TokenHome, opts *bind.CallOpts, owner common.Address, action Action) error {
+	switch action.Type {
+	case ActionUpdateMinTeleporterVersion:
+		if action.Version == nil {
+			return errors.New("missing version")
+		}
+		current, err := contract.GetMinTeleporterVersion(opts)
+		if err != nil {
+			return fmt.Errorf("failed to get min Teleporter version: %w", err)
+		}
+		// The minimum version can only be raised
+		if action.Version.Cmp(current) <= 0 {
+			return fmt.Errorf("%w: min Teleporter version is already %s", ErrNoChange, current)
+		}
+	case ActionPauseTeleporterAddress, ActionUnpauseTeleporterAddress:
+		if action.TeleporterAddress == (common.Address{}) {
+			return errors.New("missing Teleporter address")
+		}
+		paused, err := contract.IsTeleporterAddressPaused(opts, action.TeleporterAddress)
+		if err != nil {
+			return fmt.Errorf("failed to get pause status: %w", err)
+		}
+		if paused == (action.Type == ActionPauseTeleporterAddress) {
+			return fmt.Errorf("%w: paused is already %t", ErrNoChange, paused)
+		}
+	case ActionTransferOwnership:
+		// Renouncing ownership is not an action, as it would leave the contract without an owner
+		if action.NewOwner == (common.Address{}) {
+			return errors.New("missing new owner")
+		}
+		if action.NewOwner == owner {
+			return fmt.Errorf("%w: already owned by %s", ErrNoChange, owner)
+		}
+	default:
+		return fmt.Errorf("unknown action %q", action.Type)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package admin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/interfaces"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// EventType is an event emitted by the owner-only functions of the token transferrers.
+type EventType string
+
+const (
+	EventOwnershipTransferred        EventType = "OwnershipTransferred"
+	EventTeleporterAddressPaused     EventType = "TeleporterAddressPaused"
+	EventTeleporterAddressUnpaused   EventType = "TeleporterAddressUnpaused"
+	EventMinTeleporterVersionUpdated EventType = "MinTeleporterVersionUpdated"
+)
+
+// EventTypes are the audited events.
+var EventTypes = []EventType{
+	EventOwnershipTransferred,
+	EventTeleporterAddressPaused,
+	EventTeleporterAddressUnpaused,
+	EventMinTeleporterVersionUpdated,
+}
+
+var (
+	tokenHomeABI      = mustGetABI(tokenhome.TokenHomeMetaData)
+	tokenHomeFilterer = mustNewFilterer()
+)
+
+// AuditBackend is the subset of the RPC client of a chain needed to audit its token transferrers.
+type AuditBackend interface {
+	bind.ContractFilterer
+	BlockNumber(ctx context.Context) (uint64, error)
+}
+
+// Entry is an audited event of a token transferrer. Only the fields of its event are set.
+type Entry struct {
+	BlockchainID ids.ID         `json:"blockchainID"`
+	Contract     common.Address `json:"contract"`
+	BlockNumber  uint64         `json:"blockNumber"`
+	TxHash       common.Hash    `json:"txHash"`
+	LogIndex     uint           `json:"logIndex"`
+	Event        EventType      `json:"event"`
+
+	PreviousOwner           *common.Address `json:"previousOwner,omitempty"`
+	NewOwner                *common.Address `json:"newOwner,omitempty"`
+	TeleporterAddress       *common.Address `json:"teleporterAddress,omitempty"`
+	OldMinTeleporterVersion *big.Int        `json:"oldMinTeleporterVersion,omitempty"`
+	NewMinTeleporterVersion *big.Int        `json:"newMinTeleporterVersion,omitempty"`
+}
+
+// Collect returns the audited events of the token transferrer at the address, emitted from the block
+// fromBlock to the block toBlock included, in the order they were emitted.
+func Collect(
+	ctx context.Context,
+	backend bind.ContractFilterer,
+	blockchainID ids.ID,
+	address common.Address,
+	fromBlock uint64,
+	toBlock uint64,
+) ([]Entry, error) {
+	topics := make([]common.Hash, 0, len(EventTypes))
+	for _, e := range EventTypes {
+		topics = append(topics, tokenHomeABI.Events[string(e)].ID)
+	}
+	logs, err := backend.FilterLogs(ctx, interfaces.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+		Addresses: []common.Address{address},
+		Topics:    [][]common.Hash{topics},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events of %s: %w", address, err)
+	}
+	entries := make([]Entry, 0, len(logs))
+	for _, l := range logs {
+		entry, err := decodeLog(blockchainID, l)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// decodeLog returns the entry of the log of an audited event.
+func decodeLog(blockchainID ids.ID, l types.Log) (Entry, error) {
+	entry := Entry{
+		BlockchainID: blockchainID,
+		Contract:     l.Address,
+		BlockNumber:  l.BlockNumber,
+		TxHash:       l.TxHash,
+		LogIndex:     l.Index,
+	}
+	event, err := tokenHomeABI.EventByID(l.Topics[0])
+	if err != nil {
+		return Entry{}, fmt.Errorf("unknown event in %s: %w", l.TxHash, err)
+	}
+	entry.Event = EventType(event.Name)
+	switch entry.Event {
+	case EventOwnershipTransferred:
+		e, err := tokenHomeFilterer.ParseOwnershipTransferred(l)
+		if err != nil {
+			return Entry{}, err
+		}
+		entry.PreviousOwner = &e.PreviousOwner
+		entry.NewOwner = &e.NewOwner
+	case EventTeleporterAddressPaused:
+		e, err := tokenHomeFilterer.ParseTeleporterAddressPaused(l)
+		if err != nil {
+			return Entry{}, err
+		}
+		entry.TeleporterAddress = &e.TeleporterAddress
+	case EventTeleporterAddressUnpaused:
+		e, err := tokenHomeFilterer.ParseTeleporterAddressUnpaused(l)
+		if err != nil {
+			return Entry{}, err
+		}
+		entry.TeleporterAddress = &e.TeleporterAddress
+	case EventMinTeleporterVersionUpdated:
+		e, err := tokenHomeFilterer.ParseMinTeleporterVersionUpdated(l)
+		if err != nil {
+			return Entry{}, err
+		}
+		entry.OldMinTeleporterVersion = e.OldMinTeleporterVersion
+		entry.NewMinTeleporterVersion = e.NewMinTeleporterVersion
+	default:
+		return Entry{}, fmt.Errorf("unexpected event %s in %s", event.Name, l.TxHash)
+	}
+	return entry, nil
+}
+
+// AuditLog is an append-only log of the audited events of token transferrers. The events of each token
+// transferrer are appended to a file of JSON lines of their own in the directory of the log, and are
+// never rewritten.
+type AuditLog struct {
+	Dir string
+
+	lock sync.Mutex
+}
+
+// Entries returns the entries of the token transferrer, in the order they were emitted.
+func (a *AuditLog) Entries(blockchainID ids.ID, address common.Address) ([]Entry, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.read(blockchainID, address)
+}
+
+// Sync appends the events emitted by the token transferrer since the last entry of its log, or since
+// fromBlock if it has none, up to the block confirmations blocks below the latest block. It returns the
+// appended entries.
+func (a *AuditLog) Sync(
+	ctx context.Context,
+	backend AuditBackend,
+	blockchainID ids.ID,
+	address common.Address,
+	fromBlock uint64,
+	confirmations uint64,
+) ([]Entry, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	entries, err := a.read(blockchainID, address)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 0 {
+		// Every event of the block of the last entry was appended along with it
+		fromBlock = entries[len(entries)-1].BlockNumber + 1
+	}
+	latest, err := backend.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if latest < confirmations || latest-confirmations < fromBlock {
+		return nil, nil
+	}
+	appended, err := Collect(ctx, backend, blockchainID, address, fromBlock, latest-confirmations)
+	if err != nil || len(appended) == 0 {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(a.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(a.path(blockchainID, address), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	for _, entry := range appended {
+		if err := encoder.Encode(entry); err != nil {
+			return nil, err
+		}
+	}
+	return appended, file.Sync()
+}
+
+func (a *AuditLog) read(blockchainID ids.ID, address common.Address) ([]Entry, error) {
+	file, err := os.Open(a.path(blockchainID, address))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse audit log of %s: %w", address, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// path returns the file of the log of the token transferrer.
+func (a *AuditLog) path(blockchainID ids.ID, address common.Address) string {
+	return filepath.Join(a.Dir, fmt.Sprintf("%s-%s.jsonl", blockchainID, address.Hex()))
+}
+
+func mustGetABI(metadata *bind.MetaData) *abi.ABI {
+	parsed, err := metadata.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// mustNewFilterer returns a filterer that only decodes logs, so it is not bound to a contract.
+func mustNewFilterer() *tokenhome.TokenHomeFilterer {
+	filterer, err := tokenhome.NewTokenHomeFilterer(common.Address{}, nil)
+	if err != nil {
+		panic(err)
+	}
+	return filterer
+}
@@ -0,0 +1,143 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package admin
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/interfaces"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testContract   = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testOwner      = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testNewOwner   = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	testTeleporter = common.HexToAddress("0x4444444444444444444444444444444444444444")
+	testChain      = ids.ID{1}
+)
+
+// testBackend returns its logs within the block range of the query.
+type testBackend struct {
+	logs        []types.Log
+	blockNumber uint64
+}
+
+func (b *testBackend) FilterLogs(_ context.Context, query interfaces.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	for _, l := range b.logs {
+		if l.BlockNumber >= query.FromBlock.Uint64() && l.BlockNumber <= query.ToBlock.Uint64() {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+func (*testBackend) SubscribeFilterLogs(
+	context.Context,
+	interfaces.FilterQuery,
+	chan<- types.Log,
+) (interfaces.Subscription, error) {
+	return event.NewSubscription(func(<-chan struct{}) error { return nil }), nil
+}
+
+func (b *testBackend) BlockNumber(context.Context) (uint64, error) {
+	return b.blockNumber, nil
+}
+
+// newTestLog returns the log of an audited event, whose arguments are all indexed.
+func newTestLog(e EventType, blockNumber uint64, arguments ...common.Hash) types.Log {
+	return types.Log{
+		Address:     testContract,
+		Topics:      append([]common.Hash{tokenHomeABI.Events[string(e)].ID}, arguments...),
+		BlockNumber: blockNumber,
+		TxHash:      common.Hash{byte(blockNumber)},
+	}
+}
+
+func newTestBackend() *testBackend {
+	return &testBackend{
+		logs: []types.Log{
+			newTestLog(EventOwnershipTransferred, 1, common.Hash{}, common.BytesToHash(testOwner.Bytes())),
+			newTestLog(EventMinTeleporterVersionUpdated, 1, common.BigToHash(big.NewInt(1)),
+				common.BigToHash(big.NewInt(2))),
+			newTestLog(EventTeleporterAddressPaused, 5, common.BytesToHash(testTeleporter.Bytes())),
+			newTestLog(EventTeleporterAddressUnpaused, 7, common.BytesToHash(testTeleporter.Bytes())),
+			newTestLog(EventOwnershipTransferred, 9, common.BytesToHash(testOwner.Bytes()),
+				common.BytesToHash(testNewOwner.Bytes())),
+		},
+		blockNumber: 6,
+	}
+}
+
+func TestCollect(t *testing.T) {
+	entries, err := Collect(context.Background(), newTestBackend(), testChain, testContract, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+
+	zero := common.Address{}
+	require.Equal(t, Entry{
+		BlockchainID:  testChain,
+		Contract:      testContract,
+		BlockNumber:   1,
+		TxHash:        common.Hash{1},
+		Event:         EventOwnershipTransferred,
+		PreviousOwner: &zero,
+		NewOwner:      &testOwner,
+	}, entries[0])
+	require.Equal(t, EventMinTeleporterVersionUpdated, entries[1].Event)
+	require.Equal(t, big.NewInt(1), entries[1].OldMinTeleporterVersion)
+	require.Equal(t, big.NewInt(2), entries[1].NewMinTeleporterVersion)
+	require.Equal(t, EventTeleporterAddressPaused, entries[2].Event)
+	require.Equal(t, &testTeleporter, entries[2].TeleporterAddress)
+	require.Equal(t, EventTeleporterAddressUnpaused, entries[3].Event)
+	require.Equal(t, &testNewOwner, entries[4].NewOwner)
+}
+
+func TestAuditLogSync(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestBackend()
+	log := &AuditLog{Dir: t.TempDir()}
+
+	// Only the events with a confirmation are appended
+	appended, err := log.Sync(ctx, backend, testChain, testContract, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, appended, 3)
+
+	// Nothing is appended again
+	appended, err = log.Sync(ctx, backend, testChain, testContract, 0, 1)
+	require.NoError(t, err)
+	require.Empty(t, appended)
+
+	backend.blockNumber = 10
+	appended, err = log.Sync(ctx, backend, testChain, testContract, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, appended, 2)
+	require.Equal(t, EventTeleporterAddressUnpaused, appended[0].Event)
+
+	entries, err := log.Entries(testChain, testContract)
+	require.NoError(t, err)
+	require.Len(t, entries, 5)
+	expected, err := Collect(ctx, backend, testChain, testContract, 0, 10)
+	require.NoError(t, err)
+	require.Equal(t, expected, entries)
+
+	// Other contracts have logs of their own
+	entries, err = log.Entries(testChain, testNewOwner)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestCheckChange(t *testing.T) {
+	require.ErrorContains(t, checkChange(nil, nil, testOwner, TransferOwnership(common.Address{})), "missing new owner")
+	require.ErrorIs(t, checkChange(nil, nil, testOwner, TransferOwnership(testOwner)), ErrNoChange)
+	require.NoError(t, checkChange(nil, nil, testOwner, TransferOwnership(testNewOwner)))
+	require.ErrorContains(t, checkChange(nil, nil, testOwner, Action{Type: "renounceOwnership"}), "unknown action")
+}
@@ -0,0 +1,215 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package amounts converts between human-readable token amounts, such as "13.25", and the on-chain
+// values of tokens with a given number of decimals, and between the units of a TokenHome and its remotes.
+package amounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	exampleerc20 "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/mocks/ExampleERC20Decimals"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Decimals of the native token of every chain
+const NativeTokenDecimals = 18
+
+var (
+	ErrInvalidAmount       = errors.New("invalid amount")
+	ErrExcessPrecision     = errors.New("amount is more precise than the token supports")
+	ErrRemoteNotRegistered = errors.New("remote not registered")
+)
+
+// Token parses and formats the amounts of a token with the given number of decimals.
+type Token struct {
+	Decimals uint8
+}
+
+// NativeToken is the native token of any chain.
+var NativeToken = Token{Decimals: NativeTokenDecimals}
+
+// ERC20Token returns the Token of the ERC20 token at address, with the decimals returned by the token.
+func ERC20Token(ctx context.Context, caller bind.ContractCaller, address common.Address) (Token, error) {
+	token, err := exampleerc20.NewExampleERC20DecimalsCaller(address, caller)
+	if err != nil {
+		return Token{}, err
+	}
+	decimals, err := token.Decimals(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to get decimals of %s: %w", address, err)
+	}
+	return Token{Decimals: decimals}, nil
+}
+
+// ERC20TokenRemote returns the Token of the ERC20TokenRemote at address.
+func ERC20TokenRemote(ctx context.Context, caller bind.ContractCaller, address common.Address) (Token, error) {
+	remote, err := erc20tokenremote.NewERC20TokenRemoteCaller(address, caller)
+	if err != nil {
+		return Token{}, err
+	}
+	decimals, err := remote.Decimals(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to get decimals of %s: %w", address, err)
+	}
+	return Token{Decimals: decimals}, nil
+}
+
+// Parse returns the on-chain value of a non-negative decimal amount of the token, such as "13.25".
+// Amounts with more decimal places than the token, apart from trailing zeros, are rejected.
+func (t Token) Parse(amount string) (*big.Int, error) {
+	whole, fraction, hasPoint := strings.Cut(amount, ".")
+	if (whole == "" && fraction == "") || (hasPoint && fraction == "") ||
+		!isDigits(whole) || !isDigits(fraction) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > int(t.Decimals) {
+		return nil, fmt.Errorf("%w: %q has %d decimal places, more than the %d of the token",
+			ErrExcessPrecision, amount, len(fraction), t.Decimals)
+	}
+
+	digits := whole + fraction + strings.Repeat("0", int(t.Decimals)-len(fraction))
+	value, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	return value, nil
+}
+
+// Format returns the on-chain value as a decimal amount of the token, without trailing zeros.
+// A nil value is formatted as 0.
+func (t Token) Format(value *big.Int) string {
+	if value == nil {
+		return "0"
+	}
+	digits := new(big.Int).Abs(value).String()
+	sign := ""
+	if value.Sign() < 0 {
+		sign = "-"
+	}
+
+	decimals := int(t.Decimals)
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Route converts amounts between the units of a TokenHome and one of its remotes,
+// as the token transferrers scale them.
+type Route struct {
+	TokenMultiplier  *big.Int
+	MultiplyOnRemote bool
+}
+
+// NewRoute returns the Route between a home and a remote token with the given decimals.
+func NewRoute(homeDecimals uint8, remoteDecimals uint8) Route {
+	multiplyOnRemote := remoteDecimals > homeDecimals
+	decimalsShift := homeDecimals - remoteDecimals
+	if multiplyOnRemote {
+		decimalsShift = remoteDecimals - homeDecimals
+	}
+	return Route{
+		TokenMultiplier:  new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimalsShift)), nil),
+		MultiplyOnRemote: multiplyOnRemote,
+	}
+}
+
+// HomeRoute returns the Route of the remote as registered with the TokenHome at homeAddress.
+func HomeRoute(
+	ctx context.Context,
+	caller bind.ContractCaller,
+	homeAddress common.Address,
+	remoteBlockchainID ids.ID,
+	remoteAddress common.Address,
+) (Route, error) {
+	home, err := tokenhome.NewTokenHomeCaller(homeAddress, caller)
+	if err != nil {
+		return Route{}, err
+	}
+	settings, err := home.GetRemoteTokenTransferrerSettings(
+		&bind.CallOpts{Context: ctx},
+		remoteBlockchainID,
+		remoteAddress,
+	)
+	if err != nil {
+		return Route{}, fmt.Errorf("failed to get settings of remote %s on %s: %w", remoteAddress, remoteBlockchainID, err)
+	}
+	if !settings.Registered {
+		return Route{}, fmt.Errorf(
+			"%w: %s on %s is not registered with %s",
+			ErrRemoteNotRegistered, remoteAddress, remoteBlockchainID, homeAddress,
+		)
+	}
+	return Route{
+		TokenMultiplier:  settings.TokenMultiplier,
+		MultiplyOnRemote: settings.MultiplyOnRemote,
+	}, nil
+}
+
+// ToRemote returns the amount of remote tokens received for homeAmount.
+// Amounts that the remote cannot represent exactly are rejected, since the token transferrers
+// would drop the excess as dust.
+func (r Route) ToRemote(homeAmount *big.Int) (*big.Int, error) {
+	return r.scale(homeAmount, true)
+}
+
+// ToHome returns the amount of home tokens received for remoteAmount.
+// Amounts that the home cannot represent exactly are rejected.
+func (r Route) ToHome(remoteAmount *big.Int) (*big.Int, error) {
+	return r.scale(remoteAmount, false)
+}
+
+// HomeValue returns the amount of home tokens that remoteAmount is worth, rounded down as by the
+// token transferrers when the remote tokens are sent back to the home.
+func (r Route) HomeValue(remoteAmount *big.Int) *big.Int {
+	if r.MultiplyOnRemote {
+		return new(big.Int).Quo(remoteAmount, r.TokenMultiplier)
+	}
+	return new(big.Int).Mul(remoteAmount, r.TokenMultiplier)
+}
+
+// RemoteValue returns the amount of remote tokens that homeAmount is worth, rounded down as by the
+// token transferrers when the home tokens are sent to the remote.
+func (r Route) RemoteValue(homeAmount *big.Int) *big.Int {
+	if r.MultiplyOnRemote {
+		return new(big.Int).Mul(homeAmount, r.TokenMultiplier)
+	}
+	return new(big.Int).Quo(homeAmount, r.TokenMultiplier)
+}
+
+func (r Route) scale(amount *big.Int, isSendToRemote bool) (*big.Int, error) {
+	// Multiply when multiplyOnRemote and isSendToRemote are
+	// both true or both false.
+	if r.MultiplyOnRemote == isSendToRemote {
+		return new(big.Int).Mul(amount, r.TokenMultiplier), nil
+	}
+	quotient, remainder := new(big.Int).QuoRem(amount, r.TokenMultiplier, new(big.Int))
+	if remainder.Sign() != 0 {
+		return nil, fmt.Errorf("%w: %s is not a multiple of the token multiplier %s",
+			ErrExcessPrecision, amount, r.TokenMultiplier)
+	}
+	return quotient, nil
+}
@@ -0,0 +1,114 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package amounts
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name        string
+		decimals    uint8
+		amount      string
+		expected    string
+		expectedErr error
+	}{
+		{name: "whole", decimals: 18, amount: "13", expected: "13000000000000000000"},
+		{name: "fraction", decimals: 18, amount: "13.25", expected: "13250000000000000000"},
+		{name: "no whole part", decimals: 6, amount: ".5", expected: "500000"},
+		{name: "smallest unit", decimals: 6, amount: "0.000001", expected: "1"},
+		{name: "trailing zeros", decimals: 2, amount: "1.2500", expected: "125"},
+		{name: "no decimals", decimals: 0, amount: "42", expected: "42"},
+		{name: "zero", decimals: 18, amount: "0", expected: "0"},
+		{name: "excess precision", decimals: 6, amount: "0.0000001", expectedErr: ErrExcessPrecision},
+		{name: "fraction without decimals", decimals: 0, amount: "1.5", expectedErr: ErrExcessPrecision},
+		{name: "empty", decimals: 18, amount: "", expectedErr: ErrInvalidAmount},
+		{name: "point only", decimals: 18, amount: ".", expectedErr: ErrInvalidAmount},
+		{name: "trailing point", decimals: 18, amount: "1.", expectedErr: ErrInvalidAmount},
+		{name: "negative", decimals: 18, amount: "-1", expectedErr: ErrInvalidAmount},
+		{name: "exponent", decimals: 18, amount: "1e18", expectedErr: ErrInvalidAmount},
+		{name: "two points", decimals: 18, amount: "1.2.3", expectedErr: ErrInvalidAmount},
+		{name: "separator", decimals: 18, amount: "1,000", expectedErr: ErrInvalidAmount},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := Token{Decimals: tc.decimals}.Parse(tc.amount)
+			if tc.expectedErr != nil {
+				require.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, value.String())
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		decimals uint8
+		value    int64
+		expected string
+	}{
+		{decimals: 18, value: 0, expected: "0"},
+		{decimals: 6, value: 13_250_000, expected: "13.25"},
+		{decimals: 6, value: 1, expected: "0.000001"},
+		{decimals: 6, value: 1_000_000, expected: "1"},
+		{decimals: 6, value: -1_500_000, expected: "-1.5"},
+		{decimals: 0, value: 42, expected: "42"},
+	}
+
+	for _, tc := range testCases {
+		formatted := Token{Decimals: tc.decimals}.Format(big.NewInt(tc.value))
+		require.Equal(t, tc.expected, formatted)
+
+		// Formatted amounts parse back to the same value
+		if tc.value >= 0 {
+			value, err := Token{Decimals: tc.decimals}.Parse(formatted)
+			require.NoError(t, err)
+			require.Equal(t, tc.value, value.Int64())
+		}
+	}
+	require.Equal(t, "0", Token{Decimals: 18}.Format(nil))
+}
+
+func TestRoute(t *testing.T) {
+	// 18 decimal home, 6 decimal remote
+	route := NewRoute(18, 6)
+	require.Equal(t, big.NewInt(1e12), route.TokenMultiplier)
+	require.False(t, route.MultiplyOnRemote)
+
+	remoteAmount, err := route.ToRemote(big.NewInt(3e12))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(3), remoteAmount)
+	homeAmount, err := route.ToHome(remoteAmount)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(3e12), homeAmount)
+	_, err = route.ToRemote(big.NewInt(3e12 + 1))
+	require.ErrorIs(t, err, ErrExcessPrecision)
+	require.Equal(t, big.NewInt(3), route.RemoteValue(big.NewInt(3e12+1)))
+
+	// 6 decimal home, 18 decimal remote
+	route = NewRoute(6, 18)
+	require.Equal(t, big.NewInt(1e12), route.TokenMultiplier)
+	require.True(t, route.MultiplyOnRemote)
+	remoteAmount, err = route.ToRemote(big.NewInt(3))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(3e12), remoteAmount)
+	_, err = route.ToHome(big.NewInt(3e12 + 1))
+	require.ErrorIs(t, err, ErrExcessPrecision)
+	require.Equal(t, big.NewInt(3), route.HomeValue(big.NewInt(3e12+1)))
+	require.Equal(t, big.NewInt(3e12), route.RemoteValue(big.NewInt(3)))
+
+	// Same decimals
+	route = NewRoute(18, 18)
+	require.Equal(t, big.NewInt(1), route.TokenMultiplier)
+	remoteAmount, err = route.ToRemote(big.NewInt(7))
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(7), remoteAmount)
+}
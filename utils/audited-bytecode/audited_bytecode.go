@@ -0,0 +1,122 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package auditedbytecode verifies that deployed contracts match the runtime bytecode of an audited release.
+// The audited releases are those of the contract artifact registry in utils/contract-artifacts that are
+// marked as audited.
+package auditedbytecode
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Range is a byte range of runtime bytecode.
+type Range struct {
+	Start  int `json:"start"`
+	Length int `json:"length"`
+}
+
+// Contract is the runtime bytecode of a contract in a release.
+// Immutable values are set in the constructor, so their ranges are zeroed in the compiler output.
+type Contract struct {
+	RuntimeBytecode     hexutil.Bytes `json:"runtimeBytecode"`
+	ImmutableReferences []Range       `json:"immutableReferences,omitempty"`
+}
+
+// Release is the set of contracts built from an audited release tag.
+type Release struct {
+	Tag       string              `json:"tag"`
+	Commit    string              `json:"commit"`
+	Contracts map[string]Contract `json:"contracts"`
+}
+
+// Match identifies the audited contract that a runtime bytecode matches.
+type Match struct {
+	Tag      string
+	Commit   string
+	Contract string
+}
+
+// String formats the match as <Contract>@<tag>.
+func (m Match) String() string {
+	return fmt.Sprintf("%s@%s", m.Contract, m.Tag)
+}
+
+// FindMatches returns every contract of the given releases whose runtime bytecode matches code,
+// ignoring the values of immutables and the metadata appended by the compiler.
+// More than one match is expected for contracts that did not change between releases, and for
+// contracts that only differ by their constructor, such as the upgradeable and non-upgradeable variants.
+func FindMatches(releases []Release, code []byte) []Match {
+	var matches []Match
+	for _, release := range releases {
+		for _, name := range sortedNames(release.Contracts) {
+			if Equal(release.Contracts[name], code) {
+				matches = append(matches, Match{
+					Tag:      release.Tag,
+					Commit:   release.Commit,
+					Contract: name,
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// Equal returns true if code is the runtime bytecode of the contract, after masking
+// the contract's immutable references and the compiler metadata of both.
+func Equal(contract Contract, code []byte) bool {
+	if len(contract.RuntimeBytecode) != len(code) {
+		return false
+	}
+	return bytes.Equal(
+		mask(contract.RuntimeBytecode, contract.ImmutableReferences),
+		mask(code, contract.ImmutableReferences),
+	)
+}
+
+// mask returns a copy of code with the given ranges and the trailing CBOR encoded metadata zeroed.
+func mask(code []byte, immutableReferences []Range) []byte {
+	masked := make([]byte, len(code))
+	copy(masked, code)
+	for _, r := range immutableReferences {
+		if r.Start < 0 || r.Start+r.Length > len(masked) {
+			continue
+		}
+		clear(masked[r.Start : r.Start+r.Length])
+	}
+	if metadataLength := MetadataLength(masked); metadataLength > 0 {
+		clear(masked[len(masked)-metadataLength:])
+	}
+	return masked
+}
+
+// MetadataLength returns the number of trailing bytes of code taken up by the CBOR encoded
+// metadata that solc appends to the runtime bytecode, including its 2 byte length suffix.
+// Returns 0 if code does not end with metadata.
+func MetadataLength(code []byte) int {
+	if len(code) < 2 {
+		return 0
+	}
+	length := int(code[len(code)-2])<<8 | int(code[len(code)-1])
+	if length == 0 || length+2 > len(code) {
+		return 0
+	}
+	// The metadata is a CBOR map, encoded with a major type 5 initial byte
+	if code[len(code)-2-length]&0xe0 != 0xa0 {
+		return 0
+	}
+	return length + 2
+}
+
+func sortedNames(contracts map[string]Contract) []string {
+	names := make([]string, 0, len(contracts))
+	for name := range contracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
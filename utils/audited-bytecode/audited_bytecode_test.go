@@ -0,0 +1,280 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package auditedbytecode
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/interfaces"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// PUSH32 <immutable>, STOP, followed by the metadata {"solc": 0.8.25} and its length
+	runtimeCodeHex = "0x60806040527f" +
+		"0000000000000000000000000000000000000000000000000000000000000000" +
+		"00" + "a164736f6c6343000819" + "000a"
+	immutableStart = 6
+)
+
+func testRelease() Release {
+	return Release{
+		Tag:    "v1.0.0",
+		Commit: "0123abcd",
+		Contracts: map[string]Contract{
+			"Home": {
+				RuntimeBytecode:     common.FromHex(runtimeCodeHex),
+				ImmutableReferences: []Range{{Start: immutableStart, Length: 32}},
+			},
+		},
+	}
+}
+
+// deployedCode returns the release's runtime code with the immutable set and a different metadata hash.
+func deployedCode(t *testing.T) []byte {
+	code := common.FromHex(runtimeCodeHex)
+	code[immutableStart+31] = 0x01
+	code[len(code)-3] = 0x1a
+	return code
+}
+
+func TestMetadataLength(t *testing.T) {
+	require.Equal(t, 12, MetadataLength(common.FromHex(runtimeCodeHex)))
+	require.Equal(t, 0, MetadataLength(common.FromHex("0x6080604052600080fd")))
+	require.Equal(t, 0, MetadataLength(nil))
+}
+
+func TestFindMatches(t *testing.T) {
+	releases := []Release{testRelease()}
+
+	testCases := []struct {
+		name    string
+		code    func() []byte
+		matches []Match
+	}{
+		{
+			name:    "identical",
+			code:    func() []byte { return common.FromHex(runtimeCodeHex) },
+			matches: []Match{{Tag: "v1.0.0", Commit: "0123abcd", Contract: "Home"}},
+		},
+		{
+			name:    "immutable and metadata differ",
+			code:    func() []byte { return deployedCode(t) },
+			matches: []Match{{Tag: "v1.0.0", Commit: "0123abcd", Contract: "Home"}},
+		},
+		{
+			name: "code differs",
+			code: func() []byte {
+				code := deployedCode(t)
+				code[1] = 0x81
+				return code
+			},
+		},
+		{
+			name: "length differs",
+			code: func() []byte { return append(deployedCode(t), 0x00) },
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(t, testCase.matches, FindMatches(releases, testCase.code()))
+		})
+	}
+}
+
+// fakeBackend serves code, storage and logs from maps. All calls revert.
+type fakeBackend struct {
+	code    map[common.Address][]byte
+	storage map[common.Address]map[common.Hash]common.Hash
+	logs    []types.Log
+}
+
+func (b *fakeBackend) CodeAt(_ context.Context, contract common.Address, _ *big.Int) ([]byte, error) {
+	return b.code[contract], nil
+}
+
+func (b *fakeBackend) StorageAt(_ context.Context, account common.Address, key common.Hash, _ *big.Int) ([]byte, error) {
+	return b.storage[account][key].Bytes(), nil
+}
+
+func (*fakeBackend) CallContract(context.Context, interfaces.CallMsg, *big.Int) ([]byte, error) {
+	return nil, errors.New("execution reverted")
+}
+
+func (b *fakeBackend) FilterLogs(_ context.Context, query interfaces.FilterQuery) ([]types.Log, error) {
+	var logs []types.Log
+	for _, l := range b.logs {
+		if slices.Contains(query.Addresses, l.Address) {
+			logs = append(logs, l)
+		}
+	}
+	return logs, nil
+}
+
+func (*fakeBackend) SubscribeFilterLogs(
+	context.Context,
+	interfaces.FilterQuery,
+	chan<- types.Log,
+) (interfaces.Subscription, error) {
+	return event.NewSubscription(func(<-chan struct{}) error { return nil }), nil
+}
+
+func (*fakeBackend) BalanceAt(context.Context, common.Address, *big.Int) (*big.Int, error) {
+	return new(big.Int), nil
+}
+
+func (*fakeBackend) BlockNumber(context.Context) (uint64, error) {
+	return 0, nil
+}
+
+func (*fakeBackend) TransactionReceipt(context.Context, common.Hash) (*types.Receipt, error) {
+	return nil, errors.New("not found")
+}
+
+// remoteRegisteredLog returns the RemoteRegistered log of the remote emitted by the home.
+func remoteRegisteredLog(t *testing.T, home common.Address, blockchainID ids.ID, remote common.Address) types.Log {
+	parsed, err := tokenhome.TokenHomeMetaData.GetAbi()
+	require.NoError(t, err)
+	remoteRegistered := parsed.Events["RemoteRegistered"]
+	data, err := remoteRegistered.Inputs.NonIndexed().Pack(big.NewInt(0), uint8(18))
+	require.NoError(t, err)
+	return types.Log{
+		Address: home,
+		Topics:  []common.Hash{remoteRegistered.ID, common.Hash(blockchainID), common.BytesToHash(remote.Bytes())},
+		Data:    data,
+	}
+}
+
+func TestVerifyDeployment(t *testing.T) {
+	var (
+		audited        = common.HexToAddress("0x01")
+		unaudited      = common.HexToAddress("0x02")
+		proxy          = common.HexToAddress("0x03")
+		eoa            = common.HexToAddress("0x04")
+		proxyAdmin     = common.HexToAddress("0x05")
+		unauditedProxy = common.HexToAddress("0x06")
+	)
+	backend := &fakeBackend{
+		code: map[common.Address][]byte{
+			audited:        deployedCode(t),
+			unaudited:      common.FromHex("0x6080604052600080fd"),
+			proxy:          common.FromHex("0x6080604052"),
+			unauditedProxy: common.FromHex("0x6080604052"),
+		},
+		storage: map[common.Address]map[common.Hash]common.Hash{
+			proxy: {
+				inspect.ImplementationSlot: common.BytesToHash(audited.Bytes()),
+				inspect.AdminSlot:          common.BytesToHash(proxyAdmin.Bytes()),
+			},
+			unauditedProxy: {
+				inspect.ImplementationSlot: common.BytesToHash(unaudited.Bytes()),
+			},
+		},
+	}
+
+	results, err := VerifyDeployment(
+		context.Background(),
+		backend,
+		[]Release{testRelease()},
+		[]common.Address{audited, unaudited, proxy, unauditedProxy, eoa},
+	)
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	match := []Match{{Tag: "v1.0.0", Commit: "0123abcd", Contract: "Home"}}
+	require.Equal(t, Result{Address: audited, Kind: inspect.KindUnknown, Matches: match}, results[0])
+	require.True(t, results[0].Audited())
+	require.Equal(t, Result{Address: unaudited, Kind: inspect.KindUnknown}, results[1])
+	require.False(t, results[1].Audited())
+	require.Equal(t, Result{
+		Address:        proxy,
+		Kind:           inspect.KindUnknown,
+		Implementation: audited,
+		Matches:        match,
+	}, results[2])
+	require.Equal(t, Result{
+		Address:        unauditedProxy,
+		Kind:           inspect.KindUnknown,
+		Implementation: unaudited,
+	}, results[3])
+	require.Equal(t, Result{Address: eoa, Kind: inspect.KindNoCode}, results[4])
+	require.False(t, results[4].Audited())
+}
+
+func TestVerifyHome(t *testing.T) {
+	var (
+		homeChain        = ids.ID{1}
+		remoteChain      = ids.ID{2}
+		unreachableChain = ids.ID{3}
+
+		home              = common.HexToAddress("0x01")
+		remote            = common.HexToAddress("0x02")
+		remoteProxy       = common.HexToAddress("0x03")
+		unreachableRemote = common.HexToAddress("0x04")
+	)
+	homeBackend := &fakeBackend{
+		code: map[common.Address][]byte{home: deployedCode(t)},
+		logs: []types.Log{
+			remoteRegisteredLog(t, home, remoteChain, remoteProxy),
+			remoteRegisteredLog(t, home, unreachableChain, unreachableRemote),
+			// Logs of other contracts are ignored
+			remoteRegisteredLog(t, remote, remoteChain, remote),
+		},
+	}
+	remoteBackend := &fakeBackend{
+		code: map[common.Address][]byte{
+			remote:      common.FromHex("0x6080604052600080fd"),
+			remoteProxy: common.FromHex("0x6080604052"),
+		},
+		storage: map[common.Address]map[common.Hash]common.Hash{
+			remoteProxy: {inspect.ImplementationSlot: common.BytesToHash(remote.Bytes())},
+		},
+	}
+
+	deployment := Deployment{
+		HomeBlockchainID: homeChain,
+		HomeAddress:      home,
+		Chains: map[ids.ID]portfolio.Backend{
+			homeChain:   homeBackend,
+			remoteChain: remoteBackend,
+		},
+	}
+	results, err := VerifyHome(context.Background(), deployment, []Release{testRelease()})
+	require.NoError(t, err)
+	require.Equal(t, []Result{
+		{
+			BlockchainID: homeChain,
+			Address:      home,
+			Kind:         inspect.KindUnknown,
+			Matches:      []Match{{Tag: "v1.0.0", Commit: "0123abcd", Contract: "Home"}},
+		},
+		{
+			BlockchainID:   remoteChain,
+			Address:        remoteProxy,
+			Kind:           inspect.KindUnknown,
+			Implementation: remote,
+		},
+		{
+			BlockchainID: unreachableChain,
+			Address:      unreachableRemote,
+			Unreachable:  true,
+		},
+	}, results)
+
+	deployment.HomeBlockchainID = remoteChain
+	deployment.Chains = map[ids.ID]portfolio.Backend{homeChain: homeBackend}
+	_, err = VerifyHome(context.Background(), deployment, []Release{testRelease()})
+	require.ErrorContains(t, err, "missing backend of the home chain")
+}
@@ -0,0 +1,115 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package auditedbytecode
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Deployment is a TokenHome and the chains its remotes are verified on.
+type Deployment struct {
+	HomeBlockchainID ids.ID
+	HomeAddress      common.Address
+	// The backend of each chain, by blockchain ID. Remotes on other chains are reported as unreachable.
+	Chains map[ids.ID]portfolio.Backend
+}
+
+// Result is the verification status of a single deployed contract.
+type Result struct {
+	// Only set by VerifyHome
+	BlockchainID ids.ID
+	Address      common.Address
+	Kind         inspect.Kind
+	// For proxies, the implementation whose code was verified
+	Implementation common.Address
+	Matches        []Match
+	// True for remotes on chains without a backend, whose code was not verified
+	Unreachable bool
+}
+
+// Audited returns true if the verified code matches at least one contract of an audited release.
+func (r Result) Audited() bool {
+	return len(r.Matches) != 0
+}
+
+// VerifyDeployment checks the code at each address against the given releases.
+// Proxies are followed to their EIP-1967 implementation, whose code is verified instead,
+// since the proxy contracts themselves are not part of the audited contracts.
+func VerifyDeployment(
+	ctx context.Context,
+	backend inspect.Backend,
+	releases []Release,
+	addresses []common.Address,
+) ([]Result, error) {
+	results := make([]Result, 0, len(addresses))
+	for _, address := range addresses {
+		info, err := inspect.Inspect(ctx, backend, address)
+		if err != nil {
+			return nil, err
+		}
+		result := Result{
+			Address: address,
+			Kind:    info.Kind,
+		}
+
+		codeAddress := address
+		if info.IsProxy {
+			result.Implementation = info.Implementation
+			codeAddress = info.Implementation
+		}
+		code, err := backend.CodeAt(ctx, codeAddress, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(code) != 0 {
+			result.Matches = FindMatches(releases, code)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// VerifyHome checks the code of the TokenHome of the deployment and of every remote registered with it,
+// as discovered from the RemoteRegistered events of the home. The home is the first result, followed by
+// the remotes in the order of their registration.
+func VerifyHome(ctx context.Context, deployment Deployment, releases []Release) ([]Result, error) {
+	homeBackend, ok := deployment.Chains[deployment.HomeBlockchainID]
+	if !ok {
+		return nil, fmt.Errorf("missing backend of the home chain %s", deployment.HomeBlockchainID)
+	}
+	results, err := VerifyDeployment(ctx, homeBackend, releases, []common.Address{deployment.HomeAddress})
+	if err != nil {
+		return nil, err
+	}
+	results[0].BlockchainID = deployment.HomeBlockchainID
+
+	remotes, err := portfolio.DiscoverRemotes(ctx, homeBackend, deployment.HomeAddress)
+	if err != nil {
+		return nil, err
+	}
+	for _, remote := range remotes {
+		backend, ok := deployment.Chains[remote.BlockchainID]
+		if !ok {
+			results = append(results, Result{
+				BlockchainID: remote.BlockchainID,
+				Address:      remote.Address,
+				Unreachable:  true,
+			})
+			continue
+		}
+		remoteResults, err := VerifyDeployment(ctx, backend, releases, []common.Address{remote.Address})
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify remote %s on %s: %w", remote.Address, remote.BlockchainID, err)
+		}
+		remoteResults[0].BlockchainID = remote.BlockchainID
+		results = append(results, remoteResults[0])
+	}
+	return results, nil
+}
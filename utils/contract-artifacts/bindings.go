@@ -0,0 +1,130 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package contractartifacts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	auditedbytecode "github.com/ava-labs/avalanche-interchain-token-transfer/utils/audited-bytecode"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	storagelayout "github.com/ava-labs/avalanche-interchain-token-transfer/utils/storage-layout"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	opPush1  = 0x60
+	opPush32 = 0x7f
+)
+
+var (
+	errRuntimeNotFound = errors.New("runtime bytecode not found in creation bytecode")
+	// The "solc" key of the CBOR encoded metadata, followed by the header of its 3 byte version
+	solcMetadataKey = common.FromHex("0x64736f6c6343")
+)
+
+// ReleaseFromBindings builds a release from the Go bindings of the contracts, for releases whose forge build
+// output is not available. The bindings are generated by scripts/abi_bindings.sh from the forge build of the
+// same commit. The runtime bytecode is the section of the creation bytecode that the constructor returns, and
+// its immutable references are the zeroed PUSH32 values that the constructor fills in. Storage layouts are not
+// part of the bindings, so the layouts of the contracts with namespaced storage are given by layouts.
+func ReleaseFromBindings(
+	version string,
+	commit string,
+	compiler Compiler,
+	bindings map[string]*bind.MetaData,
+	layouts storagelayout.Layouts,
+) (Release, error) {
+	release := Release{
+		Version:   version,
+		Commit:    commit,
+		Contracts: make(map[string]Contract, len(bindings)),
+	}
+	for name, metadata := range bindings {
+		creationBytecode := common.FromHex(metadata.Bin)
+		if len(creationBytecode) == 0 {
+			continue
+		}
+		runtimeBytecode, err := RuntimeBytecode(creationBytecode)
+		if err != nil {
+			return Release{}, fmt.Errorf("%s: %w", name, err)
+		}
+		if solcVersion := SolcVersion(runtimeBytecode); solcVersion != compiler.Version {
+			return Release{}, fmt.Errorf("%s was built with solc %s, not %s", name, solcVersion, compiler.Version)
+		}
+
+		contract := Contract{
+			ABI:                 json.RawMessage(metadata.ABI),
+			CreationBytecode:    creationBytecode,
+			RuntimeBytecode:     runtimeBytecode,
+			ImmutableReferences: ImmutableReferences(runtimeBytecode),
+			Compiler:            compiler,
+		}
+		if layout, ok := layouts[name]; ok {
+			contract.StorageLayout = &layout
+		}
+		release.Contracts[name] = contract
+	}
+	for name := range layouts {
+		if _, ok := release.Contracts[name]; !ok {
+			return Release{}, fmt.Errorf("%w: no bindings for the storage layout of %s", ErrNotFound, name)
+		}
+	}
+	return release, nil
+}
+
+// RuntimeBytecode returns the runtime bytecode embedded in the creation bytecode of a contract built by solc.
+// The runtime bytecode ends with its metadata, and is followed by the creation bytecode of the contracts that
+// the constructor creates.
+func RuntimeBytecode(creationBytecode []byte) (hexutil.Bytes, error) {
+	code := inspect.RuntimeCode(creationBytecode)
+	index := bytes.Index(code, solcMetadataKey)
+	// The key is followed by the 3 byte version and the 2 byte length of the metadata
+	end := index + len(solcMetadataKey) + 3 + 2
+	if index < 0 || end > len(code) || auditedbytecode.MetadataLength(code[:end]) == 0 {
+		return nil, errRuntimeNotFound
+	}
+	return code[:end], nil
+}
+
+// ImmutableReferences returns the ranges of the values of the immutables in the runtime bytecode from the creation
+// bytecode of a contract. solc reserves a zeroed PUSH32 for each reference to an immutable, and pushes zero values
+// with PUSH0 otherwise.
+func ImmutableReferences(runtimeBytecode []byte) []auditedbytecode.Range {
+	var references []auditedbytecode.Range
+	code := runtimeBytecode[:len(runtimeBytecode)-auditedbytecode.MetadataLength(runtimeBytecode)]
+	for i := 0; i < len(code); i += instructionLength(code[i]) {
+		if code[i] != opPush32 || i+1+common.HashLength > len(code) {
+			continue
+		}
+		if (common.BytesToHash(code[i+1:i+1+common.HashLength]) == common.Hash{}) {
+			references = append(references, auditedbytecode.Range{Start: i + 1, Length: common.HashLength})
+		}
+	}
+	return references
+}
+
+// SolcVersion returns the solc version recorded in the metadata of the runtime bytecode, or an empty string if it
+// has none.
+func SolcVersion(runtimeBytecode []byte) string {
+	metadata := runtimeBytecode[len(runtimeBytecode)-auditedbytecode.MetadataLength(runtimeBytecode):]
+	index := bytes.Index(metadata, solcMetadataKey)
+	if index < 0 || index+len(solcMetadataKey)+3 > len(metadata) {
+		return ""
+	}
+	version := metadata[index+len(solcMetadataKey):]
+	return fmt.Sprintf("%d.%d.%d", version[0], version[1], version[2])
+}
+
+// instructionLength returns the length of the instruction with the opcode, including its push data.
+func instructionLength(opcode byte) int {
+	if opcode >= opPush1 && opcode <= opPush32 {
+		return 1 + int(opcode-opPush1) + 1
+	}
+	return 1
+}
@@ -0,0 +1,291 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package contractartifacts is the registry of the build artifacts of the contracts at each release: their
+// ABI, creation and runtime bytecode, compiler settings and storage layout. The artifacts of each release
+// are embedded from the releases directory, with one file per release version, so that the tools deploying
+// and verifying contracts share them instead of the bytecode of the bindings, which has no version attached.
+package contractartifacts
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	auditedbytecode "github.com/ava-labs/avalanche-interchain-token-transfer/utils/audited-bytecode"
+	storagelayout "github.com/ava-labs/avalanche-interchain-token-transfer/utils/storage-layout"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+//go:embed releases
+var releasesFS embed.FS
+
+var (
+	ErrNotFound = errors.New("contract artifact not found")
+
+	errInvalidReleaseFile = errors.New("invalid release file")
+)
+
+// Compiler is the compiler version and the settings that affect the bytecode of a contract.
+type Compiler struct {
+	Version       string `json:"version"`
+	EVMVersion    string `json:"evmVersion"`
+	Optimizer     bool   `json:"optimizer"`
+	OptimizerRuns int    `json:"optimizerRuns"`
+	ViaIR         bool   `json:"viaIR,omitempty"`
+	BytecodeHash  string `json:"bytecodeHash,omitempty"`
+}
+
+// Contract is the build artifact of a contract in a release. Immutable values are set in the constructor,
+// so their ranges are zeroed in the runtime bytecode. The storage layout is only set for contracts with
+// namespaced storage.
+type Contract struct {
+	ABI                 json.RawMessage         `json:"abi"`
+	CreationBytecode    hexutil.Bytes           `json:"creationBytecode"`
+	RuntimeBytecode     hexutil.Bytes           `json:"runtimeBytecode"`
+	ImmutableReferences []auditedbytecode.Range `json:"immutableReferences,omitempty"`
+	Compiler            Compiler                `json:"compiler"`
+	StorageLayout       *storagelayout.Layout   `json:"storageLayout,omitempty"`
+}
+
+// Release is the set of contracts built from a release. Audited releases are listed in audits/README.md.
+type Release struct {
+	Version   string              `json:"version"`
+	Commit    string              `json:"commit"`
+	Audited   bool                `json:"audited,omitempty"`
+	Contracts map[string]Contract `json:"contracts"`
+}
+
+// Bytecode returns the runtime bytecode of the contracts of the release, to verify deployments with.
+// The DependencyContracts are excluded, since they are not part of the audited contracts.
+func (r Release) Bytecode() auditedbytecode.Release {
+	release := auditedbytecode.Release{
+		Tag:       r.Version,
+		Commit:    r.Commit,
+		Contracts: make(map[string]auditedbytecode.Contract, len(r.Contracts)),
+	}
+	for name, contract := range r.Contracts {
+		if slices.Contains(DependencyContracts, name) {
+			continue
+		}
+		release.Contracts[name] = auditedbytecode.Contract{
+			RuntimeBytecode:     contract.RuntimeBytecode,
+			ImmutableReferences: contract.ImmutableReferences,
+		}
+	}
+	return release
+}
+
+// StorageLayouts returns the storage layouts of the contracts of the release with namespaced storage,
+// to check upgrades against.
+func (r Release) StorageLayouts() storagelayout.Layouts {
+	layouts := make(storagelayout.Layouts)
+	for name, contract := range r.Contracts {
+		if contract.StorageLayout != nil {
+			layouts[name] = *contract.StorageLayout
+		}
+	}
+	return layouts
+}
+
+// Artifact is a contract of a release.
+type Artifact struct {
+	Name    string
+	Version string
+	Commit  string
+	Contract
+}
+
+// String formats the artifact as <Contract>@<version>.
+func (a Artifact) String() string {
+	return fmt.Sprintf("%s@%s", a.Name, a.Version)
+}
+
+// RuntimeCodeHash returns the hash of the runtime bytecode, which is the code hash of deployed contracts
+// without immutables, built with the metadata hash disabled.
+func (a Artifact) RuntimeCodeHash() common.Hash {
+	return crypto.Keccak256Hash(a.RuntimeBytecode)
+}
+
+// ParseABI returns the parsed ABI of the contract.
+func (a Artifact) ParseABI() (*abi.ABI, error) {
+	parsed, err := abi.JSON(bytes.NewReader(a.ABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ABI of %s: %w", a, err)
+	}
+	return &parsed, nil
+}
+
+// Releases returns the releases embedded in the binary, sorted by version.
+func Releases() ([]Release, error) {
+	entries, err := fs.ReadDir(releasesFS, "releases")
+	if err != nil {
+		return nil, err
+	}
+	var releases []Release
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := releasesFS.ReadFile("releases/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		var release Release
+		if err := json.Unmarshal(data, &release); err != nil {
+			return nil, fmt.Errorf("%w %s: %w", errInvalidReleaseFile, entry.Name(), err)
+		}
+		releases = append(releases, release)
+	}
+	sortReleases(releases)
+	return releases, nil
+}
+
+// Registry looks up the artifacts of a set of releases.
+type Registry struct {
+	releases   []Release
+	byVersion  map[string]Release
+	byCodeHash map[common.Hash][]Artifact
+}
+
+// Load returns the registry of the releases embedded in the binary.
+func Load() (*Registry, error) {
+	releases, err := Releases()
+	if err != nil {
+		return nil, err
+	}
+	return NewRegistry(releases), nil
+}
+
+// NewRegistry returns the registry of the given releases.
+func NewRegistry(releases []Release) *Registry {
+	r := &Registry{
+		releases:   append([]Release(nil), releases...),
+		byVersion:  make(map[string]Release, len(releases)),
+		byCodeHash: make(map[common.Hash][]Artifact),
+	}
+	sortReleases(r.releases)
+	for _, release := range r.releases {
+		r.byVersion[release.Version] = release
+		for _, name := range sortedNames(release.Contracts) {
+			artifact := newArtifact(release, name)
+			hash := artifact.RuntimeCodeHash()
+			r.byCodeHash[hash] = append(r.byCodeHash[hash], artifact)
+		}
+	}
+	return r
+}
+
+// Releases returns the releases of the registry, sorted by version.
+func (r *Registry) Releases() []Release {
+	return r.releases
+}
+
+// Release returns the release of the version.
+func (r *Registry) Release(version string) (Release, error) {
+	release, ok := r.byVersion[version]
+	if !ok {
+		return Release{}, fmt.Errorf("%w: no release %s", ErrNotFound, version)
+	}
+	return release, nil
+}
+
+// LatestRelease returns the latest release.
+func (r *Registry) LatestRelease() (Release, error) {
+	if len(r.releases) == 0 {
+		return Release{}, fmt.Errorf("%w: no release", ErrNotFound)
+	}
+	return r.releases[len(r.releases)-1], nil
+}
+
+// AuditedBytecode returns the runtime bytecode of the audited releases, to verify deployments with.
+func (r *Registry) AuditedBytecode() []auditedbytecode.Release {
+	var releases []auditedbytecode.Release
+	for _, release := range r.releases {
+		if release.Audited {
+			releases = append(releases, release.Bytecode())
+		}
+	}
+	return releases
+}
+
+// Get returns the named contract at the release version.
+func (r *Registry) Get(name string, version string) (Artifact, error) {
+	release, ok := r.byVersion[version]
+	if !ok {
+		return Artifact{}, fmt.Errorf("%w: no release %s", ErrNotFound, version)
+	}
+	if _, ok := release.Contracts[name]; !ok {
+		return Artifact{}, fmt.Errorf("%w: no contract %s in release %s", ErrNotFound, name, version)
+	}
+	return newArtifact(release, name), nil
+}
+
+// Latest returns the named contract at the latest release that includes it.
+func (r *Registry) Latest(name string) (Artifact, error) {
+	for i := len(r.releases) - 1; i >= 0; i-- {
+		if _, ok := r.releases[i].Contracts[name]; ok {
+			return newArtifact(r.releases[i], name), nil
+		}
+	}
+	return Artifact{}, fmt.Errorf("%w: no release includes %s", ErrNotFound, name)
+}
+
+// ByCodeHash returns every contract whose runtime bytecode has the hash, in release order. More than one
+// artifact is expected for contracts that did not change between releases, and for contracts that only
+// differ by their constructor.
+func (r *Registry) ByCodeHash(hash common.Hash) []Artifact {
+	return r.byCodeHash[hash]
+}
+
+// ByCode returns every contract whose runtime bytecode matches code, ignoring the values of immutables and
+// the metadata appended by the compiler, in release order.
+func (r *Registry) ByCode(code []byte) []Artifact {
+	if artifacts := r.ByCodeHash(crypto.Keccak256Hash(code)); len(artifacts) != 0 {
+		return artifacts
+	}
+	var artifacts []Artifact
+	for _, release := range r.releases {
+		for _, name := range sortedNames(release.Contracts) {
+			contract := release.Contracts[name]
+			if auditedbytecode.Equal(auditedbytecode.Contract{
+				RuntimeBytecode:     contract.RuntimeBytecode,
+				ImmutableReferences: contract.ImmutableReferences,
+			}, code) {
+				artifacts = append(artifacts, newArtifact(release, name))
+			}
+		}
+	}
+	return artifacts
+}
+
+func newArtifact(release Release, name string) Artifact {
+	return Artifact{
+		Name:     name,
+		Version:  release.Version,
+		Commit:   release.Commit,
+		Contract: release.Contracts[name],
+	}
+}
+
+func sortReleases(releases []Release) {
+	sort.Slice(releases, func(i, j int) bool { return releases[i].Version < releases[j].Version })
+}
+
+func sortedNames(contracts map[string]Contract) []string {
+	names := make([]string, 0, len(contracts))
+	for name := range contracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
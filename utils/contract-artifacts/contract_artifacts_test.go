@@ -0,0 +1,317 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package contractartifacts
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	erc20tokenhomeupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHomeUpgradeable"
+	nativetokenhomeupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHomeUpgradeable"
+	erc20tokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemoteUpgradeable"
+	nativetokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemoteUpgradeable"
+	transparentupgradeableproxy "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TransparentUpgradeableProxy"
+	auditedbytecode "github.com/ava-labs/avalanche-interchain-token-transfer/utils/audited-bytecode"
+	storagelayout "github.com/ava-labs/avalanche-interchain-token-transfer/utils/storage-layout"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/vm/runtime"
+	"github.com/ava-labs/subnet-evm/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// PUSH32 <immutable>, STOP, followed by the metadata {"solc": 0.8.25} and its length
+	runtimeCodeHex = "0x60806040527f" +
+		"0000000000000000000000000000000000000000000000000000000000000000" +
+		"00" + "a164736f6c6343000819" + "000a"
+	immutableStart = 6
+	proxyCodeHex   = "0x6080604052366000803760008036600080fd"
+
+	forgeOutDir = "../../contracts/out"
+	// The release audited in audits/README.md
+	auditedVersion = "v1.0.0"
+)
+
+func testRelease(t *testing.T, version string) Release {
+	release, err := ReleaseFromForgeArtifacts("testdata/out", version, "0123abcd")
+	require.NoError(t, err)
+	return release
+}
+
+func TestReleaseFromForgeArtifacts(t *testing.T) {
+	release := testRelease(t, "v1.0.0")
+	require.Equal(t, "v1.0.0", release.Version)
+	require.Equal(t, "0123abcd", release.Commit)
+
+	// Interfaces without code and mocks are excluded, and dependencies deployed along are included
+	require.Len(t, release.Contracts, 2)
+	home := release.Contracts["Home"]
+	require.JSONEq(t, `[{
+		"type": "function",
+		"name": "owner",
+		"inputs": [],
+		"outputs": [{"name": "", "type": "address", "internalType": "address"}],
+		"stateMutability": "view"
+	}]`, string(home.ABI))
+	require.Equal(t, common.FromHex("0x6080604052348015600e575f80fd5b50"), []byte(home.CreationBytecode))
+	require.Equal(t, common.FromHex(runtimeCodeHex), []byte(home.RuntimeBytecode))
+	require.Equal(t, []auditedbytecode.Range{{Start: immutableStart, Length: 32}}, home.ImmutableReferences)
+	require.Equal(t, Compiler{
+		Version:       "0.8.25+commit.b61c2a91",
+		EVMVersion:    "shanghai",
+		Optimizer:     true,
+		OptimizerRuns: 200,
+		BytecodeHash:  "none",
+	}, home.Compiler)
+	require.Equal(t, &storagelayout.Layout{
+		Namespaces: map[string]storagelayout.Namespace{
+			"example.storage.Home": {
+				Struct: "Home.HomeStorage",
+				Fields: []storagelayout.Field{{Name: "_owner", Type: "address"}},
+			},
+		},
+	}, home.StorageLayout)
+
+	proxy := release.Contracts["TransparentUpgradeableProxy"]
+	require.Equal(t, common.FromHex(proxyCodeHex), []byte(proxy.RuntimeBytecode))
+	require.Nil(t, proxy.StorageLayout)
+
+	require.Equal(t, storagelayout.Layouts{"Home": *home.StorageLayout}, release.StorageLayouts())
+	require.Equal(t, []auditedbytecode.Match{{Tag: "v1.0.0", Commit: "0123abcd", Contract: "Home"}},
+		auditedbytecode.FindMatches([]auditedbytecode.Release{release.Bytecode()}, common.FromHex(runtimeCodeHex)))
+	// Dependencies are not audited, so they are not verified against
+	require.Empty(t, auditedbytecode.FindMatches(
+		[]auditedbytecode.Release{release.Bytecode()},
+		common.FromHex(proxyCodeHex),
+	))
+
+	// Written releases are read back unchanged
+	path := filepath.Join(t.TempDir(), "v1.0.0.json")
+	require.NoError(t, WriteRelease(path, release))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var written Release
+	require.NoError(t, json.Unmarshal(data, &written))
+	// The ABIs are indented along with the release
+	require.Len(t, written.Contracts, len(release.Contracts))
+	for name, contract := range release.Contracts {
+		writtenContract := written.Contracts[name]
+		require.JSONEq(t, string(contract.ABI), string(writtenContract.ABI), name)
+		contract.ABI, writtenContract.ABI = nil, nil
+		require.Equal(t, contract, writtenContract, name)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry([]Release{testRelease(t, "v1.1.0"), testRelease(t, "v1.0.0")})
+	require.Equal(t, "v1.0.0", registry.Releases()[0].Version)
+
+	artifact, err := registry.Get("Home", "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "Home@v1.0.0", artifact.String())
+	require.Equal(t, "0123abcd", artifact.Commit)
+	parsed, err := artifact.ParseABI()
+	require.NoError(t, err)
+	require.Contains(t, parsed.Methods, "owner")
+
+	_, err = registry.Get("Home", "v2.0.0")
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = registry.Get("Remote", "v1.0.0")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	release, err := registry.Release("v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", release.Version)
+	_, err = registry.Release("v2.0.0")
+	require.ErrorIs(t, err, ErrNotFound)
+	release, err = registry.LatestRelease()
+	require.NoError(t, err)
+	require.Equal(t, "v1.1.0", release.Version)
+	_, err = NewRegistry(nil).LatestRelease()
+	require.ErrorIs(t, err, ErrNotFound)
+
+	latest, err := registry.Latest("Home")
+	require.NoError(t, err)
+	require.Equal(t, "v1.1.0", latest.Version)
+	_, err = registry.Latest("Remote")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// The proxy has no immutables, so its code hash is that of its deployed code
+	artifacts := registry.ByCodeHash(crypto.Keccak256Hash(common.FromHex(proxyCodeHex)))
+	require.Len(t, artifacts, 2)
+	require.Equal(t, "TransparentUpgradeableProxy@v1.0.0", artifacts[0].String())
+	require.Equal(t, "TransparentUpgradeableProxy@v1.1.0", artifacts[1].String())
+	require.Empty(t, registry.ByCodeHash(common.Hash{}))
+}
+
+func TestRegistryAuditedBytecode(t *testing.T) {
+	audited := testRelease(t, "v1.0.0")
+	audited.Audited = true
+	registry := NewRegistry([]Release{testRelease(t, "v1.1.0"), audited})
+
+	releases := registry.AuditedBytecode()
+	require.Len(t, releases, 1)
+	require.Equal(t, "v1.0.0", releases[0].Tag)
+	require.Len(t, releases[0].Contracts, 1)
+	require.Contains(t, releases[0].Contracts, "Home")
+}
+
+func TestRegistryByCode(t *testing.T) {
+	registry := NewRegistry([]Release{testRelease(t, "v1.0.0")})
+
+	// The deployed code has the immutable set, so it only matches after masking it
+	code := common.FromHex(runtimeCodeHex)
+	code[immutableStart+31] = 0x01
+	require.Empty(t, registry.ByCodeHash(crypto.Keccak256Hash(code)))
+	artifacts := registry.ByCode(code)
+	require.Len(t, artifacts, 1)
+	require.Equal(t, "Home@v1.0.0", artifacts[0].String())
+
+	require.Len(t, registry.ByCode(common.FromHex(proxyCodeHex)), 1)
+	require.Empty(t, registry.ByCode(common.FromHex("0x6080604052600080fd")))
+}
+
+// Checks the runtime bytecode extracted from the bindings against the code the bindings deploy.
+func TestReleaseFromBindings(t *testing.T) {
+	layout := storagelayout.Layout{Namespaces: map[string]storagelayout.Namespace{}}
+	release, err := ReleaseFromBindings(
+		"v1.0.0",
+		"0123abcd",
+		Compiler{Version: "0.8.25", EVMVersion: "shanghai", BytecodeHash: "none"},
+		map[string]*bind.MetaData{
+			"NativeTokenRemoteUpgradeable": nativetokenremoteupgradeable.NativeTokenRemoteUpgradeableMetaData,
+			"TransparentUpgradeableProxy":  transparentupgradeableproxy.TransparentUpgradeableProxyMetaData,
+		},
+		storagelayout.Layouts{"NativeTokenRemoteUpgradeable": layout},
+	)
+	require.NoError(t, err)
+	require.Len(t, release.Contracts, 2)
+	require.Equal(t, &layout, release.Contracts["NativeTokenRemoteUpgradeable"].StorageLayout)
+
+	// The contracts are built for Shanghai, which is activated by Durango
+	cfg := &runtime.Config{ChainConfig: params.TestChainConfig, Time: *params.TestChainConfig.DurangoTimestamp}
+	remote := release.Contracts["NativeTokenRemoteUpgradeable"]
+	require.Empty(t, remote.ImmutableReferences)
+	parsed, err := nativetokenremoteupgradeable.NativeTokenRemoteUpgradeableMetaData.GetAbi()
+	require.NoError(t, err)
+	args, err := parsed.Pack("", uint8(1))
+	require.NoError(t, err)
+	code, implementation, _, err := runtime.Create(append(remote.CreationBytecode, args...), cfg)
+	require.NoError(t, err)
+	require.Equal(t, []byte(remote.RuntimeBytecode), code)
+
+	// The proxy admin is an immutable of the proxy, and the proxy embeds the creation bytecode of the admin
+	proxy := release.Contracts["TransparentUpgradeableProxy"]
+	require.Equal(t, []auditedbytecode.Range{{Start: 16, Length: common.HashLength}}, proxy.ImmutableReferences)
+	parsed, err = transparentupgradeableproxy.TransparentUpgradeableProxyMetaData.GetAbi()
+	require.NoError(t, err)
+	args, err = parsed.Pack("", implementation, common.HexToAddress("0x01"), []byte{})
+	require.NoError(t, err)
+	code, _, _, err = runtime.Create(append(proxy.CreationBytecode, args...), cfg)
+	require.NoError(t, err)
+	require.NotEqual(t, []byte(proxy.RuntimeBytecode), code)
+	require.True(t, auditedbytecode.Equal(auditedbytecode.Contract{
+		RuntimeBytecode:     proxy.RuntimeBytecode,
+		ImmutableReferences: proxy.ImmutableReferences,
+	}, code))
+
+	_, err = ReleaseFromBindings("v1.0.0", "0123abcd", Compiler{Version: "0.8.24"}, map[string]*bind.MetaData{
+		"TransparentUpgradeableProxy": transparentupgradeableproxy.TransparentUpgradeableProxyMetaData,
+	}, nil)
+	require.ErrorContains(t, err, "solc 0.8.25")
+	_, err = ReleaseFromBindings("v1.0.0", "0123abcd", Compiler{Version: "0.8.25"}, nil, storagelayout.Layouts{
+		"NativeTokenRemoteUpgradeable": layout,
+	})
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestEmbeddedReleases(t *testing.T) {
+	registry, err := Load()
+	require.NoError(t, err)
+	for _, release := range registry.Releases() {
+		require.NotEmpty(t, release.Version)
+		require.NotEmpty(t, release.Commit)
+		require.NotEmpty(t, release.Contracts, release.Version)
+		for name, contract := range release.Contracts {
+			require.NotEmpty(t, contract.CreationBytecode, name)
+			require.NotEmpty(t, contract.RuntimeBytecode, name)
+		}
+	}
+}
+
+// Checks the storage layouts of the current contracts against the latest release.
+// Requires the contracts to have been built with forge, which they are in CI.
+func TestCurrentContractsCompatibleWithLatestRelease(t *testing.T) {
+	if _, err := os.Stat(forgeOutDir); os.IsNotExist(err) {
+		if os.Getenv("CI") != "" {
+			t.Fatalf("%s not found, the contracts must be built with forge before the tests", forgeOutDir)
+		}
+		t.Skipf("%s not found, run forge build in the contracts directory", forgeOutDir)
+	}
+	registry, err := Load()
+	require.NoError(t, err)
+	latest, err := registry.LatestRelease()
+	if errors.Is(err, ErrNotFound) && os.Getenv("CI") == "" {
+		t.Skip("no release embedded, run scripts/contract_artifacts.sh")
+	}
+	require.NoError(t, err, "run scripts/contract_artifacts.sh to generate the release")
+
+	current, err := storagelayout.Load(forgeOutDir, storagelayout.UpgradeableContracts)
+	require.NoError(t, err)
+	baseline := latest.StorageLayouts()
+	for _, contract := range storagelayout.UpgradeableContracts {
+		require.Contains(t, baseline, contract, "%s has no storage layout in %s", contract, latest.Version)
+	}
+	require.Empty(t, storagelayout.Compare(baseline, current))
+}
+
+// Checks that the upgradeable contracts deployed from the bindings verify against the audited release.
+// The contracts have no immutables, so their runtime bytecode does not depend on the constructor arguments.
+func TestBindingsMatchAuditedRelease(t *testing.T) {
+	registry, err := Load()
+	require.NoError(t, err)
+	release := requireRelease(t, registry, auditedVersion)
+	require.True(t, release.Audited)
+
+	bindings := map[string]*bind.MetaData{
+		"ERC20TokenHomeUpgradeable":    erc20tokenhomeupgradeable.ERC20TokenHomeUpgradeableMetaData,
+		"NativeTokenHomeUpgradeable":   nativetokenhomeupgradeable.NativeTokenHomeUpgradeableMetaData,
+		"ERC20TokenRemoteUpgradeable":  erc20tokenremoteupgradeable.ERC20TokenRemoteUpgradeableMetaData,
+		"NativeTokenRemoteUpgradeable": nativetokenremoteupgradeable.NativeTokenRemoteUpgradeableMetaData,
+	}
+	for name, metadata := range bindings {
+		parsed, err := metadata.GetAbi()
+		require.NoError(t, err)
+		args, err := parsed.Pack("", uint8(1))
+		require.NoError(t, err)
+
+		// The contracts are built for Shanghai, which is activated by Durango
+		cfg := &runtime.Config{ChainConfig: params.TestChainConfig, Time: *params.TestChainConfig.DurangoTimestamp}
+		code, _, _, err := runtime.Create(append(common.FromHex(metadata.Bin), args...), cfg)
+		require.NoError(t, err, name)
+
+		matches := auditedbytecode.FindMatches(registry.AuditedBytecode(), code)
+		require.Contains(t, matches, auditedbytecode.Match{
+			Tag:      auditedVersion,
+			Commit:   release.Commit,
+			Contract: name,
+		}, name)
+	}
+}
+
+// requireRelease returns the release of the version, which is required in CI. Elsewhere, the test is
+// skipped if the release is not embedded.
+func requireRelease(t *testing.T, registry *Registry, version string) Release {
+	release, err := registry.Release(version)
+	if errors.Is(err, ErrNotFound) && os.Getenv("CI") == "" {
+		t.Skipf("release %s not embedded, run scripts/contract_artifacts.sh", version)
+	}
+	require.NoError(t, err, "run scripts/contract_artifacts.sh to generate the release")
+	return release
+}
@@ -0,0 +1,150 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package contractartifacts
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	auditedbytecode "github.com/ava-labs/avalanche-interchain-token-transfer/utils/audited-bytecode"
+	storagelayout "github.com/ava-labs/avalanche-interchain-token-transfer/utils/storage-layout"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// DependencyContracts are the contracts of dependencies that are deployed along with the token transferrers.
+var DependencyContracts = []string{
+	"TransparentUpgradeableProxy",
+	"ProxyAdmin",
+}
+
+// ReleaseFromForgeArtifacts builds a release from the output directory of forge build at the release commit.
+// Contracts are included if they have runtime bytecode, and their source is under src/, excluding src/mocks/,
+// or they are one of the DependencyContracts.
+func ReleaseFromForgeArtifacts(outDir string, version string, commit string) (Release, error) {
+	release := Release{
+		Version:   version,
+		Commit:    commit,
+		Contracts: make(map[string]Contract),
+	}
+	err := filepath.WalkDir(outDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "build-info" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var artifact forgeArtifact
+		if err := json.Unmarshal(data, &artifact); err != nil {
+			return fmt.Errorf("failed to parse artifact %s: %w", path, err)
+		}
+		// Artifacts are named <Contract>.json, or <Contract>.<solc version>.json if built with multiple versions
+		name := strings.SplitN(filepath.Base(path), ".", 2)[0]
+		sourcePath := artifact.AST.AbsolutePath
+		isSource := strings.HasPrefix(sourcePath, "src/") && !strings.HasPrefix(sourcePath, "src/mocks/")
+		if !isSource && !slices.Contains(DependencyContracts, name) {
+			return nil
+		}
+		if len(artifact.DeployedBytecode.Object) == 0 {
+			return nil
+		}
+
+		var immutableReferences []auditedbytecode.Range
+		for _, ranges := range artifact.DeployedBytecode.ImmutableReferences {
+			immutableReferences = append(immutableReferences, ranges...)
+		}
+		sort.Slice(immutableReferences, func(i, j int) bool {
+			return immutableReferences[i].Start < immutableReferences[j].Start
+		})
+
+		settings := artifact.Metadata.Settings
+		release.Contracts[name] = Contract{
+			ABI:                 artifact.ABI,
+			CreationBytecode:    artifact.Bytecode.Object,
+			RuntimeBytecode:     artifact.DeployedBytecode.Object,
+			ImmutableReferences: immutableReferences,
+			Compiler: Compiler{
+				Version:       artifact.Metadata.Compiler.Version,
+				EVMVersion:    settings.EVMVersion,
+				Optimizer:     settings.Optimizer.Enabled,
+				OptimizerRuns: settings.Optimizer.Runs,
+				ViaIR:         settings.ViaIR,
+				BytecodeHash:  settings.Metadata.BytecodeHash,
+			},
+		}
+		return nil
+	})
+	if err != nil {
+		return Release{}, err
+	}
+
+	layouts, err := storagelayout.LoadArtifacts(outDir)
+	if err != nil {
+		return Release{}, err
+	}
+	for name, contract := range release.Contracts {
+		layout, err := layouts.Layout(name)
+		if err != nil {
+			return Release{}, err
+		}
+		if len(layout.Namespaces) != 0 {
+			contract.StorageLayout = &layout
+			release.Contracts[name] = contract
+		}
+	}
+	return release, nil
+}
+
+type forgeArtifact struct {
+	ABI      json.RawMessage `json:"abi"`
+	Bytecode struct {
+		Object hexutil.Bytes `json:"object"`
+	} `json:"bytecode"`
+	DeployedBytecode struct {
+		Object              hexutil.Bytes                      `json:"object"`
+		ImmutableReferences map[string][]auditedbytecode.Range `json:"immutableReferences"`
+	} `json:"deployedBytecode"`
+	// The metadata of the contract, as documented by solc
+	Metadata struct {
+		Compiler struct {
+			Version string `json:"version"`
+		} `json:"compiler"`
+		Settings struct {
+			EVMVersion string `json:"evmVersion"`
+			Optimizer  struct {
+				Enabled bool `json:"enabled"`
+				Runs    int  `json:"runs"`
+			} `json:"optimizer"`
+			ViaIR    bool `json:"viaIR"`
+			Metadata struct {
+				BytecodeHash string `json:"bytecodeHash"`
+			} `json:"metadata"`
+		} `json:"settings"`
+	} `json:"metadata"`
+	AST struct {
+		AbsolutePath string `json:"absolutePath"`
+	} `json:"ast"`
+}
+
+// WriteRelease writes the release as indented JSON, to be committed to the releases directory.
+func WriteRelease(path string, release Release) error {
+	data, err := json.MarshalIndent(release, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
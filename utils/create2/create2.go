@@ -0,0 +1,370 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package create2 deploys token transferrers and proxies at the same address on every chain, with CREATE2.
+//
+// Contracts deployed from an account with CREATE have an address that depends on the nonce of the account,
+// which differs between chains. Instead, contracts are deployed through a factory, whose address is the same
+// on every chain since it is deployed with a keyless transaction, in the same way as the TeleporterMessenger.
+// The address of a contract deployed by the factory only depends on the salt, the creation bytecode of the
+// contract and its constructor arguments, so it can be predicted before deploying it, for instance to add it
+// to the allow lists of a genesis file. Contracts deployed with the same arguments on several chains have the
+// same address on each of them.
+//
+// The initialization of a proxy usually differs between chains, if only by the address of the
+// TeleporterRegistry, so Deployer.DeployProxy deploys proxies that point to an empty placeholder, and only then
+// upgrades them to their implementation, so that their address is the same on every chain.
+package create2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	proxyadmin "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/ProxyAdmin"
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	erc20tokenhomeupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHomeUpgradeable"
+	nativetokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHome"
+	nativetokenhomeupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHomeUpgradeable"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	erc20tokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemoteUpgradeable"
+	nativetokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemote"
+	nativetokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemoteUpgradeable"
+	transparentupgradeableproxy "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TransparentUpgradeableProxy"
+	contractartifacts "github.com/ava-labs/avalanche-interchain-token-transfer/utils/contract-artifacts"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ICTTInitializableDisallowed is passed to the constructor of the upgradeable contracts so that they can
+// only be initialized through a proxy.
+const ICTTInitializableDisallowed = uint8(1)
+
+var (
+	ErrNoFactory = errors.New("factory not deployed")
+	ErrReverted  = errors.New("transaction reverted")
+	// ErrUnexpectedAddress is returned if the factory deployed a contract at another address than predicted,
+	// which means it is not the expected factory.
+	ErrUnexpectedAddress = errors.New("contract deployed at an unexpected address")
+	// ErrUpgraded is returned if a proxy deployed by Deployer.DeployProxy was already upgraded to another
+	// implementation.
+	ErrUpgraded = errors.New("proxy already upgraded")
+)
+
+// placeholderInitCode deploys a contract made of a single STOP opcode, which does nothing when called.
+var placeholderInitCode = common.FromHex("0x60016000f3")
+
+// bindingContracts are the contracts whose creation bytecode is taken from their bindings.
+var bindingContracts = map[string]*bind.MetaData{
+	"ERC20TokenHome":               erc20tokenhome.ERC20TokenHomeMetaData,
+	"ERC20TokenHomeUpgradeable":    erc20tokenhomeupgradeable.ERC20TokenHomeUpgradeableMetaData,
+	"NativeTokenHome":              nativetokenhome.NativeTokenHomeMetaData,
+	"NativeTokenHomeUpgradeable":   nativetokenhomeupgradeable.NativeTokenHomeUpgradeableMetaData,
+	"ERC20TokenRemote":             erc20tokenremote.ERC20TokenRemoteMetaData,
+	"ERC20TokenRemoteUpgradeable":  erc20tokenremoteupgradeable.ERC20TokenRemoteUpgradeableMetaData,
+	"NativeTokenRemote":            nativetokenremote.NativeTokenRemoteMetaData,
+	"NativeTokenRemoteUpgradeable": nativetokenremoteupgradeable.NativeTokenRemoteUpgradeableMetaData,
+	"TransparentUpgradeableProxy":  transparentupgradeableproxy.TransparentUpgradeableProxyMetaData,
+}
+
+// Backend is the subset of the RPC client of a chain needed to deploy contracts.
+type Backend interface {
+	bind.ContractBackend
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+// Salt returns the salt of a label, such as the symbol of a token and the name of a contract.
+func Salt(label string) common.Hash {
+	return crypto.Keccak256Hash([]byte(label))
+}
+
+// Address returns the address of the contract deployed by the factory with the salt and init code.
+func Address(factory common.Address, salt common.Hash, initCode []byte) common.Address {
+	return crypto.CreateAddress2(factory, salt, crypto.Keccak256(initCode))
+}
+
+// ProxyAdminAddress returns the address of the ProxyAdmin deployed by the constructor of a
+// TransparentUpgradeableProxy, which is the first contract created by the proxy.
+func ProxyAdminAddress(proxy common.Address) common.Address {
+	return crypto.CreateAddress(proxy, 1)
+}
+
+// Contract is the ABI and creation bytecode of a contract.
+type Contract struct {
+	Name     string
+	ABI      *abi.ABI
+	Bytecode []byte
+}
+
+// BindingContracts returns the names of the contracts available from BindingContract.
+func BindingContracts() []string {
+	names := make([]string, 0, len(bindingContracts))
+	for name := range bindingContracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BindingContract returns the named token transferrer or TransparentUpgradeableProxy, as built for its
+// binding.
+func BindingContract(name string) (Contract, error) {
+	metadata, ok := bindingContracts[name]
+	if !ok {
+		return Contract{}, fmt.Errorf("no binding for contract %s", name)
+	}
+	parsed, err := metadata.GetAbi()
+	if err != nil {
+		return Contract{}, err
+	}
+	bytecode, err := hexutil.Decode(metadata.Bin)
+	if err != nil {
+		return Contract{}, fmt.Errorf("invalid bytecode of %s: %w", name, err)
+	}
+	return Contract{Name: name, ABI: parsed, Bytecode: bytecode}, nil
+}
+
+// ArtifactContract returns the contract of a release artifact, so that the same release of a contract is
+// deployed on every chain.
+func ArtifactContract(artifact contractartifacts.Artifact) (Contract, error) {
+	parsed, err := artifact.ParseABI()
+	if err != nil {
+		return Contract{}, err
+	}
+	return Contract{Name: artifact.String(), ABI: parsed, Bytecode: artifact.CreationBytecode}, nil
+}
+
+// InitCode returns the creation bytecode of the contract followed by its encoded constructor arguments.
+func (c Contract) InitCode(args ...interface{}) ([]byte, error) {
+	encodedArgs, err := c.ABI.Pack("", args...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid constructor arguments of %s: %w", c.Name, err)
+	}
+	return append(append([]byte{}, c.Bytecode...), encodedArgs...), nil
+}
+
+// Deployment is a contract to deploy with its salt and constructor arguments.
+type Deployment struct {
+	Contract Contract
+	Salt     common.Hash
+	Args     []interface{}
+}
+
+// InitCode returns the init code of the deployment.
+func (d Deployment) InitCode() ([]byte, error) {
+	return d.Contract.InitCode(d.Args...)
+}
+
+// Address returns the address the factory deploys the contract at.
+func (d Deployment) Address(factory common.Address) (common.Address, error) {
+	initCode, err := d.InitCode()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return Address(factory, d.Salt, initCode), nil
+}
+
+// ProxyDeployment returns the deployment of a TransparentUpgradeableProxy of the implementation, owned by
+// initialOwner through its ProxyAdmin. The proxy is initialized in its constructor with the call data, so
+// that no one else can initialize it first.
+func ProxyDeployment(
+	salt common.Hash,
+	implementation common.Address,
+	initialOwner common.Address,
+	data []byte,
+) (Deployment, error) {
+	proxy, err := BindingContract("TransparentUpgradeableProxy")
+	if err != nil {
+		return Deployment{}, err
+	}
+	return Deployment{
+		Contract: proxy,
+		Salt:     salt,
+		Args:     []interface{}{implementation, initialOwner, data},
+	}, nil
+}
+
+// PlaceholderDeployment returns the deployment of the empty contract that the proxies deployed by
+// Deployer.DeployProxy point to until they are upgraded to their implementation.
+func PlaceholderDeployment() Deployment {
+	return Deployment{
+		Contract: Contract{Name: "Placeholder", ABI: &abi.ABI{}, Bytecode: placeholderInitCode},
+		Salt:     Salt("Placeholder"),
+	}
+}
+
+// ProxyAddress returns the address of the proxy deployed by the factory with Deployer.DeployProxy, with the
+// salt and the key of initialOwner. It does not depend on the implementation of the proxy nor on its
+// initialization, so it is the same on every chain.
+func ProxyAddress(factory common.Address, salt common.Hash, initialOwner common.Address) (common.Address, error) {
+	placeholder, err := PlaceholderDeployment().Address(factory)
+	if err != nil {
+		return common.Address{}, err
+	}
+	deployment, err := ProxyDeployment(salt, placeholder, initialOwner, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return deployment.Address(factory)
+}
+
+// InitializeCall returns the call data of the initialize function of an upgradeable contract.
+func InitializeCall(contract Contract, args ...interface{}) ([]byte, error) {
+	data, err := contract.ABI.Pack("initialize", args...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid initialize arguments of %s: %w", contract.Name, err)
+	}
+	return data, nil
+}
+
+// Deployer deploys contracts through the factory on a chain.
+type Deployer struct {
+	backend Backend
+	key     *ecdsa.PrivateKey
+	factory common.Address
+}
+
+// NewDeployer returns a deployer sending transactions to the factory with the key.
+func NewDeployer(
+	ctx context.Context,
+	backend Backend,
+	key *ecdsa.PrivateKey,
+	factory common.Address,
+) (*Deployer, error) {
+	code, err := backend.CodeAt(ctx, factory, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("%w at %s", ErrNoFactory, factory)
+	}
+	return &Deployer{
+		backend: backend,
+		key:     key,
+		factory: factory,
+	}, nil
+}
+
+// Factory returns the address of the factory.
+func (d *Deployer) Factory() common.Address {
+	return d.factory
+}
+
+// Deploy deploys the contract, and returns its address. If a contract is already deployed at that address,
+// it was deployed with the same init code, and no transaction is sent.
+func (d *Deployer) Deploy(ctx context.Context, deployment Deployment) (common.Address, error) {
+	initCode, err := deployment.InitCode()
+	if err != nil {
+		return common.Address{}, err
+	}
+	address := Address(d.factory, deployment.Salt, initCode)
+	code, err := d.backend.CodeAt(ctx, address, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(code) != 0 {
+		return address, nil
+	}
+
+	opts, err := d.transactOpts(ctx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	factory := bind.NewBoundContract(d.factory, abi.ABI{}, d.backend, d.backend, d.backend)
+	tx, err := factory.RawTransact(opts, append(deployment.Salt.Bytes(), initCode...))
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to deploy %s: %w", deployment.Contract.Name, err)
+	}
+	if err := waitSuccess(ctx, d.backend, tx); err != nil {
+		return common.Address{}, fmt.Errorf("failed to deploy %s: %w", deployment.Contract.Name, err)
+	}
+	code, err = d.backend.CodeAt(ctx, address, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if len(code) == 0 {
+		return common.Address{}, fmt.Errorf(
+			"%w: %s is not at %s", ErrUnexpectedAddress, deployment.Contract.Name, address,
+		)
+	}
+	return address, nil
+}
+
+// DeployProxy deploys a TransparentUpgradeableProxy owned by the key of the deployer at ProxyAddress, and
+// upgrades it to the implementation with the call data through its ProxyAdmin. Unlike the proxies of
+// ProxyDeployment, its address does not depend on the call data, which usually differs between chains. Until
+// it is upgraded, the proxy points to the placeholder, which has no function, so no one else can initialize
+// it first. If the proxy was already upgraded to the implementation, no transaction is sent.
+func (d *Deployer) DeployProxy(
+	ctx context.Context,
+	salt common.Hash,
+	implementation common.Address,
+	data []byte,
+) (common.Address, error) {
+	placeholder, err := d.Deploy(ctx, PlaceholderDeployment())
+	if err != nil {
+		return common.Address{}, err
+	}
+	deployment, err := ProxyDeployment(salt, placeholder, crypto.PubkeyToAddress(d.key.PublicKey), nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	proxy, err := d.Deploy(ctx, deployment)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	value, err := d.backend.StorageAt(ctx, proxy, inspect.ImplementationSlot, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	current := common.BytesToAddress(value)
+	if current == implementation {
+		return proxy, nil
+	}
+	if current != placeholder {
+		return common.Address{}, fmt.Errorf("%w: %s points to %s", ErrUpgraded, proxy, current)
+	}
+
+	proxyAdmin, err := proxyadmin.NewProxyAdmin(ProxyAdminAddress(proxy), d.backend)
+	if err != nil {
+		return common.Address{}, err
+	}
+	opts, err := d.transactOpts(ctx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	tx, err := proxyAdmin.UpgradeAndCall(opts, proxy, implementation, data)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to upgrade proxy %s: %w", proxy, err)
+	}
+	if err := waitSuccess(ctx, d.backend, tx); err != nil {
+		return common.Address{}, fmt.Errorf("failed to upgrade proxy %s: %w", proxy, err)
+	}
+	return proxy, nil
+}
+
+func (d *Deployer) transactOpts(ctx context.Context) (*bind.TransactOpts, error) {
+	chainID, err := d.backend.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := bind.NewKeyedTransactorWithChainID(d.key, chainID)
+	if err != nil {
+		return nil, err
+	}
+	opts.Context = ctx
+	return opts, nil
+}
@@ -0,0 +1,211 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package create2
+
+import (
+	"math/big"
+	"testing"
+
+	proxyadmin "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/ProxyAdmin"
+	nativetokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemoteUpgradeable"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/core/vm/runtime"
+	"github.com/ava-labs/subnet-evm/params"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFactoryDeployment(t *testing.T) {
+	deployment := DefaultFactoryDeployment
+
+	sender, err := types.HomesteadSigner{}.Sender(deployment.Transaction)
+	require.NoError(t, err)
+	require.Equal(t, sender, deployment.Deployer)
+	require.Equal(t, crypto.CreateAddress(deployment.Deployer, 0), deployment.Factory)
+	require.Nil(t, deployment.Transaction.To())
+	require.Zero(t, deployment.Transaction.Nonce())
+	require.Equal(t, big.NewInt(factoryCreationGasPriceWei*int64(factoryCreationGasLimit)), deployment.Cost())
+
+	// The factory address only depends on the gas price
+	other, err := NewFactoryDeployment(big.NewInt(factoryCreationGasPriceWei))
+	require.NoError(t, err)
+	require.Equal(t, deployment.Factory, other.Factory)
+	other, err = NewFactoryDeployment(big.NewInt(2 * factoryCreationGasPriceWei))
+	require.NoError(t, err)
+	require.NotEqual(t, deployment.Factory, other.Factory)
+}
+
+func TestFactoryAddress(t *testing.T) {
+	cfg := &runtime.Config{Origin: DefaultFactoryDeployment.Deployer}
+	_, factory, _, err := runtime.Create(factoryCreationCode, cfg)
+	require.NoError(t, err)
+	require.Equal(t, DefaultFactoryDeployment.Factory, factory)
+
+	// The init code deploys a single STOP opcode
+	initCode := common.FromHex("0x60016000f3")
+	salt := Salt("test")
+	ret, _, err := runtime.Call(factory, append(salt.Bytes(), initCode...), cfg)
+	require.NoError(t, err)
+	address := common.BytesToAddress(ret)
+	require.Equal(t, Address(factory, salt, initCode), address)
+	require.Equal(t, []byte{0}, cfg.State.GetCode(address))
+
+	// Deploying the same init code with the same salt again fails
+	_, _, err = runtime.Call(factory, append(salt.Bytes(), initCode...), cfg)
+	require.Error(t, err)
+
+	// Another salt deploys the init code at another address
+	otherSalt := Salt("other")
+	ret, _, err = runtime.Call(factory, append(otherSalt.Bytes(), initCode...), cfg)
+	require.NoError(t, err)
+	require.Equal(t, Address(factory, otherSalt, initCode), common.BytesToAddress(ret))
+	require.NotEqual(t, address, common.BytesToAddress(ret))
+}
+
+func TestDeploymentAddress(t *testing.T) {
+	contract, err := BindingContract("ERC20TokenHomeUpgradeable")
+	require.NoError(t, err)
+	deployment := Deployment{
+		Contract: contract,
+		Salt:     Salt("TOKEN"),
+		Args:     []interface{}{ICTTInitializableDisallowed},
+	}
+	initCode, err := deployment.InitCode()
+	require.NoError(t, err)
+	require.Equal(t, contract.Bytecode, initCode[:len(contract.Bytecode)])
+	require.Equal(t, common.LeftPadBytes([]byte{ICTTInitializableDisallowed}, 32), initCode[len(contract.Bytecode):])
+
+	factory := DefaultFactoryDeployment.Factory
+	address, err := deployment.Address(factory)
+	require.NoError(t, err)
+	require.Equal(t, Address(factory, deployment.Salt, initCode), address)
+
+	// Invalid constructor arguments are rejected
+	deployment.Args = nil
+	_, err = deployment.Address(factory)
+	require.Error(t, err)
+
+	_, err = BindingContract("Unknown")
+	require.Error(t, err)
+	for _, name := range BindingContracts() {
+		_, err := BindingContract(name)
+		require.NoError(t, err)
+	}
+}
+
+func TestProxyDeployment(t *testing.T) {
+	implementation := common.HexToAddress("0x0100000000000000000000000000000000000001")
+	owner := common.HexToAddress("0x0200000000000000000000000000000000000002")
+	contract, err := BindingContract("ERC20TokenHomeUpgradeable")
+	require.NoError(t, err)
+	data, err := InitializeCall(
+		contract,
+		common.HexToAddress("0x0300000000000000000000000000000000000003"),
+		owner,
+		common.HexToAddress("0x0400000000000000000000000000000000000004"),
+		uint8(18),
+	)
+	require.NoError(t, err)
+	require.Equal(t, contract.ABI.Methods["initialize"].ID, data[:4])
+
+	_, err = InitializeCall(contract)
+	require.Error(t, err)
+
+	deployment, err := ProxyDeployment(Salt("TOKEN"), implementation, owner, data)
+	require.NoError(t, err)
+	require.Equal(t, "TransparentUpgradeableProxy", deployment.Contract.Name)
+	_, err = deployment.InitCode()
+	require.NoError(t, err)
+
+	// The address of the proxy depends on its implementation and initialization
+	address, err := deployment.Address(DefaultFactoryDeployment.Factory)
+	require.NoError(t, err)
+	other, err := ProxyDeployment(Salt("TOKEN"), owner, owner, data)
+	require.NoError(t, err)
+	otherAddress, err := other.Address(DefaultFactoryDeployment.Factory)
+	require.NoError(t, err)
+	require.NotEqual(t, address, otherAddress)
+
+	require.Equal(t, crypto.CreateAddress(address, 1), ProxyAdminAddress(address))
+}
+
+func TestPlaceholderProxy(t *testing.T) {
+	// The contracts are built for Shanghai, which is activated by Durango
+	cfg := &runtime.Config{
+		Origin:      DefaultFactoryDeployment.Deployer,
+		ChainConfig: params.TestChainConfig,
+		Time:        *params.TestChainConfig.DurangoTimestamp,
+	}
+	_, factory, _, err := runtime.Create(factoryCreationCode, cfg)
+	require.NoError(t, err)
+	deploy := func(deployment Deployment) common.Address {
+		initCode, err := deployment.InitCode()
+		require.NoError(t, err)
+		ret, _, err := runtime.Call(factory, append(deployment.Salt.Bytes(), initCode...), cfg)
+		require.NoError(t, err)
+		address, err := deployment.Address(factory)
+		require.NoError(t, err)
+		require.Equal(t, address, common.BytesToAddress(ret))
+		return address
+	}
+
+	// Deploy a NativeTokenRemoteUpgradeable implementation through the factory
+	contract, err := BindingContract("NativeTokenRemoteUpgradeable")
+	require.NoError(t, err)
+	implementation := deploy(Deployment{
+		Contract: contract,
+		Salt:     Salt(contract.Name),
+		Args:     []interface{}{ICTTInitializableDisallowed},
+	})
+	require.NotEmpty(t, cfg.State.GetCode(implementation))
+
+	// Deploy a proxy of the placeholder at its predicted address
+	owner := common.HexToAddress("0x0200000000000000000000000000000000000002")
+	placeholder := deploy(PlaceholderDeployment())
+	proxyDeployment, err := ProxyDeployment(Salt("NATIVE"), placeholder, owner, nil)
+	require.NoError(t, err)
+	proxy := deploy(proxyDeployment)
+	predicted, err := ProxyAddress(factory, Salt("NATIVE"), owner)
+	require.NoError(t, err)
+	require.Equal(t, predicted, proxy)
+	require.Equal(t, common.BytesToHash(placeholder.Bytes()), cfg.State.GetState(proxy, inspect.ImplementationSlot))
+
+	// The address of the proxy depends on its owner
+	other, err := ProxyAddress(factory, Salt("NATIVE"), factory)
+	require.NoError(t, err)
+	require.NotEqual(t, proxy, other)
+
+	// The proxy cannot be initialized before it is upgraded
+	data, err := InitializeCall(
+		contract,
+		nativetokenremoteupgradeable.TokenRemoteSettings{
+			TeleporterRegistryAddress: common.HexToAddress("0x0300000000000000000000000000000000000003"),
+			TeleporterManager:         owner,
+			TokenHomeBlockchainID:     common.HexToHash("0x04"),
+			TokenHomeAddress:          common.HexToAddress("0x0500000000000000000000000000000000000005"),
+			TokenHomeDecimals:         18,
+		},
+		"NATIVE",
+		big.NewInt(1e18),
+		big.NewInt(1),
+	)
+	require.NoError(t, err)
+	ret, _, err := runtime.Call(proxy, data, cfg)
+	require.NoError(t, err)
+	require.Empty(t, ret)
+
+	// Only the owner upgrades the proxy to the implementation through its ProxyAdmin
+	proxyAdminABI, err := proxyadmin.ProxyAdminMetaData.GetAbi()
+	require.NoError(t, err)
+	upgrade, err := proxyAdminABI.Pack("upgradeAndCall", proxy, implementation, []byte{})
+	require.NoError(t, err)
+	_, _, err = runtime.Call(ProxyAdminAddress(proxy), upgrade, cfg)
+	require.Error(t, err)
+	cfg.Origin = owner
+	_, _, err = runtime.Call(ProxyAdminAddress(proxy), upgrade, cfg)
+	require.NoError(t, err)
+	require.Equal(t, common.BytesToHash(implementation.Bytes()), cfg.State.GetState(proxy, inspect.ImplementationSlot))
+}
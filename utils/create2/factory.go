@@ -0,0 +1,165 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package create2
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// Roughly 70,000 gas needed to deploy the factory
+	factoryCreationGasLimit = uint64(100_000)
+	// Same gas price as the keyless Teleporter deployment, so that the factory can be deployed on any chain
+	// whose base fee is below it.
+	factoryCreationGasPriceWei = 2500e9
+
+	// R and S values of the keyless transaction signature, as in the keyless Teleporter deployment. The
+	// AvalancheGo APIs only allow legacy transactions without a chain ID to be broadcast if their R and S
+	// values are the same.
+	rsValueHex = "3333333333333333333333333333333333333333333333333333333333333333"
+	// Must be less than 35 to be considered non-EIP155
+	vValue = 27
+)
+
+// factoryCreationCode deploys the deterministic deployment proxy, whose runtime code deploys the init code
+// that follows the 32 byte salt of its calldata with CREATE2, and returns the deployed address.
+// See https://github.com/Arachnid/deterministic-deployment-proxy
+var factoryCreationCode = common.FromHex(
+	"0x604580600e600039806000f350fe7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe036016" +
+		"00081602082378035828234f58015156039578182fd5b8082525050506014600cf3",
+)
+
+// FactoryDeployment is the keyless transaction that deploys the factory, which is valid on every chain.
+type FactoryDeployment struct {
+	// The signed transaction, which no one holds the key of
+	Transaction *types.Transaction
+	// The sender of the transaction, which must be funded with the cost of the transaction
+	Deployer common.Address
+	// The address of the factory, which is the same on every chain
+	Factory common.Address
+}
+
+// Cost returns the balance the deployer needs to send the transaction.
+func (d FactoryDeployment) Cost() *big.Int {
+	return new(big.Int).Mul(d.Transaction.GasPrice(), new(big.Int).SetUint64(d.Transaction.Gas()))
+}
+
+// DefaultFactoryDeployment is the factory deployment used by the Deployer.
+var DefaultFactoryDeployment = mustNewFactoryDeployment()
+
+// NewFactoryDeployment constructs the keyless transaction deploying the factory using Nick's method, in
+// the same way as the keyless Teleporter deployment: the transaction has a fixed signature, from which its
+// sender is recovered. The factory is deployed by the first transaction of the sender, so its address only
+// depends on the gas price.
+func NewFactoryDeployment(gasPrice *big.Int) (FactoryDeployment, error) {
+	rsValue, ok := new(big.Int).SetString(rsValueHex, 16)
+	if !ok {
+		return FactoryDeployment{}, errors.New("failed to convert R and S value")
+	}
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce:    0,
+		Gas:      factoryCreationGasLimit,
+		GasPrice: gasPrice,
+		To:       nil,
+		Value:    big.NewInt(0),
+		Data:     factoryCreationCode,
+		V:        big.NewInt(vValue),
+		R:        rsValue,
+		S:        rsValue,
+	})
+	deployer, err := types.HomesteadSigner{}.Sender(tx)
+	if err != nil {
+		return FactoryDeployment{}, fmt.Errorf("failed to recover the deployer of the factory: %w", err)
+	}
+	return FactoryDeployment{
+		Transaction: tx,
+		Deployer:    deployer,
+		Factory:     crypto.CreateAddress(deployer, 0),
+	}, nil
+}
+
+func mustNewFactoryDeployment() FactoryDeployment {
+	deployment, err := NewFactoryDeployment(big.NewInt(factoryCreationGasPriceWei))
+	if err != nil {
+		panic(err)
+	}
+	return deployment
+}
+
+// DeployFactory deploys the factory with the keyless transaction of the deployment, unless it is already
+// deployed. The deployer is funded with the cost of the transaction from the funded key.
+func DeployFactory(
+	ctx context.Context,
+	backend Backend,
+	fundedKey *ecdsa.PrivateKey,
+	deployment FactoryDeployment,
+) error {
+	code, err := backend.CodeAt(ctx, deployment.Factory, nil)
+	if err != nil {
+		return err
+	}
+	if len(code) != 0 {
+		return nil
+	}
+	nonce, err := backend.NonceAt(ctx, deployment.Deployer, nil)
+	if err != nil {
+		return err
+	}
+	if nonce != 0 {
+		// The deployment transaction can never be included
+		return fmt.Errorf(
+			"deployer %s of factory %s has already sent a transaction", deployment.Deployer, deployment.Factory,
+		)
+	}
+
+	balance, err := backend.BalanceAt(ctx, deployment.Deployer, nil)
+	if err != nil {
+		return err
+	}
+	if missing := new(big.Int).Sub(deployment.Cost(), balance); missing.Sign() > 0 {
+		chainID, err := backend.ChainID(ctx)
+		if err != nil {
+			return err
+		}
+		opts, err := bind.NewKeyedTransactorWithChainID(fundedKey, chainID)
+		if err != nil {
+			return err
+		}
+		opts.Context = ctx
+		opts.Value = missing
+		tx, err := bind.NewBoundContract(deployment.Deployer, abi.ABI{}, backend, backend, backend).Transfer(opts)
+		if err != nil {
+			return fmt.Errorf("failed to fund factory deployer %s: %w", deployment.Deployer, err)
+		}
+		if err := waitSuccess(ctx, backend, tx); err != nil {
+			return err
+		}
+	}
+
+	if err := backend.SendTransaction(ctx, deployment.Transaction); err != nil {
+		return fmt.Errorf("failed to send factory deployment: %w", err)
+	}
+	return waitSuccess(ctx, backend, deployment.Transaction)
+}
+
+func waitSuccess(ctx context.Context, backend Backend, tx *types.Transaction) error {
+	receipt, err := bind.WaitMined(ctx, backend, tx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for transaction %s: %w", tx.Hash(), err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("%w: %s", ErrReverted, tx.Hash())
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package gateway
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/amounts"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Amount is an amount of tokens in the smallest unit of the token. It is encoded in JSON as a decimal
+// string, so that it is not rounded by clients that decode JSON numbers as floats.
+type Amount big.Int
+
+// NewAmount returns the Amount of x, which it shares.
+func NewAmount(x *big.Int) *Amount {
+	return (*Amount)(x)
+}
+
+// Int returns the amount as a big.Int, or zero if a is nil.
+func (a *Amount) Int() *big.Int {
+	if a == nil {
+		return new(big.Int)
+	}
+	return (*big.Int)(a)
+}
+
+func (a *Amount) MarshalText() ([]byte, error) {
+	return []byte(a.Int().String()), nil
+}
+
+func (a *Amount) UnmarshalText(text []byte) error {
+	x, ok := new(big.Int).SetString(string(text), 10)
+	if !ok || x.Sign() < 0 || x.BitLen() > 256 {
+		return fmt.Errorf("%w: %q", amounts.ErrInvalidAmount, text)
+	}
+	*a = Amount(*x)
+	return nil
+}
+
+// QuoteRequest is a transfer of amount from the source token transferrer to the destination.
+type QuoteRequest struct {
+	SourceBlockchainID      ids.ID         `json:"sourceBlockchainID"`
+	Source                  common.Address `json:"source"`
+	DestinationBlockchainID ids.ID         `json:"destinationBlockchainID"`
+	Destination             common.Address `json:"destination"`
+	// In units of the source
+	Amount *Amount `json:"amount"`
+	// Fee paid to the relayer of the second hop of multi-hop transfers, in units of the source
+	SecondaryFee *Amount `json:"secondaryFee,omitempty"`
+}
+
+// Quote is the outcome of a transfer, as scaled by the token transferrers.
+type Quote struct {
+	// True for transfers from a remote to another remote, which are routed by the home
+	MultiHop bool `json:"multiHop"`
+	// The amount emitted by the TokensSent event of the source. Transfers from the home emit the amount
+	// scaled to the destination, and transfers from a remote emit the amount in units of the remote.
+	SentAmount *Amount `json:"sentAmount"`
+	// The amount received by the recipient, in units of the destination. Zero if Fallback is true.
+	ReceivedAmount *Amount `json:"receivedAmount"`
+	// True if the home will send the tokens of a multi-hop transfer to its multi-hop fallback on the home chain,
+	// because the destination is not registered, needs collateral, or would receive zero tokens.
+	Fallback bool `json:"fallback"`
+	// The amount sent to the multi-hop fallback, in home token units
+	FallbackAmount *Amount `json:"fallbackAmount,omitempty"`
+}
+
+// TransferRequest is a transfer to build, or to submit with the managed signer.
+type TransferRequest struct {
+	QuoteRequest
+	// The account the tokens are sent from. Defaults to the managed signer, which must be the sender
+	// of submitted transfers.
+	Sender    common.Address `json:"sender"`
+	Recipient common.Address `json:"recipient"`
+	// The fee paid to the relayer of the first hop. The fee token is approved together with the amount.
+	PrimaryFeeTokenAddress common.Address `json:"primaryFeeTokenAddress"`
+	PrimaryFee             *Amount        `json:"primaryFee,omitempty"`
+	// Gas limit required to execute the transfer on the destination. Defaults to the gas required
+	// by the kind of the destination.
+	RequiredGasLimit uint64 `json:"requiredGasLimit,omitempty"`
+	// Recipient of the tokens on the home chain if a multi-hop transfer cannot be routed.
+	// Defaults to the sender.
+	MultiHopFallback common.Address `json:"multiHopFallback"`
+	// If true, the transactions are signed and sent by the managed signer, which waits for their receipts.
+	Submit bool `json:"submit,omitempty"`
+}
+
+// Transaction is a transaction of a transfer, to be signed by its sender unless it was submitted.
+// Its gas limit and fees are left to the signer.
+type Transaction struct {
+	BlockchainID ids.ID         `json:"blockchainID"`
+	ChainID      *Amount        `json:"chainID"`
+	From         common.Address `json:"from"`
+	To           common.Address `json:"to"`
+	Data         hexutil.Bytes  `json:"data"`
+	Value        *Amount        `json:"value"`
+	Nonce        uint64         `json:"nonce"`
+	// Set once the transaction is submitted
+	Hash *common.Hash `json:"hash,omitempty"`
+}
+
+// TransferResponse is a built or submitted transfer.
+type TransferResponse struct {
+	Quote *Quote `json:"quote"`
+	// The approvals of the tokens spent by the token transferrer, followed by the send,
+	// to be sent in order from the same account.
+	Transactions []Transaction `json:"transactions"`
+	Submitted    bool          `json:"submitted"`
+	// The message sent by a submitted transfer
+	MessageID *ids.ID `json:"messageID,omitempty"`
+}
+
+// Transfer is the status of a transfer sent by the home or one of its remotes.
+type Transfer struct {
+	MessageID ids.ID `json:"messageID"`
+	// For multi-hop transfers routed by the home, the message of the second hop
+	RoutedMessageID         *ids.ID        `json:"routedMessageID,omitempty"`
+	SourceBlockchainID      ids.ID         `json:"sourceBlockchainID"`
+	Source                  common.Address `json:"source"`
+	DestinationBlockchainID ids.ID         `json:"destinationBlockchainID"`
+	Destination             common.Address `json:"destination"`
+	Sender                  common.Address `json:"sender"`
+	Recipient               common.Address `json:"recipient"`
+	// The amount emitted by the TokensSent or TokensAndCallSent event
+	Amount *Amount `json:"amount"`
+	// The amount in home token units, unless the remote is not registered with the home
+	HomeValue *Amount                  `json:"homeValue,omitempty"`
+	TxHash    common.Hash              `json:"txHash"`
+	Status    portfolio.TransferStatus `json:"status"`
+}
+
+// TransferEventKind is the event reported by a TransferEvent.
+type TransferEventKind string
+
+const (
+	// A token transferrer sent a transfer
+	EventTokensSent        TransferEventKind = "TokensSent"
+	EventTokensAndCallSent TransferEventKind = "TokensAndCallSent"
+	// The home received the first hop of a multi-hop transfer, and sent the second hop
+	EventTokensRouted        TransferEventKind = "TokensRouted"
+	EventTokensAndCallRouted TransferEventKind = "TokensAndCallRouted"
+	// The destination of a transfer sent the tokens to the recipient. The home also sends the tokens of
+	// a multi-hop transfer that it could not route to its multi-hop fallback.
+	EventTokensWithdrawn TransferEventKind = "TokensWithdrawn"
+	// The destination of a send and call transfer called the recipient contract
+	EventCallSucceeded TransferEventKind = "CallSucceeded"
+	// The call of the recipient contract failed, and the tokens were sent to the fallback recipient
+	EventCallFailed TransferEventKind = "CallFailed"
+	// The execution of the message of a transfer failed. It can be retried on the destination.
+	EventExecutionFailed TransferEventKind = "MessageExecutionFailed"
+)
+
+// TransferEvent is a state transition of a transfer, pushed by the transfer stream.
+type TransferEvent struct {
+	// Identifies the stream, which restarts with the gateway
+	StreamID string `json:"streamID"`
+	// Position of the event in the stream. Reconnecting with it resumes the stream after the event.
+	Sequence uint64            `json:"sequence"`
+	Event    TransferEventKind `json:"event"`
+	// True if the block of a previously streamed event was reorged out, retracting that event
+	Removed bool `json:"removed,omitempty"`
+	// The message sent by the source of the transfer
+	MessageID ids.ID `json:"messageID"`
+	// For multi-hop transfers routed by the home, the message of the second hop
+	RoutedMessageID *ids.ID `json:"routedMessageID,omitempty"`
+	// The chain and token transferrer that emitted the event
+	BlockchainID ids.ID         `json:"blockchainID"`
+	Address      common.Address `json:"address"`
+	// The sender of the transfer, unless it was sent before the blocks followed by the stream
+	Sender *common.Address `json:"sender,omitempty"`
+	// The recipient, or the recipient contract of a send and call
+	Recipient common.Address `json:"recipient"`
+	// The amount emitted by the event, in units of the token transferrer that emitted it
+	Amount      *Amount     `json:"amount,omitempty"`
+	TxHash      common.Hash `json:"txHash"`
+	BlockNumber uint64      `json:"blockNumber"`
+}
+
+// TokenTransferrer describes the home, or a remote registered with it.
+type TokenTransferrer struct {
+	BlockchainID ids.ID         `json:"blockchainID"`
+	Address      common.Address `json:"address"`
+	// False if the chain is not configured, in which case only the registration of the remote is reported
+	Reachable bool         `json:"reachable"`
+	Kind      inspect.Kind `json:"kind,omitempty"`
+	// The token transferred by the home. For remotes, the remote itself.
+	TokenAddress  common.Address `json:"tokenAddress"`
+	TokenDecimals uint8          `json:"tokenDecimals"`
+	// How amounts are scaled between the home and the remote. Omitted for the home.
+	TokenMultiplier  *Amount `json:"tokenMultiplier,omitempty"`
+	MultiplyOnRemote bool    `json:"multiplyOnRemote"`
+	// Home tokens to be added as collateral before the home sends tokens to the remote
+	CollateralNeeded *Amount `json:"collateralNeeded,omitempty"`
+}
+
+// Remotes is the home and the remotes registered with it, in the order of their registration.
+type Remotes struct {
+	Home    TokenTransferrer   `json:"home"`
+	Remotes []TokenTransferrer `json:"remotes"`
+}
@@ -0,0 +1,243 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package gateway serves an HTTP API to quote, build, submit and track the transfers between a TokenHome
+// and the remotes registered with it. The API is described by the OpenAPI document served at /openapi.yaml.
+//
+// While RunStream runs, clients can also follow the state transitions of transfers over a websocket at
+// /transfers/stream, instead of polling for their status.
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/subscription"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header with the idempotency key of a transfer submission.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set to "true" on responses replayed for an idempotency key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	defaultIdempotencyTTL = 24 * time.Hour
+	// Time allowed to submit the transactions of a transfer and wait for their receipts. Submissions
+	// are not canceled when the client disconnects, so that their response is recorded for their
+	// idempotency key.
+	submitTimeout  = 2 * time.Minute
+	maxRequestSize = 1 << 16
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+// Backend is the RPC client of a chain, as needed to read the token transferrers and to send transactions.
+type Backend interface {
+	portfolio.Backend
+	bind.ContractBackend
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// Config describes the TokenHome served by the gateway, and the chains of its remotes.
+type Config struct {
+	HomeBlockchainID ids.ID
+	HomeAddress      common.Address
+	// The backend of each chain, by blockchain ID. Transfers from remotes on other chains cannot be
+	// built, and their status cannot be tracked.
+	Chains map[ids.ID]Backend
+	// Address of the TeleporterMessenger, which is the same on every chain.
+	TeleporterAddress common.Address
+	// Number of blocks searched for transfers on each chain. Zero searches from genesis.
+	LookBackBlocks uint64
+	// Key of the managed signer that submits transfers. If nil, the gateway only builds unsigned transactions.
+	SignerKey *ecdsa.PrivateKey
+	// How long the responses to submissions are kept for their idempotency key. Defaults to 24 hours.
+	IdempotencyTTL time.Duration
+	// Dial connects to a chain to stream the events of its token transferrers, typically over a websocket.
+	// It is called again to reconnect after the connection fails. Required by RunStream.
+	Dial func(ctx context.Context, blockchainID ids.ID) (subscription.Backend, error)
+	// Number of blocks on top of the block of an event before it is streamed.
+	Confirmations uint64
+}
+
+// Server serves the gateway API.
+type Server struct {
+	config          Config
+	portfolioConfig portfolio.Config
+	router          *mux.Router
+	idempotency     *idempotencyStore
+	stream          *transferStream
+	// Held while a transfer is submitted, so that the transactions of the signer get consecutive nonces
+	submitLock sync.Mutex
+}
+
+// New returns a Server for the TokenHome of the config.
+func New(config Config) (*Server, error) {
+	if _, ok := config.Chains[config.HomeBlockchainID]; !ok {
+		return nil, fmt.Errorf("no backend for the home chain %s", config.HomeBlockchainID)
+	}
+	if config.IdempotencyTTL == 0 {
+		config.IdempotencyTTL = defaultIdempotencyTTL
+	}
+	s := &Server{
+		config: config,
+		portfolioConfig: portfolio.Config{
+			HomeBlockchainID:  config.HomeBlockchainID,
+			HomeAddress:       config.HomeAddress,
+			Chains:            make(map[ids.ID]portfolio.Backend, len(config.Chains)),
+			TeleporterAddress: config.TeleporterAddress,
+			LookBackBlocks:    config.LookBackBlocks,
+		},
+		idempotency: newIdempotencyStore(config.IdempotencyTTL),
+		stream:      newTransferStream(config),
+	}
+	for blockchainID, backend := range config.Chains {
+		s.portfolioConfig.Chains[blockchainID] = backend
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/quote", s.handleQuote).Methods(http.MethodPost)
+	router.HandleFunc("/transfers", s.handleCreateTransfer).Methods(http.MethodPost)
+	router.HandleFunc("/transfers/stream", s.handleStream).Methods(http.MethodGet)
+	router.HandleFunc("/transfers/{messageID}", s.handleGetTransfer).Methods(http.MethodGet)
+	router.HandleFunc("/remotes", s.handleRemotes).Methods(http.MethodGet)
+	router.HandleFunc("/openapi.yaml", handleOpenAPI).Methods(http.MethodGet)
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, &requestError{status: http.StatusNotFound, err: errors.New("not found")})
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, &requestError{status: http.StatusMethodNotAllowed, err: errors.New("method not allowed")})
+	})
+	s.router = router
+	return s, nil
+}
+
+// SignerAddress returns the address of the managed signer, or the zero address if there is none.
+func (s *Server) SignerAddress() common.Address {
+	if s.config.SignerKey == nil {
+		return common.Address{}
+	}
+	return crypto.PubkeyToAddress(s.config.SignerKey.PublicKey)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+func handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(openAPISpec)
+}
+
+// requestError is an error caused by the request, reported with its status instead of 500.
+type requestError struct {
+	status int
+	err    error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// invalidRequest returns an error for a malformed request.
+func invalidRequest(format string, args ...interface{}) error {
+	return &requestError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+// unprocessable returns an error for a well-formed request that the token transferrers would reject.
+func unprocessable(format string, args ...interface{}) error {
+	return &requestError{status: http.StatusUnprocessableEntity, err: fmt.Errorf(format, args...)}
+}
+
+// errorResponse is the body of every error response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// errorStatus returns the status of the response for err.
+func errorStatus(err error) int {
+	var requestErr *requestError
+	if errors.As(err, &requestErr) {
+		return requestErr.status
+	}
+	return http.StatusInternalServerError
+}
+
+// encodeError returns the status and body of the response for err.
+func encodeError(err error) (int, []byte) {
+	status := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		log.Error("Gateway request failed", "err", err)
+	}
+	return encodeJSON(status, errorResponse{Error: err.Error()})
+}
+
+// encodeJSON returns the status and body of the response with the given value.
+func encodeJSON(status int, value interface{}) (int, []byte) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		log.Error("Failed to encode gateway response", "err", err)
+		return http.StatusInternalServerError, []byte(`{"error":"failed to encode response"}`)
+	}
+	return status, body
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status, body := encodeError(err)
+	writeBody(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	status, body := encodeJSON(status, value)
+	writeBody(w, status, body)
+}
+
+func writeBody(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// readBody reads the body of the request, up to maxRequestSize.
+func readBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize+1))
+	if err != nil {
+		return nil, invalidRequest("failed to read request: %w", err)
+	}
+	if len(body) > maxRequestSize {
+		return nil, &requestError{status: http.StatusRequestEntityTooLarge, err: errors.New("request too large")}
+	}
+	return body, nil
+}
+
+// decodeJSON decodes the body of a request into value, rejecting unknown fields.
+func decodeJSON(body []byte, value interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return invalidRequest("invalid request: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package gateway
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmountJSON(t *testing.T) {
+	var quote Quote
+	require.NoError(t, json.Unmarshal([]byte(`{"sentAmount":"123456789012345678901234567890"}`), &quote))
+	expected, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	require.Equal(t, expected, quote.SentAmount.Int())
+	require.Zero(t, quote.ReceivedAmount.Int().Sign())
+
+	data, err := json.Marshal(Quote{SentAmount: NewAmount(big.NewInt(7)), ReceivedAmount: NewAmount(big.NewInt(0))})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"multiHop":false,"sentAmount":"7","receivedAmount":"0","fallback":false}`, string(data))
+
+	for _, invalid := range []string{`"-1"`, `"1.5"`, `"0x10"`, `""`, `1`} {
+		var amount Amount
+		require.Error(t, json.Unmarshal([]byte(invalid), &amount), invalid)
+	}
+}
+
+// newTestServer returns a server without a managed signer, for requests that are handled without
+// reading any chain.
+func newTestServer(t *testing.T) *Server {
+	homeBlockchainID := ids.ID{1}
+	s, err := New(Config{
+		HomeBlockchainID: homeBlockchainID,
+		Chains:           map[ids.ID]Backend{homeBlockchainID: nil},
+	})
+	require.NoError(t, err)
+	return s
+}
+
+// serve returns the response of the server to the request.
+func serve(s *Server, method string, path string, body string, header http.Header) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestServerErrors(t *testing.T) {
+	s := newTestServer(t)
+
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "unknown path", method: http.MethodGet, path: "/unknown", status: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodGet, path: "/quote", status: http.StatusMethodNotAllowed},
+		{name: "malformed quote", method: http.MethodPost, path: "/quote", body: `{`, status: http.StatusBadRequest},
+		{
+			name:   "unknown field",
+			method: http.MethodPost,
+			path:   "/quote",
+			body:   `{"amount":"1","unknown":true}`,
+			status: http.StatusBadRequest,
+		},
+		{name: "missing amount", method: http.MethodPost, path: "/quote", body: `{}`, status: http.StatusBadRequest},
+		{
+			name:   "unsigned transfer without sender",
+			method: http.MethodPost,
+			path:   "/transfers",
+			body:   `{"amount":"1"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "submission without signer",
+			method: http.MethodPost,
+			path:   "/transfers",
+			body:   `{"amount":"1","submit":true}`,
+			status: http.StatusBadRequest,
+		},
+		{name: "invalid message ID", method: http.MethodGet, path: "/transfers/0x1234", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(s, tc.method, tc.path, tc.body, nil)
+			require.Equal(t, tc.status, recorder.Code)
+			var response errorResponse
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			require.NotEmpty(t, response.Error)
+		})
+	}
+}
+
+func TestServerIdempotencyKey(t *testing.T) {
+	s := newTestServer(t)
+	header := http.Header{IdempotencyKeyHeader: []string{"transfer-1"}}
+	body := `{"amount":"1","submit":true}`
+
+	first := serve(s, http.MethodPost, "/transfers", body, header)
+	require.Equal(t, http.StatusBadRequest, first.Code)
+	require.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	replayed := serve(s, http.MethodPost, "/transfers", body, header)
+	require.Equal(t, first.Code, replayed.Code)
+	require.Equal(t, first.Body.Bytes(), replayed.Body.Bytes())
+	require.Equal(t, "true", replayed.Header().Get(IdempotentReplayedHeader))
+
+	reused := serve(s, http.MethodPost, "/transfers", `{"amount":"2","submit":true}`, header)
+	require.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+}
+
+func TestServerOpenAPI(t *testing.T) {
+	recorder := serve(newTestServer(t), http.MethodGet, "/openapi.yaml", "", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	// Every route is documented
+	for _, path := range []string{"/quote:", "/transfers:", "/transfers/stream:", "/transfers/{messageID}:", "/remotes:"} {
+		require.Contains(t, recorder.Body.String(), "\n  "+path+"\n")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	errIdempotencyKeyInProgress = &requestError{
+		status: http.StatusConflict,
+		err:    errors.New("a request with the same idempotency key is in progress"),
+	}
+	errIdempotencyKeyReused = &requestError{
+		status: http.StatusUnprocessableEntity,
+		err:    errors.New("the idempotency key was used for a different request"),
+	}
+)
+
+// idempotentResponse is the response recorded for an idempotency key.
+type idempotentResponse struct {
+	requestHash [32]byte
+	created     time.Time
+	// False until the request is handled
+	done   bool
+	status int
+	body   []byte
+}
+
+// idempotencyStore records the responses to requests by their idempotency key, in memory.
+type idempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	lock      sync.Mutex
+	responses map[string]*idempotentResponse
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:       ttl,
+		now:       time.Now,
+		responses: make(map[string]*idempotentResponse),
+	}
+}
+
+// begin returns the recorded response for the key, which must have been recorded for the request with the
+// same hash. If there is none, the key is reserved for the request, which must then call finish.
+func (s *idempotencyStore) begin(key string, requestHash [32]byte) (*idempotentResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	for k, response := range s.responses {
+		if response.done && now.Sub(response.created) > s.ttl {
+			delete(s.responses, k)
+		}
+	}
+	response, ok := s.responses[key]
+	if !ok {
+		s.responses[key] = &idempotentResponse{requestHash: requestHash, created: now}
+		return nil, nil
+	}
+	if response.requestHash != requestHash {
+		return nil, errIdempotencyKeyReused
+	}
+	if !response.done {
+		return nil, errIdempotencyKeyInProgress
+	}
+	return response, nil
+}
+
+// finish records the response to the request that reserved the key.
+func (s *idempotencyStore) finish(key string, status int, body []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	response := s.responses[key]
+	response.done = true
+	response.status = status
+	response.body = body
+}
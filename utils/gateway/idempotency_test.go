@@ -0,0 +1,49 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package gateway
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	store := newIdempotencyStore(time.Hour)
+	store.now = func() time.Time { return now }
+	request := [32]byte{1}
+
+	replay, err := store.begin("key", request)
+	require.NoError(t, err)
+	require.Nil(t, replay)
+
+	// The key is reserved until the first request is handled
+	_, err = store.begin("key", request)
+	require.ErrorIs(t, err, errIdempotencyKeyInProgress)
+
+	store.finish("key", http.StatusCreated, []byte(`{"submitted":true}`))
+	replay, err = store.begin("key", request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, replay.status)
+	require.Equal(t, []byte(`{"submitted":true}`), replay.body)
+
+	_, err = store.begin("key", [32]byte{2})
+	require.ErrorIs(t, err, errIdempotencyKeyReused)
+
+	// Other keys are independent
+	replay, err = store.begin("other key", [32]byte{2})
+	require.NoError(t, err)
+	require.Nil(t, replay)
+
+	// Handled requests are forgotten after the TTL, but not those in progress
+	now = now.Add(time.Hour + time.Second)
+	replay, err = store.begin("key", [32]byte{2})
+	require.NoError(t, err)
+	require.Nil(t, replay)
+	_, err = store.begin("other key", [32]byte{2})
+	require.ErrorIs(t, err, errIdempotencyKeyInProgress)
+}
@@ -0,0 +1,157 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/amounts"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// endpoint is the token transferrer at one end of a transfer.
+type endpoint struct {
+	blockchainID ids.ID
+	address      common.Address
+	isHome       bool
+	// The settings of a remote, as registered with the home
+	settings tokenhome.RemoteTokenTransferrerSettings
+}
+
+// route returns how amounts are scaled between the home and the remote.
+func (e endpoint) route() amounts.Route {
+	return amounts.Route{
+		TokenMultiplier:  e.settings.TokenMultiplier,
+		MultiplyOnRemote: e.settings.MultiplyOnRemote,
+	}
+}
+
+// ready returns true for the home, and for remotes the home can send tokens to.
+func (e endpoint) ready() bool {
+	return e.isHome || (e.settings.Registered && e.settings.CollateralNeeded.Sign() == 0)
+}
+
+func (e endpoint) String() string {
+	return fmt.Sprintf("%s on %s", e.address, e.blockchainID)
+}
+
+// endpoint returns the token transferrer at the given address, reading its settings from the home for remotes.
+func (s *Server) endpoint(ctx context.Context, blockchainID ids.ID, address common.Address) (endpoint, error) {
+	e := endpoint{
+		blockchainID: blockchainID,
+		address:      address,
+		isHome:       blockchainID == s.config.HomeBlockchainID && address == s.config.HomeAddress,
+	}
+	if e.isHome {
+		return e, nil
+	}
+	home, err := tokenhome.NewTokenHomeCaller(s.config.HomeAddress, s.config.Chains[s.config.HomeBlockchainID])
+	if err != nil {
+		return e, err
+	}
+	e.settings, err = home.GetRemoteTokenTransferrerSettings(&bind.CallOpts{Context: ctx}, blockchainID, address)
+	if err != nil {
+		return e, fmt.Errorf("failed to get settings of remote %s: %w", e, err)
+	}
+	return e, nil
+}
+
+// quoteTransfer returns the outcome of a transfer of amount from source to destination, failing if
+// the token transferrers would reject it.
+func quoteTransfer(source, destination endpoint, amount, secondaryFee *big.Int) (*Quote, error) {
+	if amount.Sign() == 0 {
+		return nil, invalidRequest("zero amount")
+	}
+	if source.isHome && destination.isHome {
+		return nil, invalidRequest("the source and destination are both the home")
+	}
+	if !source.ready() {
+		return nil, unprocessable("source %s is not registered with the home, or needs collateral", source)
+	}
+	multiHop := !source.isHome && !destination.isHome
+	if !multiHop && secondaryFee.Sign() != 0 {
+		return nil, invalidRequest("non-zero secondary fee for a single-hop transfer")
+	}
+
+	if source.isHome {
+		if !destination.ready() {
+			return nil, unprocessable("destination %s is not registered with the home, or needs collateral", destination)
+		}
+		received := destination.route().RemoteValue(amount)
+		if received.Sign() == 0 {
+			return nil, unprocessable("%s is scaled to zero tokens of %s", amount, destination)
+		}
+		return &Quote{SentAmount: NewAmount(received), ReceivedAmount: NewAmount(received)}, nil
+	}
+
+	homeAmount := source.route().HomeValue(amount)
+	if !multiHop {
+		if homeAmount.Sign() == 0 {
+			return nil, unprocessable("%s is scaled to zero home tokens", amount)
+		}
+		return &Quote{SentAmount: NewAmount(amount), ReceivedAmount: NewAmount(homeAmount)}, nil
+	}
+
+	// The home deducts the secondary fee before scaling the amount to the destination,
+	// and sends the whole amount to the multi-hop fallback if the scaled amount is zero.
+	fee := source.route().HomeValue(secondaryFee)
+	if homeAmount.Cmp(fee) <= 0 {
+		return nil, unprocessable("%s does not cover the secondary fee of %s", amount, secondaryFee)
+	}
+	quote := &Quote{
+		MultiHop:       true,
+		SentAmount:     NewAmount(amount),
+		ReceivedAmount: NewAmount(new(big.Int)),
+	}
+	if destination.ready() {
+		quote.ReceivedAmount = NewAmount(destination.route().RemoteValue(new(big.Int).Sub(homeAmount, fee)))
+	}
+	if quote.ReceivedAmount.Int().Sign() == 0 {
+		quote.Fallback = true
+		quote.FallbackAmount = NewAmount(homeAmount)
+	}
+	return quote, nil
+}
+
+// quote returns the endpoints of the request and the outcome of the transfer between them.
+func (s *Server) quote(ctx context.Context, request *QuoteRequest) (endpoint, endpoint, *Quote, error) {
+	if request.Amount == nil {
+		return endpoint{}, endpoint{}, nil, invalidRequest("missing amount")
+	}
+	source, err := s.endpoint(ctx, request.SourceBlockchainID, request.Source)
+	if err != nil {
+		return endpoint{}, endpoint{}, nil, err
+	}
+	destination, err := s.endpoint(ctx, request.DestinationBlockchainID, request.Destination)
+	if err != nil {
+		return endpoint{}, endpoint{}, nil, err
+	}
+	quote, err := quoteTransfer(source, destination, request.Amount.Int(), request.SecondaryFee.Int())
+	return source, destination, quote, err
+}
+
+func (s *Server) handleQuote(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var request QuoteRequest
+	if err := decodeJSON(body, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+	_, _, quote, err := s.quote(r.Context(), &request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, quote)
+}
@@ -0,0 +1,187 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package gateway
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"testing"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/amounts"
+	"github.com/stretchr/testify/require"
+)
+
+// remoteEndpoint returns a registered remote with the given decimals, for an 18 decimal home.
+func remoteEndpoint(decimals uint8) endpoint {
+	route := amounts.NewRoute(18, decimals)
+	return endpoint{settings: tokenhome.RemoteTokenTransferrerSettings{
+		Registered:       true,
+		CollateralNeeded: new(big.Int),
+		TokenMultiplier:  route.TokenMultiplier,
+		MultiplyOnRemote: route.MultiplyOnRemote,
+	}}
+}
+
+func TestQuoteTransfer(t *testing.T) {
+	home := endpoint{isHome: true}
+	remote6 := remoteEndpoint(6)
+	remote18 := remoteEndpoint(18)
+	remote24 := remoteEndpoint(24)
+	unregistered := endpoint{settings: tokenhome.RemoteTokenTransferrerSettings{CollateralNeeded: new(big.Int)}}
+	collateralNeeded := remoteEndpoint(18)
+	collateralNeeded.settings.CollateralNeeded = big.NewInt(1)
+
+	testCases := []struct {
+		name         string
+		source       endpoint
+		destination  endpoint
+		amount       *big.Int
+		secondaryFee *big.Int
+		expected     *Quote
+		status       int
+	}{
+		{
+			name:        "home to remote with fewer decimals",
+			source:      home,
+			destination: remote6,
+			amount:      big.NewInt(3e12 + 1),
+			expected:    &Quote{SentAmount: NewAmount(big.NewInt(3)), ReceivedAmount: NewAmount(big.NewInt(3))},
+		},
+		{
+			name:        "home to remote with more decimals",
+			source:      home,
+			destination: remote24,
+			amount:      big.NewInt(5),
+			expected:    &Quote{SentAmount: NewAmount(big.NewInt(5e6)), ReceivedAmount: NewAmount(big.NewInt(5e6))},
+		},
+		{
+			name:        "home to remote scaled to zero",
+			source:      home,
+			destination: remote6,
+			amount:      big.NewInt(1e12 - 1),
+			status:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "home to remote needing collateral",
+			source:      home,
+			destination: collateralNeeded,
+			amount:      big.NewInt(1),
+			status:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "remote to home",
+			source:      remote24,
+			destination: home,
+			amount:      big.NewInt(7e6 + 1),
+			expected:    &Quote{SentAmount: NewAmount(big.NewInt(7e6 + 1)), ReceivedAmount: NewAmount(big.NewInt(7))},
+		},
+		{
+			name:        "remote to home scaled to zero",
+			source:      remote24,
+			destination: home,
+			amount:      big.NewInt(1e6 - 1),
+			status:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "single-hop with secondary fee",
+			source:       remote18,
+			destination:  home,
+			amount:       big.NewInt(10),
+			secondaryFee: big.NewInt(1),
+			status:       http.StatusBadRequest,
+		},
+		{
+			name:         "multi-hop",
+			source:       remote6,
+			destination:  remote24,
+			amount:       big.NewInt(10),
+			secondaryFee: big.NewInt(2),
+			expected: &Quote{
+				MultiHop:       true,
+				SentAmount:     NewAmount(big.NewInt(10)),
+				ReceivedAmount: NewAmount(big.NewInt(8e18)),
+			},
+		},
+		{
+			name:         "multi-hop scaled to zero",
+			source:       remote24,
+			destination:  remote6,
+			amount:       big.NewInt(2e18),
+			secondaryFee: big.NewInt(15e17),
+			expected: &Quote{
+				MultiHop:       true,
+				SentAmount:     NewAmount(big.NewInt(2e18)),
+				ReceivedAmount: NewAmount(new(big.Int)),
+				Fallback:       true,
+				FallbackAmount: NewAmount(big.NewInt(2e12)),
+			},
+		},
+		{
+			name:        "multi-hop to unregistered remote",
+			source:      remote18,
+			destination: unregistered,
+			amount:      big.NewInt(10),
+			expected: &Quote{
+				MultiHop:       true,
+				SentAmount:     NewAmount(big.NewInt(10)),
+				ReceivedAmount: NewAmount(new(big.Int)),
+				Fallback:       true,
+				FallbackAmount: NewAmount(big.NewInt(10)),
+			},
+		},
+		{
+			name:         "multi-hop not covering the secondary fee",
+			source:       remote18,
+			destination:  remote6,
+			amount:       big.NewInt(10),
+			secondaryFee: big.NewInt(10),
+			status:       http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "from unregistered remote",
+			source:      unregistered,
+			destination: home,
+			amount:      big.NewInt(10),
+			status:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "home to home",
+			source:      home,
+			destination: home,
+			amount:      big.NewInt(10),
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "zero amount",
+			source:      home,
+			destination: remote18,
+			amount:      new(big.Int),
+			status:      http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			secondaryFee := tc.secondaryFee
+			if secondaryFee == nil {
+				secondaryFee = new(big.Int)
+			}
+			quote, err := quoteTransfer(tc.source, tc.destination, tc.amount, secondaryFee)
+			if tc.status != 0 {
+				require.Error(t, err)
+				require.Equal(t, tc.status, errorStatus(err))
+				return
+			}
+			require.NoError(t, err)
+			// Compare the encoded quotes, since equal big.Ints may differ in their representation
+			expected, err := json.Marshal(tc.expected)
+			require.NoError(t, err)
+			actual, err := json.Marshal(quote)
+			require.NoError(t, err)
+			require.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
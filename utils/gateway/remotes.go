@@ -0,0 +1,82 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+)
+
+// remotes returns the home and the remotes registered with it.
+func (s *Server) remotes(ctx context.Context) (*Remotes, error) {
+	homeBackend := s.config.Chains[s.config.HomeBlockchainID]
+	home, err := s.tokenTransferrer(ctx, endpoint{
+		blockchainID: s.config.HomeBlockchainID,
+		address:      s.config.HomeAddress,
+		isHome:       true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !home.Kind.IsHome() {
+		return nil, fmt.Errorf("%s is a %s, not a token home", s.config.HomeAddress, home.Kind)
+	}
+
+	registered, err := portfolio.DiscoverRemotes(ctx, homeBackend, s.config.HomeAddress)
+	if err != nil {
+		return nil, err
+	}
+	remotes := &Remotes{Home: home, Remotes: make([]TokenTransferrer, 0, len(registered))}
+	for _, remote := range registered {
+		e, err := s.endpoint(ctx, remote.BlockchainID, remote.Address)
+		if err != nil {
+			return nil, err
+		}
+		transferrer, err := s.tokenTransferrer(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		remotes.Remotes = append(remotes.Remotes, transferrer)
+	}
+	return remotes, nil
+}
+
+// tokenTransferrer describes the token transferrer at the endpoint, inspecting it if its chain is configured.
+func (s *Server) tokenTransferrer(ctx context.Context, e endpoint) (TokenTransferrer, error) {
+	transferrer := TokenTransferrer{
+		BlockchainID: e.blockchainID,
+		Address:      e.address,
+	}
+	if !e.isHome {
+		transferrer.TokenMultiplier = NewAmount(e.settings.TokenMultiplier)
+		transferrer.MultiplyOnRemote = e.settings.MultiplyOnRemote
+		transferrer.CollateralNeeded = NewAmount(e.settings.CollateralNeeded)
+	}
+	backend, ok := s.config.Chains[e.blockchainID]
+	if !ok {
+		return transferrer, nil
+	}
+	info, err := inspect.Inspect(ctx, backend, e.address)
+	if err != nil {
+		return transferrer, fmt.Errorf("failed to inspect %s: %w", e, err)
+	}
+	transferrer.Reachable = true
+	transferrer.Kind = info.Kind
+	transferrer.TokenAddress = info.TokenAddress
+	transferrer.TokenDecimals = info.TokenDecimals
+	return transferrer, nil
+}
+
+func (s *Server) handleRemotes(w http.ResponseWriter, r *http.Request) {
+	remotes, err := s.remotes(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, remotes)
+}
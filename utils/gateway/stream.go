@@ -0,0 +1,804 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/subscription"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	teleportermessenger "github.com/ava-labs/teleporter/abi-bindings/go/teleporter/TeleporterMessenger"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Number of streamed events kept to resume the stream of reconnecting clients
+	streamHistory = 4096
+	// Number of transfers whose sender and recipient are kept, to report them with the later events of the transfers
+	maxTrackedTransfers = 16384
+	// Capacity of the channel of the events pushed to each client. Clients that fall further behind are
+	// disconnected, and can resume the stream from the last sequence they received.
+	clientBuffer     = 256
+	maxStreamFilters = 64
+	// Capacity of the channel of the logs of each chain
+	streamLogBuffer  = 64
+	lookupRetryDelay = time.Second
+
+	pingInterval = 30 * time.Second
+	pongWait     = 2 * pingInterval
+	writeWait    = 10 * time.Second
+)
+
+var (
+	errMissingDial      = errors.New("missing dial function")
+	errStreamRunning    = errors.New("transfer stream already running")
+	errStreamNotRunning = &requestError{
+		status: http.StatusServiceUnavailable,
+		err:    errors.New("transfer stream not running"),
+	}
+
+	// The events of the token transferrers and of Teleporter that the stream follows
+	transferrerEvents = []string{
+		"TokensSent",
+		"TokensAndCallSent",
+		"TokensRouted",
+		"TokensAndCallRouted",
+		"TokensWithdrawn",
+		"CallSucceeded",
+		"CallFailed",
+		"RemoteRegistered",
+	}
+	teleporterEvents = []string{"MessageExecuted", "MessageExecutionFailed"}
+
+	tokenHomeABI  = mustGetABI(tokenhome.TokenHomeMetaData)
+	teleporterABI = mustGetABI(teleportermessenger.TeleporterMessengerMetaData)
+
+	upgrader = websocket.Upgrader{}
+)
+
+// eventKey identifies the log of a streamed event.
+type eventKey struct {
+	blockchainID ids.ID
+	blockHash    common.Hash
+	index        uint
+}
+
+type transferrerKey struct {
+	blockchainID ids.ID
+	address      common.Address
+}
+
+// streamEntry is a streamed event, with the recipients it is matched against.
+type streamEntry struct {
+	event TransferEvent
+	key   eventKey
+	// The recipient of the event, and that of its transfer if it differs
+	recipients []common.Address
+}
+
+// trackedTransfer is a transfer whose later events are reported with its sender and recipient.
+type trackedTransfer struct {
+	messageID       ids.ID
+	routedMessageID *ids.ID
+	sender          *common.Address
+	recipient       common.Address
+}
+
+// streamFilter selects the events pushed to a client. An event matches if it matches any of the filters.
+type streamFilter struct {
+	senders    map[common.Address]bool
+	recipients map[common.Address]bool
+	messageIDs map[ids.ID]bool
+}
+
+func (f *streamFilter) matches(entry *streamEntry) bool {
+	event := &entry.event
+	if event.Sender != nil && f.senders[*event.Sender] {
+		return true
+	}
+	for _, recipient := range entry.recipients {
+		if f.recipients[recipient] {
+			return true
+		}
+	}
+	return f.messageIDs[event.MessageID] || (event.RoutedMessageID != nil && f.messageIDs[*event.RoutedMessageID])
+}
+
+// streamClient is a client of the stream.
+type streamClient struct {
+	filter streamFilter
+	// Closed when the client is disconnected by the stream, with the reason in closeCode and closeText
+	events    chan TransferEvent
+	closeCode int
+	closeText string
+}
+
+// transferStream follows the events of the transfers of the home and its remotes, and pushes them to its clients.
+type transferStream struct {
+	homeBlockchainID  ids.ID
+	homeAddress       common.Address
+	home              bind.ContractCaller
+	teleporterAddress common.Address
+
+	lock     sync.Mutex
+	running  bool
+	id       string
+	sequence uint64
+	history  []streamEntry
+	// The events in the history that can be retracted, by the key of their log
+	streamed map[eventKey]streamEntry
+	clients  map[*streamClient]struct{}
+
+	// Only used by the goroutine handling the logs
+	transferrers map[transferrerKey]bool
+	tracked      map[ids.ID]*trackedTransfer
+	trackedOrder []ids.ID
+	// The logs of token transferrers in the transaction last seen on each chain, until the Teleporter
+	// message whose execution emitted them is known
+	txLogs map[ids.ID][]types.Log
+}
+
+func newTransferStream(config Config) *transferStream {
+	t := &transferStream{
+		homeBlockchainID:  config.HomeBlockchainID,
+		homeAddress:       config.HomeAddress,
+		home:              config.Chains[config.HomeBlockchainID],
+		teleporterAddress: config.TeleporterAddress,
+		clients:           make(map[*streamClient]struct{}),
+	}
+	t.reset()
+	return t
+}
+
+// reset starts a new stream, with a new ID and no history.
+func (t *transferStream) reset() {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	t.id = hex.EncodeToString(id)
+	t.sequence = 0
+	t.history = nil
+	t.streamed = make(map[eventKey]streamEntry)
+	t.transferrers = make(map[transferrerKey]bool)
+	t.tracked = make(map[ids.ID]*trackedTransfer)
+	t.trackedOrder = nil
+	t.txLogs = make(map[ids.ID][]types.Log)
+}
+
+// RunStream follows the events of the transfers of the home and its remotes on the chains of the config,
+// and pushes them to the clients of /transfers/stream, until ctx is done or the subscription to the events
+// of a chain fails. Each run starts a new stream, from the last LookBackBlocks blocks of each chain.
+func (s *Server) RunStream(ctx context.Context) error {
+	if s.config.Dial == nil {
+		return errMissingDial
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	query, err := subscription.EventQuery(tokenHomeABI, nil, transferrerEvents...)
+	if err != nil {
+		return err
+	}
+	teleporterQuery, err := subscription.EventQuery(teleporterABI, nil, teleporterEvents...)
+	if err != nil {
+		return err
+	}
+	// Token transferrers are only known once they are registered with the home, so the logs of every
+	// address are followed, and those that are not from the home or one of its remotes are ignored.
+	query.Topics[0] = append(query.Topics[0], teleporterQuery.Topics[0]...)
+
+	subscribers := make(map[ids.ID]*subscription.Subscriber, len(s.config.Chains))
+	for blockchainID, backend := range s.config.Chains {
+		fromBlock, err := lookBackStart(ctx, backend, s.config.LookBackBlocks)
+		if err != nil {
+			return fmt.Errorf("failed to get head of %s: %w", blockchainID, err)
+		}
+		blockchainID := blockchainID
+		subscribers[blockchainID], err = subscription.NewSubscriber(subscription.Config{
+			Query:         query,
+			Confirmations: s.config.Confirmations,
+			FromBlock:     fromBlock,
+			Dial: func(ctx context.Context) (subscription.Backend, error) {
+				return s.config.Dial(ctx, blockchainID)
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.stream.start(); err != nil {
+		return err
+	}
+	defer s.stream.stop()
+
+	logs := make(chan chainLog, streamLogBuffer)
+	errs := make(chan error, len(subscribers))
+	for blockchainID, subscriber := range subscribers {
+		go func(blockchainID ids.ID, subscriber *subscription.Subscriber) {
+			errs <- forwardLogs(ctx, blockchainID, subscriber, logs)
+		}(blockchainID, subscriber)
+	}
+	for {
+		select {
+		case err := <-errs:
+			return err
+		case l := <-logs:
+			if err := s.stream.handleLog(ctx, l.blockchainID, l.log); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// chainLog is a log of the chain with the given ID.
+type chainLog struct {
+	blockchainID ids.ID
+	log          types.Log
+}
+
+// forwardLogs runs the subscriber, and forwards its logs to sink.
+func forwardLogs(
+	ctx context.Context,
+	blockchainID ids.ID,
+	subscriber *subscription.Subscriber,
+	sink chan<- chainLog,
+) error {
+	logs := make(chan types.Log, streamLogBuffer)
+	subscriberErr := make(chan error, 1)
+	go func() {
+		subscriberErr <- subscriber.Run(ctx, logs)
+	}()
+	for {
+		select {
+		case err := <-subscriberErr:
+			return fmt.Errorf("subscription to %s failed: %w", blockchainID, err)
+		case l := <-logs:
+			select {
+			case sink <- chainLog{blockchainID: blockchainID, log: l}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// lookBackStart returns the first block of the last lookBackBlocks blocks, or genesis if lookBackBlocks is zero.
+func lookBackStart(ctx context.Context, backend Backend, lookBackBlocks uint64) (uint64, error) {
+	if lookBackBlocks == 0 {
+		return 0, nil
+	}
+	latest, err := backend.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if latest > lookBackBlocks {
+		return latest - lookBackBlocks, nil
+	}
+	return 0, nil
+}
+
+func (t *transferStream) start() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.running {
+		return errStreamRunning
+	}
+	t.reset()
+	t.running = true
+	return nil
+}
+
+// stop disconnects the clients of the stream.
+func (t *transferStream) stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.running = false
+	for client := range t.clients {
+		t.disconnect(client, websocket.CloseGoingAway, "transfer stream stopped")
+	}
+}
+
+// disconnect closes the events of the client. The lock must be held.
+func (t *transferStream) disconnect(client *streamClient, code int, text string) {
+	client.closeCode = code
+	client.closeText = text
+	close(client.events)
+	delete(t.clients, client)
+}
+
+// handleLog streams the events of a log of the given chain.
+func (t *transferStream) handleLog(ctx context.Context, blockchainID ids.ID, l types.Log) error {
+	if len(l.Topics) == 0 {
+		return nil
+	}
+	if l.Removed {
+		t.retract(eventKey{blockchainID: blockchainID, blockHash: l.BlockHash, index: l.Index})
+		return nil
+	}
+	if l.Address == t.teleporterAddress {
+		return t.handleTeleporterLog(ctx, blockchainID, l)
+	}
+	isTransferrer, err := t.isTransferrer(ctx, blockchainID, l.Address)
+	if err != nil || !isTransferrer {
+		return err
+	}
+
+	key := eventKey{blockchainID: blockchainID, blockHash: l.BlockHash, index: l.Index}
+	event := TransferEvent{
+		BlockchainID: blockchainID,
+		Address:      l.Address,
+		TxHash:       l.TxHash,
+		BlockNumber:  l.BlockNumber,
+	}
+	switch l.Topics[0] {
+	case tokenHomeABI.Events["TokensSent"].ID:
+		var sent tokenhome.TokenHomeTokensSent
+		if err := parseLog(tokenHomeABI, &sent, "TokensSent", l); err != nil {
+			return nil
+		}
+		event.Event = EventTokensSent
+		event.MessageID = sent.TeleporterMessageID
+		event.Sender = &sent.Sender
+		event.Recipient = sent.Input.Recipient
+		event.Amount = NewAmount(sent.Amount)
+	case tokenHomeABI.Events["TokensAndCallSent"].ID:
+		var sent tokenhome.TokenHomeTokensAndCallSent
+		if err := parseLog(tokenHomeABI, &sent, "TokensAndCallSent", l); err != nil {
+			return nil
+		}
+		event.Event = EventTokensAndCallSent
+		event.MessageID = sent.TeleporterMessageID
+		event.Sender = &sent.Sender
+		event.Recipient = sent.Input.RecipientContract
+		event.Amount = NewAmount(sent.Amount)
+	case tokenHomeABI.Events["RemoteRegistered"].ID:
+		var registered tokenhome.TokenHomeRemoteRegistered
+		if blockchainID != t.homeBlockchainID || parseLog(tokenHomeABI, &registered, "RemoteRegistered", l) != nil {
+			return nil
+		}
+		t.transferrers[transferrerKey{
+			blockchainID: registered.RemoteBlockchainID,
+			address:      registered.RemoteTokenTransferrerAddress,
+		}] = true
+		return nil
+	default:
+		// Emitted while a message is executed, so they are streamed once the message is known
+		txLogs := t.txLogs[blockchainID]
+		if len(txLogs) > 0 && txLogs[0].TxHash != l.TxHash {
+			txLogs = nil
+		}
+		t.txLogs[blockchainID] = append(txLogs, l)
+		return nil
+	}
+	if transfer, ok := t.tracked[event.MessageID]; ok {
+		// The home routed the transfer before its source chain was followed up to it
+		transfer.sender = event.Sender
+	} else {
+		t.track(&trackedTransfer{messageID: event.MessageID, sender: event.Sender, recipient: event.Recipient})
+	}
+	t.publish(key, event, event.Recipient)
+	return nil
+}
+
+// handleTeleporterLog streams the events emitted by the execution of a message sent to a token transferrer.
+func (t *transferStream) handleTeleporterLog(ctx context.Context, blockchainID ids.ID, l types.Log) error {
+	txLogs := t.txLogs[blockchainID]
+	delete(t.txLogs, blockchainID)
+	switch l.Topics[0] {
+	case teleporterABI.Events["MessageExecuted"].ID:
+		var executed teleportermessenger.TeleporterMessengerMessageExecuted
+		if err := parseLog(teleporterABI, &executed, "MessageExecuted", l); err != nil {
+			return nil
+		}
+		for _, txLog := range txLogs {
+			if txLog.TxHash == l.TxHash {
+				t.handleExecutionLog(blockchainID, executed.MessageID, txLog)
+			}
+		}
+	case teleporterABI.Events["MessageExecutionFailed"].ID:
+		var failed teleportermessenger.TeleporterMessengerMessageExecutionFailed
+		if err := parseLog(teleporterABI, &failed, "MessageExecutionFailed", l); err != nil {
+			return nil
+		}
+		isTransferrer, err := t.isTransferrer(ctx, blockchainID, failed.Message.DestinationAddress)
+		if err != nil || !isTransferrer {
+			return err
+		}
+		event := TransferEvent{
+			Event:        EventExecutionFailed,
+			MessageID:    failed.MessageID,
+			BlockchainID: blockchainID,
+			Address:      failed.Message.DestinationAddress,
+			TxHash:       l.TxHash,
+			BlockNumber:  l.BlockNumber,
+		}
+		t.publishTransferEvent(eventKey{blockchainID: blockchainID, blockHash: l.BlockHash, index: l.Index}, event)
+	}
+	return nil
+}
+
+// handleExecutionLog streams the event of a log emitted by a token transferrer while executing the given message.
+func (t *transferStream) handleExecutionLog(blockchainID ids.ID, messageID ids.ID, l types.Log) {
+	event := TransferEvent{
+		MessageID:    messageID,
+		BlockchainID: blockchainID,
+		Address:      l.Address,
+		TxHash:       l.TxHash,
+		BlockNumber:  l.BlockNumber,
+	}
+	var routedMessageID ids.ID
+	switch l.Topics[0] {
+	case tokenHomeABI.Events["TokensRouted"].ID:
+		var routed tokenhome.TokenHomeTokensRouted
+		if err := parseLog(tokenHomeABI, &routed, "TokensRouted", l); err != nil {
+			return
+		}
+		event.Event = EventTokensRouted
+		routedMessageID = routed.TeleporterMessageID
+		event.Recipient = routed.Input.Recipient
+		event.Amount = NewAmount(routed.Amount)
+	case tokenHomeABI.Events["TokensAndCallRouted"].ID:
+		var routed tokenhome.TokenHomeTokensAndCallRouted
+		if err := parseLog(tokenHomeABI, &routed, "TokensAndCallRouted", l); err != nil {
+			return
+		}
+		event.Event = EventTokensAndCallRouted
+		routedMessageID = routed.TeleporterMessageID
+		event.Recipient = routed.Input.RecipientContract
+		event.Amount = NewAmount(routed.Amount)
+	case tokenHomeABI.Events["TokensWithdrawn"].ID:
+		var withdrawn tokenhome.TokenHomeTokensWithdrawn
+		if err := parseLog(tokenHomeABI, &withdrawn, "TokensWithdrawn", l); err != nil {
+			return
+		}
+		event.Event = EventTokensWithdrawn
+		event.Recipient = withdrawn.Recipient
+		event.Amount = NewAmount(withdrawn.Amount)
+	case tokenHomeABI.Events["CallSucceeded"].ID:
+		var succeeded tokenhome.TokenHomeCallSucceeded
+		if err := parseLog(tokenHomeABI, &succeeded, "CallSucceeded", l); err != nil {
+			return
+		}
+		event.Event = EventCallSucceeded
+		event.Recipient = succeeded.RecipientContract
+		event.Amount = NewAmount(succeeded.Amount)
+	case tokenHomeABI.Events["CallFailed"].ID:
+		var failed tokenhome.TokenHomeCallFailed
+		if err := parseLog(tokenHomeABI, &failed, "CallFailed", l); err != nil {
+			return
+		}
+		event.Event = EventCallFailed
+		event.Recipient = failed.RecipientContract
+		event.Amount = NewAmount(failed.Amount)
+	default:
+		return
+	}
+
+	key := eventKey{blockchainID: blockchainID, blockHash: l.BlockHash, index: l.Index}
+	if routedMessageID == ids.Empty {
+		t.publishTransferEvent(key, event)
+		return
+	}
+	// The second hop is tracked as part of the transfer of the first hop
+	routed := &trackedTransfer{messageID: messageID, routedMessageID: &routedMessageID, recipient: event.Recipient}
+	if transfer, ok := t.tracked[messageID]; ok {
+		routed.sender = transfer.sender
+	}
+	t.track(routed)
+	t.tracked[routedMessageID] = routed
+	event.RoutedMessageID = &routedMessageID
+	event.Sender = routed.sender
+	t.publish(key, event, event.Recipient)
+}
+
+// publishTransferEvent streams an event of the transfer of the event's message, reported with the sender
+// and recipient of the transfer if it is tracked.
+func (t *transferStream) publishTransferEvent(key eventKey, event TransferEvent) {
+	transfer, ok := t.tracked[event.MessageID]
+	if !ok {
+		t.publish(key, event, event.Recipient)
+		return
+	}
+	event.MessageID = transfer.messageID
+	event.RoutedMessageID = transfer.routedMessageID
+	event.Sender = transfer.sender
+	if event.Recipient == (common.Address{}) {
+		event.Recipient = transfer.recipient
+	}
+	t.publish(key, event, event.Recipient, transfer.recipient)
+}
+
+// track keeps the transfer of a message, forgetting the oldest transfers beyond maxTrackedTransfers.
+func (t *transferStream) track(transfer *trackedTransfer) {
+	messageID := transfer.messageID
+	if _, ok := t.tracked[messageID]; !ok {
+		t.trackedOrder = append(t.trackedOrder, messageID)
+	}
+	t.tracked[messageID] = transfer
+	for len(t.trackedOrder) > maxTrackedTransfers {
+		oldest := t.tracked[t.trackedOrder[0]]
+		delete(t.tracked, t.trackedOrder[0])
+		if oldest != nil && oldest.routedMessageID != nil {
+			delete(t.tracked, *oldest.routedMessageID)
+		}
+		t.trackedOrder = t.trackedOrder[1:]
+	}
+}
+
+// isTransferrer returns true if the address is the home, or a remote registered with it. Lookups that
+// fail are retried until they succeed or ctx is done, so that no event is missed.
+func (t *transferStream) isTransferrer(ctx context.Context, blockchainID ids.ID, address common.Address) (bool, error) {
+	if blockchainID == t.homeBlockchainID && address == t.homeAddress {
+		return true, nil
+	}
+	key := transferrerKey{blockchainID: blockchainID, address: address}
+	if registered, ok := t.transferrers[key]; ok {
+		return registered, nil
+	}
+	home, err := tokenhome.NewTokenHomeCaller(t.homeAddress, t.home)
+	if err != nil {
+		return false, err
+	}
+	for {
+		// A remote cannot emit events before it is registered, so the result is final.
+		settings, err := home.GetRemoteTokenTransferrerSettings(&bind.CallOpts{Context: ctx}, blockchainID, address)
+		if err == nil {
+			t.transferrers[key] = settings.Registered
+			return settings.Registered, nil
+		}
+		log.Warn("Failed to get remote settings, retrying", "blockchainID", blockchainID, "address", address, "err", err)
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(lookupRetryDelay):
+		}
+	}
+}
+
+// publish appends the event to the stream, and pushes it to the clients it matches.
+func (t *transferStream) publish(key eventKey, event TransferEvent, recipients ...common.Address) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.publishLocked(&streamEntry{event: event, key: key, recipients: recipients})
+}
+
+// retract streams the retraction of the event of a log whose block was reorged out, if it is in the history.
+func (t *transferStream) retract(key eventKey) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	entry, ok := t.streamed[key]
+	if !ok {
+		return
+	}
+	delete(t.streamed, key)
+	entry.event.Removed = true
+	t.publishLocked(&entry)
+}
+
+func (t *transferStream) publishLocked(entry *streamEntry) {
+	t.sequence++
+	entry.event.StreamID = t.id
+	entry.event.Sequence = t.sequence
+	t.history = append(t.history, *entry)
+	if !entry.event.Removed {
+		t.streamed[entry.key] = *entry
+	}
+	if len(t.history) > streamHistory {
+		oldest := t.history[0]
+		if streamed, ok := t.streamed[oldest.key]; ok && streamed.event.Sequence == oldest.event.Sequence {
+			delete(t.streamed, oldest.key)
+		}
+		t.history = t.history[1:]
+	}
+
+	for client := range t.clients {
+		if !client.filter.matches(entry) {
+			continue
+		}
+		select {
+		case client.events <- entry.event:
+		default:
+			t.disconnect(client, websocket.CloseTryAgainLater, "client fell behind the transfer stream")
+		}
+	}
+}
+
+// subscribe registers a client for the events matching the filter, and returns the events of the history
+// after the given sequence that match it.
+func (t *transferStream) subscribe(
+	filter streamFilter,
+	streamID string,
+	after uint64,
+) (*streamClient, []TransferEvent, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if !t.running {
+		return nil, nil, errStreamNotRunning
+	}
+	if streamID != "" && streamID != t.id {
+		return nil, nil, &requestError{
+			status: http.StatusGone,
+			err:    fmt.Errorf("stream %s was restarted as %s", streamID, t.id),
+		}
+	}
+	if after > t.sequence {
+		return nil, nil, invalidRequest("sequence %d is after the last event %d", after, t.sequence)
+	}
+	if after > 0 && (len(t.history) == 0 || after+1 < t.history[0].event.Sequence) {
+		return nil, nil, &requestError{
+			status: http.StatusGone,
+			err:    fmt.Errorf("events after sequence %d are no longer retained", after),
+		}
+	}
+
+	var backlog []TransferEvent
+	for i := range t.history {
+		entry := &t.history[i]
+		if entry.event.Sequence > after && filter.matches(entry) {
+			backlog = append(backlog, entry.event)
+		}
+	}
+	client := &streamClient{filter: filter, events: make(chan TransferEvent, clientBuffer)}
+	t.clients[client] = struct{}{}
+	return client, backlog, nil
+}
+
+func (t *transferStream) unsubscribe(client *streamClient) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.clients, client)
+}
+
+// parseStreamQuery returns the filter, stream ID and sequence to resume after of a stream request.
+func parseStreamQuery(query url.Values) (streamFilter, string, uint64, error) {
+	filter := streamFilter{
+		senders:    make(map[common.Address]bool),
+		recipients: make(map[common.Address]bool),
+		messageIDs: make(map[ids.ID]bool),
+	}
+	var (
+		streamID string
+		after    uint64
+		filters  int
+	)
+	for name, values := range query {
+		switch name {
+		case "sender", "recipient":
+			for _, value := range values {
+				if !common.IsHexAddress(value) {
+					return filter, "", 0, invalidRequest("invalid %s %q", name, value)
+				}
+				if name == "sender" {
+					filter.senders[common.HexToAddress(value)] = true
+				} else {
+					filter.recipients[common.HexToAddress(value)] = true
+				}
+			}
+			filters += len(values)
+		case "messageID":
+			for _, value := range values {
+				messageID, err := parseMessageID(value)
+				if err != nil {
+					return filter, "", 0, invalidRequest("invalid message ID: %w", err)
+				}
+				filter.messageIDs[messageID] = true
+			}
+			filters += len(values)
+		case "streamID":
+			streamID = values[0]
+		case "after":
+			var err error
+			after, err = strconv.ParseUint(values[0], 10, 64)
+			if err != nil {
+				return filter, "", 0, invalidRequest("invalid sequence %q", values[0])
+			}
+		default:
+			return filter, "", 0, invalidRequest("unknown parameter %q", name)
+		}
+	}
+	if filters == 0 {
+		return filter, "", 0, invalidRequest("missing sender, recipient or messageID")
+	}
+	if filters > maxStreamFilters {
+		return filter, "", 0, invalidRequest("more than %d senders, recipients and message IDs", maxStreamFilters)
+	}
+	return filter, streamID, after, nil
+}
+
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	filter, streamID, after, err := parseStreamQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	client, backlog, err := s.stream.subscribe(filter, streamID, after)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer s.stream.unsubscribe(client)
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader replied with the error
+		return
+	}
+	defer conn.Close()
+	serveClient(conn, client, backlog)
+}
+
+// serveClient pushes the backlog and then the events of the client to its connection, until either
+// disconnects.
+func serveClient(conn *websocket.Conn, client *streamClient, backlog []TransferEvent) {
+	// Read until the client disconnects, so that its control messages are handled
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		conn.SetReadLimit(maxRequestSize)
+		_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for _, event := range backlog {
+		if err := writeEvent(conn, event); err != nil {
+			return
+		}
+	}
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-disconnected:
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case event, ok := <-client.events:
+			if !ok {
+				message := websocket.FormatCloseMessage(client.closeCode, client.closeText)
+				_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeWait))
+				return
+			}
+			if err := writeEvent(conn, event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(conn *websocket.Conn, event TransferEvent) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+		return err
+	}
+	return conn.WriteJSON(event)
+}
+
+// parseLog unpacks a log of the given event of the contract ABI into out.
+func parseLog(contractABI *abi.ABI, out interface{}, event string, l types.Log) error {
+	contract := bind.NewBoundContract(common.Address{}, *contractABI, nil, nil, nil)
+	return contract.UnpackLog(out, event, l)
+}
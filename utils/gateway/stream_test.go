@@ -0,0 +1,369 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package gateway
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/core/types"
+	teleportermessenger "github.com/ava-labs/teleporter/abi-bindings/go/teleporter/TeleporterMessenger"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testHomeBlockchainID = ids.ID{1}
+	testHomeAddress      = common.Address{1}
+	testRemoteA          = transferrerKey{blockchainID: ids.ID{2}, address: common.Address{2}}
+	testRemoteB          = transferrerKey{blockchainID: ids.ID{3}, address: common.Address{3}}
+	testTeleporter       = common.Address{4}
+	testSender           = common.Address{5}
+	testRecipient        = common.Address{6}
+)
+
+// newTestStream returns a running stream of a home with two registered remotes.
+func newTestStream(t *testing.T) *transferStream {
+	stream := newTransferStream(Config{
+		HomeBlockchainID:  testHomeBlockchainID,
+		HomeAddress:       testHomeAddress,
+		TeleporterAddress: testTeleporter,
+	})
+	require.NoError(t, stream.start())
+	stream.transferrers[testRemoteA] = true
+	stream.transferrers[testRemoteB] = true
+	return stream
+}
+
+// eventLog returns a log of the event of the contract ABI, with the arguments of its inputs in order.
+func eventLog(
+	t *testing.T,
+	contractABI *abi.ABI,
+	name string,
+	address common.Address,
+	txHash common.Hash,
+	index uint,
+	args ...interface{},
+) types.Log {
+	event := contractABI.Events[name]
+	topics := []common.Hash{event.ID}
+	var data []interface{}
+	for i, input := range event.Inputs {
+		if !input.Indexed {
+			data = append(data, args[i])
+			continue
+		}
+		topic, err := abi.MakeTopics([]interface{}{args[i]})
+		require.NoError(t, err)
+		topics = append(topics, topic[0][0])
+	}
+	packed, err := event.Inputs.NonIndexed().Pack(data...)
+	require.NoError(t, err)
+	return types.Log{
+		Address:     address,
+		Topics:      topics,
+		Data:        packed,
+		BlockNumber: 1,
+		BlockHash:   common.Hash{1},
+		TxHash:      txHash,
+		Index:       index,
+	}
+}
+
+// streamedEvents returns the events of the history of the stream.
+func streamedEvents(stream *transferStream) []TransferEvent {
+	var events []TransferEvent
+	for _, entry := range stream.history {
+		events = append(events, entry.event)
+	}
+	return events
+}
+
+func TestTransferStreamMultiHop(t *testing.T) {
+	stream := newTestStream(t)
+	ctx := context.Background()
+	firstHop := common.Hash{0x10}
+	secondHop := common.Hash{0x11}
+	input := tokenhome.SendTokensInput{
+		DestinationBlockchainID:            testRemoteB.blockchainID,
+		DestinationTokenTransferrerAddress: testRemoteB.address,
+		Recipient:                          testRecipient,
+		PrimaryFee:                         new(big.Int),
+		SecondaryFee:                       new(big.Int),
+		RequiredGasLimit:                   big.NewInt(100_000),
+		MultiHopFallback:                   testSender,
+	}
+
+	logs := []struct {
+		blockchainID ids.ID
+		log          types.Log
+	}{
+		{
+			blockchainID: testRemoteA.blockchainID,
+			log: eventLog(t, tokenHomeABI, "TokensSent", testRemoteA.address, common.Hash{0xa1}, 0,
+				firstHop, testSender, input, big.NewInt(10)),
+		},
+		// The home routes the first hop to remote B
+		{
+			blockchainID: testHomeBlockchainID,
+			log: eventLog(t, tokenHomeABI, "TokensRouted", testHomeAddress, common.Hash{0xb1}, 0,
+				secondHop, input, big.NewInt(9)),
+		},
+		{
+			blockchainID: testHomeBlockchainID,
+			log: eventLog(t, teleporterABI, "MessageExecuted", testTeleporter, common.Hash{0xb1}, 1,
+				firstHop, common.Hash(testRemoteA.blockchainID)),
+		},
+		// Messages to other contracts are ignored
+		{
+			blockchainID: testRemoteB.blockchainID,
+			log: eventLog(t, teleporterABI, "MessageExecuted", testTeleporter, common.Hash{0xc1}, 0,
+				common.Hash{0x12}, common.Hash(testHomeBlockchainID)),
+		},
+		// Remote B delivers the second hop
+		{
+			blockchainID: testRemoteB.blockchainID,
+			log: eventLog(t, tokenHomeABI, "TokensWithdrawn", testRemoteB.address, common.Hash{0xc2}, 0,
+				testRecipient, big.NewInt(9)),
+		},
+		{
+			blockchainID: testRemoteB.blockchainID,
+			log: eventLog(t, teleporterABI, "MessageExecuted", testTeleporter, common.Hash{0xc2}, 1,
+				secondHop, common.Hash(testHomeBlockchainID)),
+		},
+	}
+	for _, l := range logs {
+		require.NoError(t, stream.handleLog(ctx, l.blockchainID, l.log))
+	}
+
+	routed := ids.ID(secondHop)
+	sender := testSender
+	expected := []TransferEvent{
+		{
+			Event:        EventTokensSent,
+			MessageID:    ids.ID(firstHop),
+			BlockchainID: testRemoteA.blockchainID,
+			Address:      testRemoteA.address,
+			Sender:       &sender,
+			Recipient:    testRecipient,
+			Amount:       NewAmount(big.NewInt(10)),
+			TxHash:       common.Hash{0xa1},
+		},
+		{
+			Event:           EventTokensRouted,
+			MessageID:       ids.ID(firstHop),
+			RoutedMessageID: &routed,
+			BlockchainID:    testHomeBlockchainID,
+			Address:         testHomeAddress,
+			Sender:          &sender,
+			Recipient:       testRecipient,
+			Amount:          NewAmount(big.NewInt(9)),
+			TxHash:          common.Hash{0xb1},
+		},
+		{
+			Event:           EventTokensWithdrawn,
+			MessageID:       ids.ID(firstHop),
+			RoutedMessageID: &routed,
+			BlockchainID:    testRemoteB.blockchainID,
+			Address:         testRemoteB.address,
+			Sender:          &sender,
+			Recipient:       testRecipient,
+			Amount:          NewAmount(big.NewInt(9)),
+			TxHash:          common.Hash{0xc2},
+		},
+	}
+	events := streamedEvents(stream)
+	require.Len(t, events, len(expected))
+	for i := range expected {
+		expected[i].StreamID = stream.id
+		expected[i].Sequence = uint64(i + 1)
+		expected[i].BlockNumber = 1
+		require.Equal(t, expected[i].Amount.Int().String(), events[i].Amount.Int().String())
+		expected[i].Amount = events[i].Amount
+		require.Equal(t, expected[i], events[i])
+	}
+
+	// The withdrawal is retracted when its block is reorged out
+	removed := logs[4].log
+	removed.Removed = true
+	require.NoError(t, stream.handleLog(ctx, testRemoteB.blockchainID, removed))
+	events = streamedEvents(stream)
+	require.Len(t, events, 4)
+	retraction := expected[2]
+	retraction.Removed = true
+	retraction.Sequence = 4
+	require.Equal(t, retraction, events[3])
+	// A retraction is only streamed once
+	require.NoError(t, stream.handleLog(ctx, testRemoteB.blockchainID, removed))
+	require.Len(t, stream.history, 4)
+}
+
+func TestTransferStreamExecutionFailed(t *testing.T) {
+	stream := newTestStream(t)
+	ctx := context.Background()
+	messageID := common.Hash{0x20}
+	input := tokenhome.SendAndCallInput{
+		DestinationBlockchainID:            testRemoteA.blockchainID,
+		DestinationTokenTransferrerAddress: testRemoteA.address,
+		RecipientContract:                  testRecipient,
+		RecipientPayload:                   []byte{1},
+		RequiredGasLimit:                   big.NewInt(200_000),
+		RecipientGasLimit:                  big.NewInt(100_000),
+		FallbackRecipient:                  testSender,
+		PrimaryFee:                         new(big.Int),
+		SecondaryFee:                       new(big.Int),
+	}
+	message := teleportermessenger.TeleporterMessage{
+		MessageNonce:            big.NewInt(1),
+		OriginSenderAddress:     testHomeAddress,
+		DestinationBlockchainID: testRemoteA.blockchainID,
+		DestinationAddress:      testRemoteA.address,
+		RequiredGasLimit:        big.NewInt(200_000),
+		AllowedRelayerAddresses: []common.Address{},
+		Receipts:                []teleportermessenger.TeleporterMessageReceipt{},
+		Message:                 []byte{},
+	}
+
+	require.NoError(t, stream.handleLog(ctx, testHomeBlockchainID, eventLog(
+		t, tokenHomeABI, "TokensAndCallSent", testHomeAddress, common.Hash{0xa1}, 0,
+		messageID, testSender, input, big.NewInt(10),
+	)))
+	require.NoError(t, stream.handleLog(ctx, testRemoteA.blockchainID, eventLog(
+		t, teleporterABI, "MessageExecutionFailed", testTeleporter, common.Hash{0xb1}, 0,
+		messageID, common.Hash(testHomeBlockchainID), message,
+	)))
+	// Failed executions of messages to other contracts are ignored
+	message.DestinationAddress = common.Address{0xff}
+	other := transferrerKey{blockchainID: testRemoteA.blockchainID, address: message.DestinationAddress}
+	stream.transferrers[other] = false
+	require.NoError(t, stream.handleLog(ctx, testRemoteA.blockchainID, eventLog(
+		t, teleporterABI, "MessageExecutionFailed", testTeleporter, common.Hash{0xb2}, 0,
+		common.Hash{0x21}, common.Hash(testHomeBlockchainID), message,
+	)))
+
+	events := streamedEvents(stream)
+	require.Len(t, events, 2)
+	require.Equal(t, EventTokensAndCallSent, events[0].Event)
+	require.Equal(t, EventExecutionFailed, events[1].Event)
+	require.Equal(t, ids.ID(messageID), events[1].MessageID)
+	require.Equal(t, testRemoteA.address, events[1].Address)
+	require.Equal(t, &testSender, events[1].Sender)
+	require.Equal(t, testRecipient, events[1].Recipient)
+	require.Nil(t, events[1].Amount)
+}
+
+func TestTransferStreamSubscribe(t *testing.T) {
+	stream := newTestStream(t)
+	sender := testSender
+	for i := byte(0); i < 4; i++ {
+		// Events of a transfer sent by testSender, and of a transfer to testRecipient
+		stream.publish(eventKey{index: uint(i)}, TransferEvent{MessageID: ids.ID{i}, Sender: &sender}, common.Address{i})
+		stream.publish(eventKey{index: uint(i) + 10}, TransferEvent{MessageID: ids.ID{i + 10}}, testRecipient)
+	}
+	bySender := streamFilter{senders: map[common.Address]bool{testSender: true}}
+	byRecipient := streamFilter{recipients: map[common.Address]bool{testRecipient: true}}
+	byMessageID := streamFilter{messageIDs: map[ids.ID]bool{{2}: true, {12}: true}}
+
+	testCases := []struct {
+		name      string
+		filter    streamFilter
+		streamID  string
+		after     uint64
+		sequences []uint64
+		status    int
+	}{
+		{name: "by sender", filter: bySender, sequences: []uint64{1, 3, 5, 7}},
+		{name: "by recipient", filter: byRecipient, sequences: []uint64{2, 4, 6, 8}},
+		{name: "by message ID", filter: byMessageID, sequences: []uint64{5, 6}},
+		{name: "resumed", filter: bySender, streamID: stream.id, after: 3, sequences: []uint64{5, 7}},
+		{name: "resumed at the last event", filter: bySender, streamID: stream.id, after: 8},
+		{name: "after the last event", filter: bySender, after: 9, status: http.StatusBadRequest},
+		{name: "restarted stream", filter: bySender, streamID: "restarted", after: 3, status: http.StatusGone},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, backlog, err := stream.subscribe(tc.filter, tc.streamID, tc.after)
+			if tc.status != 0 {
+				require.Equal(t, tc.status, errorStatus(err))
+				return
+			}
+			require.NoError(t, err)
+			defer stream.unsubscribe(client)
+			var sequences []uint64
+			for _, event := range backlog {
+				sequences = append(sequences, event.Sequence)
+			}
+			require.Equal(t, tc.sequences, sequences)
+		})
+	}
+
+	// Events beyond the history cannot be resumed
+	for i := 0; i < streamHistory; i++ {
+		stream.publish(eventKey{}, TransferEvent{})
+	}
+	_, _, err := stream.subscribe(bySender, stream.id, 3)
+	require.Equal(t, http.StatusGone, errorStatus(err))
+
+	// Clients that fall behind are disconnected
+	client, _, err := stream.subscribe(byRecipient, "", 0)
+	require.NoError(t, err)
+	for i := 0; i <= clientBuffer; i++ {
+		stream.publish(eventKey{}, TransferEvent{}, testRecipient)
+	}
+	require.Len(t, client.events, clientBuffer)
+	require.Equal(t, websocket.CloseTryAgainLater, client.closeCode)
+
+	stream.stop()
+	_, _, err = stream.subscribe(bySender, "", 0)
+	require.Equal(t, http.StatusServiceUnavailable, errorStatus(err))
+}
+
+func TestServerStream(t *testing.T) {
+	s := newTestServer(t)
+	server := httptest.NewServer(s)
+	defer server.Close()
+	streamURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/transfers/stream"
+
+	// The stream is not served until it runs
+	_, response, err := websocket.DefaultDialer.Dial(streamURL+"?sender="+testSender.Hex(), nil)
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	require.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	require.NoError(t, s.stream.start())
+	for _, query := range []string{"", "?sender=0x1234", "?messageID=0x1234", "?sender=" + testSender.Hex() + "&after=x"} {
+		_, response, err := websocket.DefaultDialer.Dial(streamURL+query, nil)
+		require.ErrorIs(t, err, websocket.ErrBadHandshake, query)
+		require.Equal(t, http.StatusBadRequest, response.StatusCode, query)
+	}
+
+	sender := testSender
+	s.stream.publish(eventKey{index: 1}, TransferEvent{Event: EventTokensSent, MessageID: ids.ID{1}, Sender: &sender})
+	conn, _, err := websocket.DefaultDialer.Dial(streamURL+"?sender="+testSender.Hex(), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The backlog is pushed first, then the new events that match
+	s.stream.publish(eventKey{index: 2}, TransferEvent{Event: EventTokensSent, MessageID: ids.ID{2}})
+	s.stream.publish(eventKey{index: 3}, TransferEvent{Event: EventTokensWithdrawn, MessageID: ids.ID{1}, Sender: &sender})
+	var event TransferEvent
+	require.NoError(t, conn.ReadJSON(&event))
+	require.Equal(t, uint64(1), event.Sequence)
+	require.Equal(t, s.stream.id, event.StreamID)
+	require.NoError(t, conn.ReadJSON(&event))
+	require.Equal(t, uint64(3), event.Sequence)
+	require.Equal(t, EventTokensWithdrawn, event.Event)
+
+	// Clients are disconnected when the stream stops
+	s.stream.stop()
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+}
@@ -0,0 +1,395 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	nativetokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/TokenRemote"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/interfaces"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// Gas required to execute a transfer on a destination of each kind, if not given by the request
+	defaultERC20RequiredGasLimit  = 100_000
+	defaultNativeRequiredGasLimit = 135_000
+)
+
+var (
+	// The ERC20 token transferrers take the amount as an argument of send, and the native token transferrers
+	// take it as the value of the transaction. Homes and remotes of the same kind share the signature of send.
+	erc20SendABI  = mustGetABI(erc20tokenhome.ERC20TokenHomeMetaData)
+	nativeSendABI = mustGetABI(nativetokenhome.NativeTokenHomeMetaData)
+	// Every ERC20 token, including the ERC20TokenRemote, implements approve
+	erc20ABI = mustGetABI(erc20tokenremote.ERC20TokenRemoteMetaData)
+)
+
+func mustGetABI(metadata *bind.MetaData) *abi.ABI {
+	parsed, err := metadata.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// approval is an amount of an ERC20 token to be approved for the source token transferrer.
+type approval struct {
+	token  common.Address
+	amount *big.Int
+}
+
+// addApproval adds amount to the approval of token, so that each token is approved once.
+func addApproval(approvals []approval, token common.Address, amount *big.Int) []approval {
+	for i := range approvals {
+		if approvals[i].token == token {
+			approvals[i].amount = new(big.Int).Add(approvals[i].amount, amount)
+			return approvals
+		}
+	}
+	return append(approvals, approval{token: token, amount: amount})
+}
+
+// buildTransfer returns the transactions of the transfer, to be sent in order by from, and the source of the transfer.
+func (s *Server) buildTransfer(
+	ctx context.Context,
+	request *TransferRequest,
+	from common.Address,
+) (*TransferResponse, endpoint, error) {
+	if request.Recipient == (common.Address{}) {
+		return nil, endpoint{}, invalidRequest("missing recipient")
+	}
+	primaryFee := request.PrimaryFee.Int()
+	if primaryFee.Sign() != 0 && request.PrimaryFeeTokenAddress == (common.Address{}) {
+		return nil, endpoint{}, invalidRequest("missing primary fee token address")
+	}
+	source, destination, quote, err := s.quote(ctx, &request.QuoteRequest)
+	if err != nil {
+		return nil, source, err
+	}
+	backend, ok := s.config.Chains[source.blockchainID]
+	if !ok {
+		return nil, source, unprocessable("chain %s of the source is not configured", source.blockchainID)
+	}
+	info, err := inspect.Inspect(ctx, backend, source.address)
+	if err != nil {
+		return nil, source, fmt.Errorf("failed to inspect %s: %w", source, err)
+	}
+	if !info.Kind.IsHome() && !info.Kind.IsRemote() {
+		return nil, source, unprocessable("source %s is a %s, not a token transferrer", source, info.Kind)
+	}
+
+	requiredGasLimit := request.RequiredGasLimit
+	if requiredGasLimit == 0 {
+		requiredGasLimit, err = s.defaultRequiredGasLimit(ctx, destination)
+		if err != nil {
+			return nil, source, err
+		}
+	}
+	var multiHopFallback common.Address
+	if quote.MultiHop {
+		multiHopFallback = request.MultiHopFallback
+		if multiHopFallback == (common.Address{}) {
+			multiHopFallback = from
+		}
+	}
+	input := erc20tokenhome.SendTokensInput{
+		DestinationBlockchainID:            destination.blockchainID,
+		DestinationTokenTransferrerAddress: destination.address,
+		Recipient:                          request.Recipient,
+		PrimaryFeeTokenAddress:             request.PrimaryFeeTokenAddress,
+		PrimaryFee:                         primaryFee,
+		SecondaryFee:                       request.SecondaryFee.Int(),
+		RequiredGasLimit:                   new(big.Int).SetUint64(requiredGasLimit),
+		MultiHopFallback:                   multiHopFallback,
+	}
+
+	amount := request.Amount.Int()
+	var (
+		approvals []approval
+		value     = new(big.Int)
+		sendData  []byte
+	)
+	if info.Kind.IsNative() {
+		value = amount
+		sendData, err = nativeSendABI.Pack("send", input)
+	} else {
+		approvals = addApproval(approvals, info.TokenAddress, amount)
+		sendData, err = erc20SendABI.Pack("send", input, amount)
+	}
+	if err != nil {
+		return nil, source, err
+	}
+	if primaryFee.Sign() != 0 {
+		approvals = addApproval(approvals, request.PrimaryFeeTokenAddress, primaryFee)
+	}
+
+	chainID, err := backend.ChainID(ctx)
+	if err != nil {
+		return nil, source, fmt.Errorf("failed to get chain ID of %s: %w", source.blockchainID, err)
+	}
+	nonce, err := backend.AcceptedNonceAt(ctx, from)
+	if err != nil {
+		return nil, source, fmt.Errorf("failed to get nonce of %s: %w", from, err)
+	}
+	response := &TransferResponse{Quote: quote}
+	newTransaction := func(to common.Address, data []byte, value *big.Int) Transaction {
+		tx := Transaction{
+			BlockchainID: source.blockchainID,
+			ChainID:      NewAmount(chainID),
+			From:         from,
+			To:           to,
+			Data:         data,
+			Value:        NewAmount(value),
+			Nonce:        nonce,
+		}
+		nonce++
+		return tx
+	}
+	for _, approval := range approvals {
+		data, err := erc20ABI.Pack("approve", source.address, approval.amount)
+		if err != nil {
+			return nil, source, err
+		}
+		response.Transactions = append(response.Transactions, newTransaction(approval.token, data, new(big.Int)))
+	}
+	response.Transactions = append(response.Transactions, newTransaction(source.address, sendData, value))
+	return response, source, nil
+}
+
+// defaultRequiredGasLimit returns the gas required to execute a transfer on the destination, by its kind.
+// Destinations on chains that are not configured are assumed to be ERC20 token transferrers.
+func (s *Server) defaultRequiredGasLimit(ctx context.Context, destination endpoint) (uint64, error) {
+	backend, ok := s.config.Chains[destination.blockchainID]
+	if !ok {
+		return defaultERC20RequiredGasLimit, nil
+	}
+	info, err := inspect.Inspect(ctx, backend, destination.address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect %s: %w", destination, err)
+	}
+	if info.Kind.IsNative() {
+		return defaultNativeRequiredGasLimit, nil
+	}
+	return defaultERC20RequiredGasLimit, nil
+}
+
+// submitTransfer signs the transactions of the transfer with the managed signer, and sends each one
+// once the previous one is accepted. It sets the hashes of the transactions, and the ID of the message
+// sent by the last one.
+func (s *Server) submitTransfer(ctx context.Context, source endpoint, response *TransferResponse) error {
+	backend := s.config.Chains[source.blockchainID]
+	var receipt *types.Receipt
+	for i := range response.Transactions {
+		tx := &response.Transactions[i]
+		signed, err := s.signTransaction(ctx, backend, tx)
+		if err != nil {
+			return err
+		}
+		if err := backend.SendTransaction(ctx, signed); err != nil {
+			return fmt.Errorf("failed to send transaction to %s: %w", tx.To, err)
+		}
+		hash := signed.Hash()
+		tx.Hash = &hash
+		receipt, err = bind.WaitMined(ctx, backend, signed)
+		if err != nil {
+			return fmt.Errorf("failed to wait for transaction %s: %w", hash, err)
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			return fmt.Errorf("transaction %s to %s reverted", hash, tx.To)
+		}
+	}
+	response.Submitted = true
+
+	// TokenHome and TokenRemote emit the same TokensSent event
+	filterer, err := tokenremote.NewTokenRemoteFilterer(source.address, backend)
+	if err != nil {
+		return err
+	}
+	for _, log := range receipt.Logs {
+		if log.Address != source.address {
+			continue
+		}
+		if sent, err := filterer.ParseTokensSent(*log); err == nil {
+			messageID := ids.ID(sent.TeleporterMessageID)
+			response.MessageID = &messageID
+			return nil
+		}
+	}
+	return fmt.Errorf("no TokensSent event in transaction %s", receipt.TxHash)
+}
+
+// signTransaction estimates the gas and fees of the transaction, and signs it with the managed signer.
+func (s *Server) signTransaction(ctx context.Context, backend Backend, tx *Transaction) (*types.Transaction, error) {
+	gas, err := backend.EstimateGas(ctx, interfaces.CallMsg{
+		From:  tx.From,
+		To:    &tx.To,
+		Data:  tx.Data,
+		Value: tx.Value.Int(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas of transaction to %s: %w", tx.To, err)
+	}
+	gasTipCap, err := backend.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	gasFeeCap := new(big.Int).Add(new(big.Int).Mul(header.BaseFee, big.NewInt(2)), gasTipCap)
+	unsigned := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   tx.ChainID.Int(),
+		Nonce:     tx.Nonce,
+		GasTipCap: gasTipCap,
+		GasFeeCap: gasFeeCap,
+		Gas:       gas,
+		To:        &tx.To,
+		Value:     tx.Value.Int(),
+		Data:      tx.Data,
+	})
+	return types.SignTx(unsigned, types.LatestSignerForChainID(tx.ChainID.Int()), s.config.SignerKey)
+}
+
+// createTransfer builds the transfer of the request body, and submits it if requested.
+func (s *Server) createTransfer(ctx context.Context, body []byte) (*TransferResponse, error) {
+	var request TransferRequest
+	if err := decodeJSON(body, &request); err != nil {
+		return nil, err
+	}
+	if !request.Submit {
+		if request.Sender == (common.Address{}) {
+			return nil, invalidRequest("missing sender")
+		}
+		response, _, err := s.buildTransfer(ctx, &request, request.Sender)
+		return response, err
+	}
+
+	if s.config.SignerKey == nil {
+		return nil, invalidRequest("no managed signer to submit transfers")
+	}
+	signer := s.SignerAddress()
+	if request.Sender != (common.Address{}) && request.Sender != signer {
+		return nil, invalidRequest("sender %s is not the managed signer %s", request.Sender, signer)
+	}
+	ctx, cancel := context.WithTimeout(ctx, submitTimeout)
+	defer cancel()
+	s.submitLock.Lock()
+	defer s.submitLock.Unlock()
+	response, source, err := s.buildTransfer(ctx, &request, signer)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.submitTransfer(ctx, source, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// handleCreateTransfer serves POST /transfers. Requests with an idempotency key are handled once:
+// the response is recorded and replayed for every request with the same key and body.
+func (s *Server) handleCreateTransfer(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" {
+		status, responseBody := encodeTransferResponse(s.createTransfer(r.Context(), body))
+		writeBody(w, status, responseBody)
+		return
+	}
+
+	replay, err := s.idempotency.begin(key, sha256.Sum256(body))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if replay != nil {
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		writeBody(w, replay.status, replay.body)
+		return
+	}
+	// The transfer is created even if the client disconnects, so that a retry gets its response
+	status, responseBody := encodeTransferResponse(s.createTransfer(context.WithoutCancel(r.Context()), body))
+	s.idempotency.finish(key, status, responseBody)
+	writeBody(w, status, responseBody)
+}
+
+// encodeTransferResponse returns the status and body of the response to a transfer request.
+func encodeTransferResponse(response *TransferResponse, err error) (int, []byte) {
+	if err != nil {
+		return encodeError(err)
+	}
+	status := http.StatusOK
+	if response.Submitted {
+		status = http.StatusCreated
+	}
+	return encodeJSON(status, response)
+}
+
+// parseMessageID parses a Teleporter message ID, encoded either as an Avalanche ID or in hex.
+func parseMessageID(s string) (ids.ID, error) {
+	if strings.HasPrefix(s, "0x") {
+		b := common.FromHex(s)
+		if len(b) != len(ids.Empty) {
+			return ids.Empty, fmt.Errorf("invalid message ID %q", s)
+		}
+		return ids.ID(b), nil
+	}
+	return ids.FromString(s)
+}
+
+func (s *Server) handleGetTransfer(w http.ResponseWriter, r *http.Request) {
+	messageID, err := parseMessageID(mux.Vars(r)["messageID"])
+	if err != nil {
+		writeError(w, invalidRequest("invalid message ID: %w", err))
+		return
+	}
+	transfer, err := portfolio.FindTransfer(r.Context(), s.portfolioConfig, messageID)
+	if errors.Is(err, portfolio.ErrTransferNotFound) {
+		err = &requestError{status: http.StatusNotFound, err: err}
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response := Transfer{
+		MessageID:               messageID,
+		SourceBlockchainID:      transfer.SourceBlockchainID,
+		Source:                  transfer.Source,
+		DestinationBlockchainID: transfer.DestinationBlockchainID,
+		Destination:             transfer.Destination,
+		Sender:                  transfer.Sender,
+		Recipient:               transfer.Recipient,
+		Amount:                  NewAmount(transfer.Amount),
+		TxHash:                  transfer.TxHash,
+		Status:                  transfer.Status,
+	}
+	if transfer.HomeValue != nil {
+		response.HomeValue = NewAmount(transfer.HomeValue)
+	}
+	if transfer.Routed {
+		response.RoutedMessageID = &transfer.MessageID
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,275 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package guardian is a circuit breaker for a TokenHome and its remotes. It periodically gathers their
+// reserves, checks them against the invariants of the token transferrers and a set of anomaly rules, and
+// on any violation pauses the delivery of Teleporter messages to the affected token transferrers.
+//
+// Token transferrers are paused with pauseTeleporterAddress, which is restricted to their owner. The key
+// of the guardian must therefore own them, which is typically a key dedicated to the guardian that they
+// are transferred to. Once paused, a token transferrer neither receives nor sends messages until it is
+// unpaused by its owner.
+package guardian
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/TokenRemote"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/reserves"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const defaultInterval = time.Minute
+
+var (
+	errMissingKey        = errors.New("missing guardian key")
+	errMissingTeleporter = errors.New("missing Teleporter contract address")
+)
+
+// Backend is the subset of the RPC client of a chain needed to check the reserves of its token
+// transferrers and pause them.
+type Backend interface {
+	reserves.Backend
+	bind.ContractBackend
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// Config configures a Guardian.
+type Config struct {
+	HomeBlockchainID ids.ID
+	HomeAddress      common.Address
+	// The backend of each chain, by blockchain ID. The supply of remotes on other chains is not checked,
+	// and they cannot be paused.
+	Chains map[ids.ID]Backend
+	// The TeleporterMessenger paused on the affected token transferrers
+	TeleporterAddress common.Address
+	// Owner of the token transferrers, which sends the pause transactions
+	Key   *ecdsa.PrivateKey
+	Rules Rules
+	// Time between two checks of the reserves. Defaults to a minute.
+	Interval time.Duration
+}
+
+// Endpoint identifies a token transferrer.
+type Endpoint struct {
+	BlockchainID ids.ID         `json:"blockchainID"`
+	Address      common.Address `json:"address"`
+}
+
+// Pause is the outcome of pausing the Teleporter address on an affected token transferrer.
+type Pause struct {
+	Endpoint
+	// True if the Teleporter address was already paused, in which case no transaction was sent
+	AlreadyPaused bool         `json:"alreadyPaused"`
+	TxHash        *common.Hash `json:"txHash,omitempty"`
+	// Why the token transferrer could not be paused, if it was not
+	Error string `json:"error,omitempty"`
+}
+
+// Incident is the report of a check of the reserves that found violations.
+type Incident struct {
+	Time       time.Time   `json:"time"`
+	Violations []Violation `json:"violations"`
+	// The token transferrers paused in response, starting with the home
+	Pauses []Pause `json:"pauses"`
+	// The reserves the violations were found in
+	Reserves *reserves.Attestation `json:"reserves"`
+}
+
+// Guardian checks the reserves of a TokenHome, and pauses its token transferrers on violations.
+type Guardian struct {
+	config   Config
+	address  common.Address
+	chainIDs map[ids.ID]*big.Int
+	// The reserves of the previous check, which the anomaly rules compare with
+	previous *reserves.Attestation
+}
+
+// New validates the config, and returns a Guardian that has not checked the reserves yet.
+func New(ctx context.Context, config Config) (*Guardian, error) {
+	if config.Key == nil {
+		return nil, errMissingKey
+	}
+	if config.TeleporterAddress == (common.Address{}) {
+		return nil, errMissingTeleporter
+	}
+	if _, ok := config.Chains[config.HomeBlockchainID]; !ok {
+		return nil, fmt.Errorf("missing backend of the home chain %s", config.HomeBlockchainID)
+	}
+	if config.Interval == 0 {
+		config.Interval = defaultInterval
+	}
+	g := &Guardian{
+		config:   config,
+		address:  crypto.PubkeyToAddress(config.Key.PublicKey),
+		chainIDs: make(map[ids.ID]*big.Int),
+	}
+	for blockchainID, backend := range config.Chains {
+		chainID, err := backend.ChainID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chain ID of %s: %w", blockchainID, err)
+		}
+		g.chainIDs[blockchainID] = chainID
+	}
+	return g, nil
+}
+
+// Address returns the address of the key of the guardian.
+func (g *Guardian) Address() common.Address {
+	return g.address
+}
+
+// Run checks the reserves every interval until the context is done. The incident of each check that
+// found violations is sent to incidents, unless it is nil. Checks that fail, such as when an RPC endpoint
+// is unavailable, are logged and retried at the next interval.
+func (g *Guardian) Run(ctx context.Context, incidents chan<- Incident) error {
+	ticker := time.NewTicker(g.config.Interval)
+	defer ticker.Stop()
+	for {
+		incident, err := g.Check(ctx)
+		if err != nil {
+			log.Error("Failed to check reserves", "home", g.config.HomeAddress, "err", err)
+		} else if incident != nil && incidents != nil {
+			select {
+			case incidents <- *incident:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Check gathers the reserves, and pauses the token transferrers affected by the violations found in them.
+// It returns the incident, or nil if no rule was violated. Token transferrers that are still paused from
+// a previous incident are reported as already paused.
+func (g *Guardian) Check(ctx context.Context) (*Incident, error) {
+	chains := make(map[ids.ID]reserves.Backend, len(g.config.Chains))
+	for blockchainID, backend := range g.config.Chains {
+		chains[blockchainID] = backend
+	}
+	current, err := reserves.Gather(ctx, reserves.Config{
+		HomeBlockchainID: g.config.HomeBlockchainID,
+		HomeAddress:      g.config.HomeAddress,
+		Chains:           chains,
+	})
+	if err != nil {
+		return nil, err
+	}
+	previous := g.previous
+	g.previous = current
+	violations := g.config.Rules.Check(previous, current)
+	if len(violations) == 0 {
+		return nil, nil
+	}
+
+	incident := &Incident{
+		Time:       time.Now().UTC(),
+		Violations: violations,
+		Reserves:   current,
+	}
+	for _, endpoint := range g.affected(violations) {
+		pause := g.pause(ctx, endpoint)
+		if pause.Error != "" {
+			log.Error("Failed to pause token transferrer", "blockchainID", pause.BlockchainID,
+				"address", pause.Address, "err", pause.Error)
+		} else {
+			log.Warn("Paused token transferrer", "blockchainID", pause.BlockchainID,
+				"address", pause.Address, "alreadyPaused", pause.AlreadyPaused)
+		}
+		incident.Pauses = append(incident.Pauses, pause)
+	}
+	return incident, nil
+}
+
+// affected returns the token transferrers to pause for the violations: the home for every violation,
+// since it releases the tokens of every remote, and each remote that broke a rule.
+func (g *Guardian) affected(violations []Violation) []Endpoint {
+	home := Endpoint{BlockchainID: g.config.HomeBlockchainID, Address: g.config.HomeAddress}
+	endpoints := []Endpoint{home}
+	seen := map[Endpoint]bool{home: true}
+	for _, violation := range violations {
+		endpoint := Endpoint{BlockchainID: violation.BlockchainID, Address: violation.Address}
+		if !seen[endpoint] {
+			seen[endpoint] = true
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// pausable is implemented by the bindings of every token transferrer.
+type pausable interface {
+	IsTeleporterAddressPaused(opts *bind.CallOpts, teleporterAddress common.Address) (bool, error)
+	PauseTeleporterAddress(opts *bind.TransactOpts, teleporterAddress common.Address) (*types.Transaction, error)
+}
+
+// pause pauses the Teleporter address on the token transferrer, unless it already is.
+func (g *Guardian) pause(ctx context.Context, endpoint Endpoint) Pause {
+	result := Pause{Endpoint: endpoint}
+	backend, ok := g.config.Chains[endpoint.BlockchainID]
+	if !ok {
+		result.Error = "chain not configured"
+		return result
+	}
+	var (
+		contract pausable
+		err      error
+	)
+	if endpoint.BlockchainID == g.config.HomeBlockchainID && endpoint.Address == g.config.HomeAddress {
+		contract, err = tokenhome.NewTokenHome(endpoint.Address, backend)
+	} else {
+		contract, err = tokenremote.NewTokenRemote(endpoint.Address, backend)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	paused, err := contract.IsTeleporterAddressPaused(&bind.CallOpts{Context: ctx}, g.config.TeleporterAddress)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to get pause status: %s", err)
+		return result
+	}
+	if paused {
+		result.AlreadyPaused = true
+		return result
+	}
+	opts, err := bind.NewKeyedTransactorWithChainID(g.config.Key, g.chainIDs[endpoint.BlockchainID])
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	opts.Context = ctx
+	tx, err := contract.PauseTeleporterAddress(opts, g.config.TeleporterAddress)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to send pause transaction: %s", err)
+		return result
+	}
+	hash := tx.Hash()
+	result.TxHash = &hash
+	receipt, err := bind.WaitMined(ctx, backend, tx)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to wait for pause transaction: %s", err)
+		return result
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		result.Error = "pause transaction reverted"
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package guardian
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/amounts"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/reserves"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Rule names a check of the reserves of a TokenHome.
+type Rule string
+
+const (
+	// The balance of the home is less than the value of the balances transferred to its remotes.
+	RuleHomeShortfall Rule = "homeShortfall"
+	// The supply of a remote is more than the balance transferred to it, plus its collateralized initial
+	// reserve imbalance.
+	RuleUnbackedSupply Rule = "unbackedSupply"
+	// The supply of a remote increased by more than Rules.MaxMint since the previous check.
+	RuleMintSpike Rule = "mintSpike"
+	// The balance of the home decreased by more than Rules.MaxOutflow since the previous check.
+	RuleOutflowSpike Rule = "outflowSpike"
+)
+
+// Violation is a rule broken by a token transferrer.
+type Violation struct {
+	Rule         Rule           `json:"rule"`
+	BlockchainID ids.ID         `json:"blockchainID"`
+	Address      common.Address `json:"address"`
+	Description  string         `json:"description"`
+}
+
+// Rules configures the anomalies detected between two consecutive checks of the reserves. The invariants
+// that the home balance covers the balances transferred, and that the supply of every remote is backed,
+// are always checked.
+type Rules struct {
+	// Largest increase of the supply of a remote between two checks, as its value in home units.
+	// If nil, the supply of remotes is not limited.
+	MaxMint *big.Int `json:"maxMint,omitempty"`
+	// Largest decrease of the balance of the home between two checks. If nil, it is not limited.
+	MaxOutflow *big.Int `json:"maxOutflow,omitempty"`
+}
+
+// Check returns the violations found in the current reserves, and the anomalies since the previous ones,
+// which are nil on the first check.
+func (r Rules) Check(previous, current *reserves.Attestation) []Violation {
+	home := current.Home
+	var violations []Violation
+	if current.Surplus.Sign() < 0 {
+		violations = append(violations, Violation{
+			Rule:         RuleHomeShortfall,
+			BlockchainID: home.BlockchainID,
+			Address:      home.Address,
+			Description: fmt.Sprintf(
+				"balance %s is %s short of the %s transferred to remotes",
+				home.Balance, new(big.Int).Neg(current.Surplus), current.TotalTransferred,
+			),
+		})
+	}
+	for _, remote := range current.Remotes {
+		if remote.Attested && !remote.Backed {
+			violations = append(violations, Violation{
+				Rule:         RuleUnbackedSupply,
+				BlockchainID: remote.BlockchainID,
+				Address:      remote.Address,
+				Description: fmt.Sprintf(
+					"supply %s exceeds the transferred balance %s", remote.Supply, remote.TransferredBalance,
+				),
+			})
+		}
+	}
+	if previous == nil {
+		return violations
+	}
+
+	if r.MaxOutflow != nil && previous.Home.Balance != nil {
+		outflow := new(big.Int).Sub(previous.Home.Balance, home.Balance)
+		if outflow.Cmp(r.MaxOutflow) > 0 {
+			violations = append(violations, Violation{
+				Rule:         RuleOutflowSpike,
+				BlockchainID: home.BlockchainID,
+				Address:      home.Address,
+				Description:  fmt.Sprintf("balance decreased by %s, more than %s", outflow, r.MaxOutflow),
+			})
+		}
+	}
+	if r.MaxMint != nil {
+		supplies := make(map[Endpoint]*big.Int)
+		for _, remote := range previous.Remotes {
+			if remote.Attested {
+				supplies[Endpoint{BlockchainID: remote.BlockchainID, Address: remote.Address}] = remote.Supply
+			}
+		}
+		for _, remote := range current.Remotes {
+			if !remote.Attested {
+				continue
+			}
+			// Remotes registered since the previous check are compared with the supply they started with. Only
+			// native token remotes start with the supply of their initial reserve imbalance, in their genesis.
+			previousSupply, ok := supplies[Endpoint{BlockchainID: remote.BlockchainID, Address: remote.Address}]
+			if !ok {
+				previousSupply = new(big.Int)
+				if remote.Kind.IsNative() && remote.InitialReserveImbalance != nil {
+					previousSupply = remote.InitialReserveImbalance
+				}
+			}
+			route := amounts.Route{TokenMultiplier: remote.TokenMultiplier, MultiplyOnRemote: remote.MultiplyOnRemote}
+			minted := route.HomeValue(new(big.Int).Sub(remote.Supply, previousSupply))
+			if minted.Cmp(r.MaxMint) > 0 {
+				violations = append(violations, Violation{
+					Rule:         RuleMintSpike,
+					BlockchainID: remote.BlockchainID,
+					Address:      remote.Address,
+					Description: fmt.Sprintf(
+						"supply increased from %s to %s, worth %s home tokens, more than %s",
+						previousSupply, remote.Supply, minted, r.MaxMint,
+					),
+				})
+			}
+		}
+	}
+	return violations
+}
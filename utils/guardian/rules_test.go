@@ -0,0 +1,142 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package guardian
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/reserves"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testHome         = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testRemoteA      = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testRemoteB      = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	testHomeChain    = ids.ID{1}
+	testRemoteChainA = ids.ID{2}
+	testRemoteChainB = ids.ID{3}
+)
+
+// newTestReserves returns the reserves of a home holding 1000 tokens, with a 1:1 remote A of the given
+// supply, and a remote B with one more decimal than the home that the home has transferred 500 tokens to.
+func newTestReserves(homeBalance int64, supplyA int64) *reserves.Attestation {
+	return &reserves.Attestation{
+		Home: reserves.Home{
+			BlockchainID: testHomeChain,
+			Address:      testHome,
+			Balance:      big.NewInt(homeBalance),
+		},
+		Remotes: []reserves.Remote{
+			{
+				BlockchainID:            testRemoteChainA,
+				Address:                 testRemoteA,
+				TokenMultiplier:         big.NewInt(1),
+				TransferredBalance:      big.NewInt(500),
+				Attested:                true,
+				Supply:                  big.NewInt(supplyA),
+				InitialReserveImbalance: big.NewInt(0),
+				Backed:                  supplyA <= 500,
+			},
+			{
+				BlockchainID:            testRemoteChainB,
+				Address:                 testRemoteB,
+				TokenMultiplier:         big.NewInt(10),
+				MultiplyOnRemote:        true,
+				TransferredBalance:      big.NewInt(5000),
+				Attested:                true,
+				Supply:                  big.NewInt(5000),
+				InitialReserveImbalance: big.NewInt(0),
+				Backed:                  true,
+			},
+		},
+		TotalTransferred: big.NewInt(1000),
+		Surplus:          big.NewInt(homeBalance - 1000),
+	}
+}
+
+func TestRulesCheck(t *testing.T) {
+	rules := Rules{MaxMint: big.NewInt(100), MaxOutflow: big.NewInt(200)}
+	tests := []struct {
+		name     string
+		previous *reserves.Attestation
+		current  *reserves.Attestation
+		expected []Rule
+	}{
+		{
+			name:    "first check",
+			current: newTestReserves(1000, 500),
+		},
+		{
+			name:     "within limits",
+			previous: newTestReserves(1100, 450),
+			current:  newTestReserves(1000, 500),
+		},
+		{
+			name:     "home shortfall",
+			previous: newTestReserves(1000, 500),
+			current:  newTestReserves(900, 500),
+			expected: []Rule{RuleHomeShortfall},
+		},
+		{
+			name:     "unbacked supply",
+			previous: newTestReserves(1000, 500),
+			current:  newTestReserves(1000, 501),
+			expected: []Rule{RuleUnbackedSupply},
+		},
+		{
+			name:     "mint spike",
+			previous: newTestReserves(1000, 300),
+			current:  newTestReserves(1000, 401),
+			expected: []Rule{RuleMintSpike},
+		},
+		{
+			name:     "outflow spike",
+			previous: newTestReserves(1300, 500),
+			current:  newTestReserves(1099, 500),
+			expected: []Rule{RuleOutflowSpike},
+		},
+		{
+			name:     "unbacked supply on the first check",
+			current:  newTestReserves(900, 900),
+			expected: []Rule{RuleHomeShortfall, RuleUnbackedSupply},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var broken []Rule
+			for _, violation := range rules.Check(test.previous, test.current) {
+				broken = append(broken, violation.Rule)
+			}
+			require.Equal(t, test.expected, broken)
+		})
+	}
+}
+
+func TestMintSpikeOfNewRemote(t *testing.T) {
+	previous := newTestReserves(1000, 500)
+	previous.Remotes = previous.Remotes[:1]
+	current := newTestReserves(1000, 500)
+
+	// The 5000 tokens of the new remote B are worth 500 home tokens
+	violations := Rules{MaxMint: big.NewInt(499)}.Check(previous, current)
+	require.Len(t, violations, 1)
+	require.Equal(t, RuleMintSpike, violations[0].Rule)
+	require.Equal(t, testRemoteB, violations[0].Address)
+	require.Equal(t, "supply increased from 0 to 5000, worth 500 home tokens, more than 499", violations[0].Description)
+	require.Empty(t, Rules{MaxMint: big.NewInt(500)}.Check(previous, current))
+
+	// ERC20 token remotes start with no supply, whatever imbalance they report
+	current.Remotes[1].Kind = inspect.KindERC20TokenRemote
+	current.Remotes[1].InitialReserveImbalance = big.NewInt(4000)
+	require.Len(t, Rules{MaxMint: big.NewInt(499)}.Check(previous, current), 1)
+
+	// Native token remotes start with the supply of their initial reserve imbalance
+	current.Remotes[1].Kind = inspect.KindNativeTokenRemote
+	require.Empty(t, Rules{MaxMint: big.NewInt(100)}.Check(previous, current))
+}
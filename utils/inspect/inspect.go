@@ -0,0 +1,281 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package inspect determines which kind of token transferrer contract, if any, is deployed at an address.
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+
+	proxyadmin "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/ProxyAdmin"
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	nativetokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHome"
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	nativetokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemote"
+	tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/TokenRemote"
+	wrappednativetoken "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/WrappedNativeToken"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Kind is the type of contract deployed at an address.
+type Kind string
+
+const (
+	KindNoCode                       Kind = "NoCode"
+	KindUnknown                      Kind = "Unknown"
+	KindERC20TokenHome               Kind = "ERC20TokenHome"
+	KindERC20TokenHomeUpgradeable    Kind = "ERC20TokenHomeUpgradeable"
+	KindNativeTokenHome              Kind = "NativeTokenHome"
+	KindNativeTokenHomeUpgradeable   Kind = "NativeTokenHomeUpgradeable"
+	KindERC20TokenRemote             Kind = "ERC20TokenRemote"
+	KindERC20TokenRemoteUpgradeable  Kind = "ERC20TokenRemoteUpgradeable"
+	KindNativeTokenRemote            Kind = "NativeTokenRemote"
+	KindNativeTokenRemoteUpgradeable Kind = "NativeTokenRemoteUpgradeable"
+	KindProxyAdmin                   Kind = "ProxyAdmin"
+	KindWrappedNativeToken           Kind = "WrappedNativeToken"
+
+	// Returned when the getters identify the contract, but its code does not match any of the bindings,
+	// for example because it was built from a different version of the contracts.
+	KindTokenHome   Kind = "TokenHome"
+	KindTokenRemote Kind = "TokenRemote"
+)
+
+// IsHome returns true for every TokenHome kind.
+func (k Kind) IsHome() bool {
+	return strings.Contains(string(k), "TokenHome")
+}
+
+// IsRemote returns true for every TokenRemote kind.
+func (k Kind) IsRemote() bool {
+	return strings.Contains(string(k), "TokenRemote")
+}
+
+// IsNative returns true for the NativeTokenHome and NativeTokenRemote kinds.
+func (k Kind) IsNative() bool {
+	return strings.HasPrefix(string(k), "Native")
+}
+
+var (
+	// EIP-1967 storage slots of proxy contracts
+	// bytes32(uint256(keccak256("eip1967.proxy.implementation")) - 1)
+	ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+	// bytes32(uint256(keccak256("eip1967.proxy.admin")) - 1)
+	AdminSlot = common.HexToHash("0xb53127684a568b3173ae13b9f8a6016e243e63b6e8ee1178d6a717850b5d6103")
+
+	// Creation bytecode of each known contract, as generated in the bindings.
+	// The non-upgradeable token transferrers only add a constructor to their upgradeable variants,
+	// so both have the same runtime code, and only the non-upgradeable kinds are listed here.
+	bindingBins = map[Kind]string{
+		KindERC20TokenHome:     erc20tokenhome.ERC20TokenHomeMetaData.Bin,
+		KindNativeTokenHome:    nativetokenhome.NativeTokenHomeMetaData.Bin,
+		KindERC20TokenRemote:   erc20tokenremote.ERC20TokenRemoteMetaData.Bin,
+		KindNativeTokenRemote:  nativetokenremote.NativeTokenRemoteMetaData.Bin,
+		KindProxyAdmin:         proxyadmin.ProxyAdminMetaData.Bin,
+		KindWrappedNativeToken: wrappednativetoken.WrappedNativeTokenMetaData.Bin,
+	}
+
+	upgradeableKinds = map[Kind]Kind{
+		KindERC20TokenHome:    KindERC20TokenHomeUpgradeable,
+		KindNativeTokenHome:   KindNativeTokenHomeUpgradeable,
+		KindERC20TokenRemote:  KindERC20TokenRemoteUpgradeable,
+		KindNativeTokenRemote: KindNativeTokenRemoteUpgradeable,
+	}
+
+	// Runtime code hashes of each known contract, derived from bindingBins
+	runtimeCodeHashes = func() map[common.Hash]Kind {
+		hashes := make(map[common.Hash]Kind, len(bindingBins))
+		for kind, bin := range bindingBins {
+			if runtime := RuntimeCode(common.FromHex(bin)); runtime != nil {
+				hashes[crypto.Keccak256Hash(runtime)] = kind
+			}
+		}
+		return hashes
+	}()
+)
+
+// Backend is the subset of the RPC client needed to inspect contracts.
+type Backend interface {
+	bind.ContractCaller
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+// ContractInfo describes the contract deployed at an address.
+// Fields that do not apply to the contract's kind are left as their zero values.
+type ContractInfo struct {
+	Address  common.Address
+	Kind     Kind
+	CodeHash common.Hash
+	// True if the runtime code, or the implementation's runtime code for proxies,
+	// is identical to that of the bindings.
+	CodeMatchesBindings bool
+
+	IsProxy        bool
+	Implementation common.Address
+	ProxyAdmin     common.Address
+
+	BlockchainID ids.ID
+	// The token transferred by a TokenHome. For TokenRemote instances, this is the contract itself.
+	TokenAddress  common.Address
+	TokenDecimals uint8
+	// The TokenHome a TokenRemote is linked to
+	TokenHomeBlockchainID ids.ID
+	TokenHomeAddress      common.Address
+	// Token transferrer contracts are Ownable, with the owner acting as the Teleporter manager
+	Owner common.Address
+}
+
+// Inspect determines the kind of contract at the given address. For proxies, the kind is
+// that of the implementation, and the getters are queried through the proxy.
+func Inspect(ctx context.Context, backend Backend, address common.Address) (*ContractInfo, error) {
+	info := &ContractInfo{
+		Address: address,
+		Kind:    KindNoCode,
+	}
+
+	code, err := backend.CodeAt(ctx, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) == 0 {
+		return info, nil
+	}
+	info.CodeHash = crypto.Keccak256Hash(code)
+
+	implementation, err := readAddressSlot(ctx, backend, address, ImplementationSlot)
+	if err != nil {
+		return nil, err
+	}
+	if implementation != (common.Address{}) {
+		info.IsProxy = true
+		info.Implementation = implementation
+		info.ProxyAdmin, err = readAddressSlot(ctx, backend, address, AdminSlot)
+		if err != nil {
+			return nil, err
+		}
+		code, err = backend.CodeAt(ctx, implementation, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	kind, ok := MatchRuntimeCode(code)
+	info.Kind = kind
+	info.CodeMatchesBindings = ok
+	// Token transferrers behind a proxy are always the upgradeable variant
+	if upgradeableKind, ok := upgradeableKinds[kind]; ok && info.IsProxy {
+		info.Kind = upgradeableKind
+	}
+
+	if err := probeGetters(ctx, backend, info); err != nil {
+		return nil, err
+	}
+
+	// Initialized token transferrers always have their blockchain ID set. If it is not set,
+	// the contract is a logic contract deployed to be used behind a proxy.
+	if upgradeableKind, ok := upgradeableKinds[info.Kind]; ok && info.BlockchainID == ids.Empty {
+		info.Kind = upgradeableKind
+	}
+	return info, nil
+}
+
+// MatchRuntimeCode returns the kind of contract with the given runtime code.
+func MatchRuntimeCode(code []byte) (Kind, bool) {
+	if len(code) == 0 {
+		return KindNoCode, false
+	}
+	if kind, ok := runtimeCodeHashes[crypto.Keccak256Hash(code)]; ok {
+		return kind, true
+	}
+	return KindUnknown, false
+}
+
+// RuntimeCode returns the runtime code embedded in the given creation code, or nil if it cannot be found.
+// Solidity creation code copies the runtime code to memory, returns it and ends with an INVALID opcode,
+// so the runtime code starts immediately after the first RETURN, INVALID sequence.
+func RuntimeCode(creationCode []byte) []byte {
+	// RETURN, INVALID, PUSH1 0x80, PUSH1 0x40, MSTORE
+	index := bytes.Index(creationCode, []byte{0xf3, 0xfe, 0x60, 0x80, 0x60, 0x40, 0x52})
+	if index < 0 {
+		return nil
+	}
+	return creationCode[index+2:]
+}
+
+func readAddressSlot(ctx context.Context, backend Backend, address common.Address, slot common.Hash) (common.Address, error) {
+	value, err := backend.StorageAt(ctx, address, slot, nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return common.BytesToAddress(value), nil
+}
+
+// probeGetters fills in the linkage and token details of token transferrers. If the code did not
+// match any of the bindings, the getters are also used to tell homes and remotes apart.
+// Getters that revert are treated as not implemented by the contract.
+func probeGetters(ctx context.Context, backend Backend, info *ContractInfo) error {
+	if info.Kind != KindUnknown && !info.Kind.IsHome() && !info.Kind.IsRemote() {
+		return nil
+	}
+	opts := &bind.CallOpts{Context: ctx}
+
+	remote, err := tokenremote.NewTokenRemoteCaller(info.Address, backend)
+	if err != nil {
+		return err
+	}
+	if tokenHomeAddress, err := remote.GetTokenHomeAddress(opts); err == nil && !info.Kind.IsHome() {
+		if info.Kind == KindUnknown {
+			info.Kind = KindTokenRemote
+		}
+		info.TokenHomeAddress = tokenHomeAddress
+		info.TokenAddress = info.Address
+		if blockchainID, err := remote.GetTokenHomeBlockchainID(opts); err == nil {
+			info.TokenHomeBlockchainID = blockchainID
+		}
+		if blockchainID, err := remote.GetBlockchainID(opts); err == nil {
+			info.BlockchainID = blockchainID
+		}
+		if owner, err := remote.Owner(opts); err == nil {
+			info.Owner = owner
+		}
+		if token, err := erc20tokenremote.NewERC20TokenRemoteCaller(info.Address, backend); err == nil {
+			if decimals, err := token.Decimals(opts); err == nil {
+				info.TokenDecimals = decimals
+			}
+		}
+		return nil
+	}
+
+	home, err := tokenhome.NewTokenHomeCaller(info.Address, backend)
+	if err != nil {
+		return err
+	}
+	tokenAddress, err := home.GetTokenAddress(opts)
+	if err != nil {
+		// Neither a home nor a remote
+		return nil
+	}
+	if info.Kind == KindUnknown {
+		info.Kind = KindTokenHome
+	}
+	info.TokenAddress = tokenAddress
+	if blockchainID, err := home.GetBlockchainID(opts); err == nil {
+		info.BlockchainID = blockchainID
+	}
+	if owner, err := home.Owner(opts); err == nil {
+		info.Owner = owner
+	}
+	// Both ERC20 tokens and wrapped native tokens implement decimals()
+	if token, err := erc20tokenremote.NewERC20TokenRemoteCaller(tokenAddress, backend); err == nil {
+		if decimals, err := token.Decimals(opts); err == nil {
+			info.TokenDecimals = decimals
+		}
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package inspect
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	erc20tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHome"
+	erc20tokenhomeupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/ERC20TokenHomeUpgradeable"
+	nativetokenhomeupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/NativeTokenHomeUpgradeable"
+	erc20tokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemoteUpgradeable"
+	nativetokenremoteupgradeable "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemoteUpgradeable"
+	wrappednativetoken "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/WrappedNativeToken"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/interfaces"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var errExecutionReverted = errors.New("execution reverted")
+
+// fakeBackend serves code and storage from maps, and answers calls to the getters in results.
+type fakeBackend struct {
+	code    map[common.Address][]byte
+	storage map[common.Address]map[common.Hash]common.Hash
+	// Return values of each getter, keyed by contract address and method name
+	results map[common.Address]map[string][]interface{}
+	abi     *abi.ABI
+}
+
+func newFakeBackend(t *testing.T) *fakeBackend {
+	// The NativeTokenRemoteUpgradeable ABI includes the getters of both TokenHome and TokenRemote
+	// that are used by Inspect, apart from getTokenAddress which is added from the ERC20TokenHome ABI.
+	remoteABI, err := nativetokenremoteupgradeable.NativeTokenRemoteUpgradeableMetaData.GetAbi()
+	require.NoError(t, err)
+	homeABI, err := erc20tokenhome.ERC20TokenHomeMetaData.GetAbi()
+	require.NoError(t, err)
+	remoteABI.Methods["getTokenAddress"] = homeABI.Methods["getTokenAddress"]
+
+	return &fakeBackend{
+		code:    make(map[common.Address][]byte),
+		storage: make(map[common.Address]map[common.Hash]common.Hash),
+		results: make(map[common.Address]map[string][]interface{}),
+		abi:     remoteABI,
+	}
+}
+
+func (b *fakeBackend) CodeAt(_ context.Context, contract common.Address, _ *big.Int) ([]byte, error) {
+	return b.code[contract], nil
+}
+
+func (b *fakeBackend) StorageAt(_ context.Context, account common.Address, key common.Hash, _ *big.Int) ([]byte, error) {
+	return b.storage[account][key].Bytes(), nil
+}
+
+func (b *fakeBackend) CallContract(_ context.Context, call interfaces.CallMsg, _ *big.Int) ([]byte, error) {
+	method, err := b.abi.MethodById(call.Data)
+	if err != nil {
+		return nil, errExecutionReverted
+	}
+	result, ok := b.results[*call.To][method.Name]
+	if !ok {
+		return nil, errExecutionReverted
+	}
+	return method.Outputs.Pack(result...)
+}
+
+func runtimeCodeOf(t *testing.T, bin string) []byte {
+	runtime := RuntimeCode(common.FromHex(bin))
+	require.NotEmpty(t, runtime)
+	return runtime
+}
+
+func TestRuntimeCodeFoundForAllBindings(t *testing.T) {
+	for kind, bin := range bindingBins {
+		runtime := RuntimeCode(common.FromHex(bin))
+		require.NotEmpty(t, runtime, kind)
+
+		matchedKind, ok := MatchRuntimeCode(runtime)
+		require.True(t, ok, kind)
+		require.Equal(t, kind, matchedKind)
+	}
+}
+
+func TestUpgradeableVariantsShareRuntimeCode(t *testing.T) {
+	upgradeableBins := map[Kind]string{
+		KindERC20TokenHomeUpgradeable:    erc20tokenhomeupgradeable.ERC20TokenHomeUpgradeableMetaData.Bin,
+		KindNativeTokenHomeUpgradeable:   nativetokenhomeupgradeable.NativeTokenHomeUpgradeableMetaData.Bin,
+		KindERC20TokenRemoteUpgradeable:  erc20tokenremoteupgradeable.ERC20TokenRemoteUpgradeableMetaData.Bin,
+		KindNativeTokenRemoteUpgradeable: nativetokenremoteupgradeable.NativeTokenRemoteUpgradeableMetaData.Bin,
+	}
+	for kind, upgradeableKind := range upgradeableKinds {
+		require.Equal(
+			t,
+			runtimeCodeOf(t, bindingBins[kind]),
+			runtimeCodeOf(t, upgradeableBins[upgradeableKind]),
+			upgradeableKind,
+		)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	ctx := context.Background()
+	backend := newFakeBackend(t)
+
+	var (
+		eoa             = common.HexToAddress("0x01")
+		wrappedToken    = common.HexToAddress("0x02")
+		home            = common.HexToAddress("0x03")
+		remoteProxy     = common.HexToAddress("0x04")
+		remoteImpl      = common.HexToAddress("0x05")
+		proxyAdmin      = common.HexToAddress("0x06")
+		unknownRemote   = common.HexToAddress("0x07")
+		unrelated       = common.HexToAddress("0x08")
+		owner           = common.HexToAddress("0x09")
+		homeChainID     = ids.GenerateTestID()
+		remoteChainID   = ids.GenerateTestID()
+		unrecognizedBin = []byte{0x60, 0x80, 0x60, 0x40, 0x52, 0x00}
+	)
+
+	backend.code[wrappedToken] = runtimeCodeOf(t, wrappednativetoken.WrappedNativeTokenMetaData.Bin)
+	backend.results[wrappedToken] = map[string][]interface{}{"decimals": {uint8(18)}}
+
+	backend.code[home] = runtimeCodeOf(t, bindingBins[KindNativeTokenHome])
+	backend.results[home] = map[string][]interface{}{
+		"getTokenAddress": {wrappedToken},
+		"getBlockchainID": {[32]byte(homeChainID)},
+		"owner":           {owner},
+	}
+
+	remoteGetters := map[string][]interface{}{
+		"getTokenHomeAddress":      {home},
+		"getTokenHomeBlockchainID": {[32]byte(homeChainID)},
+		"getBlockchainID":          {[32]byte(remoteChainID)},
+		"owner":                    {owner},
+		"decimals":                 {uint8(9)},
+	}
+	// Proxy code is not compared against the bindings, since the proxy admin is an immutable
+	backend.code[remoteProxy] = unrecognizedBin
+	backend.code[remoteImpl] = runtimeCodeOf(t, bindingBins[KindNativeTokenRemote])
+	backend.storage[remoteProxy] = map[common.Hash]common.Hash{
+		ImplementationSlot: common.BytesToHash(remoteImpl.Bytes()),
+		AdminSlot:          common.BytesToHash(proxyAdmin.Bytes()),
+	}
+	backend.results[remoteProxy] = remoteGetters
+
+	backend.code[unknownRemote] = unrecognizedBin
+	backend.results[unknownRemote] = remoteGetters
+
+	backend.code[unrelated] = unrecognizedBin
+
+	testCases := []struct {
+		name     string
+		address  common.Address
+		expected ContractInfo
+	}{
+		{
+			name:     "account without code",
+			address:  eoa,
+			expected: ContractInfo{Kind: KindNoCode},
+		},
+		{
+			name:     "unrelated contract",
+			address:  unrelated,
+			expected: ContractInfo{Kind: KindUnknown},
+		},
+		{
+			name:    "known contract",
+			address: wrappedToken,
+			expected: ContractInfo{
+				Kind:                KindWrappedNativeToken,
+				CodeMatchesBindings: true,
+			},
+		},
+		{
+			name:    "home",
+			address: home,
+			expected: ContractInfo{
+				Kind:                KindNativeTokenHome,
+				CodeMatchesBindings: true,
+				BlockchainID:        homeChainID,
+				TokenAddress:        wrappedToken,
+				TokenDecimals:       18,
+				Owner:               owner,
+			},
+		},
+		{
+			name:    "remote behind proxy",
+			address: remoteProxy,
+			expected: ContractInfo{
+				Kind:                  KindNativeTokenRemoteUpgradeable,
+				CodeMatchesBindings:   true,
+				IsProxy:               true,
+				Implementation:        remoteImpl,
+				ProxyAdmin:            proxyAdmin,
+				BlockchainID:          remoteChainID,
+				TokenAddress:          remoteProxy,
+				TokenDecimals:         9,
+				TokenHomeBlockchainID: homeChainID,
+				TokenHomeAddress:      home,
+				Owner:                 owner,
+			},
+		},
+		{
+			name:    "uninitialized logic contract",
+			address: remoteImpl,
+			expected: ContractInfo{
+				Kind:                KindNativeTokenRemoteUpgradeable,
+				CodeMatchesBindings: true,
+			},
+		},
+		{
+			name:    "remote with unrecognized code",
+			address: unknownRemote,
+			expected: ContractInfo{
+				Kind:                  KindTokenRemote,
+				BlockchainID:          remoteChainID,
+				TokenAddress:          unknownRemote,
+				TokenDecimals:         9,
+				TokenHomeBlockchainID: homeChainID,
+				TokenHomeAddress:      home,
+				Owner:                 owner,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			info, err := Inspect(ctx, backend, testCase.address)
+			require.NoError(t, err)
+
+			// The address and code hash are always set, and not specific to the test case
+			expected := testCase.expected
+			expected.Address = testCase.address
+			expected.CodeHash = info.CodeHash
+			require.Equal(t, expected, *info)
+		})
+	}
+}
+
+func TestKind(t *testing.T) {
+	testCases := []struct {
+		kind     Kind
+		isHome   bool
+		isRemote bool
+		isNative bool
+	}{
+		{kind: KindERC20TokenHome, isHome: true},
+		{kind: KindNativeTokenHomeUpgradeable, isHome: true, isNative: true},
+		{kind: KindTokenHome, isHome: true},
+		{kind: KindERC20TokenRemoteUpgradeable, isRemote: true},
+		{kind: KindNativeTokenRemote, isRemote: true, isNative: true},
+		{kind: KindTokenRemote, isRemote: true},
+		{kind: KindProxyAdmin},
+		{kind: KindUnknown},
+	}
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.isHome, testCase.kind.IsHome(), testCase.kind)
+		require.Equal(t, testCase.isRemote, testCase.kind.IsRemote(), testCase.kind)
+		require.Equal(t, testCase.isNative, testCase.kind.IsNative(), testCase.kind)
+	}
+}
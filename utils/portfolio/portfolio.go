@@ -0,0 +1,301 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package portfolio reports where the tokens of an account are, across a TokenHome and every remote
+// registered with it: the balances held on each chain, and the transfers still in flight between them.
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/amounts"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/interfaces"
+	"github.com/ava-labs/subnet-evm/precompile/contracts/warp"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Backend is the subset of the RPC client of a chain needed to build a portfolio.
+type Backend interface {
+	inspect.Backend
+	bind.ContractFilterer
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// Config describes the TokenHome and the chains searched for its remotes and transfers.
+type Config struct {
+	HomeBlockchainID ids.ID
+	HomeAddress      common.Address
+	// The backend of each chain, by blockchain ID. Remotes on other chains are reported as unreachable.
+	Chains map[ids.ID]Backend
+	// Address of the TeleporterMessenger, which is the same on every chain.
+	TeleporterAddress common.Address
+	// Number of blocks searched for transfers on each chain. Zero searches from genesis.
+	// Remotes are always searched for from genesis.
+	LookBackBlocks uint64
+}
+
+// Holding is the balance of an account at one of the token transferrers.
+type Holding struct {
+	BlockchainID ids.ID
+	Address      common.Address
+	Kind         inspect.Kind
+	Token        amounts.Token
+	// Set for remotes whose chain is not in the config, in which case the balances are nil.
+	Unreachable bool
+
+	// The balance in the token of the chain: the ERC20 balance for ERC20 kinds,
+	// and the native balance for native kinds.
+	Balance *big.Int
+	// The balance of wrapped native tokens: of the wrapped token of a NativeTokenHome,
+	// or of the NativeTokenRemote itself. Zero for ERC20 kinds.
+	WrappedBalance *big.Int
+	// Balance and WrappedBalance in home token units
+	HomeValue *big.Int
+	// How amounts are scaled between the home and this remote. Zero for the home.
+	Route amounts.Route
+}
+
+// Portfolio is the balances of an account on the chains of a TokenHome and its remotes.
+type Portfolio struct {
+	Account   common.Address
+	HomeToken amounts.Token
+	Home      Holding
+	Remotes   []Holding
+	// Transfers sent by or to the account that have not been executed on their destination.
+	InFlight []Transfer
+	// Home value of the holdings, and of the transfers in flight to the account.
+	TotalHomeValue *big.Int
+}
+
+// Get builds the portfolio of account, starting from the TokenHome of the config
+// and discovering every remote registered with it.
+func Get(ctx context.Context, config Config, account common.Address) (*Portfolio, error) {
+	homeBackend, ok := config.Chains[config.HomeBlockchainID]
+	if !ok {
+		return nil, fmt.Errorf("no backend for the home chain %s", config.HomeBlockchainID)
+	}
+	home, err := getHolding(ctx, homeBackend, config.HomeBlockchainID, config.HomeAddress, account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances at home %s: %w", config.HomeAddress, err)
+	}
+	if !home.Kind.IsHome() {
+		return nil, fmt.Errorf("%s is a %s, not a token home", config.HomeAddress, home.Kind)
+	}
+	home.HomeValue = new(big.Int).Add(home.Balance, home.WrappedBalance)
+
+	portfolio := &Portfolio{
+		Account:        account,
+		HomeToken:      home.Token,
+		Home:           home,
+		TotalHomeValue: new(big.Int).Set(home.HomeValue),
+	}
+
+	remotes, err := DiscoverRemotes(ctx, homeBackend, config.HomeAddress)
+	if err != nil {
+		return nil, err
+	}
+	for _, remote := range remotes {
+		route, err := amounts.HomeRoute(ctx, homeBackend, config.HomeAddress, remote.BlockchainID, remote.Address)
+		if err != nil {
+			return nil, err
+		}
+		backend, ok := config.Chains[remote.BlockchainID]
+		if !ok {
+			portfolio.Remotes = append(portfolio.Remotes, Holding{
+				BlockchainID: remote.BlockchainID,
+				Address:      remote.Address,
+				Route:        route,
+				Unreachable:  true,
+			})
+			continue
+		}
+		holding, err := getHolding(ctx, backend, remote.BlockchainID, remote.Address, account)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to get balances at remote %s on %s: %w",
+				remote.Address, remote.BlockchainID, err,
+			)
+		}
+		holding.Route = route
+		holding.HomeValue = route.HomeValue(new(big.Int).Add(holding.Balance, holding.WrappedBalance))
+		portfolio.Remotes = append(portfolio.Remotes, holding)
+		portfolio.TotalHomeValue.Add(portfolio.TotalHomeValue, holding.HomeValue)
+	}
+
+	portfolio.InFlight, err = getInFlight(ctx, config, portfolio)
+	if err != nil {
+		return nil, err
+	}
+	for _, transfer := range portfolio.InFlight {
+		if transfer.Recipient == account && transfer.HomeValue != nil {
+			portfolio.TotalHomeValue.Add(portfolio.TotalHomeValue, transfer.HomeValue)
+		}
+	}
+	return portfolio, nil
+}
+
+// Remote identifies a remote registered with a TokenHome.
+type Remote struct {
+	BlockchainID ids.ID
+	Address      common.Address
+}
+
+// DiscoverRemotes returns the remotes registered with the TokenHome at homeAddress,
+// in the order of their registration.
+func DiscoverRemotes(ctx context.Context, backend Backend, homeAddress common.Address) ([]Remote, error) {
+	home, err := tokenhome.NewTokenHomeFilterer(homeAddress, backend)
+	if err != nil {
+		return nil, err
+	}
+	it, err := home.FilterRemoteRegistered(&bind.FilterOpts{Context: ctx}, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registered remotes of %s: %w", homeAddress, err)
+	}
+	defer it.Close()
+
+	var remotes []Remote
+	seen := make(map[Remote]struct{})
+	for it.Next() {
+		remote := Remote{
+			BlockchainID: it.Event.RemoteBlockchainID,
+			Address:      it.Event.RemoteTokenTransferrerAddress,
+		}
+		if _, ok := seen[remote]; ok {
+			continue
+		}
+		seen[remote] = struct{}{}
+		remotes = append(remotes, remote)
+	}
+	return remotes, it.Error()
+}
+
+// BlockchainID returns the Avalanche blockchain ID of the chain, as reported by the Warp precompile.
+func BlockchainID(ctx context.Context, caller bind.ContractCaller) (ids.ID, error) {
+	input, err := warp.PackGetBlockchainID()
+	if err != nil {
+		return ids.Empty, err
+	}
+	output, err := caller.CallContract(ctx, interfaces.CallMsg{To: &warp.ContractAddress, Data: input}, nil)
+	if err != nil {
+		return ids.Empty, fmt.Errorf("failed to get blockchain ID: %w", err)
+	}
+	if len(output) != len(ids.Empty) {
+		return ids.Empty, fmt.Errorf("invalid blockchain ID 0x%x", output)
+	}
+	return ids.ID(output), nil
+}
+
+func getHolding(
+	ctx context.Context,
+	backend Backend,
+	blockchainID ids.ID,
+	address common.Address,
+	account common.Address,
+) (Holding, error) {
+	info, err := inspect.Inspect(ctx, backend, address)
+	if err != nil {
+		return Holding{}, err
+	}
+	holding := Holding{
+		BlockchainID:   blockchainID,
+		Address:        address,
+		Kind:           info.Kind,
+		Token:          amounts.Token{Decimals: info.TokenDecimals},
+		WrappedBalance: new(big.Int),
+	}
+	if !info.Kind.IsHome() && !info.Kind.IsRemote() {
+		return holding, fmt.Errorf("%s is a %s, not a token transferrer", address, info.Kind)
+	}
+
+	// The token of a NativeTokenHome is the wrapped native token, and a NativeTokenRemote is itself
+	// the wrapped native token, so both hold the wrapped balance.
+	tokenBalance, err := erc20Balance(ctx, backend, info.TokenAddress, account)
+	if err != nil {
+		return holding, err
+	}
+	if !info.Kind.IsNative() {
+		holding.Balance = tokenBalance
+		return holding, nil
+	}
+	holding.WrappedBalance = tokenBalance
+	holding.Balance, err = backend.BalanceAt(ctx, account, nil)
+	if err != nil {
+		return holding, err
+	}
+	return holding, nil
+}
+
+func erc20Balance(ctx context.Context, backend Backend, token, account common.Address) (*big.Int, error) {
+	// Every ERC20 token, including wrapped native tokens, implements balanceOf
+	erc20, err := erc20tokenremote.NewERC20TokenRemoteCaller(token, backend)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := erc20.BalanceOf(&bind.CallOpts{Context: ctx}, account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance of %s: %w", token, err)
+	}
+	return balance, nil
+}
+
+// remote returns the holding at the given remote, if it is registered.
+func (p *Portfolio) remote(blockchainID ids.ID, address common.Address) (Holding, bool) {
+	for _, holding := range p.Remotes {
+		if holding.BlockchainID == blockchainID && holding.Address == address {
+			return holding, true
+		}
+	}
+	return Holding{}, false
+}
+
+// String formats the portfolio as a report, with amounts in the units of the chain they are held on,
+// followed by their value in home token units.
+func (p *Portfolio) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "account %s\n", p.Account.Hex())
+	fmt.Fprintf(&b, "total: %s home tokens\n", p.HomeToken.Format(p.TotalHomeValue))
+
+	holdings := append([]Holding{p.Home}, p.Remotes...)
+	for i, holding := range holdings {
+		role := "remote"
+		if i == 0 {
+			role = "home"
+		}
+		fmt.Fprintf(&b, "%s %s on %s", role, holding.Address.Hex(), holding.BlockchainID)
+		if holding.Unreachable {
+			b.WriteString(": chain not configured\n")
+			continue
+		}
+		fmt.Fprintf(&b, " (%s): %s", holding.Kind, holding.Token.Format(holding.Balance))
+		if holding.Kind.IsNative() {
+			fmt.Fprintf(&b, " native, %s wrapped", holding.Token.Format(holding.WrappedBalance))
+		}
+		fmt.Fprintf(&b, " = %s home tokens\n", p.HomeToken.Format(holding.HomeValue))
+	}
+
+	for _, transfer := range p.InFlight {
+		direction := "out"
+		if transfer.Recipient == p.Account {
+			direction = "in"
+		}
+		value := "unknown"
+		if transfer.HomeValue != nil {
+			value = p.HomeToken.Format(transfer.HomeValue)
+		}
+		fmt.Fprintf(&b, "in flight (%s) %s: %s -> %s, %s home tokens, %s\n",
+			direction, transfer.MessageID, transfer.SourceBlockchainID, transfer.DestinationBlockchainID,
+			value, transfer.Status)
+	}
+	return b.String()
+}
@@ -0,0 +1,162 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package portfolio
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/amounts"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testAccount      = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testOther        = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testHome         = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	testRemoteA      = common.HexToAddress("0x4444444444444444444444444444444444444444")
+	testRemoteB      = common.HexToAddress("0x5555555555555555555555555555555555555555")
+	testHomeChain    = ids.ID{1}
+	testRemoteChainA = ids.ID{2}
+	testRemoteChainB = ids.ID{3}
+)
+
+// newTestPortfolio returns the portfolio of an 18 decimal ERC20 token with a 6 decimal remote on chain A,
+// and an unreachable remote on chain B.
+func newTestPortfolio() *Portfolio {
+	token := amounts.Token{Decimals: 18}
+	return &Portfolio{
+		Account:   testAccount,
+		HomeToken: token,
+		Home: Holding{
+			BlockchainID:   testHomeChain,
+			Address:        testHome,
+			Kind:           inspect.KindERC20TokenHome,
+			Token:          token,
+			Balance:        big.NewInt(2e18),
+			WrappedBalance: big.NewInt(0),
+			HomeValue:      big.NewInt(2e18),
+		},
+		Remotes: []Holding{
+			{
+				BlockchainID:   testRemoteChainA,
+				Address:        testRemoteA,
+				Kind:           inspect.KindERC20TokenRemote,
+				Token:          amounts.Token{Decimals: 6},
+				Balance:        big.NewInt(1_500_000),
+				WrappedBalance: big.NewInt(0),
+				HomeValue:      big.NewInt(15e17),
+				Route:          amounts.NewRoute(18, 6),
+			},
+			{
+				BlockchainID: testRemoteChainB,
+				Address:      testRemoteB,
+				Route:        amounts.NewRoute(18, 18),
+				Unreachable:  true,
+			},
+		},
+		TotalHomeValue: big.NewInt(35e17),
+	}
+}
+
+func TestTransferHomeValue(t *testing.T) {
+	p := newTestPortfolio()
+
+	// Transfers from the home emit the amount scaled to the destination
+	fromHome := Transfer{
+		SourceBlockchainID:      testHomeChain,
+		Source:                  testHome,
+		DestinationBlockchainID: testRemoteChainA,
+		Destination:             testRemoteA,
+		Amount:                  big.NewInt(3_000_000),
+	}
+	require.Equal(t, big.NewInt(3e18), p.transferHomeValue(fromHome))
+
+	// Transfers from a remote emit the amount in units of the remote
+	fromRemote := Transfer{
+		SourceBlockchainID:      testRemoteChainA,
+		Source:                  testRemoteA,
+		DestinationBlockchainID: testRemoteChainB,
+		Destination:             testRemoteB,
+		Amount:                  big.NewInt(250_000),
+	}
+	require.Equal(t, big.NewInt(25e16), p.transferHomeValue(fromRemote))
+
+	// Remotes that are not registered have no known value
+	unregistered := fromHome
+	unregistered.Destination = testOther
+	require.Nil(t, p.transferHomeValue(unregistered))
+}
+
+func TestIsMultiHop(t *testing.T) {
+	config := Config{HomeBlockchainID: testHomeChain, HomeAddress: testHome}
+	testCases := []struct {
+		name     string
+		transfer Transfer
+		expected bool
+	}{
+		{
+			name: "home to remote",
+			transfer: Transfer{
+				SourceBlockchainID: testHomeChain, DestinationBlockchainID: testRemoteChainA, Destination: testRemoteA,
+			},
+		},
+		{
+			name: "remote to home",
+			transfer: Transfer{
+				SourceBlockchainID: testRemoteChainA, DestinationBlockchainID: testHomeChain, Destination: testHome,
+			},
+		},
+		{
+			name: "remote to remote",
+			transfer: Transfer{
+				SourceBlockchainID: testRemoteChainA, DestinationBlockchainID: testRemoteChainB, Destination: testRemoteB,
+			},
+			expected: true,
+		},
+		{
+			name: "remote to another remote on the home chain",
+			transfer: Transfer{
+				SourceBlockchainID: testRemoteChainA, DestinationBlockchainID: testHomeChain, Destination: testOther,
+			},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.transfer.isMultiHop(config))
+		})
+	}
+}
+
+func TestPortfolioString(t *testing.T) {
+	p := newTestPortfolio()
+	p.InFlight = []Transfer{
+		{
+			MessageID:               ids.ID{9},
+			SourceBlockchainID:      testRemoteChainA,
+			DestinationBlockchainID: testHomeChain,
+			Sender:                  testOther,
+			Recipient:               testAccount,
+			HomeValue:               big.NewInt(5e17),
+			Status:                  StatusPending,
+		},
+	}
+
+	lines := strings.Split(strings.TrimSpace(p.String()), "\n")
+	require.Equal(t, []string{
+		"account " + testAccount.Hex(),
+		"total: 3.5 home tokens",
+		"home " + testHome.Hex() + " on " + testHomeChain.String() + " (ERC20TokenHome): 2 = 2 home tokens",
+		"remote " + testRemoteA.Hex() + " on " + testRemoteChainA.String() + " (ERC20TokenRemote): 1.5 = 1.5 home tokens",
+		"remote " + testRemoteB.Hex() + " on " + testRemoteChainB.String() + ": chain not configured",
+		"in flight (in) " + ids.ID{9}.String() + ": " + testRemoteChainA.String() + " -> " + testHomeChain.String() +
+			", 0.5 home tokens, pending",
+	}, lines)
+}
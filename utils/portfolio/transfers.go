@@ -0,0 +1,358 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/TokenRemote"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/amounts"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	teleportermessenger "github.com/ava-labs/teleporter/abi-bindings/go/teleporter/TeleporterMessenger"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrTransferNotFound is returned by FindTransfer when no token transferrer sent the message.
+var ErrTransferNotFound = errors.New("transfer not found")
+
+// TransferStatus is the delivery status of a transfer.
+type TransferStatus string
+
+const (
+	// The message of the transfer has not been received by its destination.
+	StatusPending TransferStatus = "pending"
+	// The message was received, but its execution failed. It can be retried on the destination.
+	StatusExecutionFailed TransferStatus = "execution failed"
+	// The chain the message is sent to is not in the config, or the second hop of a multi-hop
+	// transfer could not be found within the blocks searched.
+	StatusUnknown TransferStatus = "unknown"
+	// The message was executed on its destination, or the home sent the tokens of a multi-hop transfer
+	// to its multi-hop fallback. Only reported by FindTransfer, since the transfers of a portfolio
+	// are those in flight.
+	StatusDelivered TransferStatus = "delivered"
+)
+
+// Transfer is a transfer of tokens sent by a token transferrer.
+type Transfer struct {
+	// The message of the transfer that has not been executed. For multi-hop transfers
+	// that were routed by the home, this is the message of the second hop.
+	MessageID          ids.ID
+	SourceBlockchainID ids.ID
+	Source             common.Address
+	// The final destination of the transfer
+	DestinationBlockchainID ids.ID
+	Destination             common.Address
+	Sender                  common.Address
+	// The recipient, or the recipient contract of a send and call
+	Recipient common.Address
+	// The amount as emitted by the source, in units of the remote the tokens are sent to or from
+	Amount *big.Int
+	// The amount in home token units, or nil if the remote is not registered with the home
+	HomeValue *big.Int
+	TxHash    common.Hash
+	// True for multi-hop transfers that were routed by the home, and are in flight on the second hop
+	Routed bool
+	Status TransferStatus
+}
+
+// isMultiHop returns true if the transfer is sent from a remote to another remote through the home.
+func (t *Transfer) isMultiHop(config Config) bool {
+	return t.SourceBlockchainID != config.HomeBlockchainID &&
+		(t.DestinationBlockchainID != config.HomeBlockchainID || t.Destination != config.HomeAddress)
+}
+
+// getInFlight returns the transfers sent by or to the account, within the blocks searched,
+// that have not been executed on their destination.
+func getInFlight(ctx context.Context, config Config, p *Portfolio) ([]Transfer, error) {
+	var inFlight []Transfer
+	for _, source := range append([]Holding{p.Home}, p.Remotes...) {
+		if source.Unreachable {
+			continue
+		}
+		transfers, err := sentTransfers(ctx, config, source, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transfers sent by %s on %s: %w", source.Address, source.BlockchainID, err)
+		}
+		for _, transfer := range transfers {
+			if transfer.Sender != p.Account && transfer.Recipient != p.Account {
+				continue
+			}
+			transfer.HomeValue = p.transferHomeValue(transfer)
+			executed, err := resolveStatus(ctx, config, &transfer)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get status of message %s: %w", transfer.MessageID, err)
+			}
+			if !executed {
+				inFlight = append(inFlight, transfer)
+			}
+		}
+	}
+	return inFlight, nil
+}
+
+// FindTransfer returns the transfer sent with the given message by the home or by one of its remotes
+// on the chains of the config, within the blocks searched, together with its status.
+func FindTransfer(ctx context.Context, config Config, messageID ids.ID) (*Transfer, error) {
+	homeBackend, ok := config.Chains[config.HomeBlockchainID]
+	if !ok {
+		return nil, fmt.Errorf("no backend for the home chain %s", config.HomeBlockchainID)
+	}
+	remotes, err := DiscoverRemotes(ctx, homeBackend, config.HomeAddress)
+	if err != nil {
+		return nil, err
+	}
+	sources := []Remote{{BlockchainID: config.HomeBlockchainID, Address: config.HomeAddress}}
+	for _, remote := range remotes {
+		if _, ok := config.Chains[remote.BlockchainID]; ok {
+			sources = append(sources, remote)
+		}
+	}
+
+	for _, source := range sources {
+		holding := Holding{BlockchainID: source.BlockchainID, Address: source.Address}
+		transfers, err := sentTransfers(ctx, config, holding, [][32]byte{messageID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transfers sent by %s on %s: %w", source.Address, source.BlockchainID, err)
+		}
+		if len(transfers) == 0 {
+			continue
+		}
+		transfer := transfers[0]
+		transfer.HomeValue, err = transferHomeValue(ctx, config, transfer)
+		if err != nil {
+			return nil, err
+		}
+		executed, err := resolveStatus(ctx, config, &transfer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get status of message %s: %w", transfer.MessageID, err)
+		}
+		if executed {
+			transfer.Status = StatusDelivered
+		}
+		return &transfer, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTransferNotFound, messageID)
+}
+
+// sentTransfers returns the transfers sent from the token transferrer, within the blocks searched.
+// If messageIDs is not empty, only the transfers sent with those messages are returned.
+func sentTransfers(ctx context.Context, config Config, source Holding, messageIDs [][32]byte) ([]Transfer, error) {
+	backend := config.Chains[source.BlockchainID]
+	opts, err := filterOpts(ctx, backend, config.LookBackBlocks)
+	if err != nil {
+		return nil, err
+	}
+	// TokenHome and TokenRemote emit the same TokensSent and TokensAndCallSent events,
+	// so the events of both are read with the TokenRemote bindings.
+	filterer, err := tokenremote.NewTokenRemoteFilterer(source.Address, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	var transfers []Transfer
+	sent, err := filterer.FilterTokensSent(opts, messageIDs, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer sent.Close()
+	for sent.Next() {
+		transfers = append(transfers, Transfer{
+			MessageID:               sent.Event.TeleporterMessageID,
+			SourceBlockchainID:      source.BlockchainID,
+			Source:                  source.Address,
+			DestinationBlockchainID: sent.Event.Input.DestinationBlockchainID,
+			Destination:             sent.Event.Input.DestinationTokenTransferrerAddress,
+			Sender:                  sent.Event.Sender,
+			Recipient:               sent.Event.Input.Recipient,
+			Amount:                  sent.Event.Amount,
+			TxHash:                  sent.Event.Raw.TxHash,
+		})
+	}
+	if err := sent.Error(); err != nil {
+		return nil, err
+	}
+
+	sentAndCall, err := filterer.FilterTokensAndCallSent(opts, messageIDs, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer sentAndCall.Close()
+	for sentAndCall.Next() {
+		transfers = append(transfers, Transfer{
+			MessageID:               sentAndCall.Event.TeleporterMessageID,
+			SourceBlockchainID:      source.BlockchainID,
+			Source:                  source.Address,
+			DestinationBlockchainID: sentAndCall.Event.Input.DestinationBlockchainID,
+			Destination:             sentAndCall.Event.Input.DestinationTokenTransferrerAddress,
+			Sender:                  sentAndCall.Event.Sender,
+			Recipient:               sentAndCall.Event.Input.RecipientContract,
+			Amount:                  sentAndCall.Event.Amount,
+			TxHash:                  sentAndCall.Event.Raw.TxHash,
+		})
+	}
+	return transfers, sentAndCall.Error()
+}
+
+// transferHomeValue returns the amount of the transfer in home token units. Transfers from the home
+// emit the amount scaled to the destination remote, and transfers from a remote emit the amount
+// in units of the remote.
+func (p *Portfolio) transferHomeValue(transfer Transfer) *big.Int {
+	remoteBlockchainID, remoteAddress := transfer.SourceBlockchainID, transfer.Source
+	if transfer.SourceBlockchainID == p.Home.BlockchainID && transfer.Source == p.Home.Address {
+		remoteBlockchainID, remoteAddress = transfer.DestinationBlockchainID, transfer.Destination
+	}
+	remote, ok := p.remote(remoteBlockchainID, remoteAddress)
+	if !ok {
+		return nil
+	}
+	return remote.Route.HomeValue(transfer.Amount)
+}
+
+// transferHomeValue returns the amount of the transfer in home token units, as Portfolio.transferHomeValue,
+// reading the settings of the remote from the home.
+func transferHomeValue(ctx context.Context, config Config, transfer Transfer) (*big.Int, error) {
+	remoteBlockchainID, remoteAddress := transfer.SourceBlockchainID, transfer.Source
+	if transfer.SourceBlockchainID == config.HomeBlockchainID && transfer.Source == config.HomeAddress {
+		remoteBlockchainID, remoteAddress = transfer.DestinationBlockchainID, transfer.Destination
+	}
+	route, err := amounts.HomeRoute(
+		ctx,
+		config.Chains[config.HomeBlockchainID],
+		config.HomeAddress,
+		remoteBlockchainID,
+		remoteAddress,
+	)
+	if errors.Is(err, amounts.ErrRemoteNotRegistered) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return route.HomeValue(transfer.Amount), nil
+}
+
+// resolveStatus sets the status of the transfer, and returns true if it was executed on its destination.
+// The first hop of a multi-hop transfer is executed by the home, which routes it to the destination
+// with a new message, so the status of a routed transfer is that of the second message.
+func resolveStatus(ctx context.Context, config Config, transfer *Transfer) (bool, error) {
+	if !transfer.isMultiHop(config) {
+		return messageStatus(ctx, config, transfer, transfer.DestinationBlockchainID)
+	}
+
+	executed, err := messageStatus(ctx, config, transfer, config.HomeBlockchainID)
+	if err != nil || !executed {
+		return executed, err
+	}
+	routedMessageID, found, err := routedMessage(ctx, config, transfer.MessageID)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		transfer.Status = StatusUnknown
+		return false, nil
+	}
+	if routedMessageID == ids.Empty {
+		// The home sent the tokens to the multi-hop fallback instead of routing them
+		return true, nil
+	}
+	transfer.MessageID = routedMessageID
+	transfer.Routed = true
+	return messageStatus(ctx, config, transfer, transfer.DestinationBlockchainID)
+}
+
+// messageStatus sets the status of the transfer from the delivery of its message to the given chain,
+// and returns true if the message was executed.
+func messageStatus(ctx context.Context, config Config, transfer *Transfer, blockchainID ids.ID) (bool, error) {
+	backend, ok := config.Chains[blockchainID]
+	if !ok {
+		transfer.Status = StatusUnknown
+		return false, nil
+	}
+	teleporter, err := teleportermessenger.NewTeleporterMessengerCaller(config.TeleporterAddress, backend)
+	if err != nil {
+		return false, err
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	received, err := teleporter.MessageReceived(opts, transfer.MessageID)
+	if err != nil {
+		return false, err
+	}
+	if !received {
+		transfer.Status = StatusPending
+		return false, nil
+	}
+	failedMessageHash, err := teleporter.ReceivedFailedMessageHashes(opts, transfer.MessageID)
+	if err != nil {
+		return false, err
+	}
+	if failedMessageHash != ([32]byte{}) {
+		transfer.Status = StatusExecutionFailed
+		return false, nil
+	}
+	return true, nil
+}
+
+// routedMessage returns the ID of the message sent by the home to route the transfer of the given message,
+// or the empty ID if the home did not route it. found is false if the execution of the message by the home
+// was not within the blocks searched.
+func routedMessage(ctx context.Context, config Config, messageID ids.ID) (ids.ID, bool, error) {
+	backend := config.Chains[config.HomeBlockchainID]
+	opts, err := filterOpts(ctx, backend, config.LookBackBlocks)
+	if err != nil {
+		return ids.Empty, false, err
+	}
+	teleporter, err := teleportermessenger.NewTeleporterMessengerFilterer(config.TeleporterAddress, backend)
+	if err != nil {
+		return ids.Empty, false, err
+	}
+	executed, err := teleporter.FilterMessageExecuted(opts, [][32]byte{messageID}, nil)
+	if err != nil {
+		return ids.Empty, false, err
+	}
+	defer executed.Close()
+	if !executed.Next() {
+		return ids.Empty, false, executed.Error()
+	}
+
+	receipt, err := backend.TransactionReceipt(ctx, executed.Event.Raw.TxHash)
+	if err != nil {
+		return ids.Empty, false, err
+	}
+	home, err := tokenhome.NewTokenHomeFilterer(config.HomeAddress, backend)
+	if err != nil {
+		return ids.Empty, false, err
+	}
+	for _, log := range receipt.Logs {
+		if log.Address != config.HomeAddress {
+			continue
+		}
+		if routed, err := home.ParseTokensRouted(*log); err == nil {
+			return routed.TeleporterMessageID, true, nil
+		}
+		if routed, err := home.ParseTokensAndCallRouted(*log); err == nil {
+			return routed.TeleporterMessageID, true, nil
+		}
+	}
+	return ids.Empty, true, nil
+}
+
+func filterOpts(ctx context.Context, backend Backend, lookBackBlocks uint64) (*bind.FilterOpts, error) {
+	opts := &bind.FilterOpts{Context: ctx}
+	if lookBackBlocks == 0 {
+		return opts, nil
+	}
+	latest, err := backend.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if latest > lookBackBlocks {
+		opts.Start = latest - lookBackBlocks
+	}
+	return opts, nil
+}
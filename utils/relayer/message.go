@@ -0,0 +1,235 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package relayer
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MessageType is the type of a message sent between token transferrers, as defined by
+// TransferrerMessageType in ITokenTransferrer.sol.
+type MessageType uint8
+
+const (
+	RegisterRemote MessageType = iota
+	SingleHopSend
+	SingleHopCall
+	MultiHopSend
+	MultiHopCall
+)
+
+var messageTypeNames = map[MessageType]string{
+	RegisterRemote: "register remote",
+	SingleHopSend:  "single-hop send",
+	SingleHopCall:  "single-hop call",
+	MultiHopSend:   "multi-hop send",
+	MultiHopCall:   "multi-hop call",
+}
+
+func (t MessageType) String() string {
+	if name, ok := messageTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown message type %d", uint8(t))
+}
+
+// IsMultiHop returns true for the messages sent by a remote that the home routes to another remote.
+func (t MessageType) IsMultiHop() bool {
+	return t == MultiHopSend || t == MultiHopCall
+}
+
+var ErrUnknownMessageType = errors.New("unknown message type")
+
+// Message is the decoded payload of a Teleporter message sent between token transferrers.
+type Message struct {
+	Type MessageType
+	// The amount of tokens transferred, as encoded by the sender. Nil for RegisterRemote messages.
+	Amount *big.Int
+	// The recipient, or the recipient contract of a call. Empty for RegisterRemote messages.
+	Recipient common.Address
+	// The final destination of a multi-hop message, which the home routes the tokens to.
+	FinalDestinationBlockchainID ids.ID
+	FinalDestination             common.Address
+	// The fee and required gas limit of the message sent by the home for the second hop
+	// of a multi-hop message. Nil for other messages.
+	SecondaryFee      *big.Int
+	SecondaryGasLimit *big.Int
+}
+
+// The payload structs of ITokenTransferrer.sol, with fields named as the tuples unpacked by abi.
+type (
+	transferrerMessage struct {
+		MessageType uint8
+		Payload     []byte
+	}
+	registerRemoteMessage struct {
+		InitialReserveImbalance *big.Int
+		HomeTokenDecimals       uint8
+		RemoteTokenDecimals     uint8
+	}
+	singleHopSendMessage struct {
+		Recipient common.Address
+		Amount    *big.Int
+	}
+	singleHopCallMessage struct {
+		SourceBlockchainID            [32]byte
+		OriginTokenTransferrerAddress common.Address
+		OriginSenderAddress           common.Address
+		RecipientContract             common.Address
+		Amount                        *big.Int
+		RecipientPayload              []byte
+		RecipientGasLimit             *big.Int
+		FallbackRecipient             common.Address
+	}
+	multiHopSendMessage struct {
+		DestinationBlockchainID            [32]byte
+		DestinationTokenTransferrerAddress common.Address
+		Recipient                          common.Address
+		Amount                             *big.Int
+		SecondaryFee                       *big.Int
+		SecondaryGasLimit                  *big.Int
+		MultiHopFallback                   common.Address
+	}
+	multiHopCallMessage struct {
+		OriginSenderAddress                common.Address
+		DestinationBlockchainID            [32]byte
+		DestinationTokenTransferrerAddress common.Address
+		RecipientContract                  common.Address
+		Amount                             *big.Int
+		RecipientPayload                   []byte
+		RecipientGasLimit                  *big.Int
+		FallbackRecipient                  common.Address
+		SecondaryRequiredGasLimit          *big.Int
+		MultiHopFallback                   common.Address
+		SecondaryFee                       *big.Int
+	}
+)
+
+var (
+	transferrerMessageArgs = tupleArguments(
+		abi.ArgumentMarshaling{Name: "messageType", Type: "uint8"},
+		abi.ArgumentMarshaling{Name: "payload", Type: "bytes"},
+	)
+	payloadArgs = map[MessageType]abi.Arguments{
+		RegisterRemote: tupleArguments(
+			abi.ArgumentMarshaling{Name: "initialReserveImbalance", Type: "uint256"},
+			abi.ArgumentMarshaling{Name: "homeTokenDecimals", Type: "uint8"},
+			abi.ArgumentMarshaling{Name: "remoteTokenDecimals", Type: "uint8"},
+		),
+		SingleHopSend: tupleArguments(
+			abi.ArgumentMarshaling{Name: "recipient", Type: "address"},
+			abi.ArgumentMarshaling{Name: "amount", Type: "uint256"},
+		),
+		SingleHopCall: tupleArguments(
+			abi.ArgumentMarshaling{Name: "sourceBlockchainID", Type: "bytes32"},
+			abi.ArgumentMarshaling{Name: "originTokenTransferrerAddress", Type: "address"},
+			abi.ArgumentMarshaling{Name: "originSenderAddress", Type: "address"},
+			abi.ArgumentMarshaling{Name: "recipientContract", Type: "address"},
+			abi.ArgumentMarshaling{Name: "amount", Type: "uint256"},
+			abi.ArgumentMarshaling{Name: "recipientPayload", Type: "bytes"},
+			abi.ArgumentMarshaling{Name: "recipientGasLimit", Type: "uint256"},
+			abi.ArgumentMarshaling{Name: "fallbackRecipient", Type: "address"},
+		),
+		MultiHopSend: tupleArguments(
+			abi.ArgumentMarshaling{Name: "destinationBlockchainID", Type: "bytes32"},
+			abi.ArgumentMarshaling{Name: "destinationTokenTransferrerAddress", Type: "address"},
+			abi.ArgumentMarshaling{Name: "recipient", Type: "address"},
+			abi.ArgumentMarshaling{Name: "amount", Type: "uint256"},
+			abi.ArgumentMarshaling{Name: "secondaryFee", Type: "uint256"},
+			abi.ArgumentMarshaling{Name: "secondaryGasLimit", Type: "uint256"},
+			abi.ArgumentMarshaling{Name: "multiHopFallback", Type: "address"},
+		),
+		MultiHopCall: tupleArguments(
+			abi.ArgumentMarshaling{Name: "originSenderAddress", Type: "address"},
+			abi.ArgumentMarshaling{Name: "destinationBlockchainID", Type: "bytes32"},
+			abi.ArgumentMarshaling{Name: "destinationTokenTransferrerAddress", Type: "address"},
+			abi.ArgumentMarshaling{Name: "recipientContract", Type: "address"},
+			abi.ArgumentMarshaling{Name: "amount", Type: "uint256"},
+			abi.ArgumentMarshaling{Name: "recipientPayload", Type: "bytes"},
+			abi.ArgumentMarshaling{Name: "recipientGasLimit", Type: "uint256"},
+			abi.ArgumentMarshaling{Name: "fallbackRecipient", Type: "address"},
+			abi.ArgumentMarshaling{Name: "secondaryRequiredGasLimit", Type: "uint256"},
+			abi.ArgumentMarshaling{Name: "multiHopFallback", Type: "address"},
+			abi.ArgumentMarshaling{Name: "secondaryFee", Type: "uint256"},
+		),
+	}
+)
+
+// tupleArguments returns the arguments of a single struct with the given fields, as encoded by abi.encode.
+func tupleArguments(fields ...abi.ArgumentMarshaling) abi.Arguments {
+	tupleType, err := abi.NewType("tuple", "", fields)
+	if err != nil {
+		panic(err)
+	}
+	return abi.Arguments{{Type: tupleType}}
+}
+
+// unpackTuple decodes data encoded with the arguments of a single struct into out.
+func unpackTuple(arguments abi.Arguments, data []byte, out interface{}) error {
+	values, err := arguments.Unpack(data)
+	if err != nil {
+		return err
+	}
+	abi.ConvertType(values[0], out)
+	return nil
+}
+
+// DecodeMessage decodes the payload of a Teleporter message sent by a token transferrer.
+func DecodeMessage(data []byte) (*Message, error) {
+	var wrapper transferrerMessage
+	if err := unpackTuple(transferrerMessageArgs, data, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to decode transferrer message: %w", err)
+	}
+	messageType := MessageType(wrapper.MessageType)
+	arguments, ok := payloadArgs[messageType]
+	if !ok {
+		return nil, fmt.Errorf("%w %d", ErrUnknownMessageType, wrapper.MessageType)
+	}
+
+	message := &Message{Type: messageType}
+	var err error
+	switch messageType {
+	case RegisterRemote:
+		var payload registerRemoteMessage
+		err = unpackTuple(arguments, wrapper.Payload, &payload)
+	case SingleHopSend:
+		var payload singleHopSendMessage
+		err = unpackTuple(arguments, wrapper.Payload, &payload)
+		message.Amount = payload.Amount
+		message.Recipient = payload.Recipient
+	case SingleHopCall:
+		var payload singleHopCallMessage
+		err = unpackTuple(arguments, wrapper.Payload, &payload)
+		message.Amount = payload.Amount
+		message.Recipient = payload.RecipientContract
+	case MultiHopSend:
+		var payload multiHopSendMessage
+		err = unpackTuple(arguments, wrapper.Payload, &payload)
+		message.Amount = payload.Amount
+		message.Recipient = payload.Recipient
+		message.FinalDestinationBlockchainID = payload.DestinationBlockchainID
+		message.FinalDestination = payload.DestinationTokenTransferrerAddress
+		message.SecondaryFee = payload.SecondaryFee
+		message.SecondaryGasLimit = payload.SecondaryGasLimit
+	case MultiHopCall:
+		var payload multiHopCallMessage
+		err = unpackTuple(arguments, wrapper.Payload, &payload)
+		message.Amount = payload.Amount
+		message.Recipient = payload.RecipientContract
+		message.FinalDestinationBlockchainID = payload.DestinationBlockchainID
+		message.FinalDestination = payload.DestinationTokenTransferrerAddress
+		message.SecondaryFee = payload.SecondaryFee
+		message.SecondaryGasLimit = payload.SecondaryRequiredGasLimit
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s payload: %w", messageType, err)
+	}
+	return message, nil
+}
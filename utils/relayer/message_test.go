@@ -0,0 +1,150 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package relayer
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testRecipient   = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testDestination = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testFallback    = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	testBlockchain  = ids.ID{1, 2, 3}
+)
+
+// encodeMessage encodes a payload as abi.encode(TransferrerMessage(messageType, abi.encode(payload))).
+func encodeMessage(t *testing.T, messageType MessageType, payload interface{}) []byte {
+	payloadBytes, err := payloadArgs[messageType].Pack(payload)
+	require.NoError(t, err)
+	data, err := transferrerMessageArgs.Pack(transferrerMessage{
+		MessageType: uint8(messageType),
+		Payload:     payloadBytes,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func TestDecodeMessage(t *testing.T) {
+	testCases := []struct {
+		name        string
+		messageType MessageType
+		payload     interface{}
+		expected    *Message
+	}{
+		{
+			name:        "register remote",
+			messageType: RegisterRemote,
+			payload: registerRemoteMessage{
+				InitialReserveImbalance: big.NewInt(100),
+				HomeTokenDecimals:       18,
+				RemoteTokenDecimals:     6,
+			},
+			expected: &Message{Type: RegisterRemote},
+		},
+		{
+			name:        "single-hop send",
+			messageType: SingleHopSend,
+			payload:     singleHopSendMessage{Recipient: testRecipient, Amount: big.NewInt(7)},
+			expected:    &Message{Type: SingleHopSend, Recipient: testRecipient, Amount: big.NewInt(7)},
+		},
+		{
+			name:        "single-hop call",
+			messageType: SingleHopCall,
+			payload: singleHopCallMessage{
+				SourceBlockchainID: testBlockchain,
+				RecipientContract:  testRecipient,
+				Amount:             big.NewInt(8),
+				RecipientPayload:   []byte{1, 2},
+				RecipientGasLimit:  big.NewInt(100_000),
+				FallbackRecipient:  testFallback,
+			},
+			expected: &Message{Type: SingleHopCall, Recipient: testRecipient, Amount: big.NewInt(8)},
+		},
+		{
+			name:        "multi-hop send",
+			messageType: MultiHopSend,
+			payload: multiHopSendMessage{
+				DestinationBlockchainID:            testBlockchain,
+				DestinationTokenTransferrerAddress: testDestination,
+				Recipient:                          testRecipient,
+				Amount:                             big.NewInt(9),
+				SecondaryFee:                       big.NewInt(1),
+				SecondaryGasLimit:                  big.NewInt(250_000),
+				MultiHopFallback:                   testFallback,
+			},
+			expected: &Message{
+				Type:                         MultiHopSend,
+				Recipient:                    testRecipient,
+				Amount:                       big.NewInt(9),
+				FinalDestinationBlockchainID: testBlockchain,
+				FinalDestination:             testDestination,
+				SecondaryFee:                 big.NewInt(1),
+				SecondaryGasLimit:            big.NewInt(250_000),
+			},
+		},
+		{
+			name:        "multi-hop call",
+			messageType: MultiHopCall,
+			payload: multiHopCallMessage{
+				DestinationBlockchainID:            testBlockchain,
+				DestinationTokenTransferrerAddress: testDestination,
+				RecipientContract:                  testRecipient,
+				Amount:                             big.NewInt(10),
+				RecipientPayload:                   []byte{3},
+				RecipientGasLimit:                  big.NewInt(100_000),
+				FallbackRecipient:                  testFallback,
+				SecondaryRequiredGasLimit:          big.NewInt(300_000),
+				MultiHopFallback:                   testFallback,
+				SecondaryFee:                       big.NewInt(2),
+			},
+			expected: &Message{
+				Type:                         MultiHopCall,
+				Recipient:                    testRecipient,
+				Amount:                       big.NewInt(10),
+				FinalDestinationBlockchainID: testBlockchain,
+				FinalDestination:             testDestination,
+				SecondaryFee:                 big.NewInt(2),
+				SecondaryGasLimit:            big.NewInt(300_000),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			message, err := DecodeMessage(encodeMessage(t, tc.messageType, tc.payload))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, message)
+		})
+	}
+}
+
+func TestDecodeMessageErrors(t *testing.T) {
+	data, err := transferrerMessageArgs.Pack(transferrerMessage{MessageType: 5, Payload: []byte{}})
+	require.NoError(t, err)
+	_, err = DecodeMessage(data)
+	require.ErrorIs(t, err, ErrUnknownMessageType)
+
+	_, err = DecodeMessage([]byte{1, 2, 3})
+	require.ErrorContains(t, err, "failed to decode transferrer message")
+
+	data, err = transferrerMessageArgs.Pack(transferrerMessage{MessageType: uint8(SingleHopSend), Payload: []byte{1}})
+	require.NoError(t, err)
+	_, err = DecodeMessage(data)
+	require.ErrorContains(t, err, "failed to decode single-hop send payload")
+}
+
+func TestMessageTypeIsMultiHop(t *testing.T) {
+	require.False(t, SingleHopSend.IsMultiHop())
+	require.False(t, SingleHopCall.IsMultiHop())
+	require.True(t, MultiHopSend.IsMultiHop())
+	require.True(t, MultiHopCall.IsMultiHop())
+	require.Equal(t, "multi-hop call", MultiHopCall.String())
+	require.Equal(t, "unknown message type 9", MessageType(9).String())
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package relayer
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	teleportermessenger "github.com/ava-labs/teleporter/abi-bindings/go/teleporter/TeleporterMessenger"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrFeeTokenNotAllowed = errors.New("fee token not allowed")
+	ErrFeeTooLow          = errors.New("fee too low")
+	ErrAmountTooLow       = errors.New("amount too low")
+)
+
+// Policy decides which of the messages sent by the token transferrers of a chain are relayed.
+type Policy struct {
+	// Minimum primary fee of a message, by fee token. Messages paying their fee in other tokens are not
+	// relayed. If empty, messages are relayed whatever their fee.
+	MinFees map[common.Address]*big.Int `json:"minFees,omitempty"`
+	// Minimum amount of tokens transferred by a message, as encoded by the sender. RegisterRemote messages
+	// transfer no tokens and are relayed whatever this amount. If nil, any amount is relayed.
+	MinAmount *big.Int `json:"minAmount,omitempty"`
+}
+
+// Check returns nil if a message with the given fee and payload should be relayed,
+// or the reason it should not be.
+func (p Policy) Check(feeInfo teleportermessenger.TeleporterFeeInfo, message *Message) error {
+	if len(p.MinFees) != 0 {
+		minFee, ok := p.MinFees[feeInfo.FeeTokenAddress]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrFeeTokenNotAllowed, feeInfo.FeeTokenAddress)
+		}
+		if feeInfo.Amount.Cmp(minFee) < 0 {
+			return fmt.Errorf("%w: %s < %s", ErrFeeTooLow, feeInfo.Amount, minFee)
+		}
+	}
+	if p.MinAmount != nil && message.Type != RegisterRemote && message.Amount.Cmp(p.MinAmount) < 0 {
+		return fmt.Errorf("%w: %s < %s", ErrAmountTooLow, message.Amount, p.MinAmount)
+	}
+	return nil
+}
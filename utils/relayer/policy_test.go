@@ -0,0 +1,81 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package relayer
+
+import (
+	"math/big"
+	"testing"
+
+	teleportermessenger "github.com/ava-labs/teleporter/abi-bindings/go/teleporter/TeleporterMessenger"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	feeToken := common.HexToAddress("0x4444444444444444444444444444444444444444")
+	otherToken := common.HexToAddress("0x5555555555555555555555555555555555555555")
+	policy := Policy{
+		MinFees:   map[common.Address]*big.Int{feeToken: big.NewInt(10)},
+		MinAmount: big.NewInt(100),
+	}
+	send := &Message{Type: SingleHopSend, Amount: big.NewInt(100)}
+
+	testCases := []struct {
+		name     string
+		policy   Policy
+		feeInfo  teleportermessenger.TeleporterFeeInfo
+		message  *Message
+		expected error
+	}{
+		{
+			name:    "allowed",
+			policy:  policy,
+			feeInfo: teleportermessenger.TeleporterFeeInfo{FeeTokenAddress: feeToken, Amount: big.NewInt(10)},
+			message: send,
+		},
+		{
+			name:     "fee token not allowed",
+			policy:   policy,
+			feeInfo:  teleportermessenger.TeleporterFeeInfo{FeeTokenAddress: otherToken, Amount: big.NewInt(10)},
+			message:  send,
+			expected: ErrFeeTokenNotAllowed,
+		},
+		{
+			name:     "fee too low",
+			policy:   policy,
+			feeInfo:  teleportermessenger.TeleporterFeeInfo{FeeTokenAddress: feeToken, Amount: big.NewInt(9)},
+			message:  send,
+			expected: ErrFeeTooLow,
+		},
+		{
+			name:     "amount too low",
+			policy:   policy,
+			feeInfo:  teleportermessenger.TeleporterFeeInfo{FeeTokenAddress: feeToken, Amount: big.NewInt(10)},
+			message:  &Message{Type: MultiHopSend, Amount: big.NewInt(99)},
+			expected: ErrAmountTooLow,
+		},
+		{
+			name:    "register remote transfers no tokens",
+			policy:  policy,
+			feeInfo: teleportermessenger.TeleporterFeeInfo{FeeTokenAddress: feeToken, Amount: big.NewInt(10)},
+			message: &Message{Type: RegisterRemote},
+		},
+		{
+			name:    "empty policy allows any fee",
+			feeInfo: teleportermessenger.TeleporterFeeInfo{Amount: big.NewInt(0)},
+			message: &Message{Type: SingleHopSend, Amount: big.NewInt(1)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.policy.Check(tc.feeInfo, tc.message)
+			if tc.expected == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.expected)
+		})
+	}
+}
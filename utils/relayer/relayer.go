@@ -0,0 +1,535 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package relayer delivers the Teleporter messages sent between token transferrers. Unlike a generic
+// Teleporter relayer, it only relays the messages of the token transferrers it is configured with,
+// and decides from the decoded payload and the fee of each message whether to relay it.
+//
+// The SendCrossChainMessage events of every chain are followed with a subscription.Subscriber. The
+// Warp signatures of each message are aggregated by a node of the source chain, and the message is
+// delivered with the gas needed for its required gas limit, its size and its number of signers.
+// Messages sent by the delivery of a message, such as the second hop of a multi-hop transfer routed
+// by the home, are relayed as soon as the delivery is accepted.
+package relayer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/subscription"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/utils/constants"
+	avalancheWarp "github.com/ava-labs/avalanchego/vms/platformvm/warp"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/ethclient"
+	"github.com/ava-labs/subnet-evm/precompile/contracts/warp"
+	predicateutils "github.com/ava-labs/subnet-evm/predicate"
+	warpBackend "github.com/ava-labs/subnet-evm/warp"
+	teleportermessenger "github.com/ava-labs/teleporter/abi-bindings/go/teleporter/TeleporterMessenger"
+	gasUtils "github.com/ava-labs/teleporter/utils/gas-utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultSignatureTimeout = 30 * time.Second
+	signaturePollInterval   = 500 * time.Millisecond
+
+	// Capacity of the channel of the SendCrossChainMessage logs of each chain
+	logBuffer = 64
+)
+
+var (
+	// ErrAlreadyDelivered is the result of messages that were received by their destination
+	// before the relayer could deliver them.
+	ErrAlreadyDelivered = errors.New("message already delivered")
+	// ErrDeliveryReverted is the result of messages whose delivery transaction reverted.
+	ErrDeliveryReverted = errors.New("delivery transaction reverted")
+
+	errMissingKey        = errors.New("missing relayer key")
+	errMissingTeleporter = errors.New("missing Teleporter contract address")
+	errMissingClient     = errors.New("missing client")
+	errMissingDial       = errors.New("missing dial function")
+	errMissingNodeURI    = errors.New("missing node URI")
+	errDuplicateChain    = errors.New("duplicate chain")
+	errNoWarpMessage     = errors.New("no Warp message before the SendCrossChainMessage log")
+)
+
+// Chain is a chain the relayer relays messages from and delivers messages to.
+type Chain struct {
+	BlockchainID ids.ID
+	// The primary network ID for the C-Chain
+	SubnetID ids.ID
+	// Client reads the chain and sends the delivery transactions.
+	Client ethclient.Client
+	// Dial connects to the chain to subscribe to the messages sent on it, typically over a websocket.
+	// It is called again to reconnect after the connection fails.
+	Dial func(ctx context.Context) (subscription.Backend, error)
+	// URI of a node of the chain that aggregates the Warp signatures of the messages sent on the chain
+	NodeURI string
+	// The token transferrers on the chain. Only the messages sent from one of the token transferrers of
+	// a chain to one of the token transferrers of another chain are relayed.
+	Transferrers []common.Address
+	// Decides which of the messages sent from the chain are relayed
+	Policy Policy
+	// The first block searched for messages sent from the chain
+	FromBlock uint64
+}
+
+// Config configures a Relayer.
+type Config struct {
+	Chains                    []Chain
+	TeleporterContractAddress common.Address
+	// Pays for the delivery transactions, and receives the fees of the messages it delivers.
+	Key *ecdsa.PrivateKey
+	// Number of blocks on top of the block of a message before it is relayed.
+	Confirmations uint64
+	// Time to wait for the signatures of a message to be aggregated. Defaults to 30 seconds.
+	SignatureTimeout time.Duration
+}
+
+// Result is the outcome of handling a message sent between the token transferrers of the relayer.
+type Result struct {
+	MessageID               ids.ID
+	SourceBlockchainID      ids.ID
+	DestinationBlockchainID ids.ID
+	// The decoded payload, or nil if it could not be decoded
+	Message *Message
+	// Nil if the message was delivered. Otherwise, the reason it was not relayed: a policy error,
+	// ErrAlreadyDelivered, or the failure of the delivery.
+	Err error
+	// The receipt of the delivery transaction, if it was accepted.
+	DeliveryReceipt *types.Receipt
+	// True if the message was delivered but its execution failed. It can then be retried on the destination.
+	ExecutionFailed bool
+}
+
+// isFinal returns true if the message of the result should not be relayed again when it is seen again.
+func (r *Result) isFinal() bool {
+	if r.Err == nil || r.Message == nil {
+		return true
+	}
+	for _, err := range []error{ErrAlreadyDelivered, ErrFeeTokenNotAllowed, ErrFeeTooLow, ErrAmountTooLow} {
+		if errors.Is(r.Err, err) {
+			return true
+		}
+	}
+	return false
+}
+
+type chain struct {
+	Chain
+	evmChainID   *big.Int
+	teleporter   *teleportermessenger.TeleporterMessenger
+	transferrers map[common.Address]bool
+	// Held while a delivery transaction is sent to the chain, so that their nonces do not collide
+	deliveryLock sync.Mutex
+}
+
+// Relayer relays the messages sent between token transferrers.
+type Relayer struct {
+	config  Config
+	chains  map[ids.ID]*chain
+	address common.Address
+	// The messages being handled, or already handled with a final result, by message ID
+	handled sync.Map
+}
+
+// New connects to the chains of the config.
+func New(ctx context.Context, config Config) (*Relayer, error) {
+	if config.Key == nil {
+		return nil, errMissingKey
+	}
+	if config.TeleporterContractAddress == (common.Address{}) {
+		return nil, errMissingTeleporter
+	}
+	if config.SignatureTimeout == 0 {
+		config.SignatureTimeout = defaultSignatureTimeout
+	}
+
+	r := &Relayer{
+		config:  config,
+		chains:  make(map[ids.ID]*chain),
+		address: crypto.PubkeyToAddress(config.Key.PublicKey),
+	}
+	for _, c := range config.Chains {
+		switch {
+		case c.Client == nil:
+			return nil, fmt.Errorf("%s: %w", c.BlockchainID, errMissingClient)
+		case c.Dial == nil:
+			return nil, fmt.Errorf("%s: %w", c.BlockchainID, errMissingDial)
+		case c.NodeURI == "":
+			return nil, fmt.Errorf("%s: %w", c.BlockchainID, errMissingNodeURI)
+		case r.chains[c.BlockchainID] != nil:
+			return nil, fmt.Errorf("%s: %w", c.BlockchainID, errDuplicateChain)
+		}
+		evmChainID, err := c.Client.ChainID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chain ID of %s: %w", c.BlockchainID, err)
+		}
+		teleporter, err := teleportermessenger.NewTeleporterMessenger(config.TeleporterContractAddress, c.Client)
+		if err != nil {
+			return nil, err
+		}
+		transferrers := make(map[common.Address]bool)
+		for _, address := range c.Transferrers {
+			transferrers[address] = true
+		}
+		r.chains[c.BlockchainID] = &chain{
+			Chain:        c,
+			evmChainID:   evmChainID,
+			teleporter:   teleporter,
+			transferrers: transferrers,
+		}
+	}
+	return r, nil
+}
+
+// Address returns the address that pays for the deliveries and receives the fees.
+func (r *Relayer) Address() common.Address {
+	return r.address
+}
+
+// Run relays messages until ctx is done or the subscription to the messages of a chain fails.
+// The result of handling each message is sent to results, unless it is nil.
+func (r *Relayer) Run(ctx context.Context, results chan<- Result) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(r.chains))
+	for _, c := range r.chains {
+		go func(c *chain) {
+			errs <- r.watch(ctx, c, results)
+		}(c)
+	}
+	err := <-errs
+	cancel()
+	for i := 1; i < len(r.chains); i++ {
+		<-errs
+	}
+	return err
+}
+
+// watch handles the messages sent on the chain.
+func (r *Relayer) watch(ctx context.Context, c *chain, results chan<- Result) error {
+	teleporterABI, err := teleportermessenger.TeleporterMessengerMetaData.GetAbi()
+	if err != nil {
+		return err
+	}
+	query, err := subscription.EventQuery(
+		teleporterABI,
+		[]common.Address{r.config.TeleporterContractAddress},
+		"SendCrossChainMessage",
+	)
+	if err != nil {
+		return err
+	}
+	subscriber, err := subscription.NewSubscriber(subscription.Config{
+		Query:         query,
+		Confirmations: r.config.Confirmations,
+		FromBlock:     c.FromBlock,
+		Dial:          c.Dial,
+	})
+	if err != nil {
+		return err
+	}
+
+	logs := make(chan types.Log, logBuffer)
+	subscriberErr := make(chan error, 1)
+	go func() {
+		subscriberErr <- subscriber.Run(ctx, logs)
+	}()
+	for {
+		select {
+		case err := <-subscriberErr:
+			return fmt.Errorf("subscription to %s failed: %w", c.BlockchainID, err)
+		case l := <-logs:
+			if l.Removed {
+				log.Warn("Block of a handled message was reorged out",
+					"blockchainID", c.BlockchainID,
+					"blockNumber", l.BlockNumber,
+					"txHash", l.TxHash)
+				continue
+			}
+			r.handleLog(ctx, c, l, results)
+		}
+	}
+}
+
+// handleLog relays the message of a SendCrossChainMessage log emitted on the source chain, if it is sent
+// between token transferrers of the relayer, and then the messages sent by its delivery.
+func (r *Relayer) handleLog(ctx context.Context, source *chain, l types.Log, results chan<- Result) {
+	event, err := source.teleporter.ParseSendCrossChainMessage(l)
+	if err != nil {
+		log.Warn("Failed to parse SendCrossChainMessage log", "blockchainID", source.BlockchainID, "err", err)
+		return
+	}
+	destination, ok := r.chains[event.DestinationBlockchainID]
+	if !ok || !source.transferrers[event.Message.OriginSenderAddress] ||
+		!destination.transferrers[event.Message.DestinationAddress] {
+		return
+	}
+	messageID := ids.ID(event.MessageID)
+	if _, handled := r.handled.LoadOrStore(messageID, struct{}{}); handled {
+		return
+	}
+
+	result := r.relay(ctx, source, destination, event)
+	if !result.isFinal() {
+		// Relayed again if the message is seen again, such as after reconnecting
+		r.handled.Delete(messageID)
+	}
+	logResult(result)
+	if results != nil {
+		select {
+		case results <- result:
+		case <-ctx.Done():
+			return
+		}
+	}
+	if result.DeliveryReceipt == nil {
+		return
+	}
+
+	// Follow the messages sent by the delivery, such as the second hop of a multi-hop transfer
+	for _, deliveryLog := range result.DeliveryReceipt.Logs {
+		if deliveryLog.Address != r.config.TeleporterContractAddress {
+			continue
+		}
+		if _, err := destination.teleporter.ParseSendCrossChainMessage(*deliveryLog); err != nil {
+			continue
+		}
+		r.handleLog(ctx, destination, *deliveryLog, results)
+	}
+}
+
+func logResult(result Result) {
+	fields := []interface{}{
+		"messageID", result.MessageID,
+		"source", result.SourceBlockchainID,
+		"destination", result.DestinationBlockchainID,
+	}
+	if result.Message != nil {
+		fields = append(fields, "type", result.Message.Type, "amount", result.Message.Amount)
+	}
+	switch {
+	case result.Err == nil && result.ExecutionFailed:
+		log.Warn("Delivered message, but its execution failed",
+			append(fields, "txHash", result.DeliveryReceipt.TxHash)...)
+	case result.Err == nil:
+		log.Info("Delivered message", append(fields, "txHash", result.DeliveryReceipt.TxHash)...)
+	case result.isFinal():
+		log.Info("Not relaying message", append(fields, "reason", result.Err)...)
+	default:
+		log.Error("Failed to relay message", append(fields, "err", result.Err)...)
+	}
+}
+
+// relay applies the policy of the source chain to the message, and delivers it if it is allowed.
+func (r *Relayer) relay(
+	ctx context.Context,
+	source *chain,
+	destination *chain,
+	event *teleportermessenger.TeleporterMessengerSendCrossChainMessage,
+) Result {
+	result := Result{
+		MessageID:               event.MessageID,
+		SourceBlockchainID:      source.BlockchainID,
+		DestinationBlockchainID: destination.BlockchainID,
+	}
+	message, err := DecodeMessage(event.Message.Message)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Message = message
+	if err := source.Policy.Check(event.FeeInfo, message); err != nil {
+		result.Err = err
+		return result
+	}
+	if message.Type.IsMultiHop() {
+		if _, ok := r.chains[message.FinalDestinationBlockchainID]; !ok {
+			log.Warn("Second hop of multi-hop message will not be relayed to an unknown chain",
+				"messageID", result.MessageID,
+				"finalDestination", message.FinalDestinationBlockchainID)
+		}
+	}
+
+	received, err := destination.teleporter.MessageReceived(&bind.CallOpts{Context: ctx}, event.MessageID)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to check delivery: %w", err)
+		return result
+	}
+	if received {
+		result.Err = ErrAlreadyDelivered
+		return result
+	}
+
+	unsignedMessage, err := warpMessage(ctx, source, event.Raw)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	signedMessage, err := r.aggregateSignatures(ctx, source, destination, unsignedMessage.ID())
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	receipt, err := r.deliver(ctx, destination, signedMessage, event.Message)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.DeliveryReceipt = receipt
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		result.Err = fmt.Errorf("%w: %s", ErrDeliveryReverted, receipt.TxHash)
+		return result
+	}
+	for _, deliveryLog := range receipt.Logs {
+		if deliveryLog.Address != r.config.TeleporterContractAddress {
+			continue
+		}
+		if _, err := destination.teleporter.ParseMessageExecutionFailed(*deliveryLog); err == nil {
+			result.ExecutionFailed = true
+		}
+	}
+	return result
+}
+
+// warpMessage returns the Warp message sent by Teleporter along with the SendCrossChainMessage log,
+// which is the last Warp message sent before the log in the same transaction.
+func warpMessage(ctx context.Context, source *chain, sendLog types.Log) (*avalancheWarp.UnsignedMessage, error) {
+	receipt, err := source.Client.TransactionReceipt(ctx, sendLog.TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt of %s: %w", sendLog.TxHash, err)
+	}
+	var warpLog *types.Log
+	for _, l := range receipt.Logs {
+		if l.Index >= sendLog.Index {
+			break
+		}
+		if l.Address == warp.ContractAddress {
+			warpLog = l
+		}
+	}
+	if warpLog == nil {
+		return nil, errNoWarpMessage
+	}
+	return warp.UnpackSendWarpEventDataToMessage(warpLog.Data)
+}
+
+// aggregateSignatures retries until the node of the source chain has accepted the block of the message,
+// and has aggregated enough signatures for it.
+func (r *Relayer) aggregateSignatures(
+	ctx context.Context,
+	source *chain,
+	destination *chain,
+	unsignedMessageID ids.ID,
+) (*avalancheWarp.Message, error) {
+	warpClient, err := warpBackend.NewClient(source.NodeURI, source.BlockchainID.String())
+	if err != nil {
+		return nil, err
+	}
+	// Messages from the primary network are signed by the validators of the destination subnet.
+	signingSubnetID := source.SubnetID
+	if source.SubnetID == constants.PrimaryNetworkID {
+		signingSubnetID = destination.SubnetID
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, r.config.SignatureTimeout)
+	defer cancel()
+	ticker := time.NewTicker(signaturePollInterval)
+	defer ticker.Stop()
+	for {
+		signedMessageBytes, err := warpClient.GetMessageAggregateSignature(
+			cctx,
+			unsignedMessageID,
+			warp.WarpDefaultQuorumNumerator,
+			signingSubnetID.String(),
+		)
+		if err == nil {
+			return avalancheWarp.ParseMessage(signedMessageBytes)
+		}
+		log.Debug("Failed to aggregate Warp signatures", "messageID", unsignedMessageID, "err", err)
+		select {
+		case <-cctx.Done():
+			return nil, fmt.Errorf("failed to aggregate signatures for Warp message %s: %w", unsignedMessageID, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver sends the transaction that delivers the signed message to the destination,
+// and waits for it to be accepted.
+func (r *Relayer) deliver(
+	ctx context.Context,
+	destination *chain,
+	signedMessage *avalancheWarp.Message,
+	teleporterMessage teleportermessenger.TeleporterMessage,
+) (*types.Receipt, error) {
+	numSigners, err := signedMessage.Signature.NumSigners()
+	if err != nil {
+		return nil, err
+	}
+	gasLimit, err := gasUtils.CalculateReceiveMessageGasLimit(
+		numSigners,
+		teleporterMessage.RequiredGasLimit,
+		len(signedMessage.Bytes()),
+		len(signedMessage.Payload),
+		len(teleporterMessage.Receipts),
+	)
+	if err != nil {
+		return nil, err
+	}
+	callData, err := teleportermessenger.PackReceiveCrossChainMessage(0, r.address)
+	if err != nil {
+		return nil, err
+	}
+
+	destination.deliveryLock.Lock()
+	defer destination.deliveryLock.Unlock()
+
+	baseFee, err := destination.Client.EstimateBaseFee(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gasTipCap, err := destination.Client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := destination.Client.AcceptedNonceAt(ctx, r.address)
+	if err != nil {
+		return nil, err
+	}
+	gasFeeCap := new(big.Int).Mul(baseFee, big.NewInt(gasUtils.BaseFeeFactor))
+	gasFeeCap.Add(gasFeeCap, big.NewInt(gasUtils.MaxPriorityFeePerGas))
+
+	tx := predicateutils.NewPredicateTx(
+		destination.evmChainID,
+		nonce,
+		&r.config.TeleporterContractAddress,
+		gasLimit,
+		gasFeeCap,
+		gasTipCap,
+		big.NewInt(0),
+		callData,
+		types.AccessList{},
+		warp.ContractAddress,
+		signedMessage.Bytes(),
+	)
+	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(destination.evmChainID), r.config.Key)
+	if err != nil {
+		return nil, err
+	}
+	if err := destination.Client.SendTransaction(ctx, signedTx); err != nil {
+		return nil, fmt.Errorf("failed to send delivery transaction: %w", err)
+	}
+	return bind.WaitMined(ctx, destination.Client, signedTx)
+}
@@ -0,0 +1,218 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package reserves
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SignedAttestation is an attestation signed by its attester. The attestation is kept as the JSON that was
+// signed, so that its signature can be checked without depending on how attestations are encoded. The
+// signature covers the compact form of the JSON, which is kept when the signed attestation is indented.
+type SignedAttestation struct {
+	Attestation json.RawMessage `json:"attestation"`
+	Signer      common.Address  `json:"signer"`
+	// The EIP-191 personal signature of the attestation bytes, as produced by eth_sign
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// Sign encodes the attestation and signs it with the key.
+func Sign(a *Attestation, key *ecdsa.PrivateKey) (*SignedAttestation, error) {
+	encoded, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := crypto.Sign(accounts.TextHash(encoded), key)
+	if err != nil {
+		return nil, err
+	}
+	// Use the 27/28 recovery IDs of Ethereum signatures, as checked by ecrecover
+	signature[crypto.RecoveryIDOffset] += 27
+	return &SignedAttestation{
+		Attestation: encoded,
+		Signer:      crypto.PubkeyToAddress(key.PublicKey),
+		Signature:   signature,
+	}, nil
+}
+
+// Open checks that the attestation was signed by its signer, and decodes it.
+func (s *SignedAttestation) Open() (*Attestation, error) {
+	if len(s.Signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("%w: length %d", ErrInvalidSignature, len(s.Signature))
+	}
+	signature := common.CopyBytes(s.Signature)
+	if signature[crypto.RecoveryIDOffset] >= 27 {
+		signature[crypto.RecoveryIDOffset] -= 27
+	}
+	var encoded bytes.Buffer
+	if err := json.Compact(&encoded, s.Attestation); err != nil {
+		return nil, fmt.Errorf("failed to decode attestation: %w", err)
+	}
+	publicKey, err := crypto.SigToPub(accounts.TextHash(encoded.Bytes()), signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+	}
+	if signer := crypto.PubkeyToAddress(*publicKey); signer != s.Signer {
+		return nil, fmt.Errorf("%w: signed by %s, not %s", ErrInvalidSignature, signer, s.Signer)
+	}
+	var a Attestation
+	if err := json.Unmarshal(s.Attestation, &a); err != nil {
+		return nil, fmt.Errorf("failed to decode attestation: %w", err)
+	}
+	if a.Version != Version {
+		return nil, fmt.Errorf("unsupported attestation version %d", a.Version)
+	}
+	return &a, nil
+}
+
+// Verify gathers the reserves again at the blocks of the attestation, and returns how they differ from it.
+// The chains of the config must be served by archive nodes, and should include every chain of the
+// attestation. No differences means that the attestation is accurate.
+func Verify(ctx context.Context, a *Attestation, chains map[ids.ID]Backend) ([]string, error) {
+	config := Config{
+		HomeBlockchainID: a.Home.BlockchainID,
+		HomeAddress:      a.Home.Address,
+		Chains:           make(map[ids.ID]Backend),
+		BlockNumbers:     make(map[ids.ID]uint64),
+	}
+	var differences []string
+	for _, block := range a.Blocks {
+		backend, ok := chains[block.BlockchainID]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("chain %s cannot be verified", block.BlockchainID))
+			continue
+		}
+		config.Chains[block.BlockchainID] = backend
+		config.BlockNumbers[block.BlockchainID] = block.Number
+	}
+	if _, ok := config.Chains[a.Home.BlockchainID]; !ok {
+		return nil, fmt.Errorf("missing backend of the home chain %s", a.Home.BlockchainID)
+	}
+	gathered, err := Gather(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	gathered.Time = a.Time
+	return append(differences, Compare(a, gathered)...), nil
+}
+
+// Compare returns how the attestation differs from the expected one.
+func Compare(a, expected *Attestation) []string {
+	var differences []string
+	differ := func(what string, got, want interface{}) {
+		differences = append(differences, fmt.Sprintf("%s is %v, expected %v", what, got, want))
+	}
+
+	blocks := make(map[ids.ID]Block)
+	for _, block := range expected.Blocks {
+		blocks[block.BlockchainID] = block
+	}
+	for _, block := range a.Blocks {
+		if want, ok := blocks[block.BlockchainID]; ok && block.Hash != want.Hash {
+			differ(fmt.Sprintf("hash of block %d of %s", block.Number, block.BlockchainID), block.Hash, want.Hash)
+		}
+	}
+
+	if a.Home.Kind != expected.Home.Kind {
+		differ("kind of the home", a.Home.Kind, expected.Home.Kind)
+	}
+	if a.Home.TokenAddress != expected.Home.TokenAddress {
+		differ("token of the home", a.Home.TokenAddress, expected.Home.TokenAddress)
+	}
+	if a.Home.TokenDecimals != expected.Home.TokenDecimals {
+		differ("token decimals of the home", a.Home.TokenDecimals, expected.Home.TokenDecimals)
+	}
+	compareAmount(differ, "balance of the home", a.Home.Balance, expected.Home.Balance)
+
+	remotes := make(map[remoteKey]*Remote)
+	for i := range expected.Remotes {
+		remotes[expected.Remotes[i].key()] = &expected.Remotes[i]
+	}
+	for i := range a.Remotes {
+		remote := &a.Remotes[i]
+		want, ok := remotes[remote.key()]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("%s is not registered", remote.name()))
+			continue
+		}
+		delete(remotes, remote.key())
+		remote.compare(differ, want)
+	}
+	for i := range expected.Remotes {
+		if _, ok := remotes[expected.Remotes[i].key()]; ok {
+			differences = append(differences, fmt.Sprintf("%s is missing", expected.Remotes[i].name()))
+		}
+	}
+
+	compareAmount(differ, "total transferred", a.TotalTransferred, expected.TotalTransferred)
+	compareAmount(differ, "surplus", a.Surplus, expected.Surplus)
+	if a.Covered != expected.Covered {
+		differ("coverage", a.Covered, expected.Covered)
+	}
+	return differences
+}
+
+type remoteKey struct {
+	blockchainID ids.ID
+	address      common.Address
+}
+
+func (r *Remote) key() remoteKey {
+	return remoteKey{blockchainID: r.BlockchainID, address: r.Address}
+}
+
+func (r *Remote) name() string {
+	return fmt.Sprintf("remote %s on %s", r.Address, r.BlockchainID)
+}
+
+func (r *Remote) compare(differ func(what string, got, want interface{}), want *Remote) {
+	name := r.name()
+	compareAmount(differ, "token multiplier of "+name, r.TokenMultiplier, want.TokenMultiplier)
+	if r.MultiplyOnRemote != want.MultiplyOnRemote {
+		differ("multiply on remote of "+name, r.MultiplyOnRemote, want.MultiplyOnRemote)
+	}
+	compareAmount(differ, "collateral needed by "+name, r.CollateralNeeded, want.CollateralNeeded)
+	compareAmount(differ, "balance transferred to "+name, r.TransferredBalance, want.TransferredBalance)
+	compareAmount(differ, "value transferred to "+name, r.TransferredHomeValue, want.TransferredHomeValue)
+	if r.Attested != want.Attested {
+		differ("attestation of "+name, r.Attested, want.Attested)
+		return
+	}
+	if r.Kind != want.Kind {
+		differ("kind of "+name, r.Kind, want.Kind)
+	}
+	compareAmount(differ, "supply of "+name, r.Supply, want.Supply)
+	compareAmount(differ, "initial reserve imbalance of "+name, r.InitialReserveImbalance, want.InitialReserveImbalance)
+	if r.Backed != want.Backed {
+		differ("backing of "+name, r.Backed, want.Backed)
+	}
+}
+
+// compareAmount reports amounts that differ, treating nil as zero.
+func compareAmount(differ func(what string, got, want interface{}), what string, got, want *big.Int) {
+	if zeroIfNil(got).Cmp(zeroIfNil(want)) != 0 {
+		differ(what, zeroIfNil(got), zeroIfNil(want))
+	}
+}
+
+func zeroIfNil(amount *big.Int) *big.Int {
+	if amount == nil {
+		return new(big.Int)
+	}
+	return amount
+}
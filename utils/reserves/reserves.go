@@ -0,0 +1,326 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package reserves attests that a TokenHome holds enough tokens for the tokens transferred to its remotes.
+//
+// An attestation records, at a pinned block of each chain, the balance of the token held by the home, the
+// balance the home has transferred to each remote, as returned by getTransferredBalance, and the supply of
+// each remote. It is signed by the key of the attester, and can be verified by anyone with archive RPC
+// endpoints for the chains, by reading the same values again at the same blocks.
+package reserves
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	erc20tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/ERC20TokenRemote"
+	nativetokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemote"
+	tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/TokenRemote"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/amounts"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Version of the attestations produced by Gather
+const Version = 1
+
+// Backend is the subset of the RPC client of a chain needed to attest reserves. Attestations of past
+// blocks must be gathered and verified with archive nodes.
+type Backend interface {
+	portfolio.Backend
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// Config describes the TokenHome, and the chains and blocks its reserves are attested at.
+type Config struct {
+	HomeBlockchainID ids.ID
+	HomeAddress      common.Address
+	// The backend of each chain, by blockchain ID. The supply of remotes on other chains is not attested.
+	Chains map[ids.ID]Backend
+	// The block each chain is read at, by blockchain ID. Chains without one are read at their latest block.
+	BlockNumbers map[ids.ID]uint64
+}
+
+// Block is the block a chain was read at.
+type Block struct {
+	BlockchainID ids.ID      `json:"blockchainID"`
+	Number       uint64      `json:"number"`
+	Hash         common.Hash `json:"hash"`
+}
+
+// Home is the reserve of the TokenHome.
+type Home struct {
+	BlockchainID ids.ID         `json:"blockchainID"`
+	Address      common.Address `json:"address"`
+	Kind         inspect.Kind   `json:"kind"`
+	// The ERC20 token held by the home, which is the wrapped native token of native token homes
+	TokenAddress  common.Address `json:"tokenAddress"`
+	TokenDecimals uint8          `json:"tokenDecimals"`
+	// The balance of the token held by the home
+	Balance *big.Int `json:"balance"`
+}
+
+// Remote is a remote registered with the home, with the tokens transferred to it.
+type Remote struct {
+	BlockchainID     ids.ID         `json:"blockchainID"`
+	Address          common.Address `json:"address"`
+	TokenMultiplier  *big.Int       `json:"tokenMultiplier"`
+	MultiplyOnRemote bool           `json:"multiplyOnRemote"`
+	CollateralNeeded *big.Int       `json:"collateralNeeded"`
+	// The balance the home has transferred to the remote, in remote units, and its value in home units
+	TransferredBalance   *big.Int `json:"transferredBalance"`
+	TransferredHomeValue *big.Int `json:"transferredHomeValue"`
+
+	// The remaining fields are only set if the chain of the remote was read.
+	Attested bool         `json:"attested"`
+	Kind     inspect.Kind `json:"kind,omitempty"`
+	// The total supply of the remote token, which is the total native asset supply of native token
+	// remotes, in remote units
+	Supply *big.Int `json:"supply,omitempty"`
+	// The tokens created on the remote chain before it was registered, such as the native tokens of its
+	// genesis, which are backed by the collateral added to the home rather than by transferred tokens
+	InitialReserveImbalance *big.Int `json:"initialReserveImbalance,omitempty"`
+	// Whether the supply is at most the transferred balance, plus the initial reserve imbalance once the home
+	// has all the collateral for it
+	Backed bool `json:"backed,omitempty"`
+}
+
+// Attestation is the reserves of a TokenHome at a block of each chain.
+type Attestation struct {
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	// The block each chain was read at, starting with the home chain
+	Blocks  []Block  `json:"blocks"`
+	Home    Home     `json:"home"`
+	Remotes []Remote `json:"remotes"`
+	// The value of the balances transferred to every remote, in home units
+	TotalTransferred *big.Int `json:"totalTransferred"`
+	// The balance of the home in excess of the total transferred, which is negative if it is short
+	Surplus *big.Int `json:"surplus"`
+	// Whether the balance of the home covers the total transferred, and the supply of every attested remote
+	// is backed
+	Covered bool `json:"covered"`
+}
+
+// Gather reads the reserves of the TokenHome of the config.
+func Gather(ctx context.Context, config Config) (*Attestation, error) {
+	a := &Attestation{
+		Version: Version,
+		Time:    time.Now().UTC().Truncate(time.Second),
+		Home:    Home{BlockchainID: config.HomeBlockchainID, Address: config.HomeAddress},
+	}
+	homeBackend, ok := config.Chains[config.HomeBlockchainID]
+	if !ok {
+		return nil, fmt.Errorf("missing backend of the home chain %s", config.HomeBlockchainID)
+	}
+	homeBlock, err := pinBlock(ctx, config, config.HomeBlockchainID, homeBackend)
+	if err != nil {
+		return nil, err
+	}
+	a.Blocks = append(a.Blocks, homeBlock)
+	homeOpts := &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(homeBlock.Number)}
+
+	info, err := inspect.Inspect(ctx, homeBackend, config.HomeAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect home %s: %w", config.HomeAddress, err)
+	}
+	if !info.Kind.IsHome() {
+		return nil, fmt.Errorf("%s is a %s, not a token home", config.HomeAddress, info.Kind)
+	}
+	a.Home.Kind = info.Kind
+	a.Home.TokenAddress = info.TokenAddress
+	a.Home.TokenDecimals = info.TokenDecimals
+	token, err := erc20tokenremote.NewERC20TokenRemoteCaller(info.TokenAddress, homeBackend)
+	if err != nil {
+		return nil, err
+	}
+	a.Home.Balance, err = token.BalanceOf(homeOpts, config.HomeAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance of home %s: %w", config.HomeAddress, err)
+	}
+
+	registered, err := registeredRemotes(ctx, homeBackend, config.HomeAddress, homeBlock.Number)
+	if err != nil {
+		return nil, err
+	}
+	home, err := tokenhome.NewTokenHomeCaller(config.HomeAddress, homeBackend)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range registered {
+		remote, err := homeRemote(home, homeOpts, r)
+		if err != nil {
+			return nil, err
+		}
+		a.Remotes = append(a.Remotes, remote)
+	}
+
+	// Read each remote chain once, at its pinned block
+	blocks := map[ids.ID]Block{config.HomeBlockchainID: homeBlock}
+	for i := range a.Remotes {
+		remote := &a.Remotes[i]
+		backend, ok := config.Chains[remote.BlockchainID]
+		if !ok {
+			continue
+		}
+		block, ok := blocks[remote.BlockchainID]
+		if !ok {
+			block, err = pinBlock(ctx, config, remote.BlockchainID, backend)
+			if err != nil {
+				return nil, err
+			}
+			blocks[remote.BlockchainID] = block
+			a.Blocks = append(a.Blocks, block)
+		}
+		if err := attestRemote(ctx, backend, block, remote); err != nil {
+			return nil, err
+		}
+	}
+	a.total()
+	return a, nil
+}
+
+// pinBlock returns the block of the chain that the reserves are read at.
+func pinBlock(ctx context.Context, config Config, blockchainID ids.ID, backend Backend) (Block, error) {
+	var number *big.Int
+	if n, ok := config.BlockNumbers[blockchainID]; ok {
+		number = new(big.Int).SetUint64(n)
+	}
+	header, err := backend.HeaderByNumber(ctx, number)
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to get block %v of %s: %w", number, blockchainID, err)
+	}
+	return Block{
+		BlockchainID: blockchainID,
+		Number:       header.Number.Uint64(),
+		Hash:         header.Hash(),
+	}, nil
+}
+
+// registeredRemotes returns the remotes registered with the home by the block, in the order of their
+// registration.
+func registeredRemotes(
+	ctx context.Context,
+	backend Backend,
+	homeAddress common.Address,
+	blockNumber uint64,
+) ([]portfolio.Remote, error) {
+	home, err := tokenhome.NewTokenHomeFilterer(homeAddress, backend)
+	if err != nil {
+		return nil, err
+	}
+	it, err := home.FilterRemoteRegistered(&bind.FilterOpts{Context: ctx, End: &blockNumber}, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get registered remotes of %s: %w", homeAddress, err)
+	}
+	defer it.Close()
+
+	var remotes []portfolio.Remote
+	seen := make(map[portfolio.Remote]bool)
+	for it.Next() {
+		remote := portfolio.Remote{
+			BlockchainID: it.Event.RemoteBlockchainID,
+			Address:      it.Event.RemoteTokenTransferrerAddress,
+		}
+		if !seen[remote] {
+			seen[remote] = true
+			remotes = append(remotes, remote)
+		}
+	}
+	return remotes, it.Error()
+}
+
+// homeRemote returns the remote as registered with the home.
+func homeRemote(home *tokenhome.TokenHomeCaller, opts *bind.CallOpts, r portfolio.Remote) (Remote, error) {
+	settings, err := home.GetRemoteTokenTransferrerSettings(opts, r.BlockchainID, r.Address)
+	if err != nil {
+		return Remote{}, fmt.Errorf("failed to get settings of remote %s on %s: %w", r.Address, r.BlockchainID, err)
+	}
+	transferred, err := home.GetTransferredBalance(opts, r.BlockchainID, r.Address)
+	if err != nil {
+		return Remote{}, fmt.Errorf(
+			"failed to get transferred balance of remote %s on %s: %w", r.Address, r.BlockchainID, err,
+		)
+	}
+	route := amounts.Route{TokenMultiplier: settings.TokenMultiplier, MultiplyOnRemote: settings.MultiplyOnRemote}
+	return Remote{
+		BlockchainID:         r.BlockchainID,
+		Address:              r.Address,
+		TokenMultiplier:      settings.TokenMultiplier,
+		MultiplyOnRemote:     settings.MultiplyOnRemote,
+		CollateralNeeded:     settings.CollateralNeeded,
+		TransferredBalance:   transferred,
+		TransferredHomeValue: route.HomeValue(transferred),
+	}, nil
+}
+
+// attestRemote reads the supply of the remote at the block of its chain.
+func attestRemote(ctx context.Context, backend Backend, block Block, remote *Remote) error {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(block.Number)}
+	info, err := inspect.Inspect(ctx, backend, remote.Address)
+	if err != nil {
+		return fmt.Errorf("failed to inspect remote %s on %s: %w", remote.Address, remote.BlockchainID, err)
+	}
+	if !info.Kind.IsRemote() {
+		return fmt.Errorf("remote %s on %s is a %s", remote.Address, remote.BlockchainID, info.Kind)
+	}
+	remote.Kind = info.Kind
+
+	if info.Kind.IsNative() {
+		native, err := nativetokenremote.NewNativeTokenRemoteCaller(remote.Address, backend)
+		if err != nil {
+			return err
+		}
+		remote.Supply, err = native.TotalNativeAssetSupply(opts)
+	} else {
+		token, err := erc20tokenremote.NewERC20TokenRemoteCaller(remote.Address, backend)
+		if err != nil {
+			return err
+		}
+		remote.Supply, err = token.TotalSupply(opts)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get supply of remote %s on %s: %w", remote.Address, remote.BlockchainID, err)
+	}
+	caller, err := tokenremote.NewTokenRemoteCaller(remote.Address, backend)
+	if err != nil {
+		return err
+	}
+	remote.InitialReserveImbalance, err = caller.GetInitialReserveImbalance(opts)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to get initial reserve imbalance of remote %s on %s: %w", remote.Address, remote.BlockchainID, err,
+		)
+	}
+	remote.Attested = true
+	return nil
+}
+
+// total sums the balances transferred to the remotes, and checks them against the balance of the home
+// and the supply of the remotes.
+func (a *Attestation) total() {
+	a.TotalTransferred = new(big.Int)
+	a.Covered = true
+	for i := range a.Remotes {
+		remote := &a.Remotes[i]
+		a.TotalTransferred.Add(a.TotalTransferred, remote.TransferredHomeValue)
+		if !remote.Attested {
+			continue
+		}
+		backing := new(big.Int).Set(remote.TransferredBalance)
+		if remote.CollateralNeeded.Sign() == 0 {
+			backing.Add(backing, remote.InitialReserveImbalance)
+		}
+		remote.Backed = remote.Supply.Cmp(backing) <= 0
+		a.Covered = a.Covered && remote.Backed
+	}
+	a.Surplus = new(big.Int).Sub(a.Home.Balance, a.TotalTransferred)
+	a.Covered = a.Covered && a.Surplus.Sign() >= 0
+}
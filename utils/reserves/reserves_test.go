@@ -0,0 +1,197 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package reserves
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testHome         = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testRemoteA      = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testRemoteB      = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	testHomeChain    = ids.ID{1}
+	testRemoteChainA = ids.ID{2}
+	testRemoteChainB = ids.ID{3}
+)
+
+// newTestAttestation returns the attestation of an 18 decimal home holding 5e18 tokens, with 1e6 tokens
+// of a 6 decimal remote A transferred, and 2e19 tokens of a 19 decimal native remote B, whose genesis
+// minted an initial reserve imbalance of 1e19 the home has all the collateral for.
+func newTestAttestation() *Attestation {
+	a := &Attestation{
+		Version: Version,
+		Time:    time.Unix(1700000000, 0).UTC(),
+		Blocks: []Block{
+			{BlockchainID: testHomeChain, Number: 100, Hash: common.Hash{1}},
+			{BlockchainID: testRemoteChainA, Number: 200, Hash: common.Hash{2}},
+			{BlockchainID: testRemoteChainB, Number: 300, Hash: common.Hash{3}},
+		},
+		Home: Home{
+			BlockchainID:  testHomeChain,
+			Address:       testHome,
+			Kind:          inspect.KindERC20TokenHome,
+			TokenAddress:  common.HexToAddress("0x5555555555555555555555555555555555555555"),
+			TokenDecimals: 18,
+			Balance:       big.NewInt(5e18),
+		},
+		Remotes: []Remote{
+			{
+				BlockchainID:            testRemoteChainA,
+				Address:                 testRemoteA,
+				TokenMultiplier:         big.NewInt(1e12),
+				CollateralNeeded:        big.NewInt(0),
+				TransferredBalance:      big.NewInt(1e6),
+				TransferredHomeValue:    big.NewInt(1e18),
+				Attested:                true,
+				Kind:                    inspect.KindERC20TokenRemote,
+				Supply:                  big.NewInt(1e6),
+				InitialReserveImbalance: big.NewInt(0),
+			},
+			{
+				BlockchainID:            testRemoteChainB,
+				Address:                 testRemoteB,
+				TokenMultiplier:         big.NewInt(10),
+				MultiplyOnRemote:        true,
+				CollateralNeeded:        big.NewInt(0),
+				TransferredBalance:      remoteAmount(2),
+				TransferredHomeValue:    big.NewInt(2e18),
+				Attested:                true,
+				Kind:                    inspect.KindNativeTokenRemote,
+				Supply:                  remoteAmount(3),
+				InitialReserveImbalance: remoteAmount(1),
+			},
+		},
+	}
+	a.total()
+	return a
+}
+
+func TestTotal(t *testing.T) {
+	a := newTestAttestation()
+	require.Equal(t, big.NewInt(3e18), a.TotalTransferred)
+	require.Equal(t, big.NewInt(2e18), a.Surplus)
+	require.True(t, a.Remotes[0].Backed)
+	require.True(t, a.Remotes[1].Backed)
+	require.True(t, a.Covered)
+
+	// The initial reserve imbalance is only backed once the home has all the collateral for it
+	a.Remotes[1].CollateralNeeded = big.NewInt(1e18)
+	a.total()
+	require.False(t, a.Remotes[1].Backed)
+	require.False(t, a.Covered)
+
+	// Remotes on chains that were not read only count towards the total transferred
+	a = newTestAttestation()
+	a.Remotes[1] = Remote{
+		BlockchainID:         testRemoteChainB,
+		Address:              testRemoteB,
+		TokenMultiplier:      big.NewInt(10),
+		MultiplyOnRemote:     true,
+		CollateralNeeded:     big.NewInt(0),
+		TransferredBalance:   remoteAmount(6),
+		TransferredHomeValue: big.NewInt(6e18),
+	}
+	a.total()
+	require.Equal(t, big.NewInt(-2e18), a.Surplus)
+	require.False(t, a.Remotes[1].Backed)
+	require.False(t, a.Covered)
+}
+
+func TestSignAndOpen(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	a := newTestAttestation()
+
+	signed, err := Sign(a, key)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signed.Signer)
+
+	// The signature survives encoding the signed attestation
+	encoded, err := json.MarshalIndent(signed, "", "  ")
+	require.NoError(t, err)
+	var decoded SignedAttestation
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	opened, err := decoded.Open()
+	require.NoError(t, err)
+	require.Equal(t, a, opened)
+	require.Empty(t, Compare(opened, a))
+}
+
+func TestOpenTampered(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		tamper func(signed *SignedAttestation)
+	}{
+		{
+			name: "attestation",
+			tamper: func(signed *SignedAttestation) {
+				signed.Attestation = bytes.Replace(
+					signed.Attestation, []byte(`"balance":5000000000000000000`), []byte(`"balance":6000000000000000000`), 1,
+				)
+			},
+		},
+		{
+			name: "signer",
+			tamper: func(signed *SignedAttestation) {
+				signed.Signer = crypto.PubkeyToAddress(other.PublicKey)
+			},
+		},
+		{
+			name: "signature",
+			tamper: func(signed *SignedAttestation) {
+				signed.Signature = signed.Signature[:64]
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signed, err := Sign(newTestAttestation(), key)
+			require.NoError(t, err)
+			test.tamper(signed)
+			_, err = signed.Open()
+			require.ErrorIs(t, err, ErrInvalidSignature)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	expected := newTestAttestation()
+	a := newTestAttestation()
+	a.Blocks[1].Hash = common.Hash{4}
+	a.Home.Balance = big.NewInt(4e18)
+	a.Remotes[1].Supply = big.NewInt(1e18)
+	a.Remotes = a.Remotes[1:]
+	a.total()
+
+	require.Equal(t, []string{
+		"hash of block 200 of " + testRemoteChainA.String() + " is " + common.Hash{4}.Hex() +
+			", expected " + common.Hash{2}.Hex(),
+		"balance of the home is 4000000000000000000, expected 5000000000000000000",
+		"supply of remote " + testRemoteB.Hex() + " on " + testRemoteChainB.String() +
+			" is 1000000000000000000, expected 30000000000000000000",
+		"remote " + testRemoteA.Hex() + " on " + testRemoteChainA.String() + " is missing",
+		"total transferred is 2000000000000000000, expected 3000000000000000000",
+	}, Compare(a, expected))
+}
+
+// remoteAmount returns n tokens of the 19 decimal remote B.
+func remoteAmount(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n*1e18), big.NewInt(10))
+}
@@ -0,0 +1,367 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package storagelayout extracts the ERC-7201 namespaced storage layouts of contracts from forge
+// build artifacts, and checks that an upgraded contract's layout is compatible with the previous one.
+package storagelayout
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const erc7201Annotation = "@custom:storage-location erc7201:"
+
+// UpgradeableContracts are the contracts deployed behind proxies, whose storage layouts must remain compatible.
+var UpgradeableContracts = []string{
+	"ERC20TokenHomeUpgradeable",
+	"NativeTokenHomeUpgradeable",
+	"ERC20TokenRemoteUpgradeable",
+	"NativeTokenRemoteUpgradeable",
+}
+
+var (
+	errContractNotFound = errors.New("contract not found in artifacts")
+
+	// Data locations appended to type strings depending on where a type is referenced from.
+	// They do not affect the storage layout, so they are stripped before comparing types.
+	dataLocationRegex = regexp.MustCompile(` (storage ref|storage pointer|memory|calldata)\b`)
+)
+
+// Field is a single member of a storage struct, in declaration order.
+type Field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Namespace is a struct annotated with "@custom:storage-location erc7201:<id>".
+type Namespace struct {
+	Struct string  `json:"struct"`
+	Fields []Field `json:"fields"`
+}
+
+// Layout is the storage layout of a single contract, including all of the namespaces declared by
+// the contracts it inherits from. Structs holds the members of every struct type referenced by
+// the namespaces, since changing those changes the layout of the namespace as well.
+type Layout struct {
+	Namespaces map[string]Namespace `json:"namespaces"`
+	Structs    map[string][]Field   `json:"structs,omitempty"`
+}
+
+// Layouts maps contract names to their storage layouts.
+type Layouts map[string]Layout
+
+// Incompatibility describes a change between two layouts that would corrupt the storage of a live proxy.
+type Incompatibility struct {
+	Contract  string
+	Namespace string
+	Message   string
+}
+
+func (i Incompatibility) String() string {
+	if i.Namespace == "" {
+		return fmt.Sprintf("%s: %s", i.Contract, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Contract, i.Namespace, i.Message)
+}
+
+// Artifacts indexes the contract and struct definitions of a forge build output directory.
+type Artifacts struct {
+	contracts       map[int64]*astNode
+	contractsByName map[string]*astNode
+	structs         map[string]*astNode
+}
+
+// astNode holds the subset of solc AST node fields needed to extract storage layouts.
+type astNode struct {
+	ID                      int64           `json:"id"`
+	NodeType                string          `json:"nodeType"`
+	Name                    string          `json:"name"`
+	CanonicalName           string          `json:"canonicalName"`
+	AbsolutePath            string          `json:"absolutePath"`
+	LinearizedBaseContracts []int64         `json:"linearizedBaseContracts"`
+	Nodes                   []*astNode      `json:"nodes"`
+	Members                 []*astNode      `json:"members"`
+	Documentation           json.RawMessage `json:"documentation"`
+	TypeDescriptions        struct {
+		TypeString string `json:"typeString"`
+	} `json:"typeDescriptions"`
+}
+
+type artifact struct {
+	AST *astNode `json:"ast"`
+}
+
+// LoadArtifacts parses every artifact in the given forge output directory.
+func LoadArtifacts(outDir string) (*Artifacts, error) {
+	a := &Artifacts{
+		contracts:       make(map[int64]*astNode),
+		contractsByName: make(map[string]*astNode),
+		structs:         make(map[string]*astNode),
+	}
+	sourceUnits := make(map[string]struct{})
+
+	err := filepath.WalkDir(outDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Build info files contain the full compiler input and output, and are not needed.
+		if d.IsDir() && d.Name() == "build-info" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var art artifact
+		if err := json.Unmarshal(data, &art); err != nil {
+			return fmt.Errorf("failed to parse artifact %s: %w", path, err)
+		}
+		// Each contract in a source file has its own artifact containing the same AST
+		if art.AST == nil {
+			return nil
+		}
+		if _, ok := sourceUnits[art.AST.AbsolutePath]; ok {
+			return nil
+		}
+		sourceUnits[art.AST.AbsolutePath] = struct{}{}
+		a.index(art.AST)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *Artifacts) index(node *astNode) {
+	switch node.NodeType {
+	case "ContractDefinition":
+		a.contracts[node.ID] = node
+		a.contractsByName[node.Name] = node
+	case "StructDefinition":
+		a.structs[node.CanonicalName] = node
+	}
+	for _, child := range node.Nodes {
+		a.index(child)
+	}
+}
+
+// Layout returns the storage layout of the named contract.
+func (a *Artifacts) Layout(contractName string) (Layout, error) {
+	contract, ok := a.contractsByName[contractName]
+	if !ok {
+		return Layout{}, fmt.Errorf("%w: %s", errContractNotFound, contractName)
+	}
+
+	layout := Layout{
+		Namespaces: make(map[string]Namespace),
+		Structs:    make(map[string][]Field),
+	}
+	for _, baseID := range contract.LinearizedBaseContracts {
+		base, ok := a.contracts[baseID]
+		if !ok {
+			return Layout{}, fmt.Errorf("%w: base contract %d of %s", errContractNotFound, baseID, contractName)
+		}
+		for _, node := range base.Nodes {
+			if node.NodeType != "StructDefinition" {
+				continue
+			}
+			namespaceID, ok := erc7201NamespaceID(node.Documentation)
+			if !ok {
+				continue
+			}
+			fields := structFields(node)
+			layout.Namespaces[namespaceID] = Namespace{
+				Struct: node.CanonicalName,
+				Fields: fields,
+			}
+			a.addReferencedStructs(fields, layout.Structs)
+		}
+	}
+	if len(layout.Structs) == 0 {
+		layout.Structs = nil
+	}
+	return layout, nil
+}
+
+// Layouts returns the storage layouts of each of the named contracts.
+func (a *Artifacts) Layouts(contractNames []string) (Layouts, error) {
+	layouts := make(Layouts, len(contractNames))
+	for _, name := range contractNames {
+		layout, err := a.Layout(name)
+		if err != nil {
+			return nil, err
+		}
+		layouts[name] = layout
+	}
+	return layouts, nil
+}
+
+func (a *Artifacts) addReferencedStructs(fields []Field, structs map[string][]Field) {
+	for _, field := range fields {
+		for _, name := range referencedStructs(field.Type) {
+			if _, ok := structs[name]; ok {
+				continue
+			}
+			definition, ok := a.structs[name]
+			if !ok {
+				continue
+			}
+			structs[name] = structFields(definition)
+			a.addReferencedStructs(structs[name], structs)
+		}
+	}
+}
+
+// erc7201NamespaceID returns the namespace ID from the NatSpec documentation of a struct.
+// Depending on the solc version, the documentation is either a string or a StructuredDocumentation node.
+func erc7201NamespaceID(documentation json.RawMessage) (string, bool) {
+	if len(documentation) == 0 {
+		return "", false
+	}
+	var text string
+	if err := json.Unmarshal(documentation, &text); err != nil {
+		var structured struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(documentation, &structured); err != nil {
+			return "", false
+		}
+		text = structured.Text
+	}
+	index := strings.Index(text, erc7201Annotation)
+	if index < 0 {
+		return "", false
+	}
+	fields := strings.Fields(text[index+len(erc7201Annotation):])
+	if len(fields) == 0 {
+		return "", false
+	}
+	return fields[0], true
+}
+
+func structFields(definition *astNode) []Field {
+	fields := make([]Field, 0, len(definition.Members))
+	for _, member := range definition.Members {
+		fields = append(fields, Field{
+			Name: member.Name,
+			Type: dataLocationRegex.ReplaceAllString(member.TypeDescriptions.TypeString, ""),
+		})
+	}
+	return fields
+}
+
+var structTypeRegex = regexp.MustCompile(`struct ([A-Za-z0-9_$.]+)`)
+
+func referencedStructs(typeString string) []string {
+	var names []string
+	for _, match := range structTypeRegex.FindAllStringSubmatch(typeString, -1) {
+		names = append(names, match[1])
+	}
+	return names
+}
+
+// Compare returns every incompatibility between the old and new layouts of each contract in oldLayouts.
+// New contracts, new namespaces, and fields appended to the end of a namespace or struct are allowed.
+func Compare(oldLayouts Layouts, newLayouts Layouts) []Incompatibility {
+	var incompatibilities []Incompatibility
+	for _, contract := range sortedKeys(oldLayouts) {
+		oldLayout := oldLayouts[contract]
+		newLayout, ok := newLayouts[contract]
+		if !ok {
+			incompatibilities = append(incompatibilities, Incompatibility{
+				Contract: contract,
+				Message:  "contract removed",
+			})
+			continue
+		}
+		for _, namespaceID := range sortedKeys(oldLayout.Namespaces) {
+			oldNamespace := oldLayout.Namespaces[namespaceID]
+			newNamespace, ok := newLayout.Namespaces[namespaceID]
+			if !ok {
+				incompatibilities = append(incompatibilities, Incompatibility{
+					Contract:  contract,
+					Namespace: namespaceID,
+					Message:   "namespace removed",
+				})
+				continue
+			}
+			for _, message := range compareFields(oldNamespace.Fields, newNamespace.Fields) {
+				incompatibilities = append(incompatibilities, Incompatibility{
+					Contract:  contract,
+					Namespace: namespaceID,
+					Message:   message,
+				})
+			}
+		}
+		for _, structName := range sortedKeys(oldLayout.Structs) {
+			newFields, ok := newLayout.Structs[structName]
+			if !ok {
+				// The struct is no longer referenced from storage, or was renamed.
+				// Either way, the field that referenced it has been retyped, which is reported above.
+				continue
+			}
+			for _, message := range compareFields(oldLayout.Structs[structName], newFields) {
+				incompatibilities = append(incompatibilities, Incompatibility{
+					Contract:  contract,
+					Namespace: "struct " + structName,
+					Message:   message,
+				})
+			}
+		}
+	}
+	return incompatibilities
+}
+
+func compareFields(oldFields []Field, newFields []Field) []string {
+	newIndexes := make(map[string]int, len(newFields))
+	for i, field := range newFields {
+		newIndexes[field.Name] = i
+	}
+
+	var messages []string
+	for i, oldField := range oldFields {
+		newIndex, ok := newIndexes[oldField.Name]
+		switch {
+		case !ok:
+			messages = append(messages, fmt.Sprintf("field %s (%s) removed", oldField.Name, oldField.Type))
+		case newIndex != i:
+			messages = append(messages, fmt.Sprintf("field %s moved from position %d to %d", oldField.Name, i, newIndex))
+		case newFields[newIndex].Type != oldField.Type:
+			messages = append(messages, fmt.Sprintf(
+				"field %s retyped from %s to %s", oldField.Name, oldField.Type, newFields[newIndex].Type,
+			))
+		}
+	}
+	return messages
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Load returns the layouts of the given contracts from a forge output directory.
+func Load(outDir string, contractNames []string) (Layouts, error) {
+	artifacts, err := LoadArtifacts(outDir)
+	if err != nil {
+		return nil, err
+	}
+	return artifacts.Layouts(contractNames)
+}
@@ -0,0 +1,236 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package storagelayout
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const forgeOutDir = "../../contracts/out"
+
+func TestLoadArtifacts(t *testing.T) {
+	artifacts, err := LoadArtifacts("testdata/out")
+	require.NoError(t, err)
+
+	layout, err := artifacts.Layout("Derived")
+	require.NoError(t, err)
+	require.Equal(t, Layout{
+		Namespaces: map[string]Namespace{
+			"example.storage.Base": {
+				Struct: "Base.BaseStorage",
+				Fields: []Field{
+					{Name: "_owner", Type: "address"},
+					{Name: "_settings", Type: "mapping(bytes32 => struct Settings)"},
+				},
+			},
+			"example.storage.Derived": {
+				Struct: "Derived.DerivedStorage",
+				Fields: []Field{
+					{Name: "_total", Type: "uint256"},
+				},
+			},
+		},
+		Structs: map[string][]Field{
+			"Settings": {
+				{Name: "enabled", Type: "bool"},
+				{Name: "limit", Type: "uint256"},
+			},
+		},
+	}, layout)
+
+	_, err = artifacts.Layout("Missing")
+	require.ErrorIs(t, err, errContractNotFound)
+}
+
+func TestCompare(t *testing.T) {
+	base := func() Layout {
+		return Layout{
+			Namespaces: map[string]Namespace{
+				"ns": {
+					Struct: "C.S",
+					Fields: []Field{
+						{Name: "a", Type: "uint256"},
+						{Name: "b", Type: "address"},
+						{Name: "c", Type: "mapping(bytes32 => struct T)"},
+					},
+				},
+			},
+			Structs: map[string][]Field{
+				"T": {
+					{Name: "x", Type: "bool"},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		modify   func(layouts Layouts)
+		expected []string
+	}{
+		{
+			name:   "unchanged",
+			modify: func(Layouts) {},
+		},
+		{
+			name: "field appended",
+			modify: func(layouts Layouts) {
+				ns := layouts["C"].Namespaces["ns"]
+				ns.Fields = append(ns.Fields, Field{Name: "d", Type: "bool"})
+				layouts["C"].Namespaces["ns"] = ns
+			},
+		},
+		{
+			name: "struct member appended",
+			modify: func(layouts Layouts) {
+				layouts["C"].Structs["T"] = append(layouts["C"].Structs["T"], Field{Name: "y", Type: "uint8"})
+			},
+		},
+		{
+			name: "namespace added",
+			modify: func(layouts Layouts) {
+				layouts["C"].Namespaces["other"] = Namespace{Struct: "C.O"}
+			},
+		},
+		{
+			name: "field removed",
+			modify: func(layouts Layouts) {
+				ns := layouts["C"].Namespaces["ns"]
+				ns.Fields = []Field{ns.Fields[0], ns.Fields[2]}
+				layouts["C"].Namespaces["ns"] = ns
+			},
+			expected: []string{
+				"C: ns: field b (address) removed",
+				"C: ns: field c moved from position 2 to 1",
+			},
+		},
+		{
+			name: "fields reordered",
+			modify: func(layouts Layouts) {
+				ns := layouts["C"].Namespaces["ns"]
+				ns.Fields = []Field{ns.Fields[1], ns.Fields[0], ns.Fields[2]}
+				layouts["C"].Namespaces["ns"] = ns
+			},
+			expected: []string{
+				"C: ns: field a moved from position 0 to 1",
+				"C: ns: field b moved from position 1 to 0",
+			},
+		},
+		{
+			name: "field retyped",
+			modify: func(layouts Layouts) {
+				layouts["C"].Namespaces["ns"].Fields[0].Type = "uint128"
+			},
+			expected: []string{
+				"C: ns: field a retyped from uint256 to uint128",
+			},
+		},
+		{
+			name: "field inserted",
+			modify: func(layouts Layouts) {
+				ns := layouts["C"].Namespaces["ns"]
+				ns.Fields = []Field{ns.Fields[0], {Name: "new", Type: "bool"}, ns.Fields[1], ns.Fields[2]}
+				layouts["C"].Namespaces["ns"] = ns
+			},
+			expected: []string{
+				"C: ns: field b moved from position 1 to 2",
+				"C: ns: field c moved from position 2 to 3",
+			},
+		},
+		{
+			name: "struct member retyped",
+			modify: func(layouts Layouts) {
+				layouts["C"].Structs["T"][0].Type = "uint256"
+			},
+			expected: []string{
+				"C: struct T: field x retyped from bool to uint256",
+			},
+		},
+		{
+			name: "namespace removed",
+			modify: func(layouts Layouts) {
+				delete(layouts["C"].Namespaces, "ns")
+			},
+			expected: []string{
+				"C: ns: namespace removed",
+			},
+		},
+		{
+			name: "contract removed",
+			modify: func(layouts Layouts) {
+				delete(layouts, "C")
+			},
+			expected: []string{
+				"C: contract removed",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			newLayouts := Layouts{"C": base()}
+			testCase.modify(newLayouts)
+
+			var messages []string
+			for _, incompatibility := range Compare(Layouts{"C": base()}, newLayouts) {
+				messages = append(messages, incompatibility.String())
+			}
+			require.Equal(t, testCase.expected, messages)
+		})
+	}
+}
+
+// Checks that the layouts of the current contracts include the namespaces of every contract they inherit,
+// including those of their dependencies, so that none are left out of the layouts of a release.
+func TestCurrentContractsNamespaces(t *testing.T) {
+	requireForgeOutput(t)
+	inherited := []string{
+		"openzeppelin.storage.Initializable",
+		"openzeppelin.storage.Ownable",
+		"openzeppelin.storage.ReentrancyGuard",
+		"teleporter.storage.TeleporterRegistryApp",
+		"avalanche-ictt.storage.SendReentrancyGuard",
+	}
+	expected := map[string][]string{
+		"ERC20TokenHomeUpgradeable": {
+			"avalanche-ictt.storage.TokenHome",
+			"avalanche-ictt.storage.ERC20TokenHome",
+		},
+		"NativeTokenHomeUpgradeable": {
+			"avalanche-ictt.storage.TokenHome",
+			"avalanche-ictt.storage.NativeTokenHome",
+		},
+		"ERC20TokenRemoteUpgradeable": {
+			"openzeppelin.storage.ERC20",
+			"avalanche-ictt.storage.TokenRemote",
+			"avalanche-ictt.storage.ERC20TokenRemote",
+		},
+		"NativeTokenRemoteUpgradeable": {
+			"openzeppelin.storage.ERC20",
+			"avalanche-ictt.storage.TokenRemote",
+			"avalanche-ictt.storage.NativeTokenRemote",
+		},
+	}
+
+	layouts, err := Load(forgeOutDir, UpgradeableContracts)
+	require.NoError(t, err)
+	require.Len(t, layouts, len(expected))
+	for contract, namespaces := range expected {
+		require.ElementsMatch(t, append(namespaces, inherited...), sortedKeys(layouts[contract].Namespaces), contract)
+	}
+}
+
+// requireForgeOutput skips the test if the contracts have not been built with forge, unless it runs in CI,
+// where they are built before the tests.
+func requireForgeOutput(t *testing.T) {
+	if _, err := os.Stat(forgeOutDir); os.IsNotExist(err) {
+		if os.Getenv("CI") != "" {
+			t.Fatalf("%s not found, the contracts must be built with forge before the tests", forgeOutDir)
+		}
+		t.Skipf("%s not found, run forge build in the contracts directory", forgeOutDir)
+	}
+}
@@ -0,0 +1,459 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package subscription delivers the logs of token transferrer events once they are confirmed.
+// Logs are backfilled from a checkpoint with FilterLogs, as the generated Filter* methods do, and then
+// tailed with SubscribeFilterLogs, as the generated Watch* methods do. Logs are only delivered after
+// a number of confirmations, logs of delivered blocks that are reorged out are retracted, and the
+// subscriptions are transparently re-established after the connection to the chain fails.
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/interfaces"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultRetainBlocks   = 128
+	defaultMaxBlockRange  = 2048
+	defaultReconnectDelay = time.Second
+
+	// Capacity of the channels the subscriptions deliver to
+	subscriptionBuffer = 256
+)
+
+var errMissingDial = errors.New("missing dial function")
+
+// Backend is the subset of the RPC client needed to subscribe to logs.
+// Subscriptions require a websocket connection.
+type Backend interface {
+	bind.ContractFilterer
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (interfaces.Subscription, error)
+}
+
+// Config selects the logs to deliver and how they are confirmed.
+type Config struct {
+	// The addresses and topics of the logs. The block range of the query is ignored.
+	Query interfaces.FilterQuery
+	// Number of blocks on top of the block of a log before it is delivered.
+	// Zero delivers logs as soon as their block is accepted.
+	Confirmations uint64
+	// The first block whose logs are delivered, typically the checkpoint of a previous run.
+	FromBlock uint64
+	// Number of blocks below the confirmation depth whose delivered logs are kept, so that they can be
+	// retracted if the blocks are reorged out. Defaults to 128.
+	RetainBlocks uint64
+	// Maximum number of blocks requested at once when backfilling. Defaults to 2048.
+	MaxBlockRange uint64
+	// Time waited before reconnecting after the connection fails. Defaults to one second.
+	ReconnectDelay time.Duration
+	// Dial connects to the chain. It is called again to reconnect after the connection fails.
+	// Backends with a Close method are closed when their connection ends.
+	Dial func(ctx context.Context) (Backend, error)
+}
+
+// EventQuery returns the query for the given events of the contract ABI, emitted by any of the addresses.
+func EventQuery(
+	contractABI *abi.ABI,
+	addresses []common.Address,
+	eventNames ...string,
+) (interfaces.FilterQuery, error) {
+	var eventIDs []common.Hash
+	for _, name := range eventNames {
+		event, ok := contractABI.Events[name]
+		if !ok {
+			return interfaces.FilterQuery{}, fmt.Errorf("unknown event %s", name)
+		}
+		eventIDs = append(eventIDs, event.ID)
+	}
+	return interfaces.FilterQuery{
+		Addresses: addresses,
+		Topics:    [][]common.Hash{eventIDs},
+	}, nil
+}
+
+type logKey struct {
+	blockHash common.Hash
+	index     uint
+}
+
+// deliveredBlock is the logs delivered for a block.
+type deliveredBlock struct {
+	hash common.Hash
+	logs []types.Log
+}
+
+// Subscriber delivers the confirmed logs matching its query.
+type Subscriber struct {
+	config Config
+	// The first block whose logs have not all been delivered
+	checkpoint atomic.Uint64
+	// The logs delivered for recent blocks, by block number
+	delivered map[uint64]*deliveredBlock
+	// The logs received from the subscription that are not yet confirmed
+	pending map[logKey]types.Log
+}
+
+func NewSubscriber(config Config) (*Subscriber, error) {
+	if config.Dial == nil {
+		return nil, errMissingDial
+	}
+	if config.RetainBlocks == 0 {
+		config.RetainBlocks = defaultRetainBlocks
+	}
+	if config.MaxBlockRange == 0 {
+		config.MaxBlockRange = defaultMaxBlockRange
+	}
+	if config.ReconnectDelay == 0 {
+		config.ReconnectDelay = defaultReconnectDelay
+	}
+	s := &Subscriber{
+		config:    config,
+		delivered: make(map[uint64]*deliveredBlock),
+		pending:   make(map[logKey]types.Log),
+	}
+	s.checkpoint.Store(config.FromBlock)
+	return s, nil
+}
+
+// Checkpoint returns the first block whose logs have not all been delivered. Passing it as the FromBlock
+// of a new Subscriber resumes the delivery of logs without gaps.
+func (s *Subscriber) Checkpoint() uint64 {
+	return s.checkpoint.Load()
+}
+
+// Run delivers logs to sink until ctx is done, reconnecting whenever the connection fails.
+// Logs are delivered in order once confirmed. A log with Removed set retracts a previously delivered
+// log whose block was reorged out, and the logs of the new blocks are then delivered once confirmed.
+func (s *Subscriber) Run(ctx context.Context, sink chan<- types.Log) error {
+	for {
+		err := s.runConnection(ctx, sink)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Warn("Log subscription failed, reconnecting", "checkpoint", s.Checkpoint(), "err", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.config.ReconnectDelay):
+		}
+	}
+}
+
+func (s *Subscriber) runConnection(ctx context.Context, sink chan<- types.Log) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	backend, err := s.config.Dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to dial: %w", err)
+	}
+	if closer, ok := backend.(interface{ Close() }); ok {
+		defer closer.Close()
+	}
+
+	// Subscribe before backfilling, so that no log is missed in between.
+	heads := make(chan *types.Header, subscriptionBuffer)
+	headSub, err := backend.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to new heads: %w", err)
+	}
+	defer headSub.Unsubscribe()
+	logs := make(chan types.Log, subscriptionBuffer)
+	logSub, err := backend.SubscribeFilterLogs(ctx, s.query(), logs)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to logs: %w", err)
+	}
+	defer logSub.Unsubscribe()
+
+	// Logs left pending by a previous connection are backfilled instead
+	s.pending = make(map[logKey]types.Log)
+	if err := s.retractReorged(ctx, backend, sink); err != nil {
+		return err
+	}
+	head, err := backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get head: %w", err)
+	}
+	if err := s.backfill(ctx, backend, head.Number.Uint64(), sink); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-headSub.Err():
+			return fmt.Errorf("head subscription failed: %w", err)
+		case err := <-logSub.Err():
+			return fmt.Errorf("log subscription failed: %w", err)
+		case l := <-logs:
+			if err := s.handleLog(ctx, l, sink); err != nil {
+				return err
+			}
+		case header := <-heads:
+			// Handle the logs received before the head first, since they may be confirmed by it
+			if err := s.drainLogs(ctx, logs, sink); err != nil {
+				return err
+			}
+			if err := s.release(ctx, backend, header.Number.Uint64(), sink); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Subscriber) query() interfaces.FilterQuery {
+	return interfaces.FilterQuery{
+		Addresses: s.config.Query.Addresses,
+		Topics:    s.config.Query.Topics,
+	}
+}
+
+// confirmed returns the last confirmed block at the given head, or false if there is none.
+func (s *Subscriber) confirmed(head uint64) (uint64, bool) {
+	if head < s.config.Confirmations {
+		return 0, false
+	}
+	return head - s.config.Confirmations, true
+}
+
+// retractReorged retracts the delivered logs of the retained blocks that are no longer canonical,
+// which happens when a reorg deeper than the confirmations occurred while disconnected.
+func (s *Subscriber) retractReorged(ctx context.Context, backend Backend, sink chan<- types.Log) error {
+	numbers := make([]uint64, 0, len(s.delivered))
+	for number := range s.delivered {
+		numbers = append(numbers, number)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+
+	for i, number := range numbers {
+		header, err := backend.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return fmt.Errorf("failed to get header %d: %w", number, err)
+		}
+		if header.Hash() == s.delivered[number].hash {
+			continue
+		}
+		// Every later block is also reorged out, so retract them all, latest first
+		for j := len(numbers) - 1; j >= i; j-- {
+			if err := s.retractBlock(ctx, numbers[j], sink); err != nil {
+				return err
+			}
+		}
+		if number < s.Checkpoint() {
+			s.checkpoint.Store(number)
+		}
+		return nil
+	}
+	return nil
+}
+
+// backfill delivers the logs of the confirmed blocks from the checkpoint, and keeps the logs of the
+// blocks up to the head until they are confirmed.
+func (s *Subscriber) backfill(ctx context.Context, backend Backend, head uint64, sink chan<- types.Log) error {
+	confirmed, ok := s.confirmed(head)
+	for from := s.Checkpoint(); from <= head; from += s.config.MaxBlockRange {
+		to := from + s.config.MaxBlockRange - 1
+		if to > head {
+			to = head
+		}
+		query := s.query()
+		query.FromBlock = new(big.Int).SetUint64(from)
+		query.ToBlock = new(big.Int).SetUint64(to)
+		logs, err := backend.FilterLogs(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to get logs of blocks %d to %d: %w", from, to, err)
+		}
+		for _, l := range logs {
+			if !ok || l.BlockNumber > confirmed {
+				s.pending[logKey{blockHash: l.BlockHash, index: l.Index}] = l
+				continue
+			}
+			if err := s.deliver(ctx, l, sink); err != nil {
+				return err
+			}
+		}
+		if ok && min(to, confirmed)+1 > s.Checkpoint() {
+			s.checkpoint.Store(min(to, confirmed) + 1)
+		}
+	}
+	if ok {
+		s.prune(confirmed)
+	}
+	return nil
+}
+
+// handleLog keeps a new log until it is confirmed, or handles the removal of a log by a reorg.
+func (s *Subscriber) handleLog(ctx context.Context, l types.Log, sink chan<- types.Log) error {
+	key := logKey{blockHash: l.BlockHash, index: l.Index}
+	if !l.Removed {
+		if !s.isDelivered(l) {
+			s.pending[key] = l
+		}
+		return nil
+	}
+
+	if _, ok := s.pending[key]; ok {
+		// Reorged out before it was confirmed
+		delete(s.pending, key)
+		return nil
+	}
+	block, ok := s.delivered[l.BlockNumber]
+	if !ok || block.hash != l.BlockHash {
+		return nil
+	}
+	for i, delivered := range block.logs {
+		if delivered.Index != l.Index {
+			continue
+		}
+		block.logs = append(block.logs[:i], block.logs[i+1:]...)
+		if l.BlockNumber < s.Checkpoint() {
+			s.checkpoint.Store(l.BlockNumber)
+		}
+		return send(ctx, sink, l)
+	}
+	return nil
+}
+
+func (s *Subscriber) drainLogs(ctx context.Context, logs <-chan types.Log, sink chan<- types.Log) error {
+	for {
+		select {
+		case l := <-logs:
+			if err := s.handleLog(ctx, l, sink); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// release delivers the pending logs confirmed at the given head, if their blocks are still canonical.
+func (s *Subscriber) release(ctx context.Context, backend Backend, head uint64, sink chan<- types.Log) error {
+	confirmed, ok := s.confirmed(head)
+	if !ok {
+		return nil
+	}
+	var ready []types.Log
+	for key, l := range s.pending {
+		if l.BlockNumber <= confirmed {
+			ready = append(ready, l)
+			delete(s.pending, key)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		if ready[i].BlockNumber != ready[j].BlockNumber {
+			return ready[i].BlockNumber < ready[j].BlockNumber
+		}
+		return ready[i].Index < ready[j].Index
+	})
+
+	canonical := make(map[uint64]common.Hash)
+	for _, l := range ready {
+		hash, ok := canonical[l.BlockNumber]
+		if !ok {
+			header, err := backend.HeaderByNumber(ctx, new(big.Int).SetUint64(l.BlockNumber))
+			if err != nil {
+				return fmt.Errorf("failed to get header %d: %w", l.BlockNumber, err)
+			}
+			hash = header.Hash()
+			canonical[l.BlockNumber] = hash
+		}
+		if l.BlockHash != hash {
+			continue
+		}
+		if err := s.deliver(ctx, l, sink); err != nil {
+			return err
+		}
+	}
+	if confirmed+1 > s.Checkpoint() {
+		s.checkpoint.Store(confirmed + 1)
+	}
+	s.prune(confirmed)
+	return nil
+}
+
+func (s *Subscriber) isDelivered(l types.Log) bool {
+	block, ok := s.delivered[l.BlockNumber]
+	if !ok || block.hash != l.BlockHash {
+		return false
+	}
+	for _, delivered := range block.logs {
+		if delivered.Index == l.Index {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subscriber) deliver(ctx context.Context, l types.Log, sink chan<- types.Log) error {
+	if s.isDelivered(l) {
+		return nil
+	}
+	block, ok := s.delivered[l.BlockNumber]
+	if ok && block.hash != l.BlockHash {
+		// The block was replaced by a reorg whose removed logs were not received
+		if err := s.retractBlock(ctx, l.BlockNumber, sink); err != nil {
+			return err
+		}
+		ok = false
+	}
+	if !ok {
+		block = &deliveredBlock{hash: l.BlockHash}
+		s.delivered[l.BlockNumber] = block
+	}
+	block.logs = append(block.logs, l)
+	return send(ctx, sink, l)
+}
+
+// retractBlock retracts the delivered logs of a block, latest first.
+func (s *Subscriber) retractBlock(ctx context.Context, number uint64, sink chan<- types.Log) error {
+	block, ok := s.delivered[number]
+	if !ok {
+		return nil
+	}
+	delete(s.delivered, number)
+	for i := len(block.logs) - 1; i >= 0; i-- {
+		removed := block.logs[i]
+		removed.Removed = true
+		if err := send(ctx, sink, removed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prune forgets the delivered logs of blocks more than RetainBlocks below the confirmed block.
+func (s *Subscriber) prune(confirmed uint64) {
+	if confirmed < s.config.RetainBlocks {
+		return
+	}
+	for number := range s.delivered {
+		if number < confirmed-s.config.RetainBlocks {
+			delete(s.delivered, number)
+		}
+	}
+}
+
+func send(ctx context.Context, sink chan<- types.Log, l types.Log) error {
+	select {
+	case sink <- l:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
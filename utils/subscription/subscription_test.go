@@ -0,0 +1,352 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package subscription
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/interfaces"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTimeout = 5 * time.Second
+	// Time waited to check that no log is delivered
+	quietPeriod = 100 * time.Millisecond
+)
+
+var errDisconnected = errors.New("disconnected")
+
+type fakeSubscription struct {
+	chain *fakeChain
+	err   chan error
+}
+
+func newFakeSubscription(chain *fakeChain) *fakeSubscription {
+	return &fakeSubscription{chain: chain, err: make(chan error, 1)}
+}
+
+func (s *fakeSubscription) Unsubscribe() {
+	s.chain.lock.Lock()
+	defer s.chain.lock.Unlock()
+	delete(s.chain.logSubs, s)
+	delete(s.chain.headSubs, s)
+}
+
+func (s *fakeSubscription) Err() <-chan error {
+	return s.err
+}
+
+type fakeBlock struct {
+	header *types.Header
+	logs   []types.Log
+}
+
+// fakeChain is a chain whose blocks are mined, reorged and disconnected by the tests.
+// Each log carries a unique ID in its data.
+type fakeChain struct {
+	lock     sync.Mutex
+	blocks   []*fakeBlock
+	logSubs  map[*fakeSubscription]chan<- types.Log
+	headSubs map[*fakeSubscription]chan<- *types.Header
+	down     bool
+	dials    atomic.Int32
+	// Distinguishes the blocks of each fork
+	fork byte
+}
+
+func newFakeChain() *fakeChain {
+	return &fakeChain{
+		blocks:   []*fakeBlock{{header: &types.Header{Number: big.NewInt(0)}}},
+		logSubs:  make(map[*fakeSubscription]chan<- types.Log),
+		headSubs: make(map[*fakeSubscription]chan<- *types.Header),
+	}
+}
+
+func (c *fakeChain) dial(context.Context) (Backend, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.down {
+		return nil, errDisconnected
+	}
+	c.dials.Add(1)
+	return c, nil
+}
+
+// mine adds a block with a log for each of the given IDs.
+func (c *fakeChain) mine(logIDs ...byte) {
+	c.lock.Lock()
+	parent := c.blocks[len(c.blocks)-1].header
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     big.NewInt(int64(len(c.blocks))),
+		Extra:      []byte{c.fork},
+	}
+	block := &fakeBlock{header: header}
+	for i, id := range logIDs {
+		block.logs = append(block.logs, types.Log{
+			Data:        []byte{id},
+			BlockNumber: header.Number.Uint64(),
+			BlockHash:   header.Hash(),
+			Index:       uint(i),
+		})
+	}
+	c.blocks = append(c.blocks, block)
+	logSubs, headSubs := c.subscribers()
+	c.lock.Unlock()
+
+	for _, l := range block.logs {
+		for _, ch := range logSubs {
+			ch <- l
+		}
+	}
+	for _, ch := range headSubs {
+		ch <- header
+	}
+}
+
+// reorg removes the blocks after number, which are then replaced by the blocks mined next.
+func (c *fakeChain) reorg(number uint64) {
+	c.lock.Lock()
+	removed := c.blocks[number+1:]
+	c.blocks = c.blocks[:number+1]
+	c.fork++
+	logSubs, _ := c.subscribers()
+	c.lock.Unlock()
+
+	for i := len(removed) - 1; i >= 0; i-- {
+		for _, l := range removed[i].logs {
+			l.Removed = true
+			for _, ch := range logSubs {
+				ch <- l
+			}
+		}
+	}
+}
+
+// disconnect fails the subscriptions, and fails to dial until reconnect is called.
+func (c *fakeChain) disconnect() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.down = true
+	for sub := range c.logSubs {
+		sub.err <- errDisconnected
+	}
+	for sub := range c.headSubs {
+		sub.err <- errDisconnected
+	}
+	c.logSubs = make(map[*fakeSubscription]chan<- types.Log)
+	c.headSubs = make(map[*fakeSubscription]chan<- *types.Header)
+}
+
+func (c *fakeChain) reconnect() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.down = false
+}
+
+func (c *fakeChain) subscribers() ([]chan<- types.Log, []chan<- *types.Header) {
+	var logSubs []chan<- types.Log
+	for _, ch := range c.logSubs {
+		logSubs = append(logSubs, ch)
+	}
+	var headSubs []chan<- *types.Header
+	for _, ch := range c.headSubs {
+		headSubs = append(headSubs, ch)
+	}
+	return logSubs, headSubs
+}
+
+func (c *fakeChain) FilterLogs(_ context.Context, query interfaces.FilterQuery) ([]types.Log, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	to := uint64(len(c.blocks) - 1)
+	if query.ToBlock != nil && query.ToBlock.Uint64() < to {
+		to = query.ToBlock.Uint64()
+	}
+	var logs []types.Log
+	for number := query.FromBlock.Uint64(); number <= to; number++ {
+		logs = append(logs, c.blocks[number].logs...)
+	}
+	return logs, nil
+}
+
+func (c *fakeChain) SubscribeFilterLogs(
+	_ context.Context,
+	_ interfaces.FilterQuery,
+	ch chan<- types.Log,
+) (interfaces.Subscription, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	sub := newFakeSubscription(c)
+	c.logSubs[sub] = ch
+	return sub, nil
+}
+
+func (c *fakeChain) SubscribeNewHead(_ context.Context, ch chan<- *types.Header) (interfaces.Subscription, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	sub := newFakeSubscription(c)
+	c.headSubs[sub] = ch
+	return sub, nil
+}
+
+func (c *fakeChain) HeaderByNumber(_ context.Context, number *big.Int) (*types.Header, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if number == nil {
+		return c.blocks[len(c.blocks)-1].header, nil
+	}
+	if number.Uint64() >= uint64(len(c.blocks)) {
+		return nil, interfaces.NotFound
+	}
+	return c.blocks[number.Uint64()].header, nil
+}
+
+// isSubscribed returns true once the subscriber has subscribed to both heads and logs.
+func (c *fakeChain) isSubscribed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.logSubs) != 0 && len(c.headSubs) != 0
+}
+
+// delivery is a delivered log, identified by its ID.
+type delivery struct {
+	id      byte
+	removed bool
+}
+
+func startSubscriber(t *testing.T, chain *fakeChain, config Config) (*Subscriber, <-chan types.Log) {
+	config.Dial = chain.dial
+	config.ReconnectDelay = 10 * time.Millisecond
+	subscriber, err := NewSubscriber(config)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sink := make(chan types.Log, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- subscriber.Run(ctx, sink)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+	})
+	require.Eventually(t, chain.isSubscribed, testTimeout, time.Millisecond)
+	return subscriber, sink
+}
+
+func expectDeliveries(t *testing.T, sink <-chan types.Log, expected ...delivery) {
+	for _, want := range expected {
+		select {
+		case l := <-sink:
+			require.Equal(t, want, delivery{id: l.Data[0], removed: l.Removed})
+		case <-time.After(testTimeout):
+			require.FailNow(t, "timed out waiting for log", "expected %+v", want)
+		}
+	}
+	select {
+	case l := <-sink:
+		require.FailNow(t, "unexpected log", "got %+v", delivery{id: l.Data[0], removed: l.Removed})
+	case <-time.After(quietPeriod):
+	}
+}
+
+func TestSubscriberConfirmations(t *testing.T) {
+	chain := newFakeChain()
+	for id := byte(1); id <= 5; id++ {
+		chain.mine(id)
+	}
+
+	// Backfills the blocks with two confirmations, then delivers each block once it has two confirmations
+	subscriber, sink := startSubscriber(t, chain, Config{Confirmations: 2, FromBlock: 2})
+	expectDeliveries(t, sink, delivery{id: 2}, delivery{id: 3})
+	require.Equal(t, uint64(4), subscriber.Checkpoint())
+
+	chain.mine(6, 7)
+	expectDeliveries(t, sink, delivery{id: 4})
+	chain.mine()
+	expectDeliveries(t, sink, delivery{id: 5})
+	chain.mine()
+	expectDeliveries(t, sink, delivery{id: 6}, delivery{id: 7})
+	require.Equal(t, uint64(7), subscriber.Checkpoint())
+}
+
+func TestSubscriberReorg(t *testing.T) {
+	chain := newFakeChain()
+	_, sink := startSubscriber(t, chain, Config{Confirmations: 1})
+
+	chain.mine(1)
+	chain.mine()
+	expectDeliveries(t, sink, delivery{id: 1})
+
+	// Reorged out before it is confirmed, so never delivered
+	chain.mine(2)
+	chain.reorg(2)
+	chain.mine(3)
+	chain.mine()
+	expectDeliveries(t, sink, delivery{id: 3})
+
+	// Reorged out after it is confirmed, so retracted
+	chain.reorg(0)
+	expectDeliveries(t, sink, delivery{id: 3, removed: true}, delivery{id: 1, removed: true})
+	chain.mine(4)
+	chain.mine()
+	expectDeliveries(t, sink, delivery{id: 4})
+}
+
+func TestSubscriberReconnect(t *testing.T) {
+	chain := newFakeChain()
+	subscriber, sink := startSubscriber(t, chain, Config{Confirmations: 1})
+
+	chain.mine(1)
+	chain.mine(2)
+	chain.mine()
+	expectDeliveries(t, sink, delivery{id: 1}, delivery{id: 2})
+
+	// While disconnected, block 2 is reorged out and new blocks are mined
+	chain.disconnect()
+	chain.reorg(1)
+	chain.mine(3)
+	chain.mine(4)
+	chain.mine()
+	expectDeliveries(t, sink)
+	require.Equal(t, uint64(3), subscriber.Checkpoint())
+
+	chain.reconnect()
+	expectDeliveries(t, sink, delivery{id: 2, removed: true}, delivery{id: 3}, delivery{id: 4})
+	require.Equal(t, int32(2), chain.dials.Load())
+	require.Equal(t, uint64(4), subscriber.Checkpoint())
+
+	// Tails the new subscription
+	chain.mine(5)
+	chain.mine()
+	expectDeliveries(t, sink, delivery{id: 5})
+}
+
+func TestEventQuery(t *testing.T) {
+	tokenHomeABI, err := tokenhome.TokenHomeMetaData.GetAbi()
+	require.NoError(t, err)
+	address := common.HexToAddress("0x1111111111111111111111111111111111111111")
+
+	query, err := EventQuery(tokenHomeABI, []common.Address{address}, "TokensSent", "CollateralAdded")
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{address}, query.Addresses)
+	require.Equal(t, [][]common.Hash{{
+		tokenHomeABI.Events["TokensSent"].ID,
+		tokenHomeABI.Events["CollateralAdded"].ID,
+	}}, query.Topics)
+
+	_, err = EventQuery(tokenHomeABI, nil, "Unknown")
+	require.ErrorContains(t, err, "unknown event Unknown")
+}
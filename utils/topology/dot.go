@@ -0,0 +1,114 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package topology
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DOT returns the topology as a Graphviz digraph. The home is linked to each remote by an edge labeled with
+// the scaling of its amounts and its collateralization, drawn in red while the home needs collateral for it.
+// The viable multi-hop routes between remotes are drawn as dashed edges. Remotes with problems or that
+// could not be inspected are drawn in red.
+func (t *Topology) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph topology {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+	b.WriteString("  edge [fontname=\"monospace\"];\n")
+	fmt.Fprintf(&b, "  %s [label=%s, style=bold];\n", nodeID(t.Home.BlockchainID.String(), t.Home.Address.Hex()),
+		quote(nodeLabel(&t.Home)))
+
+	for i := range t.Registrations {
+		registration := &t.Registrations[i]
+		remote := &registration.Remote
+		attributes := ""
+		if !remote.Reachable || len(remote.Problems) != 0 {
+			attributes = ", color=red"
+		}
+		fmt.Fprintf(&b, "  %s [label=%s%s];\n", registrationNodeID(registration), quote(nodeLabel(remote)), attributes)
+	}
+	for i := range t.Registrations {
+		registration := &t.Registrations[i]
+		label := formatScale(registration.TokenMultiplier, registration.MultiplyOnRemote)
+		attributes := ""
+		if registration.Collateralized() {
+			label += "\ncollateralized"
+		} else {
+			label += "\nneeds " + registration.CollateralNeeded.String() + " collateral"
+			attributes = ", color=red, fontcolor=red"
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s, dir=both%s];\n",
+			nodeID(t.Home.BlockchainID.String(), t.Home.Address.Hex()),
+			registrationNodeID(registration),
+			quote(label),
+			attributes,
+		)
+	}
+	for _, route := range t.Routes {
+		if !route.MultiHop || !route.Viable {
+			continue
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s, style=dashed, constraint=false];\n",
+			nodeID(route.Source.BlockchainID.String(), route.Source.Address.Hex()),
+			nodeID(route.Destination.BlockchainID.String(), route.Destination.Address.Hex()),
+			quote("min "+route.MinAmount.String()),
+		)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func registrationNodeID(registration *Registration) string {
+	return nodeID(registration.Remote.BlockchainID.String(), registration.Remote.Address.Hex())
+}
+
+// nodeID returns the DOT ID of the token transferrer at the address on the chain.
+func nodeID(blockchainID string, address string) string {
+	return quote(blockchainID + "/" + address)
+}
+
+// nodeLabel describes the token transferrer of a node on several lines.
+func nodeLabel(node *Node) string {
+	kind := string(node.Kind)
+	if !node.Reachable {
+		kind = "unreachable"
+	}
+	lines := []string{kind, node.BlockchainID.String(), node.Address.Hex()}
+	if node.Reachable {
+		lines = append(lines, fmt.Sprintf("%d decimals", node.TokenDecimals))
+	}
+	if node.IsCollateralized != nil && !*node.IsCollateralized {
+		lines = append(lines, "awaiting collateral")
+	}
+	lines = append(lines, node.Problems...)
+	return strings.Join(lines, "\n")
+}
+
+// quote returns s as a DOT string, with newlines as line breaks.
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+// formatScale describes how a remote scales the amounts of the home, such as "x10^12" for a remote with
+// twelve more decimals than the home, or "/10^12" for a remote with twelve fewer.
+func formatScale(tokenMultiplier *big.Int, multiplyOnRemote bool) string {
+	if tokenMultiplier.Cmp(big.NewInt(1)) == 0 {
+		return "1:1"
+	}
+	multiplier := tokenMultiplier.String()
+	// Powers of ten from 1000 are written as exponents
+	if len(multiplier) > 3 && multiplier[0] == '1' && strings.Trim(multiplier[1:], "0") == "" {
+		multiplier = "10^" + strconv.Itoa(len(multiplier)-1)
+	}
+	if multiplyOnRemote {
+		return "x" + multiplier
+	}
+	return "/" + multiplier
+}
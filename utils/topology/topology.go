@@ -0,0 +1,277 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package topology discovers the token transferrers of a TokenHome: the remotes registered with it,
+// how each scales amounts and whether it is collateralized, and which of the routes between them,
+// including the multi-hop routes between remotes, can be used. Topologies are exported as JSON and
+// as Graphviz DOT.
+package topology
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	tokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/TokenRemote"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/portfolio"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Config describes the TokenHome and the chains its remotes are inspected on.
+type Config struct {
+	HomeBlockchainID ids.ID
+	HomeAddress      common.Address
+	// The backend of each chain, by blockchain ID. Remotes on other chains are reported as unreachable,
+	// from the settings the home registered them with.
+	Chains map[ids.ID]portfolio.Backend
+}
+
+// Node is a token transferrer of the topology.
+type Node struct {
+	BlockchainID ids.ID         `json:"blockchainID"`
+	Address      common.Address `json:"address"`
+	// False if the chain of the token transferrer is not configured, or it could not be inspected
+	Reachable     bool           `json:"reachable"`
+	Kind          inspect.Kind   `json:"kind,omitempty"`
+	TokenAddress  common.Address `json:"tokenAddress"`
+	TokenDecimals uint8          `json:"tokenDecimals"`
+	// Whether a remote has received the collateral of its initial reserve imbalance, as it reports it.
+	// Nil for the home and for unreachable remotes.
+	IsCollateralized *bool `json:"isCollateralized,omitempty"`
+	// Inconsistencies between the token transferrer and the home, such as a remote linked to another home
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Registration is the edge between the home and a remote, with the settings the home registered it with.
+type Registration struct {
+	Remote           Node     `json:"remote"`
+	TokenMultiplier  *big.Int `json:"tokenMultiplier"`
+	MultiplyOnRemote bool     `json:"multiplyOnRemote"`
+	// The collateral the home still needs before tokens can be sent to the remote
+	CollateralNeeded *big.Int `json:"collateralNeeded"`
+}
+
+// Collateralized returns true if the home has all the collateral it needs to send tokens to the remote.
+func (r *Registration) Collateralized() bool {
+	return r.CollateralNeeded.Sign() == 0
+}
+
+// Endpoint identifies a token transferrer.
+type Endpoint struct {
+	BlockchainID ids.ID         `json:"blockchainID"`
+	Address      common.Address `json:"address"`
+}
+
+// Route is a transfer between two token transferrers of the topology.
+type Route struct {
+	Source      Endpoint `json:"source"`
+	Destination Endpoint `json:"destination"`
+	// True for transfers between remotes, which are routed through the home
+	MultiHop bool `json:"multiHop"`
+	// Whether the home accepts transfers on the route, and if not, why
+	Viable bool   `json:"viable"`
+	Reason string `json:"reason,omitempty"`
+	// The smallest amount of source tokens that is not scaled down to zero on the way to the destination,
+	// before fees. Smaller transfers are rejected, and larger ones lose the precision the destination
+	// cannot represent.
+	MinAmount *big.Int `json:"minAmount"`
+}
+
+// Topology is a TokenHome with every remote registered with it.
+type Topology struct {
+	Home          Node           `json:"home"`
+	Registrations []Registration `json:"registrations"`
+	// The routes from the home to each remote, from each remote to the home, and between every
+	// pair of remotes
+	Routes []Route `json:"routes"`
+}
+
+// Discover returns the topology of the TokenHome of the config. The remotes are discovered from the
+// RemoteRegistered events of the home, in the order of their registration.
+func Discover(ctx context.Context, config Config) (*Topology, error) {
+	homeBackend, ok := config.Chains[config.HomeBlockchainID]
+	if !ok {
+		return nil, fmt.Errorf("missing backend of the home chain %s", config.HomeBlockchainID)
+	}
+	t := &Topology{
+		Home: Node{BlockchainID: config.HomeBlockchainID, Address: config.HomeAddress},
+	}
+	if err := inspectNode(ctx, homeBackend, &t.Home); err != nil {
+		return nil, err
+	}
+	if !t.Home.Kind.IsHome() {
+		return nil, fmt.Errorf("%s is a %s, not a token home", config.HomeAddress, t.Home.Kind)
+	}
+
+	registered, err := portfolio.DiscoverRemotes(ctx, homeBackend, config.HomeAddress)
+	if err != nil {
+		return nil, err
+	}
+	home, err := tokenhome.NewTokenHomeCaller(config.HomeAddress, homeBackend)
+	if err != nil {
+		return nil, err
+	}
+	for _, remote := range registered {
+		settings, err := home.GetRemoteTokenTransferrerSettings(
+			&bind.CallOpts{Context: ctx},
+			remote.BlockchainID,
+			remote.Address,
+		)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to get settings of remote %s on %s: %w", remote.Address, remote.BlockchainID, err,
+			)
+		}
+		registration := Registration{
+			Remote:           Node{BlockchainID: remote.BlockchainID, Address: remote.Address},
+			TokenMultiplier:  settings.TokenMultiplier,
+			MultiplyOnRemote: settings.MultiplyOnRemote,
+			CollateralNeeded: settings.CollateralNeeded,
+		}
+		if backend, ok := config.Chains[remote.BlockchainID]; ok {
+			if err := inspectRemote(ctx, backend, &t.Home, &registration); err != nil {
+				registration.Remote.Problems = append(registration.Remote.Problems, err.Error())
+			}
+		}
+		t.Registrations = append(t.Registrations, registration)
+	}
+	t.Routes = routes(t)
+	return t, nil
+}
+
+// inspectNode fills in the kind and token of the token transferrer of the node.
+func inspectNode(ctx context.Context, backend inspect.Backend, node *Node) error {
+	info, err := inspect.Inspect(ctx, backend, node.Address)
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s on %s: %w", node.Address, node.BlockchainID, err)
+	}
+	node.Reachable = true
+	node.Kind = info.Kind
+	node.TokenAddress = info.TokenAddress
+	node.TokenDecimals = info.TokenDecimals
+	return nil
+}
+
+// inspectRemote fills in the remote of the registration from its getters, and records where they
+// disagree with the home.
+func inspectRemote(ctx context.Context, backend inspect.Backend, home *Node, registration *Registration) error {
+	node := &registration.Remote
+	if err := inspectNode(ctx, backend, node); err != nil {
+		return err
+	}
+	if !node.Kind.IsRemote() {
+		node.Problems = append(node.Problems, fmt.Sprintf("registered as a remote, but is a %s", node.Kind))
+		return nil
+	}
+
+	remote, err := tokenremote.NewTokenRemoteCaller(node.Address, backend)
+	if err != nil {
+		return err
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	isCollateralized, err := remote.GetIsCollateralized(opts)
+	if err != nil {
+		return fmt.Errorf("failed to get collateralization of %s: %w", node.Address, err)
+	}
+	node.IsCollateralized = &isCollateralized
+	homeBlockchainID, err := remote.GetTokenHomeBlockchainID(opts)
+	if err != nil {
+		return fmt.Errorf("failed to get home of %s: %w", node.Address, err)
+	}
+	homeAddress, err := remote.GetTokenHomeAddress(opts)
+	if err != nil {
+		return fmt.Errorf("failed to get home of %s: %w", node.Address, err)
+	}
+	if homeBlockchainID != home.BlockchainID || homeAddress != home.Address {
+		node.Problems = append(node.Problems, fmt.Sprintf(
+			"linked to home %s on %s", homeAddress, ids.ID(homeBlockchainID),
+		))
+	}
+	tokenMultiplier, err := remote.GetTokenMultiplier(opts)
+	if err != nil {
+		return fmt.Errorf("failed to get token multiplier of %s: %w", node.Address, err)
+	}
+	multiplyOnRemote, err := remote.GetMultiplyOnRemote(opts)
+	if err != nil {
+		return fmt.Errorf("failed to get token multiplier of %s: %w", node.Address, err)
+	}
+	if tokenMultiplier.Cmp(registration.TokenMultiplier) != 0 || multiplyOnRemote != registration.MultiplyOnRemote {
+		node.Problems = append(node.Problems, fmt.Sprintf(
+			"scales by %s, but is registered as %s",
+			formatScale(tokenMultiplier, multiplyOnRemote),
+			formatScale(registration.TokenMultiplier, registration.MultiplyOnRemote),
+		))
+	}
+	return nil
+}
+
+// routes returns the routes of the topology, as checked by the home when it receives or sends tokens.
+func routes(t *Topology) []Route {
+	home := Endpoint{BlockchainID: t.Home.BlockchainID, Address: t.Home.Address}
+	var routes []Route
+	for i := range t.Registrations {
+		to := &t.Registrations[i]
+		remote := to.endpoint()
+		// Tokens can always be sent back to the home once registered, but the home only sends tokens
+		// to remotes it has all the collateral for.
+		routes = append(routes, newRoute(home, remote, false, nil, to))
+		routes = append(routes, newRoute(remote, home, false, to, nil))
+	}
+	for i := range t.Registrations {
+		for j := range t.Registrations {
+			if i == j {
+				continue
+			}
+			from, to := &t.Registrations[i], &t.Registrations[j]
+			routes = append(routes, newRoute(from.endpoint(), to.endpoint(), true, from, to))
+		}
+	}
+	return routes
+}
+
+func (r *Registration) endpoint() Endpoint {
+	return Endpoint{BlockchainID: r.Remote.BlockchainID, Address: r.Remote.Address}
+}
+
+// newRoute returns the route from the source to the destination, where from and to are the registrations
+// of the source and destination, or nil for the home.
+func newRoute(source, destination Endpoint, multiHop bool, from, to *Registration) Route {
+	route := Route{
+		Source:      source,
+		Destination: destination,
+		MultiHop:    multiHop,
+		Viable:      true,
+		MinAmount:   minAmount(from, to),
+	}
+	if to != nil && !to.Collateralized() {
+		route.Viable = false
+		route.Reason = fmt.Sprintf("the home needs %s more collateral for the destination", to.CollateralNeeded)
+	}
+	return route
+}
+
+// minAmount returns the smallest amount sent from the source that is not scaled down to zero by the home,
+// where from and to are the registrations of the source and destination, or nil for the home.
+func minAmount(from, to *Registration) *big.Int {
+	// The smallest amount of home tokens sent to the destination
+	homeAmount := big.NewInt(1)
+	if to != nil && !to.MultiplyOnRemote {
+		homeAmount.Set(to.TokenMultiplier)
+	}
+	if from == nil {
+		return homeAmount
+	}
+	// The smallest amount of source tokens worth homeAmount
+	if from.MultiplyOnRemote {
+		return homeAmount.Mul(homeAmount, from.TokenMultiplier)
+	}
+	quotient, remainder := new(big.Int).QuoRem(homeAmount, from.TokenMultiplier, new(big.Int))
+	if remainder.Sign() != 0 || quotient.Sign() == 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient
+}
@@ -0,0 +1,167 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package topology
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/inspect"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testHome         = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	testRemoteA      = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	testRemoteB      = common.HexToAddress("0x3333333333333333333333333333333333333333")
+	testRemoteC      = common.HexToAddress("0x4444444444444444444444444444444444444444")
+	testHomeChain    = ids.ID{1}
+	testRemoteChainA = ids.ID{2}
+	testRemoteChainB = ids.ID{3}
+)
+
+// newTestTopology returns the topology of an 18 decimal home with a collateralized 6 decimal remote A,
+// a 19 decimal remote B the home needs collateral for, and a remote C on the same chain as B that could
+// not be inspected.
+func newTestTopology() *Topology {
+	isCollateralized := true
+	isNotCollateralized := false
+	t := &Topology{
+		Home: Node{
+			BlockchainID:  testHomeChain,
+			Address:       testHome,
+			Reachable:     true,
+			Kind:          inspect.KindERC20TokenHome,
+			TokenDecimals: 18,
+		},
+		Registrations: []Registration{
+			{
+				Remote: Node{
+					BlockchainID:     testRemoteChainA,
+					Address:          testRemoteA,
+					Reachable:        true,
+					Kind:             inspect.KindERC20TokenRemote,
+					TokenAddress:     testRemoteA,
+					TokenDecimals:    6,
+					IsCollateralized: &isCollateralized,
+				},
+				TokenMultiplier:  big.NewInt(1e12),
+				CollateralNeeded: big.NewInt(0),
+			},
+			{
+				Remote: Node{
+					BlockchainID:     testRemoteChainB,
+					Address:          testRemoteB,
+					Reachable:        true,
+					Kind:             inspect.KindNativeTokenRemote,
+					TokenAddress:     testRemoteB,
+					TokenDecimals:    19,
+					IsCollateralized: &isNotCollateralized,
+				},
+				TokenMultiplier:  big.NewInt(10),
+				MultiplyOnRemote: true,
+				CollateralNeeded: big.NewInt(100),
+			},
+			{
+				Remote: Node{
+					BlockchainID: testRemoteChainB,
+					Address:      testRemoteC,
+					Problems:     []string{"failed to inspect"},
+				},
+				TokenMultiplier:  big.NewInt(1),
+				CollateralNeeded: big.NewInt(0),
+			},
+		},
+	}
+	t.Routes = routes(t)
+	return t
+}
+
+func TestRoutes(t *testing.T) {
+	topology := newTestTopology()
+	home := Endpoint{BlockchainID: testHomeChain, Address: testHome}
+	a := Endpoint{BlockchainID: testRemoteChainA, Address: testRemoteA}
+	b := Endpoint{BlockchainID: testRemoteChainB, Address: testRemoteB}
+	c := Endpoint{BlockchainID: testRemoteChainB, Address: testRemoteC}
+	notCollateralized := "the home needs 100 more collateral for the destination"
+
+	require.Equal(t, []Route{
+		// 1e12 home units are needed for one unit of A
+		{Source: home, Destination: a, Viable: true, MinAmount: big.NewInt(1e12)},
+		{Source: a, Destination: home, Viable: true, MinAmount: big.NewInt(1)},
+		{Source: home, Destination: b, Reason: notCollateralized, MinAmount: big.NewInt(1)},
+		// 10 units of B are needed for one home unit
+		{Source: b, Destination: home, Viable: true, MinAmount: big.NewInt(10)},
+		{Source: home, Destination: c, Viable: true, MinAmount: big.NewInt(1)},
+		{Source: c, Destination: home, Viable: true, MinAmount: big.NewInt(1)},
+		{Source: a, Destination: b, MultiHop: true, Reason: notCollateralized, MinAmount: big.NewInt(1)},
+		{Source: a, Destination: c, MultiHop: true, Viable: true, MinAmount: big.NewInt(1)},
+		// 1e13 units of B are worth the 1e12 home units needed for one unit of A
+		{Source: b, Destination: a, MultiHop: true, Viable: true, MinAmount: big.NewInt(1e13)},
+		{Source: b, Destination: c, MultiHop: true, Viable: true, MinAmount: big.NewInt(10)},
+		{Source: c, Destination: a, MultiHop: true, Viable: true, MinAmount: big.NewInt(1e12)},
+		{Source: c, Destination: b, MultiHop: true, Reason: notCollateralized, MinAmount: big.NewInt(1)},
+	}, topology.Routes)
+}
+
+func TestMinAmount(t *testing.T) {
+	// A remote with fewer decimals than the home rounds up to its next unit
+	from := &Registration{TokenMultiplier: big.NewInt(1000)}
+	to := &Registration{TokenMultiplier: big.NewInt(1500)}
+	require.Equal(t, big.NewInt(2), minAmount(from, to))
+	to.TokenMultiplier = big.NewInt(3000)
+	require.Equal(t, big.NewInt(3), minAmount(from, to))
+	require.Equal(t, big.NewInt(1), minAmount(from, nil))
+}
+
+func TestFormatScale(t *testing.T) {
+	require.Equal(t, "1:1", formatScale(big.NewInt(1), false))
+	require.Equal(t, "x10^12", formatScale(big.NewInt(1e12), true))
+	require.Equal(t, "/10", formatScale(big.NewInt(10), false))
+	require.Equal(t, "x25", formatScale(big.NewInt(25), true))
+}
+
+func TestDOT(t *testing.T) {
+	topology := newTestTopology()
+	home := `"` + testHomeChain.String() + "/" + testHome.Hex() + `"`
+	a := `"` + testRemoteChainA.String() + "/" + testRemoteA.Hex() + `"`
+	b := `"` + testRemoteChainB.String() + "/" + testRemoteB.Hex() + `"`
+	c := `"` + testRemoteChainB.String() + "/" + testRemoteC.Hex() + `"`
+
+	dot := topology.DOT()
+	require.Contains(t, dot, "digraph topology {\n")
+	require.Contains(t, dot, home+` [label="ERC20TokenHome\n`+testHomeChain.String()+`\n`+testHome.Hex()+
+		`\n18 decimals", style=bold];`)
+	require.Contains(t, dot, b+` [label="NativeTokenRemote\n`+testRemoteChainB.String()+`\n`+testRemoteB.Hex()+
+		`\n19 decimals\nawaiting collateral"];`)
+	require.Contains(t, dot, c+` [label="unreachable\n`+testRemoteChainB.String()+`\n`+testRemoteC.Hex()+
+		`\nfailed to inspect", color=red];`)
+	require.Contains(t, dot, home+" -> "+a+` [label="/10^12\ncollateralized", dir=both];`)
+	require.Contains(t, dot, home+" -> "+b+` [label="x10\nneeds 100 collateral", dir=both, color=red, fontcolor=red];`)
+	// Only the viable multi-hop routes are drawn
+	require.Contains(t, dot, b+" -> "+a+` [label="min 10000000000000", style=dashed, constraint=false];`)
+	require.NotContains(t, dot, a+" -> "+b)
+	require.True(t, dot[len(dot)-2:] == "}\n")
+}
+
+func TestTopologyJSON(t *testing.T) {
+	encoded, err := json.Marshal(newTestTopology())
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+
+	registration := decoded["registrations"].([]interface{})[1].(map[string]interface{})
+	require.Equal(t, float64(100), registration["collateralNeeded"])
+	require.Equal(t, true, registration["multiplyOnRemote"])
+	remote := registration["remote"].(map[string]interface{})
+	require.Equal(t, "NativeTokenRemote", remote["kind"])
+	require.Equal(t, false, remote["isCollateralized"])
+
+	route := decoded["routes"].([]interface{})[2].(map[string]interface{})
+	require.Equal(t, false, route["viable"])
+	require.Equal(t, "the home needs 100 more collateral for the destination", route["reason"])
+}
@@ -0,0 +1,143 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DeadLetter is a notification that could not be delivered to a subscription.
+type DeadLetter struct {
+	Subscription string       `json:"subscription"`
+	URL          string       `json:"url"`
+	Notification Notification `json:"notification"`
+	// The number of attempts made to deliver the notification, which is zero if it was never sent
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Time     time.Time `json:"time"`
+}
+
+// DeadLetterStore records the notifications that could not be delivered.
+type DeadLetterStore interface {
+	Add(deadLetter DeadLetter) error
+	// List returns the dead letters in the order they were added.
+	List() ([]DeadLetter, error)
+	// Replace replaces all the dead letters of the store.
+	Replace(deadLetters []DeadLetter) error
+}
+
+// MemoryDeadLetters is a DeadLetterStore that keeps the dead letters in memory.
+type MemoryDeadLetters struct {
+	lock        sync.Mutex
+	deadLetters []DeadLetter
+}
+
+func (m *MemoryDeadLetters) Add(deadLetter DeadLetter) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.deadLetters = append(m.deadLetters, deadLetter)
+	return nil
+}
+
+func (m *MemoryDeadLetters) List() ([]DeadLetter, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]DeadLetter(nil), m.deadLetters...), nil
+}
+
+func (m *MemoryDeadLetters) Replace(deadLetters []DeadLetter) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.deadLetters = append([]DeadLetter(nil), deadLetters...)
+	return nil
+}
+
+// FileDeadLetters is a DeadLetterStore that appends the dead letters to a file, as JSON lines,
+// so that they survive restarts.
+type FileDeadLetters struct {
+	Path string
+
+	lock sync.Mutex
+}
+
+func (f *FileDeadLetters) Add(deadLetter DeadLetter) error {
+	line, err := json.Marshal(deadLetter)
+	if err != nil {
+		return err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (f *FileDeadLetters) List() ([]DeadLetter, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	file, err := os.Open(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var deadLetters []DeadLetter
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var deadLetter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &deadLetter); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", f.Path, line, err)
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	return deadLetters, scanner.Err()
+}
+
+// Replace writes the dead letters to a temporary file that is then renamed over the file,
+// so that the dead letters are not lost if it fails.
+func (f *FileDeadLetters) Replace(deadLetters []DeadLetter) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	file, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for _, deadLetter := range deadLetters {
+		if err := encoder.Encode(deadLetter); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), f.Path)
+}
@@ -0,0 +1,238 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// The headers of webhook requests
+const (
+	IDHeader        = "X-Webhook-ID"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+
+	signaturePrefix = "sha256="
+	// Maximum size of the response bodies included in delivery errors
+	maxErrorBody = 512
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleTimestamp   = errors.New("stale webhook timestamp")
+)
+
+// Sign returns the signature of a notification sent at the timestamp, in unix seconds: the hex encoded
+// HMAC-SHA256 of the timestamp, a period and the body, keyed with the secret of the subscription and
+// prefixed with "sha256=".
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns true if the signature of a notification is valid.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// VerifyRequest checks the signature of a webhook request, and that it was sent within maxAge of now,
+// to reject replayed requests. It returns the body of the request.
+func VerifyRequest(r *http.Request, secret string, maxAge time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+	}
+	if !Verify(secret, timestamp, body, r.Header.Get(SignatureHeader)) {
+		return nil, ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(timestamp, 0)); age > maxAge || age < -maxAge {
+		return nil, fmt.Errorf("%w: sent %s ago", ErrStaleTimestamp, age.Round(time.Second))
+	}
+	return body, nil
+}
+
+// statusError is the error of a delivery the endpoint responded to with a status other than 2xx.
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.code, e.body)
+}
+
+// permanent returns true if retrying the delivery cannot succeed: the endpoint rejected the request,
+// other than to have it retried later.
+func (e *statusError) permanent() bool {
+	return e.code >= 400 && e.code < 500 &&
+		e.code != http.StatusRequestTimeout && e.code != http.StatusTooManyRequests
+}
+
+// deliverQueue delivers the notifications queued for the i-th subscription in order, until its queue
+// is closed. Once ctx is done, the notifications still queued are recorded as dead letters instead.
+func (d *Dispatcher) deliverQueue(ctx context.Context, i int) {
+	s := d.config.Subscriptions[i]
+	for n := range d.queues[i] {
+		if ctx.Err() != nil {
+			d.deadLetter(s, n, 0, ErrStopped)
+			continue
+		}
+		if attempts, err := d.deliver(ctx, s, n); err != nil {
+			d.deadLetter(s, n, attempts, err)
+		}
+	}
+}
+
+// deliver sends a notification to a subscription, retrying with exponential backoff until it is accepted,
+// rejected permanently, or the maximum number of attempts is reached. It returns the number of attempts
+// made and the error of the last one.
+func (d *Dispatcher) deliver(ctx context.Context, s Subscription, n Notification) (int, error) {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return 0, err
+	}
+	backoff := d.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err = d.post(ctx, s, n.ID, body)
+		if err == nil {
+			return attempt, nil
+		}
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && statusErr.permanent() {
+			return attempt, err
+		}
+		if attempt == d.config.MaxAttempts {
+			return attempt, err
+		}
+		log.Debug(
+			"Failed to deliver notification",
+			"subscription", s.Name,
+			"id", n.ID,
+			"attempt", attempt,
+			"err", err,
+		)
+		select {
+		case <-ctx.Done():
+			return attempt, fmt.Errorf("%w after %v", ErrStopped, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, d.config.MaxBackoff)
+	}
+}
+
+// post sends a signed notification to a subscription.
+func (d *Dispatcher) post(ctx context.Context, s Subscription, id string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, d.config.RequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IDHeader, id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(s.Secret, timestamp, body))
+
+	resp, err := d.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &statusError{code: resp.StatusCode, body: string(respBody)}
+	}
+	return nil
+}
+
+// deadLetter records a notification that could not be delivered to a subscription.
+func (d *Dispatcher) deadLetter(s Subscription, n Notification, attempts int, err error) {
+	log.Warn(
+		"Failed to deliver notification",
+		"subscription", s.Name,
+		"id", n.ID,
+		"event", n.Event,
+		"attempts", attempts,
+		"err", err,
+	)
+	deadLetter := DeadLetter{
+		Subscription: s.Name,
+		URL:          s.URL,
+		Notification: n,
+		Attempts:     attempts,
+		Error:        err.Error(),
+		Time:         time.Now().UTC(),
+	}
+	if err := d.config.DeadLetters.Add(deadLetter); err != nil {
+		log.Error("Failed to record dead letter", "subscription", s.Name, "id", n.ID, "err", err)
+	}
+}
+
+// Redeliver attempts to deliver the dead letters of the store again, and returns the number delivered.
+// The dead letters that are delivered are removed from the store, and the others are updated with the
+// error of their last attempt. Those of subscriptions that are no longer configured are kept as they are.
+// It should not be called while Run records dead letters to the same store.
+func (d *Dispatcher) Redeliver(ctx context.Context) (int, error) {
+	deadLetters, err := d.config.DeadLetters.List()
+	if err != nil {
+		return 0, err
+	}
+	subscriptions := make(map[string]Subscription)
+	for _, s := range d.config.Subscriptions {
+		subscriptions[s.Name] = s
+	}
+
+	var (
+		delivered int
+		remaining []DeadLetter
+	)
+	for i, deadLetter := range deadLetters {
+		if ctx.Err() != nil {
+			remaining = append(remaining, deadLetters[i:]...)
+			break
+		}
+		s, ok := subscriptions[deadLetter.Subscription]
+		if !ok {
+			log.Warn(
+				"Skipping dead letter",
+				"id", deadLetter.Notification.ID,
+				"err", fmt.Errorf("%w %q", errUnknownSubscription, deadLetter.Subscription),
+			)
+			remaining = append(remaining, deadLetter)
+			continue
+		}
+		attempts, err := d.deliver(ctx, s, deadLetter.Notification)
+		if err == nil {
+			delivered++
+			continue
+		}
+		deadLetter.URL = s.URL
+		deadLetter.Attempts += attempts
+		deadLetter.Error = err.Error()
+		deadLetter.Time = time.Now().UTC()
+		remaining = append(remaining, deadLetter)
+	}
+	return delivered, d.config.DeadLetters.Replace(remaining)
+}
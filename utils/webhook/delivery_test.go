@@ -0,0 +1,195 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"0x01"}`)
+	signature := Sign("secret", 1700000000, body)
+	require.Regexp(t, "^sha256=[0-9a-f]{64}$", signature)
+	require.True(t, Verify("secret", 1700000000, body, signature))
+	require.False(t, Verify("other", 1700000000, body, signature))
+	require.False(t, Verify("secret", 1700000001, body, signature))
+	require.False(t, Verify("secret", 1700000000, []byte(`{"id":"0x02"}`), signature))
+}
+
+// receiver is a webhook endpoint that responds with the given statuses in turn, and then with 200.
+type receiver struct {
+	t        *testing.T
+	secret   string
+	lock     sync.Mutex
+	statuses []int
+	received []Notification
+	attempts int
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := VerifyRequest(req, r.secret, time.Minute)
+	require.NoError(r.t, err)
+	var n Notification
+	require.NoError(r.t, json.Unmarshal(body, &n))
+	require.Equal(r.t, n.ID, req.Header.Get(IDHeader))
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.attempts++
+	if len(r.statuses) != 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	r.received = append(r.received, n)
+}
+
+func newTestDispatcher(t *testing.T, url string, deadLetters DeadLetterStore) *Dispatcher {
+	d, err := New(Config{
+		Subscriptions:  []Subscription{{Name: "ops", URL: url, Secret: "secret"}},
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+		DeadLetters:    deadLetters,
+	})
+	require.NoError(t, err)
+	return d
+}
+
+func TestDeliver(t *testing.T) {
+	testCases := []struct {
+		name             string
+		statuses         []int
+		expectedAttempts int
+		expectedError    string
+	}{
+		{
+			name:             "delivered",
+			expectedAttempts: 1,
+		},
+		{
+			name:             "delivered after retries",
+			statuses:         []int{http.StatusInternalServerError, http.StatusTooManyRequests},
+			expectedAttempts: 3,
+		},
+		{
+			name:             "rejected",
+			statuses:         []int{http.StatusBadRequest},
+			expectedAttempts: 1,
+			expectedError:    "status 400: ",
+		},
+		{
+			name: "attempts exhausted",
+			statuses: []int{
+				http.StatusServiceUnavailable,
+				http.StatusServiceUnavailable,
+				http.StatusBadGateway,
+			},
+			expectedAttempts: 3,
+			expectedError:    "status 502: ",
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := &receiver{t: t, secret: "secret", statuses: test.statuses}
+			server := httptest.NewServer(r)
+			defer server.Close()
+			d := newTestDispatcher(t, server.URL, nil)
+
+			n := Notification{ID: "0x01", Subscription: "ops", Event: EventCallFailed}
+			attempts, err := d.deliver(context.Background(), d.config.Subscriptions[0], n)
+			require.Equal(t, test.expectedAttempts, attempts)
+			require.Equal(t, test.expectedAttempts, r.attempts)
+			if test.expectedError != "" {
+				require.EqualError(t, err, test.expectedError)
+				require.Empty(t, r.received)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, r.received, 1)
+			require.Equal(t, n.ID, r.received[0].ID)
+			require.Equal(t, EventCallFailed, r.received[0].Event)
+		})
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := []byte(`{}`)
+	newRequest := func(timestamp int64, signature string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(SignatureHeader, signature)
+		return req
+	}
+	now := time.Now().Unix()
+
+	received, err := VerifyRequest(newRequest(now, Sign("secret", now, body)), "secret", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, body, received)
+
+	_, err = VerifyRequest(newRequest(now, Sign("other", now, body)), "secret", time.Minute)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	stale := now - 120
+	_, err = VerifyRequest(newRequest(stale, Sign("secret", stale, body)), "secret", time.Minute)
+	require.ErrorIs(t, err, ErrStaleTimestamp)
+}
+
+func TestRedeliver(t *testing.T) {
+	r := &receiver{t: t, secret: "secret", statuses: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(r)
+	defer server.Close()
+	store := &FileDeadLetters{Path: filepath.Join(t.TempDir(), "dead-letters.jsonl")}
+	d := newTestDispatcher(t, server.URL, store)
+
+	for _, deadLetter := range []DeadLetter{
+		{Subscription: "ops", Notification: Notification{ID: "0x01", Data: CallFailedData{Amount: "1"}}},
+		{Subscription: "ops", Notification: Notification{ID: "0x02"}, Attempts: 8},
+		{Subscription: "removed", Notification: Notification{ID: "0x03"}},
+	} {
+		require.NoError(t, store.Add(deadLetter))
+	}
+
+	// The first redelivery is rejected and kept, and the dead letter of the removed subscription is kept.
+	delivered, err := d.Redeliver(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, delivered)
+	deadLetters, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 2)
+	require.Equal(t, "0x01", deadLetters[0].Notification.ID)
+	require.Equal(t, 1, deadLetters[0].Attempts)
+	require.Equal(t, "status 400: ", deadLetters[0].Error)
+	require.Equal(t, server.URL, deadLetters[0].URL)
+	require.Equal(t, "0x03", deadLetters[1].Notification.ID)
+
+	delivered, err = d.Redeliver(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, delivered)
+	deadLetters, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	require.Equal(t, "0x03", deadLetters[0].Notification.ID)
+
+	require.Len(t, r.received, 2)
+	require.Equal(t, "0x02", r.received[0].ID)
+	require.Equal(t, "0x01", r.received[1].ID)
+	// Data is decoded generically from the file store and redelivered unchanged.
+	require.Equal(t, map[string]interface{}{
+		"recipientContract": "0x0000000000000000000000000000000000000000",
+		"amount":            "1",
+	}, r.received[1].Data)
+}
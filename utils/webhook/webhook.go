@@ -0,0 +1,524 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+// Package webhook notifies HTTP endpoints of the events of token transferrers that operators act on:
+// remote registrations, collateral additions, transfers, failed calls and reports of burned fees.
+//
+// The logs of the token transferrers of each chain are followed with a subscription.Subscriber, which
+// backfills and tails them as the generated Filter* and Watch* methods do, and are decoded with the
+// Parse* methods of the bindings. Each subscription receives the events matching its filter as JSON
+// notifications, signed with its secret, in the order they were emitted on each chain. Notifications that
+// cannot be delivered are retried with exponential backoff, and then recorded in a dead letter store
+// from which they can be redelivered.
+package webhook
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	nativetokenremote "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenRemote/NativeTokenRemote"
+	"github.com/ava-labs/avalanche-interchain-token-transfer/utils/subscription"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/accounts/abi/bind"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ava-labs/subnet-evm/interfaces"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultMaxAttempts    = 8
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+	defaultRequestTimeout = 10 * time.Second
+
+	// Capacity of the queue of notifications of each subscription. Notifications that do not fit
+	// are recorded as dead letters.
+	queueSize = 1024
+	// Capacity of the channel of the logs of each chain
+	logBuffer = 64
+)
+
+var (
+	// ErrQueueFull is the error of dead letters that were not queued, because their subscription
+	// fell too far behind.
+	ErrQueueFull = errors.New("notification queue full")
+	// ErrStopped is the error of dead letters that were still queued when the dispatcher stopped.
+	ErrStopped = errors.New("dispatcher stopped")
+
+	errMissingDial         = errors.New("missing dial function")
+	errMissingSecret       = errors.New("missing secret")
+	errDuplicateChain      = errors.New("duplicate chain")
+	errDuplicateName       = errors.New("duplicate subscription name")
+	errUnknownEvent        = errors.New("unknown event")
+	errUnknownSubscription = errors.New("unknown subscription")
+)
+
+// EventType is the event of a notification.
+type EventType string
+
+const (
+	// A remote was registered with a TokenHome
+	EventRemoteRegistered EventType = "RemoteRegistered"
+	// Collateral was added to a TokenHome for a remote. The remote is fully collateralized once the
+	// remaining collateral needed is zero.
+	EventCollateralAdded EventType = "CollateralAdded"
+	// A token transferrer sent tokens
+	EventTokensSent EventType = "TokensSent"
+	// The call of the recipient contract of a send and call failed, and the tokens were sent to its fallback
+	EventCallFailed EventType = "CallFailed"
+	// A NativeTokenRemote reported the transaction fees burned on its chain to its home
+	EventReportBurnedTxFees EventType = "ReportBurnedTxFees"
+)
+
+// EventTypes are the events that can be notified.
+var EventTypes = []EventType{
+	EventRemoteRegistered,
+	EventCollateralAdded,
+	EventTokensSent,
+	EventCallFailed,
+	EventReportBurnedTxFees,
+}
+
+// Notification is the JSON body of a webhook request.
+type Notification struct {
+	// Identifies the log of the event, so that notifications delivered more than once can be ignored
+	ID           string    `json:"id"`
+	Subscription string    `json:"subscription"`
+	Event        EventType `json:"event"`
+	// True if the block of a previously notified event was reorged out, retracting that notification
+	Removed      bool           `json:"removed,omitempty"`
+	BlockchainID ids.ID         `json:"blockchainID"`
+	Address      common.Address `json:"address"`
+	BlockNumber  uint64         `json:"blockNumber"`
+	TxHash       common.Hash    `json:"txHash"`
+	LogIndex     uint           `json:"logIndex"`
+	// The fields of the event: one of RemoteRegisteredData, CollateralAddedData, TokensSentData,
+	// CallFailedData and ReportBurnedTxFeesData. Amounts are decimal strings.
+	Data interface{} `json:"data"`
+}
+
+type RemoteRegisteredData struct {
+	RemoteBlockchainID            ids.ID         `json:"remoteBlockchainID"`
+	RemoteTokenTransferrerAddress common.Address `json:"remoteTokenTransferrerAddress"`
+	InitialCollateralNeeded       string         `json:"initialCollateralNeeded"`
+	TokenDecimals                 uint8          `json:"tokenDecimals"`
+}
+
+type CollateralAddedData struct {
+	RemoteBlockchainID            ids.ID         `json:"remoteBlockchainID"`
+	RemoteTokenTransferrerAddress common.Address `json:"remoteTokenTransferrerAddress"`
+	Amount                        string         `json:"amount"`
+	Remaining                     string         `json:"remaining"`
+}
+
+type TokensSentData struct {
+	MessageID                          ids.ID         `json:"messageID"`
+	Sender                             common.Address `json:"sender"`
+	DestinationBlockchainID            ids.ID         `json:"destinationBlockchainID"`
+	DestinationTokenTransferrerAddress common.Address `json:"destinationTokenTransferrerAddress"`
+	Recipient                          common.Address `json:"recipient"`
+	// In units of the token transferrer the tokens are sent to, for transfers from the home, and
+	// of the remote that sent them otherwise
+	Amount string `json:"amount"`
+}
+
+type CallFailedData struct {
+	RecipientContract common.Address `json:"recipientContract"`
+	Amount            string         `json:"amount"`
+}
+
+type ReportBurnedTxFeesData struct {
+	MessageID  ids.ID `json:"messageID"`
+	FeesBurned string `json:"feesBurned"`
+}
+
+// Filter selects the events notified to a subscription. Empty fields match every event.
+type Filter struct {
+	Events []EventType `json:"events,omitempty"`
+	// The chains and the token transferrers whose events are notified
+	BlockchainIDs []ids.ID         `json:"blockchainIDs,omitempty"`
+	Addresses     []common.Address `json:"addresses,omitempty"`
+	// TokensSent events are only notified if their amount is at least MinAmount
+	MinAmount *big.Int `json:"minAmount,omitempty"`
+	// CollateralAdded events are only notified if they fully collateralize their remote
+	FullyCollateralizedOnly bool `json:"fullyCollateralizedOnly,omitempty"`
+}
+
+// event is a decoded log, with the fields that filters match.
+type event struct {
+	notification Notification
+	// The amount of TokensSent events
+	amount *big.Int
+	// The remaining collateral needed of CollateralAdded events
+	remaining *big.Int
+}
+
+// matches returns true if the event should be notified to a subscription with the filter.
+func (f *Filter) matches(e *event) bool {
+	n := &e.notification
+	if len(f.Events) != 0 && !contains(f.Events, n.Event) {
+		return false
+	}
+	if len(f.BlockchainIDs) != 0 && !contains(f.BlockchainIDs, n.BlockchainID) {
+		return false
+	}
+	if len(f.Addresses) != 0 && !contains(f.Addresses, n.Address) {
+		return false
+	}
+	switch n.Event {
+	case EventTokensSent:
+		return f.MinAmount == nil || e.amount.Cmp(f.MinAmount) >= 0
+	case EventCollateralAdded:
+		return !f.FullyCollateralizedOnly || e.remaining.Sign() == 0
+	}
+	return true
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription is an endpoint notified of the events matching its filter.
+type Subscription struct {
+	// Identifies the subscription in notifications and dead letters
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Key of the HMAC-SHA256 signature of the notifications
+	Secret string `json:"secret"`
+	Filter Filter `json:"filter"`
+}
+
+// Chain is a chain whose token transferrers are followed.
+type Chain struct {
+	BlockchainID ids.ID
+	// Dial connects to the chain to subscribe to the logs of its token transferrers, typically over a
+	// websocket. It is called again to reconnect after the connection fails.
+	Dial         func(ctx context.Context) (subscription.Backend, error)
+	Transferrers []common.Address
+	// The first block whose events are notified
+	FromBlock uint64
+}
+
+// Config configures a Dispatcher.
+type Config struct {
+	Chains        []Chain
+	Subscriptions []Subscription
+	// Number of blocks on top of the block of an event before it is notified.
+	Confirmations uint64
+	// Number of attempts to deliver a notification before it is recorded as a dead letter. Defaults to 8.
+	MaxAttempts int
+	// Time waited before retrying a delivery, doubled after each attempt up to MaxBackoff.
+	// Default to one second and five minutes.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Time allowed for each request. Defaults to 10 seconds.
+	RequestTimeout time.Duration
+	// Records the notifications that could not be delivered. Defaults to a MemoryDeadLetters.
+	DeadLetters DeadLetterStore
+	// Sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// Dispatcher notifies the subscriptions of its config of the events of the token transferrers.
+type Dispatcher struct {
+	config Config
+	// The notifications to deliver to each subscription while running, in the order of the config
+	queues []chan Notification
+}
+
+// New returns a Dispatcher for the config.
+func New(config Config) (*Dispatcher, error) {
+	blockchainIDs := make(map[ids.ID]bool)
+	for _, chain := range config.Chains {
+		if chain.Dial == nil {
+			return nil, fmt.Errorf("%s: %w", chain.BlockchainID, errMissingDial)
+		}
+		if blockchainIDs[chain.BlockchainID] {
+			return nil, fmt.Errorf("%w: %s", errDuplicateChain, chain.BlockchainID)
+		}
+		blockchainIDs[chain.BlockchainID] = true
+	}
+	names := make(map[string]bool)
+	for _, s := range config.Subscriptions {
+		if names[s.Name] {
+			return nil, fmt.Errorf("%w: %q", errDuplicateName, s.Name)
+		}
+		names[s.Name] = true
+		if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("invalid URL %q of subscription %q", s.URL, s.Name)
+		}
+		if s.Secret == "" {
+			return nil, fmt.Errorf("subscription %q: %w", s.Name, errMissingSecret)
+		}
+		for _, e := range s.Filter.Events {
+			if !contains(EventTypes, e) {
+				return nil, fmt.Errorf("subscription %q: %w %q", s.Name, errUnknownEvent, e)
+			}
+		}
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.InitialBackoff == 0 {
+		config.InitialBackoff = defaultInitialBackoff
+	}
+	if config.MaxBackoff == 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	if config.RequestTimeout == 0 {
+		config.RequestTimeout = defaultRequestTimeout
+	}
+	if config.DeadLetters == nil {
+		config.DeadLetters = &MemoryDeadLetters{}
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+
+	return &Dispatcher{config: config}, nil
+}
+
+// Run notifies the subscriptions of the events of the chains until ctx is done or the subscription to the
+// logs of a chain fails. The notifications still queued when it returns are recorded as dead letters.
+// It must not be called again before it returns.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	d.queues = make([]chan Notification, len(d.config.Subscriptions))
+	var workers, watchers sync.WaitGroup
+	for i := range d.queues {
+		d.queues[i] = make(chan Notification, queueSize)
+		workers.Add(1)
+		go func(i int) {
+			defer workers.Done()
+			d.deliverQueue(ctx, i)
+		}(i)
+	}
+	// The queues are closed once the watchers stop queueing notifications to them.
+	defer func() {
+		cancel()
+		watchers.Wait()
+		for _, queue := range d.queues {
+			close(queue)
+		}
+		workers.Wait()
+	}()
+
+	errs := make(chan error, len(d.config.Chains))
+	for _, chain := range d.config.Chains {
+		subscriber, err := subscription.NewSubscriber(subscription.Config{
+			Query:         eventQuery(chain.Transferrers),
+			Confirmations: d.config.Confirmations,
+			FromBlock:     chain.FromBlock,
+			Dial:          chain.Dial,
+		})
+		if err != nil {
+			return err
+		}
+		watchers.Add(1)
+		go func(blockchainID ids.ID) {
+			defer watchers.Done()
+			errs <- d.watch(ctx, blockchainID, subscriber)
+		}(chain.BlockchainID)
+	}
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// eventQuery returns the query for the notified events of the token transferrers at the addresses.
+func eventQuery(addresses []common.Address) interfaces.FilterQuery {
+	topics := make([]common.Hash, 0, len(EventTypes))
+	for _, e := range EventTypes {
+		topics = append(topics, eventIDs[e])
+	}
+	return interfaces.FilterQuery{
+		Addresses: addresses,
+		Topics:    [][]common.Hash{topics},
+	}
+}
+
+// watch notifies the events of the logs of a chain.
+func (d *Dispatcher) watch(ctx context.Context, blockchainID ids.ID, subscriber *subscription.Subscriber) error {
+	logs := make(chan types.Log, logBuffer)
+	subscriberErr := make(chan error, 1)
+	go func() {
+		subscriberErr <- subscriber.Run(ctx, logs)
+	}()
+	for {
+		select {
+		case err := <-subscriberErr:
+			return fmt.Errorf("subscription to %s failed: %w", blockchainID, err)
+		case l := <-logs:
+			d.dispatch(blockchainID, l)
+		}
+	}
+}
+
+// dispatch queues the notifications of a log to the subscriptions it matches.
+func (d *Dispatcher) dispatch(blockchainID ids.ID, l types.Log) {
+	e, err := decodeLog(blockchainID, l)
+	if err != nil {
+		log.Warn("Failed to decode log", "blockchainID", blockchainID, "txHash", l.TxHash, "err", err)
+		return
+	}
+	if e == nil {
+		return
+	}
+	for i, s := range d.config.Subscriptions {
+		if !s.Filter.matches(e) {
+			continue
+		}
+		n := e.notification
+		n.Subscription = s.Name
+		select {
+		case d.queues[i] <- n:
+		default:
+			d.deadLetter(s, n, 0, ErrQueueFull)
+		}
+	}
+}
+
+var (
+	tokenHomeABI         = mustGetABI(tokenhome.TokenHomeMetaData)
+	nativeTokenRemoteABI = mustGetABI(nativetokenremote.NativeTokenRemoteMetaData)
+
+	// The topics of the notified events. ReportBurnedTxFees is only emitted by NativeTokenRemotes, and the
+	// other events by every TokenHome.
+	eventIDs = map[EventType]common.Hash{
+		EventRemoteRegistered:   tokenHomeABI.Events[string(EventRemoteRegistered)].ID,
+		EventCollateralAdded:    tokenHomeABI.Events[string(EventCollateralAdded)].ID,
+		EventTokensSent:         tokenHomeABI.Events[string(EventTokensSent)].ID,
+		EventCallFailed:         tokenHomeABI.Events[string(EventCallFailed)].ID,
+		EventReportBurnedTxFees: nativeTokenRemoteABI.Events[string(EventReportBurnedTxFees)].ID,
+	}
+
+	// The filterers only decode logs, so they are not bound to a contract.
+	tokenHomeFilterer         = mustNewFilterer(tokenhome.NewTokenHomeFilterer)
+	nativeTokenRemoteFilterer = mustNewFilterer(nativetokenremote.NewNativeTokenRemoteFilterer)
+)
+
+func mustGetABI(metadata *bind.MetaData) *abi.ABI {
+	parsed, err := metadata.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func mustNewFilterer[T any](newFilterer func(common.Address, bind.ContractFilterer) (*T, error)) *T {
+	filterer, err := newFilterer(common.Address{}, nil)
+	if err != nil {
+		panic(err)
+	}
+	return filterer
+}
+
+// decodeLog returns the event of a log, or nil if it is not one of the notified events.
+func decodeLog(blockchainID ids.ID, l types.Log) (*event, error) {
+	if len(l.Topics) == 0 {
+		return nil, nil
+	}
+	e := &event{notification: Notification{
+		ID:           notificationID(blockchainID, l),
+		Removed:      l.Removed,
+		BlockchainID: blockchainID,
+		Address:      l.Address,
+		BlockNumber:  l.BlockNumber,
+		TxHash:       l.TxHash,
+		LogIndex:     l.Index,
+	}}
+	n := &e.notification
+	switch l.Topics[0] {
+	case eventIDs[EventRemoteRegistered]:
+		registered, err := tokenHomeFilterer.ParseRemoteRegistered(l)
+		if err != nil {
+			return nil, err
+		}
+		n.Event = EventRemoteRegistered
+		n.Data = RemoteRegisteredData{
+			RemoteBlockchainID:            registered.RemoteBlockchainID,
+			RemoteTokenTransferrerAddress: registered.RemoteTokenTransferrerAddress,
+			InitialCollateralNeeded:       registered.InitialCollateralNeeded.String(),
+			TokenDecimals:                 registered.TokenDecimals,
+		}
+	case eventIDs[EventCollateralAdded]:
+		added, err := tokenHomeFilterer.ParseCollateralAdded(l)
+		if err != nil {
+			return nil, err
+		}
+		n.Event = EventCollateralAdded
+		n.Data = CollateralAddedData{
+			RemoteBlockchainID:            added.RemoteBlockchainID,
+			RemoteTokenTransferrerAddress: added.RemoteTokenTransferrerAddress,
+			Amount:                        added.Amount.String(),
+			Remaining:                     added.Remaining.String(),
+		}
+		e.remaining = added.Remaining
+	case eventIDs[EventTokensSent]:
+		sent, err := tokenHomeFilterer.ParseTokensSent(l)
+		if err != nil {
+			return nil, err
+		}
+		n.Event = EventTokensSent
+		n.Data = TokensSentData{
+			MessageID:                          sent.TeleporterMessageID,
+			Sender:                             sent.Sender,
+			DestinationBlockchainID:            sent.Input.DestinationBlockchainID,
+			DestinationTokenTransferrerAddress: sent.Input.DestinationTokenTransferrerAddress,
+			Recipient:                          sent.Input.Recipient,
+			Amount:                             sent.Amount.String(),
+		}
+		e.amount = sent.Amount
+	case eventIDs[EventCallFailed]:
+		failed, err := tokenHomeFilterer.ParseCallFailed(l)
+		if err != nil {
+			return nil, err
+		}
+		n.Event = EventCallFailed
+		n.Data = CallFailedData{
+			RecipientContract: failed.RecipientContract,
+			Amount:            failed.Amount.String(),
+		}
+	case eventIDs[EventReportBurnedTxFees]:
+		reported, err := nativeTokenRemoteFilterer.ParseReportBurnedTxFees(l)
+		if err != nil {
+			return nil, err
+		}
+		n.Event = EventReportBurnedTxFees
+		n.Data = ReportBurnedTxFeesData{
+			MessageID:  reported.TeleporterMessageID,
+			FeesBurned: reported.FeesBurned.String(),
+		}
+	default:
+		return nil, nil
+	}
+	return e, nil
+}
+
+// notificationID returns the ID of the notifications of a log.
+func notificationID(blockchainID ids.ID, l types.Log) string {
+	index := make([]byte, 8)
+	binary.BigEndian.PutUint64(index, uint64(l.Index))
+	return crypto.Keccak256Hash(blockchainID[:], l.TxHash[:], index).Hex()
+}
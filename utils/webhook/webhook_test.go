@@ -0,0 +1,353 @@
+// Copyright (C) 2024, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package webhook
+
+import (
+	"math/big"
+	"testing"
+
+	tokenhome "github.com/ava-labs/avalanche-interchain-token-transfer/abi-bindings/go/TokenHome/TokenHome"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/subnet-evm/accounts/abi"
+	"github.com/ava-labs/subnet-evm/core/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	homeBlockchainID   = ids.ID{1}
+	remoteBlockchainID = ids.ID{2}
+	homeAddress        = common.HexToAddress("0x1111111111111111111111111111111111111111")
+	remoteAddress      = common.HexToAddress("0x2222222222222222222222222222222222222222")
+	recipient          = common.HexToAddress("0x3333333333333333333333333333333333333333")
+)
+
+// eventLog returns a log of the event, with the arguments in the order of its inputs.
+func eventLog(
+	t *testing.T,
+	contractABI *abi.ABI,
+	name string,
+	address common.Address,
+	args ...interface{},
+) types.Log {
+	event := contractABI.Events[name]
+	topics := []common.Hash{event.ID}
+	var data []interface{}
+	for i, input := range event.Inputs {
+		if !input.Indexed {
+			data = append(data, args[i])
+			continue
+		}
+		topic, err := abi.MakeTopics([]interface{}{args[i]})
+		require.NoError(t, err)
+		topics = append(topics, topic[0][0])
+	}
+	packed, err := event.Inputs.NonIndexed().Pack(data...)
+	require.NoError(t, err)
+	return types.Log{
+		Address:     address,
+		Topics:      topics,
+		Data:        packed,
+		BlockNumber: 7,
+		TxHash:      common.Hash{7},
+		Index:       3,
+	}
+}
+
+func tokensSentLog(t *testing.T, amount int64) types.Log {
+	return eventLog(t, tokenHomeABI, "TokensSent", homeAddress,
+		[32]byte{9},
+		recipient,
+		tokenhome.SendTokensInput{
+			DestinationBlockchainID:            remoteBlockchainID,
+			DestinationTokenTransferrerAddress: remoteAddress,
+			Recipient:                          recipient,
+			PrimaryFee:                         big.NewInt(0),
+			SecondaryFee:                       big.NewInt(0),
+			RequiredGasLimit:                   big.NewInt(250_000),
+		},
+		big.NewInt(amount),
+	)
+}
+
+func collateralAddedLog(t *testing.T, remaining int64) types.Log {
+	return eventLog(t, tokenHomeABI, "CollateralAdded", homeAddress,
+		remoteBlockchainID, remoteAddress, big.NewInt(10), big.NewInt(remaining))
+}
+
+func TestDecodeLog(t *testing.T) {
+	testCases := []struct {
+		name     string
+		log      types.Log
+		expected Notification
+	}{
+		{
+			name: "remote registered",
+			log: eventLog(t, tokenHomeABI, "RemoteRegistered", homeAddress,
+				remoteBlockchainID, remoteAddress, big.NewInt(1000), uint8(18)),
+			expected: Notification{
+				Event: EventRemoteRegistered,
+				Data: RemoteRegisteredData{
+					RemoteBlockchainID:            remoteBlockchainID,
+					RemoteTokenTransferrerAddress: remoteAddress,
+					InitialCollateralNeeded:       "1000",
+					TokenDecimals:                 18,
+				},
+			},
+		},
+		{
+			name: "collateral added",
+			log:  collateralAddedLog(t, 0),
+			expected: Notification{
+				Event: EventCollateralAdded,
+				Data: CollateralAddedData{
+					RemoteBlockchainID:            remoteBlockchainID,
+					RemoteTokenTransferrerAddress: remoteAddress,
+					Amount:                        "10",
+					Remaining:                     "0",
+				},
+			},
+		},
+		{
+			name: "tokens sent",
+			log:  tokensSentLog(t, 500),
+			expected: Notification{
+				Event: EventTokensSent,
+				Data: TokensSentData{
+					MessageID:                          ids.ID{9},
+					Sender:                             recipient,
+					DestinationBlockchainID:            remoteBlockchainID,
+					DestinationTokenTransferrerAddress: remoteAddress,
+					Recipient:                          recipient,
+					Amount:                             "500",
+				},
+			},
+		},
+		{
+			name: "call failed",
+			log:  eventLog(t, tokenHomeABI, "CallFailed", homeAddress, recipient, big.NewInt(42)),
+			expected: Notification{
+				Event: EventCallFailed,
+				Data:  CallFailedData{RecipientContract: recipient, Amount: "42"},
+			},
+		},
+		{
+			name: "burned fees reported",
+			log: eventLog(t, nativeTokenRemoteABI, "ReportBurnedTxFees", homeAddress,
+				[32]byte{8}, big.NewInt(12345)),
+			expected: Notification{
+				Event: EventReportBurnedTxFees,
+				Data:  ReportBurnedTxFeesData{MessageID: ids.ID{8}, FeesBurned: "12345"},
+			},
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			e, err := decodeLog(homeBlockchainID, test.log)
+			require.NoError(t, err)
+			require.NotNil(t, e)
+
+			expected := test.expected
+			expected.ID = notificationID(homeBlockchainID, test.log)
+			expected.BlockchainID = homeBlockchainID
+			expected.Address = homeAddress
+			expected.BlockNumber = 7
+			expected.TxHash = common.Hash{7}
+			expected.LogIndex = 3
+			require.Equal(t, expected, e.notification)
+		})
+	}
+
+	t.Run("other event", func(t *testing.T) {
+		l := eventLog(t, tokenHomeABI, "TokensWithdrawn", homeAddress, recipient, big.NewInt(1))
+		e, err := decodeLog(homeBlockchainID, l)
+		require.NoError(t, err)
+		require.Nil(t, e)
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		l := tokensSentLog(t, 1)
+		e, err := decodeLog(homeBlockchainID, l)
+		require.NoError(t, err)
+		l.Removed = true
+		removed, err := decodeLog(homeBlockchainID, l)
+		require.NoError(t, err)
+		require.True(t, removed.notification.Removed)
+		require.Equal(t, e.notification.ID, removed.notification.ID)
+	})
+}
+
+func TestFilterMatches(t *testing.T) {
+	decode := func(l types.Log) *event {
+		e, err := decodeLog(homeBlockchainID, l)
+		require.NoError(t, err)
+		return e
+	}
+	small := decode(tokensSentLog(t, 99))
+	large := decode(tokensSentLog(t, 100))
+	collateralized := decode(collateralAddedLog(t, 0))
+	undercollateralized := decode(collateralAddedLog(t, 1))
+
+	testCases := []struct {
+		name     string
+		filter   Filter
+		event    *event
+		expected bool
+	}{
+		{
+			name:     "empty filter",
+			event:    small,
+			expected: true,
+		},
+		{
+			name:     "event",
+			filter:   Filter{Events: []EventType{EventCollateralAdded}},
+			event:    collateralized,
+			expected: true,
+		},
+		{
+			name:   "other event",
+			filter: Filter{Events: []EventType{EventCollateralAdded}},
+			event:  large,
+		},
+		{
+			name:     "blockchain ID",
+			filter:   Filter{BlockchainIDs: []ids.ID{remoteBlockchainID, homeBlockchainID}},
+			event:    large,
+			expected: true,
+		},
+		{
+			name:   "other blockchain ID",
+			filter: Filter{BlockchainIDs: []ids.ID{remoteBlockchainID}},
+			event:  large,
+		},
+		{
+			name:   "other address",
+			filter: Filter{Addresses: []common.Address{remoteAddress}},
+			event:  large,
+		},
+		{
+			name:     "amount at least minimum",
+			filter:   Filter{MinAmount: big.NewInt(100)},
+			event:    large,
+			expected: true,
+		},
+		{
+			name:   "amount below minimum",
+			filter: Filter{MinAmount: big.NewInt(100)},
+			event:  small,
+		},
+		{
+			name:     "minimum amount ignored by other events",
+			filter:   Filter{MinAmount: big.NewInt(100)},
+			event:    undercollateralized,
+			expected: true,
+		},
+		{
+			name:     "fully collateralized",
+			filter:   Filter{FullyCollateralizedOnly: true},
+			event:    collateralized,
+			expected: true,
+		},
+		{
+			name:   "not fully collateralized",
+			filter: Filter{FullyCollateralizedOnly: true},
+			event:  undercollateralized,
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, test.filter.matches(test.event))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name         string
+		subscription Subscription
+		expected     string
+	}{
+		{
+			name:         "valid",
+			subscription: Subscription{Name: "ops", URL: "https://example.com/hook", Secret: "secret"},
+		},
+		{
+			name:         "invalid URL",
+			subscription: Subscription{Name: "ops", URL: "example.com", Secret: "secret"},
+			expected:     `invalid URL "example.com" of subscription "ops"`,
+		},
+		{
+			name:         "missing secret",
+			subscription: Subscription{Name: "ops", URL: "https://example.com/hook"},
+			expected:     `subscription "ops": missing secret`,
+		},
+		{
+			name: "unknown event",
+			subscription: Subscription{
+				Name:   "ops",
+				URL:    "https://example.com/hook",
+				Secret: "secret",
+				Filter: Filter{Events: []EventType{"TokensWithdrawn"}},
+			},
+			expected: `subscription "ops": unknown event "TokensWithdrawn"`,
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New(Config{Subscriptions: []Subscription{test.subscription}})
+			if test.expected == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.expected)
+			}
+		})
+	}
+
+	s := Subscription{Name: "ops", URL: "https://example.com/hook", Secret: "secret"}
+	_, err := New(Config{Subscriptions: []Subscription{s, s}})
+	require.ErrorIs(t, err, errDuplicateName)
+}
+
+func TestDispatch(t *testing.T) {
+	d, err := New(Config{
+		Subscriptions: []Subscription{
+			{Name: "all", URL: "https://example.com/all", Secret: "secret"},
+			{
+				Name:   "large",
+				URL:    "https://example.com/large",
+				Secret: "secret",
+				Filter: Filter{MinAmount: big.NewInt(100)},
+			},
+		},
+	})
+	require.NoError(t, err)
+	d.queues = []chan Notification{make(chan Notification, 1), make(chan Notification, 1)}
+
+	d.dispatch(homeBlockchainID, tokensSentLog(t, 99))
+	require.Len(t, d.queues[0], 1)
+	require.Empty(t, d.queues[1])
+	require.Equal(t, "all", (<-d.queues[0]).Subscription)
+
+	d.dispatch(homeBlockchainID, tokensSentLog(t, 100))
+	require.Equal(t, "all", (<-d.queues[0]).Subscription)
+	require.Equal(t, "large", (<-d.queues[1]).Subscription)
+
+	// Notifications that do not fit in the queue of their subscription are dead letters.
+	d.dispatch(homeBlockchainID, tokensSentLog(t, 1))
+	d.dispatch(homeBlockchainID, tokensSentLog(t, 2))
+	deadLetters, err := d.config.DeadLetters.List()
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	require.Equal(t, "all", deadLetters[0].Subscription)
+	require.Equal(t, ErrQueueFull.Error(), deadLetters[0].Error)
+	require.Equal(t, TokensSentData{
+		MessageID:                          ids.ID{9},
+		Sender:                             recipient,
+		DestinationBlockchainID:            remoteBlockchainID,
+		DestinationTokenTransferrerAddress: remoteAddress,
+		Recipient:                          recipient,
+		Amount:                             "2",
+	}, deadLetters[0].Notification.Data)
+}